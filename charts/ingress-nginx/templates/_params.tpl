@@ -25,6 +25,42 @@
 {{- if .Values.udp }}
 - --udp-services-configmap={{ default "$(POD_NAMESPACE)" .Values.controller.udp.configMapNamespace }}/{{ include "ingress-nginx.fullname" . }}-udp
 {{- end }}
+{{- if .Values.controller.streamRoutes.enabled }}
+- --enable-stream-routes
+{{- end }}
+{{- if .Values.controller.ingressClassParams.enabled }}
+- --enable-ingress-class-params
+{{- end }}
+{{- if .Values.controller.gatewayAPI.enabled }}
+- --enable-gateway-api
+{{- if .Values.controller.gatewayAPI.experimental }}
+- --enable-experimental-gateway-api
+{{- end }}
+{{- if .Values.controller.gatewayAPI.referenceGrants }}
+- --enable-reference-grants
+{{- end }}
+{{- end }}
+{{- if .Values.controller.hostOwnershipPolicy }}
+- --host-ownership-policy={{ .Values.controller.hostOwnershipPolicy }}
+{{- end }}
+{{- if .Values.controller.enableWAFPolicies }}
+- --enable-waf-policies
+{{- end }}
+{{- if .Values.controller.enableDenylists }}
+- --enable-denylists
+{{- end }}
+{{- if .Values.controller.enableRedirectMaps }}
+- --enable-redirect-maps
+{{- end }}
+{{- if .Values.controller.acme.enabled }}
+- --enable-acme
+{{- if .Values.controller.acme.directoryURL }}
+- --acme-directory-url={{ .Values.controller.acme.directoryURL }}
+{{- end }}
+{{- if .Values.controller.acme.email }}
+- --acme-email={{ .Values.controller.acme.email }}
+{{- end }}
+{{- end }}
 {{- if .Values.controller.scope.enabled }}
 - --watch-namespace={{ default "$(POD_NAMESPACE)" .Values.controller.scope.namespace }}
 {{- end }}
@@ -38,6 +74,18 @@
 - --validating-webhook=:{{ .Values.controller.admissionWebhooks.port }}
 - --validating-webhook-certificate={{ .Values.controller.admissionWebhooks.certificate }}
 - --validating-webhook-key={{ .Values.controller.admissionWebhooks.key }}
+- --admission-cache-size={{ .Values.controller.admissionWebhooks.cacheSize }}
+{{- if .Values.controller.admissionWebhooks.diffWarnings }}
+- --admission-diff-warnings
+{{- end }}
+{{- if ne .Values.controller.admissionWebhooks.validation.mode "full" }}
+- --admission-validation-mode={{ .Values.controller.admissionWebhooks.validation.mode }}
+{{- end }}
+{{- if eq .Values.controller.admissionWebhooks.validation.mode "sandbox" }}
+- --admission-sandbox-timeout={{ .Values.controller.admissionWebhooks.validation.sandbox.timeout }}
+- --admission-sandbox-cpu-limit={{ .Values.controller.admissionWebhooks.validation.sandbox.cpuLimit }}
+- --admission-sandbox-concurrency={{ .Values.controller.admissionWebhooks.validation.sandbox.concurrency }}
+{{- end }}
 {{- end }}
 {{- if .Values.controller.maxmindLicenseKey }}
 - --maxmind-license-key={{ .Values.controller.maxmindLicenseKey }}
@@ -54,6 +102,9 @@
 {{- if .Values.controller.watchIngressWithoutClass }}
 - --watch-ingress-without-class=true
 {{- end }}
+{{- if .Values.controller.watchReferencedSecrets }}
+- --watch-referenced-secrets=true
+{{- end }}
 {{- if not .Values.controller.metrics.enabled }}
 - --enable-metrics={{ .Values.controller.metrics.enabled }}
 {{- end }}
@@ -20,16 +20,21 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/cachepurge"
 	"k8s.io/ingress-nginx/internal/nginx"
 )
 
 const (
-	backendsPath = "/configuration/backends"
-	generalPath  = "/configuration/general"
-	certsPath    = "/configuration/certs"
+	backendsPath  = "/configuration/backends"
+	ejectionsPath = "/configuration/ejections"
+	generalPath   = "/configuration/general"
+	certsPath     = "/configuration/certs"
 )
 
 func main() {
@@ -72,6 +77,15 @@ func main() {
 	}
 	backendsCmd.AddCommand(backendsGetCmd)
 
+	backendsEjectionsCmd := &cobra.Command{
+		Use:   "ejections",
+		Short: "Output the endpoints ejected by the outlier detection as a JSON array",
+		Run: func(_ *cobra.Command, _ []string) {
+			backendsEjections()
+		},
+	}
+	backendsCmd.AddCommand(backendsEjectionsCmd)
+
 	certCmd := &cobra.Command{
 		Use:   "certs",
 		Short: "Inspect dynamic SSL certificates",
@@ -108,6 +122,75 @@ func main() {
 	}
 	rootCmd.AddCommand(confCmd)
 
+	var manifest string
+	dryRunCmd := &cobra.Command{
+		Use:   "dry-run",
+		Short: "Output the server blocks the controller would generate for an Ingress manifest, without applying them",
+		Run: func(_ *cobra.Command, _ []string) {
+			dryRun(manifest)
+		},
+	}
+	dryRunCmd.Flags().StringVarP(&manifest, "filename", "f", "-", "Ingress manifest to render, - to read it from the standard input")
+	rootCmd.AddCommand(dryRunCmd)
+
+	snapshotsCmd := &cobra.Command{
+		Use:   "snapshots",
+		Short: "Inspect the configuration snapshots and roll back to one of them",
+	}
+	rootCmd.AddCommand(snapshotsCmd)
+
+	snapshotsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Output the configuration snapshots as a JSON array, newest first",
+		Run: func(_ *cobra.Command, _ []string) {
+			snapshotsRequest(http.MethodGet, nginx.SnapshotsPath)
+		},
+	}
+	snapshotsCmd.AddCommand(snapshotsListCmd)
+
+	snapshotsGetCmd := &cobra.Command{
+		Use:   "get [snapshot id]",
+		Short: "Output the NGINX configuration of the snapshot",
+		Args:  cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			snapshotsRequest(http.MethodGet, nginx.SnapshotsPath+"/"+args[0])
+		},
+	}
+	snapshotsCmd.AddCommand(snapshotsGetCmd)
+
+	snapshotsRollbackCmd := &cobra.Command{
+		Use:   "rollback [snapshot id]",
+		Short: "Apply the configuration of the snapshot and pause the syncs until they are resumed",
+		Args:  cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			snapshotsRequest(http.MethodPost, nginx.SnapshotsPath+"/"+args[0]+"/rollback")
+		},
+	}
+	snapshotsCmd.AddCommand(snapshotsRollbackCmd)
+
+	snapshotsResumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume the syncs paused by a rollback, applying the current configuration",
+		Run: func(_ *cobra.Command, _ []string) {
+			snapshotsRequest(http.MethodPost, nginx.SnapshotsPath+"/resume")
+		},
+	}
+	snapshotsCmd.AddCommand(snapshotsResumeCmd)
+
+	purgeRequest := cachepurge.Request{}
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Purge the responses of a host, a path or the paths with a prefix from the proxy cache",
+		Run: func(_ *cobra.Command, _ []string) {
+			purge(&purgeRequest)
+		},
+	}
+	purgeCmd.Flags().StringVar(&purgeRequest.Host, "host", "", "Host of the responses to purge")
+	purgeCmd.Flags().StringVar(&purgeRequest.Path, "path", "", "Path of the responses to purge, all the paths of the host if empty")
+	purgeCmd.Flags().BoolVar(&purgeRequest.Prefix, "prefix", false, "Purge the responses of the paths starting with the path")
+	purgeCmd.Flags().StringVar(&purgeRequest.Zone, "zone", "", "Cache zone of the responses to purge, all the zones if empty")
+	rootCmd.AddCommand(purgeCmd)
+
 	rootCmd.PersistentFlags().IntVar(&nginx.StatusPort, "status-port", 10246, `Port to use for the lua HTTP endpoint configuration.`)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -208,6 +291,27 @@ func backendsGet(name string) {
 	fmt.Println("A backend of this name was not found.")
 }
 
+func backendsEjections() {
+	statusCode, body, requestErr := nginx.NewGetStatusRequest(ejectionsPath)
+	if requestErr != nil {
+		fmt.Println(requestErr)
+		return
+	}
+	if statusCode != 200 {
+		fmt.Printf("Nginx returned code %v\n", statusCode)
+		return
+	}
+
+	var prettyBuffer bytes.Buffer
+	indentErr := json.Indent(&prettyBuffer, body, "", "  ")
+	if indentErr != nil {
+		fmt.Println(indentErr)
+		return
+	}
+
+	fmt.Println(prettyBuffer.String())
+}
+
 func certGet(host string) {
 	statusCode, body, requestErr := nginx.NewGetStatusRequest(certsPath + "?hostname=" + host)
 	if requestErr != nil {
@@ -249,3 +353,60 @@ func readNginxConf() {
 
 	fmt.Println(conf)
 }
+
+func dryRun(manifest string) {
+	var data []byte
+	var err error
+	if manifest == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(manifest)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	statusCode, body, requestErr := nginx.NewDryRunRequest(data)
+	if requestErr != nil {
+		fmt.Println(requestErr)
+		return
+	}
+	if statusCode != 200 {
+		fmt.Printf("Controller returned code %v\n", statusCode)
+	}
+
+	fmt.Print(string(body))
+}
+
+func snapshotsRequest(method, path string) {
+	statusCode, body, requestErr := nginx.NewAdminRequest(method, path, "", nil)
+	if requestErr != nil {
+		fmt.Println(requestErr)
+		return
+	}
+	if statusCode != 200 {
+		fmt.Printf("Controller returned code %v\n", statusCode)
+	}
+
+	fmt.Print(string(body))
+}
+
+func purge(request *cachepurge.Request) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	statusCode, body, requestErr := nginx.NewAdminRequest(http.MethodPost, nginx.PurgePath, "application/json", data)
+	if requestErr != nil {
+		fmt.Println(requestErr)
+		return
+	}
+	if statusCode != 200 {
+		fmt.Printf("Controller returned code %v\n", statusCode)
+	}
+
+	fmt.Print(string(body))
+}
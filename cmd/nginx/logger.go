@@ -17,14 +17,24 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 
 	"k8s.io/klog/v2"
 
 	"gopkg.in/mcuadros/go-syslog.v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/logs"
 )
 
-func logger(address string) {
+// logger runs the internal syslog server. The logs forwarded by NGINX are
+// sent to the forwarder, if any, and the other ones are printed when
+// chrooted.
+func logger(address string, chroot bool, forwarder *logs.Forwarder) {
 	channel := make(syslog.LogPartsChannel)
 	handler := syslog.NewChannelHandler(channel)
 
@@ -39,12 +49,60 @@ func logger(address string) {
 	if err := server.Boot(); err != nil {
 		klog.Fatalf("failed to boot internal syslog: %s", err.Error())
 	}
-	klog.Infof("Is Chrooted, starting logger")
+	klog.InfoS("Starting logger", "chroot", chroot, "forwarding", forwarder != nil)
 
 	for logParts := range channel {
-		fmt.Printf("%s\n", logParts["content"])
+		if forwarder != nil {
+			if record, ok := logs.RecordFromSyslog(logParts); ok {
+				forwarder.Add(record)
+				continue
+			}
+		}
+
+		if chroot {
+			fmt.Printf("%s\n", logParts["content"])
+		}
 	}
 
 	server.Wait()
 	klog.Infof("Stopping logger")
 }
+
+// newLogForwarder creates the forwarder of the access and error logs to the
+// OTLP endpoint and the syslog server of the configuration
+func newLogForwarder(conf *controller.Configuration) (*logs.Forwarder, error) {
+	var tlsConfig *tls.Config
+	if conf.LogForwardCAFile != "" {
+		pem, err := os.ReadFile(conf.LogForwardCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %v", conf.LogForwardCAFile)
+		}
+		tlsConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	var sinks []logs.Sink
+	if conf.OTLPLogsEndpoint != "" {
+		sinks = append(sinks, logs.NewOTLPSink(conf.OTLPLogsEndpoint, conf.OTLPLogsHeaders, map[string]string{
+			"service.name":       "ingress-nginx",
+			"k8s.namespace.name": k8s.IngressPodDetails.Namespace,
+			"k8s.pod.name":       k8s.IngressPodDetails.Name,
+		}, tlsConfig))
+	}
+
+	if conf.SyslogForwardAddress != "" {
+		var syslogTLS *tls.Config
+		if conf.SyslogForwardTLS {
+			syslogTLS = tlsConfig
+			if syslogTLS == nil {
+				syslogTLS = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+		}
+		sinks = append(sinks, logs.NewSyslogSink(conf.SyslogForwardAddress, k8s.IngressPodDetails.Name, syslogTLS))
+	}
+
+	return logs.NewForwarder(conf.LogForwardBufferSize, conf.LogForwardFlushInterval, sinks...), nil
+}
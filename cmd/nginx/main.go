@@ -31,6 +31,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	discovery "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -38,6 +39,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"k8s.io/ingress-nginx/internal/ingress/controller"
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	"k8s.io/ingress-nginx/internal/ingress/metric"
 	"k8s.io/ingress-nginx/internal/k8s"
 	"k8s.io/ingress-nginx/internal/net/ssl"
@@ -46,6 +48,7 @@ import (
 	"k8s.io/ingress-nginx/version"
 
 	ingressflags "k8s.io/ingress-nginx/pkg/flags"
+	"k8s.io/ingress-nginx/pkg/logs"
 	"k8s.io/ingress-nginx/pkg/metrics"
 	"k8s.io/ingress-nginx/pkg/util/process"
 )
@@ -69,7 +72,7 @@ func main() {
 		klog.Fatal(err)
 	}
 
-	kubeClient, err := createApiserverClient(conf.APIServerHost, conf.RootCAFile, conf.KubeConfigFile)
+	kubeClient, restConfig, err := createApiserverClient(conf.APIServerHost, conf.RootCAFile, conf.KubeConfigFile)
 	if err != nil {
 		handleFatalInitError(err)
 	}
@@ -115,6 +118,55 @@ func main() {
 	}
 	conf.Client = kubeClient
 
+	if conf.EnableStreamRoutes {
+		conf.StreamRouteClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the StreamRoute client: %v", err)
+		}
+	}
+
+	if conf.EnableIngressClassParams {
+		conf.ClassParamsClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the NginxIngressClassParams client: %v", err)
+		}
+	}
+
+	if conf.EnableWAFPolicies {
+		conf.WAFPolicyClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the WAFPolicy client: %v", err)
+		}
+	}
+
+	if conf.EnableDenylists {
+		conf.DenylistClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the Denylist client: %v", err)
+		}
+	}
+
+	if conf.EnableRedirectMaps {
+		conf.RedirectMapClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the RedirectMap client: %v", err)
+		}
+	}
+
+	if conf.HostOwnershipPolicy == store.HostOwnershipCRD {
+		conf.HostOwnershipClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the HostOwnership client: %v", err)
+		}
+	}
+
+	if conf.EnableGatewayAPI {
+		conf.GatewayClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			klog.Fatalf("Unexpected error creating the Gateway API client: %v", err)
+		}
+	}
+
 	err = k8s.GetIngressPod(kubeClient)
 	if err != nil {
 		klog.Fatalf("Unexpected error obtaining ingress-nginx pod: %v", err)
@@ -130,7 +182,7 @@ func main() {
 
 	mc := metric.NewDummyCollector()
 	if conf.EnableMetrics {
-		mc, err = metric.NewCollector(conf.MetricsPerHost, conf.ReportStatusClasses, reg, conf.IngressClassConfiguration.Controller, *conf.MetricsBuckets, conf.ExcludeSocketMetrics)
+		mc, err = metric.NewCollector(conf.MetricsPerHost, conf.MetricsPerPath, conf.ReportStatusClasses, conf.MetricsMaxPaths, reg, conf.IngressClassConfiguration.Controller, *conf.MetricsBuckets, conf.ExcludeSocketMetrics)
 		if err != nil {
 			klog.Fatalf("Error creating prometheus collector:  %v", err)
 		}
@@ -143,16 +195,38 @@ func main() {
 		go metrics.RegisterProfiler(nginx.ProfilerAddress, nginx.ProfilerPort)
 	}
 
+	conf.MetricsGatherer = reg
+
+	if conf.OTLPMetricsEndpoint != "" {
+		exporter := metrics.NewOTLPExporter(reg, conf.OTLPMetricsEndpoint, conf.OTLPMetricsHeaders, conf.OTLPMetricsInterval, map[string]string{
+			"service.name":       "ingress-nginx",
+			"k8s.namespace.name": k8s.IngressPodDetails.Namespace,
+			"k8s.pod.name":       k8s.IngressPodDetails.Name,
+		})
+		go exporter.Start(context.Background())
+	}
+
 	ngx := controller.NewNGINXController(conf, mc)
 
 	mux := http.NewServeMux()
 	metrics.RegisterHealthz(nginx.HealthPath, mux, ngx)
 	metrics.RegisterMetrics(reg, mux)
 
+	var forwarder *logs.Forwarder
+	if conf.OTLPLogsEndpoint != "" || conf.SyslogForwardAddress != "" {
+		forwarder, err = newLogForwarder(conf)
+		if err != nil {
+			klog.Fatalf("Error configuring the log forwarding: %v", err)
+		}
+		go forwarder.Start(context.Background())
+	}
+
 	_, errExists := os.Stat("/chroot")
 	if errExists == nil {
 		conf.IsChroot = true
-		go logger(conf.InternalLoggerAddress)
+	}
+	if conf.IsChroot || forwarder != nil {
+		go logger(conf.InternalLoggerAddress, conf.IsChroot, forwarder)
 	}
 
 	go metrics.StartHTTPServer(conf.HealthCheckHost, conf.ListenPorts.Health, mux)
@@ -171,10 +245,10 @@ func main() {
 // If neither apiserverHost nor kubeConfig is passed in, we assume the
 // controller runs inside Kubernetes and fallback to the in-cluster config. If
 // the in-cluster config is missing or fails, we fallback to the default config.
-func createApiserverClient(apiserverHost, rootCAFile, kubeConfig string) (*kubernetes.Clientset, error) {
+func createApiserverClient(apiserverHost, rootCAFile, kubeConfig string) (*kubernetes.Clientset, *rest.Config, error) {
 	cfg, err := clientcmd.BuildConfigFromFlags(apiserverHost, kubeConfig)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// TODO: remove after k8s v1.22
@@ -206,7 +280,7 @@ func createApiserverClient(apiserverHost, rootCAFile, kubeConfig string) (*kuber
 
 	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var v *discovery.Info
@@ -237,7 +311,7 @@ func createApiserverClient(apiserverHost, rootCAFile, kubeConfig string) (*kuber
 	})
 	// err is returned in case of timeout in the exponential backoff (ErrWaitTimeout)
 	if err != nil {
-		return nil, lastErr
+		return nil, nil, lastErr
 	}
 
 	// this should not happen, warn the user
@@ -254,7 +328,7 @@ func createApiserverClient(apiserverHost, rootCAFile, kubeConfig string) (*kuber
 		"platform", v.Platform,
 	)
 
-	return client, nil
+	return client, cfg, nil
 }
 
 // Handler for fatal init errors. Prints a verbose error message and exits.
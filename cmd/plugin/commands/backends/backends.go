@@ -18,6 +18,7 @@ package backends
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -46,6 +47,26 @@ func CreateCommand(flags *genericclioptions.ConfigFlags) *cobra.Command {
 			if onlyList && backend != "" {
 				return fmt.Errorf("--list and --backend cannot both be specified")
 			}
+			watchBackends, err := cmd.Flags().GetBool("watch")
+			if err != nil {
+				return err
+			}
+			interval, err := cmd.Flags().GetDuration("interval")
+			if err != nil {
+				return err
+			}
+
+			if watchBackends {
+				if onlyList {
+					return fmt.Errorf("--list and --watch cannot both be specified")
+				}
+				if interval <= 0 {
+					return fmt.Errorf("--interval must be positive")
+				}
+
+				util.PrintError(watch(flags, *pod, *deployment, *selector, *container, backend, interval))
+				return nil
+			}
 
 			util.PrintError(backends(flags, *pod, *deployment, *selector, *container, backend, onlyList))
 			return nil
@@ -59,6 +80,8 @@ func CreateCommand(flags *genericclioptions.ConfigFlags) *cobra.Command {
 
 	cmd.Flags().String("backend", "", "Output only the information for the given backend")
 	cmd.Flags().Bool("list", false, "Output a newline-separated list of backend names")
+	cmd.Flags().Bool("watch", false, "Show the endpoints, ejections and canary weights of the backends as a table refreshed every --interval")
+	cmd.Flags().Duration("interval", 2*time.Second, "Refresh interval of --watch")
 
 	return cmd
 }
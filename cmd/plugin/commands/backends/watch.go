@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"k8s.io/ingress-nginx/cmd/plugin/kubectl"
+	"k8s.io/ingress-nginx/cmd/plugin/request"
+)
+
+// backend is the part of the dynamic configuration of a backend shown by the
+// watch mode
+type backend struct {
+	Name                 string               `json:"name"`
+	Endpoints            []endpoint           `json:"endpoints"`
+	TrafficShapingPolicy trafficShapingPolicy `json:"trafficShapingPolicy"`
+	AlternativeBackends  []string             `json:"alternativeBackends"`
+}
+
+type endpoint struct {
+	Address string `json:"address"`
+	Port    string `json:"port"`
+}
+
+type trafficShapingPolicy struct {
+	Weight        int      `json:"weight"`
+	WeightTotal   int      `json:"weightTotal"`
+	Header        string   `json:"header"`
+	HeaderValue   string   `json:"headerValue"`
+	HeaderPattern string   `json:"headerPattern"`
+	Cookie        string   `json:"cookie"`
+	Query         string   `json:"query"`
+	QueryValue    string   `json:"queryValue"`
+	GeoCountries  []string `json:"geoCountries"`
+	GeoContinents []string `json:"geoContinents"`
+}
+
+// ejection is an endpoint ejected by the outlier detection until the time in
+// seconds since the epoch
+type ejection struct {
+	Backend      string  `json:"backend"`
+	Endpoint     string  `json:"endpoint"`
+	EjectedUntil float64 `json:"ejectedUntil"`
+}
+
+type backendRow struct {
+	Backend  string
+	Endpoint string
+	Status   string
+	Weight   string
+	Canary   string
+}
+
+// watch prints the backends of the pod as a table every interval until the
+// command is interrupted
+func watch(flags *genericclioptions.ConfigFlags, podName, deployment, selector, container, backendName string, interval time.Duration) error {
+	pod, err := request.ChoosePod(flags, podName, deployment, selector)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		rows, err := readBackendRows(flags, &pod, container, backendName)
+
+		// clear the screen and move the cursor to the top left corner
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %v: backends of pod %v at %v\n\n", interval, pod.Name, time.Now().Format(time.RFC3339))
+		if err != nil {
+			// the pod can be restarting, the next refresh shows it again
+			fmt.Println(err)
+		} else {
+			printBackendRows(os.Stdout, rows)
+		}
+
+		<-ticker.C
+	}
+}
+
+func readBackendRows(flags *genericclioptions.ConfigFlags, pod *apiv1.Pod, container, backendName string) ([]backendRow, error) {
+	out, err := kubectl.PodExecString(flags, pod, container, []string{"/dbg", "backends", "all"})
+	if err != nil {
+		return nil, err
+	}
+
+	var backends []backend
+	if err := json.Unmarshal([]byte(out), &backends); err != nil {
+		return nil, fmt.Errorf("reading the backends: %v", strings.TrimSpace(out))
+	}
+
+	// the controllers without outlier detection do not know the command, their
+	// endpoints are shown with an unknown status
+	var ejections []ejection
+	out, err = kubectl.PodExecString(flags, pod, container, []string{"/dbg", "backends", "ejections"})
+	if err != nil || json.Unmarshal([]byte(out), &ejections) != nil {
+		ejections = nil
+	} else if ejections == nil {
+		ejections = []ejection{}
+	}
+
+	return getBackendRows(backends, ejections, backendName, time.Now()), nil
+}
+
+// getBackendRows returns a row for every endpoint of the backends, or of the
+// backend with the given name. A nil list of ejections means their status is
+// unknown.
+func getBackendRows(backends []backend, ejections []ejection, backendName string, now time.Time) []backendRow {
+	byName := make(map[string]*backend, len(backends))
+	canaryOf := make(map[string]string)
+	for i := range backends {
+		byName[backends[i].Name] = &backends[i]
+		for _, alternative := range backends[i].AlternativeBackends {
+			canaryOf[alternative] = backends[i].Name
+		}
+	}
+
+	ejectedUntil := make(map[string]float64, len(ejections))
+	for _, e := range ejections {
+		ejectedUntil[e.Backend+"/"+e.Endpoint] = e.EjectedUntil
+	}
+
+	rows := make([]backendRow, 0)
+	for i := range backends {
+		b := &backends[i]
+		if backendName != "" && b.Name != backendName {
+			continue
+		}
+
+		weight, canary := "100%", ""
+		if primary, ok := canaryOf[b.Name]; ok {
+			weight = formatPercent(canaryPercent(&b.TrafficShapingPolicy))
+			canary = canaryDescription(primary, &b.TrafficShapingPolicy)
+		} else if len(b.AlternativeBackends) > 0 {
+			remaining := 100.0
+			for _, alternative := range b.AlternativeBackends {
+				if alternativeBackend, ok := byName[alternative]; ok {
+					remaining -= canaryPercent(&alternativeBackend.TrafficShapingPolicy)
+				}
+			}
+			weight = formatPercent(math.Max(remaining, 0))
+			canary = "canaries " + strings.Join(b.AlternativeBackends, ", ")
+		}
+
+		if len(b.Endpoints) == 0 {
+			rows = append(rows, backendRow{Backend: b.Name, Endpoint: "<none>", Status: "-", Weight: weight, Canary: canary})
+			continue
+		}
+
+		for _, e := range b.Endpoints {
+			address := e.Address + ":" + e.Port
+			rows = append(rows, backendRow{
+				Backend:  b.Name,
+				Endpoint: address,
+				Status:   endpointStatus(ejections, ejectedUntil[b.Name+"/"+address], now),
+				Weight:   weight,
+				Canary:   canary,
+			})
+		}
+	}
+
+	return rows
+}
+
+func printBackendRows(w io.Writer, rows []backendRow) {
+	printer := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	defer printer.Flush()
+
+	fmt.Fprintln(printer, "BACKEND\tENDPOINT\tSTATUS\tWEIGHT\tCANARY")
+	for i := range rows {
+		row := &rows[i]
+		fmt.Fprintf(printer, "%v\t%v\t%v\t%v\t%v\n", row.Backend, row.Endpoint, row.Status, row.Weight, row.Canary)
+	}
+}
+
+func endpointStatus(ejections []ejection, ejectedUntil float64, now time.Time) string {
+	if ejections == nil {
+		return "unknown"
+	}
+
+	remaining := ejectedUntil - float64(now.UnixNano())/float64(time.Second)
+	if remaining <= 0 {
+		return "ok"
+	}
+
+	return fmt.Sprintf("ejected (%vs)", math.Ceil(remaining))
+}
+
+// canaryPercent returns the percentage of the requests routed to the canary by
+// weight, the total weight is 100 unless a greater weightTotal is set
+func canaryPercent(policy *trafficShapingPolicy) float64 {
+	weightTotal := 100
+	if policy.WeightTotal > 100 {
+		weightTotal = policy.WeightTotal
+	}
+
+	return float64(policy.Weight) * 100 / float64(weightTotal)
+}
+
+func canaryDescription(primary string, policy *trafficShapingPolicy) string {
+	rules := make([]string, 0)
+	switch {
+	case policy.Header != "" && policy.HeaderValue != "":
+		rules = append(rules, fmt.Sprintf("header %v=%v", policy.Header, policy.HeaderValue))
+	case policy.Header != "" && policy.HeaderPattern != "":
+		rules = append(rules, fmt.Sprintf("header %v~%v", policy.Header, policy.HeaderPattern))
+	case policy.Header != "":
+		rules = append(rules, "header "+policy.Header)
+	}
+	if policy.Cookie != "" {
+		rules = append(rules, "cookie "+policy.Cookie)
+	}
+	if policy.Query != "" && policy.QueryValue != "" {
+		rules = append(rules, fmt.Sprintf("query %v=%v", policy.Query, policy.QueryValue))
+	} else if policy.Query != "" {
+		rules = append(rules, "query "+policy.Query)
+	}
+	if len(policy.GeoCountries) > 0 {
+		rules = append(rules, "countries "+strings.Join(policy.GeoCountries, ","))
+	}
+	if len(policy.GeoContinents) > 0 {
+		rules = append(rules, "continents "+strings.Join(policy.GeoContinents, ","))
+	}
+
+	if len(rules) == 0 {
+		return "canary of " + primary
+	}
+
+	return fmt.Sprintf("canary of %v (%v)", primary, strings.Join(rules, ", "))
+}
+
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetBackendRows(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	backends := []backend{
+		{
+			Name:                "default-web-80",
+			Endpoints:           []endpoint{{Address: "10.0.0.1", Port: "8080"}, {Address: "10.0.0.2", Port: "8080"}},
+			AlternativeBackends: []string{"default-web-canary-80"},
+		},
+		{
+			Name:      "default-web-canary-80",
+			Endpoints: []endpoint{{Address: "10.0.0.3", Port: "8080"}},
+			TrafficShapingPolicy: trafficShapingPolicy{
+				Weight:       25,
+				WeightTotal:  200,
+				Header:       "X-Canary",
+				GeoCountries: []string{"DE", "FR"},
+			},
+		},
+		{
+			Name: "upstream-default-backend",
+		},
+	}
+	ejections := []ejection{
+		{Backend: "default-web-80", Endpoint: "10.0.0.2:8080", EjectedUntil: 1700000012.5},
+	}
+
+	testcases := map[string]struct {
+		ejections   []ejection
+		backendName string
+		want        []backendRow
+	}{
+		"all the backends": {
+			ejections: ejections,
+			want: []backendRow{
+				{"default-web-80", "10.0.0.1:8080", "ok", "87.5%", "canaries default-web-canary-80"},
+				{"default-web-80", "10.0.0.2:8080", "ejected (13s)", "87.5%", "canaries default-web-canary-80"},
+				{"default-web-canary-80", "10.0.0.3:8080", "ok", "12.5%", "canary of default-web-80 (header X-Canary, countries DE,FR)"},
+				{"upstream-default-backend", "<none>", "-", "100%", ""},
+			},
+		},
+		"one backend with an unknown status": {
+			backendName: "default-web-canary-80",
+			want: []backendRow{
+				{"default-web-canary-80", "10.0.0.3:8080", "unknown", "12.5%", "canary of default-web-80 (header X-Canary, countries DE,FR)"},
+			},
+		},
+	}
+
+	for title, tc := range testcases {
+		t.Run(title, func(t *testing.T) {
+			rows := getBackendRows(backends, tc.ejections, tc.backendName, now)
+			if !reflect.DeepEqual(rows, tc.want) {
+				t.Errorf("expected %+v, but got %+v", tc.want, rows)
+			}
+		})
+	}
+}
@@ -41,12 +41,26 @@ func CreateCommand(flags *genericclioptions.ConfigFlags) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			diffPods, err := cmd.Flags().GetBool("diff")
+			if err != nil {
+				return err
+			}
+
+			if diffPods {
+				if *pod != "" {
+					return fmt.Errorf("--diff compares all the pods and cannot be used with --pod")
+				}
+
+				util.PrintError(diff(flags, host, *pod, *deployment, *selector, *container))
+				return nil
+			}
 
 			util.PrintError(conf(flags, host, *pod, *deployment, *selector, *container))
 			return nil
 		},
 	}
 	cmd.Flags().String("host", "", "Print just the server block with this hostname")
+	cmd.Flags().Bool("diff", false, "Compare the nginx.conf of all the pods and print the differences")
 	pod = util.AddPodFlag(cmd)
 	deployment = util.AddDeploymentFlag(cmd)
 	selector = util.AddSelectorFlag(cmd)
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"k8s.io/ingress-nginx/cmd/plugin/kubectl"
+	"k8s.io/ingress-nginx/cmd/plugin/request"
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// checksumRegex matches the checksum of the configuration the controller
+// writes at the top of nginx.conf
+var checksumRegex = regexp.MustCompile(`(?m)^# Configuration checksum: (\S+)$`)
+
+// podConf is the nginx.conf of a pod
+type podConf struct {
+	Pod  string
+	Conf string
+}
+
+// confGroup is the pods with the same nginx.conf
+type confGroup struct {
+	Pods     []string
+	Checksum string
+	Conf     string
+}
+
+// diff compares the nginx.conf of every pod of the deployment, the replicas
+// that failed a reload keep the configuration of a previous generation
+func diff(flags *genericclioptions.ConfigFlags, host, podName, deployment, selector, container string) error {
+	pods, err := request.ChoosePods(flags, podName, deployment, selector)
+	if err != nil {
+		return err
+	}
+
+	confs := make([]podConf, 0, len(pods))
+	failed := 0
+	for i := range pods {
+		nginxConf, err := kubectl.PodExecString(flags, &pods[i], container, []string{"/dbg", "conf"})
+		if err != nil {
+			failed++
+			fmt.Printf("%v: %v\n", pods[i].Name, err)
+			continue
+		}
+
+		confs = append(confs, podConf{Pod: pods[i].Name, Conf: nginxConf})
+	}
+
+	groups := groupConfs(confs, host)
+	if err := printConfDiff(os.Stdout, groups); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("the nginx.conf of %v of the %v pods could not be read", failed, len(pods))
+	}
+	if len(groups) > 1 {
+		return fmt.Errorf("the pods serve %v different nginx.conf", len(groups))
+	}
+
+	return nil
+}
+
+// groupConfs groups the pods by nginx.conf, or by server block of the host
+// when it is not empty. The groups with the most pods come first, the first
+// one is the configuration the other ones are compared to.
+func groupConfs(confs []podConf, host string) []confGroup {
+	groups := make([]confGroup, 0)
+	for _, c := range confs {
+		conf := c.Conf
+		if host != "" {
+			block, err := nginx.GetServerBlock(c.Conf, host)
+			if err != nil {
+				block = fmt.Sprintf("# no server block for host %v\n", host)
+			}
+			conf = block
+		}
+
+		found := false
+		for i := range groups {
+			if groups[i].Conf == conf {
+				groups[i].Pods = append(groups[i].Pods, c.Pod)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, confGroup{Pods: []string{c.Pod}, Checksum: configChecksum(c.Conf), Conf: conf})
+		}
+	}
+
+	// stable insertion sort, the pods of the same size groups keep their order
+	for i := 1; i < len(groups); i++ {
+		for j := i; j > 0 && len(groups[j].Pods) > len(groups[j-1].Pods); j-- {
+			groups[j], groups[j-1] = groups[j-1], groups[j]
+		}
+	}
+
+	return groups
+}
+
+func configChecksum(conf string) string {
+	match := checksumRegex.FindStringSubmatch(conf)
+	if match == nil {
+		return "unknown"
+	}
+
+	return match[1]
+}
+
+// printConfDiff prints the checksum of the configuration of every pod, and
+// the differences between the first group of pods and the other ones
+func printConfDiff(w io.Writer, groups []confGroup) error {
+	printer := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	fmt.Fprintln(printer, "POD\tCHECKSUM\tCONF")
+	for i := range groups {
+		for _, pod := range groups[i].Pods {
+			fmt.Fprintf(printer, "%v\t%v\t%v\n", pod, groups[i].Checksum, i+1)
+		}
+	}
+	if err := printer.Flush(); err != nil {
+		return err
+	}
+
+	if len(groups) == 1 {
+		fmt.Fprintf(w, "\nThe %v pods serve the same nginx.conf\n", len(groups[0].Pods))
+		return nil
+	}
+
+	for i := 1; i < len(groups); i++ {
+		unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(groups[0].Conf),
+			B:        difflib.SplitLines(groups[i].Conf),
+			FromFile: fmt.Sprintf("conf 1 (%v)", strings.Join(groups[0].Pods, ", ")),
+			ToFile:   fmt.Sprintf("conf %v (%v)", i+1, strings.Join(groups[i].Pods, ", ")),
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "\n%v", unified)
+	}
+
+	return nil
+}
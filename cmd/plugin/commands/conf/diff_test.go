@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	currentConf = `# Configuration checksum: 1111
+
+http {
+	server {
+		server_name shop.example.com ;
+		location / {
+			set $proxy_upstream_name "default-shop-80";
+		}
+	}
+}
+`
+	staleConf = `# Configuration checksum: 2222
+
+http {
+	server {
+		server_name shop.example.com ;
+		location / {
+			set $proxy_upstream_name "default-shop-8080";
+		}
+	}
+}
+`
+)
+
+func TestGroupConfs(t *testing.T) {
+	confs := []podConf{
+		{Pod: "controller-a", Conf: staleConf},
+		{Pod: "controller-b", Conf: currentConf},
+		{Pod: "controller-c", Conf: currentConf},
+	}
+
+	groups := groupConfs(confs, "")
+	expected := []confGroup{
+		{Pods: []string{"controller-b", "controller-c"}, Checksum: "1111", Conf: currentConf},
+		{Pods: []string{"controller-a"}, Checksum: "2222", Conf: staleConf},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, groups)
+	}
+
+	groups = groupConfs(confs, "other.example.com")
+	if len(groups) != 1 || len(groups[0].Pods) != 3 {
+		t.Errorf("expected the pods without server block for the host to be in one group, but got %+v", groups)
+	}
+}
+
+func TestPrintConfDiff(t *testing.T) {
+	var out bytes.Buffer
+	err := printConfDiff(&out, groupConfs([]podConf{
+		{Pod: "controller-a", Conf: currentConf},
+		{Pod: "controller-b", Conf: currentConf},
+	}, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "The 2 pods serve the same nginx.conf") {
+		t.Errorf("expected the pods to serve the same nginx.conf, but got\n%v", out.String())
+	}
+
+	out.Reset()
+	err = printConfDiff(&out, groupConfs([]podConf{
+		{Pod: "controller-a", Conf: currentConf},
+		{Pod: "controller-b", Conf: staleConf},
+		{Pod: "controller-c", Conf: currentConf},
+	}, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"controller-b   2222       2",
+		"--- conf 1 (controller-a, controller-c)",
+		"+++ conf 2 (controller-b)",
+		"-# Configuration checksum: 1111",
+		"+\t\t\tset $proxy_upstream_name \"default-shop-8080\";",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the output\n%v", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/ingress-nginx/cmd/plugin/util"
+	"k8s.io/ingress-nginx/internal/admission/policy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// CreateCommand creates and returns this cobra subcommand
+func CreateCommand() *cobra.Command {
+	var name, prefix, ingressClass *string
+	cmd := &cobra.Command{
+		Use:   "annotation-policy",
+		Short: "Output a ValidatingAdmissionPolicy checking the values of the annotations",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			util.PrintError(annotationPolicy(os.Stdout, *name, *prefix, *ingressClass))
+			return nil
+		},
+	}
+	name = cmd.Flags().String("name", "ingress-nginx-annotations", "Name of the ValidatingAdmissionPolicy and of its binding")
+	prefix = cmd.Flags().String("annotations-prefix", parser.DefaultAnnotationsPrefix, "Prefix of the annotations, the --annotations-prefix of the controller")
+	ingressClass = cmd.Flags().String("ingress-class", ingressclass.DefaultAnnotationValue, "Name of the IngressClass of the controller, only its Ingresses are checked")
+
+	return cmd
+}
+
+func annotationPolicy(w io.Writer, name, prefix, ingressClass string) error {
+	fields := annotations.NewAnnotationExtractor(&resolver.Mock{}).AnnotationFields()
+	p, binding := policy.Generate(name, prefix, ingressClass, fields)
+
+	for i, obj := range []interface{}{p, binding} {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		fmt.Fprint(w, string(out))
+	}
+
+	return nil
+}
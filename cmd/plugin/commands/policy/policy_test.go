@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestAnnotationPolicy(t *testing.T) {
+	testCases := []struct {
+		title        string
+		name         string
+		prefix       string
+		ingressClass string
+	}{
+		{"default flags", "ingress-nginx-annotations", "nginx.ingress.kubernetes.io", "nginx"},
+		{"custom prefix and class", "internal-annotations", "internal.example.com", "internal"},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		if err := annotationPolicy(&out, tc.name, tc.prefix, tc.ingressClass); err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.title, err)
+		}
+
+		docs := strings.Split(out.String(), "---\n")
+		if len(docs) != 2 {
+			t.Fatalf("%v: expected a policy and a binding, got %v documents", tc.title, len(docs))
+		}
+
+		var p admissionregistration.ValidatingAdmissionPolicy
+		if err := yaml.UnmarshalStrict([]byte(docs[0]), &p); err != nil {
+			t.Fatalf("%v: unexpected error reading the policy: %v", tc.title, err)
+		}
+		if p.Kind != "ValidatingAdmissionPolicy" || p.Name != tc.name {
+			t.Errorf("%v: expected the ValidatingAdmissionPolicy %v, got %v %v", tc.title, tc.name, p.Kind, p.Name)
+		}
+		if len(p.Spec.Validations) == 0 {
+			t.Errorf("%v: expected validations of the annotations", tc.title)
+		}
+		for _, validation := range p.Spec.Validations {
+			if !strings.Contains(validation.Expression, tc.prefix+"/") {
+				t.Errorf("%v: expected the validation %q to check an annotation with the prefix %v", tc.title, validation.Expression, tc.prefix)
+			}
+		}
+
+		var binding admissionregistration.ValidatingAdmissionPolicyBinding
+		if err := yaml.UnmarshalStrict([]byte(docs[1]), &binding); err != nil {
+			t.Fatalf("%v: unexpected error reading the binding: %v", tc.title, err)
+		}
+		if binding.Name != tc.name || binding.Spec.PolicyName != tc.name {
+			t.Errorf("%v: expected the binding %v of the policy %v, got %v of %v", tc.title, tc.name, tc.name, binding.Name, binding.Spec.PolicyName)
+		}
+		if binding.Spec.ParamRef == nil || binding.Spec.ParamRef.Name != tc.ingressClass {
+			t.Errorf("%v: expected the binding to use the IngressClass %v, got %+v", tc.title, tc.ingressClass, binding.Spec.ParamRef)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package purge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"k8s.io/ingress-nginx/cmd/plugin/kubectl"
+	"k8s.io/ingress-nginx/cmd/plugin/request"
+	"k8s.io/ingress-nginx/cmd/plugin/util"
+)
+
+// CreateCommand creates and returns this cobra subcommand
+func CreateCommand(flags *genericclioptions.ConfigFlags) *cobra.Command {
+	var pod, deployment, selector, container *string
+	var host, path, zone string
+	var prefix bool
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Purge the cached responses of a host, a path or the paths with a prefix in all the ingress-nginx pods",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if host == "" {
+				return fmt.Errorf("--host is required")
+			}
+			if prefix && path == "" {
+				return fmt.Errorf("--prefix requires --path")
+			}
+
+			util.PrintError(purge(flags, *pod, *deployment, *selector, *container, purgeArgs(host, path, zone, prefix)))
+			return nil
+		},
+	}
+
+	pod = util.AddPodFlag(cmd)
+	deployment = util.AddDeploymentFlag(cmd)
+	selector = util.AddSelectorFlag(cmd)
+	container = util.AddContainerFlag(cmd)
+
+	cmd.Flags().StringVar(&host, "host", "", "Host of the responses to purge")
+	cmd.Flags().StringVar(&path, "path", "", "Path of the responses to purge, all the paths of the host if empty")
+	cmd.Flags().BoolVar(&prefix, "prefix", false, "Purge the responses of the paths starting with --path")
+	cmd.Flags().StringVar(&zone, "zone", "", "Cache zone of the responses to purge, all the zones if empty")
+
+	return cmd
+}
+
+func purgeArgs(host, path, zone string, prefix bool) []string {
+	args := []string{"/dbg", "purge", "--host", host}
+	if path != "" {
+		args = append(args, "--path", path)
+	}
+	if prefix {
+		args = append(args, "--prefix")
+	}
+	if zone != "" {
+		args = append(args, "--zone", zone)
+	}
+
+	return args
+}
+
+// purge purges the responses in the cache of every pod, every replica of the
+// controller caches the responses it proxies
+func purge(flags *genericclioptions.ConfigFlags, podName, deployment, selector, container string, command []string) error {
+	pods, err := request.ChoosePods(flags, podName, deployment, selector)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range pods {
+		out, err := kubectl.PodExecString(flags, &pods[i], container, command)
+		if err != nil {
+			failed++
+			fmt.Printf("%v: %v\n", pods[i].Name, err)
+			continue
+		}
+
+		fmt.Printf("%v: %v\n", pods[i].Name, strings.TrimSpace(out))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("the purge failed in %v of the %v pods", failed, len(pods))
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package purge
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestPurgeArgs(t *testing.T) {
+	testCases := []struct {
+		title    string
+		host     string
+		path     string
+		zone     string
+		prefix   bool
+		expected []string
+	}{
+		{"host", "app.example.com", "", "", false, []string{"/dbg", "purge", "--host", "app.example.com"}},
+		{"path", "app.example.com", "/static/app.js", "", false, []string{"/dbg", "purge", "--host", "app.example.com", "--path", "/static/app.js"}},
+		{"path prefix", "app.example.com", "/static/", "", true, []string{"/dbg", "purge", "--host", "app.example.com", "--path", "/static/", "--prefix"}},
+		{"zone", "app.example.com", "", "static", false, []string{"/dbg", "purge", "--host", "app.example.com", "--zone", "static"}},
+	}
+
+	for _, tc := range testCases {
+		if args := purgeArgs(tc.host, tc.path, tc.zone, tc.prefix); !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("%v: expected %v, but got %v", tc.title, tc.expected, args)
+		}
+	}
+}
+
+func TestPurgeFlags(t *testing.T) {
+	testCases := []struct {
+		title    string
+		args     []string
+		expected string
+	}{
+		{"missing host", []string{"--path", "/static/"}, "--host is required"},
+		{"prefix without path", []string{"--host", "app.example.com", "--prefix"}, "--prefix requires --path"},
+	}
+
+	for _, tc := range testCases {
+		cmd := CreateCommand(genericclioptions.NewConfigFlags(false))
+		cmd.SetArgs(tc.args)
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+
+		err := cmd.Execute()
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("%v: expected the error %q, but got %v", tc.title, tc.expected, err)
+		}
+	}
+}
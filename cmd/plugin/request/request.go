@@ -47,6 +47,35 @@ func ChoosePod(flags *genericclioptions.ConfigFlags, podName, deployment, select
 	return GetDeploymentPod(flags, deployment)
 }
 
+// ChoosePods finds the pod with the given name, or all the pods of the
+// deployment or of the label selector
+func ChoosePods(flags *genericclioptions.ConfigFlags, podName, deployment, selector string) ([]apiv1.Pod, error) {
+	if podName != "" {
+		pod, err := GetNamedPod(flags, podName)
+		if err != nil {
+			return nil, err
+		}
+		return []apiv1.Pod{pod}, nil
+	}
+
+	var pods []apiv1.Pod
+	var err error
+	if selector != "" {
+		pods, err = getLabeledPods(flags, selector)
+	} else {
+		pods, err = getDeploymentPods(flags, deployment)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no ingress-nginx pods found in namespace %v", util.GetNamespace(flags))
+	}
+
+	return pods, nil
+}
+
 // GetNamedPod finds a pod with the given name
 func GetNamedPod(flags *genericclioptions.ConfigFlags, name string) (apiv1.Pod, error) {
 	allPods, err := getPods(flags)
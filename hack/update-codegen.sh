@@ -39,6 +39,6 @@ chmod +x ${CODEGEN_PKG}/kube_codegen.sh
 #  --output-base "$(dirname ${BASH_SOURCE})/../../.."
 ${CODEGEN_PKG}/kube_codegen.sh "deepcopy" \
   k8s.io/ingress-nginx/internal k8s.io/ingress-nginx/pkg/apis \
-  .:ingress \
+  ".:ingress nginxingress:v1alpha1" \
   --output-base "$(dirname ${BASH_SOURCE})/../../.." \
   --go-header-file ${SCRIPT_ROOT}/hack/boilerplate/boilerplate.generated.go.txt
@@ -59,6 +59,16 @@ export LUA_CJSON_VERSION=2.1.0.13
 # Check for recent changes: https://github.com/leev/ngx_http_geoip2_module/compare/a607a41a8115fecfc05b5c283c81532a3d605425...master
 export GEOIP2_VERSION=a607a41a8115fecfc05b5c283c81532a3d605425
 
+# Check for recent changes: https://github.com/nginx/njs/compare/0.8.4...master
+export NJS_VERSION=0.8.4
+# sha256sum of the archive of NJS_VERSION, set with the version
+export NJS_SHA256=""
+
+# Check for recent changes: https://github.com/tokers/zstd-nginx-module/compare/0.1.1...master
+export ZSTD_NGX_VERSION=0.1.1
+# sha256sum of the archive of ZSTD_NGX_VERSION, set with the version
+export ZSTD_NGX_SHA256=""
+
 # Check for recent changes: https://github.com/openresty/luajit2/compare/v2.1-20240314...v2.1-agentzh
 export LUAJIT_VERSION=v2.1-20240314
 
@@ -134,6 +144,29 @@ get_src()
   rm -rf "$f"
 }
 
+# get_verified_src is get_src failing when the checksum of the archive does
+# not match, for the sources pinned by their checksum
+get_verified_src()
+{
+  hash="$1"
+  url="$2"
+  dest="$3"
+  f=$(basename "$url")
+
+  if [ -z "$hash" ]; then
+    echo "Missing the sha256sum of $url, pin it in build.sh"
+    exit 10
+  fi
+
+  echo "Downloading $url"
+
+  curl -sSL "$url" -o "$f"
+  echo "$hash  $f" | sha256sum -c - || exit 10
+  mkdir ${BUILD_PATH}/${dest}
+  tar xvzf "$f" -C ${BUILD_PATH}/${dest} --strip-components=1
+  rm -rf "$f"
+}
+
 # install required packages to build
 # Dependencies from "ninja" and below are OTEL dependencies
 apk add \
@@ -168,6 +201,7 @@ apk add \
   git g++ pkgconf flex bison doxygen yajl-dev lmdb-dev libtool autoconf libxml2 libxml2-dev \
   python3 \
   libmaxminddb-dev \
+  zstd-dev \
   bc \
   unzip \
   dos2unix \
@@ -334,6 +368,16 @@ git reset --hard 63ca02abdcf79c9e788d2eedcc388d2335902e52
 git submodule init
 git submodule update
 
+# Get the njs module, loaded for the Ingresses importing njs scripts
+cd "$BUILD_PATH"
+get_verified_src "$NJS_SHA256" \
+        "https://github.com/nginx/njs/archive/$NJS_VERSION.tar.gz" "njs"
+
+# Get the Zstandard module, loaded when zstd compression is enabled
+cd "$BUILD_PATH"
+get_verified_src "$ZSTD_NGX_SHA256" \
+        "https://github.com/tokers/zstd-nginx-module/archive/$ZSTD_NGX_VERSION.tar.gz" "zstd-nginx-module"
+
 cd "$BUILD_PATH"
 git clone --depth=1 https://github.com/ssdeep-project/ssdeep
 cd ssdeep/
@@ -490,6 +534,8 @@ WITH_MODULES=" \
   --add-dynamic-module=$BUILD_PATH/nginx-http-auth-digest \
   --add-dynamic-module=$BUILD_PATH/ModSecurity-nginx \
   --add-dynamic-module=$BUILD_PATH/ngx_http_geoip2_module \
+  --add-dynamic-module=$BUILD_PATH/njs/nginx \
+  --add-dynamic-module=$BUILD_PATH/zstd-nginx-module \
   --add-dynamic-module=$BUILD_PATH/ngx_brotli"
 
 ./configure \
@@ -27,13 +27,16 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 )
 
 // Checker must return an error if the ingress provided as argument
 // contains invalid instructions
 type Checker interface {
-	CheckIngress(ing *networking.Ingress) error
+	CheckIngress(ing *networking.Ingress) ([]string, error)
 	CheckWarning(ing *networking.Ingress) ([]string, error)
+	CheckStreamRoute(route *v1alpha1.StreamRoute) error
 }
 
 // IngressAdmission implements the AdmissionController interface
@@ -48,6 +51,12 @@ var ingressResource = metav1.GroupVersionKind{
 	Kind:    "Ingress",
 }
 
+var streamRouteResource = metav1.GroupVersionKind{
+	Group:   v1alpha1.GroupName,
+	Version: v1alpha1.SchemeGroupVersion.Version,
+	Kind:    "StreamRoute",
+}
+
 // HandleAdmission populates the admission Response
 // with Allowed=false if the Object is an ingress that would prevent nginx to reload the configuration
 // with Allowed=true otherwise
@@ -57,6 +66,10 @@ func (ia *IngressAdmission) HandleAdmission(obj runtime.Object) (runtime.Object,
 		return nil, fmt.Errorf("request is not of type AdmissionReview v1 or v1beta1")
 	}
 
+	if apiequality.Semantic.DeepEqual(review.Request.Kind, streamRouteResource) {
+		return ia.handleStreamRouteAdmission(review)
+	}
+
 	if !apiequality.Semantic.DeepEqual(review.Request.Kind, ingressResource) {
 		return nil, fmt.Errorf("rejecting admission review because the request does not contain an Ingress resource but %s with name %s in namespace %s",
 			review.Request.Kind.String(), review.Request.Name, review.Request.Namespace)
@@ -92,7 +105,8 @@ func (ia *IngressAdmission) HandleAdmission(obj runtime.Object) (runtime.Object,
 		status.Warnings = warning
 	}
 
-	if err := ia.Checker.CheckIngress(&ingress); err != nil {
+	diffWarnings, err := ia.Checker.CheckIngress(&ingress)
+	if err != nil {
 		klog.ErrorS(err, "invalid ingress configuration", "ingress", fmt.Sprintf("%v/%v", review.Request.Namespace, review.Request.Name))
 		status.Allowed = false
 		status.Result = &metav1.Status{
@@ -106,6 +120,54 @@ func (ia *IngressAdmission) HandleAdmission(obj runtime.Object) (runtime.Object,
 
 	klog.InfoS("successfully validated configuration, accepting", "ingress", fmt.Sprintf("%v/%v", review.Request.Namespace, review.Request.Name))
 	status.Allowed = true
+	status.Warnings = append(status.Warnings, diffWarnings...)
+	review.Response = status
+
+	return review, nil
+}
+
+// handleStreamRouteAdmission populates the admission Response with
+// Allowed=false if the StreamRoute is invalid or uses a port already in use
+func (ia *IngressAdmission) handleStreamRouteAdmission(review *admissionv1.AdmissionReview) (runtime.Object, error) {
+	status := &admissionv1.AdmissionResponse{}
+	status.UID = review.Request.UID
+
+	route := v1alpha1.StreamRoute{}
+
+	codec := json.NewSerializerWithOptions(json.DefaultMetaFactory, scheme, scheme, json.SerializerOptions{
+		Pretty: true,
+	})
+	_, _, err := codec.Decode(review.Request.Object.Raw, nil, &route)
+	if err != nil {
+		klog.ErrorS(err, "failed to decode stream route")
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+		}
+
+		review.Response = status
+		return review, nil
+	}
+
+	if route.Namespace == "" {
+		route.Namespace = review.Request.Namespace
+	}
+
+	if err := ia.Checker.CheckStreamRoute(&route); err != nil {
+		klog.ErrorS(err, "invalid stream route", "streamroute", fmt.Sprintf("%v/%v", review.Request.Namespace, review.Request.Name))
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+		}
+
+		review.Response = status
+		return review, nil
+	}
+
+	klog.InfoS("successfully validated stream route, accepting", "streamroute", fmt.Sprintf("%v/%v", review.Request.Namespace, review.Request.Name))
+	status.Allowed = true
 	review.Response = status
 
 	return review, nil
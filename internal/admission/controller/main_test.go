@@ -25,17 +25,22 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/json"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 )
 
-const testIngressName = "testIngressName"
+const (
+	testIngressName     = "testIngressName"
+	testStreamRouteName = "testStreamRouteName"
+)
 
 type failTestChecker struct {
 	t *testing.T
 }
 
-func (ftc failTestChecker) CheckIngress(_ *networking.Ingress) error {
+func (ftc failTestChecker) CheckIngress(_ *networking.Ingress) ([]string, error) {
 	ftc.t.Error("checker should not be called")
-	return nil
+	return nil, nil
 }
 
 func (ftc failTestChecker) CheckWarning(_ *networking.Ingress) ([]string, error) {
@@ -43,16 +48,22 @@ func (ftc failTestChecker) CheckWarning(_ *networking.Ingress) ([]string, error)
 	return nil, nil
 }
 
+func (ftc failTestChecker) CheckStreamRoute(_ *v1alpha1.StreamRoute) error {
+	ftc.t.Error("checker should not be called")
+	return nil
+}
+
 type testChecker struct {
-	t   *testing.T
-	err error
+	t        *testing.T
+	err      error
+	warnings []string
 }
 
-func (tc testChecker) CheckIngress(ing *networking.Ingress) error {
+func (tc testChecker) CheckIngress(ing *networking.Ingress) ([]string, error) {
 	if ing.ObjectMeta.Name != testIngressName {
 		tc.t.Errorf("CheckIngress should be called with %v ingress, but got %v", testIngressName, ing.ObjectMeta.Name)
 	}
-	return tc.err
+	return tc.warnings, tc.err
 }
 
 func (tc testChecker) CheckWarning(ing *networking.Ingress) ([]string, error) {
@@ -62,6 +73,16 @@ func (tc testChecker) CheckWarning(ing *networking.Ingress) ([]string, error) {
 	return nil, tc.err
 }
 
+func (tc testChecker) CheckStreamRoute(route *v1alpha1.StreamRoute) error {
+	if route.ObjectMeta.Name != testStreamRouteName {
+		tc.t.Errorf("CheckStreamRoute should be called with %v stream route, but got %v", testStreamRouteName, route.ObjectMeta.Name)
+	}
+	if route.ObjectMeta.Namespace != "default" {
+		tc.t.Errorf("CheckStreamRoute should be called with the namespace of the request, but got %q", route.ObjectMeta.Namespace)
+	}
+	return tc.err
+}
+
 func TestHandleAdmission(t *testing.T) {
 	adm := &IngressAdmission{
 		Checker: failTestChecker{t: t},
@@ -121,6 +142,69 @@ func TestHandleAdmission(t *testing.T) {
 		t.Fatalf("when the checker returns an error, the request should not be allowed")
 	}
 
+	adm.Checker = testChecker{
+		t:        t,
+		err:      nil,
+		warnings: []string{`configuration change: new server block "foo.bar"`},
+	}
+
+	if _, err := adm.HandleAdmission(review); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !review.Response.Allowed {
+		t.Fatalf("when the checker returns no error, the request should be allowed")
+	}
+	if len(review.Response.Warnings) != 1 {
+		t.Errorf("expected the warnings of the checker in the response, got %v", review.Response.Warnings)
+	}
+}
+
+func TestHandleStreamRouteAdmission(t *testing.T) {
+	adm := &IngressAdmission{
+		Checker: failTestChecker{t: t},
+	}
+
+	result, err := adm.HandleAdmission(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			Kind:      v1.GroupVersionKind{Group: v1alpha1.GroupName, Version: "v1alpha1", Kind: "StreamRoute"},
+			Namespace: "default",
+			Object: runtime.RawExtension{
+				Raw: []byte{0xff},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	review, isV1 := (result).(*admissionv1.AdmissionReview)
+	if !isV1 {
+		t.Fatalf("expected AdmissionReview V1 object but %T returned", result)
+	}
+
+	if review.Response.Allowed {
+		t.Fatalf("when the request object is not decodable, the request should not be allowed")
+	}
+
+	raw, err := json.Marshal(v1alpha1.StreamRoute{ObjectMeta: v1.ObjectMeta{Name: testStreamRouteName}})
+	if err != nil {
+		t.Fatalf("failed to prepare test stream route data: %v", err.Error())
+	}
+
+	review.Request.Object.Raw = raw
+
+	adm.Checker = testChecker{
+		t:   t,
+		err: fmt.Errorf("this is a test error"),
+	}
+
+	if _, err := adm.HandleAdmission(review); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if review.Response.Allowed {
+		t.Fatalf("when the checker returns an error, the request should not be allowed")
+	}
+
 	adm.Checker = testChecker{
 		t:   t,
 		err: nil,
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy generates the ValidatingAdmissionPolicy checking the values
+// of the annotations of the Ingresses in the API server, so the invalid
+// values are rejected even when the admission webhook is unavailable.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+)
+
+const (
+	annotationsVariable  = "variables.annotations"
+	ingressClassVariable = "variables.ingressClass"
+)
+
+// ingressClassExpression is true when the Ingress belongs to the IngressClass
+// of the parameters: the class of the spec, else the deprecated annotation,
+// else the default IngressClass
+var ingressClassExpression = fmt.Sprintf(
+	"has(object.spec.ingressClassName) ? object.spec.ingressClassName == params.metadata.name : "+
+		"(%[1]s in %[2]s ? %[2]s[%[1]s] == params.metadata.name : "+
+		"(has(params.metadata.annotations) && %[3]s in params.metadata.annotations && params.metadata.annotations[%[3]s] == \"true\"))",
+	strconv.Quote(ingressclass.IngressKey), annotationsVariable, strconv.Quote(networking.AnnotationIsDefaultIngressClass))
+
+// Generate returns the ValidatingAdmissionPolicy validating the annotations
+// with the prefix of the Ingresses, and its binding to the IngressClass of
+// the controller. Only the Ingresses of the class are checked, and only the
+// validators with a CEL equivalent, the others are left to the admission
+// webhook. Like the controller, the empty values are not validated.
+func Generate(name, prefix, ingressClass string, fields parser.AnnotationFields) (*admissionregistration.ValidatingAdmissionPolicy, *admissionregistration.ValidatingAdmissionPolicyBinding) {
+	names := make([]string, 0, len(fields))
+	for annotation := range fields {
+		names = append(names, annotation)
+	}
+	sort.Strings(names)
+
+	var validations []admissionregistration.Validation
+	for _, annotation := range names {
+		config := fields[annotation]
+		key := prefix + "/" + annotation
+
+		if validation, ok := validate(key, config.Validator); ok {
+			validations = append(validations, validation)
+		}
+
+		for _, alias := range config.AnnotationAliases {
+			// an alias is only used when the annotation is not set
+			validation, ok := validate(prefix+"/"+alias, config.Validator)
+			if !ok {
+				continue
+			}
+			validation.Expression = fmt.Sprintf("%s || %s", isSet(key), validation.Expression)
+			validations = append(validations, validation)
+		}
+	}
+
+	failurePolicy := admissionregistration.Fail
+	policy := &admissionregistration.ValidatingAdmissionPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistration.SchemeGroupVersion.String(),
+			Kind:       "ValidatingAdmissionPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: admissionregistration.ValidatingAdmissionPolicySpec{
+			FailurePolicy: &failurePolicy,
+			ParamKind: &admissionregistration.ParamKind{
+				APIVersion: networking.SchemeGroupVersion.String(),
+				Kind:       "IngressClass",
+			},
+			MatchConstraints: &admissionregistration.MatchResources{
+				ResourceRules: []admissionregistration.NamedRuleWithOperations{
+					{
+						RuleWithOperations: admissionregistration.RuleWithOperations{
+							Operations: []admissionregistration.OperationType{
+								admissionregistration.Create,
+								admissionregistration.Update,
+							},
+							Rule: admissionregistration.Rule{
+								APIGroups:   []string{networking.GroupName},
+								APIVersions: []string{networking.SchemeGroupVersion.Version},
+								Resources:   []string{"ingresses"},
+							},
+						},
+					},
+				},
+			},
+			Variables: []admissionregistration.Variable{
+				{
+					Name:       "annotations",
+					Expression: "has(object.metadata.annotations) ? object.metadata.annotations : {}",
+				},
+				{
+					Name:       "ingressClass",
+					Expression: ingressClassExpression,
+				},
+			},
+			Validations: validations,
+		},
+	}
+
+	parameterNotFoundAction := admissionregistration.AllowAction
+	binding := &admissionregistration.ValidatingAdmissionPolicyBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistration.SchemeGroupVersion.String(),
+			Kind:       "ValidatingAdmissionPolicyBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: admissionregistration.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName: name,
+			// the Ingresses are not checked while the IngressClass is missing,
+			// the controller does not serve them either
+			ParamRef: &admissionregistration.ParamRef{
+				Name:                    ingressClass,
+				ParameterNotFoundAction: &parameterNotFoundAction,
+			},
+			ValidationActions: []admissionregistration.ValidationAction{admissionregistration.Deny},
+		},
+	}
+
+	return policy, binding
+}
+
+// validate returns the validation of the annotation, false when the
+// validator cannot be expressed in CEL
+func validate(key string, validator parser.AnnotationValidator) (admissionregistration.Validation, bool) {
+	value := fmt.Sprintf("%s[%s]", annotationsVariable, strconv.Quote(key))
+	rule, ok := parser.CELExpression(validator, value)
+	if !ok {
+		return admissionregistration.Validation{}, false
+	}
+
+	reason := metav1.StatusReasonInvalid
+	return admissionregistration.Validation{
+		Expression: fmt.Sprintf("!%s || !%s || (%s)", ingressClassVariable, isSet(key), rule),
+		Message:    fmt.Sprintf("annotation %s contains an invalid value", key),
+		Reason:     &reason,
+	}, true
+}
+
+// isSet returns the CEL expression of an annotation with a value
+func isSet(key string) string {
+	quoted := strconv.Quote(key)
+	return fmt.Sprintf("(%s in %s && %s[%s] != \"\")", quoted, annotationsVariable, annotationsVariable, quoted)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+)
+
+func TestGenerate(t *testing.T) {
+	fields := parser.AnnotationFields{
+		"ssl-redirect": {
+			Validator: parser.ValidateBool,
+		},
+		"server-snippet": {
+			Validator: parser.ValidateNull,
+		},
+		"allowlist-source-range": {
+			Validator:         parser.ValidateRegex(parser.BasicCharsRegex, true),
+			AnnotationAliases: []string{"whitelist-source-range"},
+		},
+	}
+
+	policy, binding := Generate("annotations", "example.com", "nginx", fields)
+
+	if binding.Spec.PolicyName != policy.Name {
+		t.Errorf("expected the binding of the policy %v but got %v", policy.Name, binding.Spec.PolicyName)
+	}
+	if binding.Spec.ParamRef == nil || binding.Spec.ParamRef.Name != "nginx" {
+		t.Errorf("expected the binding of the IngressClass nginx but got %v", binding.Spec.ParamRef)
+	}
+	if policy.Spec.ParamKind == nil || policy.Spec.ParamKind.Kind != "IngressClass" {
+		t.Errorf("expected IngressClass parameters but got %v", policy.Spec.ParamKind)
+	}
+
+	expected := []struct {
+		expression string
+		message    string
+	}{
+		{
+			expression: `!variables.ingressClass || !("example.com/allowlist-source-range" in variables.annotations && variables.annotations["example.com/allowlist-source-range"] != "") || ` +
+				`(variables.annotations["example.com/allowlist-source-range"].replace(" ", "").matches("^[/\\-\\.\\_\\~a-zA-Z0-9\\/:]*$"))`,
+			message: "annotation example.com/allowlist-source-range contains an invalid value",
+		},
+		{
+			expression: `("example.com/allowlist-source-range" in variables.annotations && variables.annotations["example.com/allowlist-source-range"] != "") || ` +
+				`!variables.ingressClass || !("example.com/whitelist-source-range" in variables.annotations && variables.annotations["example.com/whitelist-source-range"] != "") || ` +
+				`(variables.annotations["example.com/whitelist-source-range"].replace(" ", "").matches("^[/\\-\\.\\_\\~a-zA-Z0-9\\/:]*$"))`,
+			message: "annotation example.com/whitelist-source-range contains an invalid value",
+		},
+		{
+			expression: `!variables.ingressClass || !("example.com/ssl-redirect" in variables.annotations && variables.annotations["example.com/ssl-redirect"] != "") || ` +
+				`(variables.annotations["example.com/ssl-redirect"] in ["1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE", "false", "False"])`,
+			message: "annotation example.com/ssl-redirect contains an invalid value",
+		},
+	}
+
+	validations := policy.Spec.Validations
+	if len(validations) != len(expected) {
+		t.Fatalf("expected %v validations but got %v", len(expected), len(validations))
+	}
+	for i, e := range expected {
+		if validations[i].Expression != e.expression {
+			t.Errorf("expected the expression %v but got %v", e.expression, validations[i].Expression)
+		}
+		if validations[i].Message != e.message {
+			t.Errorf("expected the message %v but got %v", e.message, validations[i].Message)
+		}
+	}
+}
@@ -0,0 +1,531 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// SyncInterval defines the time interval in which the certificates of the
+// Ingresses with the acme annotation are checked
+var SyncInterval = time.Minute
+
+// RenewBefore defines how long before their expiration the certificates are
+// renewed
+var RenewBefore = 30 * 24 * time.Hour
+
+// RetryInterval defines how long the controller waits before ordering again
+// a certificate after a failure, to stay below the rate limits of the ACME
+// server
+var RetryInterval = time.Hour
+
+// ChallengePropagationDelay defines how long the controller waits for all
+// the replicas to serve a challenge before asking the ACME server to
+// validate it
+var ChallengePropagationDelay = 5 * time.Second
+
+const (
+	// DefaultDirectoryURL is the directory of the Let's Encrypt production
+	// environment
+	DefaultDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+	// the Secrets created by the controller, the others are never overwritten
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "ingress-nginx-acme"
+
+	accountSecretSuffix = "-acme-account"
+	accountKeyKey       = "account.key"
+
+	orderTimeout = 5 * time.Minute
+)
+
+// Controller obtains and renews the certificates of the Ingresses from an
+// ACME server, answering the HTTP-01 challenges itself
+type Controller interface {
+	// Run checks the certificates of the Ingresses until the channel is
+	// closed. It must only run in the leader to avoid concurrent orders.
+	Run(chan struct{})
+	// RunChallenges publishes the pending challenges in NGINX until the
+	// channel is closed. It must run in every replica, any of them can
+	// receive the validation requests of the ACME server.
+	RunChallenges(chan struct{})
+}
+
+// ChallengeStore publishes the key authorizations of the HTTP-01 challenges
+type ChallengeStore interface {
+	Present(token, keyAuthorization string) error
+	CleanUp(token string) error
+}
+
+type ingressLister interface {
+	// ListIngresses returns the list of Ingresses
+	ListIngresses() []*ingress.Ingress
+}
+
+// Config is a structure that implements Client interfaces
+type Config struct {
+	Client clientset.Interface
+
+	IngressLister ingressLister
+
+	// DirectoryURL is the directory of the ACME server
+	DirectoryURL string
+	// Email is the contact of the ACME account
+	Email string
+
+	// Namespace and Name identify the Secret with the key of the ACME account,
+	// <Name>-acme-account, and the ConfigMap with the pending challenges,
+	// <Name>-acme-challenges
+	Namespace string
+	Name      string
+
+	ChallengeStore ChallengeStore
+
+	Recorder record.EventRecorder
+}
+
+// certificate is requested by the TLS sections of the Ingresses
+type certificate struct {
+	namespace  string
+	secretName string
+	hosts      []string
+	ingress    *ingress.Ingress
+}
+
+func (c *certificate) key() string {
+	return c.namespace + "/" + c.secretName
+}
+
+type controller struct {
+	Config
+
+	client *acme.Client
+
+	// last failure of the certificates, indexed by Secret
+	failures map[string]time.Time
+
+	// challenges published in this replica, indexed by token
+	publishedMu sync.Mutex
+	published   map[string]string
+
+	now func() time.Time
+	// obtain orders a certificate for the hosts and returns the PEM encoded
+	// certificate chain and private key
+	obtain func(ctx context.Context, hosts []string) (certPEM, keyPEM []byte, err error)
+}
+
+// NewController returns a new ACME controller
+func NewController(config Config) Controller {
+	c := &controller{
+		Config:    config,
+		failures:  make(map[string]time.Time),
+		published: make(map[string]string),
+		now:       time.Now,
+	}
+	c.obtain = c.obtainCertificate
+
+	return c
+}
+
+// Run starts the loop checking the certificates until stopCh is closed
+func (c *controller) Run(stopCh chan struct{}) {
+	wait.Until(c.sync, SyncInterval, stopCh)
+}
+
+func (c *controller) sync() {
+	for _, cert := range c.certificates() {
+		key := cert.key()
+		if failedAt, ok := c.failures[key]; ok && c.now().Sub(failedAt) < RetryInterval {
+			continue
+		}
+
+		secret, err := c.Client.CoreV1().Secrets(cert.namespace).Get(context.TODO(), cert.secretName, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			if !needsRenewal(secret, cert.hosts, c.now()) {
+				continue
+			}
+			if secret.Labels[managedByLabel] != managedByValue {
+				c.failures[key] = c.now()
+				c.event(cert.ingress, apiv1.EventTypeWarning, fmt.Sprintf("Secret %v was not created by the controller, not replacing its certificate", key))
+				continue
+			}
+		case k8sErrors.IsNotFound(err):
+			secret = nil
+		default:
+			klog.ErrorS(err, "Error getting Secret", "secret", key)
+			continue
+		}
+
+		klog.InfoS("Ordering certificate from the ACME server", "secret", key, "hosts", cert.hosts)
+		ctx, cancel := context.WithTimeout(context.Background(), orderTimeout)
+		certPEM, keyPEM, err := c.obtain(ctx, cert.hosts)
+		cancel()
+		if err != nil {
+			c.failures[key] = c.now()
+			c.event(cert.ingress, apiv1.EventTypeWarning, fmt.Sprintf("Error obtaining the certificate of Secret %v: %v", key, err))
+			continue
+		}
+
+		err = c.storeCertificate(cert, secret, certPEM, keyPEM)
+		if err != nil {
+			c.failures[key] = c.now()
+			c.event(cert.ingress, apiv1.EventTypeWarning, fmt.Sprintf("Error storing the certificate in Secret %v: %v", key, err))
+			continue
+		}
+
+		delete(c.failures, key)
+		c.event(cert.ingress, apiv1.EventTypeNormal, fmt.Sprintf("Certificate for %v stored in Secret %v", strings.Join(cert.hosts, ", "), key))
+	}
+}
+
+// certificates returns the certificates of the TLS sections of the Ingresses
+// with the acme annotation. The hosts of the TLS sections using the same
+// Secret are merged.
+func (c *controller) certificates() []*certificate {
+	certs := map[string]*certificate{}
+	for _, ing := range c.IngressLister.ListIngresses() {
+		if ing.ParsedAnnotations == nil || !ing.ParsedAnnotations.ACME {
+			continue
+		}
+
+		for _, tls := range ing.Spec.TLS {
+			if tls.SecretName == "" {
+				continue
+			}
+
+			cert := &certificate{
+				namespace:  ing.Namespace,
+				secretName: tls.SecretName,
+				ingress:    ing,
+			}
+			if existing, ok := certs[cert.key()]; ok {
+				cert = existing
+			}
+
+			for _, host := range tls.Hosts {
+				// the HTTP-01 challenge cannot be used for wildcard certificates
+				if strings.HasPrefix(host, "*.") {
+					klog.Warningf("Ingress %v/%v: the ACME HTTP-01 challenge does not support the wildcard host %v", ing.Namespace, ing.Name, host)
+					continue
+				}
+				cert.hosts = append(cert.hosts, strings.ToLower(host))
+			}
+
+			certs[cert.key()] = cert
+		}
+	}
+
+	keys := make([]string, 0, len(certs))
+	for key, cert := range certs {
+		if len(cert.hosts) == 0 {
+			continue
+		}
+		cert.hosts = sets.NewString(cert.hosts...).List()
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]*certificate, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, certs[key])
+	}
+
+	return result
+}
+
+// needsRenewal returns if the certificate of the Secret is missing, expires
+// soon or does not cover all the hosts
+func needsRenewal(secret *apiv1.Secret, hosts []string, now time.Time) bool {
+	block, _ := pem.Decode(secret.Data[apiv1.TLSCertKey])
+	if block == nil {
+		return true
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+
+	if cert.NotAfter.Sub(now) < RenewBefore {
+		return true
+	}
+
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *controller) storeCertificate(cert *certificate, secret *apiv1.Secret, certPEM, keyPEM []byte) error {
+	if secret == nil {
+		secret = &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cert.secretName,
+				Namespace: cert.namespace,
+				Labels: map[string]string{
+					managedByLabel: managedByValue,
+				},
+			},
+			Type: apiv1.SecretTypeTLS,
+			Data: map[string][]byte{
+				apiv1.TLSCertKey:       certPEM,
+				apiv1.TLSPrivateKeyKey: keyPEM,
+			},
+		}
+
+		_, err := c.Client.CoreV1().Secrets(cert.namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+		return err
+	}
+
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[apiv1.TLSCertKey] = certPEM
+	secret.Data[apiv1.TLSPrivateKeyKey] = keyPEM
+
+	_, err := c.Client.CoreV1().Secrets(cert.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *controller) event(ing *ingress.Ingress, eventType, message string) {
+	if eventType == apiv1.EventTypeWarning {
+		klog.Warningf("Ingress %v/%v: %v", ing.Namespace, ing.Name, message)
+	} else {
+		klog.InfoS(message, "ingress", klog.KObj(ing))
+	}
+
+	if c.Recorder == nil {
+		return
+	}
+
+	c.Recorder.Event(&ing.Ingress, eventType, "ACME", message)
+}
+
+// obtainCertificate orders a certificate for the hosts from the ACME server
+func (c *controller) obtainCertificate(ctx context.Context, hosts []string) (certPEM, keyPEM []byte, err error) {
+	client, err := c.acmeClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(hosts...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		err = c.authorize(ctx, client, authzURL)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("waiting for order: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hosts[0]},
+		DNSNames: hosts,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finalizing order: %w", err)
+	}
+
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
+
+// authorize completes the HTTP-01 challenge of an authorization of an order
+func (c *controller) authorize(ctx context.Context, client *acme.Client, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("getting authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, ch := range authz.Challenges {
+		if ch.Type == "http-01" {
+			challenge = ch
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("the ACME server did not offer an HTTP-01 challenge for %v", authz.Identifier.Value)
+	}
+
+	keyAuthorization, err := client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+
+	err = c.presentChallenge(challenge.Token, keyAuthorization)
+	if err != nil {
+		return fmt.Errorf("presenting challenge: %w", err)
+	}
+	defer func() {
+		if err := c.cleanUpChallenge(challenge.Token); err != nil {
+			klog.ErrorS(err, "Error removing ACME challenge", "token", challenge.Token)
+		}
+	}()
+
+	select {
+	case <-time.After(ChallengePropagationDelay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	_, err = client.Accept(ctx, challenge)
+	if err != nil {
+		return fmt.Errorf("accepting challenge: %w", err)
+	}
+
+	_, err = client.WaitAuthorization(ctx, authz.URI)
+	if err != nil {
+		return fmt.Errorf("validating %v: %w", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+// acmeClient returns the client of the ACME account, registering it the
+// first time. The key of the account is kept in a Secret.
+func (c *controller) acmeClient(ctx context.Context) (*acme.Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	key, err := c.accountKey()
+	if err != nil {
+		return nil, fmt.Errorf("getting ACME account key: %w", err)
+	}
+
+	client := &acme.Client{
+		Key:          key,
+		DirectoryURL: c.DirectoryURL,
+		UserAgent:    "ingress-nginx",
+	}
+
+	account := &acme.Account{}
+	if c.Email != "" {
+		account.Contact = []string{"mailto:" + c.Email}
+	}
+
+	_, err = client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("registering ACME account: %w", err)
+	}
+
+	c.client = client
+	return client, nil
+}
+
+func (c *controller) accountKey() (*ecdsa.PrivateKey, error) {
+	name := c.Name + accountSecretSuffix
+	secrets := c.Client.CoreV1().Secrets(c.Namespace)
+
+	secret, err := secrets.Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil {
+		block, _ := pem.Decode(secret.Data[accountKeyKey])
+		if block == nil {
+			return nil, fmt.Errorf("no valid PEM formatted key in Secret %v/%v", c.Namespace, name)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = secrets.Create(context.TODO(), &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.Namespace,
+			Labels: map[string]string{
+				managedByLabel: managedByValue,
+			},
+		},
+		Data: map[string][]byte{
+			accountKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+type testIngressLister struct {
+	ingresses []*ingress.Ingress
+}
+
+func (l testIngressLister) ListIngresses() []*ingress.Ingress {
+	return l.ingresses
+}
+
+func buildIngress(name string, enabled bool, tls ...networking.IngressTLS) *ingress.Ingress {
+	return &ingress.Ingress{
+		Ingress: networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: metav1.NamespaceDefault,
+			},
+			Spec: networking.IngressSpec{
+				TLS: tls,
+			},
+		},
+		ParsedAnnotations: &annotations.Ingress{
+			ACME: enabled,
+		},
+	}
+}
+
+func newCertificate(t *testing.T, notAfter time.Time, hosts ...string) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestCertificates(t *testing.T) {
+	c := &controller{
+		Config: Config{
+			IngressLister: testIngressLister{ingresses: []*ingress.Ingress{
+				buildIngress("disabled", false, networking.IngressTLS{Hosts: []string{"disabled.com"}, SecretName: "disabled"}),
+				buildIngress("first", true,
+					networking.IngressTLS{Hosts: []string{"B.example.com", "a.example.com"}, SecretName: "example"},
+					networking.IngressTLS{Hosts: []string{"*.wildcard.com"}, SecretName: "wildcard"},
+					networking.IngressTLS{Hosts: []string{"nosecret.com"}},
+				),
+				buildIngress("second", true, networking.IngressTLS{Hosts: []string{"c.example.com", "a.example.com"}, SecretName: "example"}),
+			}},
+		},
+	}
+
+	certs := c.certificates()
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate but %v returned", len(certs))
+	}
+	if certs[0].key() != "default/example" {
+		t.Errorf("expected certificate default/example but %v returned", certs[0].key())
+	}
+	expected := []string{"a.example.com", "b.example.com", "c.example.com"}
+	if !reflect.DeepEqual(certs[0].hosts, expected) {
+		t.Errorf("expected hosts %v but %v returned", expected, certs[0].hosts)
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	valid, _ := newCertificate(t, now.Add(60*24*time.Hour), "example.com", "www.example.com")
+	expiring, _ := newCertificate(t, now.Add(10*24*time.Hour), "example.com")
+
+	testCases := []struct {
+		name     string
+		cert     []byte
+		hosts    []string
+		expected bool
+	}{
+		{"valid certificate", valid, []string{"example.com", "www.example.com"}, false},
+		{"missing host", valid, []string{"example.com", "api.example.com"}, true},
+		{"expiring certificate", expiring, []string{"example.com"}, true},
+		{"invalid certificate", []byte("invalid"), []string{"example.com"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &apiv1.Secret{Data: map[string][]byte{apiv1.TLSCertKey: tc.cert}}
+			if got := needsRenewal(secret, tc.hosts, now); got != tc.expected {
+				t.Errorf("expected %v but %v returned", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	now := time.Now()
+	expiringCert, expiringKey := newCertificate(t, now.Add(time.Hour), "managed.com")
+	unmanagedCert, unmanagedKey := newCertificate(t, now.Add(time.Hour), "unmanaged.com")
+
+	client := testclient.NewSimpleClientset(
+		&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "managed",
+				Namespace: metav1.NamespaceDefault,
+				Labels:    map[string]string{managedByLabel: managedByValue},
+			},
+			Type: apiv1.SecretTypeTLS,
+			Data: map[string][]byte{apiv1.TLSCertKey: expiringCert, apiv1.TLSPrivateKeyKey: expiringKey},
+		},
+		&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "unmanaged",
+				Namespace: metav1.NamespaceDefault,
+			},
+			Type: apiv1.SecretTypeTLS,
+			Data: map[string][]byte{apiv1.TLSCertKey: unmanagedCert, apiv1.TLSPrivateKeyKey: unmanagedKey},
+		},
+	)
+
+	c := NewController(Config{
+		Client: client,
+		IngressLister: testIngressLister{ingresses: []*ingress.Ingress{
+			buildIngress("ing", true,
+				networking.IngressTLS{Hosts: []string{"new.com"}, SecretName: "new"},
+				networking.IngressTLS{Hosts: []string{"managed.com"}, SecretName: "managed"},
+				networking.IngressTLS{Hosts: []string{"unmanaged.com"}, SecretName: "unmanaged"},
+				networking.IngressTLS{Hosts: []string{"failing.com"}, SecretName: "failing"},
+			),
+		}},
+	}).(*controller)
+	c.now = func() time.Time { return now }
+
+	var ordered []string
+	c.obtain = func(_ context.Context, hosts []string) (certPEM, keyPEM []byte, err error) {
+		ordered = append(ordered, hosts...)
+		if hosts[0] == "failing.com" {
+			return nil, nil, fmt.Errorf("rate limited")
+		}
+		certPEM, keyPEM = newCertificate(t, now.Add(90*24*time.Hour), hosts...)
+		return certPEM, keyPEM, nil
+	}
+
+	c.sync()
+
+	expected := []string{"failing.com", "managed.com", "new.com"}
+	if !reflect.DeepEqual(ordered, expected) {
+		t.Errorf("expected orders for %v but %v returned", expected, ordered)
+	}
+
+	for _, name := range []string{"new", "managed"} {
+		secret, err := client.CoreV1().Secrets(metav1.NamespaceDefault).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error getting Secret %v: %v", name, err)
+		}
+		if secret.Labels[managedByLabel] != managedByValue {
+			t.Errorf("expected Secret %v to be managed by the controller", name)
+		}
+		if needsRenewal(secret, []string{name + ".com"}, now) {
+			t.Errorf("expected a new certificate in Secret %v", name)
+		}
+	}
+
+	secret, err := client.CoreV1().Secrets(metav1.NamespaceDefault).Get(context.TODO(), "unmanaged", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error getting Secret unmanaged: %v", err)
+	}
+	if string(secret.Data[apiv1.TLSCertKey]) != string(unmanagedCert) {
+		t.Errorf("expected the certificate of the unmanaged Secret to be kept")
+	}
+
+	// the failed order is not retried before the retry interval
+	ordered = nil
+	c.sync()
+	if len(ordered) != 0 {
+		t.Errorf("expected no order but %v returned", ordered)
+	}
+
+	now = now.Add(RetryInterval)
+	c.sync()
+	if !reflect.DeepEqual(ordered, []string{"failing.com"}) {
+		t.Errorf("expected an order for failing.com but %v returned", ordered)
+	}
+}
+
+type testChallengeStore struct {
+	challenges map[string]string
+}
+
+func (s *testChallengeStore) Present(token, keyAuthorization string) error {
+	s.challenges[token] = keyAuthorization
+	return nil
+}
+
+func (s *testChallengeStore) CleanUp(token string) error {
+	delete(s.challenges, token)
+	return nil
+}
+
+func TestChallenges(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+	store := &testChallengeStore{challenges: map[string]string{}}
+
+	c := NewController(Config{
+		Client:         client,
+		Namespace:      "ingress-nginx",
+		Name:           "ingress-nginx-leader",
+		ChallengeStore: store,
+	}).(*controller)
+
+	publish := func() {
+		cm, err := client.CoreV1().ConfigMaps("ingress-nginx").Get(context.TODO(), "ingress-nginx-leader-acme-challenges", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error getting the challenges: %v", err)
+		}
+		c.syncChallenges(cm)
+	}
+
+	if err := c.presentChallenge("token1", "token1.thumbprint"); err != nil {
+		t.Fatalf("unexpected error presenting challenge: %v", err)
+	}
+	if err := c.presentChallenge("token2", "token2.thumbprint"); err != nil {
+		t.Fatalf("unexpected error presenting challenge: %v", err)
+	}
+	publish()
+
+	expected := map[string]string{"token1": "token1.thumbprint", "token2": "token2.thumbprint"}
+	if !reflect.DeepEqual(store.challenges, expected) {
+		t.Errorf("expected challenges %v but %v returned", expected, store.challenges)
+	}
+
+	if err := c.cleanUpChallenge("token1"); err != nil {
+		t.Fatalf("unexpected error removing challenge: %v", err)
+	}
+	publish()
+
+	expected = map[string]string{"token2": "token2.thumbprint"}
+	if !reflect.DeepEqual(store.challenges, expected) {
+		t.Errorf("expected challenges %v but %v returned", expected, store.challenges)
+	}
+
+	c.publishChallenges(nil)
+	if len(store.challenges) != 0 {
+		t.Errorf("expected no challenges but %v returned", store.challenges)
+	}
+}
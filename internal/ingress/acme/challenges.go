@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// challengesResyncPeriod defines the interval in which the challenges are
+// published again, in case NGINX was not ready
+const challengesResyncPeriod = 10 * time.Second
+
+const challengesConfigMapSuffix = "-acme-challenges"
+
+// the pending challenges are kept in a ConfigMap watched by all the
+// replicas, the key authorizations indexed by token
+func (c *controller) challengesConfigMapName() string {
+	return c.Name + challengesConfigMapSuffix
+}
+
+func (c *controller) updateChallenges(update func(data map[string]string)) error {
+	configMaps := c.Client.CoreV1().ConfigMaps(c.Namespace)
+	name := c.challengesConfigMapName()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(context.TODO(), name, metav1.GetOptions{})
+		if k8sErrors.IsNotFound(err) {
+			cm = &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: c.Namespace,
+					Labels: map[string]string{
+						managedByLabel: managedByValue,
+					},
+				},
+				Data: map[string]string{},
+			}
+			update(cm.Data)
+			_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		update(cm.Data)
+		_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *controller) presentChallenge(token, keyAuthorization string) error {
+	return c.updateChallenges(func(data map[string]string) {
+		data[token] = keyAuthorization
+	})
+}
+
+func (c *controller) cleanUpChallenge(token string) error {
+	return c.updateChallenges(func(data map[string]string) {
+		delete(data, token)
+	})
+}
+
+// RunChallenges watches the ConfigMap with the pending challenges until
+// stopCh is closed
+func (c *controller) RunChallenges(stopCh chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.Client, challengesResyncPeriod,
+		informers.WithNamespace(c.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", c.challengesConfigMapName()).String()
+		}))
+
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.syncChallenges(obj)
+		},
+		UpdateFunc: func(_, cur interface{}) {
+			c.syncChallenges(cur)
+		},
+		DeleteFunc: func(_ interface{}) {
+			c.publishChallenges(nil)
+		},
+	})
+	if err != nil {
+		klog.ErrorS(err, "Error watching the ACME challenges")
+		return
+	}
+
+	factory.Start(stopCh)
+	<-stopCh
+	factory.Shutdown()
+}
+
+func (c *controller) syncChallenges(obj interface{}) {
+	cm, ok := obj.(*apiv1.ConfigMap)
+	if !ok || cm.Name != c.challengesConfigMapName() {
+		return
+	}
+
+	c.publishChallenges(cm.Data)
+}
+
+// publishChallenges makes the challenges published in NGINX match the
+// pending ones
+func (c *controller) publishChallenges(challenges map[string]string) {
+	c.publishedMu.Lock()
+	defer c.publishedMu.Unlock()
+
+	for token, keyAuthorization := range challenges {
+		if c.published[token] == keyAuthorization {
+			continue
+		}
+
+		err := c.ChallengeStore.Present(token, keyAuthorization)
+		if err != nil {
+			klog.ErrorS(err, "Error publishing ACME challenge", "token", token)
+			continue
+		}
+		c.published[token] = keyAuthorization
+	}
+
+	for token := range c.published {
+		if _, ok := challenges[token]; ok {
+			continue
+		}
+
+		err := c.ChallengeStore.CleanUp(token)
+		if err != nil {
+			klog.ErrorS(err, "Error removing ACME challenge", "token", token)
+			continue
+		}
+		delete(c.published, token)
+	}
+}
+
+type nginxChallengeStore struct{}
+
+// NewNGINXChallengeStore returns a ChallengeStore publishing the challenges
+// in the acme_challenges shared dictionary of NGINX
+func NewNGINXChallengeStore() ChallengeStore {
+	return nginxChallengeStore{}
+}
+
+type nginxChallenge struct {
+	Token            string `json:"token"`
+	KeyAuthorization string `json:"keyAuthorization,omitempty"`
+}
+
+func (nginxChallengeStore) post(challenge *nginxChallenge) error {
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/acme-challenges", "application/json", challenge)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code: %d", statusCode)
+	}
+
+	return nil
+}
+
+func (s nginxChallengeStore) Present(token, keyAuthorization string) error {
+	return s.post(&nginxChallenge{Token: token, KeyAuthorization: keyAuthorization})
+}
+
+func (s nginxChallengeStore) CleanUp(token string) error {
+	return s.post(&nginxChallenge{Token: token})
+}
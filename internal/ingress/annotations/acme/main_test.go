@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *networking.Ingress {
+	return &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{
+			TLS: []networking.IngressTLS{
+				{
+					Hosts:      []string{"foo.bar.com"},
+					SecretName: "foo-tls",
+				},
+			},
+		},
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err == nil {
+		t.Errorf("expected error parsing ingress without annotations")
+	}
+
+	testCases := []struct {
+		value    string
+		expected bool
+		err      bool
+	}{
+		{"true", true, false},
+		{"false", false, false},
+		{"yes", false, true},
+	}
+
+	for _, tc := range testCases {
+		ing.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix(acmeAnnotation): tc.value,
+		})
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.value, err)
+		}
+		val, ok := i.(bool)
+		if !ok {
+			t.Errorf("%v: expected a bool type", tc.value)
+		}
+		if val != tc.expected {
+			t.Errorf("%v: expected %v but %v returned", tc.value, tc.expected, val)
+		}
+	}
+}
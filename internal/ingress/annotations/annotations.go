@@ -22,8 +22,12 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/disableproxyintercepterrors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/healthcheck"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/njs"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/normalization"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/opentelemetry"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/outlierdetection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sslcipher"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/streamsnippet"
@@ -33,40 +37,59 @@ import (
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/ingress-nginx/internal/ingress/annotations/acme"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/alias"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreqglobal"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/backendprotocol"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/backendprotocolpaths"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/botdetection"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/brotli"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/clientbodybuffersize"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/conditionalredirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/csrf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customerrorpages"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/customhttperrors"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/defaultbackend"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/fastcgi"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoaccess"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/globalratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/grpcweb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/http2pushpreload"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipallowlist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipdenylist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/loadbalancing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/maintenance"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/plugins"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/portinredirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/satisfy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/securityheaders"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/serversnippet"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/serviceupstream"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sessionaffinity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/slowstart"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sslpassthrough"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/strictrequestvalidation"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/trailingslash"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamhashby"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamvhost"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/websocket"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/xforwardedprefix"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/zstd"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
@@ -77,7 +100,9 @@ const DeniedKeyName = "Denied"
 // Ingress defines the valid annotations present in one NGINX Ingress rule
 type Ingress struct {
 	metav1.ObjectMeta
+	ACME                        bool
 	BackendProtocol             string
+	BackendProtocolPaths        map[string]string
 	Aliases                     []string
 	BasicDigestAuth             auth.Config
 	Canary                      canary.Config
@@ -95,6 +120,7 @@ type Ingress struct {
 	ExternalAuth                authreq.Config
 	EnableGlobalAuth            bool
 	HTTP2PushPreload            bool
+	GRPCWeb                     bool
 	Opentelemetry               opentelemetry.Config
 	Proxy                       proxy.Config
 	ProxySSL                    proxyssl.Config
@@ -110,6 +136,9 @@ type Ingress struct {
 	UsePortInRedirects          bool
 	UpstreamHashBy              upstreamhashby.Config
 	LoadBalancing               string
+	SlowStartSeconds            int
+	OutlierDetection            outlierdetection.Config
+	HealthCheck                 healthcheck.Config
 	UpstreamVhost               string
 	Denylist                    ipdenylist.SourceRange
 	XForwardedPrefix            string
@@ -117,8 +146,37 @@ type Ingress struct {
 	Logs                        log.Config
 	ModSecurity                 modsecurity.Config
 	Mirror                      mirror.Config
+	WebSocket                   websocket.Config
+	BotDetection                botdetection.Config
+	CSRF                        csrf.Config
+	Normalization               normalization.Config
+	StrictRequestValidation     bool
+	SecurityHeaders             securityheaders.Config
+	Plugins                     plugins.Config
+	NJS                         njs.Config
+	ProxyCache                  proxycache.Config
+	Brotli                      brotli.Config
+	Zstd                        zstd.Config
+	RedirectMap                 redirectmap.Config
+	ConditionalRedirects        conditionalredirect.Config
+	TrailingSlash               trailingslash.Config
+	CustomErrorPages            customerrorpages.Config
+	Maintenance                 maintenance.Config
+	GeoAccess                   geoaccess.Config
 	StreamSnippet               string
 	Allowlist                   ipallowlist.SourceRange
+	// ClassServerSnippet and ClassLocationSnippet are not annotations, they
+	// are the snippets of the ConfigMap or the parameters of the IngressClass
+	// of the Ingress
+	ClassServerSnippet   string
+	ClassLocationSnippet string
+	// ClassAllowSnippetAnnotations overrides allow-snippet-annotations for
+	// the Ingresses of an IngressClass with its own configuration
+	ClassAllowSnippetAnnotations *bool
+	// ClassDefaultSSLCertificate is the default certificate of the
+	// parameters of the IngressClass of the Ingress, or the certificate of
+	// the Listener of the Ingresses translated from the HTTPRoutes
+	ClassDefaultSSLCertificate string
 }
 
 // Extractor defines the annotation parsers to be used in the extraction of annotations
@@ -130,6 +188,7 @@ type Extractor struct {
 func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 	return Extractor{
 		map[string]parser.IngressAnnotation{
+			"ACME":                        acme.NewParser(cfg),
 			"Aliases":                     alias.NewParser(cfg),
 			"BasicDigestAuth":             auth.NewParser(auth.AuthDirectory, cfg),
 			"Canary":                      canary.NewParser(cfg),
@@ -146,6 +205,7 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"ExternalAuth":                authreq.NewParser(cfg),
 			"EnableGlobalAuth":            authreqglobal.NewParser(cfg),
 			"HTTP2PushPreload":            http2pushpreload.NewParser(cfg),
+			"GRPCWeb":                     grpcweb.NewParser(cfg),
 			"Opentelemetry":               opentelemetry.NewParser(cfg),
 			"Proxy":                       proxy.NewParser(cfg),
 			"ProxySSL":                    proxyssl.NewParser(cfg),
@@ -161,6 +221,9 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"UsePortInRedirects":          portinredirect.NewParser(cfg),
 			"UpstreamHashBy":              upstreamhashby.NewParser(cfg),
 			"LoadBalancing":               loadbalancing.NewParser(cfg),
+			"SlowStartSeconds":            slowstart.NewParser(cfg),
+			"OutlierDetection":            outlierdetection.NewParser(cfg),
+			"HealthCheck":                 healthcheck.NewParser(cfg),
 			"UpstreamVhost":               upstreamvhost.NewParser(cfg),
 			"Allowlist":                   ipallowlist.NewParser(cfg),
 			"Denylist":                    ipdenylist.NewParser(cfg),
@@ -168,13 +231,42 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"SSLCipher":                   sslcipher.NewParser(cfg),
 			"Logs":                        log.NewParser(cfg),
 			"BackendProtocol":             backendprotocol.NewParser(cfg),
+			"BackendProtocolPaths":        backendprotocolpaths.NewParser(cfg),
 			"ModSecurity":                 modsecurity.NewParser(cfg),
 			"Mirror":                      mirror.NewParser(cfg),
+			"WebSocket":                   websocket.NewParser(cfg),
+			"BotDetection":                botdetection.NewParser(cfg),
+			"CSRF":                        csrf.NewParser(cfg),
+			"Normalization":               normalization.NewParser(cfg),
+			"StrictRequestValidation":     strictrequestvalidation.NewParser(cfg),
+			"SecurityHeaders":             securityheaders.NewParser(cfg),
+			"Plugins":                     plugins.NewParser(cfg),
+			"NJS":                         njs.NewParser(cfg),
+			"ProxyCache":                  proxycache.NewParser(cfg),
+			"Brotli":                      brotli.NewParser(cfg),
+			"Zstd":                        zstd.NewParser(cfg),
+			"RedirectMap":                 redirectmap.NewParser(cfg),
+			"ConditionalRedirects":        conditionalredirect.NewParser(cfg),
+			"TrailingSlash":               trailingslash.NewParser(cfg),
+			"CustomErrorPages":            customerrorpages.NewParser(cfg),
+			"Maintenance":                 maintenance.NewParser(cfg),
+			"GeoAccess":                   geoaccess.NewParser(cfg),
 			"StreamSnippet":               streamsnippet.NewParser(cfg),
 		},
 	}
 }
 
+// AnnotationFields returns the annotations of all the parsers, without prefix
+func (e Extractor) AnnotationFields() parser.AnnotationFields {
+	fields := parser.AnnotationFields{}
+	for _, annotationParser := range e.annotations {
+		for name, config := range annotationParser.GetDocumentation() {
+			fields[name] = config
+		}
+	}
+	return fields
+}
+
 // Extract extracts the annotations from an Ingress
 func (e Extractor) Extract(ing *networking.Ingress) (*Ingress, error) {
 	pia := &Ingress{
@@ -157,9 +157,8 @@ func (a authTLS) Parse(ing *networking.Ingress) (interface{}, error) {
 	if ns == "" {
 		ns = ing.Namespace
 	}
-	secCfg := a.r.GetSecurityConfiguration()
-	// We don't accept different namespaces for secrets.
-	if !secCfg.AllowCrossNamespaceResources && ns != ing.Namespace {
+	// We don't accept different namespaces for secrets, unless allowed.
+	if ns != ing.Namespace && !resolver.IsCrossNamespaceSecretAllowed(a.r, ing, tlsauthsecret) {
 		return &Config{}, ing_errors.NewLocationDenied("cross namespace secrets are not supported")
 	}
 
@@ -202,6 +202,20 @@ func TestInvalidAnnotations(t *testing.T) {
 		t.Errorf("received error is different from cross namespace error: %s Expected %s", err, expErr)
 	}
 
+	// Cross NameSpace without ReferenceGrant
+	grantedSecret := &mockSecret{resolver.Mock{AllowCrossNamespace: true, ReferenceGrants: true}}
+	_, err = NewParser(grantedSecret).Parse(ing)
+	if err == nil || err.Error() != expErr.Error() {
+		t.Errorf("received error is different from cross namespace error: %s Expected %s", err, expErr)
+	}
+
+	// Cross NameSpace with ReferenceGrant
+	grantedSecret.GrantedSecrets = []string{"nondefault/demo-secret"}
+	_, err = NewParser(grantedSecret).Parse(ing)
+	if err != nil && err.Error() == expErr.Error() {
+		t.Errorf("expected the ReferenceGrant to permit the cross namespace secret")
+	}
+
 	// Invalid Auth Certificate
 	data[parser.GetAnnotationWithPrefix(annotationAuthTLSSecret)] = "default/invalid-demo-secret"
 	ing.SetAnnotations(data)
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backendprotocolpaths
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	validProtocols   = []string{"auto_http", "http", "https", "grpc", "grpcs", "fcgi"}
+	validateProtocol = parser.ValidateOptions(validProtocols, false, true)
+)
+
+const (
+	backendProtocolPathsAnnotation = "backend-protocol-paths"
+)
+
+var pathRegex = regexp.MustCompile(`^/[-._~a-zA-Z0-9/]*$`)
+
+var backendProtocolPathsConfig = parser.Annotation{
+	Group: "backend",
+	Annotations: parser.AnnotationFields{
+		backendProtocolPathsAnnotation: {
+			Validator: parser.ValidatorFunc(validatePaths),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow, // Low, as it allows just a set of options per path
+			Documentation: `this annotation overrides the backend-protocol of some paths of the Ingress.
+			It is a comma separated list of path=protocol pairs, e.g. /api=GRPC,/=HTTP`,
+		},
+	},
+}
+
+// parsePaths returns the protocols indexed by path
+func parsePaths(value string) (map[string]string, error) {
+	paths := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		path, protocol, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("%q is not a path=protocol pair", pair)
+		}
+
+		path = strings.TrimSpace(path)
+		if !pathRegex.MatchString(path) {
+			return nil, fmt.Errorf("%q is not a valid path", path)
+		}
+
+		protocol = strings.TrimSpace(protocol)
+		if err := validateProtocol.Validate(protocol); err != nil {
+			return nil, err
+		}
+
+		paths[path] = strings.ToUpper(protocol)
+	}
+
+	return paths, nil
+}
+
+func validatePaths(value string) error {
+	_, err := parsePaths(value)
+	return err
+}
+
+type backendProtocolPaths struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new per path backend protocol annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return backendProtocolPaths{
+		r:                r,
+		annotationConfig: backendProtocolPathsConfig,
+	}
+}
+
+func (a backendProtocolPaths) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// override the backend protocol of some paths. It returns the protocols
+// indexed by path.
+func (a backendProtocolPaths) Parse(ing *networking.Ingress) (interface{}, error) {
+	value, err := parser.GetStringAnnotation(backendProtocolPathsAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("validation error %s. Using the backend-protocol of the Ingress for all the paths", err)
+		}
+		return map[string]string{}, nil
+	}
+
+	// the value was validated by GetStringAnnotation
+	paths, err := parsePaths(value)
+	if err != nil {
+		return map[string]string{}, nil
+	}
+
+	return paths, nil
+}
+
+func (a backendProtocolPaths) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, backendProtocolPathsConfig.Annotations)
+}
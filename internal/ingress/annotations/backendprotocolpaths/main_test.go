@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backendprotocolpaths
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix(backendProtocolPathsAnnotation)
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    map[string]string
+	}{
+		{"without annotations", map[string]string{}, map[string]string{}},
+		{"single path", map[string]string{annotation: "/api=GRPC"}, map[string]string{"/api": "GRPC"}},
+		{
+			"several paths",
+			map[string]string{annotation: "/api = grpc, /=HTTP,/legacy/v1=https"},
+			map[string]string{"/api": "GRPC", "/": "HTTP", "/legacy/v1": "HTTPS"},
+		},
+		{"invalid protocol", map[string]string{annotation: "/api=GRPC,/=SMTP"}, map[string]string{}},
+		{"missing protocol", map[string]string{annotation: "/api"}, map[string]string{}},
+		{"invalid path", map[string]string{annotation: "/api;rm=GRPC"}, map[string]string{}},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, err := ap.Parse(ing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected %v but returned %v", testCase.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package botdetection
+
+import (
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	enableBotDetectionAnnotation         = "enable-bot-detection"
+	botDetectionChallengeAnnotation      = "bot-detection-challenge"
+	botDetectionChallengeScoreAnnotation = "bot-detection-challenge-score"
+	botDetectionBlockScoreAnnotation     = "bot-detection-block-score"
+	botDetectionRateLimitAnnotation      = "bot-detection-rate-limit"
+)
+
+const (
+	// ChallengeNone never challenges the clients, they are only blocked
+	ChallengeNone = "none"
+	// ChallengeCookie redirects the clients to the same URL with a signed
+	// cookie, the clients keeping the cookies pass the challenge
+	ChallengeCookie = "cookie"
+	// ChallengeJavaScript serves a page setting the signed cookie with
+	// JavaScript, the clients running the script pass the challenge
+	ChallengeJavaScript = "javascript"
+)
+
+const (
+	defaultChallengeScore = 50
+	defaultBlockScore     = 100
+)
+
+// Challenges are the challenges served to the clients scored as bots
+var Challenges = []string{ChallengeNone, ChallengeCookie, ChallengeJavaScript}
+
+var botDetectionAnnotations = parser.Annotation{
+	Group: "bot-detection",
+	Annotations: parser.AnnotationFields{
+		enableBotDetectionAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables the bot detection of the requests of this location, scoring them with their User-Agent,
+			the reputation of their address and their rate, and challenging or blocking the ones scored as bots`,
+		},
+		botDetectionChallengeAnnotation: {
+			Validator:     parser.ValidateOptions(Challenges, true, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the challenge served to the clients scored as bots: none, cookie or javascript. Defaults to cookie`,
+		},
+		botDetectionChallengeScoreAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the score from which the requests are challenged. Defaults to 50`,
+		},
+		botDetectionBlockScoreAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the score from which the requests are denied with a 403 response, even when the client passed the challenge.
+			0 never blocks the requests. Defaults to 100`,
+		},
+		botDetectionRateLimitAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the number of requests per minute of a client to the Ingress above which its requests are scored as bots.
+			0 does not score the rate of the requests. Defaults to 0`,
+		},
+	},
+}
+
+// Config describes the bot detection of a location
+type Config struct {
+	Enabled        bool   `json:"enabled"`
+	Challenge      string `json:"challenge"`
+	ChallengeScore int    `json:"challengeScore"`
+	BlockScore     int    `json:"blockScore"`
+	RateLimit      int    `json:"rateLimit"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type botDetection struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new bot detection annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return botDetection{
+		r:                r,
+		annotationConfig: botDetectionAnnotations,
+	}
+}
+
+func (b botDetection) getNonNegativeInt(name string, ing *networking.Ingress, defaultValue int) int {
+	value, err := parser.GetIntAnnotation(name, ing, b.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to %d", name, defaultValue)
+		}
+		return defaultValue
+	}
+
+	if value < 0 {
+		klog.Warningf("%s must not be negative, defaulting to %d", name, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the bot detection of the locations
+func (b botDetection) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	enabled, err := parser.GetBoolAnnotation(enableBotDetectionAnnotation, ing, b.annotationConfig.Annotations)
+	if err != nil || !enabled {
+		return config, nil
+	}
+	config.Enabled = true
+
+	config.Challenge, err = parser.GetStringAnnotation(botDetectionChallengeAnnotation, ing, b.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to %s", botDetectionChallengeAnnotation, ChallengeCookie)
+		}
+		config.Challenge = ChallengeCookie
+	}
+
+	config.ChallengeScore = b.getNonNegativeInt(botDetectionChallengeScoreAnnotation, ing, defaultChallengeScore)
+	config.BlockScore = b.getNonNegativeInt(botDetectionBlockScoreAnnotation, ing, defaultBlockScore)
+	config.RateLimit = b.getNonNegativeInt(botDetectionRateLimitAnnotation, ing, 0)
+
+	return config, nil
+}
+
+func (b botDetection) GetDocumentation() parser.AnnotationFields {
+	return b.annotationConfig.Annotations
+}
+
+func (b botDetection) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(b.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, botDetectionAnnotations.Annotations)
+}
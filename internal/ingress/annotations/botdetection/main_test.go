@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package botdetection
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix(enableBotDetectionAnnotation)
+	challenge := parser.GetAnnotationWithPrefix(botDetectionChallengeAnnotation)
+	challengeScore := parser.GetAnnotationWithPrefix(botDetectionChallengeScoreAnnotation)
+	blockScore := parser.GetAnnotationWithPrefix(botDetectionBlockScoreAnnotation)
+	rateLimit := parser.GetAnnotationWithPrefix(botDetectionRateLimitAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *Config
+	}{
+		{
+			name:        "without annotations",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			name: "disabled",
+			annotations: map[string]string{
+				enable:    "false",
+				challenge: ChallengeJavaScript,
+			},
+			expected: &Config{},
+		},
+		{
+			name: "enabled with the defaults",
+			annotations: map[string]string{
+				enable: "true",
+			},
+			expected: &Config{Enabled: true, Challenge: ChallengeCookie, ChallengeScore: 50, BlockScore: 100},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				enable:         "true",
+				challenge:      ChallengeJavaScript,
+				challengeScore: "30",
+				blockScore:     "0",
+				rateLimit:      "600",
+			},
+			expected: &Config{Enabled: true, Challenge: ChallengeJavaScript, ChallengeScore: 30, RateLimit: 600},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				enable:         "true",
+				challenge:      "captcha",
+				challengeScore: "-1",
+				rateLimit:      "fast",
+			},
+			expected: &Config{Enabled: true, Challenge: ChallengeCookie, ChallengeScore: 50, BlockScore: 100},
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, err := ap.Parse(ing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but returned %T", result)
+			}
+			if !config.Equal(testCase.expected) {
+				t.Errorf("expected %+v but returned %+v", testCase.expected, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brotli
+
+import (
+	"regexp"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	brotliEnableAnnotation = "enable-brotli"
+	brotliLevelAnnotation  = "brotli-level"
+	brotliTypesAnnotation  = "brotli-types"
+)
+
+var (
+	// levelRegex matches the compression levels from 1 to 11
+	levelRegex = regexp.MustCompile(`^([1-9]|1[01])$`)
+	// typesRegex matches a list of MIME types separated by spaces
+	typesRegex = regexp.MustCompile(`^[a-z0-9*][a-z0-9.+\-]*/[a-z0-9*][a-z0-9.+\-]*( +[a-z0-9*][a-z0-9.+\-]*/[a-z0-9*][a-z0-9.+\-]*)*$`)
+)
+
+var brotliAnnotations = parser.Annotation{
+	Group: "compression",
+	Annotations: parser.AnnotationFields{
+		brotliEnableAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables or disables the Brotli compression of the responses of the locations, overriding the
+			enable-brotli of the ConfigMap`,
+		},
+		brotliLevelAnnotation: {
+			Validator:     parser.ValidateRegex(levelRegex, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation sets the Brotli compression level of the responses, from 1 to 11`,
+		},
+		brotliTypesAnnotation: {
+			Validator:     parser.ValidateRegex(typesRegex, false),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation sets the space separated MIME types of the responses compressed with Brotli`,
+		},
+	},
+}
+
+// Config describes the Brotli compression of the responses of a location
+type Config struct {
+	Enable bool `json:"enable,omitempty"`
+	// EnableSet is true when the annotation overrides the enable-brotli of
+	// the ConfigMap
+	EnableSet bool `json:"enableSet,omitempty"`
+	// Level is the compression level, the level of the ConfigMap when it is 0
+	Level int `json:"level,omitempty"`
+	// Types are the MIME types compressed, the types of the ConfigMap when
+	// it is empty
+	Types string `json:"types,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type brotli struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new Brotli annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return brotli{
+		r:                r,
+		annotationConfig: brotliAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the Brotli compression of the locations
+func (b brotli) Parse(ing *networking.Ingress) (interface{}, error) {
+	var err error
+	config := &Config{}
+
+	config.Enable, err = parser.GetBoolAnnotation(brotliEnableAnnotation, ing, b.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+	} else {
+		config.EnableSet = true
+	}
+
+	config.Level, err = parser.GetIntAnnotation(brotliLevelAnnotation, ing, b.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return config, err
+	}
+
+	types, err := parser.GetStringAnnotation(brotliTypesAnnotation, ing, b.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return config, err
+	}
+	config.Types = strings.Join(strings.Fields(types), " ")
+
+	return config, nil
+}
+
+func (b brotli) GetDocumentation() parser.AnnotationFields {
+	return b.annotationConfig.Annotations
+}
+
+func (b brotli) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(b.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, brotliAnnotations.Annotations)
+}
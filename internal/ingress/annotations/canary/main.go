@@ -17,6 +17,10 @@ limitations under the License.
 package canary
 
 import (
+	"regexp"
+	"strings"
+	"time"
+
 	networking "k8s.io/api/networking/v1"
 	"k8s.io/klog/v2"
 
@@ -33,6 +37,39 @@ const (
 	canaryByHeaderValueAnnotation   = "canary-by-header-value"
 	canaryByHeaderPatternAnnotation = "canary-by-header-pattern"
 	canaryByCookieAnnotation        = "canary-by-cookie"
+	canaryByQueryAnnotation         = "canary-by-query"
+	canaryByQueryValueAnnotation    = "canary-by-query-value"
+	canaryStickyAnnotation          = "canary-sticky"
+	canaryStickyCookieAnnotation    = "canary-sticky-cookie"
+	canaryStickyMaxAgeAnnotation    = "canary-sticky-max-age"
+	canaryRolloutStepAnnotation     = "canary-rollout-step"
+	canaryRolloutIntervalAnnotation = "canary-rollout-interval"
+	canaryRolloutMaxErrorRate       = "canary-rollout-max-error-rate"
+	canaryRolloutMaxLatency         = "canary-rollout-max-latency"
+	canaryRolloutMinRequests        = "canary-rollout-min-requests"
+	canaryRolloutOnFailure          = "canary-rollout-on-failure"
+	canaryRolloutPaused             = "canary-rollout-paused"
+	canaryByGeoCountryAnnotation    = "canary-by-geo-country"
+	canaryByGeoContinentAnnotation  = "canary-by-geo-continent"
+)
+
+var (
+	// geoCountriesRegex matches a comma separated list of ISO 3166-1 alpha-2 country codes
+	geoCountriesRegex = regexp.MustCompile(`^(?i)[a-z]{2}(,[a-z]{2})*$`)
+	// geoContinentsRegex matches a comma separated list of the continent codes of the GeoIP databases
+	geoContinentsRegex = regexp.MustCompile(`^(?i)(af|an|as|eu|na|oc|sa)(,(af|an|as|eu|na|oc|sa))*$`)
+)
+
+const (
+	// RolloutOnFailurePause stops incrementing the canary weight when the SLO is violated
+	RolloutOnFailurePause = "pause"
+	// RolloutOnFailureRollback sets the canary weight to 0 when the SLO is violated
+	RolloutOnFailureRollback = "rollback"
+
+	defaultRolloutInterval = time.Minute
+	// defaultRolloutMinRequests is the number of requests the canary must
+	// serve during a step for its SLO to be evaluated
+	defaultRolloutMinRequests = 10
 )
 
 var CanaryAnnotations = parser.Annotation{
@@ -88,6 +125,97 @@ var CanaryAnnotations = parser.Annotation{
 			Documentation: `This annotation defines the cookie that should be used for notifying the Ingress to route the request to the service specified in the Canary Ingress.
 			When the cookie is set to 'always', it will be routed to the canary. When the cookie is set to 'never', it will never be routed to the canary`,
 		},
+		canaryByQueryAnnotation: {
+			Validator: parser.ValidateRegex(parser.BasicCharsRegex, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the query parameter that should be used for notifying the Ingress to route the request to the service specified in the Canary Ingress.
+			When the query parameter is set to 'always', it will be routed to the canary. When the query parameter is set to 'never', it will never be routed to the canary.
+			For any other value, the query parameter will be ignored and the request compared against the other canary rules by precedence`,
+		},
+		canaryByQueryValueAnnotation: {
+			Validator: parser.ValidateRegex(parser.BasicCharsRegex, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the query parameter value to match for notifying the Ingress to route the request to the service specified in the Canary Ingress.
+			When the query parameter is set to this value, it will be routed to the canary. For any other value, the query parameter will be ignored and the request compared against the other canary rules by precedence.
+			This annotation has to be used together with 'canary-by-query'. It doesn't have any effect if the 'canary-by-query' annotation is not defined`,
+		},
+		canaryStickyAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation makes the weighted canary decision once per client and persists it in a signed cookie,
+			so subsequent requests of the same client are served by the same variant`,
+		},
+		canaryStickyCookieAnnotation: {
+			Validator:     parser.ValidateRegex(parser.BasicCharsRegex, true),
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the name of the cookie used to persist the sticky canary assignment. It defaults to a name derived from the canary backend`,
+		},
+		canaryStickyMaxAgeAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the time in seconds the sticky canary assignment is kept. If unspecified, the assignment lasts for the browser session`,
+		},
+		canaryRolloutStepAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation enables the progressive rollout of the canary, incrementing canary-weight by the given step on every rollout interval while the canary meets its SLO`,
+		},
+		canaryRolloutIntervalAnnotation: {
+			Validator:     parser.ValidateDuration,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the time between two steps of the progressive canary rollout, e.g. 30s or 5m. Defaults to 1m`,
+		},
+		canaryRolloutMaxErrorRate: {
+			Validator:     parser.ValidateFloat,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the maximum percentage of 5xx responses served by the canary during a rollout step. If unspecified, the error rate is not checked`,
+		},
+		canaryRolloutMaxLatency: {
+			Validator:     parser.ValidateDuration,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the maximum average request duration of the canary during a rollout step, e.g. 500ms. If unspecified, the latency is not checked`,
+		},
+		canaryRolloutMinRequests: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the minimum number of requests the canary must serve during a rollout step for the weight to be incremented. The weight is kept until the canary serves them. Defaults to 10`,
+		},
+		canaryRolloutOnFailure: {
+			Validator:     parser.ValidateOptions([]string{RolloutOnFailurePause, RolloutOnFailureRollback}, true, true),
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines what happens when the canary violates its SLO during a rollout. Can be 'pause' (default) or 'rollback'`,
+		},
+		canaryRolloutPaused: {
+			Validator:     parser.ValidateBool,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation pauses the progressive rollout of the canary. It is set by the controller when the canary violates its SLO`,
+		},
+		canaryByGeoCountryAnnotation: {
+			Validator: parser.ValidateRegex(geoCountriesRegex, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines a comma separated list of ISO country codes, e.g. 'DE,FR,IT'. The requests whose client address is located in one of these countries by the GeoIP databases are routed to the canary.
+			The header, cookie and query rules take precedence, the requests of the other countries are compared against the weight`,
+		},
+		canaryByGeoContinentAnnotation: {
+			Validator: parser.ValidateRegex(geoContinentsRegex, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines a comma separated list of continent codes (AF, AN, AS, EU, NA, OC or SA). The requests whose client address is located in one of these continents by the GeoIP databases are routed to the canary.
+			The header, cookie and query rules take precedence, the requests of the other continents are compared against the weight`,
+		},
 	},
 }
 
@@ -105,6 +233,30 @@ type Config struct {
 	HeaderValue   string
 	HeaderPattern string
 	Cookie        string
+	Query         string
+	QueryValue    string
+	Sticky        bool
+	StickyCookie  string
+	StickyMaxAge  int
+	// GeoCountries are the uppercase country codes of the clients routed to the canary
+	GeoCountries []string
+	// GeoContinents are the uppercase continent codes of the clients routed to the canary
+	GeoContinents []string
+	Rollout       RolloutConfig
+}
+
+// RolloutConfig describes the progressive rollout of a canary
+type RolloutConfig struct {
+	// Step is the weight added to the canary on every interval. Zero disables the rollout
+	Step         int
+	Interval     time.Duration
+	MaxErrorRate float32
+	MaxLatency   time.Duration
+	// MinRequests is the number of requests the canary must serve during a
+	// step before its weight is incremented
+	MinRequests int
+	OnFailure   string
+	Paused      bool
 }
 
 // NewParser parses the ingress for canary related annotations
@@ -177,14 +329,143 @@ func (c canary) Parse(ing *networking.Ingress) (interface{}, error) {
 		config.Cookie = ""
 	}
 
+	config.Query, err = parser.GetStringAnnotation(canaryByQueryAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to ''", canaryByQueryAnnotation)
+		}
+		config.Query = ""
+	}
+
+	config.QueryValue, err = parser.GetStringAnnotation(canaryByQueryValueAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to ''", canaryByQueryValueAnnotation)
+		}
+		config.QueryValue = ""
+	}
+
+	config.Sticky, err = parser.GetBoolAnnotation(canaryStickyAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to 'false'", canaryStickyAnnotation)
+		}
+		config.Sticky = false
+	}
+
+	config.StickyCookie, err = parser.GetStringAnnotation(canaryStickyCookieAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to ''", canaryStickyCookieAnnotation)
+		}
+		config.StickyCookie = ""
+	}
+
+	config.StickyMaxAge, err = parser.GetIntAnnotation(canaryStickyMaxAgeAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to '0'", canaryStickyMaxAgeAnnotation)
+		}
+		config.StickyMaxAge = 0
+	}
+
+	config.GeoCountries = c.parseGeoCodes(canaryByGeoCountryAnnotation, ing)
+	config.GeoContinents = c.parseGeoCodes(canaryByGeoContinentAnnotation, ing)
+
+	config.Rollout = c.parseRollout(ing)
+
 	if !config.Enabled && (config.Weight > 0 || config.Header != "" || config.HeaderValue != "" || config.Cookie != "" ||
-		config.HeaderPattern != "") {
+		config.HeaderPattern != "" || config.Query != "" || config.QueryValue != "" || config.Sticky ||
+		len(config.GeoCountries) > 0 || len(config.GeoContinents) > 0 || config.Rollout.Step > 0) {
 		return nil, errors.NewInvalidAnnotationConfiguration(canaryAnnotation, "configured but not enabled")
 	}
 
 	return config, nil
 }
 
+// parseGeoCodes returns the uppercase codes of a comma separated list
+func (c canary) parseGeoCodes(name string, ing *networking.Ingress) []string {
+	value, err := parser.GetStringAnnotation(name, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, ignoring", name)
+		}
+		return nil
+	}
+
+	codes := []string{}
+	for _, code := range strings.Split(value, ",") {
+		codes = append(codes, strings.ToUpper(strings.TrimSpace(code)))
+	}
+	return codes
+}
+
+func (c canary) parseRollout(ing *networking.Ingress) RolloutConfig {
+	rollout := RolloutConfig{
+		Interval:    defaultRolloutInterval,
+		MinRequests: defaultRolloutMinRequests,
+		OnFailure:   RolloutOnFailurePause,
+	}
+
+	step, err := parser.GetIntAnnotation(canaryRolloutStepAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil || step < 0 {
+		if errors.IsValidationError(err) || err == nil {
+			klog.Warningf("%s is invalid, defaulting to '0'", canaryRolloutStepAnnotation)
+		}
+		step = 0
+	}
+	rollout.Step = step
+
+	interval, err := parser.GetStringAnnotation(canaryRolloutIntervalAnnotation, ing, c.annotationConfig.Annotations)
+	if err == nil {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			rollout.Interval = d
+		} else {
+			klog.Warningf("%s is invalid, defaulting to '%v'", canaryRolloutIntervalAnnotation, defaultRolloutInterval)
+		}
+	} else if errors.IsValidationError(err) {
+		klog.Warningf("%s is invalid, defaulting to '%v'", canaryRolloutIntervalAnnotation, defaultRolloutInterval)
+	}
+
+	rollout.MaxErrorRate, err = parser.GetFloatAnnotation(canaryRolloutMaxErrorRate, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, ignoring", canaryRolloutMaxErrorRate)
+		}
+		rollout.MaxErrorRate = 0
+	}
+
+	latency, err := parser.GetStringAnnotation(canaryRolloutMaxLatency, ing, c.annotationConfig.Annotations)
+	if err == nil {
+		if d, err := time.ParseDuration(latency); err == nil {
+			rollout.MaxLatency = d
+		}
+	} else if errors.IsValidationError(err) {
+		klog.Warningf("%s is invalid, ignoring", canaryRolloutMaxLatency)
+	}
+
+	minRequests, err := parser.GetIntAnnotation(canaryRolloutMinRequests, ing, c.annotationConfig.Annotations)
+	if err == nil && minRequests > 0 {
+		rollout.MinRequests = minRequests
+	} else if errors.IsValidationError(err) || err == nil {
+		klog.Warningf("%s is invalid, defaulting to '%d'", canaryRolloutMinRequests, defaultRolloutMinRequests)
+	}
+
+	onFailure, err := parser.GetStringAnnotation(canaryRolloutOnFailure, ing, c.annotationConfig.Annotations)
+	if err == nil {
+		rollout.OnFailure = onFailure
+	} else if errors.IsValidationError(err) {
+		klog.Warningf("%s is invalid, defaulting to '%s'", canaryRolloutOnFailure, RolloutOnFailurePause)
+	}
+
+	rollout.Paused, err = parser.GetBoolAnnotation(canaryRolloutPaused, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		rollout.Paused = false
+	}
+
+	return rollout
+}
+
 func (c canary) GetDocumentation() parser.AnnotationFields {
 	return c.annotationConfig.Annotations
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package canary
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -106,16 +107,19 @@ func TestAnnotations(t *testing.T) {
 		canaryWeight  int
 		canaryHeader  string
 		canaryCookie  string
+		canaryQuery   string
 		expErr        bool
 	}{
-		{"canary disabled and no weight", false, 0, "", "", false},
-		{"canary disabled and weight", false, 20, "", "", true},
-		{"canary disabled and header", false, 0, "X-Canary", "", true},
-		{"canary disabled and cookie", false, 0, "", "canary_enabled", true},
-		{"canary enabled and weight", true, 20, "", "", false},
-		{"canary enabled and no weight", true, 0, "", "", false},
-		{"canary enabled by header", true, 20, "X-Canary", "", false},
-		{"canary enabled by cookie", true, 20, "", "canary_enabled", false},
+		{"canary disabled and no weight", false, 0, "", "", "", false},
+		{"canary disabled and weight", false, 20, "", "", "", true},
+		{"canary disabled and header", false, 0, "X-Canary", "", "", true},
+		{"canary disabled and cookie", false, 0, "", "canary_enabled", "", true},
+		{"canary disabled and query", false, 0, "", "", "canary", true},
+		{"canary enabled and weight", true, 20, "", "", "", false},
+		{"canary enabled and no weight", true, 0, "", "", "", false},
+		{"canary enabled by header", true, 20, "X-Canary", "", "", false},
+		{"canary enabled by cookie", true, 20, "", "canary_enabled", "", false},
+		{"canary enabled by query", true, 20, "", "", "canary", false},
 	}
 
 	for _, test := range tests {
@@ -123,6 +127,7 @@ func TestAnnotations(t *testing.T) {
 		data[parser.GetAnnotationWithPrefix("canary-weight")] = strconv.Itoa(test.canaryWeight)
 		data[parser.GetAnnotationWithPrefix("canary-by-header")] = test.canaryHeader
 		data[parser.GetAnnotationWithPrefix("canary-by-cookie")] = test.canaryCookie
+		data[parser.GetAnnotationWithPrefix("canary-by-query")] = test.canaryQuery
 
 		i, err := NewParser(&resolver.Mock{}).Parse(ing)
 		if test.expErr {
@@ -152,5 +157,93 @@ func TestAnnotations(t *testing.T) {
 		if canaryConfig.Cookie != test.canaryCookie {
 			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.canaryCookie, canaryConfig.Cookie)
 		}
+		if canaryConfig.Query != test.canaryQuery {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.canaryQuery, canaryConfig.Query)
+		}
+	}
+}
+
+func TestStickyAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("canary")] = "true"
+	data[parser.GetAnnotationWithPrefix("canary-weight")] = "10"
+	data[parser.GetAnnotationWithPrefix("canary-sticky")] = "true"
+	data[parser.GetAnnotationWithPrefix("canary-sticky-cookie")] = "canary_assignment"
+	data[parser.GetAnnotationWithPrefix("canary-sticky-max-age")] = "3600"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	canaryConfig, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+	if !canaryConfig.Sticky {
+		t.Errorf("expected sticky canary to be enabled")
+	}
+	if canaryConfig.StickyCookie != "canary_assignment" {
+		t.Errorf("expected %v but %v was returned", "canary_assignment", canaryConfig.StickyCookie)
+	}
+	if canaryConfig.StickyMaxAge != 3600 {
+		t.Errorf("expected %v but %v was returned", 3600, canaryConfig.StickyMaxAge)
+	}
+
+	data[parser.GetAnnotationWithPrefix("canary")] = "false"
+	ing.SetAnnotations(data)
+
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected error when sticky is configured but canary is not enabled")
+	}
+}
+
+func TestGeoAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("canary")] = "true"
+	data[parser.GetAnnotationWithPrefix("canary-by-geo-country")] = "de, fr,IT"
+	data[parser.GetAnnotationWithPrefix("canary-by-geo-continent")] = "eu"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	canaryConfig, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+	if !reflect.DeepEqual(canaryConfig.GeoCountries, []string{"DE", "FR", "IT"}) {
+		t.Errorf("expected %v but %v was returned", []string{"DE", "FR", "IT"}, canaryConfig.GeoCountries)
+	}
+	if !reflect.DeepEqual(canaryConfig.GeoContinents, []string{"EU"}) {
+		t.Errorf("expected %v but %v was returned", []string{"EU"}, canaryConfig.GeoContinents)
+	}
+
+	data[parser.GetAnnotationWithPrefix("canary-by-geo-country")] = "DEU"
+	data[parser.GetAnnotationWithPrefix("canary-by-geo-continent")] = "XX"
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	canaryConfig = i.(*Config)
+	if canaryConfig.GeoCountries != nil || canaryConfig.GeoContinents != nil {
+		t.Errorf("expected invalid geo codes to be ignored, got %v and %v", canaryConfig.GeoCountries, canaryConfig.GeoContinents)
+	}
+
+	data[parser.GetAnnotationWithPrefix("canary")] = "false"
+	data[parser.GetAnnotationWithPrefix("canary-by-geo-continent")] = "EU"
+	ing.SetAnnotations(data)
+
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected error when geo routing is configured but canary is not enabled")
 	}
 }
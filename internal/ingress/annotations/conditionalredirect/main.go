@@ -0,0 +1,305 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditionalredirect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const conditionalRedirectsAnnotation = "conditional-redirects"
+
+// maxRules is the maximum number of conditional redirects of a location
+const maxRules = 20
+
+var (
+	// headerRegex matches the names of the headers
+	headerRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	// nameRegex matches the names of the cookies and query parameters which
+	// are NGINX variable names
+	nameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// valueRegex matches the values compared with the requests, which are
+	// quoted and cannot contain variables
+	valueRegex = regexp.MustCompile(`^[^\s"'\\{};$]*$`)
+	// targetRegex matches the absolute URLs and the paths the requests are
+	// redirected to
+	targetRegex = regexp.MustCompile(`^(https?://|/)[^\s"'\\{};]*$`)
+	// variableRegex matches the variables of the targets
+	variableRegex = regexp.MustCompile(`\$([A-Za-z0-9_]*)`)
+)
+
+// targetVariables are the variables the targets can contain
+var targetVariables = map[string]bool{
+	"scheme":      true,
+	"host":        true,
+	"request_uri": true,
+	"uri":         true,
+	"args":        true,
+	"is_args":     true,
+}
+
+var validStatusCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+var conditionalRedirectsAnnotations = parser.Annotation{
+	Group: "redirect",
+	Annotations: parser.AnnotationFields{
+		conditionalRedirectsAnnotation: {
+			Validator: parser.ValidatorFunc(validateRules),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium, // Medium, as it allows arbitrary URLs that needs to be validated
+			Documentation: `This annotation redirects the requests matching a condition on a header, a cookie or a query parameter, as a JSON list of rules
+			applied in order. A rule has one of header, cookie or query, one of equals, notEquals, matches, notMatches or present, a target and a statusCode`,
+		},
+	},
+}
+
+// Rule redirects the requests whose variable matches the condition
+type Rule struct {
+	// Variable is the NGINX variable of the header, cookie or query
+	// parameter of the condition
+	Variable string `json:"variable"`
+	// Operator is the operator of the if condition, empty when the
+	// variable is only tested
+	Operator string `json:"operator,omitempty"`
+	// Value is the string or the regular expression the variable is
+	// compared with
+	Value      string `json:"value,omitempty"`
+	Target     string `json:"target"`
+	StatusCode int    `json:"statusCode"`
+}
+
+// Config describes the conditional redirects of a location, in order
+type Config struct {
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.Rules) != len(c2.Rules) {
+		return false
+	}
+	for i, rule := range c1.Rules {
+		if rule != c2.Rules[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rule is a rule of the annotation
+type rule struct {
+	Header     string  `json:"header"`
+	Cookie     string  `json:"cookie"`
+	Query      string  `json:"query"`
+	Equals     *string `json:"equals"`
+	NotEquals  *string `json:"notEquals"`
+	Matches    string  `json:"matches"`
+	NotMatches string  `json:"notMatches"`
+	IgnoreCase bool    `json:"ignoreCase"`
+	Present    *bool   `json:"present"`
+	Target     string  `json:"target"`
+	StatusCode int     `json:"statusCode"`
+}
+
+type conditionalRedirects struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new conditional redirects annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return conditionalRedirects{
+		r:                r,
+		annotationConfig: conditionalRedirectsAnnotations,
+	}
+}
+
+// parseRules returns the rules of the annotation
+func parseRules(value string) ([]Rule, error) {
+	var raw []rule
+	decoder := json.NewDecoder(bytes.NewBufferString(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid conditional redirects: %w", err)
+	}
+
+	if len(raw) > maxRules {
+		return nil, fmt.Errorf("more than %d conditional redirects are configured", maxRules)
+	}
+
+	rules := make([]Rule, 0, len(raw))
+	for i := range raw {
+		r, err := parseRule(&raw[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditional redirect %d: %w", i+1, err)
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func parseRule(raw *rule) (Rule, error) {
+	r := Rule{StatusCode: http.StatusFound}
+
+	sources := 0
+	if raw.Header != "" {
+		if !headerRegex.MatchString(raw.Header) {
+			return r, fmt.Errorf("invalid header name %q", raw.Header)
+		}
+		r.Variable = "$http_" + strings.ReplaceAll(strings.ToLower(raw.Header), "-", "_")
+		sources++
+	}
+	if raw.Cookie != "" {
+		if !nameRegex.MatchString(raw.Cookie) {
+			return r, fmt.Errorf("invalid cookie name %q", raw.Cookie)
+		}
+		r.Variable = "$cookie_" + raw.Cookie
+		sources++
+	}
+	if raw.Query != "" {
+		if !nameRegex.MatchString(raw.Query) {
+			return r, fmt.Errorf("invalid query parameter name %q", raw.Query)
+		}
+		r.Variable = "$arg_" + raw.Query
+		sources++
+	}
+	if sources != 1 {
+		return r, fmt.Errorf("a rule needs one of header, cookie or query")
+	}
+
+	conditions := 0
+	if raw.Equals != nil {
+		r.Operator, r.Value = "=", *raw.Equals
+		conditions++
+	}
+	if raw.NotEquals != nil {
+		r.Operator, r.Value = "!=", *raw.NotEquals
+		conditions++
+	}
+	if raw.Matches != "" {
+		r.Operator, r.Value = "~", raw.Matches
+		conditions++
+	}
+	if raw.NotMatches != "" {
+		r.Operator, r.Value = "!~", raw.NotMatches
+		conditions++
+	}
+	if raw.Present != nil {
+		// an empty variable is a missing header, cookie or query parameter
+		r.Operator, r.Value = "!=", ""
+		if !*raw.Present {
+			r.Operator = "="
+		}
+		conditions++
+	}
+	if conditions != 1 {
+		return r, fmt.Errorf("a rule needs one of equals, notEquals, matches, notMatches or present")
+	}
+
+	if raw.Matches != "" || raw.NotMatches != "" {
+		// the regular expressions are quoted
+		if strings.ContainsAny(r.Value, "\"\n\r") {
+			return r, fmt.Errorf("invalid regular expression %q", r.Value)
+		}
+		if _, err := regexp.Compile(r.Value); err != nil {
+			return r, fmt.Errorf("invalid regular expression %q: %w", r.Value, err)
+		}
+		if raw.IgnoreCase {
+			r.Operator += "*"
+		}
+	} else if !valueRegex.MatchString(r.Value) {
+		return r, fmt.Errorf("invalid value %q", r.Value)
+	}
+
+	if !targetRegex.MatchString(raw.Target) {
+		return r, fmt.Errorf("invalid target %q", raw.Target)
+	}
+	for _, match := range variableRegex.FindAllStringSubmatch(raw.Target, -1) {
+		if !targetVariables[match[1]] {
+			return r, fmt.Errorf("the variable %q of the target %q is not $scheme, $host, $request_uri, $uri, $args or $is_args", match[0], raw.Target)
+		}
+	}
+	r.Target = raw.Target
+
+	if raw.StatusCode != 0 {
+		if !validStatusCodes[raw.StatusCode] {
+			return r, fmt.Errorf("the status code %d is not 301, 302, 303, 307 or 308", raw.StatusCode)
+		}
+		r.StatusCode = raw.StatusCode
+	}
+
+	return r, nil
+}
+
+func validateRules(value string) error {
+	_, err := parseRules(value)
+	return err
+}
+
+// Parse parses the annotations contained in the ingress
+// to redirect the requests matching the conditions
+func (a conditionalRedirects) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	value, err := parser.GetStringAnnotation(conditionalRedirectsAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+		return config, nil
+	}
+
+	config.Rules, err = parseRules(value)
+	if err != nil {
+		return &Config{}, err
+	}
+
+	return config, nil
+}
+
+func (a conditionalRedirects) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a conditionalRedirects) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, conditionalRedirectsAnnotations.Annotations)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditionalredirect
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix(conditionalRedirectsAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		value     string
+		expected  *Config
+		expectErr bool
+	}{
+		{"", &Config{}, false},
+		{
+			`[{"header": "User-Agent", "matches": "Android|iPhone", "ignoreCase": true, "target": "https://m.example.com$request_uri"}]`,
+			&Config{Rules: []Rule{{Variable: "$http_user_agent", Operator: "~*", Value: "Android|iPhone", Target: "https://m.example.com$request_uri", StatusCode: 302}}},
+			false,
+		},
+		{
+			`[{"cookie": "consent", "present": false, "target": "/consent?return=$request_uri", "statusCode": 307},
+			  {"query": "lang", "equals": "fr", "target": "https://fr.example.com$uri", "statusCode": 301},
+			  {"header": "X-Beta", "notEquals": "", "target": "/beta"}]`,
+			&Config{Rules: []Rule{
+				{Variable: "$cookie_consent", Operator: "=", Target: "/consent?return=$request_uri", StatusCode: 307},
+				{Variable: "$arg_lang", Operator: "=", Value: "fr", Target: "https://fr.example.com$uri", StatusCode: 301},
+				{Variable: "$http_x_beta", Operator: "!=", Target: "/beta", StatusCode: 302},
+			}},
+			false,
+		},
+		{`[{"header": "Accept-Language", "notMatches": "^en", "target": "/intl"}]`,
+			&Config{Rules: []Rule{{Variable: "$http_accept_language", Operator: "!~", Value: "^en", Target: "/intl", StatusCode: 302}}},
+			false,
+		},
+		{`{"header": "User-Agent"}`, &Config{}, true},
+		{`[{"header": "User-Agent", "cookie": "consent", "present": true, "target": "/"}]`, &Config{}, true},
+		{`[{"header": "User-Agent", "target": "/"}]`, &Config{}, true},
+		{`[{"header": "User-Agent", "equals": "a", "present": true, "target": "/"}]`, &Config{}, true},
+		{`[{"header": "User Agent", "present": true, "target": "/"}]`, &Config{}, true},
+		{`[{"cookie": "consent-given", "present": true, "target": "/"}]`, &Config{}, true},
+		{`[{"query": "q", "equals": "$host", "target": "/"}]`, &Config{}, true},
+		{`[{"query": "q", "matches": "(unclosed", "target": "/"}]`, &Config{}, true},
+		{`[{"query": "q", "matches": "a\"; return 200 \"", "target": "/"}]`, &Config{}, true},
+		{`[{"query": "q", "present": true, "target": "javascript:alert(1)"}]`, &Config{}, true},
+		{`[{"query": "q", "present": true, "target": "/$http_authorization"}]`, &Config{}, true},
+		{`[{"query": "q", "present": true, "target": "/; return 200"}]`, &Config{}, true},
+		{`[{"query": "q", "present": true, "target": "/", "statusCode": 200}]`, &Config{}, true},
+		{`[{"query": "q", "present": true, "target": "/", "code": 301}]`, &Config{}, true},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		annotations := map[string]string{}
+		if testCase.value != "" {
+			annotations[annotation] = testCase.value
+		}
+		ing.SetAnnotations(annotations)
+
+		result, err := ap.Parse(ing)
+		if testCase.expectErr && err == nil {
+			t.Errorf("expected an error with the annotation %v", testCase.value)
+		}
+		if !testCase.expectErr && err != nil {
+			t.Errorf("unexpected error with the annotation %v: %v", testCase.value, err)
+		}
+
+		config, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but returned %T", result)
+		}
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %+v but returned %+v with the annotation %v", testCase.expected, config, testCase.value)
+		}
+	}
+}
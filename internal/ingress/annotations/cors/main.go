@@ -17,11 +17,13 @@ limitations under the License.
 package cors
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
 	networking "k8s.io/api/networking/v1"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
@@ -52,6 +54,15 @@ var (
 	// Expose Headers must contain valid values only (*, X-HEADER12, X-ABC)
 	// May contain or not spaces between each Header
 	corsExposeHeadersRegex = regexp.MustCompile(`^(([A-Za-z0-9\-\_]+|\*),?\s?)+$`)
+
+	// policyOriginRegex matches the serialized origins of the browsers, a
+	// lowercase scheme and host without path or trailing slash
+	policyOriginRegex = regexp.MustCompile(`^https?://[a-z0-9]([a-z0-9\-.]*[a-z0-9])?(:\d{1,5})?$`)
+	// policyOriginPatternRegex matches the characters allowed in the origin
+	// regexes of a policy, which are written between double quotes in nginx.conf
+	policyOriginPatternRegex = regexp.MustCompile(`^\^[A-Za-z0-9\\.\-_:/?*+()\[\]{}|,^$]+\$$`)
+	policyMethodRegex        = regexp.MustCompile(`^[A-Z]+$`)
+	policyHeaderRegex        = regexp.MustCompile(`^[A-Za-z0-9\-_]+$`)
 )
 
 const (
@@ -62,6 +73,7 @@ const (
 	corsAllowCredentialsAnnotation = "cors-allow-credentials" //#nosec G101
 	corsExposeHeadersAnnotation    = "cors-expose-headers"
 	corsMaxAgeAnnotation           = "cors-max-age"
+	corsPolicyAnnotation           = "cors-policy"
 )
 
 var corsAnnotation = parser.Annotation{
@@ -114,6 +126,14 @@ var corsAnnotation = parser.Annotation{
 			Risk:          parser.AnnotationRiskLow,
 			Documentation: `This annotation controls how long, in seconds, preflight requests can be cached.`,
 		},
+		corsPolicyAnnotation: {
+			Validator: parser.ValidatorFunc(validatePolicy),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation enables CORS with a policy written in YAML or JSON, replacing the other cors annotations.
+			The allowOrigins are matched exactly and the allowOriginRegexes must be anchored with ^ and $. The policy also sets the allowMethods,
+			allowHeaders, exposeHeaders, allowCredentials, maxAge and allowPrivateNetwork of the responses.`,
+		},
 	},
 }
 
@@ -124,13 +144,30 @@ type cors struct {
 
 // Config contains the Cors configuration to be used in the Ingress
 type Config struct {
-	CorsEnabled          bool     `json:"corsEnabled"`
-	CorsAllowOrigin      []string `json:"corsAllowOrigin"`
-	CorsAllowMethods     string   `json:"corsAllowMethods"`
-	CorsAllowHeaders     string   `json:"corsAllowHeaders"`
-	CorsAllowCredentials bool     `json:"corsAllowCredentials"`
-	CorsExposeHeaders    string   `json:"corsExposeHeaders"`
-	CorsMaxAge           int      `json:"corsMaxAge"`
+	CorsEnabled bool `json:"corsEnabled"`
+	// CorsAllowOrigin contains the origins matched exactly, or only *
+	CorsAllowOrigin []string `json:"corsAllowOrigin"`
+	// CorsAllowOriginRegex contains the anchored regexes matching the other
+	// allowed origins
+	CorsAllowOriginRegex    []string `json:"corsAllowOriginRegex,omitempty"`
+	CorsAllowMethods        string   `json:"corsAllowMethods"`
+	CorsAllowHeaders        string   `json:"corsAllowHeaders"`
+	CorsAllowCredentials    bool     `json:"corsAllowCredentials"`
+	CorsExposeHeaders       string   `json:"corsExposeHeaders"`
+	CorsMaxAge              int      `json:"corsMaxAge"`
+	CorsAllowPrivateNetwork bool     `json:"corsAllowPrivateNetwork"`
+}
+
+// policy is the value of the cors-policy annotation
+type policy struct {
+	AllowOrigins        []string `json:"allowOrigins,omitempty"`
+	AllowOriginRegexes  []string `json:"allowOriginRegexes,omitempty"`
+	AllowMethods        []string `json:"allowMethods,omitempty"`
+	AllowHeaders        []string `json:"allowHeaders,omitempty"`
+	ExposeHeaders       []string `json:"exposeHeaders,omitempty"`
+	AllowCredentials    bool     `json:"allowCredentials,omitempty"`
+	MaxAge              *int     `json:"maxAge,omitempty"`
+	AllowPrivateNetwork bool     `json:"allowPrivateNetwork,omitempty"`
 }
 
 // NewParser creates a new CORS annotation parser
@@ -167,6 +204,9 @@ func (c1 *Config) Equal(c2 *Config) bool {
 	if c1.CorsEnabled != c2.CorsEnabled {
 		return false
 	}
+	if c1.CorsAllowPrivateNetwork != c2.CorsAllowPrivateNetwork {
+		return false
+	}
 
 	if len(c1.CorsAllowOrigin) != len(c2.CorsAllowOrigin) {
 		return false
@@ -178,13 +218,140 @@ func (c1 *Config) Equal(c2 *Config) bool {
 		}
 	}
 
+	if len(c1.CorsAllowOriginRegex) != len(c2.CorsAllowOriginRegex) {
+		return false
+	}
+
+	for i, v := range c1.CorsAllowOriginRegex {
+		if v != c2.CorsAllowOriginRegex[i] {
+			return false
+		}
+	}
+
 	return true
 }
 
+// parsePolicy parses and validates the value of the cors-policy annotation
+func parsePolicy(value string) (*policy, error) {
+	p := &policy{}
+	if err := yaml.UnmarshalStrict([]byte(value), p); err != nil {
+		return nil, fmt.Errorf("could not parse policy: %w", err)
+	}
+
+	if len(p.AllowOrigins) == 0 && len(p.AllowOriginRegexes) == 0 {
+		return nil, fmt.Errorf("the policy does not allow any origin")
+	}
+
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			if len(p.AllowOrigins) > 1 || len(p.AllowOriginRegexes) > 0 {
+				return nil, fmt.Errorf("the origin * cannot be combined with other origins")
+			}
+			if p.AllowCredentials {
+				return nil, fmt.Errorf("the origin * cannot be allowed with credentials")
+			}
+			continue
+		}
+		if !policyOriginRegex.MatchString(origin) {
+			return nil, fmt.Errorf("%q is not an origin like https://example.com or https://example.com:8443", origin)
+		}
+	}
+
+	for _, pattern := range p.AllowOriginRegexes {
+		if !policyOriginPatternRegex.MatchString(pattern) {
+			return nil, fmt.Errorf("the origin regex %q must start with ^, end with $ and contain no quote or space", pattern)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid origin regex %q: %w", pattern, err)
+		}
+	}
+
+	for _, method := range p.AllowMethods {
+		if method != "*" && !policyMethodRegex.MatchString(method) {
+			return nil, fmt.Errorf("%q is not an uppercase method", method)
+		}
+	}
+
+	for _, headers := range [][]string{p.AllowHeaders, p.ExposeHeaders} {
+		for _, header := range headers {
+			if header == "*" {
+				// the browsers take * as a header name in the credentialed requests
+				if p.AllowCredentials {
+					return nil, fmt.Errorf("the header * cannot be allowed or exposed with credentials")
+				}
+				continue
+			}
+			if !policyHeaderRegex.MatchString(header) {
+				return nil, fmt.Errorf("%q is not a header name", header)
+			}
+		}
+	}
+
+	if p.MaxAge != nil && *p.MaxAge < 0 {
+		return nil, fmt.Errorf("the maxAge cannot be negative")
+	}
+
+	return p, nil
+}
+
+func validatePolicy(value string) error {
+	_, err := parsePolicy(value)
+	return err
+}
+
+// policyConfig returns the configuration of a valid policy
+func policyConfig(p *policy) *Config {
+	config := &Config{
+		CorsEnabled:             true,
+		CorsAllowOrigin:         p.AllowOrigins,
+		CorsAllowOriginRegex:    p.AllowOriginRegexes,
+		CorsAllowMethods:        defaultCorsMethods,
+		CorsAllowHeaders:        defaultCorsHeaders,
+		CorsAllowCredentials:    p.AllowCredentials,
+		CorsExposeHeaders:       strings.Join(p.ExposeHeaders, ", "),
+		CorsMaxAge:              defaultCorsMaxAge,
+		CorsAllowPrivateNetwork: p.AllowPrivateNetwork,
+	}
+	if config.CorsAllowOrigin == nil {
+		config.CorsAllowOrigin = []string{}
+	}
+	if len(p.AllowMethods) > 0 {
+		config.CorsAllowMethods = strings.Join(p.AllowMethods, ", ")
+	}
+	if len(p.AllowHeaders) > 0 {
+		config.CorsAllowHeaders = strings.Join(p.AllowHeaders, ", ")
+	}
+	if p.MaxAge != nil {
+		config.CorsMaxAge = *p.MaxAge
+	}
+
+	return config
+}
+
+// wildcardOriginRegex returns the anchored regex of an origin of the
+// cors-allow-origin annotation with a wildcard subdomain, which matches a
+// single label
+func wildcardOriginRegex(origin string) string {
+	return "^" + strings.Replace(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[A-Za-z0-9\-]+`, 1) + "$"
+}
+
 // Parse parses the annotations contained in the ingress
 // rule used to indicate if the location/s should allows CORS
 func (c cors) Parse(ing *networking.Ingress) (interface{}, error) {
 	var err error
+
+	rawPolicy, err := parser.GetStringAnnotation(corsPolicyAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return nil, err
+	}
+	if rawPolicy != "" {
+		p, policyErr := parsePolicy(rawPolicy)
+		if policyErr != nil {
+			return nil, errors.NewLocationDenied(fmt.Sprintf("invalid cors-policy: %v", policyErr))
+		}
+		return policyConfig(p), nil
+	}
+
 	config := &Config{}
 
 	config.CorsEnabled, err = parser.GetBoolAnnotation(corsEnableAnnotation, ing, c.annotationConfig.Annotations)
@@ -214,8 +381,11 @@ func (c cors) Parse(ing *networking.Ingress) (interface{}, error) {
 				klog.Errorf("Error parsing cors-allow-origin parameters. Supplied incorrect origin: %s. Skipping.", origin)
 				continue
 			}
+			if strings.Contains(origin, "*") {
+				config.CorsAllowOriginRegex = append(config.CorsAllowOriginRegex, wildcardOriginRegex(origin))
+				continue
+			}
 			config.CorsAllowOrigin = append(config.CorsAllowOrigin, origin)
-			klog.Infof("Current config.corsAllowOrigin %v", config.CorsAllowOrigin)
 		}
 	} else {
 		if errors.IsValidationError(err) {
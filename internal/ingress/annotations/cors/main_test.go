@@ -203,3 +203,171 @@ func TestIngresCorsConfigAllowOriginWithTrailingComma(t *testing.T) {
 		t.Errorf("expected %v but returned %v", expectedCorsAllowOrigins, nginxCors.CorsAllowOrigin)
 	}
 }
+
+func TestIngressCorsConfigWildcardOrigin(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(corsEnableAnnotation)] = "true"
+	data[parser.GetAnnotationWithPrefix(corsAllowOriginAnnotation)] = "https://origin.test.com, https://*.Test.com:4443"
+	ing.SetAnnotations(data)
+
+	corst, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("error parsing annotations: %v", err)
+	}
+
+	nginxCors, ok := corst.(*Config)
+	if !ok {
+		t.Errorf("expected a Config type but returned %t", corst)
+	}
+
+	expectedCorsAllowOrigins := []string{"https://origin.test.com"}
+	if !reflect.DeepEqual(nginxCors.CorsAllowOrigin, expectedCorsAllowOrigins) {
+		t.Errorf("expected %v but returned %v", expectedCorsAllowOrigins, nginxCors.CorsAllowOrigin)
+	}
+
+	expectedCorsAllowOriginRegexes := []string{`^https://[A-Za-z0-9\-]+\.test\.com:4443$`}
+	if !reflect.DeepEqual(nginxCors.CorsAllowOriginRegex, expectedCorsAllowOriginRegexes) {
+		t.Errorf("expected %v but returned %v", expectedCorsAllowOriginRegexes, nginxCors.CorsAllowOriginRegex)
+	}
+}
+
+func TestIngressCorsPolicy(t *testing.T) {
+	maxAge := 600
+
+	tests := []struct {
+		description string
+		policy      string
+		expected    *Config
+		expectErr   bool
+	}{
+		{
+			description: "yaml policy",
+			policy: `allowOrigins:
+- https://app.example.com
+- https://admin.example.com:8443
+allowOriginRegexes:
+- ^https://[a-z0-9-]+\.preview\.example\.com$
+allowMethods: [GET, POST]
+allowHeaders: [Content-Type, Authorization]
+exposeHeaders: [X-Request-ID]
+allowCredentials: true
+maxAge: 600
+allowPrivateNetwork: true`,
+			expected: &Config{
+				CorsEnabled:             true,
+				CorsAllowOrigin:         []string{"https://app.example.com", "https://admin.example.com:8443"},
+				CorsAllowOriginRegex:    []string{`^https://[a-z0-9-]+\.preview\.example\.com$`},
+				CorsAllowMethods:        "GET, POST",
+				CorsAllowHeaders:        "Content-Type, Authorization",
+				CorsExposeHeaders:       "X-Request-ID",
+				CorsAllowCredentials:    true,
+				CorsMaxAge:              maxAge,
+				CorsAllowPrivateNetwork: true,
+			},
+		},
+		{
+			description: "json policy with defaults",
+			policy:      `{"allowOrigins": ["*"]}`,
+			expected: &Config{
+				CorsEnabled:      true,
+				CorsAllowOrigin:  []string{"*"},
+				CorsAllowMethods: defaultCorsMethods,
+				CorsAllowHeaders: defaultCorsHeaders,
+				CorsMaxAge:       defaultCorsMaxAge,
+			},
+		},
+		{
+			description: "unknown field",
+			policy:      `{"allowOrigin": ["https://app.example.com"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "no origin",
+			policy:      `{"allowMethods": ["GET"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "origin with a path",
+			policy:      `{"allowOrigins": ["https://app.example.com/"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "origin with a wildcard",
+			policy:      `{"allowOrigins": ["https://*.example.com"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "any origin with credentials",
+			policy:      `{"allowOrigins": ["*"], "allowCredentials": true}`,
+			expectErr:   true,
+		},
+		{
+			description: "any origin with other origins",
+			policy:      `{"allowOrigins": ["*", "https://app.example.com"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "unanchored origin regex",
+			policy:      `{"allowOriginRegexes": ["https://.*\\.example\\.com"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "origin regex with a quote",
+			policy:      `{"allowOriginRegexes": ["^https://\"$"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "invalid origin regex",
+			policy:      `{"allowOriginRegexes": ["^https://(a$"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "lowercase method",
+			policy:      `{"allowOrigins": ["https://app.example.com"], "allowMethods": ["get"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "any header with credentials",
+			policy:      `{"allowOrigins": ["https://app.example.com"], "allowHeaders": ["*"], "allowCredentials": true}`,
+			expectErr:   true,
+		},
+		{
+			description: "invalid header",
+			policy:      `{"allowOrigins": ["https://app.example.com"], "exposeHeaders": ["$upstream_addr"]}`,
+			expectErr:   true,
+		},
+		{
+			description: "negative max age",
+			policy:      `{"allowOrigins": ["https://app.example.com"], "maxAge": -1}`,
+			expectErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ing := buildIngress()
+			ing.SetAnnotations(map[string]string{
+				parser.GetAnnotationWithPrefix(corsPolicyAnnotation): test.policy,
+				// the other annotations are ignored when a policy is set
+				parser.GetAnnotationWithPrefix(corsAllowOriginAnnotation): "https://other.example.com",
+			})
+
+			corst, err := NewParser(&resolver.Mock{}).Parse(ing)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("expected an error but returned %v", corst)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(corst, test.expected) {
+				t.Errorf("expected %+v but returned %+v", test.expected, corst)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csrf
+
+import (
+	"regexp"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	enableCSRFProtectionAnnotation = "enable-csrf-protection"
+	csrfHeaderNameAnnotation       = "csrf-header-name"
+	csrfTrustedOriginsAnnotation   = "csrf-trusted-origins"
+)
+
+const defaultHeaderName = "X-CSRF-Token"
+
+var (
+	headerNameRegex = regexp.MustCompile(`^[A-Za-z0-9\-]+$`)
+	// trustedOriginsRegex matches a comma separated list of origins like
+	// https://app.example.com or https://app.example.com:8443
+	trustedOriginsRegex = regexp.MustCompile(`^(https?://[A-Za-z0-9\-.]+(:\d+)?,?)+$`)
+)
+
+var csrfAnnotations = parser.Annotation{
+	Group: "csrf",
+	Annotations: parser.AnnotationFields{
+		enableCSRFProtectionAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables the CSRF protection of this location. The clients are issued a signed token in a cookie,
+			the requests with an unsafe method must come from the origin of the Ingress or a trusted origin and send the token back`,
+		},
+		csrfHeaderNameAnnotation: {
+			Validator:     parser.ValidateRegex(headerNameRegex, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the request header sending the CSRF token back. Defaults to X-CSRF-Token`,
+		},
+		csrfTrustedOriginsAnnotation: {
+			Validator: parser.ValidateRegex(trustedOriginsRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the comma separated list of the other origins allowed to send unsafe requests,
+			like https://app.example.com`,
+		},
+	},
+}
+
+// Config describes the CSRF protection of a location
+type Config struct {
+	Enabled        bool     `json:"enabled"`
+	HeaderName     string   `json:"headerName,omitempty"`
+	TrustedOrigins []string `json:"trustedOrigins,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.HeaderName != c2.HeaderName {
+		return false
+	}
+	if len(c1.TrustedOrigins) != len(c2.TrustedOrigins) {
+		return false
+	}
+	for i, origin := range c1.TrustedOrigins {
+		if origin != c2.TrustedOrigins[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type csrf struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new CSRF protection annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return csrf{
+		r:                r,
+		annotationConfig: csrfAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the CSRF protection of the locations
+func (c csrf) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	enabled, err := parser.GetBoolAnnotation(enableCSRFProtectionAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil || !enabled {
+		return config, nil
+	}
+	config.Enabled = true
+
+	config.HeaderName, err = parser.GetStringAnnotation(csrfHeaderNameAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to %s", csrfHeaderNameAnnotation, defaultHeaderName)
+		}
+		config.HeaderName = defaultHeaderName
+	}
+
+	origins, err := parser.GetStringAnnotation(csrfTrustedOriginsAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, no other origin is trusted", csrfTrustedOriginsAnnotation)
+		}
+		return config, nil
+	}
+
+	// the browsers send the origins in lowercase
+	for _, origin := range strings.Split(origins, ",") {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin != "" {
+			config.TrustedOrigins = append(config.TrustedOrigins, origin)
+		}
+	}
+
+	return config, nil
+}
+
+func (c csrf) GetDocumentation() parser.AnnotationFields {
+	return c.annotationConfig.Annotations
+}
+
+func (c csrf) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(c.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, csrfAnnotations.Annotations)
+}
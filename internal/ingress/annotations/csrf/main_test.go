@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csrf
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix(enableCSRFProtectionAnnotation)
+	headerName := parser.GetAnnotationWithPrefix(csrfHeaderNameAnnotation)
+	trustedOrigins := parser.GetAnnotationWithPrefix(csrfTrustedOriginsAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *Config
+	}{
+		{
+			name:        "without annotations",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			name: "disabled",
+			annotations: map[string]string{
+				enable:     "false",
+				headerName: "X-XSRF-Token",
+			},
+			expected: &Config{},
+		},
+		{
+			name: "enabled with the defaults",
+			annotations: map[string]string{
+				enable: "true",
+			},
+			expected: &Config{Enabled: true, HeaderName: defaultHeaderName},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				enable:         "true",
+				headerName:     "X-XSRF-Token",
+				trustedOrigins: "https://App.example.com, https://admin.example.com:8443,",
+			},
+			expected: &Config{
+				Enabled:        true,
+				HeaderName:     "X-XSRF-Token",
+				TrustedOrigins: []string{"https://app.example.com", "https://admin.example.com:8443"},
+			},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				enable:         "true",
+				headerName:     "X-Token: $cookie_session",
+				trustedOrigins: "app.example.com/path",
+			},
+			expected: &Config{Enabled: true, HeaderName: defaultHeaderName},
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, err := ap.Parse(ing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but returned %T", result)
+			}
+			if !config.Equal(testCase.expected) {
+				t.Errorf("expected %+v but returned %+v", testCase.expected, config)
+			}
+		})
+	}
+}
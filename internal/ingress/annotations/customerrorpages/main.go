@@ -0,0 +1,258 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customerrorpages
+
+import (
+	"crypto/sha1" //nolint:gosec // Not used for security, only to name the pages directories
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+const customErrorPagesAnnotation = "custom-error-pages"
+
+// maxPagesSize is the maximum total size of the pages of a ConfigMap
+const maxPagesSize = 256 * 1024
+
+var (
+	// configMapNameRegex matches the name of a ConfigMap, optionally with its namespace
+	configMapNameRegex = regexp.MustCompile(`^([a-z0-9][a-z0-9\-]*/)?[a-z0-9][a-z0-9\-.]*$`)
+	// pageKeyRegex matches the keys of the pages of the ConfigMaps, the
+	// status code and the format of the page
+	pageKeyRegex = regexp.MustCompile(`^([45][0-9]{2})\.(html|json)$`)
+)
+
+var customErrorPagesAnnotations = parser.Annotation{
+	Group: "backend",
+	Annotations: parser.AnnotationFields{
+		customErrorPagesAnnotation: {
+			Validator: parser.ValidateRegex(configMapNameRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium, // Medium, as the pages are served as written in the ConfigMap
+			Documentation: `This annotation references a ConfigMap whose keys are error pages named after their status code and format, like 404.html
+			or 503.json. NGINX serves the pages of the ConfigMap instead of the errors of the locations of the Ingress`,
+		},
+	},
+}
+
+// Page describes the formats of the error page of a status code
+type Page struct {
+	Code int  `json:"code"`
+	HTML bool `json:"html"`
+	JSON bool `json:"json"`
+}
+
+// Config describes the error pages of a location
+type Config struct {
+	// ConfigMap is the namespace/name of the ConfigMap of the pages
+	ConfigMap string `json:"configMap,omitempty"`
+	// Directory contains the pages of the ConfigMap
+	Directory string `json:"directory,omitempty"`
+	// Pages are the error pages, sorted by status code
+	Pages []Page `json:"pages,omitempty"`
+	// Contents are the pages of the ConfigMap by key, written to Directory
+	// when the configuration is synced
+	Contents map[string]string `json:"-"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.ConfigMap != c2.ConfigMap {
+		return false
+	}
+	if c1.Directory != c2.Directory {
+		return false
+	}
+	if len(c1.Pages) != len(c2.Pages) {
+		return false
+	}
+	for i, page := range c1.Pages {
+		if page != c2.Pages[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type customErrorPages struct {
+	r                resolver.Resolver
+	pagesDirectory   string
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new custom error pages annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return customErrorPages{
+		r:                r,
+		pagesDirectory:   file.ErrorPagesDirectory,
+		annotationConfig: customErrorPagesAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress to serve the
+// error pages of a ConfigMap
+func (a customErrorPages) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	cm, err := parser.GetStringAnnotation(customErrorPagesAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if ing_errors.IsValidationError(err) {
+			return config, err
+		}
+		return config, nil
+	}
+
+	cmns, cmn, err := cache.SplitMetaNamespaceKey(cm)
+	if err != nil {
+		return config, ing_errors.NewLocationDenied(fmt.Sprintf("error reading configmap name from annotation: %v", err))
+	}
+
+	if cmns != "" && cmns != ing.Namespace && !a.r.GetSecurityConfiguration().AllowCrossNamespaceResources {
+		return config, ing_errors.NewLocationDenied("cross namespace error pages are not allowed")
+	}
+	if cmns == "" {
+		cmns = ing.Namespace
+	}
+
+	key := fmt.Sprintf("%v/%v", cmns, cmn)
+	cmap, err := a.r.GetConfigMap(key)
+	if err != nil {
+		return config, ing_errors.NewLocationDenied(fmt.Sprintf("unexpected error reading configmap %s: %v", key, err))
+	}
+
+	// the pages are validated before they are written, the Ingresses
+	// referencing invalid pages are rejected by the admission webhook
+	pages := map[int]*Page{}
+	size := 0
+	for name, content := range cmap.Data {
+		match := pageKeyRegex.FindStringSubmatch(name)
+		if match == nil {
+			return config, ing_errors.NewValidationError(customErrorPagesAnnotation)
+		}
+		size += len(content)
+
+		code, _ := strconv.Atoi(match[1])
+		page, ok := pages[code]
+		if !ok {
+			page = &Page{Code: code}
+			pages[code] = page
+		}
+		if match[2] == "html" {
+			page.HTML = true
+		} else {
+			page.JSON = true
+		}
+	}
+	if len(pages) == 0 || size > maxPagesSize {
+		return config, ing_errors.NewValidationError(customErrorPagesAnnotation)
+	}
+
+	for _, page := range pages {
+		config.Pages = append(config.Pages, *page)
+	}
+	sort.Slice(config.Pages, func(i, j int) bool {
+		return config.Pages[i].Code < config.Pages[j].Code
+	})
+
+	config.Directory = pagesDirectory(a.pagesDirectory, key, cmap.Data)
+	config.Contents = cmap.Data
+	config.ConfigMap = key
+
+	return config, nil
+}
+
+// pagesDirectory returns the directory of the pages of the ConfigMap, named
+// after the ConfigMap and the checksum of its pages: a change of the pages
+// changes the directory of the locations
+func pagesDirectory(directory, key string, pages map[string]string) string {
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	//nolint:gosec // Not used for security, only to name the pages directories
+	checksum := sha1.New()
+	for _, name := range names {
+		fmt.Fprintf(checksum, "%v\x00%v\x00", name, pages[name])
+	}
+
+	return fmt.Sprintf("%v/%v-%x", directory, strings.ReplaceAll(key, "/", "-"), checksum.Sum(nil)[:6])
+}
+
+// WritePages writes the pages of a location when their directory is missing,
+// before the configuration referencing them is tested. The pages are written
+// to a temporary directory renamed to the directory, NGINX never reads a
+// partial directory.
+func WritePages(config *Config) error {
+	if config == nil || config.Directory == "" {
+		return nil
+	}
+	if _, err := os.Stat(config.Directory); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Directory), file.ReadWriteByUser); err != nil {
+		return fmt.Errorf("unexpected error creating the directory of the error pages: %w", err)
+	}
+	tmpDirectory, err := os.MkdirTemp(filepath.Dir(config.Directory), filepath.Base(config.Directory)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unexpected error creating the directory of the error pages: %w", err)
+	}
+	defer os.RemoveAll(tmpDirectory)
+
+	for name, content := range config.Contents {
+		filename := filepath.Join(tmpDirectory, name)
+		if err := os.WriteFile(filename, []byte(content), file.ReadWriteByUser); err != nil {
+			return fmt.Errorf("unexpected error writing the error page %v: %w", name, err)
+		}
+	}
+
+	if err := os.Rename(tmpDirectory, config.Directory); err != nil {
+		return fmt.Errorf("unexpected error writing the error pages of %v: %w", config.ConfigMap, err)
+	}
+
+	return nil
+}
+
+func (a customErrorPages) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a customErrorPages) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, customErrorPagesAnnotations.Annotations)
+}
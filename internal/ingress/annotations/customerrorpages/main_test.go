@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customerrorpages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix(customErrorPagesAnnotation)
+
+	r := &resolver.Mock{
+		ConfigMaps: map[string]*api.ConfigMap{
+			"default/error-pages": {
+				Data: map[string]string{
+					"404.html": "<h1>Not found</h1>",
+					"503.html": "<h1>Back soon</h1>",
+					"503.json": `{"error": "unavailable"}`,
+				},
+			},
+			"default/invalid-keys": {
+				Data: map[string]string{"200.html": "<h1>OK</h1>"},
+			},
+			"default/empty": {},
+		},
+	}
+	directory := t.TempDir()
+	ap := customErrorPages{r: r, pagesDirectory: directory, annotationConfig: customErrorPagesAnnotations}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	ing.SetAnnotations(map[string]string{})
+	result, err := ap.Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.(*Config).Equal(&Config{}) {
+		t.Errorf("expected an empty configuration but returned %+v", result)
+	}
+
+	ing.SetAnnotations(map[string]string{annotation: "error-pages"})
+	result, err = ap.Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, ok := result.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type but returned %T", result)
+	}
+	expected := &Config{
+		ConfigMap: "default/error-pages",
+		Directory: config.Directory,
+		Pages: []Page{
+			{Code: 404, HTML: true},
+			{Code: 503, HTML: true, JSON: true},
+		},
+	}
+	if !config.Equal(expected) {
+		t.Errorf("expected %+v but returned %+v", expected, config)
+	}
+	if filepath.Dir(config.Directory) != directory {
+		t.Errorf("expected the pages to be written in %v but returned %v", directory, config.Directory)
+	}
+	if _, err := os.Stat(config.Directory); !os.IsNotExist(err) {
+		t.Errorf("expected the pages not to be written while parsing the annotations but returned %v", err)
+	}
+
+	if err := WritePages(config); err != nil {
+		t.Fatalf("unexpected error writing the pages: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.Directory, "503.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading the page: %v", err)
+	}
+	if string(content) != r.ConfigMaps["default/error-pages"].Data["503.json"] {
+		t.Errorf("unexpected content of the page: %v", string(content))
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected only the directory of the pages to be left but returned %v, %v", entries, err)
+	}
+	if err := WritePages(config); err != nil {
+		t.Errorf("unexpected error writing the pages again: %v", err)
+	}
+
+	invalidCases := map[string]string{
+		"invalid page names":     "invalid-keys",
+		"no pages":               "empty",
+		"missing ConfigMap":      "missing",
+		"cross namespace":        "other/error-pages",
+		"invalid ConfigMap name": "error-pages;",
+	}
+	for name, value := range invalidCases {
+		ing.SetAnnotations(map[string]string{annotation: value})
+		if _, err := ap.Parse(ing); !errors.IsValidationError(err) && !errors.IsLocationDenied(err) {
+			t.Errorf("%v: expected an error but returned %v", name, err)
+		}
+	}
+}
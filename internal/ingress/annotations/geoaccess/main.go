@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoaccess
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	geoAllowCountriesAnnotation = "geo-allow-countries"
+	geoDenyCountriesAnnotation  = "geo-deny-countries"
+	geoAllowASNsAnnotation      = "geo-allow-asns"
+	geoDenyASNsAnnotation       = "geo-deny-asns"
+	geoDenyStatusCodeAnnotation = "geo-deny-status-code"
+)
+
+const defaultStatusCode = 403
+
+var (
+	// countriesRegex matches a comma separated list of ISO 3166-1 alpha-2 country codes
+	countriesRegex = regexp.MustCompile(`^(?i)[a-z]{2}(,[a-z]{2})*$`)
+	// asnsRegex matches a comma separated list of autonomous system numbers, with or without the AS prefix
+	asnsRegex = regexp.MustCompile(`^(?i)(as)?\d{1,10}(,(as)?\d{1,10})*$`)
+)
+
+var geoAccessAnnotations = parser.Annotation{
+	Group: "geoaccess",
+	Annotations: parser.AnnotationFields{
+		geoAllowCountriesAnnotation: {
+			Validator: parser.ValidateRegex(countriesRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium, // Failure on parsing this may cause undesired access
+			Documentation: `This annotation defines a comma separated list of ISO country codes, e.g. 'DE,FR'. Only the requests whose client address is located in one of these countries
+			by the GeoIP2 databases are proxied, the others are denied`,
+		},
+		geoDenyCountriesAnnotation: {
+			Validator:     parser.ValidateRegex(countriesRegex, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskMedium, // Failure on parsing this may cause undesired access
+			Documentation: `This annotation defines a comma separated list of ISO country codes whose requests are denied`,
+		},
+		geoAllowASNsAnnotation: {
+			Validator: parser.ValidateRegex(asnsRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium, // Failure on parsing this may cause undesired access
+			Documentation: `This annotation defines a comma separated list of autonomous system numbers, e.g. '15169,AS8075'. Only the requests whose client address belongs to one of these
+			autonomous systems by the GeoIP2 ASN database are proxied, the others are denied`,
+		},
+		geoDenyASNsAnnotation: {
+			Validator:     parser.ValidateRegex(asnsRegex, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskMedium, // Failure on parsing this may cause undesired access
+			Documentation: `This annotation defines a comma separated list of autonomous system numbers whose requests are denied`,
+		},
+		geoDenyStatusCodeAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the status code of the responses to the denied requests, between 400 and 599. Defaults to 403`,
+		},
+	},
+}
+
+// Config describes the access to a location by the country and the
+// autonomous system of the clients
+type Config struct {
+	AllowCountries []string `json:"allowCountries,omitempty"`
+	DenyCountries  []string `json:"denyCountries,omitempty"`
+	AllowASNs      []string `json:"allowASNs,omitempty"`
+	DenyASNs       []string `json:"denyASNs,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+}
+
+// Enabled returns true when the access is restricted by a country or an
+// autonomous system
+func (c *Config) Enabled() bool {
+	return len(c.AllowCountries) > 0 || len(c.DenyCountries) > 0 || len(c.AllowASNs) > 0 || len(c.DenyASNs) > 0
+}
+
+func equalCodes(c1, c2 []string) bool {
+	if len(c1) != len(c2) {
+		return false
+	}
+	for i, code := range c1 {
+		if code != c2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.StatusCode != c2.StatusCode {
+		return false
+	}
+
+	return equalCodes(c1.AllowCountries, c2.AllowCountries) &&
+		equalCodes(c1.DenyCountries, c2.DenyCountries) &&
+		equalCodes(c1.AllowASNs, c2.AllowASNs) &&
+		equalCodes(c1.DenyASNs, c2.DenyASNs)
+}
+
+type geoAccess struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new country and ASN access annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return geoAccess{
+		r:                r,
+		annotationConfig: geoAccessAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress to allow or deny the
+// requests of the locations by the country and the autonomous system of the
+// clients
+func (a geoAccess) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	for _, codes := range []struct {
+		annotation string
+		target     *[]string
+		normalize  func(string) string
+	}{
+		{geoAllowCountriesAnnotation, &config.AllowCountries, strings.ToUpper},
+		{geoDenyCountriesAnnotation, &config.DenyCountries, strings.ToUpper},
+		{geoAllowASNsAnnotation, &config.AllowASNs, normalizeASN},
+		{geoDenyASNsAnnotation, &config.DenyASNs, normalizeASN},
+	} {
+		val, err := parser.GetStringAnnotation(codes.annotation, ing, a.annotationConfig.Annotations)
+		if err != nil {
+			if ing_errors.IsMissingAnnotations(err) {
+				continue
+			}
+			// the requests of an invalid list would otherwise be proxied
+			return config, ing_errors.LocationDeniedError{
+				Reason: fmt.Errorf("annotation %s: %w", codes.annotation, err),
+			}
+		}
+
+		for _, code := range strings.Split(val, ",") {
+			*codes.target = append(*codes.target, codes.normalize(strings.TrimSpace(code)))
+		}
+	}
+
+	if !config.Enabled() {
+		return config, nil
+	}
+
+	config.StatusCode = defaultStatusCode
+	code, err := parser.GetIntAnnotation(geoDenyStatusCodeAnnotation, ing, a.annotationConfig.Annotations)
+	if err == nil && code >= 400 && code <= 599 {
+		config.StatusCode = code
+	} else if err == nil || ing_errors.IsValidationError(err) {
+		klog.Warningf("%s is invalid, defaulting to %d", geoDenyStatusCodeAnnotation, defaultStatusCode)
+	}
+
+	return config, nil
+}
+
+// normalizeASN strips the AS prefix and the leading zeros of an autonomous
+// system number, the value of the $geoip2_asn variable
+func normalizeASN(asn string) string {
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asn), "AS"), 10, 64)
+	if err != nil {
+		return asn
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+func (a geoAccess) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a geoAccess) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, geoAccessAnnotations.Annotations)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoaccess
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *networking.Ingress {
+	return &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{
+			DefaultBackend: &networking.IngressBackend{
+				Service: &networking.IngressServiceBackend{
+					Name: "default-backend",
+					Port: networking.ServiceBackendPort{
+						Number: 80,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		title       string
+		annotations map[string]string
+		expected    *Config
+		denied      bool
+	}{
+		{
+			title:       "no annotation",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			title: "countries",
+			annotations: map[string]string{
+				geoAllowCountriesAnnotation: "de, fr",
+				geoDenyCountriesAnnotation:  "RU",
+			},
+			expected: &Config{
+				AllowCountries: []string{"DE", "FR"},
+				DenyCountries:  []string{"RU"},
+				StatusCode:     403,
+			},
+		},
+		{
+			title: "autonomous systems and status code",
+			annotations: map[string]string{
+				geoAllowASNsAnnotation:      "15169,as8075",
+				geoDenyASNsAnnotation:       "AS064496",
+				geoDenyStatusCodeAnnotation: "451",
+			},
+			expected: &Config{
+				AllowASNs:  []string{"15169", "8075"},
+				DenyASNs:   []string{"64496"},
+				StatusCode: 451,
+			},
+		},
+		{
+			title: "invalid status code",
+			annotations: map[string]string{
+				geoDenyCountriesAnnotation:  "RU",
+				geoDenyStatusCodeAnnotation: "200",
+			},
+			expected: &Config{
+				DenyCountries: []string{"RU"},
+				StatusCode:    403,
+			},
+		},
+		{
+			title: "status code without rules",
+			annotations: map[string]string{
+				geoDenyStatusCodeAnnotation: "451",
+			},
+			expected: &Config{},
+		},
+		{
+			title: "invalid countries",
+			annotations: map[string]string{
+				geoAllowCountriesAnnotation: "DEU",
+			},
+			denied: true,
+		},
+		{
+			title: "invalid autonomous systems",
+			annotations: map[string]string{
+				geoDenyASNsAnnotation: "google",
+			},
+			denied: true,
+		},
+	}
+
+	ing := buildIngress()
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			data := map[string]string{}
+			for k, v := range test.annotations {
+				data[parser.GetAnnotationWithPrefix(k)] = v
+			}
+			ing.SetAnnotations(data)
+
+			i, err := NewParser(&resolver.Mock{}).Parse(ing)
+			if test.denied {
+				if !ing_errors.IsLocationDenied(err) {
+					t.Errorf("expected a location denied error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config, ok := i.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but %T was returned", i)
+			}
+			if !reflect.DeepEqual(config, test.expected) {
+				t.Errorf("expected %+v but got %+v", test.expected, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthcheck
+
+import (
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	healthCheckTypeAnnotation               = "health-check-type"
+	healthCheckPathAnnotation               = "health-check-path"
+	healthCheckIntervalAnnotation           = "health-check-interval"
+	healthCheckTimeoutAnnotation            = "health-check-timeout"
+	healthCheckHealthyThresholdAnnotation   = "health-check-healthy-threshold"
+	healthCheckUnhealthyThresholdAnnotation = "health-check-unhealthy-threshold"
+	healthCheckGRPCServiceAnnotation        = "health-check-grpc-service"
+
+	backendProtocolAnnotation = "backend-protocol"
+)
+
+const (
+	// TypeHTTP checks the endpoints sending a GET request to the health check path
+	TypeHTTP = "http"
+	// TypeTCP checks the endpoints opening a TCP connection
+	TypeTCP = "tcp"
+	// TypeGRPC checks the endpoints calling the grpc.health.v1.Health service
+	TypeGRPC = "grpc"
+)
+
+const (
+	defaultPath               = "/"
+	defaultInterval           = 5
+	defaultTimeout            = 1
+	defaultHealthyThreshold   = 2
+	defaultUnhealthyThreshold = 3
+)
+
+var healthCheckAnnotations = parser.Annotation{
+	Group: "backend",
+	Annotations: parser.AnnotationFields{
+		healthCheckTypeAnnotation: {
+			Validator: parser.ValidateOptions([]string{TypeHTTP, TypeTCP, TypeGRPC}, true, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables the active health checks of the endpoints of the backend.
+			Valid options are "http", "tcp" and "grpc". The grpc health checks require the GRPC backend protocol`,
+		},
+		healthCheckPathAnnotation: {
+			Validator:     parser.ValidateRegex(parser.URLIsValidRegex, true),
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the path requested by the http health checks (default "/")`,
+		},
+		healthCheckIntervalAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the time in seconds between two health checks of an endpoint (default 5)`,
+		},
+		healthCheckTimeoutAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the timeout in seconds of a health check (default 1)`,
+		},
+		healthCheckHealthyThresholdAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the number of consecutive successful health checks after which an unhealthy endpoint is healthy again (default 2)`,
+		},
+		healthCheckUnhealthyThresholdAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the number of consecutive failed health checks after which an endpoint is unhealthy (default 3)`,
+		},
+		healthCheckGRPCServiceAnnotation: {
+			Validator:     parser.ValidateRegex(parser.BasicCharsRegex, true),
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the service name sent by the grpc health checks. By default the health of the whole server is checked`,
+		},
+	},
+}
+
+// Config describes the active health checks of the endpoints of a backend
+type Config struct {
+	Type               string `json:"type"`
+	Path               string `json:"path"`
+	Interval           int    `json:"interval"`
+	Timeout            int    `json:"timeout"`
+	HealthyThreshold   int    `json:"healthyThreshold"`
+	UnhealthyThreshold int    `json:"unhealthyThreshold"`
+	GRPCService        string `json:"grpcService"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type healthCheck struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new active health check annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return healthCheck{
+		r:                r,
+		annotationConfig: healthCheckAnnotations,
+	}
+}
+
+func (h healthCheck) getPositiveInt(name string, ing *networking.Ingress, defaultValue int) int {
+	value, err := parser.GetIntAnnotation(name, ing, h.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to %d", name, defaultValue)
+		}
+		return defaultValue
+	}
+
+	if value <= 0 {
+		klog.Warningf("%s must be greater than 0, defaulting to %d", name, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the active health checks of the backends
+func (h healthCheck) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	checkType, err := parser.GetStringAnnotation(healthCheckTypeAnnotation, ing, h.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, health checks are disabled", healthCheckTypeAnnotation)
+			return config, nil
+		}
+		return config, err
+	}
+
+	if checkType == TypeGRPC {
+		backendProtocol := ing.GetAnnotations()[parser.GetAnnotationWithPrefix(backendProtocolAnnotation)]
+		if !strings.EqualFold(backendProtocol, "GRPC") {
+			klog.Warningf("%s %s requires the GRPC backend protocol, health checks are disabled", healthCheckTypeAnnotation, TypeGRPC)
+			return config, nil
+		}
+
+		config.GRPCService, err = parser.GetStringAnnotation(healthCheckGRPCServiceAnnotation, ing, h.annotationConfig.Annotations)
+		if err != nil {
+			if errors.IsValidationError(err) {
+				klog.Warningf("%s is invalid, checking the health of the whole server", healthCheckGRPCServiceAnnotation)
+			}
+			config.GRPCService = ""
+		}
+	}
+
+	config.Type = checkType
+
+	if checkType == TypeHTTP {
+		config.Path, err = parser.GetStringAnnotation(healthCheckPathAnnotation, ing, h.annotationConfig.Annotations)
+		if err != nil {
+			if errors.IsValidationError(err) {
+				klog.Warningf("%s is invalid, defaulting to %s", healthCheckPathAnnotation, defaultPath)
+			}
+			config.Path = defaultPath
+		}
+	}
+
+	config.Interval = h.getPositiveInt(healthCheckIntervalAnnotation, ing, defaultInterval)
+	config.Timeout = h.getPositiveInt(healthCheckTimeoutAnnotation, ing, defaultTimeout)
+	config.HealthyThreshold = h.getPositiveInt(healthCheckHealthyThresholdAnnotation, ing, defaultHealthyThreshold)
+	config.UnhealthyThreshold = h.getPositiveInt(healthCheckUnhealthyThresholdAnnotation, ing, defaultUnhealthyThreshold)
+
+	return config, nil
+}
+
+func (h healthCheck) GetDocumentation() parser.AnnotationFields {
+	return h.annotationConfig.Annotations
+}
+
+func (h healthCheck) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(h.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, healthCheckAnnotations.Annotations)
+}
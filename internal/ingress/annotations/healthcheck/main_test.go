@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthcheck
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	checkType := parser.GetAnnotationWithPrefix(healthCheckTypeAnnotation)
+	path := parser.GetAnnotationWithPrefix(healthCheckPathAnnotation)
+	interval := parser.GetAnnotationWithPrefix(healthCheckIntervalAnnotation)
+	timeout := parser.GetAnnotationWithPrefix(healthCheckTimeoutAnnotation)
+	healthyThreshold := parser.GetAnnotationWithPrefix(healthCheckHealthyThresholdAnnotation)
+	unhealthyThreshold := parser.GetAnnotationWithPrefix(healthCheckUnhealthyThresholdAnnotation)
+	grpcService := parser.GetAnnotationWithPrefix(healthCheckGRPCServiceAnnotation)
+	backendProtocol := parser.GetAnnotationWithPrefix(backendProtocolAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *Config
+	}{
+		{
+			name:        "disabled without annotations",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			name: "disabled with an invalid type",
+			annotations: map[string]string{
+				checkType: "udp",
+				path:      "/healthz",
+			},
+			expected: &Config{},
+		},
+		{
+			name: "http defaults",
+			annotations: map[string]string{
+				checkType: "http",
+			},
+			expected: &Config{
+				Type:               TypeHTTP,
+				Path:               "/",
+				Interval:           5,
+				Timeout:            1,
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+			},
+		},
+		{
+			name: "http with all annotations",
+			annotations: map[string]string{
+				checkType:          "http",
+				path:               "/healthz",
+				interval:           "10",
+				timeout:            "2",
+				healthyThreshold:   "1",
+				unhealthyThreshold: "5",
+			},
+			expected: &Config{
+				Type:               TypeHTTP,
+				Path:               "/healthz",
+				Interval:           10,
+				Timeout:            2,
+				HealthyThreshold:   1,
+				UnhealthyThreshold: 5,
+			},
+		},
+		{
+			name: "tcp ignores the path",
+			annotations: map[string]string{
+				checkType: "tcp",
+				path:      "/healthz",
+				interval:  "-1",
+			},
+			expected: &Config{
+				Type:               TypeTCP,
+				Interval:           5,
+				Timeout:            1,
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+			},
+		},
+		{
+			name: "grpc requires the grpc backend protocol",
+			annotations: map[string]string{
+				checkType:       "grpc",
+				backendProtocol: "HTTP",
+			},
+			expected: &Config{},
+		},
+		{
+			name: "grpc with a service",
+			annotations: map[string]string{
+				checkType:       "grpc",
+				backendProtocol: "GRPC",
+				grpcService:     "helloworld.Greeter",
+				path:            "/healthz",
+			},
+			expected: &Config{
+				Type:               TypeGRPC,
+				Interval:           5,
+				Timeout:            1,
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+				GRPCService:        "helloworld.Greeter",
+			},
+		},
+		{
+			name: "invalid grpc service checks the whole server",
+			annotations: map[string]string{
+				checkType:       "grpc",
+				backendProtocol: "grpc",
+				grpcService:     "helloworld.Greeter;",
+			},
+			expected: &Config{
+				Type:               TypeGRPC,
+				Interval:           5,
+				Timeout:            1,
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+			},
+		},
+		{
+			name: "invalid path is defaulted",
+			annotations: map[string]string{
+				checkType: "http",
+				path:      "/healthz;rm",
+			},
+			expected: &Config{
+				Type:               TypeHTTP,
+				Path:               "/",
+				Interval:           5,
+				Timeout:            1,
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+			},
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, _ := ap.Parse(ing)
+			config, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but returned %T", result)
+			}
+			if !config.Equal(testCase.expected) {
+				t.Errorf("expected %+v but returned %+v", testCase.expected, config)
+			}
+		})
+	}
+}
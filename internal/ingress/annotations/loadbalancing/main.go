@@ -29,7 +29,7 @@ const (
 	loadBalanceAlghoritmAnnotation = "load-balance"
 )
 
-var loadBalanceAlghoritms = []string{"round_robin", "chash", "chashsubset", "sticky_balanced", "sticky_persistent", "ewma"}
+var loadBalanceAlghoritms = []string{"round_robin", "chash", "chashsubset", "sticky_balanced", "sticky_persistent", "ewma", "least_conn"}
 
 var loadBalanceAnnotations = parser.Annotation{
 	Group: "backend",
@@ -17,17 +17,30 @@ limitations under the License.
 package log
 
 import (
+	"maps"
+	"regexp"
+	"strings"
+
 	networking "k8s.io/api/networking/v1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
 const (
 	enableAccessLogAnnotation  = "enable-access-log"
 	enableRewriteLogAnnotation = "enable-rewrite-log"
+	accessLogFieldsAnnotation  = "access-log-fields"
+	accessLogSampleAnnotation  = "access-log-sample-rate"
+	errorLogLevelAnnotation    = "error-log-level"
 )
 
+// ErrorLogLevels are the severities of the error_log directive, by increasing severity
+var ErrorLogLevels = []string{"debug", "info", "notice", "warn", "error", "crit", "alert", "emerg"}
+
+var regexAccessLogFields = regexp.MustCompile(`^[A-Za-z0-9_.@\-]+=[A-Za-z0-9_.\-/:@]*(,[A-Za-z0-9_.@\-]+=[A-Za-z0-9_.\-/:@]*)*$`)
+
 var logAnnotations = parser.Annotation{
 	Group: "log",
 	Annotations: parser.AnnotationFields{
@@ -43,6 +56,27 @@ var logAnnotations = parser.Annotation{
 			Risk:          parser.AnnotationRiskLow,
 			Documentation: `This configuration setting allows you to control if this location should generate logs from the rewrite feature usage`,
 		},
+		accessLogFieldsAnnotation: {
+			Validator: parser.ValidateRegex(regexAccessLogFields, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines a comma separated list of name=value fields added to the JSON access log of this location,
+			when the access log schema is defined with log-format-json-fields. The values are literal`,
+		},
+		accessLogSampleAnnotation: {
+			Validator: parser.ValidateFloat,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the ratio, between 0 and 1, of the requests of this location written to the access log,
+			apart from the errors and slow requests defined by the access-log-sample-min-status and access-log-sample-slow-threshold configuration`,
+		},
+		errorLogLevelAnnotation: {
+			Validator: parser.ValidateOptions(ErrorLogLevels, true, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation sets the severity of the error_log of the server of the hosts of this ingress,
+			overriding the error-log-level configuration. The first ingress defining it for a host takes precedence`,
+		},
 	},
 }
 
@@ -55,6 +89,13 @@ type log struct {
 type Config struct {
 	Access  bool `json:"accessLog"`
 	Rewrite bool `json:"rewriteLog"`
+	// Fields are the fields added to the JSON access log
+	Fields map[string]string `json:"accessLogFields,omitempty"`
+	// SampleRate is the ratio of the requests written to the access log
+	SampleRate    float32 `json:"accessLogSampleRate"`
+	SampleRateSet bool    `json:"accessLogSampleRateSet"`
+	// ErrorLogLevel is the severity of the error_log of the server
+	ErrorLogLevel string `json:"errorLogLevel,omitempty"`
 }
 
 // Equal tests for equality between two Config types
@@ -67,6 +108,22 @@ func (bd1 *Config) Equal(bd2 *Config) bool {
 		return false
 	}
 
+	if !maps.Equal(bd1.Fields, bd2.Fields) {
+		return false
+	}
+
+	if bd1.SampleRateSet != bd2.SampleRateSet {
+		return false
+	}
+
+	if bd1.SampleRate != bd2.SampleRate {
+		return false
+	}
+
+	if bd1.ErrorLogLevel != bd2.ErrorLogLevel {
+		return false
+	}
+
 	return true
 }
 
@@ -94,6 +151,39 @@ func (l log) Parse(ing *networking.Ingress) (interface{}, error) {
 		config.Rewrite = false
 	}
 
+	fields, err := parser.GetStringAnnotation(accessLogFieldsAnnotation, ing, l.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return nil, err
+	}
+	for _, field := range strings.Split(fields, ",") {
+		name, value, _ := strings.Cut(field, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if config.Fields == nil {
+			config.Fields = map[string]string{}
+		}
+		config.Fields[name] = strings.TrimSpace(value)
+	}
+
+	sampleRate, err := parser.GetFloatAnnotation(accessLogSampleAnnotation, ing, l.annotationConfig.Annotations)
+	if err == nil {
+		if sampleRate < 0 || sampleRate > 1 {
+			return nil, errors.NewInvalidAnnotationContent(accessLogSampleAnnotation, sampleRate)
+		}
+		config.SampleRateSet = true
+		config.SampleRate = sampleRate
+	} else if errors.IsValidationError(err) {
+		return nil, err
+	}
+
+	config.ErrorLogLevel, err = parser.GetStringAnnotation(errorLogLevelAnnotation, ing, l.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return nil, err
+	}
+	config.ErrorLogLevel = strings.TrimSpace(config.ErrorLogLevel)
+
 	return config, nil
 }
 
@@ -17,6 +17,7 @@ limitations under the License.
 package log
 
 import (
+	"reflect"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -131,3 +132,88 @@ func TestInvalidBoolConfig(t *testing.T) {
 		t.Errorf("expected access log to be enabled due to invalid config, but it is disabled")
 	}
 }
+
+func TestIngressAccessLogFields(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(accessLogFieldsAnnotation)] = "team=payments, tier=backend"
+	ing.SetAnnotations(data)
+
+	log, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nginxLogs, ok := log.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	expected := map[string]string{"team": "payments", "tier": "backend"}
+	if !reflect.DeepEqual(nginxLogs.Fields, expected) {
+		t.Errorf("expected access log fields %v but got %v", expected, nginxLogs.Fields)
+	}
+
+	data[parser.GetAnnotationWithPrefix(accessLogFieldsAnnotation)] = "team=$http_authorization"
+	ing.SetAnnotations(data)
+
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected an error for the access log fields with a variable")
+	}
+}
+
+func TestIngressAccessLogSampleRate(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(accessLogSampleAnnotation)] = "0.05"
+	ing.SetAnnotations(data)
+
+	log, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nginxLogs, ok := log.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	if !nginxLogs.SampleRateSet || nginxLogs.SampleRate != 0.05 {
+		t.Errorf("expected a sample rate of 0.05 but got %v", nginxLogs.SampleRate)
+	}
+
+	data[parser.GetAnnotationWithPrefix(accessLogSampleAnnotation)] = "1.5"
+	ing.SetAnnotations(data)
+
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected an error for a sample rate greater than 1")
+	}
+}
+
+func TestIngressErrorLogLevel(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(errorLogLevelAnnotation)] = "info"
+	ing.SetAnnotations(data)
+
+	log, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nginxLogs, ok := log.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	if nginxLogs.ErrorLogLevel != "info" {
+		t.Errorf("expected an error log level info but got %v", nginxLogs.ErrorLogLevel)
+	}
+
+	data[parser.GetAnnotationWithPrefix(errorLogLevelAnnotation)] = "verbose"
+	ing.SetAnnotations(data)
+
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected an error for an unknown error log level")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"crypto/sha1" //nolint:gosec // Not used for security, only to name the geo variables
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/net"
+)
+
+const (
+	maintenanceModeAnnotation      = "maintenance-mode"
+	maintenanceAllowlistAnnotation = "maintenance-allowlist-source-range"
+)
+
+var maintenanceAnnotations = parser.Annotation{
+	Group: "maintenance",
+	Annotations: parser.AnnotationFields{
+		maintenanceModeAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation puts the locations of the Ingress in maintenance, NGINX answers all their requests with a 503 response
+			before the authentication and without proxying them to the backends`,
+		},
+		maintenanceAllowlistAnnotation: {
+			Validator:     parser.ValidateCIDRs,
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskMedium, // Failure on parsing this may cause undesired access
+			Documentation: `This annotation defines a comma separated list of IPs and networks whose requests are still proxied during the maintenance`,
+		},
+	},
+}
+
+// Config describes the maintenance of a location
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Allowlist are the IPs and networks whose requests are proxied during
+	// the maintenance, sorted
+	Allowlist []string `json:"allowlist,omitempty"`
+	// ID names the variable of the allowlist
+	ID string `json:"id,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.ID != c2.ID {
+		return false
+	}
+	if len(c1.Allowlist) != len(c2.Allowlist) {
+		return false
+	}
+	for i, cidr := range c1.Allowlist {
+		if cidr != c2.Allowlist[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type maintenance struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new maintenance annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return maintenance{
+		r:                r,
+		annotationConfig: maintenanceAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress to answer the
+// requests of the locations with a 503 response during a maintenance
+func (a maintenance) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	enabled, err := parser.GetBoolAnnotation(maintenanceModeAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if ing_errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to 'false'", maintenanceModeAnnotation)
+		}
+		return config, nil
+	}
+	if !enabled {
+		return config, nil
+	}
+	config.Enabled = true
+
+	val, err := parser.GetStringAnnotation(maintenanceAllowlistAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if ing_errors.IsMissingAnnotations(err) {
+			return config, nil
+		}
+		// the locations are denied, which NGINX also answers with a 503 response
+		return config, ing_errors.LocationDeniedError{
+			Reason: err,
+		}
+	}
+
+	ipnets, ips, err := net.ParseIPNets(strings.Split(val, ",")...)
+	if err != nil && len(ips) == 0 {
+		return config, ing_errors.LocationDeniedError{
+			Reason: fmt.Errorf("the annotation does not contain a valid IP address or network: %w", err),
+		}
+	}
+
+	for k := range ipnets {
+		config.Allowlist = append(config.Allowlist, k)
+	}
+	for k := range ips {
+		config.Allowlist = append(config.Allowlist, k)
+	}
+	sort.Strings(config.Allowlist)
+
+	//nolint:gosec // Not used for security, only to name the geo variables
+	checksum := sha1.Sum([]byte(strings.Join(config.Allowlist, ",")))
+	config.ID = hex.EncodeToString(checksum[:])[:16]
+
+	return config, nil
+}
+
+func (a maintenance) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a maintenance) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, maintenanceAnnotations.Annotations)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	mode := parser.GetAnnotationWithPrefix(maintenanceModeAnnotation)
+	allowlist := parser.GetAnnotationWithPrefix(maintenanceAllowlistAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{}, &Config{}},
+		{map[string]string{mode: "false", allowlist: "10.0.0.0/8"}, &Config{}},
+		{map[string]string{mode: "true"}, &Config{Enabled: true}},
+		{
+			map[string]string{mode: "true", allowlist: "192.168.1.10, 10.0.0.0/8"},
+			&Config{Enabled: true, Allowlist: []string{"10.0.0.0/8", "192.168.1.10"}, ID: "fc4d5d677fd8e876"},
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+
+		result, err := ap.Parse(ing)
+		if err != nil {
+			t.Errorf("unexpected error with the annotations %v: %v", testCase.annotations, err)
+		}
+
+		config, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but returned %T", result)
+		}
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %+v but returned %+v with the annotations %v", testCase.expected, config, testCase.annotations)
+		}
+	}
+
+	ing.SetAnnotations(map[string]string{mode: "true", allowlist: "not-an-address"})
+	result, err := ap.Parse(ing)
+	if !errors.IsLocationDenied(err) {
+		t.Errorf("expected the location to be denied but returned %v", err)
+	}
+	if !result.(*Config).Enabled {
+		t.Errorf("expected the maintenance to be enabled")
+	}
+}
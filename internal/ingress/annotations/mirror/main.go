@@ -32,8 +32,12 @@ const (
 	mirrorRequestBodyAnnotation = "mirror-request-body"
 	mirrorTargetAnnotation      = "mirror-target"
 	mirrorHostAnnotation        = "mirror-host"
+	mirrorPercentageAnnotation  = "mirror-percentage"
+	mirrorTimeoutAnnotation     = "mirror-timeout"
 )
 
+const defaultMirrorPercentage = 100
+
 var OnOffRegex = regexp.MustCompile(`^(on|off)$`)
 
 var mirrorAnnotation = parser.Annotation{
@@ -57,6 +61,18 @@ var mirrorAnnotation = parser.Annotation{
 			Risk:          parser.AnnotationRiskHigh,
 			Documentation: `This annotation defines if a specific Host header should be set for mirrored request.`,
 		},
+		mirrorPercentageAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the percentage (0-100) of requests that should be mirrored. Defaults to 100`,
+		},
+		mirrorTimeoutAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the timeout in seconds for connecting to, sending to and reading from the mirror backend`,
+		},
 	},
 }
 
@@ -66,6 +82,8 @@ type Config struct {
 	RequestBody string `json:"requestBody"`
 	Target      string `json:"target"`
 	Host        string `json:"host"`
+	Percentage  int    `json:"percentage"`
+	Timeout     int    `json:"timeout"`
 }
 
 // Equal tests for equality between two Configuration types
@@ -94,6 +112,14 @@ func (m1 *Config) Equal(m2 *Config) bool {
 		return false
 	}
 
+	if m1.Percentage != m2.Percentage {
+		return false
+	}
+
+	if m1.Timeout != m2.Timeout {
+		return false
+	}
+
 	return true
 }
 
@@ -153,6 +179,22 @@ func (a mirror) Parse(ing *networking.Ingress) (interface{}, error) {
 		}
 	}
 
+	config.Percentage, err = parser.GetIntAnnotation(mirrorPercentageAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil || config.Percentage < 0 || config.Percentage > 100 {
+		if errors.IsValidationError(err) || err == nil {
+			klog.Warningf("annotation %s contains invalid value, defaulting to %d", mirrorPercentageAnnotation, defaultMirrorPercentage)
+		}
+		config.Percentage = defaultMirrorPercentage
+	}
+
+	config.Timeout, err = parser.GetIntAnnotation(mirrorTimeoutAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil || config.Timeout < 0 {
+		if errors.IsValidationError(err) || err == nil {
+			klog.Warningf("annotation %s contains invalid value, defaulting", mirrorTimeoutAnnotation)
+		}
+		config.Timeout = 0
+	}
+
 	return config, nil
 }
 
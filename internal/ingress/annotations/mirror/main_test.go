@@ -31,6 +31,8 @@ func TestParse(t *testing.T) {
 	requestBody := parser.GetAnnotationWithPrefix("mirror-request-body")
 	backendURL := parser.GetAnnotationWithPrefix("mirror-target")
 	host := parser.GetAnnotationWithPrefix("mirror-host")
+	percentage := parser.GetAnnotationWithPrefix("mirror-percentage")
+	timeout := parser.GetAnnotationWithPrefix("mirror-timeout")
 
 	ap := NewParser(&resolver.Mock{})
 	if ap == nil {
@@ -47,60 +49,85 @@ func TestParse(t *testing.T) {
 			RequestBody: "on",
 			Target:      "https://test.env.com/$request_uri",
 			Host:        "test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "https://test.env.com$request_uri"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "https://test.env.com$request_uri",
 			Host:        "test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "https://test.env.com:8080$request_uri"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "https://test.env.com:8080$request_uri",
 			Host:        "test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "https://test.env.com:8080/$request_uri"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "https://test.env.com:8080/$request_uri",
 			Host:        "test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{requestBody: "off"}, &Config{
 			Source:      "",
 			RequestBody: "off",
 			Target:      "",
 			Host:        "",
+			Percentage:  100,
 		}},
 		{map[string]string{host: "test.env.com", backendURL: "http://some.test.env.com/$someparam"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "http://some.test.env.com/$someparam",
 			Host:        "test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "IamNotAURL"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "IamNotAURL",
 			Host:        "",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "http://some.test.env.com:2121/$someparam=1&$someotherparam=2"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "http://some.test.env.com:2121/$someparam=1&$someotherparam=2",
 			Host:        "some.test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "http://some.test.env.com", host: "someInvalidParm.%^&*()_=!@#'\""}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "http://some.test.env.com",
 			Host:        "some.test.env.com",
+			Percentage:  100,
 		}},
 		{map[string]string{backendURL: "http://some.test.env.com", host: "_sbrubles-i\"@xpto:12345"}, &Config{
 			Source:      ngxURI,
 			RequestBody: "on",
 			Target:      "http://some.test.env.com",
 			Host:        "some.test.env.com",
+			Percentage:  100,
+		}},
+		{map[string]string{backendURL: "https://test.env.com$request_uri", percentage: "25", timeout: "2"}, &Config{
+			Source:      ngxURI,
+			RequestBody: "on",
+			Target:      "https://test.env.com$request_uri",
+			Host:        "test.env.com",
+			Percentage:  25,
+			Timeout:     2,
+		}},
+		{map[string]string{backendURL: "https://test.env.com$request_uri", percentage: "120", timeout: "-1"}, &Config{
+			Source:      ngxURI,
+			RequestBody: "on",
+			Target:      "https://test.env.com$request_uri",
+			Host:        "test.env.com",
+			Percentage:  100,
 		}},
 	}
 
@@ -17,10 +17,14 @@ limitations under the License.
 package modsecurity
 
 import (
+	"fmt"
+	"regexp"
+
 	networking "k8s.io/api/networking/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/pkg/util/file"
 	"k8s.io/klog/v2"
 )
 
@@ -29,8 +33,13 @@ const (
 	modsecEnableOwaspCoreAnnotation = "enable-owasp-core-rules"
 	modesecTransactionIDAnnotation  = "modsecurity-transaction-id"
 	modsecSnippetAnnotation         = "modsecurity-snippet"
+	// WAFPolicyAnnotation references the WAFPolicy of the namespace of the
+	// Ingress tuning its OWASP Core Rule Set
+	WAFPolicyAnnotation = "modsecurity-waf-policy"
 )
 
+var regexWAFPolicyName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
 var modsecurityAnnotation = parser.Annotation{
 	Group: "modsecurity",
 	Annotations: parser.AnnotationFields{
@@ -58,6 +67,13 @@ var modsecurityAnnotation = parser.Annotation{
 			Risk:          parser.AnnotationRiskCritical,
 			Documentation: `This annotation enables adding a specific snippet configuration for ModSecurity`,
 		},
+		WAFPolicyAnnotation: {
+			Validator: parser.ValidateRegex(regexWAFPolicyName, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation references a WAFPolicy of the namespace of the Ingress, tuning the paranoia level, the anomaly thresholds
+			and the rule exclusions of the OWASP Core Rule Set`,
+		},
 	},
 }
 
@@ -68,6 +84,18 @@ type Config struct {
 	OWASPRules    bool   `json:"enable-owasp-core-rules"`
 	TransactionID string `json:"modsecurity-transaction-id"`
 	Snippet       string `json:"modsecurity-snippet"`
+	// WAFPolicy is the namespace/name of the WAFPolicy of the Ingress
+	WAFPolicy string `json:"modsecurity-waf-policy,omitempty"`
+	// WAFPolicySetupFile contains the rules of the WAFPolicy loaded before
+	// the OWASP Core Rule Set
+	WAFPolicySetupFile string `json:"modsecurity-waf-policy-setup-file,omitempty"`
+	// WAFPolicyExclusionsFile contains the rules of the WAFPolicy loaded
+	// after the OWASP Core Rule Set
+	WAFPolicyExclusionsFile string `json:"modsecurity-waf-policy-exclusions-file,omitempty"`
+	// WAFPolicySetupRules and WAFPolicyExclusionsRules are the contents of
+	// the files, written with WriteWAFPolicyRules at sync time
+	WAFPolicySetupRules      string `json:"-"`
+	WAFPolicyExclusionsRules string `json:"-"`
 }
 
 // Equal tests for equality between two Config types
@@ -93,6 +121,15 @@ func (modsec1 *Config) Equal(modsec2 *Config) bool {
 	if modsec1.Snippet != modsec2.Snippet {
 		return false
 	}
+	if modsec1.WAFPolicy != modsec2.WAFPolicy {
+		return false
+	}
+	if modsec1.WAFPolicySetupFile != modsec2.WAFPolicySetupFile {
+		return false
+	}
+	if modsec1.WAFPolicyExclusionsFile != modsec2.WAFPolicyExclusionsFile {
+		return false
+	}
 
 	return true
 }
@@ -100,14 +137,16 @@ func (modsec1 *Config) Equal(modsec2 *Config) bool {
 // NewParser creates a new ModSecurity annotation parser
 func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 	return modSecurity{
-		r:                r,
-		annotationConfig: modsecurityAnnotation,
+		r:                  r,
+		wafPolicyDirectory: file.ModSecurityDirectory,
+		annotationConfig:   modsecurityAnnotation,
 	}
 }
 
 type modSecurity struct {
-	r                resolver.Resolver
-	annotationConfig parser.Annotation
+	r                  resolver.Resolver
+	wafPolicyDirectory string
+	annotationConfig   parser.Annotation
 }
 
 // Parse parses the annotations contained in the ingress
@@ -147,9 +186,47 @@ func (a modSecurity) Parse(ing *networking.Ingress) (interface{}, error) {
 		config.Snippet = ""
 	}
 
+	policyName, err := parser.GetStringAnnotation(WAFPolicyAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return nil, err
+	}
+	if policyName != "" {
+		// the Ingresses referencing a missing WAFPolicy keep the default
+		// rules, the ones referencing an invalid WAFPolicy are rejected
+		if err := a.parseWAFPolicy(fmt.Sprintf("%v/%v", ing.Namespace, policyName), config); err != nil {
+			if errors.IsValidationError(err) {
+				return nil, err
+			}
+			klog.Warningf("Ignoring the WAFPolicy of the Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+		}
+	}
+
 	return config, nil
 }
 
+// parseWAFPolicy renders the rules of the WAFPolicy and names their files
+func (a modSecurity) parseWAFPolicy(key string, config *Config) error {
+	policy, err := a.r.GetWAFPolicy(key)
+	if err != nil {
+		return fmt.Errorf("unexpected error reading WAFPolicy %v: %w", key, err)
+	}
+
+	setup, exclusions, err := renderWAFPolicy(policy)
+	if err != nil {
+		return errors.ValidationError{
+			Reason: fmt.Errorf("invalid WAFPolicy %v: %w", key, err),
+		}
+	}
+
+	config.WAFPolicy = key
+	config.WAFPolicySetupFile = wafPolicyRulesFile(a.wafPolicyDirectory, policy, "setup", setup)
+	config.WAFPolicySetupRules = setup
+	config.WAFPolicyExclusionsFile = wafPolicyRulesFile(a.wafPolicyDirectory, policy, "exclusions", exclusions)
+	config.WAFPolicyExclusionsRules = exclusions
+
+	return nil
+}
+
 func (a modSecurity) GetDocumentation() parser.AnnotationFields {
 	return a.annotationConfig.Annotations
 }
@@ -17,13 +17,17 @@ limitations under the License.
 package modsecurity
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	api "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 )
 
 func TestParse(t *testing.T) {
@@ -41,22 +45,22 @@ func TestParse(t *testing.T) {
 		annotations map[string]string
 		expected    Config
 	}{
-		{map[string]string{enable: "true"}, Config{true, true, false, "", ""}},
-		{map[string]string{enable: "false"}, Config{false, true, false, "", ""}},
-		{map[string]string{enable: ""}, Config{false, false, false, "", ""}},
+		{map[string]string{enable: "true"}, Config{true, true, false, "", "", "", "", "", "", ""}},
+		{map[string]string{enable: "false"}, Config{false, true, false, "", "", "", "", "", "", ""}},
+		{map[string]string{enable: ""}, Config{false, false, false, "", "", "", "", "", "", ""}},
 
-		{map[string]string{owasp: "true"}, Config{false, false, true, "", ""}},
-		{map[string]string{owasp: "false"}, Config{false, false, false, "", ""}},
-		{map[string]string{owasp: ""}, Config{false, false, false, "", ""}},
+		{map[string]string{owasp: "true"}, Config{false, false, true, "", "", "", "", "", "", ""}},
+		{map[string]string{owasp: "false"}, Config{false, false, false, "", "", "", "", "", "", ""}},
+		{map[string]string{owasp: ""}, Config{false, false, false, "", "", "", "", "", "", ""}},
 
-		{map[string]string{transID: "ok"}, Config{false, false, false, "ok", ""}},
-		{map[string]string{transID: ""}, Config{false, false, false, "", ""}},
+		{map[string]string{transID: "ok"}, Config{false, false, false, "ok", "", "", "", "", "", ""}},
+		{map[string]string{transID: ""}, Config{false, false, false, "", "", "", "", "", "", ""}},
 
-		{map[string]string{snippet: "ModSecurity Rule"}, Config{false, false, false, "", "ModSecurity Rule"}},
-		{map[string]string{snippet: ""}, Config{false, false, false, "", ""}},
+		{map[string]string{snippet: "ModSecurity Rule"}, Config{false, false, false, "", "ModSecurity Rule", "", "", "", "", ""}},
+		{map[string]string{snippet: ""}, Config{false, false, false, "", "", "", "", "", "", ""}},
 
-		{map[string]string{}, Config{false, false, false, "", ""}},
-		{nil, Config{false, false, false, "", ""}},
+		{map[string]string{}, Config{false, false, false, "", "", "", "", "", "", ""}},
+		{nil, Config{false, false, false, "", "", "", "", "", "", ""}},
 	}
 
 	ing := &networking.Ingress{
@@ -82,3 +86,87 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseWAFPolicy(t *testing.T) {
+	paranoiaLevel := int32(2)
+	invalidParanoiaLevel := int32(5)
+	ap := modSecurity{
+		r: &resolver.Mock{WAFPolicies: map[string]*v1alpha1.WAFPolicy{
+			"default/strict": {
+				ObjectMeta: meta_v1.ObjectMeta{Name: "strict", Namespace: api.NamespaceDefault},
+				Spec: v1alpha1.WAFPolicySpec{
+					ParanoiaLevel:  &paranoiaLevel,
+					RuleExclusions: []v1alpha1.WAFRuleExclusion{{IDs: []int64{942100}}},
+				},
+			},
+			"default/invalid": {
+				ObjectMeta: meta_v1.ObjectMeta{Name: "invalid", Namespace: api.NamespaceDefault},
+				Spec: v1alpha1.WAFPolicySpec{
+					ParanoiaLevel: &invalidParanoiaLevel,
+				},
+			},
+		}},
+		wafPolicyDirectory: t.TempDir(),
+		annotationConfig:   modsecurityAnnotation,
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				parser.GetAnnotationWithPrefix(WAFPolicyAnnotation): "strict",
+			},
+		},
+	}
+
+	result, err := ap.Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, ok := result.(*Config)
+	if !ok {
+		t.Fatalf("unexpected type: %T", result)
+	}
+	if config.WAFPolicy != "default/strict" {
+		t.Errorf("expected the WAFPolicy default/strict but returned %v", config.WAFPolicy)
+	}
+
+	// the files are written at sync time
+	if _, err := os.Stat(config.WAFPolicySetupFile); err == nil {
+		t.Errorf("expected the rules not to be written while parsing")
+	}
+	if err := WriteWAFPolicyRules(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	setup, err := os.ReadFile(config.WAFPolicySetupFile)
+	if err != nil {
+		t.Fatalf("unexpected error reading the setup rules: %v", err)
+	}
+	if !strings.Contains(string(setup), "setvar:tx.paranoia_level=2") {
+		t.Errorf("expected the paranoia level in the setup rules but returned %v", string(setup))
+	}
+
+	exclusions, err := os.ReadFile(config.WAFPolicyExclusionsFile)
+	if err != nil {
+		t.Fatalf("unexpected error reading the exclusion rules: %v", err)
+	}
+	if !strings.Contains(string(exclusions), "SecRuleRemoveById 942100") {
+		t.Errorf("expected the removed rule in the exclusion rules but returned %v", string(exclusions))
+	}
+
+	ing.Annotations[parser.GetAnnotationWithPrefix(WAFPolicyAnnotation)] = "missing"
+	result, err = ap.Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config := result.(*Config); config.WAFPolicy != "" || config.WAFPolicySetupFile != "" {
+		t.Errorf("expected the missing WAFPolicy to be ignored but returned %v", config)
+	}
+
+	ing.Annotations[parser.GetAnnotationWithPrefix(WAFPolicyAnnotation)] = "invalid"
+	if _, err := ap.Parse(ing); !errors.IsValidationError(err) {
+		t.Errorf("expected a validation error for the invalid WAFPolicy but returned %v", err)
+	}
+}
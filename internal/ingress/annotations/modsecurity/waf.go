@@ -0,0 +1,249 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package modsecurity
+
+import (
+	"crypto/sha1" //nolint:gosec // Not used for security, only to name the rules files
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+// wafPolicyFirstRuleID is the ID of the first rule of a WAFPolicy disabling
+// the rule engine or removing rules for a path, the IDs from 99000 to 99999
+// are reserved for them
+const wafPolicyFirstRuleID = 99000
+
+var (
+	wafPolicyTagRegex    = regexp.MustCompile(`^[A-Za-z0-9_./\-]+$`)
+	wafPolicyTargetRegex = regexp.MustCompile(`^[A-Z_]+(:[A-Za-z0-9_.\-]+)?$`)
+	wafPolicyPathRegex   = regexp.MustCompile(`^/[A-Za-z0-9_.~/%\-]*$`)
+)
+
+// renderWAFPolicy returns the rules of the WAFPolicy loaded before the OWASP
+// Core Rule Set, tuning it and removing rules for some paths, and the rules
+// loaded after it, removing rules for all the requests
+func renderWAFPolicy(policy *v1alpha1.WAFPolicy) (setup, exclusions string, err error) {
+	spec := policy.Spec
+	setupRules := []string{}
+	exclusionRules := []string{}
+
+	if spec.ParanoiaLevel != nil {
+		if *spec.ParanoiaLevel < 1 || *spec.ParanoiaLevel > 4 {
+			return "", "", fmt.Errorf("the paranoia level must be between 1 and 4")
+		}
+		setupRules = append(setupRules,
+			fmt.Sprintf(`SecAction "id:900000,phase:1,pass,nolog,setvar:tx.paranoia_level=%v"`, *spec.ParanoiaLevel))
+	}
+
+	thresholds := []string{}
+	if spec.InboundAnomalyThreshold != nil {
+		if *spec.InboundAnomalyThreshold < 1 {
+			return "", "", fmt.Errorf("the inbound anomaly threshold must be positive")
+		}
+		thresholds = append(thresholds, fmt.Sprintf("setvar:tx.inbound_anomaly_score_threshold=%v", *spec.InboundAnomalyThreshold))
+	}
+	if spec.OutboundAnomalyThreshold != nil {
+		if *spec.OutboundAnomalyThreshold < 1 {
+			return "", "", fmt.Errorf("the outbound anomaly threshold must be positive")
+		}
+		thresholds = append(thresholds, fmt.Sprintf("setvar:tx.outbound_anomaly_score_threshold=%v", *spec.OutboundAnomalyThreshold))
+	}
+	if len(thresholds) > 0 {
+		setupRules = append(setupRules,
+			fmt.Sprintf(`SecAction "id:900110,phase:1,pass,nolog,%v"`, strings.Join(thresholds, ",")))
+	}
+
+	ruleID := wafPolicyFirstRuleID
+	for _, path := range spec.DisabledPaths {
+		if !wafPolicyPathRegex.MatchString(path) {
+			return "", "", fmt.Errorf("invalid disabled path %q", path)
+		}
+		setupRules = append(setupRules,
+			fmt.Sprintf(`SecRule REQUEST_FILENAME "@beginsWith %v" "id:%v,phase:1,pass,nolog,ctl:ruleEngine=Off"`, path, ruleID))
+		ruleID++
+	}
+
+	for i := range spec.RuleExclusions {
+		exclusion := &spec.RuleExclusions[i]
+		if err := validateRuleExclusion(exclusion); err != nil {
+			return "", "", err
+		}
+
+		if exclusion.Path != "" {
+			setupRules = append(setupRules,
+				fmt.Sprintf(`SecRule REQUEST_FILENAME "@beginsWith %v" "id:%v,phase:1,pass,nolog,%v"`,
+					exclusion.Path, ruleID, strings.Join(ruleExclusionActions(exclusion), ",")))
+			ruleID++
+			continue
+		}
+
+		exclusionRules = append(exclusionRules, ruleExclusionDirectives(exclusion)...)
+	}
+
+	if ruleID > wafPolicyFirstRuleID+1000 {
+		return "", "", fmt.Errorf("too many disabled paths and rule exclusions with a path")
+	}
+
+	return joinRules(policy, setupRules), joinRules(policy, exclusionRules), nil
+}
+
+func validateRuleExclusion(exclusion *v1alpha1.WAFRuleExclusion) error {
+	if len(exclusion.IDs) == 0 && len(exclusion.Tags) == 0 {
+		return fmt.Errorf("the rule exclusions must have IDs or tags")
+	}
+	for _, id := range exclusion.IDs {
+		if id < 1 {
+			return fmt.Errorf("invalid rule ID %v", id)
+		}
+	}
+	for _, tag := range exclusion.Tags {
+		if !wafPolicyTagRegex.MatchString(tag) {
+			return fmt.Errorf("invalid rule tag %q", tag)
+		}
+	}
+	for _, target := range exclusion.Targets {
+		if !wafPolicyTargetRegex.MatchString(target) {
+			return fmt.Errorf("invalid rule target %q", target)
+		}
+	}
+	if exclusion.Path != "" && !wafPolicyPathRegex.MatchString(exclusion.Path) {
+		return fmt.Errorf("invalid rule exclusion path %q", exclusion.Path)
+	}
+
+	return nil
+}
+
+// ruleExclusionActions returns the actions removing the rules, or their
+// targets, of the requests of the path of the exclusion
+func ruleExclusionActions(exclusion *v1alpha1.WAFRuleExclusion) []string {
+	actions := []string{}
+	for _, id := range exclusion.IDs {
+		if len(exclusion.Targets) == 0 {
+			actions = append(actions, fmt.Sprintf("ctl:ruleRemoveById=%v", id))
+		}
+		for _, target := range exclusion.Targets {
+			actions = append(actions, fmt.Sprintf("ctl:ruleRemoveTargetById=%v;%v", id, target))
+		}
+	}
+	for _, tag := range exclusion.Tags {
+		if len(exclusion.Targets) == 0 {
+			actions = append(actions, fmt.Sprintf("ctl:ruleRemoveByTag=%v", tag))
+		}
+		for _, target := range exclusion.Targets {
+			actions = append(actions, fmt.Sprintf("ctl:ruleRemoveTargetByTag=%v;%v", tag, target))
+		}
+	}
+
+	return actions
+}
+
+// ruleExclusionDirectives returns the directives removing the rules, or their
+// targets, of all the requests
+func ruleExclusionDirectives(exclusion *v1alpha1.WAFRuleExclusion) []string {
+	directives := []string{}
+	for _, id := range exclusion.IDs {
+		if len(exclusion.Targets) == 0 {
+			directives = append(directives, fmt.Sprintf("SecRuleRemoveById %v", id))
+		}
+		for _, target := range exclusion.Targets {
+			directives = append(directives, fmt.Sprintf(`SecRuleUpdateTargetById %v "!%v"`, id, target))
+		}
+	}
+	for _, tag := range exclusion.Tags {
+		if len(exclusion.Targets) == 0 {
+			directives = append(directives, fmt.Sprintf("SecRuleRemoveByTag %v", tag))
+		}
+		for _, target := range exclusion.Targets {
+			directives = append(directives, fmt.Sprintf(`SecRuleUpdateTargetByTag %v "!%v"`, tag, target))
+		}
+	}
+
+	return directives
+}
+
+func joinRules(policy *v1alpha1.WAFPolicy, rules []string) string {
+	if len(rules) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("# WAFPolicy %v/%v\n%v\n", policy.Namespace, policy.Name, strings.Join(rules, "\n"))
+}
+
+// wafPolicyRulesFile returns the file of the rules of a WAFPolicy, in the
+// directory, named after the policy and the checksum of the rules, a change of
+// the rules changing the file of the locations
+func wafPolicyRulesFile(directory string, policy *v1alpha1.WAFPolicy, kind, rules string) string {
+	if rules == "" {
+		return ""
+	}
+
+	//nolint:gosec // Not used for security, only to name the rules files
+	checksum := sha1.Sum([]byte(rules))
+	return fmt.Sprintf("%v/%v-%v-%v-%x.conf", directory, policy.Namespace, policy.Name, kind, checksum[:6])
+}
+
+// WriteWAFPolicyRules writes the rules files of the WAFPolicy of a location
+// which are missing, before the configuration referencing them is tested
+func WriteWAFPolicyRules(config *Config) error {
+	if config == nil {
+		return nil
+	}
+
+	if err := writeWAFPolicyRules(config.WAFPolicySetupFile, config.WAFPolicySetupRules); err != nil {
+		return err
+	}
+	return writeWAFPolicyRules(config.WAFPolicyExclusionsFile, config.WAFPolicyExclusionsRules)
+}
+
+// writeWAFPolicyRules writes the rules to a temporary file renamed to the
+// file, NGINX never reads a partial file
+func writeWAFPolicyRules(filename, rules string) error {
+	if filename == "" {
+		return nil
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unexpected error writing the rules of the WAFPolicy: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.WriteString(rules)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), file.ReadWriteByUser)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), filename)
+	}
+	if err != nil {
+		return fmt.Errorf("unexpected error writing the rules of the WAFPolicy: %w", err)
+	}
+
+	return nil
+}
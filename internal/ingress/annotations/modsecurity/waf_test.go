@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package modsecurity
+
+import (
+	"testing"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestRenderWAFPolicy(t *testing.T) {
+	paranoiaLevel := int32(3)
+	inboundThreshold := int32(10)
+	policy := &v1alpha1.WAFPolicy{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "payments"},
+		Spec: v1alpha1.WAFPolicySpec{
+			ParanoiaLevel:           &paranoiaLevel,
+			InboundAnomalyThreshold: &inboundThreshold,
+			DisabledPaths:           []string{"/healthz"},
+			RuleExclusions: []v1alpha1.WAFRuleExclusion{
+				{IDs: []int64{942100, 942200}},
+				{Tags: []string{"attack-sqli"}, Targets: []string{"ARGS:query"}},
+				{IDs: []int64{920350}, Path: "/upload"},
+				{IDs: []int64{941100}, Targets: []string{"REQUEST_COOKIES:session"}, Path: "/login"},
+			},
+		},
+	}
+
+	setup, exclusions, err := renderWAFPolicy(policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `# WAFPolicy payments/api
+SecAction "id:900000,phase:1,pass,nolog,setvar:tx.paranoia_level=3"
+SecAction "id:900110,phase:1,pass,nolog,setvar:tx.inbound_anomaly_score_threshold=10"
+SecRule REQUEST_FILENAME "@beginsWith /healthz" "id:99000,phase:1,pass,nolog,ctl:ruleEngine=Off"
+SecRule REQUEST_FILENAME "@beginsWith /upload" "id:99001,phase:1,pass,nolog,ctl:ruleRemoveById=920350"
+SecRule REQUEST_FILENAME "@beginsWith /login" "id:99002,phase:1,pass,nolog,ctl:ruleRemoveTargetById=941100;REQUEST_COOKIES:session"
+`
+	if setup != expected {
+		t.Errorf("expected the setup rules\n%v\nbut returned\n%v", expected, setup)
+	}
+
+	expected = `# WAFPolicy payments/api
+SecRuleRemoveById 942100
+SecRuleRemoveById 942200
+SecRuleUpdateTargetByTag attack-sqli "!ARGS:query"
+`
+	if exclusions != expected {
+		t.Errorf("expected the exclusion rules\n%v\nbut returned\n%v", expected, exclusions)
+	}
+}
+
+func TestRenderInvalidWAFPolicy(t *testing.T) {
+	paranoiaLevel := int32(5)
+	for _, spec := range []v1alpha1.WAFPolicySpec{
+		{ParanoiaLevel: &paranoiaLevel},
+		{DisabledPaths: []string{`/health" "id:1`}},
+		{RuleExclusions: []v1alpha1.WAFRuleExclusion{{Targets: []string{"ARGS"}}}},
+		{RuleExclusions: []v1alpha1.WAFRuleExclusion{{IDs: []int64{942100}, Targets: []string{`ARGS:a"`}}}},
+	} {
+		if _, _, err := renderWAFPolicy(&v1alpha1.WAFPolicy{Spec: spec}); err == nil {
+			t.Errorf("expected an error for the WAFPolicy %+v", spec)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package njs
+
+import (
+	"crypto/sha1" //nolint:gosec // Not used for security, only to name the scripts directories
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+const (
+	njsScriptsAnnotation = "njs-scripts"
+	njsSetAnnotation     = "njs-set"
+)
+
+// maxScriptsSize is the maximum total size of the scripts of a ConfigMap
+const maxScriptsSize = 256 * 1024
+
+var (
+	// configMapNameRegex matches the name of a ConfigMap, optionally with its namespace
+	configMapNameRegex = regexp.MustCompile(`^([a-z0-9][a-z0-9\-]*/)?[a-z0-9][a-z0-9\-.]*$`)
+	// setRegex matches a comma separated list of variable=module.function
+	setRegex = regexp.MustCompile(`^([a-z][a-z0-9_]*=[a-z][a-z0-9_]*\.[A-Za-z_][A-Za-z0-9_]*,?)+$`)
+	// scriptKeyRegex matches the keys of the scripts of the ConfigMaps, the
+	// names of the modules are the keys without the .js extension
+	scriptKeyRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*\.js$`)
+)
+
+var njsAnnotations = parser.Annotation{
+	Group: "njs",
+	Annotations: parser.AnnotationFields{
+		njsScriptsAnnotation: {
+			Validator: parser.ValidateRegex(configMapNameRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			// the scripts run in the NGINX workers, like the snippets
+			Risk: parser.AnnotationRiskCritical,
+			Documentation: `This annotation references a ConfigMap whose keys are njs scripts, like auth.js, imported as modules
+			named after the keys in the locations of the Ingress`,
+		},
+		njsSetAnnotation: {
+			Validator: parser.ValidateRegex(setRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the comma separated list of variables set by a function of the njs scripts,
+			like tenant=auth.tenant setting $njs_tenant with the function tenant of the module auth`,
+		},
+	},
+}
+
+// Variable describes a variable set by a function of an njs module
+type Variable struct {
+	// Name is the name of the variable without the njs_ prefix
+	Name     string `json:"name"`
+	Module   string `json:"module"`
+	Function string `json:"function"`
+}
+
+// Config describes the njs scripts of a location
+type Config struct {
+	// ConfigMap is the namespace/name of the ConfigMap of the scripts
+	ConfigMap string `json:"configMap,omitempty"`
+	// Directory contains the scripts of the ConfigMap
+	Directory string `json:"directory,omitempty"`
+	// Modules are the names of the imported modules, sorted
+	Modules   []string   `json:"modules,omitempty"`
+	Variables []Variable `json:"variables,omitempty"`
+	// Scripts are the scripts of the ConfigMap by key, written to Directory
+	// when the configuration is synced
+	Scripts map[string]string `json:"-"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.ConfigMap != c2.ConfigMap {
+		return false
+	}
+	if c1.Directory != c2.Directory {
+		return false
+	}
+	if len(c1.Modules) != len(c2.Modules) {
+		return false
+	}
+	for i, module := range c1.Modules {
+		if module != c2.Modules[i] {
+			return false
+		}
+	}
+	if len(c1.Variables) != len(c2.Variables) {
+		return false
+	}
+	for i, variable := range c1.Variables {
+		if variable != c2.Variables[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type njs struct {
+	r                resolver.Resolver
+	scriptsDirectory string
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new njs annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return njs{
+		r:                r,
+		scriptsDirectory: file.NJSDirectory,
+		annotationConfig: njsAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress to import the njs
+// scripts of a ConfigMap and set variables with their functions
+func (a njs) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	cm, err := parser.GetStringAnnotation(njsScriptsAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if ing_errors.IsValidationError(err) {
+			return config, err
+		}
+		return config, nil
+	}
+
+	cmns, cmn, err := cache.SplitMetaNamespaceKey(cm)
+	if err != nil {
+		return config, ing_errors.NewLocationDenied(fmt.Sprintf("error reading configmap name from annotation: %v", err))
+	}
+
+	if cmns != "" && cmns != ing.Namespace && !a.r.GetSecurityConfiguration().AllowCrossNamespaceResources {
+		return config, ing_errors.NewLocationDenied("cross namespace njs scripts are not allowed")
+	}
+	if cmns == "" {
+		cmns = ing.Namespace
+	}
+
+	key := fmt.Sprintf("%v/%v", cmns, cmn)
+	cmap, err := a.r.GetConfigMap(key)
+	if err != nil {
+		return config, ing_errors.NewLocationDenied(fmt.Sprintf("unexpected error reading configmap %s: %v", key, err))
+	}
+
+	// the Ingresses referencing invalid scripts are rejected by the
+	// admission webhook
+	size := 0
+	for name, script := range cmap.Data {
+		if !scriptKeyRegex.MatchString(name) {
+			return config, ing_errors.NewValidationError(njsScriptsAnnotation)
+		}
+		size += len(script)
+		config.Modules = append(config.Modules, strings.TrimSuffix(name, ".js"))
+	}
+	if len(config.Modules) == 0 || size > maxScriptsSize {
+		return config, ing_errors.NewValidationError(njsScriptsAnnotation)
+	}
+	sort.Strings(config.Modules)
+
+	variables, err := parser.GetStringAnnotation(njsSetAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil && ing_errors.IsValidationError(err) {
+		return config, err
+	}
+	if err == nil {
+		config.Variables, err = parseVariables(variables, config.Modules)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	config.Directory = scriptsDirectory(a.scriptsDirectory, key, config.Modules, cmap.Data)
+	config.Scripts = cmap.Data
+	config.ConfigMap = key
+
+	return config, nil
+}
+
+// parseVariables returns the variables of the njs-set annotation, whose
+// functions must be the ones of the imported modules
+func parseVariables(value string, modules []string) ([]Variable, error) {
+	variables := []Variable{}
+	for _, entry := range strings.Split(strings.ReplaceAll(value, " ", ""), ",") {
+		if entry == "" {
+			continue
+		}
+
+		name, function, _ := strings.Cut(entry, "=")
+		module, function, _ := strings.Cut(function, ".")
+		if !slices.Contains(modules, module) {
+			return nil, ing_errors.NewLocationDenied(fmt.Sprintf("the njs module %v of the variable %v is not in the scripts", module, name))
+		}
+
+		variables = append(variables, Variable{Name: name, Module: module, Function: function})
+	}
+
+	return variables, nil
+}
+
+// scriptsDirectory returns the directory of the scripts of the ConfigMap,
+// named after the ConfigMap and the checksum of its scripts: a change of the
+// scripts changes the directory of the locations
+func scriptsDirectory(directory, key string, modules []string, scripts map[string]string) string {
+	//nolint:gosec // Not used for security, only to name the scripts directories
+	checksum := sha1.New()
+	for _, module := range modules {
+		fmt.Fprintf(checksum, "%v\x00%v\x00", module, scripts[module+".js"])
+	}
+
+	return fmt.Sprintf("%v/%v-%x", directory, strings.ReplaceAll(key, "/", "-"), checksum.Sum(nil)[:6])
+}
+
+// WriteScripts writes the scripts of a location when their directory is
+// missing, before the configuration referencing them is tested. The scripts
+// are written to a temporary directory renamed to the directory, NGINX never
+// reads a partial directory.
+func WriteScripts(config *Config) error {
+	if config == nil || config.Directory == "" {
+		return nil
+	}
+	if _, err := os.Stat(config.Directory); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Directory), file.ReadWriteByUser); err != nil {
+		return fmt.Errorf("unexpected error creating the directory of the njs scripts: %w", err)
+	}
+	tmpDirectory, err := os.MkdirTemp(filepath.Dir(config.Directory), filepath.Base(config.Directory)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unexpected error creating the directory of the njs scripts: %w", err)
+	}
+	defer os.RemoveAll(tmpDirectory)
+
+	for _, module := range config.Modules {
+		filename := filepath.Join(tmpDirectory, module+".js")
+		if err := os.WriteFile(filename, []byte(config.Scripts[module+".js"]), file.ReadWriteByUser); err != nil {
+			return fmt.Errorf("unexpected error writing the njs script %v: %w", module, err)
+		}
+	}
+
+	if err := os.Rename(tmpDirectory, config.Directory); err != nil {
+		return fmt.Errorf("unexpected error writing the njs scripts of %v: %w", config.ConfigMap, err)
+	}
+
+	return nil
+}
+
+func (a njs) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a njs) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, njsAnnotations.Annotations)
+}
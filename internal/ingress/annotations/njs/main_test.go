@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package njs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	scripts := parser.GetAnnotationWithPrefix(njsScriptsAnnotation)
+	set := parser.GetAnnotationWithPrefix(njsSetAnnotation)
+
+	r := &resolver.Mock{
+		ConfigMaps: map[string]*api.ConfigMap{
+			"default/scripts": {
+				Data: map[string]string{
+					"tenant.js": "function get(r) { return r.headersIn['X-Tenant'] || 'none'; }\nexport default { get };",
+					"utils.js":  "export default {};",
+				},
+			},
+			"default/invalid-keys": {
+				Data: map[string]string{"../tenant.js": "export default {};"},
+			},
+		},
+	}
+	directory := t.TempDir()
+	ap := njs{r: r, scriptsDirectory: directory, annotationConfig: njsAnnotations}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	ing.SetAnnotations(map[string]string{})
+	result, err := ap.Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.(*Config).Equal(&Config{}) {
+		t.Errorf("expected an empty configuration but returned %+v", result)
+	}
+
+	ing.SetAnnotations(map[string]string{scripts: "scripts", set: "tenant=tenant.get, tenant_copy=tenant.get"})
+	result, err = ap.Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, ok := result.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type but returned %T", result)
+	}
+	expected := &Config{
+		ConfigMap: "default/scripts",
+		Directory: config.Directory,
+		Modules:   []string{"tenant", "utils"},
+		Variables: []Variable{
+			{Name: "tenant", Module: "tenant", Function: "get"},
+			{Name: "tenant_copy", Module: "tenant", Function: "get"},
+		},
+	}
+	if !config.Equal(expected) {
+		t.Errorf("expected %+v but returned %+v", expected, config)
+	}
+	if filepath.Dir(config.Directory) != directory {
+		t.Errorf("expected the scripts to be written in %v but returned %v", directory, config.Directory)
+	}
+	if _, err := os.Stat(config.Directory); !os.IsNotExist(err) {
+		t.Errorf("expected the scripts not to be written while parsing the annotations but returned %v", err)
+	}
+
+	if err := WriteScripts(config); err != nil {
+		t.Fatalf("unexpected error writing the scripts: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.Directory, "tenant.js"))
+	if err != nil {
+		t.Fatalf("unexpected error reading the script: %v", err)
+	}
+	if string(content) != r.ConfigMaps["default/scripts"].Data["tenant.js"] {
+		t.Errorf("unexpected content of the script: %v", string(content))
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected only the directory of the scripts to be left but returned %v, %v", entries, err)
+	}
+	if err := WriteScripts(config); err != nil {
+		t.Errorf("unexpected error writing the scripts again: %v", err)
+	}
+
+	invalidCases := map[string]map[string]string{
+		"invalid script names":   {scripts: "invalid-keys"},
+		"invalid variable":       {scripts: "scripts", set: "$tenant=tenant.get"},
+		"function of no module":  {scripts: "scripts", set: "tenant=other.get"},
+		"missing ConfigMap":      {scripts: "missing"},
+		"cross namespace":        {scripts: "other/scripts"},
+		"invalid ConfigMap name": {scripts: "scripts;"},
+	}
+	for name, annotations := range invalidCases {
+		ing.SetAnnotations(annotations)
+		if _, err := ap.Parse(ing); !errors.IsValidationError(err) && !errors.IsLocationDenied(err) {
+			t.Errorf("%v: expected an error but returned %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalization
+
+import (
+	networking "k8s.io/api/networking/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	normalizeMergeSlashesAnnotation           = "normalize-merge-slashes"
+	normalizePercentEncodingAnnotation        = "normalize-percent-encoding"
+	normalizeRejectEncodedTraversalAnnotation = "normalize-reject-encoded-traversal"
+	normalizeDuplicateHeadersAnnotation       = "normalize-duplicate-headers"
+)
+
+var normalizationAnnotations = parser.Annotation{
+	Group: "normalization",
+	Annotations: parser.AnnotationFields{
+		normalizeMergeSlashesAnnotation: {
+			Validator:     parser.ValidateBool,
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation redirects the requests whose path contains duplicate slashes to the path with the slashes merged, with a 308 response`,
+		},
+		normalizePercentEncodingAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation proxies the requests with the path NGINX routed them with, percent-decoded with the dot segments resolved
+			and the duplicate slashes merged, instead of the path sent by the client`,
+		},
+		normalizeRejectEncodedTraversalAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation denies with a 400 response the requests whose path contains an encoded dot, slash, backslash, NUL or percent,
+			or a NUL byte`,
+		},
+		normalizeDuplicateHeadersAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation merges the values of the request headers sent several times into one header, and denies with a 400 response
+			the requests sending several Host, Content-Length, Content-Type, Transfer-Encoding or Authorization headers`,
+		},
+	},
+}
+
+// Config describes the normalization of the requests of a location
+type Config struct {
+	MergeSlashes           bool `json:"mergeSlashes"`
+	PercentEncoding        bool `json:"percentEncoding"`
+	RejectEncodedTraversal bool `json:"rejectEncodedTraversal"`
+	DuplicateHeaders       bool `json:"duplicateHeaders"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type normalization struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new request normalization annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return normalization{
+		r:                r,
+		annotationConfig: normalizationAnnotations,
+	}
+}
+
+func (n normalization) getBool(name string, ing *networking.Ingress) bool {
+	value, err := parser.GetBoolAnnotation(name, ing, n.annotationConfig.Annotations)
+	return err == nil && value
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the normalization of the requests of the locations
+func (n normalization) Parse(ing *networking.Ingress) (interface{}, error) {
+	return &Config{
+		MergeSlashes:           n.getBool(normalizeMergeSlashesAnnotation, ing),
+		PercentEncoding:        n.getBool(normalizePercentEncodingAnnotation, ing),
+		RejectEncodedTraversal: n.getBool(normalizeRejectEncodedTraversalAnnotation, ing),
+		DuplicateHeaders:       n.getBool(normalizeDuplicateHeadersAnnotation, ing),
+	}, nil
+}
+
+func (n normalization) GetDocumentation() parser.AnnotationFields {
+	return n.annotationConfig.Annotations
+}
+
+func (n normalization) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(n.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, normalizationAnnotations.Annotations)
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package opentelemetry
 
 import (
+	"maps"
 	"regexp"
+	"strings"
 
 	networking "k8s.io/api/networking/v1"
 
@@ -30,9 +32,29 @@ const (
 	enableOpenTelemetryAnnotation = "enable-opentelemetry"
 	otelTrustSpanAnnotation       = "opentelemetry-trust-incoming-span"
 	otelOperationNameAnnotation   = "opentelemetry-operation-name"
+	otelSpanAttributesAnnotation  = "opentelemetry-span-attributes"
+	otelPropagatorAnnotation      = "opentelemetry-propagator"
+	otelForwardBaggageAnnotation  = "opentelemetry-forward-baggage"
 )
 
-var regexOperationName = regexp.MustCompile(`^[A-Za-z0-9_\-]*$`)
+const (
+	// PropagatorW3C propagates the W3C trace context headers
+	PropagatorW3C = "w3c"
+	// PropagatorB3 propagates the single b3 header
+	PropagatorB3 = "b3"
+	// PropagatorB3Multi propagates the X-B3-* headers
+	PropagatorB3Multi = "b3multi"
+	// PropagatorNone does not propagate the trace context to the upstreams
+	PropagatorNone = "none"
+)
+
+// Propagators are the trace context propagation formats
+var Propagators = []string{PropagatorW3C, PropagatorB3, PropagatorB3Multi, PropagatorNone}
+
+var (
+	regexOperationName  = regexp.MustCompile(`^[A-Za-z0-9_\-]*$`)
+	regexSpanAttributes = regexp.MustCompile(`^[A-Za-z0-9_.\-]+=[A-Za-z0-9_.\-/:@]*(,[A-Za-z0-9_.\-]+=[A-Za-z0-9_.\-/:@]*)*$`)
+)
 
 var otelAnnotations = parser.Annotation{
 	Group: "opentelemetry",
@@ -56,6 +78,25 @@ var otelAnnotations = parser.Annotation{
 			Risk:          parser.AnnotationRiskMedium,
 			Documentation: `This annotation defines what operation name should be added to the span`,
 		},
+		otelSpanAttributesAnnotation: {
+			Validator: parser.ValidateRegex(regexSpanAttributes, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines a comma separated list of key=value attributes added to the spans of the location,
+			in addition to the attributes of the opentelemetry-span-attributes configuration`,
+		},
+		otelPropagatorAnnotation: {
+			Validator:     parser.ValidateOptions(Propagators, true, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the trace context format accepted from the clients and sent to the upstreams: w3c, b3, b3multi or none`,
+		},
+		otelForwardBaggageAnnotation: {
+			Validator:     parser.ValidateBool,
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines if the baggage header of the requests is forwarded to the upstreams`,
+		},
 	},
 }
 
@@ -71,6 +112,13 @@ type Config struct {
 	TrustEnabled  bool   `json:"trust-enabled"`
 	TrustSet      bool   `json:"trust-set"`
 	OperationName string `json:"operation-name"`
+	// SpanAttributes are the attributes added to the spans
+	SpanAttributes map[string]string `json:"span-attributes,omitempty"`
+	// Propagator is the trace context format, empty to use the one of the configuration
+	Propagator string `json:"propagator,omitempty"`
+	// ForwardBaggage defines if the baggage header is forwarded to the upstreams
+	ForwardBaggage bool `json:"forward-baggage"`
+	BaggageSet     bool `json:"baggage-set"`
 }
 
 // Equal tests for equality between two Config types
@@ -95,6 +143,22 @@ func (bd1 *Config) Equal(bd2 *Config) bool {
 		return false
 	}
 
+	if !maps.Equal(bd1.SpanAttributes, bd2.SpanAttributes) {
+		return false
+	}
+
+	if bd1.Propagator != bd2.Propagator {
+		return false
+	}
+
+	if bd1.BaggageSet != bd2.BaggageSet {
+		return false
+	}
+
+	if bd1.ForwardBaggage != bd2.ForwardBaggage {
+		return false
+	}
+
 	return true
 }
 
@@ -109,6 +173,26 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 // Parse parses the annotations to look for opentelemetry configurations
 func (c opentelemetry) Parse(ing *networking.Ingress) (interface{}, error) {
 	cfg := Config{}
+	attributes, err := parser.GetStringAnnotation(otelSpanAttributesAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return nil, err
+	}
+	cfg.SpanAttributes = ParseSpanAttributes(attributes)
+
+	propagator, err := parser.GetStringAnnotation(otelPropagatorAnnotation, ing, c.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return nil, err
+	}
+	cfg.Propagator = strings.TrimSpace(propagator)
+
+	forwardBaggage, err := parser.GetBoolAnnotation(otelForwardBaggageAnnotation, ing, c.annotationConfig.Annotations)
+	if err == nil {
+		cfg.BaggageSet = true
+		cfg.ForwardBaggage = forwardBaggage
+	} else if errors.IsValidationError(err) {
+		return nil, err
+	}
+
 	enabled, err := parser.GetBoolAnnotation(enableOpenTelemetryAnnotation, ing, c.annotationConfig.Annotations)
 	if err != nil {
 		return &cfg, nil
@@ -145,6 +229,25 @@ func (c opentelemetry) Parse(ing *networking.Ingress) (interface{}, error) {
 	return &cfg, nil
 }
 
+// ParseSpanAttributes parses a comma separated list of key=value span
+// attributes. The entries without key are ignored.
+func ParseSpanAttributes(value string) map[string]string {
+	var attributes map[string]string
+	for _, entry := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if attributes == nil {
+			attributes = map[string]string{}
+		}
+		attributes[key] = strings.TrimSpace(val)
+	}
+
+	return attributes
+}
+
 func (c opentelemetry) GetDocumentation() parser.AnnotationFields {
 	return c.annotationConfig.Annotations
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package opentelemetry
 
 import (
+	"reflect"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -197,3 +198,80 @@ func TestIngressAnnotationOpentelemetryUnset(t *testing.T) {
 		t.Errorf("expected a Config type")
 	}
 }
+
+func TestIngressAnnotationOpentelemetrySpanAttributes(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(otelSpanAttributesAnnotation)] = "team=payments, tier=backend"
+	ing.SetAnnotations(data)
+
+	val, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openTelemetry, ok := val.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	if openTelemetry.Set {
+		t.Errorf("expected annotation value to be false, got true")
+	}
+
+	expected := map[string]string{"team": "payments", "tier": "backend"}
+	if !reflect.DeepEqual(openTelemetry.SpanAttributes, expected) {
+		t.Errorf("expected span attributes %v, got %v", expected, openTelemetry.SpanAttributes)
+	}
+}
+
+func TestIngressAnnotationOpentelemetryWithBadSpanAttributes(t *testing.T) {
+	for _, attributes := range []string{"team", "team=$host", `team=pay"ments`, "team=payments;", "=payments"} {
+		ing := buildIngress()
+
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix(otelSpanAttributesAnnotation)] = attributes
+		ing.SetAnnotations(data)
+
+		if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+			t.Errorf("expected an error for the span attributes %q", attributes)
+		}
+	}
+}
+
+func TestIngressAnnotationOpentelemetryPropagation(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(otelPropagatorAnnotation)] = PropagatorB3Multi
+	data[parser.GetAnnotationWithPrefix(otelForwardBaggageAnnotation)] = "false"
+	ing.SetAnnotations(data)
+
+	val, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openTelemetry, ok := val.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	if openTelemetry.Propagator != PropagatorB3Multi {
+		t.Errorf("expected propagator %v, got %v", PropagatorB3Multi, openTelemetry.Propagator)
+	}
+
+	if !openTelemetry.BaggageSet {
+		t.Errorf("expected annotation value to be true, got false")
+	}
+
+	if openTelemetry.ForwardBaggage {
+		t.Errorf("expected annotation value to be false, got true")
+	}
+
+	data[parser.GetAnnotationWithPrefix(otelPropagatorAnnotation)] = "jaeger"
+	ing.SetAnnotations(data)
+
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected an error for the jaeger propagator")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outlierdetection
+
+import (
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	consecutiveErrorsAnnotation  = "outlier-detection-consecutive-errors"
+	baseEjectionTimeAnnotation   = "outlier-detection-base-ejection-time"
+	maxEjectionTimeAnnotation    = "outlier-detection-max-ejection-time"
+	maxEjectionPercentAnnotation = "outlier-detection-max-ejection-percent"
+)
+
+const (
+	defaultBaseEjectionTime   = 30
+	defaultMaxEjectionTime    = 300
+	defaultMaxEjectionPercent = 50
+)
+
+var outlierDetectionAnnotations = parser.Annotation{
+	Group: "backend",
+	Annotations: parser.AnnotationFields{
+		consecutiveErrorsAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables the passive outlier detection of the endpoints of the backend. An endpoint is ejected
+			from the load balancing rotation after the given number of consecutive 5xx responses or connection failures`,
+		},
+		baseEjectionTimeAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the time in seconds an endpoint is ejected the first time (default 30).
+			The ejection time is doubled every time the endpoint is ejected again`,
+		},
+		maxEjectionTimeAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the maximum time in seconds an endpoint is ejected (default 300)`,
+		},
+		maxEjectionPercentAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the maximum percentage of the endpoints of the backend that can be ejected at the same time (default 50)`,
+		},
+	},
+}
+
+// Config describes the passive outlier detection of the endpoints of a backend
+type Config struct {
+	ConsecutiveErrors  int `json:"consecutiveErrors"`
+	BaseEjectionTime   int `json:"baseEjectionTime"`
+	MaxEjectionTime    int `json:"maxEjectionTime"`
+	MaxEjectionPercent int `json:"maxEjectionPercent"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type outlierDetection struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new outlier detection annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return outlierDetection{
+		r:                r,
+		annotationConfig: outlierDetectionAnnotations,
+	}
+}
+
+func (o outlierDetection) getPositiveInt(name string, ing *networking.Ingress, defaultValue int) int {
+	value, err := parser.GetIntAnnotation(name, ing, o.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to %d", name, defaultValue)
+		}
+		return defaultValue
+	}
+
+	if value <= 0 {
+		klog.Warningf("%s must be greater than 0, defaulting to %d", name, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the outlier detection of the backends
+func (o outlierDetection) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	consecutiveErrors, err := parser.GetIntAnnotation(consecutiveErrorsAnnotation, ing, o.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, outlier detection is disabled", consecutiveErrorsAnnotation)
+			return config, nil
+		}
+		return config, err
+	}
+
+	if consecutiveErrors <= 0 {
+		return config, nil
+	}
+
+	config.ConsecutiveErrors = consecutiveErrors
+	config.BaseEjectionTime = o.getPositiveInt(baseEjectionTimeAnnotation, ing, defaultBaseEjectionTime)
+	config.MaxEjectionTime = o.getPositiveInt(maxEjectionTimeAnnotation, ing, defaultMaxEjectionTime)
+	config.MaxEjectionPercent = o.getPositiveInt(maxEjectionPercentAnnotation, ing, defaultMaxEjectionPercent)
+
+	if config.MaxEjectionTime < config.BaseEjectionTime {
+		klog.Warningf("%s is lower than %s, defaulting to %d", maxEjectionTimeAnnotation, baseEjectionTimeAnnotation, config.BaseEjectionTime)
+		config.MaxEjectionTime = config.BaseEjectionTime
+	}
+
+	if config.MaxEjectionPercent > 100 {
+		klog.Warningf("%s must not be greater than 100, defaulting to 100", maxEjectionPercentAnnotation)
+		config.MaxEjectionPercent = 100
+	}
+
+	return config, nil
+}
+
+func (o outlierDetection) GetDocumentation() parser.AnnotationFields {
+	return o.annotationConfig.Annotations
+}
+
+func (o outlierDetection) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(o.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, outlierDetectionAnnotations.Annotations)
+}
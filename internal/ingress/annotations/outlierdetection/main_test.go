@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outlierdetection
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	consecutiveErrors := parser.GetAnnotationWithPrefix(consecutiveErrorsAnnotation)
+	baseEjectionTime := parser.GetAnnotationWithPrefix(baseEjectionTimeAnnotation)
+	maxEjectionTime := parser.GetAnnotationWithPrefix(maxEjectionTimeAnnotation)
+	maxEjectionPercent := parser.GetAnnotationWithPrefix(maxEjectionPercentAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *Config
+	}{
+		{
+			name:        "disabled without annotations",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			name: "disabled with invalid consecutive errors",
+			annotations: map[string]string{
+				consecutiveErrors: "many",
+				baseEjectionTime:  "10",
+			},
+			expected: &Config{},
+		},
+		{
+			name: "defaults",
+			annotations: map[string]string{
+				consecutiveErrors: "5",
+			},
+			expected: &Config{
+				ConsecutiveErrors:  5,
+				BaseEjectionTime:   30,
+				MaxEjectionTime:    300,
+				MaxEjectionPercent: 50,
+			},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				consecutiveErrors:  "3",
+				baseEjectionTime:   "10",
+				maxEjectionTime:    "60",
+				maxEjectionPercent: "30",
+			},
+			expected: &Config{
+				ConsecutiveErrors:  3,
+				BaseEjectionTime:   10,
+				MaxEjectionTime:    60,
+				MaxEjectionPercent: 30,
+			},
+		},
+		{
+			name: "invalid values are defaulted",
+			annotations: map[string]string{
+				consecutiveErrors:  "3",
+				baseEjectionTime:   "-10",
+				maxEjectionTime:    "10",
+				maxEjectionPercent: "200",
+			},
+			expected: &Config{
+				ConsecutiveErrors:  3,
+				BaseEjectionTime:   30,
+				MaxEjectionTime:    30,
+				MaxEjectionPercent: 100,
+			},
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, _ := ap.Parse(ing)
+			config, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but returned %T", result)
+			}
+			if !config.Equal(testCase.expected) {
+				t.Errorf("expected %+v but returned %+v", testCase.expected, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// celRule returns a CEL expression checking the value of an annotation like
+// a validator, value being the CEL expression of the value
+type celRule func(value string) string
+
+// celValidator is an AnnotationValidator with a CEL equivalent
+type celValidator struct {
+	validate func(string) error
+	rule     celRule
+}
+
+// Validate validates the value of the annotation
+func (v celValidator) Validate(value string) error {
+	return v.validate(value)
+}
+
+// CELExpression returns the CEL expression equivalent to the validator for
+// the value, or false when the validator cannot be expressed in CEL. The
+// expressions never reject a value accepted by the validator.
+func CELExpression(v AnnotationValidator, value string) (string, bool) {
+	cv, ok := v.(celValidator)
+	if !ok {
+		return "", false
+	}
+
+	return cv.rule(value), true
+}
+
+func celString(s string) string {
+	return strconv.Quote(s)
+}
+
+func celMatches(value string, regex *regexp.Regexp) string {
+	return fmt.Sprintf("%s.matches(%s)", value, celString(regex.String()))
+}
+
+func celBool(value string) string {
+	options := []string{"1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE", "false", "False"}
+	quoted := make([]string, len(options))
+	for i, option := range options {
+		quoted[i] = celString(option)
+	}
+	return fmt.Sprintf("%s in [%s]", value, strings.Join(quoted, ", "))
+}
+
+func celInt(value string) string {
+	return celMatches(value, regexp.MustCompile(`^[+-]?[0-9]+$`))
+}
+
+func celServiceName(value string) string {
+	return fmt.Sprintf("%s.size() <= 63 && %s", value, celMatches(value, regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)))
+}
+
+func celServerName(value string) string {
+	return celMatches(value+".trim()", IsValidRegex)
+}
+
+func celArrayOfServerName(value string) string {
+	return fmt.Sprintf("%s.split(\",\").all(name, %s)", value, celMatches("name.trim()", IsValidRegex))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"testing"
+)
+
+func TestCELExpression(t *testing.T) {
+	testCases := map[string]struct {
+		validator AnnotationValidator
+		expected  string
+	}{
+		"bool": {
+			validator: ValidateBool,
+			expected:  `value in ["1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE", "false", "False"]`,
+		},
+		"int": {
+			validator: ValidateInt,
+			expected:  `value.matches("^[+-]?[0-9]+$")`,
+		},
+		"regex": {
+			validator: ValidateRegex(SizeRegex, true),
+			expected:  `value.replace(" ", "").matches("^(?i)\\d+[bkmg]?$")`,
+		},
+		"options": {
+			validator: ValidateOptions([]string{"on", "off"}, true, true),
+			expected:  `value.trim().matches("^(on|off)$")`,
+		},
+		"case insensitive options": {
+			validator: ValidateOptions([]string{"a.b"}, false, false),
+			expected:  `value.matches("(?i)^(a\\.b)$")`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expression, ok := CELExpression(tc.validator, "value")
+			if !ok {
+				t.Fatalf("expected a CEL expression")
+			}
+			if expression != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, expression)
+			}
+		})
+	}
+}
+
+func TestCELExpressionUnsupported(t *testing.T) {
+	for _, validator := range []AnnotationValidator{ValidateNull, ValidateDuration, nil, ValidatorFunc(func(string) error { return nil })} {
+		if expression, ok := CELExpression(validator, "value"); ok {
+			t.Errorf("expected no CEL expression but got %v", expression)
+		}
+	}
+}
@@ -221,7 +221,9 @@ func normalizeString(input string) string {
 
 var configmapAnnotations = sets.NewString(
 	"auth-proxy-set-header",
+	"custom-error-pages",
 	"fastcgi-params-configmap",
+	"njs-scripts",
 )
 
 // AnnotationsReferencesConfigmap checks if at least one annotation in the Ingress rule
@@ -232,7 +234,7 @@ func AnnotationsReferencesConfigmap(ing *networking.Ingress) bool {
 	}
 
 	for name := range ing.GetAnnotations() {
-		if configmapAnnotations.Has(name) {
+		if configmapAnnotations.Has(TrimAnnotationPrefix(name)) {
 			return true
 		}
 	}
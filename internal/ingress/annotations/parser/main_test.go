@@ -252,3 +252,26 @@ func TestStringToURL(t *testing.T) {
 		}
 	}
 }
+
+func TestAnnotationsReferencesConfigmap(t *testing.T) {
+	ing := buildIngress()
+
+	if AnnotationsReferencesConfigmap(ing) {
+		t.Errorf("expected an Ingress without annotations not to reference a ConfigMap")
+	}
+
+	ing.SetAnnotations(map[string]string{GetAnnotationWithPrefix("rewrite-target"): "/"})
+	if AnnotationsReferencesConfigmap(ing) {
+		t.Errorf("expected an Ingress with the rewrite-target annotation not to reference a ConfigMap")
+	}
+
+	ing.SetAnnotations(map[string]string{GetAnnotationWithPrefix("fastcgi-params-configmap"): "params"})
+	if !AnnotationsReferencesConfigmap(ing) {
+		t.Errorf("expected an Ingress with the fastcgi-params-configmap annotation to reference a ConfigMap")
+	}
+
+	ing.SetAnnotations(map[string]string{GetAnnotationWithPrefix("njs-scripts"): "scripts"})
+	if !AnnotationsReferencesConfigmap(ing) {
+		t.Errorf("expected an Ingress with the njs-scripts annotation to reference a ConfigMap")
+	}
+}
@@ -31,7 +31,18 @@ import (
 	"k8s.io/klog/v2"
 )
 
-type AnnotationValidator func(string) error
+// AnnotationValidator validates the value of an annotation
+type AnnotationValidator interface {
+	Validate(value string) error
+}
+
+// ValidatorFunc is an AnnotationValidator without a CEL equivalent
+type ValidatorFunc func(string) error
+
+// Validate validates the value of the annotation
+func (f ValidatorFunc) Validate(value string) error {
+	return f(value)
+}
 
 const (
 	AnnotationRiskLow AnnotationRisk = iota
@@ -74,6 +85,9 @@ var (
 	// RegexPathWithCapture allows entries that SHOULD start with "/" and may contain alphanumeric + capture
 	// character for regex based paths, like /something/$1/anything/$2
 	RegexPathWithCapture = regexp.MustCompile(`^/?[` + alphaNumericChars + `\/\$]*$`)
+	// RegexPathWithNamedCapture is like RegexPathWithCapture, also allowing the
+	// named captures in braces, like /something/${id}/anything
+	RegexPathWithNamedCapture = regexp.MustCompile(`^/?(?:[` + alphaNumericChars + `\/\$]|\$\{[A-Za-z_][A-Za-z0-9_]*\})*$`)
 	// HeadersVariable defines a regex that allows headers separated by comma
 	HeadersVariable = regexp.MustCompile(`^[A-Za-z0-9-_, ]*$`)
 	// URLWithNginxVariableRegex defines a url that can contain nginx variables.
@@ -83,43 +97,57 @@ var (
 
 // ValidateArrayOfServerName validates if all fields on a Server name annotation are
 // regexes. They can be *.something*, ~^www\d+\.example\.com$ but not fancy character
-func ValidateArrayOfServerName(value string) error {
-	for _, fqdn := range strings.Split(value, ",") {
-		if err := ValidateServerName(fqdn); err != nil {
-			return err
+var ValidateArrayOfServerName AnnotationValidator = celValidator{
+	validate: func(value string) error {
+		for _, fqdn := range strings.Split(value, ",") {
+			if err := ValidateServerName.Validate(fqdn); err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	},
+	rule: celArrayOfServerName,
 }
 
 // ValidateServerName validates if the passed value is an acceptable server name. The server name
 // can contain regex characters, as those are accepted values on nginx configuration
-func ValidateServerName(value string) error {
-	value = strings.TrimSpace(value)
-	if !IsValidRegex.MatchString(value) {
-		return fmt.Errorf("value %s is invalid server name", value)
-	}
-	return nil
+var ValidateServerName AnnotationValidator = celValidator{
+	validate: func(value string) error {
+		value = strings.TrimSpace(value)
+		if !IsValidRegex.MatchString(value) {
+			return fmt.Errorf("value %s is invalid server name", value)
+		}
+		return nil
+	},
+	rule: celServerName,
 }
 
 // ValidateRegex receives a regex as an argument and uses it to validate
 // the value of the field.
 // Annotation can define if the spaces should be trimmed before validating the value
 func ValidateRegex(regex *regexp.Regexp, removeSpace bool) AnnotationValidator {
-	return func(s string) error {
-		if removeSpace {
-			s = strings.ReplaceAll(s, " ", "")
-		}
-		if !regex.MatchString(s) {
-			return fmt.Errorf("value %s is invalid", s)
-		}
-		return nil
+	return celValidator{
+		validate: func(s string) error {
+			if removeSpace {
+				s = strings.ReplaceAll(s, " ", "")
+			}
+			if !regex.MatchString(s) {
+				return fmt.Errorf("value %s is invalid", s)
+			}
+			return nil
+		},
+		rule: func(value string) string {
+			if removeSpace {
+				value += `.replace(" ", "")`
+			}
+			return celMatches(value, regex)
+		},
 	}
 }
 
 // CommonNameAnnotationValidator checks whether the annotation value starts with
 // 'CN=' and is followed by a valid regex.
-func CommonNameAnnotationValidator(s string) error {
+var CommonNameAnnotationValidator AnnotationValidator = ValidatorFunc(func(s string) error {
 	if !strings.HasPrefix(s, "CN=") {
 		return fmt.Errorf("value %s is not a valid Common Name annotation: missing prefix 'CN='", s)
 	}
@@ -129,65 +157,98 @@ func CommonNameAnnotationValidator(s string) error {
 	}
 
 	return nil
-}
+})
 
 // ValidateOptions receives an array of valid options that can be the value of annotation.
 // If no valid option is found, it will return an error
 func ValidateOptions(options []string, caseSensitive, trimSpace bool) AnnotationValidator {
-	return func(s string) error {
-		if trimSpace {
-			s = strings.TrimSpace(s)
-		}
-		if !caseSensitive {
-			s = strings.ToLower(s)
-		}
-		for _, option := range options {
-			if s == option {
-				return nil
+	return celValidator{
+		validate: func(s string) error {
+			if trimSpace {
+				s = strings.TrimSpace(s)
 			}
-		}
-		return fmt.Errorf("value does not match any valid option")
+			if !caseSensitive {
+				s = strings.ToLower(s)
+			}
+			for _, option := range options {
+				if s == option {
+					return nil
+				}
+			}
+			return fmt.Errorf("value does not match any valid option")
+		},
+		rule: func(value string) string {
+			if trimSpace {
+				value += ".trim()"
+			}
+			quoted := make([]string, len(options))
+			for i, option := range options {
+				quoted[i] = regexp.QuoteMeta(option)
+			}
+			pattern := "^(" + strings.Join(quoted, "|") + ")$"
+			if !caseSensitive {
+				// the options are lower case, the value is lowered with the
+				// Unicode case folding of the regular expression
+				pattern = "(?i)" + pattern
+			}
+			return celMatches(value, regexp.MustCompile(pattern))
+		},
 	}
 }
 
 // ValidateBool validates if the specified value is a bool
-func ValidateBool(value string) error {
-	_, err := strconv.ParseBool(value)
-	return err
+var ValidateBool AnnotationValidator = celValidator{
+	validate: func(value string) error {
+		_, err := strconv.ParseBool(value)
+		return err
+	},
+	rule: celBool,
 }
 
 // ValidateInt validates if the specified value is an integer
-func ValidateInt(value string) error {
-	_, err := strconv.Atoi(value)
-	return err
+var ValidateInt AnnotationValidator = celValidator{
+	validate: func(value string) error {
+		_, err := strconv.Atoi(value)
+		return err
+	},
+	rule: celInt,
 }
 
+// ValidateFloat validates if the specified value is a float
+var ValidateFloat AnnotationValidator = ValidatorFunc(func(value string) error {
+	_, err := strconv.ParseFloat(value, 32)
+	return err
+})
+
 // ValidateCIDRs validates if the specified value is an array of IPs and CIDRs
-func ValidateCIDRs(value string) error {
+var ValidateCIDRs AnnotationValidator = ValidatorFunc(func(value string) error {
 	_, err := net.ParseCIDRs(value)
 	return err
-}
+})
 
 // ValidateDuration validates if the specified value is a valid time
-func ValidateDuration(value string) error {
+var ValidateDuration AnnotationValidator = ValidatorFunc(func(value string) error {
 	_, err := time.ParseDuration(value)
 	return err
-}
+})
 
 // ValidateNull always return null values and should not be widely used.
 // It is used on the "snippet" annotations, as it is up to the admin to allow its
 // usage, knowing it can be critical!
-func ValidateNull(_ string) error {
+var ValidateNull AnnotationValidator = ValidatorFunc(func(_ string) error {
 	return nil
-}
+})
 
 // ValidateServiceName validates if a provided service name is a valid string
-func ValidateServiceName(value string) error {
-	errs := machineryvalidation.NameIsDNS1035Label(value, false)
-	if len(errs) != 0 {
-		return fmt.Errorf("annotation does not contain a valid service name: %+v", errs)
-	}
-	return nil
+var ValidateServiceName AnnotationValidator = celValidator{
+	validate: func(value string) error {
+		errs := machineryvalidation.NameIsDNS1035Label(value, false)
+		if len(errs) != 0 {
+			return fmt.Errorf("annotation does not contain a valid service name: %+v", errs)
+		}
+		return nil
+	},
+	rule: celServiceName,
 }
 
 // checkAnnotation will check each annotation for:
@@ -233,7 +294,7 @@ func checkAnnotation(name string, ing *networking.Ingress, fields AnnotationFiel
 		}
 		// We don't run validation against empty values
 		if EnableAnnotationValidation && annotationValue != "" {
-			if err := validateFunc(annotationValue); err != nil {
+			if err := validateFunc.Validate(annotationValue); err != nil {
 				klog.Warningf("validation error on ingress %s/%s: annotation %s contains invalid value %s", ing.GetNamespace(), ing.GetName(), name, annotationValue)
 				return "", ing_errors.NewValidationError(annotationFullName)
 			}
@@ -63,7 +63,7 @@ func TestValidateArrayOfServerName(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := ValidateArrayOfServerName(tt.value); (err != nil) != tt.wantErr {
+			if err := ValidateArrayOfServerName.Validate(tt.value); (err != nil) != tt.wantErr {
 				t.Errorf("ValidateArrayOfServerName() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -104,7 +104,7 @@ func Test_checkAnnotation(t *testing.T) {
 				},
 				fields: AnnotationFields{
 					"otherannotation": AnnotationConfig{
-						Validator: func(_ string) error { return nil },
+						Validator: ValidatorFunc(func(_ string) error { return nil }),
 					},
 				},
 			},
@@ -171,12 +171,12 @@ func Test_checkAnnotation(t *testing.T) {
 				},
 				fields: AnnotationFields{
 					"some-new-annotation": AnnotationConfig{
-						Validator: func(value string) error {
+						Validator: ValidatorFunc(func(value string) error {
 							if value != "xpto" {
 								return fmt.Errorf("this is an error")
 							}
 							return nil
-						},
+						}),
 					},
 				},
 			},
@@ -196,12 +196,12 @@ func Test_checkAnnotation(t *testing.T) {
 				},
 				fields: AnnotationFields{
 					"some-other-annotation": AnnotationConfig{
-						Validator: func(value string) error {
+						Validator: ValidatorFunc(func(value string) error {
 							if value != "xpto" {
 								return fmt.Errorf("this is an error")
 							}
 							return nil
-						},
+						}),
 					},
 				},
 			},
@@ -357,7 +357,7 @@ func TestCommonNameAnnotationValidator(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := CommonNameAnnotationValidator(tt.annotation); (err != nil) != tt.wantErr {
+			if err := CommonNameAnnotationValidator.Validate(tt.annotation); (err != nil) != tt.wantErr {
 				t.Errorf("CommonNameAnnotationValidator() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
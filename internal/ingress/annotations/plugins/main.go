@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	networking "k8s.io/api/networking/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const pluginsAnnotation = "plugins"
+
+// maxPlugins is the maximum number of plugins the controller loads, see plugins.lua
+const maxPlugins = 20
+
+// pluginsDirectory contains the installed plugins, one directory with a main.lua file per plugin
+var pluginsDirectory = "/etc/nginx/lua/plugins"
+
+// nameRegex matches the names of the plugins, which are Lua module names
+var nameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+var pluginsAnnotations = parser.Annotation{
+	Group: "plugins",
+	Annotations: parser.AnnotationFields{
+		pluginsAnnotation: {
+			Validator: parser.ValidatorFunc(validatePlugins),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation configures the Lua plugins of this Ingress as a JSON object with the names of the plugins as keys.
+			A JSON object enables the plugin with this configuration, true enables it without configuration and false disables a plugin enabled in the ConfigMap`,
+		},
+	},
+}
+
+// Plugin describes the configuration of a plugin for the locations of an Ingress
+type Plugin struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Config is the compact JSON configuration passed to the plugin
+	Config string `json:"config,omitempty"`
+}
+
+// Config describes the plugins of a location, sorted by name
+type Config struct {
+	Plugins []Plugin `json:"plugins,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.Plugins) != len(c2.Plugins) {
+		return false
+	}
+	for i, plugin := range c1.Plugins {
+		if plugin != c2.Plugins[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type plugins struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new plugins annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return plugins{
+		r:                r,
+		annotationConfig: pluginsAnnotations,
+	}
+}
+
+// parsePlugins returns the plugins of the annotation sorted by name. The
+// referenced plugins must be installed.
+func parsePlugins(value string) ([]Plugin, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("invalid plugins configuration: %w", err)
+	}
+
+	if len(raw) > maxPlugins {
+		return nil, fmt.Errorf("more than %d plugins are configured", maxPlugins)
+	}
+
+	result := make([]Plugin, 0, len(raw))
+	for name, rawConfig := range raw {
+		if !nameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid plugin name %q", name)
+		}
+
+		if _, err := os.Stat(filepath.Join(pluginsDirectory, name, "main.lua")); err != nil {
+			return nil, fmt.Errorf("plugin %q is not installed in %s", name, pluginsDirectory)
+		}
+
+		plugin := Plugin{Name: name}
+		switch trimmed := bytes.TrimSpace(rawConfig); {
+		case bytes.Equal(trimmed, []byte("false")):
+		case bytes.Equal(trimmed, []byte("true")):
+			plugin.Enabled = true
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			var config bytes.Buffer
+			if err := json.Compact(&config, trimmed); err != nil {
+				return nil, fmt.Errorf("invalid configuration of plugin %q: %w", name, err)
+			}
+			plugin.Enabled = true
+			plugin.Config = config.String()
+		default:
+			return nil, fmt.Errorf("the configuration of plugin %q must be a JSON object, true or false", name)
+		}
+
+		result = append(result, plugin)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func validatePlugins(value string) error {
+	_, err := parsePlugins(value)
+	return err
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the plugins of the locations
+func (p plugins) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	value, err := parser.GetStringAnnotation(pluginsAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil {
+		// the Ingresses referencing plugins which are not installed are rejected
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+		return config, nil
+	}
+
+	config.Plugins, err = parsePlugins(value)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+func (p plugins) GetDocumentation() parser.AnnotationFields {
+	return p.annotationConfig.Annotations
+}
+
+func (p plugins) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(p.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, pluginsAnnotations.Annotations)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	pluginsDirectory = t.TempDir()
+	for _, name := range []string{"hello_world", "open_idc"} {
+		if err := os.MkdirAll(filepath.Join(pluginsDirectory, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pluginsDirectory, name, "main.lua"), []byte("return {}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	annotation := parser.GetAnnotationWithPrefix(pluginsAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{
+			name:        "without annotations",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			name: "plugins sorted by name",
+			annotations: map[string]string{
+				annotation: `{"open_idc": {"issuer": "https://sso.example.com", "scopes": ["openid"]}, "hello_world": false}`,
+			},
+			expected: &Config{Plugins: []Plugin{
+				{Name: "hello_world"},
+				{Name: "open_idc", Enabled: true, Config: `{"issuer":"https://sso.example.com","scopes":["openid"]}`},
+			}},
+		},
+		{
+			name: "plugin enabled without configuration",
+			annotations: map[string]string{
+				annotation: `{"hello_world": true}`,
+			},
+			expected: &Config{Plugins: []Plugin{{Name: "hello_world", Enabled: true}}},
+		},
+		{
+			name: "plugin not installed",
+			annotations: map[string]string{
+				annotation: `{"hello_world": true, "missing": {}}`,
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid plugin name",
+			annotations: map[string]string{
+				annotation: `{"../hello_world": true}`,
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid plugin configuration",
+			annotations: map[string]string{
+				annotation: `{"hello_world": "enabled"}`,
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid JSON",
+			annotations: map[string]string{
+				annotation: `hello_world`,
+			},
+			expectErr: true,
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, err := ap.Parse(ing)
+			if testCase.expectErr {
+				if !errors.IsValidationError(err) {
+					t.Fatalf("expected a validation error but returned %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but returned %T", result)
+			}
+			if !config.Equal(testCase.expected) {
+				t.Errorf("expected %+v but returned %+v", testCase.expected, config)
+			}
+		})
+	}
+}
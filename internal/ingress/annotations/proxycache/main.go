@@ -0,0 +1,299 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxycache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	proxyCacheZoneAnnotation   = "proxy-cache-zone"
+	proxyCacheValidAnnotation  = "proxy-cache-valid"
+	proxyCacheKeyAnnotation    = "proxy-cache-key"
+	proxyCacheBypassAnnotation = "proxy-cache-bypass"
+
+	proxyCacheUseStaleAnnotation         = "proxy-cache-use-stale"
+	proxyCacheBackgroundUpdateAnnotation = "proxy-cache-background-update"
+)
+
+// DefaultKey is the cache key of the locations without proxy-cache-key, the
+// responses of the hosts sharing a backend are cached separately
+const DefaultKey = "$scheme$host$request_uri"
+
+// ScopedKey returns the cache key of the responses of the locations of an
+// Ingress: the key of the location is prefixed with the host of the request
+// and the Ingress, so a proxy-cache-key without the host does not serve the
+// responses of a host or of an Ingress to the others sharing the cache zone.
+func ScopedKey(namespace, name, key string) string {
+	return fmt.Sprintf("$host:%v/%v:%v", namespace, name, key)
+}
+
+// authorizationBypass is always a bypass condition, the responses of the
+// authenticated requests are not cached nor served from the cache
+const authorizationBypass = "$http_authorization"
+
+var (
+	zoneNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	// keyRegex matches the keys composed of variables and literal characters,
+	// without quotes, spaces or semicolons
+	keyRegex = regexp.MustCompile(`^[$A-Za-z0-9_\-/:.{}]+$`)
+	// bypassRegex matches a list of variables separated by commas or spaces
+	bypassRegex     = regexp.MustCompile(`^(\$[A-Za-z0-9_]+[, ]*)+$`)
+	statusCodeRegex = regexp.MustCompile(`^([1-5]\d{2}|any)$`)
+	durationRegex   = regexp.MustCompile(`^\d+(ms|s|m|h|d|w|M|y)$`)
+	// useStaleRegex matches a list of the conditions of proxy_cache_use_stale
+	// separated by commas or spaces
+	useStaleRegex = regexp.MustCompile(`^((error|timeout|invalid_header|updating|http_500|http_502|http_503|http_504|http_403|http_404|http_429|off)[, ]*)+$`)
+)
+
+var proxyCacheAnnotations = parser.Annotation{
+	Group: "proxy-cache",
+	Annotations: parser.AnnotationFields{
+		proxyCacheZoneAnnotation: {
+			Validator: parser.ValidateRegex(zoneNameRegex, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables the caching of the responses of this location in a zone of the proxy-cache-zones
+			of the ConfigMap`,
+		},
+		proxyCacheValidAnnotation: {
+			Validator: parser.ValidatorFunc(validateValid),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the comma separated caching times of the responses by status code, like 200 302 10m, 404 1m.
+			Without it only the responses with caching headers are cached`,
+		},
+		proxyCacheKeyAnnotation: {
+			Validator:     parser.ValidateRegex(keyRegex, false),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the cache key of the responses with NGINX variables. Defaults to $scheme$host$request_uri`,
+		},
+		proxyCacheBypassAnnotation: {
+			Validator: parser.ValidateRegex(bypassRegex, false),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the NGINX variables of the requests not served from the cache nor cached when one of
+			them is not empty nor 0, like $cookie_session. The requests with an Authorization header always bypass the cache`,
+		},
+		proxyCacheUseStaleAnnotation: {
+			Validator: parser.ValidatorFunc(validateUseStale),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the conditions the stale cached responses are served in, like updating or error, with
+			the values of proxy_cache_use_stale. Without it, the stale responses are served as permitted by the stale-while-revalidate
+			and stale-if-error extensions of their Cache-Control header`,
+		},
+		proxyCacheBackgroundUpdateAnnotation: {
+			Validator:     parser.ValidateBool,
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation updates the expired cached responses with background requests while the stale responses are served. Defaults to true`,
+		},
+	},
+}
+
+// Config describes the caching of the responses of a location
+type Config struct {
+	// Zone is the name of the cache zone, the responses are not cached when
+	// it is empty
+	Zone string `json:"zone,omitempty"`
+	// Valid are the caching times by status code
+	Valid  []string `json:"valid,omitempty"`
+	Key    string   `json:"key,omitempty"`
+	Bypass []string `json:"bypass,omitempty"`
+	// UseStale are the conditions the stale responses are served in
+	UseStale []string `json:"useStale,omitempty"`
+	// BackgroundUpdate updates the expired responses in the background while
+	// the stale responses are served
+	BackgroundUpdate bool `json:"backgroundUpdate,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Zone != c2.Zone || c1.Key != c2.Key || c1.BackgroundUpdate != c2.BackgroundUpdate {
+		return false
+	}
+	if len(c1.Valid) != len(c2.Valid) || len(c1.Bypass) != len(c2.Bypass) || len(c1.UseStale) != len(c2.UseStale) {
+		return false
+	}
+	for i, valid := range c1.Valid {
+		if valid != c2.Valid[i] {
+			return false
+		}
+	}
+	for i, bypass := range c1.Bypass {
+		if bypass != c2.Bypass[i] {
+			return false
+		}
+	}
+	for i, condition := range c1.UseStale {
+		if condition != c2.UseStale[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type proxyCache struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new proxy cache annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxyCache{
+		r:                r,
+		annotationConfig: proxyCacheAnnotations,
+	}
+}
+
+// parseValid returns the caching times of a comma separated list of status
+// codes followed by a time, like 200 302 10m, 404 1m
+func parseValid(value string) ([]string, error) {
+	valid := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		elements := strings.Fields(entry)
+		if len(elements) == 0 {
+			continue
+		}
+
+		for _, code := range elements[:len(elements)-1] {
+			if !statusCodeRegex.MatchString(code) {
+				return nil, fmt.Errorf("invalid status code %q", code)
+			}
+		}
+		if !durationRegex.MatchString(elements[len(elements)-1]) {
+			return nil, fmt.Errorf("invalid caching time %q", elements[len(elements)-1])
+		}
+
+		valid = append(valid, strings.Join(elements, " "))
+	}
+
+	return valid, nil
+}
+
+func validateValid(value string) error {
+	_, err := parseValid(value)
+	return err
+}
+
+func splitVariables(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// validateUseStale checks the conditions of proxy_cache_use_stale, off
+// cannot be combined with other conditions
+func validateUseStale(value string) error {
+	if !useStaleRegex.MatchString(value) {
+		return fmt.Errorf("invalid stale conditions %q", value)
+	}
+
+	conditions := splitVariables(value)
+	for _, condition := range conditions {
+		if condition == "off" && len(conditions) > 1 {
+			return fmt.Errorf("off cannot be combined with other stale conditions")
+		}
+	}
+
+	return nil
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the caching of the responses of the locations
+func (p proxyCache) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	zone, err := parser.GetStringAnnotation(proxyCacheZoneAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+		return config, nil
+	}
+	config.Zone = zone
+
+	valid, err := parser.GetStringAnnotation(proxyCacheValidAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return config, err
+	}
+	if err == nil {
+		config.Valid, err = parseValid(valid)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	config.Key, err = parser.GetStringAnnotation(proxyCacheKeyAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil {
+		config.Key = DefaultKey
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+	}
+
+	config.Bypass = []string{authorizationBypass}
+	bypass, err := parser.GetStringAnnotation(proxyCacheBypassAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return config, err
+	}
+	for _, variable := range splitVariables(bypass) {
+		if variable != authorizationBypass {
+			config.Bypass = append(config.Bypass, variable)
+		}
+	}
+
+	useStale, err := parser.GetStringAnnotation(proxyCacheUseStaleAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil && errors.IsValidationError(err) {
+		return config, err
+	}
+	config.UseStale = splitVariables(useStale)
+
+	config.BackgroundUpdate, err = parser.GetBoolAnnotation(proxyCacheBackgroundUpdateAnnotation, ing, p.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+		config.BackgroundUpdate = true
+	}
+
+	return config, nil
+}
+
+func (p proxyCache) GetDocumentation() parser.AnnotationFields {
+	return p.annotationConfig.Annotations
+}
+
+func (p proxyCache) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(p.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, proxyCacheAnnotations.Annotations)
+}
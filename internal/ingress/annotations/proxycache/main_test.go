@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxycache
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	zone := parser.GetAnnotationWithPrefix(proxyCacheZoneAnnotation)
+	valid := parser.GetAnnotationWithPrefix(proxyCacheValidAnnotation)
+	key := parser.GetAnnotationWithPrefix(proxyCacheKeyAnnotation)
+	bypass := parser.GetAnnotationWithPrefix(proxyCacheBypassAnnotation)
+	useStale := parser.GetAnnotationWithPrefix(proxyCacheUseStaleAnnotation)
+	backgroundUpdate := parser.GetAnnotationWithPrefix(proxyCacheBackgroundUpdateAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{map[string]string{}, &Config{}, false},
+		{map[string]string{valid: "200 10m"}, &Config{}, false},
+		{
+			map[string]string{zone: "static"},
+			&Config{Zone: "static", Key: DefaultKey, Bypass: []string{"$http_authorization"}, BackgroundUpdate: true},
+			false,
+		},
+		{
+			map[string]string{
+				zone:   "static",
+				valid:  "200  302 10m, 404 1m,any 5s",
+				key:    "$host$uri$arg_page",
+				bypass: "$cookie_session, $http_pragma $http_authorization",
+			},
+			&Config{
+				Zone:             "static",
+				Valid:            []string{"200 302 10m", "404 1m", "any 5s"},
+				Key:              "$host$uri$arg_page",
+				Bypass:           []string{"$http_authorization", "$cookie_session", "$http_pragma"},
+				BackgroundUpdate: true,
+			},
+			false,
+		},
+		{
+			map[string]string{zone: "static", useStale: "error timeout, updating http_503", backgroundUpdate: "false"},
+			&Config{
+				Zone:     "static",
+				Key:      DefaultKey,
+				Bypass:   []string{"$http_authorization"},
+				UseStale: []string{"error", "timeout", "updating", "http_503"},
+			},
+			false,
+		},
+		{map[string]string{zone: "static", key: "$host; return 200"}, &Config{Zone: "static", Key: DefaultKey}, true},
+		{map[string]string{zone: "Static"}, &Config{}, true},
+		{map[string]string{zone: "static", valid: "200 302"}, &Config{Zone: "static"}, true},
+		{map[string]string{zone: "static", valid: "600 10m"}, &Config{Zone: "static"}, true},
+		{map[string]string{zone: "static", valid: "200 10m; proxy_pass http://evil"}, &Config{Zone: "static"}, true},
+		{
+			map[string]string{zone: "static", bypass: "cookie_session"},
+			&Config{Zone: "static", Key: DefaultKey, Bypass: []string{"$http_authorization"}},
+			true,
+		},
+		{
+			map[string]string{zone: "static", useStale: "off updating"},
+			&Config{Zone: "static", Key: DefaultKey, Bypass: []string{"$http_authorization"}},
+			true,
+		},
+		{
+			map[string]string{zone: "static", useStale: "http_418"},
+			&Config{Zone: "static", Key: DefaultKey, Bypass: []string{"$http_authorization"}},
+			true,
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := ap.Parse(ing)
+		if testCase.expectErr && err == nil {
+			t.Errorf("expected an error with the annotations %v", testCase.annotations)
+		}
+		if !testCase.expectErr && err != nil {
+			t.Errorf("unexpected error with the annotations %v: %v", testCase.annotations, err)
+		}
+
+		config, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but returned %T", result)
+		}
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %+v but returned %+v with the annotations %v", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
+
+func TestScopedKey(t *testing.T) {
+	expected := "$host:default/shop:$uri"
+	if actual := ScopedKey("default", "shop", "$uri"); actual != expected {
+		t.Errorf("expected %v but returned %v", expected, actual)
+	}
+}
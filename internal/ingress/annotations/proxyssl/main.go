@@ -199,9 +199,8 @@ func (p proxySSL) Parse(ing *networking.Ingress) (interface{}, error) {
 		return &Config{}, ing_errors.NewLocationDenied(err.Error())
 	}
 
-	secCfg := p.r.GetSecurityConfiguration()
-	// We don't accept different namespaces for secrets.
-	if !secCfg.AllowCrossNamespaceResources && ns != ing.Namespace {
+	// We don't accept different namespaces for secrets, unless allowed.
+	if ns != ing.Namespace && !resolver.IsCrossNamespaceSecretAllowed(p.r, ing, proxysslsecret) {
 		return &Config{}, ing_errors.NewLocationDenied("cross namespace secrets are not supported")
 	}
 
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redirectmap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+const (
+	// RedirectMapAnnotation references the RedirectMap of the namespace of
+	// the Ingress redirecting the paths of its hosts
+	RedirectMapAnnotation = "redirect-map"
+
+	// DefaultStatusCode is the status code of the redirects of the
+	// RedirectMaps without statusCode
+	DefaultStatusCode = 301
+
+	// maxSourceLength is the length of the longest source, the buckets of
+	// the hash of the nginx map must fit the sources
+	maxSourceLength = 2048
+)
+
+var (
+	regexRedirectMapName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// the sources and targets are quoted in the nginx map, they cannot
+	// contain variables, quotes, escapes or spaces
+	sourceRegex = regexp.MustCompile(`^/[^\s"'\\{};$]*$`)
+	targetRegex = regexp.MustCompile(`^(https?://|/)[^\s"'\\{};$]*$`)
+)
+
+var validStatusCodes = map[int]bool{
+	301: true,
+	302: true,
+	307: true,
+	308: true,
+}
+
+var redirectMapAnnotations = parser.Annotation{
+	Group: "redirect",
+	Annotations: parser.AnnotationFields{
+		RedirectMapAnnotation: {
+			Validator: parser.ValidateRegex(regexRedirectMapName, true),
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation references a RedirectMap of the namespace of the Ingress, redirecting the paths of its sources
+			to their targets on the hosts of the Ingress`,
+		},
+	},
+}
+
+// Config returns the redirects of the hosts of an Ingress
+type Config struct {
+	// Name is the namespace and name of the RedirectMap
+	Name                string     `json:"name,omitempty"`
+	StatusCode          int        `json:"statusCode,omitempty"`
+	PreserveQueryString bool       `json:"preserveQueryString,omitempty"`
+	Redirects           []Redirect `json:"redirects,omitempty"`
+}
+
+// Redirect redirects the requests of the exact path Source to Target
+type Redirect struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Name != c2.Name {
+		return false
+	}
+	if c1.StatusCode != c2.StatusCode {
+		return false
+	}
+	if c1.PreserveQueryString != c2.PreserveQueryString {
+		return false
+	}
+	if len(c1.Redirects) != len(c2.Redirects) {
+		return false
+	}
+	for i := range c1.Redirects {
+		if c1.Redirects[i] != c2.Redirects[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type redirectMap struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new redirect map annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return redirectMap{
+		r:                r,
+		annotationConfig: redirectMapAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress to read the
+// redirects of the RedirectMap it references
+func (a redirectMap) Parse(ing *networking.Ingress) (interface{}, error) {
+	name, err := parser.GetStringAnnotation(RedirectMapAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return nil, err
+		}
+		return &Config{}, nil
+	}
+
+	key := fmt.Sprintf("%v/%v", ing.Namespace, name)
+	rm, err := a.r.GetRedirectMap(key)
+	if err != nil {
+		// the hosts of the Ingresses referencing a missing RedirectMap are
+		// not redirected
+		klog.Warningf("Ignoring the RedirectMap of the Ingress %v/%v: unexpected error reading RedirectMap %v: %v", ing.Namespace, ing.Name, key, err)
+		return &Config{}, nil
+	}
+
+	config, err := newConfig(key, rm)
+	if err != nil {
+		klog.Warningf("Ignoring the RedirectMap of the Ingress %v/%v: invalid RedirectMap %v: %v", ing.Namespace, ing.Name, key, err)
+		return &Config{}, nil
+	}
+
+	return config, nil
+}
+
+// newConfig returns the valid redirects of the RedirectMap, the first
+// redirect of a source wins
+func newConfig(key string, rm *v1alpha1.RedirectMap) (*Config, error) {
+	config := &Config{
+		Name:                key,
+		StatusCode:          DefaultStatusCode,
+		PreserveQueryString: rm.Spec.PreserveQueryString,
+	}
+
+	if rm.Spec.StatusCode != nil {
+		config.StatusCode = int(*rm.Spec.StatusCode)
+		if !validStatusCodes[config.StatusCode] {
+			return nil, fmt.Errorf("the status code %v is not 301, 302, 307 or 308", config.StatusCode)
+		}
+	}
+
+	sources := map[string]bool{}
+	for _, redirect := range rm.Spec.Redirects {
+		if len(redirect.Source) > maxSourceLength || !sourceRegex.MatchString(redirect.Source) {
+			klog.Warningf("Ignoring the redirect of the source %q of the RedirectMap %v: invalid source", redirect.Source, key)
+			continue
+		}
+		if !targetRegex.MatchString(redirect.Target) {
+			klog.Warningf("Ignoring the redirect of the source %q of the RedirectMap %v: invalid target %q", redirect.Source, key, redirect.Target)
+			continue
+		}
+		if rm.Spec.PreserveQueryString && strings.Contains(redirect.Target, "?") {
+			klog.Warningf("Ignoring the redirect of the source %q of the RedirectMap %v: the target %q has a query string, the query string of the requests cannot be preserved",
+				redirect.Source, key, redirect.Target)
+			continue
+		}
+		if sources[redirect.Source] {
+			klog.Warningf("Ignoring the duplicate redirect of the source %q of the RedirectMap %v", redirect.Source, key)
+			continue
+		}
+
+		sources[redirect.Source] = true
+		config.Redirects = append(config.Redirects, Redirect{Source: redirect.Source, Target: redirect.Target})
+	}
+
+	return config, nil
+}
+
+func (a redirectMap) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a redirectMap) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, redirectMapAnnotations.Annotations)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redirectmap
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix(RedirectMapAnnotation)
+
+	found := int32(302)
+	invalid := int32(200)
+	ap := NewParser(&resolver.Mock{
+		RedirectMaps: map[string]*v1alpha1.RedirectMap{
+			"default/legacy": {
+				Spec: v1alpha1.RedirectMapSpec{
+					Redirects: []v1alpha1.Redirect{
+						{Source: "/about-us", Target: "https://example.com/company"},
+						{Source: "/jobs", Target: "/careers"},
+						{Source: "/jobs", Target: "/other"},
+						{Source: "/promo code", Target: "/promo"},
+						{Source: "/promo", Target: "/deals/$host"},
+						{Source: "products", Target: "/shop"},
+					},
+				},
+			},
+			"default/campaign": {
+				Spec: v1alpha1.RedirectMapSpec{
+					StatusCode:          &found,
+					PreserveQueryString: true,
+					Redirects: []v1alpha1.Redirect{
+						{Source: "/spring", Target: "https://shop.example.com/sale"},
+						{Source: "/summer", Target: "https://shop.example.com/sale?season=summer"},
+					},
+				},
+			},
+			"default/invalid": {
+				Spec: v1alpha1.RedirectMapSpec{
+					StatusCode: &invalid,
+					Redirects:  []v1alpha1.Redirect{{Source: "/old", Target: "/new"}},
+				},
+			},
+		},
+	})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{map[string]string{}, &Config{}, false},
+		{
+			map[string]string{annotation: "legacy"},
+			&Config{
+				Name:       "default/legacy",
+				StatusCode: 301,
+				Redirects: []Redirect{
+					{Source: "/about-us", Target: "https://example.com/company"},
+					{Source: "/jobs", Target: "/careers"},
+				},
+			},
+			false,
+		},
+		{
+			map[string]string{annotation: "campaign"},
+			&Config{
+				Name:                "default/campaign",
+				StatusCode:          302,
+				PreserveQueryString: true,
+				Redirects:           []Redirect{{Source: "/spring", Target: "https://shop.example.com/sale"}},
+			},
+			false,
+		},
+		{map[string]string{annotation: "invalid"}, &Config{}, false},
+		{map[string]string{annotation: "missing"}, &Config{}, false},
+		{map[string]string{annotation: "default/legacy"}, nil, true},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := ap.Parse(ing)
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("expected an error with the annotations %v", testCase.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error with the annotations %v: %v", testCase.annotations, err)
+		}
+
+		config, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but returned %T", result)
+		}
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %+v but returned %+v with the annotations %v", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
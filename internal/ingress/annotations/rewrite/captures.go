@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rewrite
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// namedGroupRegex matches the start of the named groups of PCRE,
+	// (?<name>, (?P<name> and (?'name'
+	namedGroupRegex = regexp.MustCompile(`^\(\?(?:P?<([A-Za-z_][A-Za-z0-9_]*)>|'([A-Za-z_][A-Za-z0-9_]*)')`)
+	// referenceRegex matches the captures and variables of a rewrite target,
+	// NGINX reads a single digit after $ as a numbered capture
+	referenceRegex = regexp.MustCompile(`\$(?:([0-9])|([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)\})`)
+)
+
+// targetVariables are the variables of NGINX and of the locations of the
+// controller a rewrite target can reference besides the capture groups
+var targetVariables = map[string]bool{
+	"args":               true,
+	"is_args":            true,
+	"query_string":       true,
+	"request_uri":        true,
+	"uri":                true,
+	"document_uri":       true,
+	"host":               true,
+	"hostname":           true,
+	"http_host":          true,
+	"best_http_host":     true,
+	"scheme":             true,
+	"pass_access_scheme": true,
+	"server_name":        true,
+	"server_port":        true,
+	"pass_server_port":   true,
+	"remote_addr":        true,
+	"request_method":     true,
+	"request_id":         true,
+	"req_id":             true,
+	"namespace":          true,
+	"ingress_name":       true,
+	"service_name":       true,
+	"service_port":       true,
+	"location_path":      true,
+}
+
+// targetVariablePrefixes are the prefixes of the variables of the headers,
+// query parameters and cookies of the requests
+var targetVariablePrefixes = []string{"http_", "arg_", "cookie_"}
+
+// captureGroups returns the number of capture groups of a PCRE regular
+// expression and the names of its named groups, or an error if the
+// parentheses or the character classes of the regular expression are not
+// balanced
+func captureGroups(re string) (int, map[string]bool, error) {
+	count := 0
+	names := map[string]bool{}
+	depth := 0
+	inClass := false
+
+	for i := 0; i < len(re); i++ {
+		c := re[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(re) {
+				return 0, nil, fmt.Errorf("the regular expression %q ends with a backslash", re)
+			}
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			// a ] at the start of a class is a literal character
+			if i+1 < len(re) && re[i+1] == '^' {
+				i++
+			}
+			if i+1 < len(re) && re[i+1] == ']' {
+				i++
+			}
+		case c == '(':
+			depth++
+			if match := namedGroupRegex.FindStringSubmatch(re[i:]); match != nil {
+				count++
+				names[match[1]+match[2]] = true
+			} else if i+1 == len(re) || (re[i+1] != '?' && re[i+1] != '*') {
+				count++
+			}
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return 0, nil, fmt.Errorf("the regular expression %q has an unmatched )", re)
+			}
+		}
+	}
+
+	if inClass {
+		return 0, nil, fmt.Errorf("the regular expression %q has an unterminated character class", re)
+	}
+	if depth > 0 {
+		return 0, nil, fmt.Errorf("the regular expression %q has an unmatched (", re)
+	}
+
+	return count, names, nil
+}
+
+// validateTargetCaptures returns an error if the rewrite target references a
+// name which is neither a named capture group of a path of the Ingress nor a
+// variable, NGINX failing to load the configuration with an unknown variable.
+// The numbered capture groups missing from a path are only logged, NGINX
+// replacing them with an empty string.
+func validateTargetCaptures(target string, ing *networking.Ingress) error {
+	references := referenceRegex.FindAllStringSubmatch(target, -1)
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			// the paths equal to the target are not rewritten
+			if path.Path == "" || path.Path == target {
+				continue
+			}
+
+			count, names, err := captureGroups(path.Path)
+			if err != nil {
+				klog.Warningf("Not validating the rewrite target %q of the Ingress %v/%v: %v", target, ing.Namespace, ing.Name, err)
+				continue
+			}
+
+			for _, reference := range references {
+				if reference[1] != "" {
+					index, _ := strconv.Atoi(reference[1])
+					if index > count {
+						klog.Warningf("The rewrite target %q of the Ingress %v/%v references the capture group %v but the path %q has %v capture groups, it is replaced with an empty string",
+							target, ing.Namespace, ing.Name, reference[0], path.Path, count)
+					}
+					continue
+				}
+
+				name := reference[2] + reference[3]
+				if names[name] || isTargetVariable(name) {
+					continue
+				}
+				return fmt.Errorf("the rewrite target %q references %v which is neither a named capture group of the path %q nor a variable",
+					target, reference[0], path.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isTargetVariable(name string) bool {
+	if targetVariables[name] {
+		return true
+	}
+
+	for _, prefix := range targetVariablePrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package rewrite
 
 import (
+	"fmt"
 	"net/url"
 
 	networking "k8s.io/api/networking/v1"
@@ -33,6 +34,7 @@ const (
 	preserveTrailingSlashAnnotation = "preserve-trailing-slash"
 	forceSSLRedirectAnnotation      = "force-ssl-redirect"
 	useRegexAnnotation              = "use-regex"
+	caseInsensitivePathsAnnotation  = "use-case-insensitive-paths"
 	appRootAnnotation               = "app-root"
 )
 
@@ -40,11 +42,11 @@ var rewriteAnnotations = parser.Annotation{
 	Group: "rewrite",
 	Annotations: parser.AnnotationFields{
 		rewriteTargetAnnotation: {
-			Validator: parser.ValidateRegex(parser.RegexPathWithCapture, false),
+			Validator: parser.ValidateRegex(parser.RegexPathWithNamedCapture, false),
 			Scope:     parser.AnnotationScopeIngress,
 			Risk:      parser.AnnotationRiskMedium,
 			Documentation: `This annotation allows to specify the target URI where the traffic must be redirected. It can contain regular characters and captured 
-			groups specified as '$1', '$2', etc.`,
+			groups specified as '$1', '$2', etc., or named groups specified as '$name' or '${name}'`,
 		},
 		sslRedirectAnnotation: {
 			Validator:     parser.ValidateBool,
@@ -71,6 +73,13 @@ var rewriteAnnotations = parser.Annotation{
 			Documentation: `This annotation defines if the paths defined on an Ingress use regular expressions. To use regex on path
 			the pathType should also be defined as 'ImplementationSpecific'.`,
 		},
+		caseInsensitivePathsAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines if the paths defined on an Ingress match the requests regardless of their case. The Prefix and Exact paths
+			are lowercased, and like with 'use-regex' the paths of all the Ingresses of the host use the case insensitive regex modifier.`,
+		},
 		appRootAnnotation: {
 			Validator:     parser.ValidateRegex(parser.RegexPathWithCapture, false),
 			Scope:         parser.AnnotationScopeLocation,
@@ -94,6 +103,9 @@ type Config struct {
 	AppRoot string `json:"appRoot"`
 	// UseRegex indicates whether or not the locations use regex paths
 	UseRegex bool `json:"useRegex"`
+	// CaseInsensitivePaths indicates whether or not the locations match the
+	// paths regardless of their case
+	CaseInsensitivePaths bool `json:"caseInsensitivePaths"`
 }
 
 // Equal tests for equality between two Redirect types
@@ -119,6 +131,9 @@ func (r1 *Config) Equal(r2 *Config) bool {
 	if r1.UseRegex != r2.UseRegex {
 		return false
 	}
+	if r1.CaseInsensitivePaths != r2.CaseInsensitivePaths {
+		return false
+	}
 
 	return true
 }
@@ -149,6 +164,13 @@ func (a rewrite) Parse(ing *networking.Ingress) (interface{}, error) {
 		}
 		config.Target = ""
 	}
+	if config.Target != "" {
+		if err := validateTargetCaptures(config.Target, ing); err != nil {
+			return config, errors.ValidationError{
+				Reason: fmt.Errorf("annotation %s: %w", rewriteTargetAnnotation, err),
+			}
+		}
+	}
 	config.SSLRedirect, err = parser.GetBoolAnnotation(sslRedirectAnnotation, ing, a.annotationConfig.Annotations)
 	if err != nil {
 		if errors.IsValidationError(err) {
@@ -180,6 +202,14 @@ func (a rewrite) Parse(ing *networking.Ingress) (interface{}, error) {
 		config.UseRegex = false
 	}
 
+	config.CaseInsensitivePaths, err = parser.GetBoolAnnotation(caseInsensitivePathsAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to 'false'", caseInsensitivePathsAnnotation)
+		}
+		config.CaseInsensitivePaths = false
+	}
+
 	config.AppRoot, err = parser.GetStringAnnotation(appRootAnnotation, ing, a.annotationConfig.Annotations)
 	if err != nil {
 		if !errors.IsMissingAnnotations(err) && !errors.IsInvalidContent(err) {
@@ -25,6 +25,7 @@ import (
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -272,3 +273,76 @@ func TestUseRegex(t *testing.T) {
 		t.Errorf("Unexpected value got in UseRegex")
 	}
 }
+
+func TestCaseInsensitivePaths(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("use-case-insensitive-paths")] = "true"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockBackend{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	redirect, ok := i.(*Config)
+	if !ok {
+		t.Errorf("expected a Config type")
+	}
+	if !redirect.CaseInsensitivePaths {
+		t.Errorf("Unexpected value got in CaseInsensitivePaths")
+	}
+	if redirect.UseRegex {
+		t.Errorf("Unexpected value got in UseRegex")
+	}
+}
+
+func TestRewriteTargetCaptures(t *testing.T) {
+	tests := []struct {
+		title   string
+		path    string
+		target  string
+		wantErr bool
+	}{
+		{"numbered groups", "/api(/|$)(.*)", "/$2", false},
+		{"whole match", "/api", "/v2$0", false},
+		{"named groups", "/users/(?<id>[0-9]+)/(?P<page>.*)", "/profiles/$id/$page", false},
+		{"named group in braces", "/users/(?'id'[0-9]+)", "/profiles/${id}_view", false},
+		{"named groups are numbered", "/users/(?<id>[0-9]+)", "/profiles/$1", false},
+		{"non capturing group", "/(?:api|v1)/(.*)", "/$1", false},
+		{"escaped parenthesis and class", `/a\(b[(]/(.*)`, "/$1", false},
+		{"variables", "/api/(.*)", "/$1$is_args$args", false},
+		{"header variable", "/api/(.*)", "/$http_x_tenant/$1", false},
+		{"too many numbered groups", "/api/(.*)", "/$1/$2", false},
+		{"unknown named group", "/users/(?<id>[0-9]+)", "/profiles/$user", true},
+		{"unknown named group in braces", "/users/(?<id>[0-9]+)", "/profiles/${user}", true},
+		{"unknown variable", "/api/(.*)", "/$1$tenant", true},
+		{"unbalanced parenthesis", "/api/(.*", "/$1", false},
+		{"unmatched parenthesis", "/api/.*)", "/", false},
+		{"unterminated class", "/api/[a-z", "/", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			ing := buildIngress()
+			ing.Spec.Rules[0].HTTP.Paths[0].Path = test.path
+			ing.SetAnnotations(map[string]string{
+				parser.GetAnnotationWithPrefix("rewrite-target"): test.target,
+			})
+
+			i, err := NewParser(mockBackend{}).Parse(ing)
+			if test.wantErr {
+				if !errors.IsValidationError(err) {
+					t.Fatalf("expected a validation error but returned %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if target := i.(*Config).Target; target != test.target {
+				t.Errorf("expected %v as rewrite target but returned %v", test.target, target)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securityheaders
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	securityHeadersAnnotation        = "security-headers"
+	securityHeadersCSPAnnotation     = "security-headers-csp"
+	securityHeadersExcludeAnnotation = "security-headers-exclude"
+)
+
+const (
+	// PresetNone sends no security header
+	PresetNone = "none"
+	// PresetStandard sends the headers which do not break the applications
+	PresetStandard = "standard"
+	// PresetStrict also isolates the documents from the other origins and
+	// restricts their resources with a Content-Security-Policy
+	PresetStrict = "strict"
+)
+
+const (
+	hstsHeader = "Strict-Transport-Security"
+	cspHeader  = "Content-Security-Policy"
+
+	// cspOff removes the Content-Security-Policy of the preset
+	cspOff = "off"
+	// nonceVariable is replaced in the Content-Security-Policy with a random
+	// value generated for each request, passed to the backends in the
+	// X-CSP-Nonce header
+	nonceVariable = "{nonce}"
+	// nonceValue is the NGINX variable set with the nonce by lua_ingress
+	nonceValue = "$csp_nonce"
+)
+
+// Presets are the sets of security headers
+var Presets = []string{PresetNone, PresetStandard, PresetStrict}
+
+// cspRegex are the characters of the directives of a Content-Security-Policy,
+// without quotes, backslashes or NGINX variables
+var cspRegex = regexp.MustCompile(`^[A-Za-z0-9 '*:;/.,_+=@{}\-]*$`)
+
+var securityHeadersAnnotations = parser.Annotation{
+	Group: "headers",
+	Annotations: parser.AnnotationFields{
+		securityHeadersAnnotation: {
+			Validator: parser.ValidateOptions(Presets, true, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation sets the security headers of the responses of this location from a preset: none, standard or strict.
+			Defaults to the security-headers of the ConfigMap`,
+		},
+		securityHeadersCSPAnnotation: {
+			Validator: parser.ValidateRegex(cspRegex, false),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskMedium,
+			Documentation: `This annotation sets the Content-Security-Policy header of the responses of this location, replacing the one of the preset.
+			{nonce} is replaced with a random value generated for each request, passed to the backend in the X-CSP-Nonce header. off removes the header`,
+		},
+		securityHeadersExcludeAnnotation: {
+			Validator:     parser.ValidateRegex(parser.HeadersVariable, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines a comma separated list of the headers of the preset which are not sent`,
+		},
+	},
+}
+
+// Header is a response header
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Config describes the security headers of a location
+type Config struct {
+	// HSTS is the Strict-Transport-Security header of the HTTPS responses,
+	// replacing the one of the hsts configuration
+	HSTS string `json:"hsts,omitempty"`
+	// Headers are the other security headers of the responses
+	Headers []Header `json:"headers,omitempty"`
+	// Nonce generates the nonce of the Content-Security-Policy of the
+	// requests
+	Nonce bool `json:"nonce,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return c1.HSTS == c2.HSTS && slices.Equal(c1.Headers, c2.Headers) && c1.Nonce == c2.Nonce
+}
+
+var presetHSTS = map[string]string{
+	PresetStandard: "max-age=31536000; includeSubDomains",
+	PresetStrict:   "max-age=63072000; includeSubDomains; preload",
+}
+
+var presetHeaders = map[string][]Header{
+	PresetStandard: {
+		{Name: "X-Content-Type-Options", Value: "nosniff"},
+		{Name: "X-Frame-Options", Value: "SAMEORIGIN"},
+		{Name: "Referrer-Policy", Value: "strict-origin-when-cross-origin"},
+	},
+	PresetStrict: {
+		{Name: "X-Content-Type-Options", Value: "nosniff"},
+		{Name: "X-Frame-Options", Value: "DENY"},
+		{Name: "Referrer-Policy", Value: "no-referrer"},
+		{Name: "Cross-Origin-Opener-Policy", Value: "same-origin"},
+		{Name: "Cross-Origin-Embedder-Policy", Value: "require-corp"},
+		{Name: "Cross-Origin-Resource-Policy", Value: "same-origin"},
+		{Name: cspHeader, Value: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'"},
+	},
+}
+
+// NewConfig returns the security headers of the preset
+func NewConfig(preset string) Config {
+	config := Config{HSTS: presetHSTS[preset]}
+	for _, header := range presetHeaders[preset] {
+		config.Headers = append(config.Headers, Header{
+			Name:  header.Name,
+			Value: strings.ReplaceAll(header.Value, nonceVariable, nonceValue),
+		})
+	}
+	config.Nonce = usesNonce(config.Headers)
+
+	return config
+}
+
+type securityHeaders struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new security headers annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return securityHeaders{
+		r:                r,
+		annotationConfig: securityHeadersAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the security headers of the locations
+func (s securityHeaders) Parse(ing *networking.Ingress) (interface{}, error) {
+	preset, err := parser.GetStringAnnotation(securityHeadersAnnotation, ing, s.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to the security-headers of the ConfigMap", securityHeadersAnnotation)
+		}
+		preset = s.r.GetDefaultBackend().SecurityHeaders
+	}
+	config := NewConfig(preset)
+
+	csp, err := parser.GetStringAnnotation(securityHeadersCSPAnnotation, ing, s.annotationConfig.Annotations)
+	switch {
+	case err == nil && csp == cspOff:
+		config.Headers = without(config.Headers, cspHeader)
+	case err == nil && strings.Count(csp, "{") != strings.Count(csp, nonceVariable):
+		klog.Warningf("%s is invalid, only the %s variable is allowed", securityHeadersCSPAnnotation, nonceVariable)
+	case err == nil:
+		config.Headers = append(without(config.Headers, cspHeader), Header{
+			Name:  cspHeader,
+			Value: strings.ReplaceAll(csp, nonceVariable, nonceValue),
+		})
+	case errors.IsValidationError(err):
+		klog.Warningf("%s is invalid, using the Content-Security-Policy of the preset", securityHeadersCSPAnnotation)
+	}
+
+	exclude, err := parser.GetStringAnnotation(securityHeadersExcludeAnnotation, ing, s.annotationConfig.Annotations)
+	if err == nil {
+		for _, name := range strings.Split(exclude, ",") {
+			name = strings.TrimSpace(name)
+			if strings.EqualFold(name, hstsHeader) {
+				config.HSTS = ""
+			}
+			config.Headers = without(config.Headers, name)
+		}
+	}
+	config.Nonce = usesNonce(config.Headers)
+
+	return &config, nil
+}
+
+// usesNonce returns true when one of the headers contains the nonce
+func usesNonce(headers []Header) bool {
+	return slices.ContainsFunc(headers, func(header Header) bool {
+		return strings.Contains(header.Value, nonceValue)
+	})
+}
+
+// without returns the headers without the header of the name
+func without(headers []Header, name string) []Header {
+	return slices.DeleteFunc(slices.Clone(headers), func(header Header) bool {
+		return strings.EqualFold(header.Name, name)
+	})
+}
+
+func (s securityHeaders) GetDocumentation() parser.AnnotationFields {
+	return s.annotationConfig.Annotations
+}
+
+func (s securityHeaders) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(s.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, securityHeadersAnnotations.Annotations)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securityheaders
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+	securityHeaders string
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{SecurityHeaders: m.securityHeaders}
+}
+
+func TestParse(t *testing.T) {
+	preset := parser.GetAnnotationWithPrefix(securityHeadersAnnotation)
+	csp := parser.GetAnnotationWithPrefix(securityHeadersCSPAnnotation)
+	exclude := parser.GetAnnotationWithPrefix(securityHeadersExcludeAnnotation)
+
+	standard := NewConfig(PresetStandard)
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		defPreset   string
+		expected    *Config
+	}{
+		{
+			name:        "without annotations",
+			annotations: map[string]string{},
+			expected:    &Config{},
+		},
+		{
+			name:        "preset of the ConfigMap",
+			annotations: map[string]string{},
+			defPreset:   PresetStandard,
+			expected:    &standard,
+		},
+		{
+			name:        "annotation replacing the preset of the ConfigMap",
+			annotations: map[string]string{preset: PresetNone},
+			defPreset:   PresetStandard,
+			expected:    &Config{},
+		},
+		{
+			name:        "invalid preset",
+			annotations: map[string]string{preset: "paranoid"},
+			defPreset:   PresetStandard,
+			expected:    &standard,
+		},
+		{
+			name:        "strict preset",
+			annotations: map[string]string{preset: PresetStrict},
+			expected: &Config{
+				HSTS: "max-age=63072000; includeSubDomains; preload",
+				Headers: []Header{
+					{Name: "X-Content-Type-Options", Value: "nosniff"},
+					{Name: "X-Frame-Options", Value: "DENY"},
+					{Name: "Referrer-Policy", Value: "no-referrer"},
+					{Name: "Cross-Origin-Opener-Policy", Value: "same-origin"},
+					{Name: "Cross-Origin-Embedder-Policy", Value: "require-corp"},
+					{Name: "Cross-Origin-Resource-Policy", Value: "same-origin"},
+					{Name: "Content-Security-Policy", Value: "default-src 'self'; script-src 'self' 'nonce-$csp_nonce'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'"},
+				},
+				Nonce: true,
+			},
+		},
+		{
+			name: "overridden Content-Security-Policy and excluded headers",
+			annotations: map[string]string{
+				preset:  PresetStrict,
+				csp:     "default-src 'self' https://cdn.example.com; script-src 'nonce-{nonce}'",
+				exclude: "Cross-Origin-Embedder-Policy, cross-origin-resource-policy, Strict-Transport-Security",
+			},
+			expected: &Config{
+				Headers: []Header{
+					{Name: "X-Content-Type-Options", Value: "nosniff"},
+					{Name: "X-Frame-Options", Value: "DENY"},
+					{Name: "Referrer-Policy", Value: "no-referrer"},
+					{Name: "Cross-Origin-Opener-Policy", Value: "same-origin"},
+					{Name: "Content-Security-Policy", Value: "default-src 'self' https://cdn.example.com; script-src 'nonce-$csp_nonce'"},
+				},
+				Nonce: true,
+			},
+		},
+		{
+			name:        "Content-Security-Policy without preset",
+			annotations: map[string]string{csp: "frame-ancestors 'none'"},
+			expected: &Config{
+				Headers: []Header{{Name: "Content-Security-Policy", Value: "frame-ancestors 'none'"}},
+			},
+		},
+		{
+			name:        "Content-Security-Policy removed",
+			annotations: map[string]string{preset: PresetStrict, csp: "off", exclude: "X-Content-Type-Options,X-Frame-Options,Referrer-Policy,Cross-Origin-Opener-Policy,Cross-Origin-Embedder-Policy,Cross-Origin-Resource-Policy"},
+			expected:    &Config{HSTS: "max-age=63072000; includeSubDomains; preload", Headers: []Header{}},
+		},
+		{
+			name:        "Content-Security-Policy with a nonce excluded",
+			annotations: map[string]string{preset: PresetStrict, exclude: "Content-Security-Policy"},
+			expected: &Config{
+				HSTS: "max-age=63072000; includeSubDomains; preload",
+				Headers: []Header{
+					{Name: "X-Content-Type-Options", Value: "nosniff"},
+					{Name: "X-Frame-Options", Value: "DENY"},
+					{Name: "Referrer-Policy", Value: "no-referrer"},
+					{Name: "Cross-Origin-Opener-Policy", Value: "same-origin"},
+					{Name: "Cross-Origin-Embedder-Policy", Value: "require-corp"},
+					{Name: "Cross-Origin-Resource-Policy", Value: "same-origin"},
+				},
+			},
+		},
+		{
+			name:        "invalid Content-Security-Policy",
+			annotations: map[string]string{csp: "script-src 'nonce-{host}'"},
+			expected:    &Config{},
+		},
+		{
+			name:        "Content-Security-Policy with a variable",
+			annotations: map[string]string{csp: "script-src 'nonce-$request_id'"},
+			expected:    &Config{},
+		},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ing.SetAnnotations(testCase.annotations)
+			result, err := NewParser(mockBackend{securityHeaders: testCase.defPreset}).Parse(ing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type but returned %T", result)
+			}
+			if !config.Equal(testCase.expected) {
+				t.Errorf("expected %+v but returned %+v", testCase.expected, config)
+			}
+		})
+	}
+}
@@ -64,7 +64,12 @@ const (
 	// This is used to control the cookie change after request failure
 	annotationAffinityCookieChangeOnFailure = "session-cookie-change-on-failure"
 
+	// The value of this header is used to route the requests when the affinity mode is header
+	annotationAffinityHeaderName = "session-header-name"
+
 	cookieAffinity = "cookie"
+
+	headerAffinityMode = "header"
 )
 
 var sessionAffinityAnnotations = parser.Annotation{
@@ -77,12 +82,13 @@ var sessionAffinityAnnotations = parser.Annotation{
 			Documentation: `This annotation enables and sets the affinity type in all Upstreams of an Ingress. This way, a request will always be directed to the same upstream server. The only affinity type available for NGINX is cookie`,
 		},
 		annotationAffinityMode: {
-			Validator: parser.ValidateOptions([]string{"balanced", "persistent"}, true, true),
+			Validator: parser.ValidateOptions([]string{"balanced", "persistent", headerAffinityMode}, true, true),
 			Scope:     parser.AnnotationScopeIngress,
 			Risk:      parser.AnnotationRiskMedium,
 			Documentation: `This annotation defines the stickiness of a session. 
 			Setting this to balanced (default) will redistribute some sessions if a deployment gets scaled up, therefore rebalancing the load on the servers. 
-			Setting this to persistent will not rebalance sessions to new servers, therefore providing maximum stickiness.`,
+			Setting this to persistent will not rebalance sessions to new servers, therefore providing maximum stickiness.
+			Setting this to header will route the requests with the same value of the session-header-name header to the same server, without using a cookie.`,
 		},
 		annotationAffinityCanaryBehavior: {
 			Validator: parser.ValidateOptions([]string{"sticky", "legacy"}, true, true),
@@ -148,6 +154,12 @@ var sessionAffinityAnnotations = parser.Annotation{
 			Documentation: `This annotation, when set to false will send request to upstream pointed by sticky cookie even if previous attempt failed. 
 			When set to true and previous attempt failed, sticky cookie will be changed to point to another upstream.`,
 		},
+		annotationAffinityHeaderName: {
+			Validator:     parser.ValidateRegex(parser.BasicCharsRegex, true),
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskMedium,
+			Documentation: `This annotation defines the name of the header used to route the requests when the affinity mode is header, e.g. a device ID or an API key`,
+		},
 	},
 }
 
@@ -161,6 +173,8 @@ type Config struct {
 	Mode string `json:"mode"`
 	// Affinity behavior for canaries (sticky or legacy)
 	CanaryBehavior string `json:"canaryBehavior"`
+	// The name of the header used to route the requests when the affinity mode is header
+	HeaderName string `json:"headerName"`
 	Cookie
 }
 
@@ -285,10 +299,21 @@ func (a affinity) Parse(ing *networking.Ingress) (interface{}, error) {
 		klog.V(3).InfoS("No default affinity found", "ingress", ing.Name)
 	}
 
+	var headerName string
+	if am == headerAffinityMode {
+		headerName, err = parser.GetStringAnnotation(annotationAffinityHeaderName, ing, a.annotationConfig.Annotations)
+		if err != nil || headerName == "" {
+			klog.Warningf("%s is required by the %s affinity mode, session affinity is disabled", annotationAffinityHeaderName, headerAffinityMode)
+			am = ""
+			headerName = ""
+		}
+	}
+
 	return &Config{
 		Type:           at,
 		Mode:           am,
 		CanaryBehavior: cb,
+		HeaderName:     headerName,
 		Cookie:         *cookie,
 	}, nil
 }
@@ -134,3 +134,46 @@ func TestIngressAffinityCookieConfig(t *testing.T) {
 		t.Errorf("expected secure parameter set to true but returned %v", nginxAffinity.Cookie.Secure)
 	}
 }
+
+func TestIngressAffinityHeaderConfig(t *testing.T) {
+	testCases := []struct {
+		name         string
+		headerName   string
+		expectedMode string
+		expectedName string
+	}{
+		{"header mode with a header name", "X-Device-ID", "header", "X-Device-ID"},
+		{"header mode without a header name is disabled", "", "", ""},
+		{"header mode with an invalid header name is disabled", "X-Device-ID;", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := buildIngress()
+			data := map[string]string{}
+			data[parser.GetAnnotationWithPrefix(annotationAffinityMode)] = "header"
+			if tc.headerName != "" {
+				data[parser.GetAnnotationWithPrefix(annotationAffinityHeaderName)] = tc.headerName
+			}
+			ing.SetAnnotations(data)
+
+			affin, err := NewParser(&resolver.Mock{}).Parse(ing)
+			if err != nil {
+				t.Errorf("unexpected error parsing annotations: %v", err)
+			}
+
+			nginxAffinity, ok := affin.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type")
+			}
+
+			if nginxAffinity.Mode != tc.expectedMode {
+				t.Errorf("expected %q as affinity mode but returned %q", tc.expectedMode, nginxAffinity.Mode)
+			}
+
+			if nginxAffinity.HeaderName != tc.expectedName {
+				t.Errorf("expected %q as session-header-name but returned %q", tc.expectedName, nginxAffinity.HeaderName)
+			}
+		})
+	}
+}
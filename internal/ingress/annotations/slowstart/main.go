@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slowstart
+
+import (
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	slowStartSecondsAnnotation = "slow-start-seconds"
+)
+
+var slowStartAnnotations = parser.Annotation{
+	Group: "backend",
+	Annotations: parser.AnnotationFields{
+		slowStartSecondsAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeIngress,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the window in seconds during which the traffic sent to newly added endpoints
+			is ramped up linearly instead of sending them full load immediately. Only supported by the round_robin and least_conn load balancing algorithms`,
+		},
+	},
+}
+
+type slowStart struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new slow start annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return slowStart{
+		r:                r,
+		annotationConfig: slowStartAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the slow start window of the backends
+func (s slowStart) Parse(ing *networking.Ingress) (interface{}, error) {
+	seconds, err := parser.GetIntAnnotation(slowStartSecondsAnnotation, ing, s.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to 0", slowStartSecondsAnnotation)
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if seconds < 0 {
+		klog.Warningf("%s must not be negative, defaulting to 0", slowStartSecondsAnnotation)
+		return 0, nil
+	}
+
+	return seconds, nil
+}
+
+func (s slowStart) GetDocumentation() parser.AnnotationFields {
+	return s.annotationConfig.Annotations
+}
+
+func (s slowStart) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(s.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, slowStartAnnotations.Annotations)
+}
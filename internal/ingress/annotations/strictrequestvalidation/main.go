@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strictrequestvalidation
+
+import (
+	networking "k8s.io/api/networking/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	strictRequestValidationAnnotation = "strict-request-validation"
+)
+
+var strictRequestValidationAnnotations = parser.Annotation{
+	Group: "request-validation",
+	Annotations: parser.AnnotationFields{
+		strictRequestValidationAnnotation: {
+			Validator: parser.ValidateBool,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `Enables or disables the rejection of the ambiguous requests which could be smuggled through chained proxies:
+			conflicting Content-Length and Transfer-Encoding headers, obsolete line folding, invalid header names and absolute URIs not matching the Host header`,
+		},
+	},
+}
+
+type strictRequestValidation struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new strict request validation annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return strictRequestValidation{
+		r:                r,
+		annotationConfig: strictRequestValidationAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress
+// rule used to indicate if the ambiguous requests must be rejected
+func (a strictRequestValidation) Parse(ing *networking.Ingress) (interface{}, error) {
+	strict, err := parser.GetBoolAnnotation(strictRequestValidationAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		return a.r.GetDefaultBackend().StrictRequestValidation, nil
+	}
+
+	return strict, nil
+}
+
+func (a strictRequestValidation) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a strictRequestValidation) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, strictRequestValidationAnnotations.Annotations)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strictrequestvalidation
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+	strictRequestValidation bool
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{StrictRequestValidation: m.strictRequestValidation}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		title  string
+		strict string
+		def    bool
+		exp    bool
+	}{
+		{"false - default false", "false", false, false},
+		{"false - default true", "false", true, false},
+		{"no annotation - default false", "", false, false},
+		{"invalid annotation - default true", "not-a-bool", true, true},
+		{"no annotation - default true", "", true, true},
+		{"true - default false", "true", false, true},
+	}
+
+	for _, test := range tests {
+		ing := &networking.Ingress{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      "foo",
+				Namespace: api.NamespaceDefault,
+			},
+		}
+
+		data := map[string]string{}
+		if test.strict != "" {
+			data[parser.GetAnnotationWithPrefix(strictRequestValidationAnnotation)] = test.strict
+		}
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{strictRequestValidation: test.def}).Parse(ing)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.title, err)
+		}
+		strict, ok := i.(bool)
+		if !ok {
+			t.Errorf("%v: expected a bool type", test.title)
+		}
+
+		if strict != test.exp {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.title, test.exp, strict)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trailingslash
+
+import (
+	"net/http"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	trailingSlashAnnotation     = "trailing-slash"
+	trailingSlashCodeAnnotation = "trailing-slash-redirect-code"
+)
+
+const (
+	// ModeAdd redirects the paths without a trailing slash to the path with it
+	ModeAdd = "add"
+	// ModeRemove redirects the paths with a trailing slash to the path without it
+	ModeRemove = "remove"
+)
+
+const defaultCode = http.StatusPermanentRedirect
+
+var trailingSlashAnnotations = parser.Annotation{
+	Group: "redirect",
+	Annotations: parser.AnnotationFields{
+		trailingSlashAnnotation: {
+			Validator: parser.ValidateOptions([]string{ModeAdd, ModeRemove}, false, true),
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow, // Low, as it allows just a set of options
+			Documentation: `This annotation redirects the requests between the /path and /path/ forms of the paths. With add, the paths without a trailing slash
+			are redirected to the path with it, except the file names with a dot. With remove, the paths with trailing slashes are redirected to the path without them`,
+		},
+		trailingSlashCodeAnnotation: {
+			Validator:     parser.ValidateOptions([]string{"301", "308"}, true, true),
+			Scope:         parser.AnnotationScopeLocation,
+			Risk:          parser.AnnotationRiskLow, // Low, as it allows just a set of options
+			Documentation: `This annotation defines the status code of the trailing slash redirects, 301 or 308. Defaults to 308, which keeps the method and the body of the requests`,
+		},
+	},
+}
+
+// Config describes the trailing slash redirects of a location
+type Config struct {
+	// Mode is add or remove, empty when the requests are not redirected
+	Mode string `json:"mode,omitempty"`
+	Code int    `json:"code,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type trailingSlash struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new trailing slash annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return trailingSlash{
+		r:                r,
+		annotationConfig: trailingSlashAnnotations,
+	}
+}
+
+// Parse parses the annotations contained in the ingress
+// to redirect the requests between the forms of the paths
+func (a trailingSlash) Parse(ing *networking.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	mode, err := parser.GetStringAnnotation(trailingSlashAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+		return config, nil
+	}
+
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != ModeAdd && mode != ModeRemove {
+		klog.Warningf("%s is invalid, the trailing slash redirects are disabled", trailingSlashAnnotation)
+		return config, nil
+	}
+
+	code, err := parser.GetIntAnnotation(trailingSlashCodeAnnotation, ing, a.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			return config, err
+		}
+		code = defaultCode
+	} else if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+		klog.Warningf("%s must be 301 or 308, defaulting to %d", trailingSlashCodeAnnotation, defaultCode)
+		code = defaultCode
+	}
+
+	config.Mode = mode
+	config.Code = code
+
+	return config, nil
+}
+
+func (a trailingSlash) GetDocumentation() parser.AnnotationFields {
+	return a.annotationConfig.Annotations
+}
+
+func (a trailingSlash) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(a.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, trailingSlashAnnotations.Annotations)
+}
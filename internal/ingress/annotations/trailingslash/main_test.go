@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trailingslash
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	modeAnnotation := parser.GetAnnotationWithPrefix(trailingSlashAnnotation)
+	codeAnnotation := parser.GetAnnotationWithPrefix(trailingSlashCodeAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{map[string]string{}, &Config{}, false},
+		{map[string]string{modeAnnotation: "add"}, &Config{Mode: ModeAdd, Code: 308}, false},
+		{map[string]string{modeAnnotation: "Remove", codeAnnotation: "301"}, &Config{Mode: ModeRemove, Code: 301}, false},
+		{map[string]string{codeAnnotation: "301"}, &Config{}, false},
+		{map[string]string{modeAnnotation: "always"}, &Config{}, true},
+		{map[string]string{modeAnnotation: "add", codeAnnotation: "302"}, &Config{}, true},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+
+		result, err := ap.Parse(ing)
+		if testCase.expectErr && err == nil {
+			t.Errorf("expected an error with the annotations %v", testCase.annotations)
+		}
+		if !testCase.expectErr && err != nil {
+			t.Errorf("unexpected error with the annotations %v: %v", testCase.annotations, err)
+		}
+
+		config, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but returned %T", result)
+		}
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %+v but returned %+v with the annotations %v", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"regexp"
 
 	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
@@ -30,6 +31,7 @@ const (
 	upstreamHashByAnnotation       = "upstream-hash-by"
 	upstreamHashBySubsetAnnotation = "upstream-hash-by-subset"
 	upstreamHashBySubsetSize       = "upstream-hash-by-subset-size"
+	upstreamHashByBoundedLoad      = "upstream-hash-by-bounded-load-factor"
 )
 
 var (
@@ -59,6 +61,13 @@ var upstreamHashByAnnotations = parser.Annotation{
 			Risk:          parser.AnnotationRiskLow,
 			Documentation: `This annotation determines the size of each subset (default 3)`,
 		},
+		upstreamHashByBoundedLoad: {
+			Validator: parser.ValidateFloat,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation enables consistent hashing with bounded loads. An endpoint does not receive more than
+			load factor times the average number of in-flight requests, so hot keys spill over to the next endpoints. The value must be 1 or greater, e.g. 1.25`,
+		},
 	},
 }
 
@@ -72,6 +81,8 @@ type Config struct {
 	UpstreamHashBy           string `json:"upstream-hash-by,omitempty"`
 	UpstreamHashBySubset     bool   `json:"upstream-hash-by-subset,omitempty"`
 	UpstreamHashBySubsetSize int    `json:"upstream-hash-by-subset-size,omitempty"`
+
+	UpstreamHashByBoundedLoadFactor float32 `json:"upstream-hash-by-bounded-load-factor,omitempty"`
 }
 
 // NewParser creates a new UpstreamHashBy annotation parser
@@ -101,7 +112,17 @@ func (a upstreamhashby) Parse(ing *networking.Ingress) (interface{}, error) {
 		upstreamHashbySubsetSize = 3
 	}
 
-	return &Config{upstreamHashBy, upstreamHashBySubset, upstreamHashbySubsetSize}, nil
+	boundedLoadFactor, err := parser.GetFloatAnnotation(upstreamHashByBoundedLoad, ing, a.annotationConfig.Annotations)
+	if err != nil && !errors.IsMissingAnnotations(err) {
+		return nil, err
+	}
+
+	if boundedLoadFactor != 0 && boundedLoadFactor < 1 {
+		klog.Warningf("%s must be 1 or greater, bounded loads are disabled", upstreamHashByBoundedLoad)
+		boundedLoadFactor = 0
+	}
+
+	return &Config{upstreamHashBy, upstreamHashBySubset, upstreamHashbySubsetSize, boundedLoadFactor}, nil
 }
 
 func (a upstreamhashby) GetDocumentation() parser.AnnotationFields {
@@ -74,3 +74,50 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseBoundedLoadFactor(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix(upstreamHashByBoundedLoad)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    float32
+		expectErr   bool
+	}{
+		{map[string]string{annotation: "1.25"}, 1.25, false},
+		{map[string]string{annotation: "2"}, 2, false},
+		{map[string]string{annotation: "0.5"}, 0, false},
+		{map[string]string{annotation: "high"}, 0, true},
+		{map[string]string{}, 0, false},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := ap.Parse(ing)
+		if (err != nil) != testCase.expectErr {
+			t.Fatalf("expected error: %t got error: %t err value: %s. %+v", testCase.expectErr, err != nil, err, testCase.annotations)
+		}
+		if !testCase.expectErr {
+			uc, ok := result.(*Config)
+			if !ok {
+				t.Fatalf("expected a Config type")
+			}
+
+			if uc.UpstreamHashByBoundedLoadFactor != testCase.expected {
+				t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, uc.UpstreamHashByBoundedLoadFactor, testCase.annotations)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	webSocketReadTimeoutAnnotation    = "websocket-read-timeout"
+	webSocketSendTimeoutAnnotation    = "websocket-send-timeout"
+	webSocketMaxConnectionsAnnotation = "websocket-max-connections"
+)
+
+var webSocketAnnotations = parser.Annotation{
+	Group: "websocket",
+	Annotations: parser.AnnotationFields{
+		webSocketReadTimeoutAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the timeout in seconds for reading from the backend of WebSocket connections,
+			i.e. how long an idle WebSocket is kept open. It only applies to requests with an Upgrade header, the other requests use proxy-read-timeout`,
+		},
+		webSocketSendTimeoutAnnotation: {
+			Validator: parser.ValidateInt,
+			Scope:     parser.AnnotationScopeLocation,
+			Risk:      parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the timeout in seconds for sending to the backend of WebSocket connections.
+			It only applies to requests with an Upgrade header, the other requests use proxy-send-timeout`,
+		},
+		webSocketMaxConnectionsAnnotation: {
+			Validator:     parser.ValidateInt,
+			Scope:         parser.AnnotationScopeIngress,
+			Risk:          parser.AnnotationRiskLow,
+			Documentation: `This annotation defines the maximum number of concurrent WebSocket connections to the Ingress, per controller replica`,
+		},
+	},
+}
+
+// Config describes the timeouts and the connection limit of the WebSocket
+// connections. A zero value keeps the behavior of the regular requests.
+type Config struct {
+	ReadTimeout    int `json:"readTimeout"`
+	SendTimeout    int `json:"sendTimeout"`
+	MaxConnections int `json:"maxConnections"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return *c1 == *c2
+}
+
+type webSocket struct {
+	r                resolver.Resolver
+	annotationConfig parser.Annotation
+}
+
+// NewParser creates a new WebSocket annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return webSocket{
+		r:                r,
+		annotationConfig: webSocketAnnotations,
+	}
+}
+
+func (w webSocket) getNonNegativeInt(name string, ing *networking.Ingress) int {
+	value, err := parser.GetIntAnnotation(name, ing, w.annotationConfig.Annotations)
+	if err != nil {
+		if errors.IsValidationError(err) {
+			klog.Warningf("%s is invalid, defaulting to 0", name)
+		}
+		return 0
+	}
+
+	if value < 0 {
+		klog.Warningf("%s must not be negative, defaulting to 0", name)
+		return 0
+	}
+
+	return value
+}
+
+// Parse parses the annotations contained in the ingress
+// to configure the WebSocket connections
+func (w webSocket) Parse(ing *networking.Ingress) (interface{}, error) {
+	return &Config{
+		ReadTimeout:    w.getNonNegativeInt(webSocketReadTimeoutAnnotation, ing),
+		SendTimeout:    w.getNonNegativeInt(webSocketSendTimeoutAnnotation, ing),
+		MaxConnections: w.getNonNegativeInt(webSocketMaxConnectionsAnnotation, ing),
+	}, nil
+}
+
+func (w webSocket) GetDocumentation() parser.AnnotationFields {
+	return w.annotationConfig.Annotations
+}
+
+func (w webSocket) Validate(anns map[string]string) error {
+	maxrisk := parser.StringRiskToRisk(w.r.GetSecurityConfiguration().AnnotationsRiskLevel)
+	return parser.CheckAnnotationRisk(anns, maxrisk, webSocketAnnotations.Annotations)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zstd
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix(zstdEnableAnnotation)
+	level := parser.GetAnnotationWithPrefix(zstdLevelAnnotation)
+	types := parser.GetAnnotationWithPrefix(zstdTypesAnnotation)
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{map[string]string{}, &Config{}, false},
+		{map[string]string{enable: "false"}, &Config{EnableSet: true}, false},
+		{
+			map[string]string{enable: "true", level: "19", types: "text/html  application/json image/svg+xml"},
+			&Config{Enable: true, EnableSet: true, Level: 19, Types: "text/html application/json image/svg+xml"},
+			false,
+		},
+		{map[string]string{level: "5"}, &Config{Level: 5}, false},
+		{map[string]string{enable: "yes please"}, &Config{}, true},
+		{map[string]string{enable: "true", level: "23"}, &Config{Enable: true, EnableSet: true}, true},
+		{map[string]string{enable: "true", level: "0"}, &Config{Enable: true, EnableSet: true}, true},
+		{map[string]string{types: "text/html; gzip off"}, &Config{}, true},
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: networking.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := ap.Parse(ing)
+		if testCase.expectErr && err == nil {
+			t.Errorf("expected an error with the annotations %v", testCase.annotations)
+		}
+		if !testCase.expectErr && err != nil {
+			t.Errorf("unexpected error with the annotations %v: %v", testCase.annotations, err)
+		}
+
+		config, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but returned %T", result)
+		}
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %+v but returned %+v with the annotations %v", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+// serveAdmin listens on the admin socket, used by the dbg command to render
+// the configuration of an Ingress, to manage the configuration snapshots and
+// to purge the cached responses
+func (n *NGINXController) serveAdmin() {
+	err := os.Remove(nginx.AdminSocket)
+	if err != nil && !os.IsNotExist(err) {
+		klog.ErrorS(err, "Error removing the admin socket")
+		return
+	}
+
+	listener, err := net.Listen("unix", nginx.AdminSocket)
+	if err != nil {
+		klog.ErrorS(err, "Error listening on the admin socket")
+		return
+	}
+
+	// the socket is only accessible to the user of the controller, through
+	// kubectl exec, and to NGINX, proxying the read-only snapshot requests of
+	// the status port
+	err = os.Chmod(nginx.AdminSocket, file.ReadWriteByUser)
+	if err != nil {
+		klog.ErrorS(err, "Error setting the permissions of the admin socket")
+		listener.Close()
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(nginx.DryRunPath, n.handleDryRun)
+	mux.HandleFunc(nginx.SnapshotsPath, n.handleSnapshots)
+	mux.HandleFunc(nginx.SnapshotsPath+"/", n.handleSnapshots)
+	mux.HandleFunc(nginx.PurgePath, n.handlePurge)
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	klog.ErrorS(server.Serve(listener), "Error serving admin requests")
+}
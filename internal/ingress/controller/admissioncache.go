@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"golang.org/x/sync/singleflight"
+	networking "k8s.io/api/networking/v1"
+)
+
+// admissionCache keeps the results of the admission checks of the candidate
+// Ingresses for the current configuration generation, so the repeated checks
+// of an Ingress, like the dry runs and the retries of the API server, do not
+// render and test the configuration again. The generation changes with every
+// change of the store. The concurrent checks of an Ingress are deduplicated.
+type admissionCache struct {
+	lock       sync.Mutex
+	generation uint64
+	size       int
+	results    map[uint64]admissionResult
+
+	group singleflight.Group
+}
+
+type admissionResult struct {
+	warnings []string
+	err      error
+}
+
+func newAdmissionCache(size int) *admissionCache {
+	return &admissionCache{
+		size:    size,
+		results: make(map[uint64]admissionResult),
+	}
+}
+
+// invalidate starts a new configuration generation, dropping the results of
+// the previous one
+func (c *admissionCache) invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	c.results = make(map[uint64]admissionResult)
+}
+
+// check returns the result of the check of the Ingress for the current
+// generation, running it only when it is not cached
+func (c *admissionCache) check(ing *networking.Ingress, check func() ([]string, error)) ([]string, error) {
+	key, err := admissionKey(ing)
+	if err != nil {
+		return check()
+	}
+
+	c.lock.Lock()
+	generation := c.generation
+	result, ok := c.results[key]
+	c.lock.Unlock()
+	if ok {
+		return result.warnings, result.err
+	}
+
+	v, _, _ := c.group.Do(fmt.Sprintf("%d/%d", generation, key), func() (interface{}, error) {
+		warnings, err := check()
+		result := admissionResult{warnings: warnings, err: err}
+
+		var limitErr *sandboxLimitError
+		if errors.As(err, &limitErr) && limitErr.queued {
+			// the configuration was not tested, it is tested again. A
+			// test aborted by the limits is cached like the other results,
+			// it would be aborted again.
+			return result, nil
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if c.generation == generation {
+			if len(c.results) >= c.size {
+				c.results = make(map[uint64]admissionResult)
+			}
+			c.results[key] = result
+		}
+		return result, nil
+	})
+
+	result = v.(admissionResult)
+	return result.warnings, result.err
+}
+
+// admissionKey hashes the fields of the Ingress used by the checks, the
+// metadata changed by the API server on every update is ignored
+func admissionKey(ing *networking.Ingress) (uint64, error) {
+	return hashstructure.Hash(struct {
+		Namespace   string
+		Name        string
+		Labels      map[string]string
+		Annotations map[string]string
+		Spec        networking.IngressSpec
+		Deleted     bool
+	}{
+		Namespace:   ing.Namespace,
+		Name:        ing.Name,
+		Labels:      ing.Labels,
+		Annotations: ing.Annotations,
+		Spec:        ing.Spec,
+		Deleted:     !ing.DeletionTimestamp.IsZero(),
+	}, hashstructure.FormatV2, nil)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func cacheIngress(resourceVersion, host string) *networking.Ingress {
+	return &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app",
+			Namespace:       "default",
+			ResourceVersion: resourceVersion,
+		},
+		Spec: networking.IngressSpec{
+			Rules: []networking.IngressRule{{Host: host}},
+		},
+	}
+}
+
+func TestAdmissionCache(t *testing.T) {
+	c := newAdmissionCache(10)
+
+	var checks int32
+	check := func() ([]string, error) {
+		atomic.AddInt32(&checks, 1)
+		return []string{"warning"}, nil
+	}
+
+	warnings, err := c.check(cacheIngress("1", "app.example.com"), check)
+	if err != nil || len(warnings) != 1 {
+		t.Fatalf("unexpected result %v, %v", warnings, err)
+	}
+
+	// the metadata set by the API server is ignored
+	warnings, err = c.check(cacheIngress("2", "app.example.com"), check)
+	if err != nil || len(warnings) != 1 || checks != 1 {
+		t.Errorf("expected the cached result, got %v, %v after %v checks", warnings, err, checks)
+	}
+
+	c.check(cacheIngress("2", "other.example.com"), check)
+	if checks != 2 {
+		t.Errorf("expected another Ingress to be checked, got %v checks", checks)
+	}
+
+	c.invalidate()
+	c.check(cacheIngress("2", "app.example.com"), check)
+	if checks != 3 {
+		t.Errorf("expected the Ingress to be checked again after a change of the store, got %v checks", checks)
+	}
+}
+
+func TestAdmissionCacheErrors(t *testing.T) {
+	c := newAdmissionCache(10)
+	ing := cacheIngress("1", "app.example.com")
+
+	checks := 0
+	_, err := c.check(ing, func() ([]string, error) {
+		checks++
+		return nil, errors.New("invalid")
+	})
+	c.check(ing, func() ([]string, error) {
+		checks++
+		return nil, nil
+	})
+	if err == nil || checks != 1 {
+		t.Errorf("expected the error to be cached, got %v after %v checks", err, checks)
+	}
+
+	ing = cacheIngress("1", "other.example.com")
+	c.check(ing, func() ([]string, error) {
+		checks++
+		return nil, &sandboxLimitError{msg: "busy", queued: true}
+	})
+	_, err = c.check(ing, func() ([]string, error) {
+		checks++
+		return nil, nil
+	})
+	if err != nil || checks != 3 {
+		t.Errorf("expected the queued limit error not to be cached, got %v after %v checks", err, checks)
+	}
+
+	ing = cacheIngress("1", "large.example.com")
+	c.check(ing, func() ([]string, error) {
+		checks++
+		return nil, &sandboxLimitError{msg: "timeout"}
+	})
+	_, err = c.check(ing, func() ([]string, error) {
+		checks++
+		return nil, nil
+	})
+	if err == nil || checks != 4 {
+		t.Errorf("expected the aborted test to be cached, got %v after %v checks", err, checks)
+	}
+}
+
+func TestAdmissionCacheDeduplication(t *testing.T) {
+	c := newAdmissionCache(10)
+
+	var checks int32
+	release := make(chan struct{})
+	check := func() ([]string, error) {
+		atomic.AddInt32(&checks, 1)
+		<-release
+		return nil, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.check(cacheIngress("1", "app.example.com"), check)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if checks != 1 {
+		t.Errorf("expected the concurrent checks to be deduplicated, got %v checks", checks)
+	}
+}
+
+func TestAdmissionCacheSize(t *testing.T) {
+	c := newAdmissionCache(2)
+	check := func() ([]string, error) { return nil, nil }
+
+	for _, host := range []string{"a", "b", "c"} {
+		c.check(cacheIngress("1", host), check)
+	}
+	if len(c.results) > 2 {
+		t.Errorf("expected at most 2 cached results, got %v", len(c.results))
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cachepurge removes the responses cached by NGINX in the proxy cache
+// zones, selecting them by host and path.
+package cachepurge
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxHeaderSize is the size of the beginning of the cache files the key is
+// searched in, the keys are stored after a binary header
+const maxHeaderSize = 16 * 1024
+
+var keyPrefix = []byte("\nKEY: ")
+
+// variableRegex matches the NGINX variables of a cache key
+var variableRegex = regexp.MustCompile(`\$(\{[A-Za-z0-9_]+\}|[A-Za-z0-9_]+)`)
+
+// Request selects the cached responses to purge
+type Request struct {
+	Host string `json:"host"`
+	// Path is the path of the responses, without query string. The responses
+	// of all the paths of the host are purged when it is empty
+	Path string `json:"path,omitempty"`
+	// Prefix purges the responses of the paths starting with Path
+	Prefix bool `json:"prefix,omitempty"`
+	// Zone is the cache zone of the responses, all the zones when it is empty
+	Zone string `json:"zone,omitempty"`
+}
+
+// Result describes the responses purged by a request
+type Result struct {
+	Purged int `json:"purged"`
+}
+
+// Validate returns an error when the request does not select a host
+func (r *Request) Validate() error {
+	if r.Host == "" {
+		return errors.New("the host of the responses to purge is required")
+	}
+	if strings.ContainsAny(r.Host, "/?# ") {
+		return fmt.Errorf("invalid host %q", r.Host)
+	}
+	if r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("the path %q does not start with /", r.Path)
+	}
+
+	return nil
+}
+
+// Matcher returns the regular expression matching the keys of the responses
+// of the host and the path of the request cached by a location, from the
+// proxy_cache_key of the location. The variables of the host and of the path
+// are replaced with the ones of the request, the other variables match any
+// value. It returns nil when the request selects a path and the key of the
+// location does not contain it.
+func (r *Request) Matcher(key string) *regexp.Regexp {
+	path := `/[^?]*`
+	switch {
+	case r.Path == "":
+	case r.Prefix:
+		path = regexp.QuoteMeta(r.Path) + `[^?]*`
+	default:
+		path = regexp.QuoteMeta(r.Path)
+	}
+
+	hasPath := false
+	var expression strings.Builder
+	expression.WriteString("^")
+	last := 0
+	for _, match := range variableRegex.FindAllStringSubmatchIndex(key, -1) {
+		expression.WriteString(regexp.QuoteMeta(key[last:match[0]]))
+		last = match[1]
+
+		switch strings.Trim(key[match[2]:match[3]], "{}") {
+		case "host":
+			expression.WriteString(regexp.QuoteMeta(strings.ToLower(r.Host)))
+		case "http_host":
+			expression.WriteString(`(?i:` + regexp.QuoteMeta(r.Host) + `)(:\d+)?`)
+		case "scheme":
+			expression.WriteString(`https?`)
+		case "request_uri":
+			hasPath = true
+			expression.WriteString(path + `(\?.*)?`)
+		case "uri", "document_uri":
+			hasPath = true
+			expression.WriteString(path)
+		default:
+			expression.WriteString(`.*`)
+		}
+	}
+	expression.WriteString(regexp.QuoteMeta(key[last:]))
+	expression.WriteString("$")
+
+	if r.Path != "" && !hasPath {
+		return nil
+	}
+
+	return regexp.MustCompile(expression.String())
+}
+
+// Purge removes the cache files of the directory of a cache zone whose key
+// matches one of the matchers, it returns the number of files removed. NGINX
+// fetches the responses of the removed files from the backends again.
+func Purge(directory string, matchers []*regexp.Regexp) (int, error) {
+	purged := 0
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// the temporary files of the responses being cached start with a dot
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		key, err := readKey(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !matches(matchers, key) {
+			return nil
+		}
+
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		purged++
+
+		return nil
+	})
+
+	return purged, err
+}
+
+// matches returns true when one of the matchers matches the key
+func matches(matchers []*regexp.Regexp, key string) bool {
+	for _, matcher := range matchers {
+		if matcher.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readKey returns the key of a cache file, an empty key when it has none
+func readKey(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header, err := bufio.NewReaderSize(f, maxHeaderSize).Peek(maxHeaderSize)
+	if err != nil && len(header) == 0 {
+		return "", nil
+	}
+
+	start := bytes.Index(header, keyPrefix)
+	if start < 0 {
+		return "", nil
+	}
+	header = header[start+len(keyPrefix):]
+
+	end := bytes.IndexByte(header, '\n')
+	if end < 0 {
+		return "", nil
+	}
+
+	return string(header[:end]), nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachepurge
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func writeCacheFile(t *testing.T, directory, name, key string) string {
+	t.Helper()
+
+	path := filepath.Join(directory, name[len(name)-1:], name[len(name)-3:len(name)-1], name)
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		t.Fatalf("unexpected error creating the cache directory: %v", err)
+	}
+
+	content := "\x05\x00\x00\x00\x00\x00\x00\x00binary header\nKEY: " + key + "\nHTTP/1.1 200 OK\r\n\r\nbody"
+	err = os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error writing the cache file: %v", err)
+	}
+
+	return path
+}
+
+const defaultKey = "$host:default/shop:$scheme$host$request_uri"
+
+func TestMatcher(t *testing.T) {
+	testCases := []struct {
+		request  Request
+		key      string
+		cached   string
+		expected bool
+	}{
+		{Request{Host: "example.com"}, defaultKey, "example.com:default/shop:httpsexample.com/", true},
+		{Request{Host: "example.com"}, defaultKey, "example.com.evil.org:default/shop:httpsexample.com.evil.org/", false},
+		{Request{Host: "Example.com"}, defaultKey, "example.com:default/shop:httpexample.com/assets/app.js", true},
+		{Request{Host: "example.com", Path: "/assets/app.js"}, defaultKey, "example.com:default/shop:httpexample.com/assets/app.js?v=2", true},
+		{Request{Host: "example.com", Path: "/assets"}, defaultKey, "example.com:default/shop:httpexample.com/assets/app.js", false},
+		{Request{Host: "example.com", Path: "/assets/", Prefix: true}, defaultKey, "example.com:default/shop:httpexample.com/assets/app.js", true},
+		{Request{Host: "example.com", Path: "/assets/", Prefix: true}, defaultKey, "example.com:default/shop:httpexample.com/api/users", false},
+		{Request{Host: "example.com"}, defaultKey, "", false},
+		{Request{Host: "example.com", Path: "/products"}, "$host:default/shop:$uri$arg_page", "example.com:default/shop:/products2", true},
+		{Request{Host: "example.com", Path: "/products"}, "$host:default/shop:$uri$arg_page", "shop.example.com:default/shop:/products2", false},
+		{Request{Host: "example.com"}, "$host:default/shop:${cookie_lang}", "example.com:default/shop:en", true},
+		{Request{Host: "example.com"}, "$host:default/shop:$http_host$uri", "example.com:default/shop:Example.com:8443/", true},
+	}
+
+	for _, testCase := range testCases {
+		matcher := testCase.request.Matcher(testCase.key)
+		if actual := matcher.MatchString(testCase.cached); actual != testCase.expected {
+			t.Errorf("expected %v matching the key %q with %+v and %q but returned %v", testCase.expected, testCase.cached, testCase.request, testCase.key, actual)
+		}
+	}
+
+	request := Request{Host: "example.com", Path: "/products"}
+	if matcher := request.Matcher("$host:default/shop:$cookie_lang"); matcher != nil {
+		t.Errorf("expected no matcher of a path with a key without path but returned %v", matcher)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	directory := t.TempDir()
+	app := writeCacheFile(t, directory, "0f3c9a61b2d4e5f6a7b8c9d0e1f2a3b4", "example.com:default/shop:httpsexample.com/assets/app.js")
+	style := writeCacheFile(t, directory, "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d", "example.com:default/shop:httpsexample.com/assets/style.css?v=1")
+	api := writeCacheFile(t, directory, "9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c", "example.com:default/shop:httpsexample.com/api/users")
+
+	request := &Request{Host: "example.com", Path: "/assets/", Prefix: true}
+	purged, err := Purge(directory, []*regexp.Regexp{request.Matcher(defaultKey)})
+	if err != nil {
+		t.Fatalf("unexpected error purging: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("expected 2 purged responses but returned %v", purged)
+	}
+	for _, path := range []string{app, style} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %v to be removed", path)
+		}
+	}
+	if _, err := os.Stat(api); err != nil {
+		t.Errorf("expected %v to be kept: %v", api, err)
+	}
+
+	request = &Request{Host: "example.com"}
+	purged, err = Purge(filepath.Join(directory, "missing"), []*regexp.Regexp{request.Matcher(defaultKey)})
+	if err != nil || purged != 0 {
+		t.Errorf("expected no purged responses in a missing directory but returned %v, %v", purged, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, request := range []Request{{}, {Host: "example.com/assets"}, {Host: "example.com", Path: "assets"}} {
+		if err := request.Validate(); err == nil {
+			t.Errorf("expected an error validating %+v", request)
+		}
+	}
+
+	request := Request{Host: "example.com", Path: "/assets/", Prefix: true}
+	if err := request.Validate(); err != nil {
+		t.Errorf("unexpected error validating %+v: %v", request, err)
+	}
+}
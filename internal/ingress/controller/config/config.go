@@ -17,6 +17,7 @@ limitations under the License.
 package config
 
 import (
+	"slices"
 	"strconv"
 	"time"
 
@@ -86,6 +87,19 @@ const (
 	defaultLimitConnZoneVariable = "$binary_remote_addr"
 )
 
+const (
+	// RequestIDFormatHex is the 32 hexadecimal digits $request_id of NGINX
+	RequestIDFormatHex = "hex"
+	// RequestIDFormatUUID4 is a random UUID
+	RequestIDFormatUUID4 = "uuid4"
+	// RequestIDFormatUUID7 is a UUID starting with the time of the request,
+	// sorting the request IDs by time
+	RequestIDFormatUUID7 = "uuid7"
+)
+
+// RequestIDFormats are the formats of the generated request IDs
+var RequestIDFormats = []string{RequestIDFormatHex, RequestIDFormatUUID4, RequestIDFormatUUID7}
+
 // Configuration represents the content of nginx.conf file
 type Configuration struct {
 	defaults.Backend `json:",squash"` //nolint:staticcheck // Ignore unknown JSON option "squash" error
@@ -200,6 +214,22 @@ type Configuration struct {
 	// http://nginx.org/en/docs/stream/ngx_stream_log_module.html
 	DisableStreamAccessLog bool `json:"disable-stream-access-log,omitempty"`
 
+	// AccessLogSampleRate sets the ratio, between 0 and 1, of the requests
+	// written to the HTTP access log, apart from the errors and slow requests
+	// Default: 1
+	AccessLogSampleRate float32 `json:"access-log-sample-rate"`
+
+	// AccessLogSampleMinStatus sets the status from which the requests are
+	// always written to the access log when it is sampled
+	// Default: 500
+	AccessLogSampleMinStatus int `json:"access-log-sample-min-status"`
+
+	// AccessLogSampleSlowThreshold sets the time in seconds to the response
+	// from which the requests are always written to the access log when it is
+	// sampled, 0 to disable
+	// Default: 0
+	AccessLogSampleSlowThreshold float32 `json:"access-log-sample-slow-threshold"`
+
 	// DisableIpv6DNS disables IPv6 for nginx resolver
 	DisableIpv6DNS bool `json:"disable-ipv6-dns"`
 
@@ -294,6 +324,10 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_log_module.html#log_format
 	LogFormatStream string `json:"log-format-stream,omitempty"`
 
+	// LogFormatJSONFields defines the fields of the JSON access log, replacing
+	// log-format-upstream. The fields are written as JSON strings.
+	LogFormatJSONFields []LogField `json:"log-format-json-fields,omitempty"`
+
 	// If disabled, a worker process will accept one new connection at a time.
 	// Otherwise, a worker process will accept all new connections at a time.
 	// http://nginx.org/en/docs/ngx_core_module.html#multi_accept
@@ -412,6 +446,16 @@ type Configuration struct {
 	// Default: false
 	SSLRejectHandshake bool `json:"ssl-reject-handshake"`
 
+	// SSLKeyEngine is the OpenSSL engine loading the private keys of the
+	// Secrets with a tls.key-id key, kept in an HSM or a KMS
+	// https://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_certificate_key
+	SSLKeyEngine string `json:"ssl-key-engine,omitempty"`
+
+	// SSLExpiryWarningDays is the number of days before their expiration a
+	// warning Event is emitted on the Ingresses of the served certificates.
+	// Zero disables the Events.
+	SSLExpiryWarningDays int `json:"ssl-expiry-warning-days"`
+
 	// Enables or disables the use of the PROXY protocol to receive client connection
 	// (real IP address) information passed through proxy servers and load balancers
 	// such as HAproxy and Amazon Elastic Load Balancer (ELB).
@@ -452,6 +496,19 @@ type Configuration struct {
 	// MIME Types that will be compressed on-the-fly using Brotli module
 	BrotliTypes string `json:"brotli-types,omitempty"`
 
+	// Enables or disables the use of the NGINX Zstandard Module for compression
+	// https://github.com/tokers/zstd-nginx-module
+	EnableZstd bool `json:"enable-zstd,omitempty"`
+
+	// Zstandard Compression Level that will be used
+	ZstdLevel int `json:"zstd-level,omitempty"`
+
+	// Minimum length of responses, in bytes, that will be eligible for zstd compression
+	ZstdMinLength int `json:"zstd-min-length,omitempty"`
+
+	// MIME Types that will be compressed on-the-fly using Zstandard module
+	ZstdTypes string `json:"zstd-types,omitempty"`
+
 	// Enables or disables the HTTP/2 support in secure connections
 	// http://nginx.org/en/docs/http/ngx_http_v2_module.html
 	// Default: true
@@ -546,6 +603,12 @@ type Configuration struct {
 	// Default: 1
 	ProxyStreamResponses int `json:"proxy-stream-responses,omitempty"`
 
+	// Enables the PROXY protocol (version 1) on the connections to the backends
+	// of all the TCP services, as the encoding field of the TCP services ConfigMap.
+	// NGINX can't send it to the backends of HTTP services.
+	// http://nginx.org/en/docs/stream/ngx_stream_proxy_module.html#proxy_protocol
+	ProxyStreamProxyProtocol bool `json:"proxy-stream-proxy-protocol"`
+
 	// Modifies the HTTP version the proxy uses to interact with the backend.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_http_version
 	ProxyHTTPVersion string `json:"proxy-http-version"`
@@ -578,6 +641,19 @@ type Configuration struct {
 	// Default: true
 	GenerateRequestID bool `json:"generate-request-id,omitempty"`
 
+	// RequestIDFormat is the format of the generated request IDs: hex, the
+	// $request_id of NGINX, uuid4 or uuid7
+	// Default: hex
+	RequestIDFormat string `json:"request-id-format,omitempty"`
+
+	// RequestIDPrefix is prepended to the generated request IDs, to identify
+	// the cluster or the controller generating them
+	RequestIDPrefix string `json:"request-id-prefix,omitempty"`
+
+	// RequestIDTrustedCIDRs are the addresses of the proxies whose X-Request-ID
+	// header is used. When empty, the header of all the clients is used.
+	RequestIDTrustedCIDRs []string `json:"request-id-trusted-cidrs,omitempty"`
+
 	// Adds an X-Original-Uri header with the original request URI to the backend request
 	// Default: true
 	ProxyAddOriginalURIHeader bool `json:"proxy-add-original-uri-header"`
@@ -598,6 +674,24 @@ type Configuration struct {
 	// Default: true
 	OpentelemetryTrustIncomingSpan bool `json:"opentelemetry-trust-incoming-span"`
 
+	// OpentelemetrySpanAttributes are the attributes added to the spans of all the locations
+	OpentelemetrySpanAttributes map[string]string `json:"opentelemetry-span-attributes,omitempty"`
+
+	// OpentelemetryRouteOperationName names the server spans after the method and
+	// the path of the location, and adds the http.route attribute to them
+	// Default: false
+	OpentelemetryRouteOperationName bool `json:"opentelemetry-route-operation-name"`
+
+	// OpentelemetryPropagator sets the trace context format accepted from the
+	// clients and sent to the upstreams: w3c, b3, b3multi or none
+	// Default: w3c
+	OpentelemetryPropagator string `json:"opentelemetry-propagator"`
+
+	// OpentelemetryForwardBaggage sets whether or not the baggage header of the
+	// requests is forwarded to the upstreams
+	// Default: true
+	OpentelemetryForwardBaggage bool `json:"opentelemetry-forward-baggage"`
+
 	// OtlpCollectorHost specifies the host to use when uploading traces
 	OtlpCollectorHost string `json:"otlp-collector-host"`
 
@@ -694,9 +788,14 @@ type Configuration struct {
 	// +optional
 	GlobalExternalAuth GlobalExternalAuth `json:"global-external-auth"`
 
-	// Checksum contains a checksum of the configmap configuration
+	// Checksum contains a checksum of the configmap configuration, without
+	// the DynamicKeys
 	Checksum string `json:"-"`
 
+	// DynamicChecksum contains a checksum of the DynamicKeys of the configmap
+	// configuration
+	DynamicChecksum string `json:"-"`
+
 	// Block all requests from given IPs
 	BlockCIDRs []string `json:"block-cidrs"`
 
@@ -759,10 +858,149 @@ type Configuration struct {
 	// like used on Rewrite configurations the user should use pathType as ImplementationSpecific
 	StrictValidatePathType bool `json:"strict-validate-path-type"`
 
+	// NamespaceHostSuffixes are the suffixes of the hosts the Ingresses of
+	// the namespaces can use, with the namespace-suffixes host ownership
+	// policy, like "team-a: a.example.com a.example.org, team-b: b.example.com"
+	NamespaceHostSuffixes map[string][]string `json:"namespace-host-suffixes"`
+
 	// GRPCBufferSizeKb Sets the size of the buffer used for reading the response received
 	// from the gRPC server. The response is passed to the client synchronously,
 	// as soon as it is received.
 	GRPCBufferSizeKb int `json:"grpc-buffer-size-kb"`
+
+	// CanaryStickySecret is the <namespace>/<name> of the Secret with the key
+	// used to sign the cookies persisting sticky canary assignments, in its
+	// secret key. If empty, the canary decisions are not persisted
+	CanaryStickySecret string `json:"canary-sticky-secret"`
+
+	// EWMADecayTime is the time in seconds after which the response times measured
+	// by the ewma load balancer lose most of their weight in the score of an endpoint
+	EWMADecayTime float32 `json:"ewma-decay-time"`
+
+	// EWMAInitialWeight is the score, in seconds, of the endpoints the ewma load
+	// balancer has no response time for yet
+	EWMAInitialWeight float32 `json:"ewma-initial-weight"`
+
+	// EnableEWMAMetrics exports the ewma score of the endpoints picked by the ewma
+	// load balancer as a metric, labeled by endpoint
+	EnableEWMAMetrics bool `json:"enable-ewma-metrics"`
+
+	// EnableEndpointMetrics exports the response time, the status and the
+	// connection failures of the upstream attempts as metrics, labeled by
+	// endpoint
+	EnableEndpointMetrics bool `json:"enable-endpoint-metrics"`
+
+	// MetricsDropLabels are the labels of the request metrics whose values are
+	// dropped, aggregating the series of all their values
+	MetricsDropLabels []string `json:"metrics-drop-labels"`
+
+	// MetricsLimitLabels are the labels of the request metrics whose distinct
+	// values are capped by MetricsMaxLabelValues
+	MetricsLimitLabels []string `json:"metrics-limit-labels"`
+
+	// MetricsMaxLabelValues caps the distinct values of the labels of
+	// MetricsLimitLabels, the other values being reported as overflow. 0
+	// means no limit
+	MetricsMaxLabelValues int `json:"metrics-max-label-values"`
+
+	// SessionAffinityRedisHost configures the redis host where the endpoints of
+	// the sticky sessions are stored. If empty, the endpoint is only encoded in the cookie
+	SessionAffinityRedisHost string `json:"session-affinity-redis-host"`
+
+	// SessionAffinityRedisPort configures the redis port.
+	SessionAffinityRedisPort int `json:"session-affinity-redis-port"`
+
+	// SessionAffinityRedisConnectTimeout configures timeout when connecting to redis.
+	// The unit is millisecond.
+	SessionAffinityRedisConnectTimeout int `json:"session-affinity-redis-connect-timeout"`
+
+	// SessionAffinityRedisMaxIdleTimeout configures how long connections
+	// should be kept alive in idle state. The unit is millisecond.
+	SessionAffinityRedisMaxIdleTimeout int `json:"session-affinity-redis-max-idle-timeout"`
+
+	// SessionAffinityRedisPoolSize configures how many connections
+	// should be kept alive in the pool, per NGINX worker.
+	SessionAffinityRedisPoolSize int `json:"session-affinity-redis-pool-size"`
+
+	// SessionAffinityRedisTTL is the time in seconds a sticky session is kept
+	// in redis after it was last used
+	SessionAffinityRedisTTL int `json:"session-affinity-redis-ttl"`
+
+	// BotDetectionSecret is the <namespace>/<name> of the Secret with the key
+	// used to sign the cookies of the clients which passed the bot detection
+	// challenge, in its secret key. If empty, the challenges are not served
+	BotDetectionSecret string `json:"bot-detection-secret"`
+
+	// BotDetectionReputationCIDRs are the addresses of the clients with a bad
+	// reputation, scored as bots by the bot detection of the locations
+	BotDetectionReputationCIDRs []string `json:"bot-detection-reputation-cidrs"`
+
+	// EnableCrowdSec denies the requests of the addresses with a decision of
+	// the CrowdSec Local API, in the access phase of all the locations
+	EnableCrowdSec bool `json:"enable-crowdsec"`
+
+	// CrowdSecLAPIURL is the URL of the CrowdSec Local API, e.g.
+	// http://crowdsec-service.crowdsec:8080
+	CrowdSecLAPIURL string `json:"crowdsec-lapi-url"`
+
+	// CrowdSecAPIKeySecret is the <namespace>/<name> of the Secret with the
+	// API key of the bouncer registered in the CrowdSec Local API with cscli
+	// bouncers add, in its api-key key
+	CrowdSecAPIKeySecret string `json:"crowdsec-api-key-secret"`
+
+	// CrowdSecCacheTTL is the time in seconds the decisions of the Local API
+	// about an address are cached by every replica
+	CrowdSecCacheTTL int `json:"crowdsec-cache-ttl"`
+
+	// CrowdSecTimeout is the timeout of the requests to the Local API. The unit
+	// is millisecond. The requests are allowed when the Local API cannot be
+	// reached in time
+	CrowdSecTimeout int `json:"crowdsec-timeout"`
+
+	// EnableAutoBan bans the clients receiving AutoBanThreshold responses with
+	// the AutoBanStatusCodes within AutoBanWindow seconds, denying their
+	// requests to all the servers for AutoBanDuration seconds
+	EnableAutoBan bool `json:"enable-auto-ban"`
+
+	// AutoBanStatusCodes are the status codes of the responses counted by the
+	// automatic banning
+	AutoBanStatusCodes []int `json:"auto-ban-status-codes"`
+
+	// AutoBanThreshold is the number of responses with the AutoBanStatusCodes
+	// banning a client
+	AutoBanThreshold int `json:"auto-ban-threshold"`
+
+	// AutoBanWindow is the time in seconds the responses of a client are
+	// counted by the automatic banning
+	AutoBanWindow int `json:"auto-ban-window"`
+
+	// AutoBanDuration is the time in seconds a client is banned
+	AutoBanDuration int `json:"auto-ban-duration"`
+
+	// CSRFSecret is the <namespace>/<name> of the Secret with the key used
+	// to sign the tokens of the CSRF protection of the locations, in its
+	// secret key
+	CSRFSecret string `json:"csrf-secret"`
+
+	// ProxyCacheZones are the zones the locations cache the responses of their
+	// backends in, with the proxy-cache-zone annotation
+	ProxyCacheZones []ProxyCacheZone `json:"proxy-cache-zones"`
+
+	// ProxyCachePath is the directory of the proxy cache zones, every zone is
+	// cached in a subdirectory named after it
+	ProxyCachePath string `json:"proxy-cache-path"`
+}
+
+// ProxyCacheZone describes a zone of the proxy cache
+type ProxyCacheZone struct {
+	Name string `json:"name"`
+	// MaxSize is the maximum size of the cached responses, like 10g
+	MaxSize string `json:"maxSize"`
+	// Inactive is the time after which the responses not requested are removed
+	Inactive string `json:"inactive"`
+	// KeysZoneSize is the size of the shared memory zone of the keys, sized
+	// after MaxSize
+	KeysZoneSize string `json:"keysZoneSize"`
 }
 
 // NewDefault returns the default nginx configuration
@@ -797,6 +1035,9 @@ func NewDefault() Configuration {
 		BrotliLevel:                      4,
 		BrotliMinLength:                  20,
 		BrotliTypes:                      brotliTypes,
+		ZstdLevel:                        3,
+		ZstdMinLength:                    20,
+		ZstdTypes:                        brotliTypes,
 		ClientHeaderBufferSize:           "1k",
 		ClientHeaderTimeout:              60,
 		ClientBodyBufferSize:             "8k",
@@ -809,6 +1050,7 @@ func NewDefault() Configuration {
 		ComputeFullForwardedFor:          false,
 		ProxyAddOriginalURIHeader:        false,
 		GenerateRequestID:                true,
+		RequestIDFormat:                  RequestIDFormatHex,
 		HTTP2MaxFieldSize:                "",
 		HTTP2MaxHeaderSize:               "",
 		HTTP2MaxRequests:                 0,
@@ -828,6 +1070,8 @@ func NewDefault() Configuration {
 		LogFormatEscapeJSON:              false,
 		LogFormatStream:                  logFormatStream,
 		LogFormatUpstream:                logFormatUpstream,
+		AccessLogSampleRate:              1,
+		AccessLogSampleMinStatus:         500,
 		EnableMultiAccept:                true,
 		MaxWorkerConnections:             16384,
 		MaxWorkerOpenFiles:               0,
@@ -848,11 +1092,13 @@ func NewDefault() Configuration {
 		SSLProtocols:                     sslProtocols,
 		SSLEarlyData:                     sslEarlyData,
 		SSLRejectHandshake:               false,
+		SSLExpiryWarningDays:             10,
 		SSLSessionCache:                  true,
 		SSLSessionCacheSize:              sslSessionCacheSize,
 		SSLSessionTickets:                false,
 		SSLSessionTimeout:                sslSessionTimeout,
 		EnableBrotli:                     false,
+		EnableZstd:                       false,
 		EnableAioWrite:                   true,
 		UseGzip:                          false,
 		UseGeoIP2:                        false,
@@ -896,6 +1142,7 @@ func NewDefault() Configuration {
 			ProxyHTTPVersion:            "1.1",
 			ProxyMaxTempFileSize:        "1024m",
 			ServiceUpstream:             false,
+			SecurityHeaders:             "none",
 			AllowedResponseHeaders:      []string{},
 		},
 		UpstreamKeepaliveConnections:           320,
@@ -908,6 +1155,8 @@ func NewDefault() Configuration {
 		OpentelemetryTrustIncomingSpan:         true,
 		OpentelemetryConfig:                    "/etc/ingress-controller/telemetry/opentelemetry.toml",
 		OtlpCollectorPort:                      "4317",
+		OpentelemetryPropagator:                "w3c",
+		OpentelemetryForwardBaggage:            true,
 		OtelServiceName:                        "nginx",
 		OtelSampler:                            "AlwaysOn",
 		OtelSamplerRatio:                       0.01,
@@ -931,6 +1180,26 @@ func NewDefault() Configuration {
 		DebugConnections:                       []string{},
 		StrictValidatePathType:                 false, // TODO: This will be true in future releases
 		GRPCBufferSizeKb:                       0,
+		EWMADecayTime:                          10,
+		EWMAInitialWeight:                      0,
+		EnableEWMAMetrics:                      false,
+		EnableEndpointMetrics:                  false,
+		MetricsDropLabels:                      []string{},
+		MetricsLimitLabels:                     []string{"host", "namespace", "ingress", "service"},
+		MetricsMaxLabelValues:                  0,
+		SessionAffinityRedisPort:               6379,
+		CrowdSecCacheTTL:                       60,
+		CrowdSecTimeout:                        200,
+		AutoBanStatusCodes:                     []int{401, 403, 429},
+		AutoBanThreshold:                       20,
+		AutoBanWindow:                          60,
+		AutoBanDuration:                        600,
+		ProxyCacheZones:                        []ProxyCacheZone{},
+		ProxyCachePath:                         "/tmp/nginx/proxy-cache",
+		SessionAffinityRedisConnectTimeout:     50,
+		SessionAffinityRedisMaxIdleTimeout:     10000,
+		SessionAffinityRedisPoolSize:           50,
+		SessionAffinityRedisTTL:                86400,
 	}
 
 	if klog.V(5).Enabled() {
@@ -940,6 +1209,13 @@ func NewDefault() Configuration {
 	return cfg
 }
 
+// MetricsLabelLimited returns true when the values of a label of the request
+// metrics are dropped or may be reported as overflow
+func (cfg *Configuration) MetricsLabelLimited(label string) bool {
+	return slices.Contains(cfg.MetricsDropLabels, label) ||
+		(cfg.MetricsMaxLabelValues > 0 && slices.Contains(cfg.MetricsLimitLabels, label))
+}
+
 // TemplateConfig contains the nginx configuration to render the file nginx.conf
 type TemplateConfig struct {
 	ProxySetHeaders          map[string]string                `json:"ProxySetHeaders"`
@@ -954,12 +1230,15 @@ type TemplateConfig struct {
 	Cfg                      Configuration                    `json:"Cfg"`
 	IsIPV6Enabled            bool                             `json:"IsIPV6Enabled"`
 	IsSSLPassthroughEnabled  bool                             `json:"IsSSLPassthroughEnabled"`
+	IsNJSAvailable           bool                             `json:"IsNJSAvailable"`
+	IsZstdAvailable          bool                             `json:"IsZstdAvailable"`
 	NginxStatusIpv4Whitelist []string                         `json:"NginxStatusIpv4Whitelist"`
 	NginxStatusIpv6Whitelist []string                         `json:"NginxStatusIpv6Whitelist"`
 	RedirectServers          interface{}                      `json:"RedirectServers"`
 	ListenPorts              *ListenPorts                     `json:"ListenPorts"`
 	PublishService           *apiv1.Service                   `json:"PublishService"`
 	EnableMetrics            bool                             `json:"EnableMetrics"`
+	EnableACME               bool                             `json:"EnableACME"`
 	MaxmindEditionFiles      *[]string                        `json:"MaxmindEditionFiles"`
 	MonitorMaxBatchSize      int                              `json:"MonitorMaxBatchSize"`
 	PID                      string                           `json:"PID"`
@@ -967,6 +1246,15 @@ type TemplateConfig struct {
 	StatusPort               int                              `json:"StatusPort"`
 	StreamPort               int                              `json:"StreamPort"`
 	StreamSnippets           []string                         `json:"StreamSnippets"`
+	// AdminSocket is the unix socket of the ingress controller serving the
+	// configuration snapshots on SnapshotsPath, empty when they are disabled
+	AdminSocket   string `json:"AdminSocket"`
+	SnapshotsPath string `json:"SnapshotsPath"`
+	// EnableDrain counts the requests in flight, to drain them on shutdown
+	EnableDrain bool `json:"EnableDrain"`
+	// LogForwardingAddress is the internal syslog server the access and error
+	// logs are also sent to, to be forwarded, empty when they are not
+	LogForwardingAddress string `json:"LogForwardingAddress"`
 }
 
 // ListenPorts describe the ports required to run the
@@ -996,3 +1284,10 @@ type GlobalExternalAuth struct {
 	ProxySetHeaders        map[string]string `json:"proxySetHeaders,omitempty"`
 	AlwaysSetCookie        bool              `json:"alwaysSetCookie,omitempty"`
 }
+
+// LogField is a field of the JSON access log, its value contains text and
+// NGINX variables
+type LogField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
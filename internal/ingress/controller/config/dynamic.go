@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// DynamicKeys are the keys of the configuration ConfigMap applied without
+// reloading NGINX. They are only read by the Lua modules, which get them
+// from the general configuration posted to /configuration/general.
+var DynamicKeys = sets.NewString(
+	"hsts",
+	"hsts-include-subdomains",
+	"hsts-max-age",
+	"hsts-preload",
+	"global-rate-limit-memcached-host",
+	"global-rate-limit-memcached-port",
+	"global-rate-limit-memcached-connect-timeout",
+	"global-rate-limit-memcached-max-idle-timeout",
+	"global-rate-limit-memcached-pool-size",
+	"global-rate-limit-status-code",
+	"enable-auto-ban",
+	"auto-ban-status-codes",
+	"auto-ban-threshold",
+	"auto-ban-window",
+	"auto-ban-duration",
+	"canary-sticky-secret",
+	"bot-detection-secret",
+	"crowdsec-api-key-secret",
+	"csrf-secret",
+)
+
+// DynamicConfiguration contains the values of the DynamicKeys, in the
+// format of the configuration of the lua_ingress module
+type DynamicConfiguration struct {
+	HSTS                  bool                        `json:"hsts"`
+	HSTSMaxAge            string                      `json:"hsts_max_age"`
+	HSTSIncludeSubdomains bool                        `json:"hsts_include_subdomains"`
+	HSTSPreload           bool                        `json:"hsts_preload"`
+	GlobalThrottle        GlobalThrottleConfiguration `json:"global_throttle"`
+	AutoBan               AutoBanConfiguration        `json:"auto_ban"`
+}
+
+// GlobalThrottleConfiguration contains the memcached server of the global
+// rate limiting and the status code of the rejected requests
+type GlobalThrottleConfiguration struct {
+	Memcached  MemcachedConfiguration `json:"memcached"`
+	StatusCode int                    `json:"status_code"`
+}
+
+// MemcachedConfiguration describes a memcached server and its connections
+type MemcachedConfiguration struct {
+	Host           string `json:"host"`
+	Port           int    `json:"port"`
+	ConnectTimeout int    `json:"connect_timeout"`
+	MaxIdleTimeout int    `json:"max_idle_timeout"`
+	PoolSize       int    `json:"pool_size"`
+}
+
+// AutoBanConfiguration contains the thresholds of the automatic banning of
+// the clients
+type AutoBanConfiguration struct {
+	Enabled     bool  `json:"enabled"`
+	StatusCodes []int `json:"status_codes"`
+	Threshold   int   `json:"threshold"`
+	Window      int   `json:"window"`
+	Duration    int   `json:"duration"`
+}
+
+// LuaSecret is a value of a Secret referenced by the configuration, read by
+// the Lua modules. The values of the Secrets are posted to
+// /configuration/secrets, they are never rendered in nginx.conf.
+type LuaSecret struct {
+	// Name is the name of the value in the Lua modules
+	Name string
+	// Secret is the <namespace>/<name> of the Secret
+	Secret string
+	// Key is the key of the value in the data of the Secret
+	Key string
+}
+
+// LuaSecrets returns the values of the Secrets referenced by the
+// configuration
+func (cfg *Configuration) LuaSecrets() []LuaSecret {
+	var secrets []LuaSecret
+	add := func(name, secret, key string) {
+		if secret != "" {
+			secrets = append(secrets, LuaSecret{Name: name, Secret: secret, Key: key})
+		}
+	}
+
+	add("canary_sticky_secret", cfg.CanaryStickySecret, "secret")
+	add("bot_detection_secret", cfg.BotDetectionSecret, "secret")
+	add("crowdsec_api_key", cfg.CrowdSecAPIKeySecret, "api-key")
+	add("csrf_secret", cfg.CSRFSecret, "secret")
+
+	return secrets
+}
+
+// Dynamic returns the values of the DynamicKeys of the configuration
+func (cfg *Configuration) Dynamic() DynamicConfiguration {
+	return DynamicConfiguration{
+		HSTS:                  cfg.HSTS,
+		HSTSMaxAge:            cfg.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.HSTSIncludeSubdomains,
+		HSTSPreload:           cfg.HSTSPreload,
+		GlobalThrottle: GlobalThrottleConfiguration{
+			Memcached: MemcachedConfiguration{
+				Host:           cfg.GlobalRateLimitMemcachedHost,
+				Port:           cfg.GlobalRateLimitMemcachedPort,
+				ConnectTimeout: cfg.GlobalRateLimitMemcachedConnectTimeout,
+				MaxIdleTimeout: cfg.GlobalRateLimitMemcachedMaxIdleTimeout,
+				PoolSize:       cfg.GlobalRateLimitMemcachedPoolSize,
+			},
+			StatusCode: cfg.GlobalRateLimitStatusCode,
+		},
+		AutoBan: AutoBanConfiguration{
+			Enabled:     cfg.EnableAutoBan,
+			StatusCodes: cfg.AutoBanStatusCodes,
+			Threshold:   cfg.AutoBanThreshold,
+			Window:      cfg.AutoBanWindow,
+			Duration:    cfg.AutoBanDuration,
+		},
+	}
+}
+
+// UpdateChecksums sets the Checksum of the configuration without the
+// DynamicKeys, which changes when NGINX must be reloaded, and the
+// DynamicChecksum of the DynamicKeys.
+func (cfg *Configuration) UpdateChecksums() error {
+	reloadCfg := *cfg
+	reloadCfg.clearDynamicKeys()
+
+	hash, err := hashstructure.Hash(reloadCfg, hashstructure.FormatV1, &hashstructure.HashOptions{
+		TagName: "json",
+	})
+	if err != nil {
+		return err
+	}
+
+	dynamicHash, err := hashstructure.Hash(cfg.Dynamic(), hashstructure.FormatV1, &hashstructure.HashOptions{
+		TagName: "json",
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.Checksum = fmt.Sprintf("%v", hash)
+	cfg.DynamicChecksum = fmt.Sprintf("%v", dynamicHash)
+	return nil
+}
+
+// clearDynamicKeys sets the fields of the DynamicKeys to their zero value
+func (cfg *Configuration) clearDynamicKeys() {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if DynamicKeys.Has(name) {
+			v.Field(i).Set(reflect.Zero(t.Field(i).Type))
+		}
+	}
+}
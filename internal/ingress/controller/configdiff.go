@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	klog "k8s.io/klog/v2"
+)
+
+// maxDiffWarnings is the maximum number of warnings summarizing the changes
+// of the configuration, the API server limits the size of the warnings
+const maxDiffWarnings = 10
+
+const (
+	serverStartMarker = "## start server "
+	serverEndMarker   = "## end server "
+)
+
+// serverBlocks returns the directives of the server blocks of a NGINX
+// configuration, by server name. Comments and closing braces are ignored.
+func serverBlocks(content []byte) map[string][]string {
+	servers := map[string][]string{}
+
+	server := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, serverStartMarker):
+			server = strings.TrimPrefix(line, serverStartMarker)
+			servers[server] = []string{}
+		case strings.HasPrefix(line, serverEndMarker):
+			server = ""
+		case server == "" || line == "" || line == "}" || strings.HasPrefix(line, "#"):
+		default:
+			servers[server] = append(servers[server], line)
+		}
+	}
+
+	return servers
+}
+
+// diffDirectives returns the directives of candidate missing in running, and
+// the directives of running missing in candidate
+func diffDirectives(running, candidate []string) (added, removed []string) {
+	count := map[string]int{}
+	for _, directive := range running {
+		count[directive]++
+	}
+	for _, directive := range candidate {
+		if count[directive] > 0 {
+			count[directive]--
+			continue
+		}
+		added = append(added, directive)
+	}
+
+	for _, directive := range running {
+		if count[directive] > 0 {
+			count[directive]--
+			removed = append(removed, directive)
+		}
+	}
+
+	return added, removed
+}
+
+// diffHosts returns the server names of the rules of the Ingresses, the
+// default server for the rules without host and the default backends
+func diffHosts(ings ...*networking.Ingress) sets.Set[string] {
+	hosts := sets.New[string]()
+	for _, ing := range ings {
+		if ing == nil {
+			continue
+		}
+		if ing.Spec.DefaultBackend != nil {
+			hosts.Insert(defServerName)
+		}
+		for i := range ing.Spec.Rules {
+			host := ing.Spec.Rules[i].Host
+			if host == "" {
+				host = defServerName
+			}
+			hosts.Insert(host)
+		}
+	}
+	return hosts
+}
+
+// configDiff returns warnings summarizing the changes of the server blocks
+// of the hosts in the candidate NGINX configuration compared to the running
+// one. The changes of the other server blocks are not caused by the
+// validated Ingress.
+func configDiff(running, candidate []byte, hosts sets.Set[string]) []string {
+	runningServers := serverBlocks(running)
+	candidateServers := serverBlocks(candidate)
+
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		_, inRunning := runningServers[name]
+		_, inCandidate := candidateServers[name]
+		if inRunning || inCandidate {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var warnings []string
+	for _, name := range names {
+		runningDirectives, inRunning := runningServers[name]
+		candidateDirectives, inCandidate := candidateServers[name]
+		switch {
+		case !inRunning:
+			warnings = append(warnings, fmt.Sprintf("configuration change: new server block %q", name))
+		case !inCandidate:
+			warnings = append(warnings, fmt.Sprintf("configuration change: removed server block %q", name))
+		default:
+			added, removed := diffDirectives(runningDirectives, candidateDirectives)
+			for _, directive := range added {
+				warnings = append(warnings, fmt.Sprintf("configuration change: server %q adds %q", name, directive))
+			}
+			for _, directive := range removed {
+				warnings = append(warnings, fmt.Sprintf("configuration change: server %q removes %q", name, directive))
+			}
+		}
+	}
+
+	if len(warnings) > maxDiffWarnings {
+		more := len(warnings) - maxDiffWarnings
+		warnings = append(warnings[:maxDiffWarnings], fmt.Sprintf("configuration change: %v more changes", more))
+	}
+
+	return warnings
+}
+
+// configurationDiff returns warnings summarizing the changes of the server
+// blocks of the hosts of an Ingress in the candidate NGINX configuration
+// compared to the running one. The hosts of the running version of the
+// Ingress are included to report the removed server blocks.
+func (n *NGINXController) configurationDiff(candidate []byte, ing, runningIng *networking.Ingress) []string {
+	running, err := os.ReadFile(cfgPath)
+	if err != nil {
+		klog.Warningf("Error reading the running NGINX configuration: %v", err)
+		return nil
+	}
+
+	return configDiff(running, candidate, diffHosts(ing, runningIng))
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestConfigDiff(t *testing.T) {
+	running := []byte(`
+http {
+    ## start server _
+    server {
+        server_name _ ;
+        listen 80 default_server;
+    }
+    ## end server _
+
+    ## start server foo.bar
+    server {
+        server_name foo.bar ;
+        # custom timeout
+        proxy_read_timeout 60s;
+        location / {
+            proxy_pass http://upstream_balancer;
+        }
+    }
+    ## end server foo.bar
+
+    ## start server old.bar
+    server {
+        server_name old.bar ;
+    }
+    ## end server old.bar
+}
+`)
+
+	candidate := []byte(`
+http {
+    ## start server _
+    server {
+        server_name _ ;
+        listen 80 default_server;
+    }
+    ## end server _
+
+    ## start server foo.bar
+    server {
+        server_name foo.bar ;
+        proxy_read_timeout 120s;
+        location / {
+            proxy_pass http://upstream_balancer;
+        }
+    }
+    ## end server foo.bar
+
+    ## start server new.bar
+    server {
+        server_name new.bar ;
+    }
+    ## end server new.bar
+}
+`)
+
+	expected := []string{
+		`configuration change: server "foo.bar" adds "proxy_read_timeout 120s;"`,
+		`configuration change: server "foo.bar" removes "proxy_read_timeout 60s;"`,
+		`configuration change: new server block "new.bar"`,
+		`configuration change: removed server block "old.bar"`,
+	}
+	hosts := sets.New[string]("foo.bar", "new.bar", "old.bar", "missing.bar")
+	if warnings := configDiff(running, candidate, hosts); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("expected %v but got %v", expected, warnings)
+	}
+
+	// the server blocks of the other hosts are ignored
+	if warnings := configDiff(running, candidate, sets.New[string]("_", "new.bar")); !reflect.DeepEqual(warnings, expected[2:3]) {
+		t.Errorf("expected %v but got %v", expected[2:3], warnings)
+	}
+
+	if warnings := configDiff(running, running, hosts); len(warnings) != 0 {
+		t.Errorf("expected no warnings for the same configuration, got %v", warnings)
+	}
+
+	var many strings.Builder
+	many.WriteString("## start server many.bar\n")
+	for _, directive := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		many.WriteString(directive + " on;\n")
+	}
+	many.WriteString("## end server many.bar\n")
+
+	warnings := configDiff([]byte("## start server many.bar\n## end server many.bar\n"), []byte(many.String()), sets.New[string]("many.bar"))
+	if len(warnings) != maxDiffWarnings+1 || warnings[maxDiffWarnings] != "configuration change: 2 more changes" {
+		t.Errorf("expected the warnings to be limited to %v, got %v", maxDiffWarnings, warnings)
+	}
+}
+
+func TestDiffHosts(t *testing.T) {
+	candidate := &networking.Ingress{
+		Spec: networking.IngressSpec{
+			Rules: []networking.IngressRule{{Host: "foo.bar"}, {}},
+		},
+	}
+	running := &networking.Ingress{
+		Spec: networking.IngressSpec{
+			Rules: []networking.IngressRule{{Host: "old.bar"}},
+		},
+	}
+
+	expected := sets.New[string]("foo.bar", "_", "old.bar")
+	if hosts := diffHosts(candidate, running); !hosts.Equal(expected) {
+		t.Errorf("expected %v but got %v", sets.List(expected), sets.List(hosts))
+	}
+	if hosts := diffHosts(candidate, nil); hosts.Has("old.bar") {
+		t.Errorf("expected only the hosts of the candidate, got %v", sets.List(hosts))
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/mitchellh/hashstructure/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	apiv1 "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
@@ -31,14 +32,17 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/securityheaders"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+	"k8s.io/ingress-nginx/internal/ingress/controller/sharding"
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/inspector"
@@ -46,6 +50,7 @@ import (
 	"k8s.io/ingress-nginx/internal/k8s"
 	"k8s.io/ingress-nginx/internal/nginx"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 	utilingress "k8s.io/ingress-nginx/pkg/util/ingress"
 	"k8s.io/klog/v2"
 )
@@ -53,10 +58,12 @@ import (
 const (
 	defUpstreamName             = "upstream-default-backend"
 	defServerName               = "_"
+	rejectHandshakeAnnotation   = "ssl-reject-handshake"
 	rootLocation                = "/"
 	emptyZone                   = ""
 	orphanMetricLabelNoService  = "no-service"
 	orphanMetricLabelNoEndpoint = "no-endpoint"
+	sslPassthroughBackendPrefix = "ssl-passthrough-"
 )
 
 // Configuration contains all the settings required by an Ingress controller
@@ -82,6 +89,66 @@ type Configuration struct {
 	// +optional
 	UDPConfigMapName string
 
+	// +optional
+	EnableStreamRoutes bool
+	// StreamRouteClient is used to watch and update the StreamRoutes when
+	// they are enabled
+	// +optional
+	StreamRouteClient dynamic.Interface
+
+	// +optional
+	EnableIngressClassParams bool
+	// ClassParamsClient is used to watch the NginxIngressClassParams when
+	// they are enabled
+	// +optional
+	ClassParamsClient dynamic.Interface
+
+	// +optional
+	EnableWAFPolicies bool
+	// WAFPolicyClient is used to watch the WAFPolicies when they are enabled
+	// +optional
+	WAFPolicyClient dynamic.Interface
+
+	// +optional
+	EnableDenylists bool
+	// DenylistClient is used to watch the Denylists when they are enabled
+	// +optional
+	DenylistClient dynamic.Interface
+
+	// +optional
+	EnableRedirectMaps bool
+	// RedirectMapClient is used to watch the RedirectMaps when they are enabled
+	// +optional
+	RedirectMapClient dynamic.Interface
+
+	// +optional
+	EnableGatewayAPI bool
+	// GatewayClient is used to watch and update the Gateway API objects when
+	// they are enabled
+	// +optional
+	GatewayClient dynamic.Interface
+	// EnableExperimentalGatewayAPI also serves the TLSRoutes and TCPRoutes
+	// of the experimental channel of the Gateway API
+	// +optional
+	EnableExperimentalGatewayAPI bool
+	// EnableReferenceGrants authorizes the references of the annotations to
+	// the Secrets of other namespaces with the ReferenceGrants
+	// +optional
+	EnableReferenceGrants bool
+
+	// HostOwnershipPolicy decides which namespaces can define the rules of
+	// the hosts
+	HostOwnershipPolicy string
+	// HostOwnershipClient is used to watch the HostOwnerships with the crd
+	// policy
+	// +optional
+	HostOwnershipClient dynamic.Interface
+
+	// WatchReferencedSecrets only watches the Secrets referenced by the
+	// Ingresses and the other resources of the controller
+	// +optional
+	WatchReferencedSecrets bool
+
 	DefaultSSLCertificate string
 
 	// +optional
@@ -107,22 +174,62 @@ type Configuration struct {
 
 	EnableMetrics        bool
 	MetricsPerHost       bool
+	MetricsPerPath       bool
+	MetricsMaxPaths      int
 	MetricsBuckets       *collectors.HistogramBuckets
 	ReportStatusClasses  bool
 	ExcludeSocketMetrics []string
 
+	// OTLPMetricsEndpoint is the OTLP/HTTP endpoint the metrics are pushed to
+	// every OTLPMetricsInterval, empty disabling the push
+	OTLPMetricsEndpoint string
+	OTLPMetricsHeaders  map[string]string
+	OTLPMetricsInterval time.Duration
+
+	// OTLPLogsEndpoint and SyslogForwardAddress are the OTLP/HTTP endpoint and
+	// the syslog server the access and error logs of NGINX are forwarded to,
+	// through the internal syslog server. Empty disables the forwarding.
+	OTLPLogsEndpoint        string
+	OTLPLogsHeaders         map[string]string
+	SyslogForwardAddress    string
+	SyslogForwardTLS        bool
+	LogForwardCAFile        string
+	LogForwardBufferSize    int
+	LogForwardFlushInterval time.Duration
+
 	FakeCertificate *ingress.SSLCert
 
 	SyncRateLimit float32
 
+	// SyncQuietPeriod and SyncMaxDelay coalesce the changes received in a
+	// burst in a single sync
+	SyncQuietPeriod time.Duration
+	SyncMaxDelay    time.Duration
+
+	// ConfigurationSnapshots is the number of configurations applied kept on
+	// disk to roll back to, 0 disables the snapshots
+	ConfigurationSnapshots int
+
 	DisableCatchAll bool
 
 	IngressClassConfiguration *ingressclass.Configuration
 
+	// Sharding defines the subset of the Ingresses owned by the controller
+	Sharding *sharding.Configuration
+
 	ValidationWebhook         string
 	ValidationWebhookCertPath string
 	ValidationWebhookKeyPath  string
 	DisableFullValidationTest bool
+	// AdmissionDiffWarnings adds warnings summarizing the changes of the
+	// configuration to the successful validations
+	AdmissionDiffWarnings bool
+	// AdmissionValidation defines how the admission controller tests the
+	// candidate configurations, nil tests them with nginx -t
+	AdmissionValidation *AdmissionValidationConfiguration
+	// AdmissionCacheSize is the maximum number of results of the admission
+	// controller cached for the current configuration, 0 disables the cache
+	AdmissionCacheSize int
 
 	GlobalExternalAuth  *ngx_config.GlobalExternalAuth
 	MaxmindEditionFiles *[]string
@@ -132,6 +239,14 @@ type Configuration struct {
 	PostShutdownGracePeriod int
 	ShutdownGracePeriod     int
 
+	// DrainGracePeriodHTTP, DrainGracePeriodWebSocket and DrainGracePeriodGRPC
+	// are the seconds the requests in flight of every protocol are served on
+	// shutdown, after the new requests are rejected. Zero disables draining
+	// the protocol.
+	DrainGracePeriodHTTP      int
+	DrainGracePeriodWebSocket int
+	DrainGracePeriodGRPC      int
+
 	InternalLoggerAddress string
 	IsChroot              bool
 	DeepInspector         bool
@@ -141,6 +256,19 @@ type Configuration struct {
 	DisableSyncEvents bool
 
 	EnableTopologyAwareRouting bool
+	// Minimum number of ready endpoints in the zone of the controller to keep the traffic in the zone
+	TopologyAwareRoutingMinZoneEndpoints int
+	// Use the zone of the endpoints when the EndpointSlices have no topology hints
+	TopologyAwareRoutingUseEndpointZone bool
+
+	EnableCanaryRollout bool
+	MetricsGatherer     prometheus.Gatherer
+
+	// EnableACME enables obtaining the certificates of the Ingresses with the
+	// acme annotation from the ACME server
+	EnableACME       bool
+	ACMEDirectoryURL string
+	ACMEEmail        string
 }
 
 func getIngressPodZone(svc *apiv1.Service) string {
@@ -176,19 +304,66 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		return nil
 	}
 
+	n.syncLock.Lock()
+	defer n.syncLock.Unlock()
+
+	n.metricCollector.SetReloadQueueDepth(n.syncQueue.Len())
+
+	if n.rolledBack != "" {
+		klog.Warningf("Configuration rolled back to snapshot %v, skipping sync until it is resumed", n.rolledBack)
+		return nil
+	}
+
+	buildStart := time.Now()
 	ings := n.store.ListIngresses()
+
+	var gateways *gatewayTranslation
+	if n.cfg.EnableGatewayAPI {
+		gateways = n.translateGateways()
+		ings = append(ings, gateways.ingresses...)
+	}
+
+	ings = n.excludeInvalidIngresses(ings)
 	hosts, servers, pcfg := n.getConfiguration(ings)
+	if gateways != nil && len(gateways.streamServices) > 0 {
+		pcfg.TCPEndpoints = append(pcfg.TCPEndpoints, gateways.streamServices...)
+		sort.SliceStable(pcfg.TCPEndpoints, func(i, j int) bool {
+			return pcfg.TCPEndpoints[i].Port < pcfg.TCPEndpoints[j].Port
+		})
+	}
+	if gateways != nil && len(gateways.passthroughBackends) > 0 {
+		pcfg.PassthroughBackends = append(pcfg.PassthroughBackends, gatewayPassthroughBackends(hosts, gateways.passthroughBackends)...)
+	}
+	n.metricCollector.ObserveConfigBuildDuration(time.Since(buildStart).Seconds())
+
+	if n.cfg.EnableStreamRoutes && n.isLeader.Load() {
+		n.syncStreamRouteStatus()
+	}
+	if gateways != nil && n.isLeader.Load() {
+		n.syncGatewayStatus(gateways)
+	}
+	if n.isLeader.Load() {
+		if err := n.store.SyncHostOwnership(); err != nil {
+			klog.Warningf("Error storing the claims of the hosts: %v", err)
+		}
+	}
 
 	n.metricCollector.SetSSLExpireTime(servers)
+	n.metricCollector.SetSSLExpireDays(servers)
 	n.metricCollector.SetSSLInfo(servers)
 
+	backendConfig := n.store.GetBackendConfiguration()
+	n.metricCollector.SetLabelLimits(backendConfig.MetricsDropLabels, backendConfig.MetricsLimitLabels, backendConfig.MetricsMaxLabelValues)
+
 	if n.runningConfig.Equal(pcfg) {
 		klog.V(3).Infof("No configuration change detected, skipping backend reload")
 		return nil
 	}
 
 	n.metricCollector.SetHosts(hosts)
+	n.metricCollector.SetEndpoints(backendEndpoints(pcfg.Backends))
 
+	reloaded := false
 	if !utilingress.IsDynamicConfigurationEnough(pcfg, n.runningConfig) {
 		klog.InfoS("Configuration changes detected, backend reload required")
 
@@ -201,20 +376,32 @@ func (n *NGINXController) syncIngress(interface{}) error {
 
 		pcfg.ConfigurationChecksum = fmt.Sprintf("%v", hash)
 
-		err = n.OnUpdate(*pcfg)
+		reloadStart := time.Now()
+		reloaded, err = n.OnUpdate(*pcfg)
 		if err != nil {
 			n.metricCollector.IncReloadErrorCount()
+			n.metricCollector.IncReloadFailureCount(reloadFailureReason(err))
 			n.metricCollector.ConfigSuccess(hash, false)
 			klog.Errorf("Unexpected failure reloading the backend:\n%v", err)
 			n.recorder.Eventf(k8s.IngressPodDetails, apiv1.EventTypeWarning, "RELOAD", fmt.Sprintf("Error reloading NGINX: %v", err))
+
+			if testErr, ok := err.(*testError); ok {
+				n.reportInvalidIngress(testErr, pcfg.Servers)
+			}
 			return err
 		}
 
-		klog.InfoS("Backend successfully reloaded")
-		n.metricCollector.ConfigSuccess(hash, true)
-		n.metricCollector.IncReloadCount()
+		if reloaded {
+			klog.InfoS("Backend successfully reloaded")
+			n.metricCollector.ObserveReloadDuration(time.Since(reloadStart).Seconds())
+			n.metricCollector.ConfigSuccess(hash, true)
+			n.metricCollector.IncReloadCount()
 
-		n.recorder.Eventf(k8s.IngressPodDetails, apiv1.EventTypeNormal, "RELOAD", "NGINX reload triggered due to a change in configuration")
+			n.recorder.Eventf(k8s.IngressPodDetails, apiv1.EventTypeNormal, "RELOAD", "NGINX reload triggered due to a change in configuration")
+		} else {
+			klog.InfoS("Rendered configuration did not change, backend reload skipped")
+			n.metricCollector.IncReloadSkippedCount()
+		}
 	}
 
 	isFirstSync := n.runningConfig.Equal(&ingress.Configuration{})
@@ -258,6 +445,10 @@ func (n *NGINXController) syncIngress(interface{}) error {
 
 	n.runningConfig = pcfg
 
+	if reloaded && n.snapshots != nil {
+		n.saveSnapshot(pcfg)
+	}
+
 	return nil
 }
 
@@ -308,40 +499,56 @@ func (n *NGINXController) CheckWarning(ing *networking.Ingress) ([]string, error
 }
 
 // CheckIngress returns an error in case the provided ingress, when added
-// to the current configuration, generates an invalid configuration
-func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
+// to the current configuration, generates an invalid configuration, and
+// warnings summarizing the changes of the configuration when enabled
+func (n *NGINXController) CheckIngress(ing *networking.Ingress) ([]string, error) {
+	if ing == nil || n.admissionCache == nil {
+		return n.checkIngress(ing)
+	}
+
+	return n.admissionCache.check(ing, func() ([]string, error) {
+		return n.checkIngress(ing)
+	})
+}
+
+func (n *NGINXController) checkIngress(ing *networking.Ingress) ([]string, error) {
 	startCheck := time.Now().UnixNano() / 1000000
 
 	if ing == nil {
 		// no ingress to add, no state change
-		return nil
+		return nil, nil
 	}
 
 	// Skip checks if the ingress is marked as deleted
 	if !ing.DeletionTimestamp.IsZero() {
-		return nil
+		return nil, nil
 	}
 
 	if n.cfg.DeepInspector {
 		if err := inspector.DeepInspect(ing); err != nil {
-			return fmt.Errorf("invalid object: %w", err)
+			return nil, fmt.Errorf("invalid object: %w", err)
 		}
 	}
 
 	// Do not attempt to validate an ingress that's not meant to be controlled by the current instance of the controller.
 	if ingressClass, err := n.store.GetIngressClass(ing, n.cfg.IngressClassConfiguration); ingressClass == "" {
 		klog.Warningf("ignoring ingress %v in %v based on annotation %v: %v", ing.Name, ing.ObjectMeta.Namespace, ingressClass, err)
-		return nil
+		return nil, nil
 	}
 
 	if n.cfg.Namespace != "" && ing.ObjectMeta.Namespace != n.cfg.Namespace {
 		klog.Warningf("ignoring ingress %v in namespace %v different from the namespace watched %s", ing.Name, ing.ObjectMeta.Namespace, n.cfg.Namespace)
-		return nil
+		return nil, nil
 	}
 
 	if n.cfg.DisableCatchAll && ing.Spec.DefaultBackend != nil {
-		return fmt.Errorf("this deployment is trying to create a catch-all ingress while DisableCatchAll flag is set to true. Remove '.spec.defaultBackend' or set DisableCatchAll flag to false")
+		return nil, fmt.Errorf("this deployment is trying to create a catch-all ingress while DisableCatchAll flag is set to true. Remove '.spec.defaultBackend' or set DisableCatchAll flag to false")
+	}
+
+	if err := n.store.CheckHostOwnership(ing); err != nil {
+		return nil, err
 	}
+
 	startRender := time.Now().UnixNano() / 1000000
 	cfg := n.store.GetBackendConfiguration()
 	cfg.Resolver = n.resolver
@@ -349,10 +556,16 @@ func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
 	// Adds the pathType Validation
 	if cfg.StrictValidatePathType {
 		if err := inspector.ValidatePathType(ing); err != nil {
-			return fmt.Errorf("ingress contains invalid paths: %w", err)
+			return nil, fmt.Errorf("ingress contains invalid paths: %w", err)
 		}
 	}
 
+	parsed, err := n.store.ExtractAnnotations(ing)
+	if err != nil {
+		n.metricCollector.IncCheckErrorCount(ing.ObjectMeta.Namespace, ing.Name)
+		return nil, err
+	}
+
 	var arrayBadWords []string
 
 	if cfg.AnnotationValueWordBlocklist != "" {
@@ -362,24 +575,24 @@ func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
 	for key, value := range ing.ObjectMeta.GetAnnotations() {
 		if parser.AnnotationsPrefix != parser.DefaultAnnotationsPrefix {
 			if strings.HasPrefix(key, fmt.Sprintf("%s/", parser.DefaultAnnotationsPrefix)) {
-				return fmt.Errorf("this deployment has a custom annotation prefix defined. Use '%s' instead of '%s'", parser.AnnotationsPrefix, parser.DefaultAnnotationsPrefix)
+				return nil, fmt.Errorf("this deployment has a custom annotation prefix defined. Use '%s' instead of '%s'", parser.AnnotationsPrefix, parser.DefaultAnnotationsPrefix)
 			}
 		}
 
 		if strings.HasPrefix(key, fmt.Sprintf("%s/", parser.AnnotationsPrefix)) && len(arrayBadWords) != 0 {
 			for _, forbiddenvalue := range arrayBadWords {
 				if strings.Contains(value, strings.TrimSpace(forbiddenvalue)) {
-					return fmt.Errorf("%s annotation contains invalid word %s", key, forbiddenvalue)
+					return nil, fmt.Errorf("%s annotation contains invalid word %s", key, forbiddenvalue)
 				}
 			}
 		}
 
-		if !cfg.AllowSnippetAnnotations && strings.HasSuffix(key, "-snippet") {
-			return fmt.Errorf("%s annotation cannot be used. Snippet directives are disabled by the Ingress administrator", key)
+		if !n.allowSnippetAnnotations(parsed) && strings.HasSuffix(key, "-snippet") {
+			return nil, fmt.Errorf("%s annotation cannot be used. Snippet directives are disabled by the Ingress administrator", key)
 		}
 
 		if cfg.GlobalRateLimitMemcachedHost == "" && strings.HasPrefix(key, fmt.Sprintf("%s/%s", parser.AnnotationsPrefix, "global-rate-limit")) {
-			return fmt.Errorf("'global-rate-limit*' annotations require 'global-rate-limit-memcached-host' settings configured in the global configmap")
+			return nil, fmt.Errorf("'global-rate-limit*' annotations require 'global-rate-limit-memcached-host' settings configured in the global configmap")
 		}
 	}
 
@@ -392,11 +605,6 @@ func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
 			toCheck.ObjectMeta.Name == ing.ObjectMeta.Name
 	}
 	ings := store.FilterIngresses(allIngresses, filter)
-	parsed, err := annotations.NewAnnotationExtractor(n.store).Extract(ing)
-	if err != nil {
-		n.metricCollector.IncCheckErrorCount(ing.ObjectMeta.Namespace, ing.Name)
-		return err
-	}
 	ings = append(ings, &ingress.Ingress{
 		Ingress:           *ing,
 		ParsedAnnotations: parsed,
@@ -407,7 +615,7 @@ func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
 	err = checkOverlap(ing, servers)
 	if err != nil {
 		n.metricCollector.IncCheckErrorCount(ing.ObjectMeta.Namespace, ing.Name)
-		return err
+		return nil, err
 	}
 	testedSize := len(ings)
 	if n.cfg.DisableFullValidationTest {
@@ -418,14 +626,30 @@ func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
 	content, err := n.generateTemplate(cfg, *pcfg)
 	if err != nil {
 		n.metricCollector.IncCheckErrorCount(ing.ObjectMeta.Namespace, ing.Name)
-		return err
+		return nil, err
 	}
 
-	err = n.testTemplate(content)
+	n.generatedFilesLock.Lock()
+	err = n.writeGeneratedFiles(pcfg)
+	if err == nil {
+		err = n.validateTemplate(content)
+	}
+	n.generatedFilesLock.Unlock()
 	if err != nil {
 		n.metricCollector.IncCheckErrorCount(ing.ObjectMeta.Namespace, ing.Name)
-		return err
+		return nil, err
+	}
+	var warnings []string
+	if n.cfg.AdmissionDiffWarnings && !n.cfg.DisableFullValidationTest {
+		var runningIng *networking.Ingress
+		for _, current := range allIngresses {
+			if filter(current) {
+				runningIng = &current.Ingress
+			}
+		}
+		warnings = n.configurationDiff(content, ing, runningIng)
 	}
+
 	n.metricCollector.IncCheckCount(ing.ObjectMeta.Namespace, ing.Name)
 	endCheck := time.Now().UnixNano() / 1000000
 	n.metricCollector.SetAdmissionMetrics(
@@ -436,10 +660,82 @@ func (n *NGINXController) CheckIngress(ing *networking.Ingress) error {
 		float64(len(content)),
 		float64(endCheck-startCheck)/1000,
 	)
-	return nil
+	return warnings, nil
 }
 
+// getStreamServices returns the TCP or UDP services defined in the ConfigMap
+// and, when enabled, by the StreamRoutes.
 func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Protocol) []ingress.L4Service {
+	svcs := n.getConfigMapStreamServices(configmapName, proto)
+	if n.cfg.EnableStreamRoutes {
+		svcs = append(svcs, n.getStreamRouteServices(proto)...)
+	}
+
+	// Keep upstream order sorted to reduce unnecessary nginx config reloads.
+	sort.SliceStable(svcs, func(i, j int) bool {
+		return svcs[i].Port < svcs[j].Port
+	})
+	return svcs
+}
+
+// reservedStreamPorts returns the ports used by the ingress controller that
+// cannot be used by stream services.
+func (n *NGINXController) reservedStreamPorts() sets.Int {
+	return sets.NewInt(
+		n.cfg.ListenPorts.HTTP,
+		n.cfg.ListenPorts.HTTPS,
+		n.cfg.ListenPorts.SSLProxy,
+		n.cfg.ListenPorts.Health,
+		n.cfg.ListenPorts.Default,
+		nginx.ProfilerPort,
+		nginx.StatusPort,
+		nginx.StreamPort,
+	)
+}
+
+// getStreamServiceEndpoints returns the endpoints of the port of a Service
+// used by a stream service. The port can be a number or a name.
+func (n *NGINXController) getStreamServiceEndpoints(svc *apiv1.Service, svcPort string, proto apiv1.Protocol) []ingress.Endpoint {
+	var zone string
+	if n.cfg.EnableTopologyAwareRouting {
+		zone = getIngressPodZone(svc)
+	} else {
+		zone = emptyZone
+	}
+
+	nsName := k8s.MetaNamespaceKey(svc)
+	/* #nosec */
+	targetPort, err := strconv.Atoi(svcPort) // #nosec
+	if err != nil {
+		// not a port number, fall back to using port name
+		klog.V(3).Infof("Searching Endpoints with %v port name %q for Service %q", proto, svcPort, nsName)
+		for i := range svc.Spec.Ports {
+			sp := svc.Spec.Ports[i]
+			if sp.Name == svcPort {
+				if sp.Protocol == proto {
+					return getEndpointsFromSlices(svc, &sp, proto, zone, n.cfg.TopologyAwareRoutingMinZoneEndpoints, n.cfg.TopologyAwareRoutingUseEndpointZone, n.store.GetServiceEndpointsSlices)
+				}
+			}
+		}
+		return nil
+	}
+
+	klog.V(3).Infof("Searching Endpoints with %v port number %d for Service %q", proto, targetPort, nsName)
+	for i := range svc.Spec.Ports {
+		sp := svc.Spec.Ports[i]
+		//nolint:gosec // Ignore G109 error
+		if sp.Port == int32(targetPort) {
+			if sp.Protocol == proto {
+				return getEndpointsFromSlices(svc, &sp, proto, zone, n.cfg.TopologyAwareRoutingMinZoneEndpoints, n.cfg.TopologyAwareRoutingUseEndpointZone, n.store.GetServiceEndpointsSlices)
+			}
+		}
+	}
+	return nil
+}
+
+// getConfigMapStreamServices returns the TCP or UDP services defined in the
+// ConfigMap.
+func (n *NGINXController) getConfigMapStreamServices(configmapName string, proto apiv1.Protocol) []ingress.L4Service {
 	if configmapName == "" {
 		return []ingress.L4Service{}
 	}
@@ -458,18 +754,7 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 	svcs := make([]ingress.L4Service, 0, len(configmap.Data))
 	var svcProxyProtocol ingress.ProxyProtocol
 
-	rp := []int{
-		n.cfg.ListenPorts.HTTP,
-		n.cfg.ListenPorts.HTTPS,
-		n.cfg.ListenPorts.SSLProxy,
-		n.cfg.ListenPorts.Health,
-		n.cfg.ListenPorts.Default,
-		nginx.ProfilerPort,
-		nginx.StatusPort,
-		nginx.StreamPort,
-	}
-
-	reservedPorts := sets.NewInt(rp...)
+	reservedPorts := n.reservedStreamPorts()
 	// svcRef format: <(str)namespace>/<(str)service>:<(intstr)port>[:<("PROXY")decode>:<("PROXY")encode>]
 	for port, svcRef := range configmap.Data {
 		externalPort, err := strconv.Atoi(port) // #nosec
@@ -509,64 +794,32 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 			klog.Warningf("Error getting Service %q: %v", nsName, err)
 			continue
 		}
-		var endps []ingress.Endpoint
-		/* #nosec */
-		targetPort, err := strconv.Atoi(svcPort) // #nosec
-		var zone string
-		if n.cfg.EnableTopologyAwareRouting {
-			zone = getIngressPodZone(svc)
-		} else {
-			zone = emptyZone
-		}
-
-		if err != nil {
-			// not a port number, fall back to using port name
-			klog.V(3).Infof("Searching Endpoints with %v port name %q for Service %q", proto, svcPort, nsName)
-			for i := range svc.Spec.Ports {
-				sp := svc.Spec.Ports[i]
-				if sp.Name == svcPort {
-					if sp.Protocol == proto {
-						endps = getEndpointsFromSlices(svc, &sp, proto, zone, n.store.GetServiceEndpointsSlices)
-						break
-					}
-				}
-			}
-		} else {
-			klog.V(3).Infof("Searching Endpoints with %v port number %d for Service %q", proto, targetPort, nsName)
-			for i := range svc.Spec.Ports {
-				sp := svc.Spec.Ports[i]
-				//nolint:gosec // Ignore G109 error
-				if sp.Port == int32(targetPort) {
-					if sp.Protocol == proto {
-						endps = getEndpointsFromSlices(svc, &sp, proto, zone, n.store.GetServiceEndpointsSlices)
-						break
-					}
-				}
-			}
-		}
-		// stream services cannot contain empty upstreams and there is
-		// no default backend equivalent
+		endps := n.getStreamServiceEndpoints(svc, svcPort, proto)
+		// there is no default backend equivalent, the connections are closed
+		// until the Service has Endpoints again. The stream service is kept so
+		// the Endpoints are updated without reloading NGINX.
 		if len(endps) == 0 {
 			klog.Warningf("Service %q does not have any active Endpoint for %v port %v", nsName, proto, svcPort)
-			continue
+		}
+		// the session affinity of the Service is honored, like kube-proxy
+		var sessionAffinity string
+		if svc.Spec.SessionAffinity == apiv1.ServiceAffinityClientIP {
+			sessionAffinity = string(v1alpha1.StreamRouteSessionAffinityClientIP)
 		}
 		svcs = append(svcs, ingress.L4Service{
 			Port: externalPort,
 			Backend: ingress.L4Backend{
-				Name:          svcName,
-				Namespace:     svcNs,
-				Port:          intstr.FromString(svcPort),
-				Protocol:      proto,
-				ProxyProtocol: svcProxyProtocol,
+				Name:            svcName,
+				Namespace:       svcNs,
+				Port:            intstr.FromString(svcPort),
+				Protocol:        proto,
+				ProxyProtocol:   svcProxyProtocol,
+				SessionAffinity: sessionAffinity,
 			},
 			Endpoints: endps,
 			Service:   svc,
 		})
 	}
-	// Keep upstream order sorted to reduce unnecessary nginx config reloads.
-	sort.SliceStable(svcs, func(i, j int) bool {
-		return svcs[i].Port < svcs[j].Port
-	})
 	return svcs
 }
 
@@ -595,7 +848,7 @@ func (n *NGINXController) getDefaultUpstream() *ingress.Backend {
 	} else {
 		zone = emptyZone
 	}
-	endps := getEndpointsFromSlices(svc, &svc.Spec.Ports[0], apiv1.ProtocolTCP, zone, n.store.GetServiceEndpointsSlices)
+	endps := getEndpointsFromSlices(svc, &svc.Spec.Ports[0], apiv1.ProtocolTCP, zone, n.cfg.TopologyAwareRoutingMinZoneEndpoints, n.cfg.TopologyAwareRoutingUseEndpointZone, n.store.GetServiceEndpointsSlices)
 	if len(endps) == 0 {
 		klog.Warningf("Service %q does not have any active Endpoint", svcKey)
 		endps = []ingress.Endpoint{n.DefaultEndpoint()}
@@ -664,8 +917,12 @@ func (n *NGINXController) getConfiguration(ingresses []*ingress.Ingress) (sets.S
 		UDPEndpoints:          n.getStreamServices(n.cfg.UDPConfigMapName, apiv1.ProtocolUDP),
 		PassthroughBackends:   passUpstreams,
 		BackendConfigChecksum: n.store.GetBackendConfiguration().Checksum,
+		DynamicConfigChecksum: n.store.GetBackendConfiguration().DynamicChecksum,
 		DefaultSSLCertificate: n.getDefaultSSLCertificate(),
+		SSLRejectHandshake:    n.getSSLRejectHandshake(),
 		StreamSnippets:        n.getStreamSnippets(ingresses),
+		Denylist:              n.getDenylist(),
+		LuaSecrets:            n.getLuaSecrets(),
 	}
 }
 
@@ -713,7 +970,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 		ingKey := k8s.MetaNamespaceKey(ing)
 		anns := ing.ParsedAnnotations
 
-		if !n.store.GetBackendConfiguration().AllowSnippetAnnotations {
+		if !n.allowSnippetAnnotations(anns) {
 			dropSnippetDirectives(anns, ingKey)
 		}
 
@@ -768,16 +1025,20 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 			}
 
 			for _, path := range rule.HTTP.Paths {
-				if path.Backend.Service == nil {
+				var ups *ingress.Backend
+				switch {
+				case path.Backend.Service != nil:
+					ups = upstreams[upstreamName(ing.Namespace, path.Backend.Service)]
+				case anns.Redirect.URL != "":
+					// the redirects without backend, like the RequestRedirect
+					// filters of the HTTPRoutes, never proxy the requests
+					ups = upstreams[defUpstreamName]
+				default:
 					// skip non-service backends
 					klog.V(3).Infof("Ingress %q and path %q does not contain a service backend, using default backend", ingKey, path.Path)
 					continue
 				}
 
-				upsName := upstreamName(ing.Namespace, path.Backend.Service)
-
-				ups := upstreams[upsName]
-
 				// Backend is not referenced to by a server
 				if ups.NoServer {
 					continue
@@ -787,6 +1048,9 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 				if path.Path != "" {
 					nginxPath = path.Path
 				}
+				if isLiteralCaseInsensitivePath(path.PathType, anns.Rewrite) {
+					nginxPath = strings.ToLower(nginxPath)
+				}
 
 				addLoc := true
 				for _, loc := range server.Locations {
@@ -886,6 +1150,10 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 						}
 					}
 				}
+
+				if anns.SessionAffinity.Mode == "header" {
+					ups.SessionAffinity.HeaderSessionAffinity.Name = anns.SessionAffinity.HeaderName
+				}
 			}
 		}
 
@@ -930,7 +1198,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 				} else {
 					zone = emptyZone
 				}
-				endps := getEndpointsFromSlices(location.DefaultBackend, &sp, apiv1.ProtocolTCP, zone, n.store.GetServiceEndpointsSlices)
+				endps := getEndpointsFromSlices(location.DefaultBackend, &sp, apiv1.ProtocolTCP, zone, n.cfg.TopologyAwareRoutingMinZoneEndpoints, n.cfg.TopologyAwareRoutingUseEndpointZone, n.store.GetServiceEndpointsSlices)
 				// custom backend is valid only if contains at least one endpoint
 				if len(endps) > 0 {
 					name := fmt.Sprintf("custom-default-backend-%v-%v", location.DefaultBackend.GetNamespace(), location.DefaultBackend.GetName())
@@ -1000,7 +1268,7 @@ func (n *NGINXController) createUpstreams(data []*ingress.Ingress, du *ingress.B
 		ingKey := k8s.MetaNamespaceKey(ing)
 		anns := ing.ParsedAnnotations
 
-		if !n.store.GetBackendConfiguration().AllowSnippetAnnotations {
+		if !n.allowSnippetAnnotations(anns) {
 			dropSnippetDirectives(anns, ingKey)
 		}
 
@@ -1014,11 +1282,15 @@ func (n *NGINXController) createUpstreams(data []*ingress.Ingress, du *ingress.B
 			upstreams[defBackend].UpstreamHashBy.UpstreamHashBy = anns.UpstreamHashBy.UpstreamHashBy
 			upstreams[defBackend].UpstreamHashBy.UpstreamHashBySubset = anns.UpstreamHashBy.UpstreamHashBySubset
 			upstreams[defBackend].UpstreamHashBy.UpstreamHashBySubsetSize = anns.UpstreamHashBy.UpstreamHashBySubsetSize
+			upstreams[defBackend].UpstreamHashBy.UpstreamHashByBoundedLoadFactor = anns.UpstreamHashBy.UpstreamHashByBoundedLoadFactor
 
 			upstreams[defBackend].LoadBalancing = anns.LoadBalancing
 			if upstreams[defBackend].LoadBalancing == "" {
 				upstreams[defBackend].LoadBalancing = n.store.GetBackendConfiguration().LoadBalancing
 			}
+			upstreams[defBackend].SlowStartSeconds = anns.SlowStartSeconds
+			upstreams[defBackend].OutlierDetection = anns.OutlierDetection
+			upstreams[defBackend].HealthCheck = anns.HealthCheck
 
 			svcKey := fmt.Sprintf("%v/%v", ing.Namespace, ing.Spec.DefaultBackend.Service.Name)
 
@@ -1079,11 +1351,15 @@ func (n *NGINXController) createUpstreams(data []*ingress.Ingress, du *ingress.B
 				upstreams[name].UpstreamHashBy.UpstreamHashBy = anns.UpstreamHashBy.UpstreamHashBy
 				upstreams[name].UpstreamHashBy.UpstreamHashBySubset = anns.UpstreamHashBy.UpstreamHashBySubset
 				upstreams[name].UpstreamHashBy.UpstreamHashBySubsetSize = anns.UpstreamHashBy.UpstreamHashBySubsetSize
+				upstreams[name].UpstreamHashBy.UpstreamHashByBoundedLoadFactor = anns.UpstreamHashBy.UpstreamHashByBoundedLoadFactor
 
 				upstreams[name].LoadBalancing = anns.LoadBalancing
 				if upstreams[name].LoadBalancing == "" {
 					upstreams[name].LoadBalancing = n.store.GetBackendConfiguration().LoadBalancing
 				}
+				upstreams[name].SlowStartSeconds = anns.SlowStartSeconds
+				upstreams[name].OutlierDetection = anns.OutlierDetection
+				upstreams[name].HealthCheck = anns.HealthCheck
 
 				svcKey := fmt.Sprintf("%v/%v", ing.Namespace, svcName)
 
@@ -1195,7 +1471,7 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 			return upstreams, nil
 		}
 		servicePort := externalNamePorts(backendPort, svc)
-		endps := getEndpointsFromSlices(svc, servicePort, apiv1.ProtocolTCP, zone, n.store.GetServiceEndpointsSlices)
+		endps := getEndpointsFromSlices(svc, servicePort, apiv1.ProtocolTCP, zone, n.cfg.TopologyAwareRoutingMinZoneEndpoints, n.cfg.TopologyAwareRoutingUseEndpointZone, n.store.GetServiceEndpointsSlices)
 		if len(endps) == 0 {
 			klog.Warningf("Service %q does not have any active Endpoint.", svcKey)
 			return upstreams, nil
@@ -1211,7 +1487,7 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 		if strconv.Itoa(int(servicePort.Port)) == backendPort ||
 			servicePort.TargetPort.String() == backendPort ||
 			servicePort.Name == backendPort {
-			endps := getEndpointsFromSlices(svc, &servicePort, apiv1.ProtocolTCP, zone, n.store.GetServiceEndpointsSlices)
+			endps := getEndpointsFromSlices(svc, &servicePort, apiv1.ProtocolTCP, zone, n.cfg.TopologyAwareRoutingMinZoneEndpoints, n.cfg.TopologyAwareRoutingUseEndpointZone, n.store.GetServiceEndpointsSlices)
 			if len(endps) == 0 {
 				klog.Warningf("Service %q does not have any active Endpoint.", svcKey)
 			}
@@ -1224,6 +1500,33 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 	return upstreams, nil
 }
 
+// allowSnippetAnnotations returns if the snippet annotations of the Ingress
+// are allowed. The configuration of its IngressClass takes precedence over
+// the configmap setting.
+func (n *NGINXController) allowSnippetAnnotations(anns *annotations.Ingress) bool {
+	if anns.ClassAllowSnippetAnnotations != nil {
+		return *anns.ClassAllowSnippetAnnotations
+	}
+
+	return n.store.GetBackendConfiguration().AllowSnippetAnnotations
+}
+
+// getIngressDefaultSSLCertificate returns the certificate of the hosts of
+// the TLS section of the Ingress without a valid certificate, the default
+// certificate of the parameters of its IngressClass when set
+func (n *NGINXController) getIngressDefaultSSLCertificate(anns *annotations.Ingress) *ingress.SSLCert {
+	if anns.ClassDefaultSSLCertificate != "" {
+		certificate, err := n.store.GetLocalSSLCert(anns.ClassDefaultSSLCertificate)
+		if err == nil {
+			return certificate
+		}
+
+		klog.Warningf("Error loading the default certificate of the IngressClass, falling back to the default certificate:\n%v", err)
+	}
+
+	return n.getDefaultSSLCertificate()
+}
+
 func (n *NGINXController) getDefaultSSLCertificate() *ingress.SSLCert {
 	// read custom default SSL certificate, fall back to generated default certificate
 	if n.cfg.DefaultSSLCertificate != "" {
@@ -1238,6 +1541,45 @@ func (n *NGINXController) getDefaultSSLCertificate() *ingress.SSLCert {
 	return n.cfg.FakeCertificate
 }
 
+// getSSLRejectHandshake returns if the default server rejects the TLS handshakes
+// for unknown server names. The ssl-reject-handshake annotation of the
+// IngressClasses of the controller takes precedence over the configmap
+// setting, unless they disagree: the default server is shared by the classes.
+func (n *NGINXController) getSSLRejectHandshake() bool {
+	enabled := n.store.GetBackendConfiguration().SSLRejectHandshake
+
+	icConfig := n.cfg.IngressClassConfiguration
+	if icConfig == nil || icConfig.IgnoreIngressClass {
+		return enabled
+	}
+
+	var classValue *bool
+	for _, ic := range n.store.ListIngressClasses() {
+		value, ok := ic.Annotations[parser.GetAnnotationWithPrefix(rejectHandshakeAnnotation)]
+		if !ok {
+			continue
+		}
+
+		rejectHandshake, err := strconv.ParseBool(value)
+		if err != nil {
+			klog.Warningf("Error parsing annotation %q of IngressClass %q: %v", rejectHandshakeAnnotation, ic.Name, err)
+			continue
+		}
+
+		if classValue != nil && *classValue != rejectHandshake {
+			klog.Warningf("The IngressClasses of the controller set different values of annotation %q, using the configmap setting", rejectHandshakeAnnotation)
+			return enabled
+		}
+		classValue = &rejectHandshake
+	}
+
+	if classValue == nil {
+		return enabled
+	}
+
+	return *classValue
+}
+
 // createServers builds a map of host name to Server structs from a map of
 // already computed Upstream structs. Each Server is configured with at least
 // one root location, which uses a default backend if left unspecified.
@@ -1285,6 +1627,8 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 					Access:  n.store.GetBackendConfiguration().EnableAccessLogForDefaultBackend,
 					Rewrite: false,
 				},
+				SecurityHeaders:         securityheaders.NewConfig(bdef.SecurityHeaders),
+				StrictRequestValidation: bdef.StrictRequestValidation,
 			},
 		},
 	}
@@ -1294,7 +1638,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 		ingKey := k8s.MetaNamespaceKey(ing)
 		anns := ing.ParsedAnnotations
 
-		if !n.store.GetBackendConfiguration().AllowSnippetAnnotations {
+		if !n.allowSnippetAnnotations(anns) {
 			dropSnippetDirectives(anns, ingKey)
 		}
 
@@ -1363,6 +1707,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 				SSLPassthrough:         anns.SSLPassthrough,
 				SSLCiphers:             anns.SSLCipher.SSLCiphers,
 				SSLPreferServerCiphers: anns.SSLCipher.SSLPreferServerCiphers,
+				ErrorLogLevel:          anns.Logs.ErrorLogLevel,
 			}
 		}
 	}
@@ -1372,7 +1717,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 		ingKey := k8s.MetaNamespaceKey(ing)
 		anns := ing.ParsedAnnotations
 
-		if !n.store.GetBackendConfiguration().AllowSnippetAnnotations {
+		if !n.allowSnippetAnnotations(anns) {
 			dropSnippetDirectives(anns, ingKey)
 		}
 
@@ -1396,10 +1741,10 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 				klog.Warningf("Aliases already configured for server %q, skipping (Ingress %q)", host, ingKey)
 			}
 
-			if anns.ServerSnippet != "" {
+			if serverSnippet := joinSnippets(anns.ClassServerSnippet, anns.ServerSnippet); serverSnippet != "" {
 				if servers[host].ServerSnippet == "" {
-					servers[host].ServerSnippet = anns.ServerSnippet
-				} else {
+					servers[host].ServerSnippet = serverSnippet
+				} else if servers[host].ServerSnippet != serverSnippet {
 					klog.Warningf("Server snippet already configured for server %q, skipping (Ingress %q)",
 						host, ingKey)
 				}
@@ -1415,6 +1760,20 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 				servers[host].SSLPreferServerCiphers = anns.SSLCipher.SSLPreferServerCiphers
 			}
 
+			// only set the error log level if the server does not have it previously configured
+			if servers[host].ErrorLogLevel == "" && anns.Logs.ErrorLogLevel != "" {
+				servers[host].ErrorLogLevel = anns.Logs.ErrorLogLevel
+			} else if anns.Logs.ErrorLogLevel != "" && servers[host].ErrorLogLevel != anns.Logs.ErrorLogLevel {
+				klog.Warningf("Error log level already configured for server %q, skipping (Ingress %q)", host, ingKey)
+			}
+
+			// only add the redirect map if the server does not have one previously configured
+			if servers[host].RedirectMap.Name == "" && anns.RedirectMap.Name != "" {
+				servers[host].RedirectMap = anns.RedirectMap
+			} else if anns.RedirectMap.Name != "" && servers[host].RedirectMap.Name != anns.RedirectMap.Name {
+				klog.Warningf("Redirect map already configured for server %q, skipping (Ingress %q)", host, ingKey)
+			}
+
 			// only add a certificate if the server does not have one previously configured
 			if servers[host].SSLCert != nil {
 				continue
@@ -1428,7 +1787,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 			tlsSecretName := extractTLSSecretName(host, ing, n.store.GetLocalSSLCert)
 			if tlsSecretName == "" {
 				klog.V(3).Infof("Host %q is listed in the TLS section but secretName is empty. Using default certificate", host)
-				servers[host].SSLCert = n.getDefaultSSLCertificate()
+				servers[host].SSLCert = n.getIngressDefaultSSLCertificate(anns)
 				continue
 			}
 
@@ -1436,14 +1795,14 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 			cert, err := n.store.GetLocalSSLCert(secrKey)
 			if err != nil {
 				klog.Warningf("Error getting SSL certificate %q: %v. Using default certificate", secrKey, err)
-				servers[host].SSLCert = n.getDefaultSSLCertificate()
+				servers[host].SSLCert = n.getIngressDefaultSSLCertificate(anns)
 				continue
 			}
 
 			if cert.Certificate == nil {
 				klog.Warningf("SSL certificate %q does not contain a valid SSL certificate for server %q", secrKey, host)
 				klog.Warningf("Using default certificate")
-				servers[host].SSLCert = n.getDefaultSSLCertificate()
+				servers[host].SSLCert = n.getIngressDefaultSSLCertificate(anns)
 				continue
 			}
 
@@ -1457,7 +1816,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 				if err != nil {
 					klog.Warningf("SSL certificate %q does not contain a Common Name or Subject Alternative Name for server %q: %v", secrKey, host, err)
 					klog.Warningf("Using default certificate")
-					servers[host].SSLCert = n.getDefaultSSLCertificate()
+					servers[host].SSLCert = n.getIngressDefaultSSLCertificate(anns)
 					continue
 				}
 			}
@@ -1501,15 +1860,28 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 	return servers
 }
 
+// joinSnippets returns the non-empty snippets, one per line
+func joinSnippets(snippets ...string) string {
+	nonEmpty := make([]string, 0, len(snippets))
+	for _, snippet := range snippets {
+		if snippet != "" {
+			nonEmpty = append(nonEmpty, snippet)
+		}
+	}
+
+	return strings.Join(nonEmpty, "\n")
+}
+
 func locationApplyAnnotations(loc *ingress.Location, anns *annotations.Ingress) {
 	loc.BasicDigestAuth = anns.BasicDigestAuth
 	loc.ClientBodyBufferSize = anns.ClientBodyBufferSize
 	loc.CustomHeaders = anns.CustomHeaders
-	loc.ConfigurationSnippet = anns.ConfigurationSnippet
+	loc.ConfigurationSnippet = joinSnippets(anns.ClassLocationSnippet, anns.ConfigurationSnippet)
 	loc.CorsConfig = anns.CorsConfig
 	loc.ExternalAuth = anns.ExternalAuth
 	loc.EnableGlobalAuth = anns.EnableGlobalAuth
 	loc.HTTP2PushPreload = anns.HTTP2PushPreload
+	loc.GRPCWeb = anns.GRPCWeb
 	loc.Opentelemetry = anns.Opentelemetry
 	loc.Proxy = anns.Proxy
 	loc.ProxySSL = anns.ProxySSL
@@ -1527,12 +1899,31 @@ func locationApplyAnnotations(loc *ingress.Location, anns *annotations.Ingress)
 	loc.Logs = anns.Logs
 	loc.DefaultBackend = anns.DefaultBackend
 	loc.BackendProtocol = anns.BackendProtocol
+	if protocol, ok := anns.BackendProtocolPaths[loc.Path]; ok {
+		loc.BackendProtocol = protocol
+	}
 	loc.FastCGI = anns.FastCGI
 	loc.CustomHTTPErrors = anns.CustomHTTPErrors
 	loc.DisableProxyInterceptErrors = anns.DisableProxyInterceptErrors
 	loc.ModSecurity = anns.ModSecurity
 	loc.Satisfy = anns.Satisfy
 	loc.Mirror = anns.Mirror
+	loc.WebSocket = anns.WebSocket
+	loc.BotDetection = anns.BotDetection
+	loc.CSRF = anns.CSRF
+	loc.Normalization = anns.Normalization
+	loc.StrictRequestValidation = anns.StrictRequestValidation
+	loc.SecurityHeaders = anns.SecurityHeaders
+	loc.Plugins = anns.Plugins
+	loc.NJS = anns.NJS
+	loc.ProxyCache = anns.ProxyCache
+	loc.Brotli = anns.Brotli
+	loc.Zstd = anns.Zstd
+	loc.ConditionalRedirects = anns.ConditionalRedirects
+	loc.TrailingSlash = anns.TrailingSlash
+	loc.CustomErrorPages = anns.CustomErrorPages
+	loc.Maintenance = anns.Maintenance
+	loc.GeoAccess = anns.GeoAccess
 
 	loc.DefaultBackendUpstreamName = defUpstreamName
 }
@@ -1883,5 +2274,12 @@ func newTrafficShapingPolicy(cfg *canary.Config) ingress.TrafficShapingPolicy {
 		HeaderValue:   cfg.HeaderValue,
 		HeaderPattern: cfg.HeaderPattern,
 		Cookie:        cfg.Cookie,
+		Query:         cfg.Query,
+		QueryValue:    cfg.QueryValue,
+		Sticky:        cfg.Sticky,
+		StickyCookie:  cfg.StickyCookie,
+		StickyMaxAge:  cfg.StickyMaxAge,
+		GeoCountries:  cfg.GeoCountries,
+		GeoContinents: cfg.GeoContinents,
 	}
 }
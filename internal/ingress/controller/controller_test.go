@@ -38,15 +38,20 @@ import (
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipallowlist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sessionaffinity"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
@@ -66,14 +71,29 @@ const (
 )
 
 type fakeIngressStore struct {
-	ingresses     []*ingress.Ingress
-	configuration ngx_config.Configuration
+	ingresses      []*ingress.Ingress
+	streamRoutes   []*v1alpha1.StreamRoute
+	gatewayClasses []*gatewayv1.GatewayClass
+	gateways       []*gatewayv1.Gateway
+	httpRoutes     []*gatewayv1.HTTPRoute
+	tlsRoutes      []*gatewayv1alpha2.TLSRoute
+	tcpRoutes      []*gatewayv1alpha2.TCPRoute
+	grantedSecrets []string
+	denylists      []*v1alpha1.Denylist
+	services       map[string]*corev1.Service
+	secrets        map[string]*corev1.Secret
+	configuration  ngx_config.Configuration
+	ingressClasses []*networking.IngressClass
 }
 
 func (fakeIngressStore) GetIngressClass(_ *networking.Ingress, _ *ingressclass.Configuration) (string, error) {
 	return "nginx", nil
 }
 
+func (fis *fakeIngressStore) ListIngressClasses() []*networking.IngressClass {
+	return fis.ingressClasses
+}
+
 func (fis *fakeIngressStore) GetBackendConfiguration() ngx_config.Configuration {
 	return fis.configuration
 }
@@ -89,11 +109,17 @@ func (fakeIngressStore) GetConfigMap(_ string) (*corev1.ConfigMap, error) {
 	return nil, fmt.Errorf("test error")
 }
 
-func (fakeIngressStore) GetSecret(_ string) (*corev1.Secret, error) {
+func (fis *fakeIngressStore) GetSecret(key string) (*corev1.Secret, error) {
+	if secret, ok := fis.secrets[key]; ok {
+		return secret, nil
+	}
 	return nil, fmt.Errorf("test error")
 }
 
-func (fakeIngressStore) GetService(_ string) (*corev1.Service, error) {
+func (fis *fakeIngressStore) GetService(key string) (*corev1.Service, error) {
+	if svc, ok := fis.services[key]; ok {
+		return svc, nil
+	}
 	return nil, fmt.Errorf("test error")
 }
 
@@ -105,6 +131,51 @@ func (fis *fakeIngressStore) ListIngresses() []*ingress.Ingress {
 	return fis.ingresses
 }
 
+func (fis *fakeIngressStore) ListStreamRoutes() []*v1alpha1.StreamRoute {
+	return fis.streamRoutes
+}
+
+func (fis *fakeIngressStore) ListGatewayClasses() []*gatewayv1.GatewayClass {
+	return fis.gatewayClasses
+}
+
+func (fis *fakeIngressStore) ListGateways() []*gatewayv1.Gateway {
+	return fis.gateways
+}
+
+func (fis *fakeIngressStore) ListHTTPRoutes() []*gatewayv1.HTTPRoute {
+	return fis.httpRoutes
+}
+
+func (fis *fakeIngressStore) ListTLSRoutes() []*gatewayv1alpha2.TLSRoute {
+	return fis.tlsRoutes
+}
+
+func (fis *fakeIngressStore) ListTCPRoutes() []*gatewayv1alpha2.TCPRoute {
+	return fis.tcpRoutes
+}
+
+func (fis *fakeIngressStore) CheckHostOwnership(_ *networking.Ingress) error {
+	return nil
+}
+
+func (fis *fakeIngressStore) SyncHostOwnership() error {
+	return nil
+}
+
+func (fis *fakeIngressStore) ListDenylists() []*v1alpha1.Denylist {
+	return fis.denylists
+}
+
+func (fis *fakeIngressStore) IsSecretReferenceGranted(_ schema.GroupKind, _, secret string) bool {
+	for _, granted := range fis.grantedSecrets {
+		if granted == secret {
+			return true
+		}
+	}
+	return false
+}
+
 func (fis *fakeIngressStore) FilterIngresses(ingresses []*ingress.Ingress, _ store.IngressFilterFunc) []*ingress.Ingress {
 	return ingresses
 }
@@ -121,12 +192,24 @@ func (fakeIngressStore) GetAuthCertificate(string) (*resolver.AuthSSLCert, error
 	return nil, fmt.Errorf("test error")
 }
 
+func (fakeIngressStore) GetWAFPolicy(string) (*v1alpha1.WAFPolicy, error) {
+	return nil, fmt.Errorf("test error")
+}
+
+func (fakeIngressStore) GetRedirectMap(string) (*v1alpha1.RedirectMap, error) {
+	return nil, fmt.Errorf("test error")
+}
+
 func (fakeIngressStore) GetDefaultBackend() defaults.Backend {
 	return defaults.Backend{}
 }
 
 func (fakeIngressStore) Run(_ chan struct{}) {}
 
+func (fis *fakeIngressStore) ExtractAnnotations(ing *networking.Ingress) (*annotations.Ingress, error) {
+	return annotations.NewAnnotationExtractor(fis).Extract(ing)
+}
+
 type testNginxTestCommand struct {
 	t        *testing.T
 	expected string
@@ -191,11 +274,11 @@ func TestCheckIngress(t *testing.T) {
 
 	// Ensure no panic with wrong arguments
 	var nginx *NGINXController
-	if err := nginx.CheckIngress(nil); err != nil {
+	if _, err := nginx.CheckIngress(nil); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 	nginx = newNGINXController(t)
-	if err := nginx.CheckIngress(nil); err != nil {
+	if _, err := nginx.CheckIngress(nil); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 	nginx.metricCollector = metric.DummyCollector{}
@@ -226,7 +309,7 @@ func TestCheckIngress(t *testing.T) {
 			err:      nil,
 			expected: "_,example.com",
 		}
-		if nginx.CheckIngress(ing) != nil {
+		if _, err := nginx.CheckIngress(ing); err != nil {
 			t.Errorf("with a new ingress without error, no error should be returned")
 		}
 
@@ -245,7 +328,7 @@ func TestCheckIngress(t *testing.T) {
 				err:      nil,
 				expected: "_,test.example.com",
 			}
-			if nginx.CheckIngress(ing) != nil {
+			if _, err := nginx.CheckIngress(ing); err != nil {
 				t.Errorf("with a new ingress without error, no error should be returned")
 			}
 		})
@@ -257,7 +340,7 @@ func TestCheckIngress(t *testing.T) {
 				out:      []byte("this is the test command output"),
 				expected: "_,test.example.com",
 			}
-			if nginx.CheckIngress(ing) == nil {
+			if _, err := nginx.CheckIngress(ing); err == nil {
 				t.Errorf("with a new ingress with an error, an error should be returned")
 			}
 		})
@@ -272,7 +355,7 @@ func TestCheckIngress(t *testing.T) {
 				t:   t,
 				err: nil,
 			}
-			if nginx.CheckIngress(ing) == nil {
+			if _, err := nginx.CheckIngress(ing); err == nil {
 				t.Errorf("with a custom annotation prefix, ingresses using the default should be rejected")
 			}
 		})
@@ -289,7 +372,7 @@ func TestCheckIngress(t *testing.T) {
 				err: nil,
 			}
 			ing.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/server-snippet"] = "bla"
-			if err := nginx.CheckIngress(ing); err == nil {
+			if _, err := nginx.CheckIngress(ing); err == nil {
 				t.Errorf("with a snippet annotation, ingresses using the default should be rejected")
 			}
 		})
@@ -306,11 +389,11 @@ func TestCheckIngress(t *testing.T) {
 				err: nil,
 			}
 			ing.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/custom-headers"] = "invalid_directive"
-			if err := nginx.CheckIngress(ing); err == nil {
+			if _, err := nginx.CheckIngress(ing); err == nil {
 				t.Errorf("with an invalid value in annotation the ingress should be rejected")
 			}
 			ing.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/custom-headers"] = "another_directive"
-			if err := nginx.CheckIngress(ing); err == nil {
+			if _, err := nginx.CheckIngress(ing); err == nil {
 				t.Errorf("with an invalid value in annotation the ingress should be rejected")
 			}
 		})
@@ -334,7 +417,7 @@ func TestCheckIngress(t *testing.T) {
 				},
 			}
 
-			if nginx.CheckIngress(ing) == nil {
+			if _, err := nginx.CheckIngress(ing); err == nil {
 				t.Errorf("with a new catch-all ingress and catch-alls disable, should return error")
 			}
 
@@ -353,7 +436,7 @@ func TestCheckIngress(t *testing.T) {
 			}
 			nginx.cfg.Namespace = "other-namespace"
 			ing.ObjectMeta.Namespace = "test-namespace"
-			if nginx.CheckIngress(ing) != nil {
+			if _, err := nginx.CheckIngress(ing); err != nil {
 				t.Errorf("with a new ingress without error, no error should be returned")
 			}
 		})
@@ -364,7 +447,7 @@ func TestCheckIngress(t *testing.T) {
 			Time: time.Now(),
 		}
 
-		if nginx.CheckIngress(ing) != nil {
+		if _, err := nginx.CheckIngress(ing); err != nil {
 			t.Errorf("when the ingress is marked as deleted, no error should be returned")
 		}
 	})
@@ -2468,6 +2551,62 @@ func TestGetBackendServers(t *testing.T) {
 				}
 			},
 		},
+		{
+			Ingresses: []*ingress.Ingress{
+				{
+					Ingress: networking.Ingress{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "redirect",
+							Namespace: "example",
+						},
+						Spec: networking.IngressSpec{
+							Rules: []networking.IngressRule{
+								{
+									Host: "example.com",
+									IngressRuleValue: networking.IngressRuleValue{
+										HTTP: &networking.HTTPIngressRuleValue{
+											Paths: []networking.HTTPIngressPath{
+												{
+													Path:     "/old",
+													PathType: &pathTypePrefix,
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					ParsedAnnotations: &annotations.Ingress{
+						Redirect: redirect.Config{
+							URL:  "https://example.com/new",
+							Code: 301,
+						},
+					},
+				},
+			},
+			Validate: func(_ []*ingress.Ingress, _ []*ingress.Backend, servers []*ingress.Server) {
+				if len(servers) != 2 {
+					t.Errorf("servers count should be 2, got %d", len(servers))
+					return
+				}
+
+				var loc *ingress.Location
+				for _, l := range servers[1].Locations {
+					if l.Path == "/old" {
+						loc = l
+					}
+				}
+				if loc == nil {
+					t.Errorf("expected a location for the redirect without backend")
+					return
+				}
+				if loc.Backend != defUpstreamName || loc.Redirect.URL != "https://example.com/new" {
+					t.Errorf("expected the redirect location to use the default backend, got %v and %+v", loc.Backend, loc.Redirect)
+				}
+			},
+			SetConfigMap: testConfigMap,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -2522,6 +2661,18 @@ func newNGINXController(t *testing.T) *NGINXController {
 		"",
 		10*time.Minute,
 		clientSet,
+		false,
+		nil,
+		nil,
+		nil,
+		false,
+		false,
+		nil,
+		"",
+		"",
+		nil,
+		nil,
+		nil,
 		channels.NewRingChannel(10),
 		false,
 		true,
@@ -2529,6 +2680,7 @@ func newNGINXController(t *testing.T) *NGINXController {
 			Controller:      "k8s.io/ingress-nginx",
 			AnnotationValue: "nginx",
 		},
+		nil,
 		false,
 	)
 
@@ -2588,6 +2740,18 @@ func newDynamicNginxController(t *testing.T, setConfigMap func(string) *corev1.C
 		"",
 		10*time.Minute,
 		clientSet,
+		false,
+		nil,
+		nil,
+		nil,
+		false,
+		false,
+		nil,
+		"",
+		"",
+		nil,
+		nil,
+		nil,
 		channels.NewRingChannel(10),
 		false,
 		true,
@@ -2595,6 +2759,7 @@ func newDynamicNginxController(t *testing.T, setConfigMap func(string) *corev1.C
 			Controller:      "k8s.io/ingress-nginx",
 			AnnotationValue: "nginx",
 		},
+		nil,
 		false)
 
 	sslCert := ssl.GetFakeSSLCert()
@@ -2612,3 +2777,138 @@ func newDynamicNginxController(t *testing.T, setConfigMap func(string) *corev1.C
 		metricCollector: metric.DummyCollector{},
 	}
 }
+
+func TestLocationApplyAnnotationsBackendProtocolPaths(t *testing.T) {
+	anns := &annotations.Ingress{
+		BackendProtocol:      "HTTP",
+		BackendProtocolPaths: map[string]string{"/api": "GRPC"},
+	}
+
+	testCases := map[string]string{
+		"/api":  "GRPC",
+		"/":     "HTTP",
+		"/api2": "HTTP",
+	}
+
+	for path, expected := range testCases {
+		loc := &ingress.Location{Path: path}
+		locationApplyAnnotations(loc, anns)
+		if loc.BackendProtocol != expected {
+			t.Errorf("expected backend protocol %s for path %s but returned %s", expected, path, loc.BackendProtocol)
+		}
+	}
+}
+
+func TestGetSSLRejectHandshake(t *testing.T) {
+	newIngressClass := func(name, value string) *networking.IngressClass {
+		ic := &networking.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if value != "" {
+			ic.Annotations = map[string]string{"nginx.ingress.kubernetes.io/ssl-reject-handshake": value}
+		}
+		return ic
+	}
+
+	testCases := []struct {
+		name           string
+		configmap      bool
+		ingressClasses []*networking.IngressClass
+		expected       bool
+	}{
+		{
+			name:      "configmap setting without IngressClass",
+			configmap: true,
+			expected:  true,
+		},
+		{
+			name:           "IngressClass without annotation",
+			configmap:      true,
+			ingressClasses: []*networking.IngressClass{newIngressClass("nginx", "")},
+			expected:       true,
+		},
+		{
+			name:           "IngressClass annotation enables it",
+			ingressClasses: []*networking.IngressClass{newIngressClass("nginx", "true")},
+			expected:       true,
+		},
+		{
+			name:           "IngressClass annotation disables it",
+			configmap:      true,
+			ingressClasses: []*networking.IngressClass{newIngressClass("nginx", "false")},
+			expected:       false,
+		},
+		{
+			name:           "invalid IngressClass annotation",
+			configmap:      true,
+			ingressClasses: []*networking.IngressClass{newIngressClass("nginx", "maybe")},
+			expected:       true,
+		},
+		{
+			name:           "IngressClass named differently than the annotation value",
+			ingressClasses: []*networking.IngressClass{newIngressClass("internal", "true")},
+			expected:       true,
+		},
+		{
+			name:           "IngressClasses agreeing",
+			ingressClasses: []*networking.IngressClass{newIngressClass("internal", "true"), newIngressClass("nginx", ""), newIngressClass("public", "true")},
+			expected:       true,
+		},
+		{
+			name:           "IngressClasses disagreeing",
+			ingressClasses: []*networking.IngressClass{newIngressClass("internal", "true"), newIngressClass("public", "false")},
+			expected:       false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nginx := &NGINXController{
+				store: &fakeIngressStore{
+					configuration:  ngx_config.Configuration{SSLRejectHandshake: tc.configmap},
+					ingressClasses: tc.ingressClasses,
+				},
+				cfg: &Configuration{
+					IngressClassConfiguration: &ingressclass.Configuration{AnnotationValue: "nginx"},
+				},
+			}
+
+			if got := nginx.getSSLRejectHandshake(); got != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLocationApplyAnnotationsClassLocationSnippet(t *testing.T) {
+	loc := &ingress.Location{}
+	locationApplyAnnotations(loc, &annotations.Ingress{
+		ClassLocationSnippet: "set $class internal;",
+		ConfigurationSnippet: "set $ingress example;",
+	})
+
+	expected := "set $class internal;\nset $ingress example;"
+	if loc.ConfigurationSnippet != expected {
+		t.Errorf("expected configuration snippet %q but got %q", expected, loc.ConfigurationSnippet)
+	}
+
+	locationApplyAnnotations(loc, &annotations.Ingress{ClassLocationSnippet: "set $class internal;"})
+	if loc.ConfigurationSnippet != "set $class internal;" {
+		t.Errorf("expected only the snippet of the class but got %q", loc.ConfigurationSnippet)
+	}
+}
+
+func TestAllowSnippetAnnotationsOfClass(t *testing.T) {
+	n := &NGINXController{
+		store: &fakeIngressStore{
+			configuration: ngx_config.Configuration{AllowSnippetAnnotations: false},
+		},
+	}
+
+	if n.allowSnippetAnnotations(&annotations.Ingress{}) {
+		t.Errorf("expected the snippet annotations to be disallowed by the configmap")
+	}
+
+	allowed := true
+	if !n.allowSnippetAnnotations(&annotations.Ingress{ClassAllowSnippetAnnotations: &allowed}) {
+		t.Errorf("expected the snippet annotations to be allowed by the IngressClass")
+	}
+}
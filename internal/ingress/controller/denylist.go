@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// getDenylist returns the sorted addresses of the Denylists which are not
+// expired yet. An address of several Denylists is denied until the latest
+// expiration, forever if one of them never expires.
+func (n *NGINXController) getDenylist() []ingress.DenylistEntry {
+	now := time.Now()
+	expirations := map[string]int64{}
+
+	for _, denylist := range n.store.ListDenylists() {
+		for _, entry := range denylist.Spec.Entries {
+			if _, _, err := net.ParseCIDR(entry.CIDR); err != nil && net.ParseIP(entry.CIDR) == nil {
+				klog.Warningf("Ignoring the address %q of the Denylist %v: it is not an IP address or a CIDR", entry.CIDR, denylist.Name)
+				continue
+			}
+
+			var expiresAt int64
+			if entry.ExpiresAt != nil {
+				if !entry.ExpiresAt.After(now) {
+					continue
+				}
+				expiresAt = entry.ExpiresAt.Unix()
+			}
+
+			current, exists := expirations[entry.CIDR]
+			switch {
+			case !exists:
+				expirations[entry.CIDR] = expiresAt
+			case current == 0 || expiresAt == 0:
+				expirations[entry.CIDR] = 0
+			case expiresAt > current:
+				expirations[entry.CIDR] = expiresAt
+			}
+		}
+	}
+
+	if len(expirations) == 0 {
+		return nil
+	}
+
+	entries := make([]ingress.DenylistEntry, 0, len(expirations))
+	for cidr, expiresAt := range expirations {
+		entries = append(entries, ingress.DenylistEntry{CIDR: cidr, ExpiresAt: expiresAt})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CIDR < entries[j].CIDR
+	})
+
+	return entries
+}
+
+// configureDenylist POSTs the addresses denied to all the servers to an
+// internal HTTP endpoint that is handled by Lua
+func configureDenylist(entries []ingress.DenylistEntry) error {
+	if entries == nil {
+		entries = []ingress.DenylistEntry{}
+	}
+
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/denylist", "application/json", entries)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code: %d", statusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestGetDenylist(t *testing.T) {
+	now := time.Now()
+	expired := metav1.NewTime(now.Add(-time.Minute))
+	soon := metav1.NewTime(now.Add(time.Hour))
+	later := metav1.NewTime(now.Add(2 * time.Hour))
+
+	n := &NGINXController{
+		store: &fakeIngressStore{
+			denylists: []*v1alpha1.Denylist{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "attack"},
+					Spec: v1alpha1.DenylistSpec{Entries: []v1alpha1.DenylistEntry{
+						{CIDR: "203.0.113.0/24", ExpiresAt: &soon},
+						{CIDR: "198.51.100.7", ExpiresAt: &expired},
+						{CIDR: "192.0.2.1", ExpiresAt: &soon},
+						{CIDR: "2001:db8::/32"},
+						{CIDR: "not-an-address"},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "scanners"},
+					Spec: v1alpha1.DenylistSpec{Entries: []v1alpha1.DenylistEntry{
+						{CIDR: "203.0.113.0/24", ExpiresAt: &later},
+						{CIDR: "192.0.2.1"},
+					}},
+				},
+			},
+		},
+	}
+
+	expected := []ingress.DenylistEntry{
+		{CIDR: "192.0.2.1"},
+		{CIDR: "2001:db8::/32"},
+		{CIDR: "203.0.113.0/24", ExpiresAt: later.Unix()},
+	}
+	if entries := n.getDenylist(); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v but returned %v", expected, entries)
+	}
+
+	n.store = &fakeIngressStore{}
+	if entries := n.getDenylist(); entries != nil {
+		t.Errorf("expected no entries but returned %v", entries)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// drainEnabled indicates if the requests in flight of a protocol are drained
+// on shutdown
+func (n *NGINXController) drainEnabled() bool {
+	return n.cfg.DrainGracePeriodHTTP > 0 || n.cfg.DrainGracePeriodWebSocket > 0 || n.cfg.DrainGracePeriodGRPC > 0
+}
+
+// drainStatus returns if requests in flight are still served within the
+// grace period of their protocol after the elapsed time, and if requests
+// are left past the grace period of their protocol. The protocols without
+// grace period are not waited for.
+func (n *NGINXController) drainStatus(requests *nginx.ActiveRequests, elapsed time.Duration) (waiting, exceeded bool) {
+	protocols := []struct {
+		requests    int
+		gracePeriod int
+	}{
+		{requests.HTTP, n.cfg.DrainGracePeriodHTTP},
+		{requests.WebSocket, n.cfg.DrainGracePeriodWebSocket},
+		{requests.GRPC, n.cfg.DrainGracePeriodGRPC},
+	}
+
+	for _, protocol := range protocols {
+		if protocol.requests <= 0 || protocol.gracePeriod <= 0 {
+			continue
+		}
+
+		if elapsed < time.Duration(protocol.gracePeriod)*time.Second {
+			waiting = true
+		} else {
+			exceeded = true
+		}
+	}
+
+	return waiting, exceeded
+}
+
+// drain rejects the new requests and waits for the requests in flight to be
+// done, up to the grace period of their protocol. It returns false when
+// requests are left past their grace period.
+func (n *NGINXController) drain() bool {
+	klog.InfoS("Draining NGINX connections")
+	err := nginx.StartDrain()
+	if err != nil {
+		klog.Warningf("Error starting to drain NGINX connections: %v", err)
+		return true
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		requests, err := nginx.GetActiveRequests()
+		if err != nil {
+			klog.Warningf("Error getting the active requests of NGINX: %v", err)
+			return true
+		}
+
+		waiting, exceeded := n.drainStatus(requests, time.Since(start))
+		if !waiting {
+			if exceeded {
+				klog.InfoS("Grace period of the requests in flight elapsed", "http", requests.HTTP, "websocket", requests.WebSocket, "grpc", requests.GRPC)
+			}
+			return !exceeded
+		}
+
+		klog.V(2).InfoS("Waiting for the requests in flight", "http", requests.HTTP, "websocket", requests.WebSocket, "grpc", requests.GRPC)
+		<-ticker.C
+	}
+}
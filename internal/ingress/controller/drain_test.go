@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+func TestDrainStatus(t *testing.T) {
+	n := &NGINXController{cfg: &Configuration{
+		DrainGracePeriodWebSocket: 30,
+		DrainGracePeriodGRPC:      10,
+	}}
+
+	testCases := []struct {
+		name     string
+		requests nginx.ActiveRequests
+		elapsed  time.Duration
+		waiting  bool
+		exceeded bool
+	}{
+		{"no requests", nginx.ActiveRequests{}, 0, false, false},
+		{"http requests are not waited for", nginx.ActiveRequests{HTTP: 3}, 0, false, false},
+		{"websocket connections within their grace period", nginx.ActiveRequests{WebSocket: 2, GRPC: 1}, 20 * time.Second, true, true},
+		{"grpc requests within their grace period", nginx.ActiveRequests{GRPC: 1}, 5 * time.Second, true, false},
+		{"websocket connections past their grace period", nginx.ActiveRequests{WebSocket: 2}, 30 * time.Second, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := tc.requests
+			waiting, exceeded := n.drainStatus(&requests, tc.elapsed)
+			if waiting != tc.waiting || exceeded != tc.exceeded {
+				t.Errorf("expected waiting %v and exceeded %v, got %v and %v", tc.waiting, tc.exceeded, waiting, exceeded)
+			}
+		})
+	}
+}
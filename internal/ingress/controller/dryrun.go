@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// DryRun returns the server blocks of nginx.conf generated for the hosts of
+// the Ingress, as if it was created or replaced the Ingress with the same
+// name, without applying the configuration
+func (n *NGINXController) DryRun(ing *networking.Ingress) (string, error) {
+	if ing.Namespace == "" {
+		ing.Namespace = apiv1.NamespaceDefault
+	}
+
+	if ingressClass, err := n.store.GetIngressClass(ing, n.cfg.IngressClassConfiguration); ingressClass == "" {
+		return "", fmt.Errorf("ingress %v/%v is not handled by this controller: %v", ing.Namespace, ing.Name, err)
+	}
+
+	k8s.SetDefaultNGINXPathType(ing)
+
+	parsed, err := n.store.ExtractAnnotations(ing)
+	if err != nil {
+		return "", err
+	}
+
+	ings := store.FilterIngresses(n.store.ListIngresses(), func(toCheck *ingress.Ingress) bool {
+		return toCheck.Namespace == ing.Namespace && toCheck.Name == ing.Name
+	})
+	ings = append(ings, &ingress.Ingress{
+		Ingress:           *ing,
+		ParsedAnnotations: parsed,
+	})
+
+	_, _, pcfg := n.getConfiguration(ings)
+
+	cfg := n.store.GetBackendConfiguration()
+	cfg.Resolver = n.resolver
+
+	content, err := n.generateTemplate(cfg, *pcfg)
+	if err != nil {
+		return "", err
+	}
+
+	hosts := sets.New[string]()
+	if ing.Spec.DefaultBackend != nil {
+		hosts.Insert(defServerName)
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host == "" {
+			hosts.Insert(defServerName)
+			continue
+		}
+		hosts.Insert(rule.Host)
+	}
+
+	blocks := make([]string, 0, hosts.Len())
+	for _, host := range sets.List(hosts) {
+		block, err := nginx.GetServerBlock(string(content), host)
+		if err != nil {
+			return "", err
+		}
+
+		blocks = append(blocks, fmt.Sprintf("## start server %v\n%v## end server %v\n", host, block, host))
+	}
+
+	return strings.Join(blocks, "\n"), nil
+}
+
+// handleDryRun renders the configuration of the Ingress manifest of the body
+func (n *NGINXController) handleDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ing := &networking.Ingress{}
+	err := yaml.NewYAMLOrJSONDecoder(r.Body, 4096).Decode(ing)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid Ingress manifest: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if ing.Kind != "Ingress" {
+		http.Error(w, fmt.Sprintf("expected an Ingress manifest but got %q", ing.Kind), http.StatusBadRequest)
+		return
+	}
+
+	conf, err := n.DryRun(ing)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, conf)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// serverBlockTemplate renders a server block with the paths of every server
+type serverBlockTemplate struct{}
+
+func (serverBlockTemplate) Write(conf *ngx_config.TemplateConfig) ([]byte, error) {
+	var b strings.Builder
+	for _, s := range conf.Servers {
+		fmt.Fprintf(&b, "## start server %v\n", s.Hostname)
+		for _, loc := range s.Locations {
+			fmt.Fprintf(&b, "location %v {}\n", loc.Path)
+		}
+		fmt.Fprintf(&b, "## end server %v\n", s.Hostname)
+	}
+	return []byte(b.String()), nil
+}
+
+func TestDryRun(t *testing.T) {
+	n := newNGINXController(t)
+	n.metricCollector = metric.DummyCollector{}
+	n.t = serverBlockTemplate{}
+	n.store = &fakeIngressStore{
+		ingresses: []*ingress.Ingress{
+			{
+				Ingress: networking.Ingress{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+					Spec: networking.IngressSpec{
+						Rules: []networking.IngressRule{{Host: "other.com"}},
+					},
+				},
+				ParsedAnnotations: &annotations.Ingress{},
+			},
+		},
+	}
+
+	pathTypePrefix := networking.PathTypePrefix
+	ing := &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "example"},
+		Spec: networking.IngressSpec{
+			Rules: []networking.IngressRule{
+				{
+					Host: "example.com",
+					IngressRuleValue: networking.IngressRuleValue{
+						HTTP: &networking.HTTPIngressRuleValue{
+							Paths: []networking.HTTPIngressPath{
+								{
+									Path:     "/api",
+									PathType: &pathTypePrefix,
+									Backend: networking.IngressBackend{
+										Service: &networking.IngressServiceBackend{
+											Name: "http-svc",
+											Port: networking.ServiceBackendPort{Number: 80},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	conf, err := n.DryRun(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "## start server example.com\nlocation /api/ {}\nlocation /api {}\nlocation / {}\n## end server example.com\n"
+	if conf != expected {
+		t.Errorf("expected configuration\n%v\nbut got\n%v", expected, conf)
+	}
+	if ing.Namespace != "default" {
+		t.Errorf("expected the default namespace but %v returned", ing.Namespace)
+	}
+}
+
+func TestHandleDryRun(t *testing.T) {
+	n := newNGINXController(t)
+	n.metricCollector = metric.DummyCollector{}
+	n.t = serverBlockTemplate{}
+	n.store = &fakeIngressStore{}
+
+	testCases := []struct {
+		name     string
+		method   string
+		manifest string
+		status   int
+		body     string
+	}{
+		{
+			name:   "GET request",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			body:   "only POST requests are allowed\n",
+		},
+		{
+			name:     "invalid manifest",
+			method:   http.MethodPost,
+			manifest: "kind: [",
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:     "not an Ingress",
+			method:   http.MethodPost,
+			manifest: "apiVersion: v1\nkind: Service\nmetadata:\n  name: example\n",
+			status:   http.StatusBadRequest,
+			body:     "expected an Ingress manifest but got \"Service\"\n",
+		},
+		{
+			name:     "Ingress",
+			method:   http.MethodPost,
+			manifest: "apiVersion: networking.k8s.io/v1\nkind: Ingress\nmetadata:\n  name: example\nspec:\n  rules:\n  - host: example.com\n",
+			status:   http.StatusOK,
+			body:     "## start server example.com\nlocation / {}\n## end server example.com\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/dry-run", strings.NewReader(tc.manifest))
+			w := httptest.NewRecorder()
+
+			n.handleDryRun(w, req)
+
+			if w.Code != tc.status {
+				t.Errorf("expected status %v but %v returned", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("expected body %q but %q returned", tc.body, w.Body.String())
+			}
+		})
+	}
+}
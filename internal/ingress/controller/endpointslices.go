@@ -35,10 +35,15 @@ import (
 )
 
 // getEndpointsFromSlices returns a list of Endpoint structs for a given service/target port combination.
+// When zoneForHints is not empty only the endpoints of that zone are returned, unless the zone has less
+// than minZoneEndpoints ready endpoints, in which case the traffic spills over to all the zones. The
+// endpoints of the slices without topology hints are all kept, unless useEndpointZone is set and the
+// zone of the endpoints is used instead.
 func getEndpointsFromSlices(s *corev1.Service, port *corev1.ServicePort, proto corev1.Protocol, zoneForHints string,
-	getServiceEndpointsSlices func(string) ([]*discoveryv1.EndpointSlice, error),
+	minZoneEndpoints int, useEndpointZone bool, getServiceEndpointsSlices func(string) ([]*discoveryv1.EndpointSlice, error),
 ) []ingress.Endpoint {
 	upsServers := []ingress.Endpoint{}
+	zoneUpsServers := []ingress.Endpoint{}
 
 	if s == nil || port == nil {
 		return upsServers
@@ -124,6 +129,8 @@ func getEndpointsFromSlices(s *corev1.Service, port *corev1.ServicePort, proto c
 			}
 			if useTopologyHints {
 				klog.V(3).Infof("All endpoint slices has zone hint, using zone %q for Service %q", zoneForHints, svcKey)
+			} else if useEndpointZone {
+				klog.V(3).Infof("Missing zone hints, using the zone of the endpoints to match zone %q for Service %q", zoneForHints, svcKey)
 			}
 		}
 
@@ -131,19 +138,8 @@ func getEndpointsFromSlices(s *corev1.Service, port *corev1.ServicePort, proto c
 			if (ep.Conditions.Ready != nil) && !(*ep.Conditions.Ready) {
 				continue
 			}
-			epHasZone := false
-			if useTopologyHints {
-				for _, epzone := range ep.Hints.ForZones {
-					if epzone.Name == zoneForHints {
-						epHasZone = true
-						break
-					}
-				}
-			}
-
-			if useTopologyHints && !epHasZone {
-				continue
-			}
+			epInZone := zoneForHints != emptyZone &&
+				((!useTopologyHints && !useEndpointZone) || endpointInZone(&ep, zoneForHints, useTopologyHints))
 
 			for _, epPort := range ports {
 				for _, epAddress := range ep.Addresses {
@@ -157,12 +153,39 @@ func getEndpointsFromSlices(s *corev1.Service, port *corev1.ServicePort, proto c
 						Target:  ep.TargetRef,
 					}
 					upsServers = append(upsServers, ups)
+					if epInZone {
+						zoneUpsServers = append(zoneUpsServers, ups)
+					}
 					processedUpstreamServers[hostPort] = struct{}{}
 				}
 			}
 		}
 	}
 
+	if zoneForHints != emptyZone {
+		if len(zoneUpsServers) > 0 && len(zoneUpsServers) >= minZoneEndpoints {
+			klog.V(3).Infof("Endpoints found in zone %q for Service %q: %v", zoneForHints, svcKey, zoneUpsServers)
+			return zoneUpsServers
+		}
+		klog.V(3).Infof("Found %d endpoints in zone %q for Service %q, spilling over to all the zones",
+			len(zoneUpsServers), zoneForHints, svcKey)
+	}
+
 	klog.V(3).Infof("Endpoints found for Service %q: %v", svcKey, upsServers)
 	return upsServers
 }
+
+// endpointInZone checks if the endpoint should receive the traffic of the zone, using the
+// topology hints of the endpoint when available and the zone of the endpoint otherwise.
+func endpointInZone(ep *discoveryv1.Endpoint, zone string, useTopologyHints bool) bool {
+	if useTopologyHints {
+		for _, epzone := range ep.Hints.ForZones {
+			if epzone.Name == zone {
+				return true
+			}
+		}
+		return false
+	}
+
+	return ep.Zone != nil && *ep.Zone == zone
+}
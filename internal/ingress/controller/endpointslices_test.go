@@ -920,10 +920,106 @@ func TestGetEndpointsFromSlices(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			result := getEndpointsFromSlices(testCase.svc, testCase.port, testCase.proto, testCase.zone, testCase.fn)
+			result := getEndpointsFromSlices(testCase.svc, testCase.port, testCase.proto, testCase.zone, 1, false, testCase.fn)
 			if len(testCase.result) != len(result) {
 				t.Errorf("Expected %d Endpoints but got %d", len(testCase.result), len(result))
 			}
 		})
 	}
 }
+
+func TestGetEndpointsFromSlicesTopologySpillover(t *testing.T) {
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: "1.1.1.1",
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "default",
+					TargetPort: intstr.FromInt(80),
+				},
+			},
+		},
+	}
+	port := &corev1.ServicePort{TargetPort: intstr.FromInt(80)}
+
+	endpoint := func(address, zone string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses: []string{address},
+			Conditions: discoveryv1.EndpointConditions{
+				Ready: &[]bool{true}[0],
+			},
+			Zone: &[]string{zone}[0],
+		}
+	}
+	fn := func(string) ([]*discoveryv1.EndpointSlice, error) {
+		return []*discoveryv1.EndpointSlice{{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{discoveryv1.LabelServiceName: "default"},
+			},
+			Endpoints: []discoveryv1.Endpoint{
+				endpoint("1.1.1.1", "eu-west-1a"),
+				endpoint("1.1.1.2", "eu-west-1a"),
+				endpoint("1.1.1.3", "eu-west-1b"),
+				endpoint("1.1.1.4", "eu-west-1c"),
+			},
+		}}, nil
+	}
+
+	tests := []struct {
+		name             string
+		zone             string
+		minZoneEndpoints int
+		useEndpointZone  bool
+		expected         []string
+	}{
+		{
+			name:             "zone labels are ignored without hints",
+			zone:             "eu-west-1a",
+			minZoneEndpoints: 1,
+			expected:         []string{"1.1.1.1", "1.1.1.2", "1.1.1.3", "1.1.1.4"},
+		},
+		{
+			name:             "zone labels are used without hints when enabled",
+			zone:             "eu-west-1a",
+			minZoneEndpoints: 1,
+			useEndpointZone:  true,
+			expected:         []string{"1.1.1.1", "1.1.1.2"},
+		},
+		{
+			name:             "zone with enough endpoints",
+			zone:             "eu-west-1a",
+			minZoneEndpoints: 2,
+			useEndpointZone:  true,
+			expected:         []string{"1.1.1.1", "1.1.1.2"},
+		},
+		{
+			name:             "spills over when the zone has less endpoints than the minimum",
+			zone:             "eu-west-1b",
+			minZoneEndpoints: 2,
+			useEndpointZone:  true,
+			expected:         []string{"1.1.1.1", "1.1.1.2", "1.1.1.3", "1.1.1.4"},
+		},
+		{
+			name:             "spills over when the zone has no endpoints",
+			zone:             "eu-west-1d",
+			minZoneEndpoints: 1,
+			useEndpointZone:  true,
+			expected:         []string{"1.1.1.1", "1.1.1.2", "1.1.1.3", "1.1.1.4"},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := getEndpointsFromSlices(svc, port, corev1.ProtocolTCP, testCase.zone, testCase.minZoneEndpoints, testCase.useEndpointZone, fn)
+			if len(result) != len(testCase.expected) {
+				t.Fatalf("Expected %v Endpoints but got %v", testCase.expected, result)
+			}
+			for i, address := range testCase.expected {
+				if result[i].Address != address {
+					t.Errorf("Expected Endpoint %v but got %v", address, result[i].Address)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// certificateExpiryCheckInterval defines how often the expiration of the
+// served certificates is checked
+var certificateExpiryCheckInterval = 10 * time.Minute
+
+// checkCertificateExpiry emits warning Events for the served certificates
+// expiring within ssl-expiry-warning-days. It runs on the leader only, to
+// emit a single Event per certificate.
+func (n *NGINXController) checkCertificateExpiry() {
+	days := n.store.GetBackendConfiguration().SSLExpiryWarningDays
+	if days <= 0 {
+		return
+	}
+
+	// the syncs replace the running configuration, they never modify it
+	n.syncLock.Lock()
+	runningConfig := n.runningConfig
+	n.syncLock.Unlock()
+
+	if runningConfig == nil {
+		return
+	}
+
+	n.reportExpiringCertificates(runningConfig.Servers, time.Now().Add(time.Duration(days)*24*time.Hour))
+}
+
+// reportExpiringCertificates emits a warning Event on the Ingresses of every
+// certificate expiring before the deadline, or on the controller Pod for the
+// default certificate. The Event is emitted once per certificate and
+// expiration time, a renewed certificate is reported again.
+func (n *NGINXController) reportExpiringCertificates(servers []*ingress.Server, deadline time.Time) {
+	reported := sets.New[string]()
+
+	report := func(object runtime.Object, key, message string) {
+		reported.Insert(key)
+		if n.expiringCertificates.Has(key) {
+			return
+		}
+
+		n.recorder.Event(object, apiv1.EventTypeWarning, "SSLExpiry", message)
+	}
+
+	for _, s := range servers {
+		if s.SSLCert != nil && s.SSLCert.ExpireTime.Unix() > 0 && s.SSLCert.ExpireTime.Before(deadline) {
+			message := fmt.Sprintf("SSL certificate of host %v expires on %v", s.Hostname, s.SSLCert.ExpireTime.UTC().Format(time.RFC3339))
+			if s.SSLCert.Name != "" {
+				message = fmt.Sprintf("SSL certificate in Secret %v/%v expires on %v", s.SSLCert.Namespace, s.SSLCert.Name, s.SSLCert.ExpireTime.UTC().Format(time.RFC3339))
+			}
+
+			owners := certificateOwners(s)
+			if len(owners) == 0 {
+				report(k8s.IngressPodDetails, fmt.Sprintf("pod/%v/%v", s.SSLCert.UID, s.SSLCert.ExpireTime.Unix()), message)
+			}
+			for _, ing := range owners {
+				report(&ing.Ingress, fmt.Sprintf("%v/%v/%v", ing.UID, s.SSLCert.UID, s.SSLCert.ExpireTime.Unix()), message)
+			}
+		}
+
+		ca := s.CertificateAuth.AuthSSLCert
+		if ca.CAExpireTime.Unix() > 0 && ca.CAExpireTime.Before(deadline) {
+			message := fmt.Sprintf("CA certificate in Secret %v expires on %v", ca.Secret, ca.CAExpireTime.UTC().Format(time.RFC3339))
+			for _, ing := range caOwners(s) {
+				report(&ing.Ingress, fmt.Sprintf("%v/%v/%v", ing.UID, ca.Secret, ca.CAExpireTime.Unix()), message)
+			}
+		}
+	}
+
+	n.expiringCertificates = reported
+}
+
+// certificateOwners returns the Ingresses of the server with a TLS section
+// for its host or its Secret
+func certificateOwners(s *ingress.Server) []*ingress.Ingress {
+	var owners []*ingress.Ingress
+	seen := sets.New[string]()
+
+	for _, loc := range s.Locations {
+		ing := loc.Ingress
+		if ing == nil || seen.Has(string(ing.UID)) {
+			continue
+		}
+
+		for _, tls := range ing.Spec.TLS {
+			if sets.New(tls.Hosts...).Has(s.Hostname) || (ing.Namespace == s.SSLCert.Namespace && tls.SecretName == s.SSLCert.Name) {
+				seen.Insert(string(ing.UID))
+				owners = append(owners, ing)
+				break
+			}
+		}
+	}
+
+	return owners
+}
+
+// caOwners returns the Ingresses of the server authenticating the client
+// certificates with its CA
+func caOwners(s *ingress.Server) []*ingress.Ingress {
+	var owners []*ingress.Ingress
+	seen := sets.New[string]()
+
+	for _, loc := range s.Locations {
+		ing := loc.Ingress
+		if ing == nil || ing.ParsedAnnotations == nil || seen.Has(string(ing.UID)) {
+			continue
+		}
+
+		if ing.ParsedAnnotations.CertificateAuth.Secret == s.CertificateAuth.Secret {
+			seen.Insert(string(ing.UID))
+			owners = append(owners, ing)
+		}
+	}
+
+	return owners
+}
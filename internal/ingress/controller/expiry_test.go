@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+func TestReportExpiringCertificates(t *testing.T) {
+	k8s.IngressPodDetails = &k8s.PodInfo{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx", Namespace: metav1.NamespaceDefault},
+	}
+
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	deadline := now.Add(10 * 24 * time.Hour)
+
+	caAuth := authtls.Config{AuthSSLCert: resolver.AuthSSLCert{Secret: "default/ca", CAExpireTime: now.Add(5 * 24 * time.Hour)}}
+	ing := &ingress.Ingress{
+		Ingress: networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: metav1.NamespaceDefault, UID: "ing-uid"},
+			Spec: networking.IngressSpec{
+				TLS: []networking.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "example-tls"}},
+			},
+		},
+		ParsedAnnotations: &annotations.Ingress{CertificateAuth: caAuth},
+	}
+	other := &ingress.Ingress{
+		Ingress: networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: metav1.NamespaceDefault, UID: "other-uid"},
+		},
+		ParsedAnnotations: &annotations.Ingress{},
+	}
+
+	servers := []*ingress.Server{
+		{
+			Hostname: "_",
+			SSLCert:  &ingress.SSLCert{UID: "default-uid", ExpireTime: now.Add(24 * time.Hour)},
+		},
+		{
+			Hostname:        "example.com",
+			SSLCert:         &ingress.SSLCert{Name: "example-tls", Namespace: metav1.NamespaceDefault, UID: "example-uid", ExpireTime: now.Add(3 * 24 * time.Hour)},
+			CertificateAuth: caAuth,
+			Locations: []*ingress.Location{
+				{Path: "/", Ingress: ing},
+				{Path: "/api", Ingress: ing},
+				{Path: "/other", Ingress: other},
+			},
+		},
+		{
+			Hostname: "valid.com",
+			SSLCert:  &ingress.SSLCert{Name: "valid-tls", Namespace: metav1.NamespaceDefault, UID: "valid-uid", ExpireTime: now.Add(60 * 24 * time.Hour)},
+			Locations: []*ingress.Location{
+				{Path: "/", Ingress: other},
+			},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{recorder: recorder}
+
+	events := func() []string {
+		var events []string
+		for {
+			select {
+			case e := <-recorder.Events:
+				events = append(events, e)
+			default:
+				return events
+			}
+		}
+	}
+
+	n.reportExpiringCertificates(servers, deadline)
+	expected := []string{
+		"Warning SSLExpiry SSL certificate of host _ expires on 2024-03-02T00:00:00Z",
+		"Warning SSLExpiry SSL certificate in Secret default/example-tls expires on 2024-03-04T00:00:00Z",
+		"Warning SSLExpiry CA certificate in Secret default/ca expires on 2024-03-06T00:00:00Z",
+	}
+	e := events()
+	if len(e) != len(expected) {
+		t.Fatalf("expected Events %v but %v returned", expected, e)
+	}
+	for i := range expected {
+		if e[i] != expected[i] {
+			t.Errorf("expected Event %v but %v returned", expected[i], e[i])
+		}
+	}
+
+	n.reportExpiringCertificates(servers, deadline)
+	if e := events(); len(e) != 0 {
+		t.Errorf("expected no new Event for certificates already reported but %v returned", e)
+	}
+
+	// the certificate was renewed, but still expires before the deadline
+	servers[1].SSLCert.ExpireTime = now.Add(8 * 24 * time.Hour)
+	n.reportExpiringCertificates(servers, deadline)
+	expected = []string{"Warning SSLExpiry SSL certificate in Secret default/example-tls expires on 2024-03-09T00:00:00Z"}
+	if e := events(); len(e) != 1 || e[0] != expected[0] {
+		t.Errorf("expected Events %v but %v returned", expected, e)
+	}
+}
@@ -0,0 +1,952 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	klog "k8s.io/klog/v2"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+const (
+	gatewayKind   = "Gateway"
+	httpRouteKind = "HTTPRoute"
+	tlsRouteKind  = "TLSRoute"
+	tcpRouteKind  = "TCPRoute"
+	secretKind    = "Secret"
+	serviceKind   = "Service"
+)
+
+// errUnsupported is returned for the features of the Gateway API that cannot
+// be translated to Ingresses
+type errUnsupported struct {
+	msg string
+}
+
+func (e errUnsupported) Error() string {
+	return e.msg
+}
+
+// errRefNotPermitted is returned for the references to the objects of other
+// namespaces not permitted by a ReferenceGrant
+type errRefNotPermitted struct {
+	msg string
+}
+
+func (e errRefNotPermitted) Error() string {
+	return e.msg
+}
+
+// gatewayTranslation contains the Ingresses and the stream services
+// translated from the Routes attached to the Gateways of the controller, and
+// the status of the Gateway API objects
+type gatewayTranslation struct {
+	ingresses []*ingress.Ingress
+	// streamServices contains the TCP services of the TLSRoutes and TCPRoutes
+	streamServices []ingress.L4Service
+	// passthroughBackends contains the SSL passthrough backends of the
+	// TLSRoutes of the HTTPS port
+	passthroughBackends []*ingress.SSLPassthroughBackend
+	// streamClaims contains the Routes served by the stream services, by port
+	// and hostname, empty for the Routes without hostnames
+	streamClaims map[int32]map[string]string
+
+	classes  []*gatewayv1.GatewayClass
+	gateways []*gatewayv1.Gateway
+	// listeners contains the status of the Listeners, by Gateway key and
+	// Listener name
+	listeners map[string]map[gatewayv1.SectionName]*gatewayv1.ListenerStatus
+	// routes contains the Routes attached to the Gateways of the controller,
+	// by kind and key
+	routes map[string]*gatewayRoute
+}
+
+// gatewayRoute is a Route attached to the Gateways of the controller and its
+// status for them
+type gatewayRoute struct {
+	resource schema.GroupVersionResource
+	kind     string
+	// object is the Route, its status is updated through status
+	object  metav1.Object
+	status  *gatewayv1.RouteStatus
+	parents []gatewayv1.RouteParentStatus
+}
+
+// routeAttachment is a Listener a Route is attached to, with the hostnames
+// of the Route served by the Listener
+type routeAttachment struct {
+	gateway   *gatewayv1.Gateway
+	listener  *gatewayv1.Listener
+	hostnames []string
+}
+
+// gatewayControllerName returns the controllerName of the GatewayClasses of
+// the controller, its --controller-class
+func (n *NGINXController) gatewayControllerName() string {
+	if n.cfg.IngressClassConfiguration == nil {
+		return ""
+	}
+	return n.cfg.IngressClassConfiguration.Controller
+}
+
+// listenerReasonUnsupportedValue is the reason of the Listeners not accepted
+// for a value of their spec the controller does not support
+const listenerReasonUnsupportedValue gatewayv1.ListenerConditionReason = "UnsupportedValue"
+
+func gatewayCondition[T, R ~string](conditionType T, ok bool, reason R, message string, generation int64) metav1.Condition {
+	status := metav1.ConditionTrue
+	if !ok {
+		status = metav1.ConditionFalse
+	}
+	return metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		Reason:             string(reason),
+		Message:            message,
+		ObservedGeneration: generation,
+	}
+}
+
+// translateGateways translates the Routes attached to the Gateways of the
+// GatewayClasses of the controller to Ingresses and stream services
+func (n *NGINXController) translateGateways() *gatewayTranslation {
+	t := &gatewayTranslation{
+		streamClaims: map[int32]map[string]string{},
+		listeners:    map[string]map[gatewayv1.SectionName]*gatewayv1.ListenerStatus{},
+		routes:       map[string]*gatewayRoute{},
+	}
+
+	controllerName := n.gatewayControllerName()
+	classNames := map[string]bool{}
+	for _, class := range n.store.ListGatewayClasses() {
+		if string(class.Spec.ControllerName) == controllerName {
+			t.classes = append(t.classes, class)
+			classNames[class.Name] = true
+		}
+	}
+
+	gateways := map[string]*gatewayv1.Gateway{}
+	for _, gateway := range n.store.ListGateways() {
+		if !classNames[string(gateway.Spec.GatewayClassName)] {
+			continue
+		}
+
+		key := k8s.MetaNamespaceKey(gateway)
+		t.gateways = append(t.gateways, gateway)
+		gateways[key] = gateway
+
+		t.listeners[key] = map[gatewayv1.SectionName]*gatewayv1.ListenerStatus{}
+		for i := range gateway.Spec.Listeners {
+			listener := &gateway.Spec.Listeners[i]
+			t.listeners[key][listener.Name] = n.listenerStatus(gateway, listener)
+		}
+	}
+
+	httpRoutes := n.store.ListHTTPRoutes()
+	sort.SliceStable(httpRoutes, func(i, j int) bool {
+		return k8s.MetaNamespaceKey(httpRoutes[i]) < k8s.MetaNamespaceKey(httpRoutes[j])
+	})
+	for _, route := range httpRoutes {
+		n.translateHTTPRoute(t, gateways, route)
+	}
+
+	if n.cfg.EnableExperimentalGatewayAPI {
+		n.translateStreamRoutes(t, gateways)
+	}
+
+	return t
+}
+
+// listenerRouteKind returns the kind of the Routes served by the Listeners of
+// a protocol, empty when the protocol is not supported
+func (n *NGINXController) listenerRouteKind(protocol gatewayv1.ProtocolType) string {
+	switch protocol {
+	case gatewayv1.HTTPProtocolType, gatewayv1.HTTPSProtocolType:
+		return httpRouteKind
+	case gatewayv1.TLSProtocolType:
+		if n.cfg.EnableExperimentalGatewayAPI {
+			return tlsRouteKind
+		}
+	case gatewayv1.TCPProtocolType:
+		if n.cfg.EnableExperimentalGatewayAPI {
+			return tcpRouteKind
+		}
+	}
+	return ""
+}
+
+// listenerStatus checks if a Listener can be served by the controller
+func (n *NGINXController) listenerStatus(gateway *gatewayv1.Gateway, listener *gatewayv1.Listener) *gatewayv1.ListenerStatus {
+	group := gatewayv1.Group(gatewayv1.GroupName)
+	kind := n.listenerRouteKind(listener.Protocol)
+	status := &gatewayv1.ListenerStatus{
+		Name:           listener.Name,
+		SupportedKinds: []gatewayv1.RouteGroupKind{{Group: &group, Kind: gatewayv1.Kind(kind)}},
+	}
+
+	accepted := gatewayCondition(gatewayv1.ListenerConditionAccepted, true, gatewayv1.ListenerReasonAccepted,
+		"The Listener is served by the ingress controller", gateway.Generation)
+	resolvedRefs := gatewayCondition(gatewayv1.ListenerConditionResolvedRefs, true, gatewayv1.ListenerReasonResolvedRefs,
+		"The references of the Listener are resolved", gateway.Generation)
+
+	switch {
+	case kind == "":
+		status.SupportedKinds = []gatewayv1.RouteGroupKind{}
+		accepted = gatewayCondition(gatewayv1.ListenerConditionAccepted, false, gatewayv1.ListenerReasonUnsupportedProtocol,
+			fmt.Sprintf("protocol %v is not supported", listener.Protocol), gateway.Generation)
+	case listener.Protocol == gatewayv1.HTTPProtocolType:
+		if int(listener.Port) != n.cfg.ListenPorts.HTTP {
+			accepted = gatewayCondition(gatewayv1.ListenerConditionAccepted, false, gatewayv1.ListenerReasonPortUnavailable,
+				fmt.Sprintf("HTTP listeners must use the port %v", n.cfg.ListenPorts.HTTP), gateway.Generation)
+		}
+	case listener.Protocol == gatewayv1.HTTPSProtocolType:
+		if int(listener.Port) != n.cfg.ListenPorts.HTTPS {
+			accepted = gatewayCondition(gatewayv1.ListenerConditionAccepted, false, gatewayv1.ListenerReasonPortUnavailable,
+				fmt.Sprintf("HTTPS listeners must use the port %v", n.cfg.ListenPorts.HTTPS), gateway.Generation)
+		}
+		if _, err := n.listenerCertificate(gateway, listener); err != nil {
+			reason := gatewayv1.ListenerReasonInvalidCertificateRef
+			if errors.As(err, &errRefNotPermitted{}) {
+				reason = gatewayv1.ListenerReasonRefNotPermitted
+			}
+			resolvedRefs = gatewayCondition(gatewayv1.ListenerConditionResolvedRefs, false, reason,
+				err.Error(), gateway.Generation)
+		}
+	default:
+		if err := n.checkStreamListenerPort(listener.Protocol, int(listener.Port)); err != nil {
+			accepted = gatewayCondition(gatewayv1.ListenerConditionAccepted, false, gatewayv1.ListenerReasonPortUnavailable,
+				err.Error(), gateway.Generation)
+		}
+		if listener.Protocol == gatewayv1.TLSProtocolType && (listener.TLS == nil || listener.TLS.Mode == nil || *listener.TLS.Mode != gatewayv1.TLSModePassthrough) {
+			accepted = gatewayCondition(gatewayv1.ListenerConditionAccepted, false, listenerReasonUnsupportedValue,
+				"TLS listeners only support the Passthrough TLS mode", gateway.Generation)
+		}
+	}
+
+	programmed := gatewayCondition(gatewayv1.ListenerConditionProgrammed, true, gatewayv1.ListenerReasonProgrammed,
+		"The Listener is programmed", gateway.Generation)
+	if accepted.Status != metav1.ConditionTrue || resolvedRefs.Status != metav1.ConditionTrue {
+		programmed = gatewayCondition(gatewayv1.ListenerConditionProgrammed, false, gatewayv1.ListenerReasonInvalid,
+			"The Listener is invalid", gateway.Generation)
+	}
+
+	status.Conditions = []metav1.Condition{accepted, resolvedRefs, programmed}
+	return status
+}
+
+// listenerReady returns true if all the conditions of the Listener are true
+func listenerReady(status *gatewayv1.ListenerStatus) bool {
+	for _, condition := range status.Conditions {
+		if condition.Status != metav1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
+
+// listenerCertificate returns the key of the Secret of the certificate of a
+// HTTPS Listener. Only the first certificate is used.
+func (n *NGINXController) listenerCertificate(gateway *gatewayv1.Gateway, listener *gatewayv1.Listener) (string, error) {
+	if listener.TLS == nil || len(listener.TLS.CertificateRefs) == 0 {
+		return "", fmt.Errorf("%v listeners require a certificateRef", listener.Protocol)
+	}
+
+	if listener.TLS.Mode != nil && *listener.TLS.Mode != gatewayv1.TLSModeTerminate {
+		return "", fmt.Errorf("TLS mode %v is not supported for %v listeners", *listener.TLS.Mode, listener.Protocol)
+	}
+
+	ref := listener.TLS.CertificateRefs[0]
+	if (ref.Group != nil && *ref.Group != "") || (ref.Kind != nil && *ref.Kind != secretKind) {
+		return "", fmt.Errorf("the certificateRef must reference a Secret")
+	}
+
+	key := fmt.Sprintf("%v/%v", gateway.Namespace, ref.Name)
+	if ref.Namespace != nil && string(*ref.Namespace) != gateway.Namespace {
+		key = fmt.Sprintf("%v/%v", *ref.Namespace, ref.Name)
+		if !n.store.IsSecretReferenceGranted(store.GatewayGroupKind, gateway.Namespace, key) {
+			return "", errRefNotPermitted{fmt.Sprintf("no ReferenceGrant permits the reference to secret %v", key)}
+		}
+	}
+
+	if _, err := n.store.GetLocalSSLCert(key); err != nil {
+		return "", fmt.Errorf("secret %v does not contain a valid certificate: %w", key, err)
+	}
+
+	return key, nil
+}
+
+// listenerAllowsRoute returns true if the Listener allows a Route of the kind
+// and namespace to attach to it
+func listenerAllowsRoute(gateway *gatewayv1.Gateway, listener *gatewayv1.Listener, kind, namespace string) bool {
+	allowed := listener.AllowedRoutes
+	if allowed == nil {
+		return namespace == gateway.Namespace
+	}
+
+	if len(allowed.Kinds) > 0 {
+		found := false
+		for _, k := range allowed.Kinds {
+			if string(k.Kind) == kind && (k.Group == nil || *k.Group == gatewayv1.GroupName) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	from := gatewayv1.NamespacesFromSame
+	if allowed.Namespaces != nil && allowed.Namespaces.From != nil {
+		from = *allowed.Namespaces.From
+	}
+
+	switch from {
+	case gatewayv1.NamespacesFromAll:
+		return true
+	case gatewayv1.NamespacesFromSame:
+		return namespace == gateway.Namespace
+	default:
+		// the namespace selectors are not supported
+		return false
+	}
+}
+
+// hostnameMatches returns the most specific of the hostnames when they
+// match, a wildcard hostname matching the hostnames of its subdomains
+func hostnameMatches(a, b string) (string, bool) {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	switch {
+	case a == b:
+		return a, true
+	case strings.HasPrefix(a, "*.") && strings.HasSuffix(b, a[1:]):
+		return b, true
+	case strings.HasPrefix(b, "*.") && strings.HasSuffix(a, b[1:]):
+		return a, true
+	default:
+		return "", false
+	}
+}
+
+// listenerHostnames returns the hostnames of a Route served by a Listener.
+// An empty hostname matches all the hostnames.
+func listenerHostnames(listenerHostname *gatewayv1.Hostname, routeHostnames []gatewayv1.Hostname) []string {
+	if listenerHostname == nil || *listenerHostname == "" {
+		if len(routeHostnames) == 0 {
+			return []string{""}
+		}
+		hostnames := make([]string, len(routeHostnames))
+		for i, hostname := range routeHostnames {
+			hostnames[i] = string(hostname)
+		}
+		return hostnames
+	}
+
+	if len(routeHostnames) == 0 {
+		return []string{string(*listenerHostname)}
+	}
+
+	var hostnames []string
+	for _, hostname := range routeHostnames {
+		if match, ok := hostnameMatches(string(*listenerHostname), string(hostname)); ok {
+			hostnames = append(hostnames, match)
+		}
+	}
+	return hostnames
+}
+
+// isGatewayParent returns true if the parentRef references a Gateway
+func isGatewayParent(ref *gatewayv1.ParentReference) bool {
+	return (ref.Group == nil || *ref.Group == gatewayv1.GroupName) && (ref.Kind == nil || *ref.Kind == gatewayKind)
+}
+
+// attachRoute attaches a Route to the Listeners of its parents served by the
+// controller. It returns the Listeners the Route is attached to and the
+// status of the Route for these parents.
+func (n *NGINXController) attachRoute(t *gatewayTranslation, gateways map[string]*gatewayv1.Gateway, kind string,
+	route metav1.Object, parentRefs []gatewayv1.ParentReference, hostnames []gatewayv1.Hostname,
+) ([]routeAttachment, []gatewayv1.RouteParentStatus) {
+	var attachments []routeAttachment
+	var parents []gatewayv1.RouteParentStatus
+	for i := range parentRefs {
+		ref := &parentRefs[i]
+		if !isGatewayParent(ref) {
+			continue
+		}
+
+		namespace := route.GetNamespace()
+		if ref.Namespace != nil {
+			namespace = string(*ref.Namespace)
+		}
+		gatewayKey := fmt.Sprintf("%v/%v", namespace, ref.Name)
+		gateway, ok := gateways[gatewayKey]
+		if !ok {
+			continue
+		}
+
+		allowed, attached := false, false
+		for j := range gateway.Spec.Listeners {
+			listener := &gateway.Spec.Listeners[j]
+			if ref.SectionName != nil && *ref.SectionName != listener.Name {
+				continue
+			}
+			if ref.Port != nil && *ref.Port != listener.Port {
+				continue
+			}
+
+			status := t.listeners[gatewayKey][listener.Name]
+			if n.listenerRouteKind(listener.Protocol) != kind || !listenerReady(status) ||
+				!listenerAllowsRoute(gateway, listener, kind, route.GetNamespace()) {
+				continue
+			}
+			allowed = true
+
+			listenerHosts := listenerHostnames(listener.Hostname, hostnames)
+			if len(listenerHosts) == 0 {
+				continue
+			}
+			attached = true
+			status.AttachedRoutes++
+
+			attachments = append(attachments, routeAttachment{
+				gateway:   gateway,
+				listener:  listener,
+				hostnames: listenerHosts,
+			})
+		}
+
+		accepted := gatewayCondition(gatewayv1.RouteConditionAccepted, true, gatewayv1.RouteReasonAccepted,
+			fmt.Sprintf("The %v is served by the ingress controller", kind), route.GetGeneration())
+		switch {
+		case !allowed:
+			accepted = gatewayCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonNotAllowedByListeners,
+				fmt.Sprintf("No listener of the Gateway allows the %v", kind), route.GetGeneration())
+		case !attached:
+			accepted = gatewayCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonNoMatchingListenerHostname,
+				fmt.Sprintf("No listener of the Gateway matches the hostnames of the %v", kind), route.GetGeneration())
+		}
+
+		parents = append(parents, gatewayv1.RouteParentStatus{
+			ParentRef:      *ref,
+			ControllerName: gatewayv1.GatewayController(n.gatewayControllerName()),
+			Conditions:     []metav1.Condition{accepted},
+		})
+	}
+
+	return attachments, parents
+}
+
+// addRoute records the status of a Route for its parents. The Route is not
+// accepted by its parents when it cannot be served.
+func (t *gatewayTranslation) addRoute(route *gatewayRoute, err error, resolvedRefs metav1.Condition) {
+	for i := range route.parents {
+		conditions := route.parents[i].Conditions
+		if err != nil && conditions[0].Status == metav1.ConditionTrue {
+			conditions[0] = gatewayCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonUnsupportedValue,
+				err.Error(), route.object.GetGeneration())
+		}
+		route.parents[i].Conditions = append(conditions, resolvedRefs)
+	}
+
+	t.routes[fmt.Sprintf("%v/%v/%v", route.kind, route.object.GetNamespace(), route.object.GetName())] = route
+}
+
+// translateHTTPRoute attaches the HTTPRoute to the Listeners of its parents
+// and translates it to Ingresses
+func (n *NGINXController) translateHTTPRoute(t *gatewayTranslation, gateways map[string]*gatewayv1.Gateway, route *gatewayv1.HTTPRoute) {
+	attachments, parents := n.attachRoute(t, gateways, httpRouteKind, route, route.Spec.ParentRefs, route.Spec.Hostnames)
+	if len(parents) == 0 {
+		return
+	}
+
+	// hostnames of the route by key of the certificate of their Listener,
+	// empty for the HTTP Listeners
+	hostnames := map[string][]string{}
+	for _, attachment := range attachments {
+		certificate := ""
+		if attachment.listener.Protocol == gatewayv1.HTTPSProtocolType {
+			certificate, _ = n.listenerCertificate(attachment.gateway, attachment.listener)
+		}
+		hostnames[certificate] = append(hostnames[certificate], attachment.hostnames...)
+	}
+
+	ings, err := n.httpRouteIngresses(route, hostnames)
+
+	var backendRefs []gatewayv1.BackendRef
+	for _, rule := range route.Spec.Rules {
+		for _, backendRef := range rule.BackendRefs {
+			backendRefs = append(backendRefs, backendRef.BackendRef)
+		}
+	}
+
+	t.addRoute(&gatewayRoute{
+		resource: store.HTTPRoutesResource,
+		kind:     httpRouteKind,
+		object:   route,
+		status:   &route.Status.RouteStatus,
+		parents:  parents,
+	}, err, n.backendRefsCondition(route, httpRouteKind, backendRefs))
+
+	if err != nil {
+		klog.Warningf("HTTPRoute %v cannot be served: %v", k8s.MetaNamespaceKey(route), err)
+		return
+	}
+	t.ingresses = append(t.ingresses, ings...)
+}
+
+// backendRefsCondition returns the ResolvedRefs condition of a Route, true
+// when its backends are existing Services of its namespace
+func (n *NGINXController) backendRefsCondition(route metav1.Object, kind string, backendRefs []gatewayv1.BackendRef) metav1.Condition {
+	for _, backendRef := range backendRefs {
+		ref := backendRef.BackendObjectReference
+		if (ref.Group != nil && *ref.Group != "") || (ref.Kind != nil && *ref.Kind != serviceKind) {
+			return gatewayCondition(gatewayv1.RouteConditionResolvedRefs, false, gatewayv1.RouteReasonInvalidKind,
+				fmt.Sprintf("backend %v is not a Service", ref.Name), route.GetGeneration())
+		}
+		if ref.Namespace != nil && string(*ref.Namespace) != route.GetNamespace() {
+			return gatewayCondition(gatewayv1.RouteConditionResolvedRefs, false, gatewayv1.RouteReasonRefNotPermitted,
+				fmt.Sprintf("backend %v/%v is not in the namespace of the %v", *ref.Namespace, ref.Name, kind), route.GetGeneration())
+		}
+		if _, err := n.store.GetService(fmt.Sprintf("%v/%v", route.GetNamespace(), ref.Name)); err != nil {
+			return gatewayCondition(gatewayv1.RouteConditionResolvedRefs, false, gatewayv1.RouteReasonBackendNotFound,
+				fmt.Sprintf("service %v not found", ref.Name), route.GetGeneration())
+		}
+	}
+
+	return gatewayCondition(gatewayv1.RouteConditionResolvedRefs, true, gatewayv1.RouteReasonResolvedRefs,
+		fmt.Sprintf("The references of the %v are resolved", kind), route.GetGeneration())
+}
+
+// httpRouteRule is a rule of a HTTPRoute translated to the paths of an
+// Ingress and the settings of their locations
+type httpRouteRule struct {
+	paths    []networking.HTTPIngressPath
+	backends []gatewayv1.HTTPBackendRef
+	weights  []int
+
+	useRegex      bool
+	rewriteTarget string
+	upstreamVhost string
+	redirectURL   string
+	redirectCode  int
+}
+
+// prefixRewrite returns the regular expression of the location of a prefix
+// and the rewrite target replacing it
+func prefixRewrite(prefix, replacement string) (path, target string) {
+	path = regexp.QuoteMeta(strings.TrimSuffix(prefix, "/")) + "(/|$)(.*)"
+
+	replacement = strings.TrimSuffix(replacement, "/")
+	if replacement == "" {
+		return path, "/$2"
+	}
+	return path, replacement + "$1$2"
+}
+
+// translateHTTPRouteRule translates a rule of a HTTPRoute to the paths and
+// the settings of the locations of an Ingress
+func translateHTTPRouteRule(route *gatewayv1.HTTPRoute, rule *gatewayv1.HTTPRouteRule) (*httpRouteRule, error) {
+	r := &httpRouteRule{}
+
+	var replacePrefix *string
+	for _, filter := range rule.Filters {
+		switch {
+		case filter.Type == gatewayv1.HTTPRouteFilterURLRewrite && filter.URLRewrite != nil:
+			if filter.URLRewrite.Hostname != nil {
+				r.upstreamVhost = string(*filter.URLRewrite.Hostname)
+			}
+			if path := filter.URLRewrite.Path; path != nil {
+				switch {
+				case path.Type == gatewayv1.FullPathHTTPPathModifier && path.ReplaceFullPath != nil:
+					r.rewriteTarget = *path.ReplaceFullPath
+				case path.Type == gatewayv1.PrefixMatchHTTPPathModifier && path.ReplacePrefixMatch != nil:
+					replacePrefix = path.ReplacePrefixMatch
+				}
+			}
+		case filter.Type == gatewayv1.HTTPRouteFilterRequestRedirect && filter.RequestRedirect != nil:
+			redirect := filter.RequestRedirect
+			scheme, hostname, port, path := "$scheme", "$host", "", "$request_uri"
+			if redirect.Scheme != nil {
+				scheme = *redirect.Scheme
+			}
+			if redirect.Hostname != nil {
+				hostname = string(*redirect.Hostname)
+			}
+			if redirect.Port != nil {
+				port = fmt.Sprintf(":%v", *redirect.Port)
+			}
+			if redirect.Path != nil {
+				if redirect.Path.Type != gatewayv1.FullPathHTTPPathModifier || redirect.Path.ReplaceFullPath == nil {
+					return nil, errUnsupported{"only the ReplaceFullPath path modifier of the RequestRedirect filter is supported"}
+				}
+				path = *redirect.Path.ReplaceFullPath
+			}
+
+			r.redirectURL = fmt.Sprintf("%v://%v%v%v", scheme, hostname, port, path)
+			r.redirectCode = 302
+			if redirect.StatusCode != nil {
+				r.redirectCode = *redirect.StatusCode
+			}
+			if r.redirectCode != 301 && r.redirectCode != 302 {
+				return nil, errUnsupported{fmt.Sprintf("redirect status code %v is not supported", r.redirectCode)}
+			}
+		default:
+			return nil, errUnsupported{fmt.Sprintf("filter %v is not supported", filter.Type)}
+		}
+	}
+
+	matches := rule.Matches
+	if len(matches) == 0 {
+		matches = []gatewayv1.HTTPRouteMatch{{}}
+	}
+
+	for _, match := range matches {
+		if len(match.Headers) > 0 || len(match.QueryParams) > 0 || match.Method != nil {
+			return nil, errUnsupported{"only the path of the requests can be matched"}
+		}
+
+		matchType, value := gatewayv1.PathMatchPathPrefix, "/"
+		if match.Path != nil && match.Path.Type != nil {
+			matchType = *match.Path.Type
+		}
+		if match.Path != nil && match.Path.Value != nil {
+			value = *match.Path.Value
+		}
+
+		pathType := networking.PathTypePrefix
+		switch matchType {
+		case gatewayv1.PathMatchExact:
+			pathType = networking.PathTypeExact
+		case gatewayv1.PathMatchRegularExpression:
+			pathType = networking.PathTypeImplementationSpecific
+			r.useRegex = true
+		}
+
+		if replacePrefix != nil {
+			if matchType != gatewayv1.PathMatchPathPrefix {
+				return nil, errUnsupported{"the ReplacePrefixMatch path modifier requires PathPrefix matches"}
+			}
+			value, r.rewriteTarget = prefixRewrite(value, *replacePrefix)
+			pathType = networking.PathTypeImplementationSpecific
+			r.useRegex = true
+		}
+
+		r.paths = append(r.paths, networking.HTTPIngressPath{
+			Path:     value,
+			PathType: &pathType,
+		})
+	}
+
+	for _, backendRef := range rule.BackendRefs {
+		if len(backendRef.Filters) > 0 {
+			return nil, errUnsupported{"the filters of the backendRefs are not supported"}
+		}
+
+		ref := backendRef.BackendObjectReference
+		if (ref.Group != nil && *ref.Group != "") || (ref.Kind != nil && *ref.Kind != serviceKind) ||
+			(ref.Namespace != nil && string(*ref.Namespace) != route.Namespace) {
+			continue
+		}
+		if ref.Port == nil {
+			return nil, errUnsupported{fmt.Sprintf("the port of the backend %v is required", ref.Name)}
+		}
+
+		weight := 1
+		if backendRef.Weight != nil {
+			weight = int(*backendRef.Weight)
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		r.backends = append(r.backends, backendRef)
+		r.weights = append(r.weights, weight)
+	}
+
+	if len(r.backends) > 2 {
+		return nil, errUnsupported{"the traffic of a rule can only be split between two backends"}
+	}
+	return r, nil
+}
+
+// httpRouteIngresses translates the rules of a HTTPRoute to Ingresses, for
+// its hostnames by certificate of their Listener. The traffic split between
+// two backends uses a canary Ingress.
+func (n *NGINXController) httpRouteIngresses(route *gatewayv1.HTTPRoute, hostnames map[string][]string) ([]*ingress.Ingress, error) {
+	rules := make([]*httpRouteRule, 0, len(route.Spec.Rules))
+	for i := range route.Spec.Rules {
+		rule, err := translateHTTPRouteRule(route, &route.Spec.Rules[i])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	certificates := make([]string, 0, len(hostnames))
+	for certificate := range hostnames {
+		certificates = append(certificates, certificate)
+	}
+	sort.Strings(certificates)
+
+	var ings []*ingress.Ingress
+	for g, certificate := range certificates {
+		hosts := uniqueStrings(hostnames[certificate])
+
+		for i, rule := range rules {
+			name := fmt.Sprintf("%v-httproute-%d-%d", route.Name, g, i)
+
+			// the redirects never reach a backend
+			if rule.redirectURL != "" {
+				ing, err := n.httpRouteIngress(route, name, hosts, certificate, rule, nil)
+				if err != nil {
+					return nil, err
+				}
+				ings = append(ings, ing)
+				continue
+			}
+
+			if len(rule.backends) == 0 {
+				continue
+			}
+
+			ing, err := n.httpRouteIngress(route, name, hosts, certificate, rule, &rule.backends[0])
+			if err != nil {
+				return nil, err
+			}
+			ings = append(ings, ing)
+
+			if len(rule.backends) == 1 {
+				continue
+			}
+
+			canaryIng, err := n.httpRouteIngress(route, name+"-canary", hosts, certificate, rule, &rule.backends[1])
+			if err != nil {
+				return nil, err
+			}
+			canaryIng.ParsedAnnotations.Canary = canary.Config{
+				Enabled:     true,
+				Weight:      rule.weights[1],
+				WeightTotal: rule.weights[0] + rule.weights[1],
+			}
+			ings = append(ings, canaryIng)
+		}
+	}
+
+	return ings, nil
+}
+
+// httpRouteIngress returns the Ingress of a rule of a HTTPRoute for a backend,
+// without backend for the redirects
+func (n *NGINXController) httpRouteIngress(route *gatewayv1.HTTPRoute, name string, hosts []string, certificate string,
+	rule *httpRouteRule, backendRef *gatewayv1.HTTPBackendRef,
+) (*ingress.Ingress, error) {
+	paths := make([]networking.HTTPIngressPath, len(rule.paths))
+	for i := range rule.paths {
+		paths[i] = rule.paths[i]
+		if backendRef == nil {
+			continue
+		}
+		paths[i].Backend = networking.IngressBackend{
+			Service: &networking.IngressServiceBackend{
+				Name: string(backendRef.Name),
+				Port: networking.ServiceBackendPort{Number: int32(*backendRef.Port)},
+			},
+		}
+	}
+
+	ing := networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         route.Namespace,
+			UID:               route.UID,
+			ResourceVersion:   route.ResourceVersion,
+			CreationTimestamp: route.CreationTimestamp,
+		},
+	}
+
+	var tlsHosts []string
+	for _, host := range hosts {
+		ing.Spec.Rules = append(ing.Spec.Rules, networking.IngressRule{
+			Host: host,
+			IngressRuleValue: networking.IngressRuleValue{
+				HTTP: &networking.HTTPIngressRuleValue{Paths: paths},
+			},
+		})
+		if host != "" {
+			tlsHosts = append(tlsHosts, host)
+		}
+	}
+
+	// the TLS section without secretName uses the default certificate of the
+	// Ingress, the certificate of the Listener
+	if certificate != "" && len(tlsHosts) > 0 {
+		ing.Spec.TLS = []networking.IngressTLS{{Hosts: tlsHosts}}
+	}
+
+	anns, err := n.store.ExtractAnnotations(&ing)
+	if err != nil {
+		return nil, err
+	}
+
+	anns.ClassDefaultSSLCertificate = certificate
+	anns.Rewrite.UseRegex = rule.useRegex
+	anns.Rewrite.Target = rule.rewriteTarget
+	anns.UpstreamVhost = rule.upstreamVhost
+	if rule.redirectURL != "" {
+		anns.Redirect.URL = rule.redirectURL
+		anns.Redirect.Code = rule.redirectCode
+	}
+
+	return &ingress.Ingress{
+		Ingress:           ing,
+		ParsedAnnotations: anns,
+	}, nil
+}
+
+// uniqueStrings returns the strings without duplicates, in their order
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// syncGatewayStatus updates the status of the GatewayClasses, the Gateways
+// and the Routes served by the controller. It runs on the leader only, the
+// fields of the status not owned by the controller, like the addresses of
+// the Gateways, are kept.
+func (n *NGINXController) syncGatewayStatus(t *gatewayTranslation) {
+	for _, class := range t.classes {
+		conditions := append([]metav1.Condition(nil), class.Status.Conditions...)
+		meta.SetStatusCondition(&conditions, gatewayCondition(gatewayv1.GatewayClassConditionStatusAccepted, true,
+			gatewayv1.GatewayClassReasonAccepted, "The GatewayClass is managed by the ingress controller", class.Generation))
+		if reflect.DeepEqual(conditions, class.Status.Conditions) {
+			continue
+		}
+
+		class.Status.Conditions = conditions
+		if err := n.updateGatewayStatus(store.GatewayClassesResource, "", class); err != nil {
+			klog.Warningf("Error updating the status of GatewayClass %q: %v", class.Name, err)
+		}
+	}
+
+	for _, gateway := range t.gateways {
+		key := k8s.MetaNamespaceKey(gateway)
+		status := gatewayv1.GatewayStatus{
+			Conditions: append([]metav1.Condition(nil), gateway.Status.Conditions...),
+		}
+		meta.SetStatusCondition(&status.Conditions, gatewayCondition(gatewayv1.GatewayConditionAccepted, true,
+			gatewayv1.GatewayReasonAccepted, "The Gateway is served by the ingress controller", gateway.Generation))
+		meta.SetStatusCondition(&status.Conditions, gatewayCondition(gatewayv1.GatewayConditionProgrammed, true,
+			gatewayv1.GatewayReasonProgrammed, "The Gateway is programmed", gateway.Generation))
+
+		for i := range gateway.Spec.Listeners {
+			listener := t.listeners[key][gateway.Spec.Listeners[i].Name]
+			var conditions []metav1.Condition
+			for j := range gateway.Status.Listeners {
+				if gateway.Status.Listeners[j].Name == listener.Name {
+					conditions = append(conditions, gateway.Status.Listeners[j].Conditions...)
+				}
+			}
+			for _, condition := range listener.Conditions {
+				meta.SetStatusCondition(&conditions, condition)
+			}
+			listener.Conditions = conditions
+			status.Listeners = append(status.Listeners, *listener)
+		}
+
+		if reflect.DeepEqual(status, gateway.Status) {
+			continue
+		}
+
+		gateway.Status = status
+		if err := n.updateGatewayStatus(store.GatewaysResource, gateway.Namespace, gateway); err != nil {
+			klog.Warningf("Error updating the status of Gateway %q: %v", key, err)
+		}
+	}
+
+	controllerName := gatewayv1.GatewayController(n.gatewayControllerName())
+	for key, route := range t.routes {
+		// the status of the parents of other controllers is kept
+		var status []gatewayv1.RouteParentStatus
+		for _, parent := range route.status.Parents {
+			if parent.ControllerName != controllerName {
+				status = append(status, parent)
+			}
+		}
+
+		for _, parent := range route.parents {
+			var conditions []metav1.Condition
+			for _, current := range route.status.Parents {
+				if current.ControllerName == controllerName && reflect.DeepEqual(current.ParentRef, parent.ParentRef) {
+					conditions = append(conditions, current.Conditions...)
+				}
+			}
+			for _, condition := range parent.Conditions {
+				meta.SetStatusCondition(&conditions, condition)
+			}
+			parent.Conditions = conditions
+			status = append(status, parent)
+		}
+
+		if reflect.DeepEqual(status, route.status.Parents) {
+			continue
+		}
+
+		route.status.Parents = status
+		if err := n.updateGatewayStatus(route.resource, route.object.GetNamespace(), route.object); err != nil {
+			klog.Warningf("Error updating the status of %v: %v", key, err)
+		}
+	}
+}
+
+func (n *NGINXController) updateGatewayStatus(resource schema.GroupVersionResource, namespace string, obj interface{}) error {
+	if n.cfg.GatewayClient == nil {
+		return nil
+	}
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.cfg.GatewayClient.Resource(resource).Namespace(namespace).
+		UpdateStatus(context.TODO(), &unstructured.Unstructured{Object: u}, metav1.UpdateOptions{})
+	return err
+}
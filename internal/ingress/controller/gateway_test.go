@@ -0,0 +1,472 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+)
+
+func TestListenerHostnames(t *testing.T) {
+	hostname := func(h gatewayv1.Hostname) *gatewayv1.Hostname { return &h }
+
+	testCases := []struct {
+		name     string
+		listener *gatewayv1.Hostname
+		route    []gatewayv1.Hostname
+		expected []string
+	}{
+		{"no hostnames", nil, nil, []string{""}},
+		{"hostnames of the route", nil, []gatewayv1.Hostname{"foo.bar"}, []string{"foo.bar"}},
+		{"hostname of the listener", hostname("foo.bar"), nil, []string{"foo.bar"}},
+		{"wildcard listener", hostname("*.bar"), []gatewayv1.Hostname{"foo.bar", "foo.baz", "*.bar"}, []string{"foo.bar", "*.bar"}},
+		{"wildcard route", hostname("foo.bar"), []gatewayv1.Hostname{"*.bar", "*.baz"}, []string{"foo.bar"}},
+		{"no matching hostname", hostname("foo.bar"), []gatewayv1.Hostname{"bar.foo"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if hostnames := listenerHostnames(tc.listener, tc.route); !reflect.DeepEqual(hostnames, tc.expected) {
+				t.Errorf("expected hostnames %v but got %v", tc.expected, hostnames)
+			}
+		})
+	}
+}
+
+func TestListenerAllowsRoute(t *testing.T) {
+	gateway := &gatewayv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "infra"}}
+	from := func(f gatewayv1.FromNamespaces) *gatewayv1.AllowedRoutes {
+		return &gatewayv1.AllowedRoutes{Namespaces: &gatewayv1.RouteNamespaces{From: &f}}
+	}
+
+	testCases := []struct {
+		name      string
+		allowed   *gatewayv1.AllowedRoutes
+		kind      string
+		namespace string
+		expected  bool
+	}{
+		{"same namespace by default", nil, httpRouteKind, "infra", true},
+		{"other namespace by default", nil, httpRouteKind, "apps", false},
+		{"all namespaces", from(gatewayv1.NamespacesFromAll), httpRouteKind, "apps", true},
+		{"namespace selector", from(gatewayv1.NamespacesFromSelector), httpRouteKind, "infra", false},
+		{"other kind", &gatewayv1.AllowedRoutes{Kinds: []gatewayv1.RouteGroupKind{{Kind: "TCPRoute"}}}, httpRouteKind, "infra", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := &gatewayv1.Listener{Name: "http", AllowedRoutes: tc.allowed}
+			if allowed := listenerAllowsRoute(gateway, listener, tc.kind, tc.namespace); allowed != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestListenerCertificateReferenceGrant(t *testing.T) {
+	namespace := gatewayv1.Namespace("certificates")
+	gateway := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+		Spec: gatewayv1.GatewaySpec{
+			GatewayClassName: "nginx",
+			Listeners: []gatewayv1.Listener{{
+				Name:     "https",
+				Port:     443,
+				Protocol: gatewayv1.HTTPSProtocolType,
+				TLS: &gatewayv1.GatewayTLSConfig{
+					CertificateRefs: []gatewayv1.SecretObjectReference{{Name: "tls", Namespace: &namespace}},
+				},
+			}},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		grantedSecrets []string
+		reason         gatewayv1.ListenerConditionReason
+	}{
+		{"without ReferenceGrant", nil, gatewayv1.ListenerReasonRefNotPermitted},
+		// the fake store does not contain the certificate
+		{"with ReferenceGrant", []string{"certificates/tls"}, gatewayv1.ListenerReasonInvalidCertificateRef},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &NGINXController{
+				cfg: &Configuration{
+					ListenPorts: &ngx_config.ListenPorts{HTTP: 80, HTTPS: 443},
+				},
+				store: &fakeIngressStore{grantedSecrets: tc.grantedSecrets},
+			}
+
+			status := n.listenerStatus(gateway, &gateway.Spec.Listeners[0])
+			resolvedRefs := meta.FindStatusCondition(status.Conditions, string(gatewayv1.ListenerConditionResolvedRefs))
+			if resolvedRefs == nil || resolvedRefs.Reason != string(tc.reason) {
+				t.Errorf("expected the reason %v of the ResolvedRefs condition, got %+v", tc.reason, resolvedRefs)
+			}
+		})
+	}
+}
+
+func TestTranslateGateways(t *testing.T) {
+	port := gatewayv1.PortNumber(8080)
+	canaryPort := gatewayv1.PortNumber(8081)
+	weight := int32(1)
+	canaryWeight := int32(3)
+	prefix := gatewayv1.PathMatchPathPrefix
+	value := "/api"
+	replacement := "/"
+
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "default", Generation: 2},
+		Spec: gatewayv1.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1.CommonRouteSpec{
+				ParentRefs: []gatewayv1.ParentReference{{Name: "gateway"}, {Name: "other"}},
+			},
+			Hostnames: []gatewayv1.Hostname{"foo.bar"},
+			Rules: []gatewayv1.HTTPRouteRule{
+				{
+					Matches: []gatewayv1.HTTPRouteMatch{{Path: &gatewayv1.HTTPPathMatch{Type: &prefix, Value: &value}}},
+					Filters: []gatewayv1.HTTPRouteFilter{{
+						Type: gatewayv1.HTTPRouteFilterURLRewrite,
+						URLRewrite: &gatewayv1.HTTPURLRewriteFilter{
+							Path: &gatewayv1.HTTPPathModifier{
+								Type:               gatewayv1.PrefixMatchHTTPPathModifier,
+								ReplacePrefixMatch: &replacement,
+							},
+						},
+					}},
+					BackendRefs: []gatewayv1.HTTPBackendRef{
+						{BackendRef: gatewayv1.BackendRef{
+							BackendObjectReference: gatewayv1.BackendObjectReference{Name: "api", Port: &port},
+							Weight:                 &weight,
+						}},
+						{BackendRef: gatewayv1.BackendRef{
+							BackendObjectReference: gatewayv1.BackendObjectReference{Name: "api-canary", Port: &canaryPort},
+							Weight:                 &canaryWeight,
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	n := &NGINXController{
+		cfg: &Configuration{
+			EnableGatewayAPI:          true,
+			IngressClassConfiguration: &ingressclass.Configuration{Controller: "k8s.io/ingress-nginx"},
+			ListenPorts:               &ngx_config.ListenPorts{HTTP: 80, HTTPS: 443},
+		},
+		store: &fakeIngressStore{
+			gatewayClasses: []*gatewayv1.GatewayClass{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+					Spec:       gatewayv1.GatewayClassSpec{ControllerName: "k8s.io/ingress-nginx"},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "other"},
+					Spec:       gatewayv1.GatewayClassSpec{ControllerName: "example.com/other"},
+				},
+			},
+			gateways: []*gatewayv1.Gateway{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+					Spec: gatewayv1.GatewaySpec{
+						GatewayClassName: "nginx",
+						Listeners: []gatewayv1.Listener{
+							{Name: "http", Port: 80, Protocol: gatewayv1.HTTPProtocolType},
+							{Name: "other-port", Port: 8080, Protocol: gatewayv1.HTTPProtocolType},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+					Spec: gatewayv1.GatewaySpec{
+						GatewayClassName: "other",
+						Listeners:        []gatewayv1.Listener{{Name: "http", Port: 80, Protocol: gatewayv1.HTTPProtocolType}},
+					},
+				},
+			},
+			httpRoutes: []*gatewayv1.HTTPRoute{route},
+		},
+	}
+
+	translation := n.translateGateways()
+
+	if len(translation.classes) != 1 || len(translation.gateways) != 1 {
+		t.Fatalf("expected the GatewayClass and the Gateway of the controller, got %v and %v", len(translation.classes), len(translation.gateways))
+	}
+
+	listeners := translation.listeners["default/gateway"]
+	if listeners["http"].AttachedRoutes != 1 || !listenerReady(listeners["http"]) {
+		t.Errorf("expected the HTTPRoute to be attached to the ready listener http, got %+v", listeners["http"])
+	}
+	if listenerReady(listeners["other-port"]) {
+		t.Errorf("expected the listener on a port other than the HTTP port not to be ready")
+	}
+
+	parents := translation.routes["HTTPRoute/default/route"].parents
+	if len(parents) != 1 || parents[0].ParentRef.Name != "gateway" {
+		t.Fatalf("expected the status of the HTTPRoute for the Gateway of the controller only, got %+v", parents)
+	}
+	if parents[0].Conditions[0].Type != string(gatewayv1.RouteConditionAccepted) || parents[0].Conditions[0].Status != metav1.ConditionTrue {
+		t.Errorf("expected the HTTPRoute to be accepted, got %+v", parents[0].Conditions[0])
+	}
+
+	if len(translation.ingresses) != 2 {
+		t.Fatalf("expected an Ingress and its canary, got %v Ingresses", len(translation.ingresses))
+	}
+
+	ing := translation.ingresses[0]
+	if len(ing.Spec.Rules) != 1 || ing.Spec.Rules[0].Host != "foo.bar" {
+		t.Fatalf("expected a rule for the host foo.bar, got %+v", ing.Spec.Rules)
+	}
+	path := ing.Spec.Rules[0].HTTP.Paths[0]
+	if path.Path != "/api(/|$)(.*)" || *path.PathType != networking.PathTypeImplementationSpecific {
+		t.Errorf("expected the regular expression of the prefix, got %v", path.Path)
+	}
+	if path.Backend.Service.Name != "api" || path.Backend.Service.Port.Number != int32(port) {
+		t.Errorf("expected the backend api:%v, got %+v", port, path.Backend.Service)
+	}
+	if !ing.ParsedAnnotations.Rewrite.UseRegex || ing.ParsedAnnotations.Rewrite.Target != "/$2" {
+		t.Errorf("expected the rewrite of the prefix, got %+v", ing.ParsedAnnotations.Rewrite)
+	}
+
+	canaryIng := translation.ingresses[1]
+	if canaryIng.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name != "api-canary" {
+		t.Errorf("expected the canary Ingress to route to api-canary")
+	}
+	c := canaryIng.ParsedAnnotations.Canary
+	if !c.Enabled || c.Weight != 3 || c.WeightTotal != 4 {
+		t.Errorf("expected the canary to receive 3 of 4 requests, got %+v", c)
+	}
+}
+
+func TestTranslateHTTPRouteRuleUnsupported(t *testing.T) {
+	route := &gatewayv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "default"}}
+	method := gatewayv1.HTTPMethodPost
+	code := 308
+
+	testCases := map[string]gatewayv1.HTTPRouteRule{
+		"header match": {
+			Matches: []gatewayv1.HTTPRouteMatch{{Headers: []gatewayv1.HTTPHeaderMatch{{Name: "foo", Value: "bar"}}}},
+		},
+		"query parameter match": {
+			Matches: []gatewayv1.HTTPRouteMatch{{QueryParams: []gatewayv1.HTTPQueryParamMatch{{Name: "foo", Value: "bar"}}}},
+		},
+		"method match": {
+			Matches: []gatewayv1.HTTPRouteMatch{{Method: &method}},
+		},
+		"redirect status code": {
+			Filters: []gatewayv1.HTTPRouteFilter{{
+				Type:            gatewayv1.HTTPRouteFilterRequestRedirect,
+				RequestRedirect: &gatewayv1.HTTPRequestRedirectFilter{StatusCode: &code},
+			}},
+		},
+	}
+
+	for name, rule := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := translateHTTPRouteRule(route, &rule); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestHTTPRouteRedirectWithoutBackend(t *testing.T) {
+	scheme := "https"
+	code := 301
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "default"},
+		Spec: gatewayv1.HTTPRouteSpec{
+			Rules: []gatewayv1.HTTPRouteRule{{
+				Filters: []gatewayv1.HTTPRouteFilter{{
+					Type:            gatewayv1.HTTPRouteFilterRequestRedirect,
+					RequestRedirect: &gatewayv1.HTTPRequestRedirectFilter{Scheme: &scheme, StatusCode: &code},
+				}},
+			}},
+		},
+	}
+
+	n := &NGINXController{
+		cfg:   &Configuration{},
+		store: &fakeIngressStore{},
+	}
+
+	ings, err := n.httpRouteIngresses(route, map[string][]string{"": {"foo.bar"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ings) != 1 {
+		t.Fatalf("expected an Ingress for the redirect, got %v", len(ings))
+	}
+
+	path := ings[0].Spec.Rules[0].HTTP.Paths[0]
+	if path.Backend.Service != nil {
+		t.Errorf("expected a path without backend, got %+v", path.Backend.Service)
+	}
+	redirect := ings[0].ParsedAnnotations.Redirect
+	if redirect.URL != "https://$host$request_uri" || redirect.Code != 301 {
+		t.Errorf("expected the permanent redirect to HTTPS, got %+v", redirect)
+	}
+}
+
+func TestTranslateStreamRoutes(t *testing.T) {
+	port := gatewayv1.PortNumber(5432)
+	passthrough := gatewayv1.TLSModePassthrough
+	now := metav1.Now()
+	older := metav1.NewTime(now.Add(-time.Hour))
+
+	backendRefs := []gatewayv1.BackendRef{{BackendObjectReference: gatewayv1.BackendObjectReference{Name: "db", Port: &port}}}
+	parentRefs := []gatewayv1.ParentReference{{Name: "gateway"}}
+
+	tlsRoute := func(name string, created metav1.Time, hostnames ...gatewayv1.Hostname) *gatewayv1alpha2.TLSRoute {
+		return &gatewayv1alpha2.TLSRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: created},
+			Spec: gatewayv1alpha2.TLSRouteSpec{
+				CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: parentRefs},
+				Hostnames:       hostnames,
+				Rules:           []gatewayv1alpha2.TLSRouteRule{{BackendRefs: backendRefs}},
+			},
+		}
+	}
+
+	n := &NGINXController{
+		cfg: &Configuration{
+			EnableGatewayAPI:             true,
+			EnableExperimentalGatewayAPI: true,
+			EnableSSLPassthrough:         true,
+			IngressClassConfiguration:    &ingressclass.Configuration{Controller: "k8s.io/ingress-nginx"},
+			ListenPorts:                  &ngx_config.ListenPorts{HTTP: 80, HTTPS: 443},
+		},
+		store: &fakeIngressStore{
+			gatewayClasses: []*gatewayv1.GatewayClass{{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+				Spec:       gatewayv1.GatewayClassSpec{ControllerName: "k8s.io/ingress-nginx"},
+			}},
+			gateways: []*gatewayv1.Gateway{{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+				Spec: gatewayv1.GatewaySpec{
+					GatewayClassName: "nginx",
+					Listeners: []gatewayv1.Listener{
+						{Name: "tcp", Port: 5432, Protocol: gatewayv1.TCPProtocolType},
+						{Name: "tls", Port: 8443, Protocol: gatewayv1.TLSProtocolType, TLS: &gatewayv1.GatewayTLSConfig{Mode: &passthrough}},
+						{Name: "tls-terminate", Port: 9443, Protocol: gatewayv1.TLSProtocolType},
+						{Name: "tls-https", Port: 443, Protocol: gatewayv1.TLSProtocolType, TLS: &gatewayv1.GatewayTLSConfig{Mode: &passthrough}},
+						{Name: "reserved", Port: 443, Protocol: gatewayv1.TCPProtocolType},
+					},
+				},
+			}},
+			tcpRoutes: []*gatewayv1alpha2.TCPRoute{{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec: gatewayv1alpha2.TCPRouteSpec{
+					CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: []gatewayv1.ParentReference{{Name: "gateway", SectionName: func(s gatewayv1.SectionName) *gatewayv1.SectionName { return &s }("tcp")}}},
+					Rules:           []gatewayv1alpha2.TCPRouteRule{{BackendRefs: backendRefs}},
+				},
+			}},
+			tlsRoutes: []*gatewayv1alpha2.TLSRoute{
+				tlsRoute("conflicting", now, "DB.example.com"),
+				tlsRoute("db", older, "db.example.com"),
+				tlsRoute("cache", now, "cache.example.com"),
+			},
+			services: map[string]*corev1.Service{
+				"default/db": {ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}},
+			},
+		},
+	}
+
+	translation := n.translateGateways()
+
+	listeners := translation.listeners["default/gateway"]
+	if !listenerReady(listeners["tcp"]) || !listenerReady(listeners["tls"]) || !listenerReady(listeners["tls-https"]) {
+		t.Errorf("expected the TCP and TLS passthrough listeners to be ready")
+	}
+	if listenerReady(listeners["tls-terminate"]) || listenerReady(listeners["reserved"]) {
+		t.Errorf("expected the TLS listener terminating TLS and the listener on a reserved port not to be ready")
+	}
+
+	accepted := func(key string) bool {
+		route, ok := translation.routes[key]
+		return ok && route.parents[0].Conditions[0].Status == metav1.ConditionTrue
+	}
+	if !accepted("TCPRoute/default/db") || !accepted("TLSRoute/default/db") || !accepted("TLSRoute/default/cache") {
+		t.Errorf("expected the TCPRoute and the TLSRoutes to be accepted")
+	}
+	if accepted("TLSRoute/default/conflicting") {
+		t.Errorf("expected the TLSRoute using the hostname of an older TLSRoute not to be accepted")
+	}
+
+	if len(translation.streamServices) != 2 {
+		t.Fatalf("expected the stream services of the ports 5432 and 8443, got %v", len(translation.streamServices))
+	}
+	for _, svc := range translation.streamServices {
+		switch svc.Port {
+		case 5432:
+			if svc.Backend.Name != "db" || len(svc.SNIServices) != 0 {
+				t.Errorf("expected the TCP service of the TCPRoute, got %+v", svc)
+			}
+		case 8443:
+			if len(svc.SNIServices) != 2 || svc.SNIServices[0].Hostnames[0] != "cache.example.com" || svc.SNIServices[1].Hostnames[0] != "db.example.com" {
+				t.Errorf("expected the SNI services of the TLSRoutes cache and db, got %+v", svc.SNIServices)
+			}
+		default:
+			t.Errorf("unexpected stream service on port %v", svc.Port)
+		}
+	}
+
+	if len(translation.passthroughBackends) != 2 {
+		t.Fatalf("expected the SSL passthrough backends of the TLSRoutes cache and db on the HTTPS port, got %v", len(translation.passthroughBackends))
+	}
+	for _, backend := range translation.passthroughBackends {
+		if backend.Backend != "" || (backend.Hostname != "cache.example.com" && backend.Hostname != "db.example.com") {
+			t.Errorf("unexpected SSL passthrough backend %+v", backend)
+		}
+	}
+}
+
+func TestCheckStreamListenerPortHTTPS(t *testing.T) {
+	n := &NGINXController{
+		cfg: &Configuration{
+			ListenPorts: &ngx_config.ListenPorts{HTTP: 80, HTTPS: 443},
+		},
+		store: &fakeIngressStore{},
+	}
+
+	if err := n.checkStreamListenerPort(gatewayv1.TLSProtocolType, 443); err == nil {
+		t.Errorf("expected an error for a TLS listener on the HTTPS port without SSL passthrough")
+	}
+
+	n.cfg.EnableSSLPassthrough = true
+	if err := n.checkStreamListenerPort(gatewayv1.TLSProtocolType, 443); err != nil {
+		t.Errorf("unexpected error for a TLS listener on the HTTPS port with SSL passthrough: %v", err)
+	}
+	if err := n.checkStreamListenerPort(gatewayv1.TCPProtocolType, 443); err == nil {
+		t.Errorf("expected an error for a TCP listener on the HTTPS port")
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	klog "k8s.io/klog/v2"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// gatewayRouteOlder returns true if the Route a was created before b. A port
+// or a hostname used by several Routes belongs to the oldest one.
+func gatewayRouteOlder(a, b metav1.Object) bool {
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return fmt.Sprintf("%v/%v", a.GetNamespace(), a.GetName()) < fmt.Sprintf("%v/%v", b.GetNamespace(), b.GetName())
+}
+
+// checkStreamListenerPort returns an error if the port of a TLS or TCP
+// Listener is used by the controller or by another stream service. The TLS
+// Listeners of the HTTPS port are served by the SSL passthrough listener.
+func (n *NGINXController) checkStreamListenerPort(protocol gatewayv1.ProtocolType, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %d", port)
+	}
+	if protocol == gatewayv1.TLSProtocolType && port == n.cfg.ListenPorts.HTTPS {
+		if !n.cfg.EnableSSLPassthrough {
+			return fmt.Errorf("TLS listeners on the HTTPS port %d require --enable-ssl-passthrough", port)
+		}
+		return nil
+	}
+	if n.reservedStreamPorts().Has(port) {
+		return fmt.Errorf("port %d is reserved for the ingress controller", port)
+	}
+	if configmapName, ports := n.getConfigMapStreamPorts(apiv1.ProtocolTCP); ports.Has(port) {
+		return fmt.Errorf("TCP port %d is already used by the ConfigMap %q", port, configmapName)
+	}
+
+	if n.cfg.EnableStreamRoutes {
+		for _, route := range n.store.ListStreamRoutes() {
+			if streamRouteProtocol(route) == apiv1.ProtocolTCP && int(route.Spec.Port) == port {
+				return fmt.Errorf("TCP port %d is already used by the StreamRoute %q", port, k8s.MetaNamespaceKey(route))
+			}
+		}
+	}
+
+	return nil
+}
+
+// translateStreamRoutes translates the TCPRoutes and the TLSRoutes attached
+// to the Gateways of the controller to TCP services. The TLSRoutes are routed
+// by the server name of the connections, without terminating TLS.
+func (n *NGINXController) translateStreamRoutes(t *gatewayTranslation, gateways map[string]*gatewayv1.Gateway) {
+	tcpRoutes := n.store.ListTCPRoutes()
+	sort.SliceStable(tcpRoutes, func(i, j int) bool {
+		return gatewayRouteOlder(tcpRoutes[i], tcpRoutes[j])
+	})
+	for _, route := range tcpRoutes {
+		attachments, parents := n.attachRoute(t, gateways, tcpRouteKind, route, route.Spec.ParentRefs, nil)
+		if len(parents) == 0 {
+			continue
+		}
+
+		var backendRefs []gatewayv1.BackendRef
+		for _, rule := range route.Spec.Rules {
+			backendRefs = append(backendRefs, rule.BackendRefs...)
+		}
+
+		n.translateStreamRoute(t, &gatewayRoute{
+			resource: store.TCPRoutesResource,
+			kind:     tcpRouteKind,
+			object:   route,
+			status:   &route.Status.RouteStatus,
+			parents:  parents,
+		}, attachments, backendRefs, false)
+	}
+
+	tlsRoutes := n.store.ListTLSRoutes()
+	sort.SliceStable(tlsRoutes, func(i, j int) bool {
+		return gatewayRouteOlder(tlsRoutes[i], tlsRoutes[j])
+	})
+	for _, route := range tlsRoutes {
+		attachments, parents := n.attachRoute(t, gateways, tlsRouteKind, route, route.Spec.ParentRefs, route.Spec.Hostnames)
+		if len(parents) == 0 {
+			continue
+		}
+
+		var backendRefs []gatewayv1.BackendRef
+		for _, rule := range route.Spec.Rules {
+			backendRefs = append(backendRefs, rule.BackendRefs...)
+		}
+
+		n.translateStreamRoute(t, &gatewayRoute{
+			resource: store.TLSRoutesResource,
+			kind:     tlsRouteKind,
+			object:   route,
+			status:   &route.Status.RouteStatus,
+			parents:  parents,
+		}, attachments, backendRefs, true)
+	}
+
+	t.streamServices = groupSNIServices(apiv1.ProtocolTCP, t.streamServices)
+}
+
+// translateStreamRoute translates a TCPRoute or a TLSRoute to the TCP
+// services of the ports of its Listeners, routing the connections by server
+// name when sni is true.
+func (n *NGINXController) translateStreamRoute(t *gatewayTranslation, route *gatewayRoute, attachments []routeAttachment,
+	backendRefs []gatewayv1.BackendRef, sni bool,
+) {
+	resolvedRefs := n.backendRefsCondition(route.object, route.kind, backendRefs)
+
+	var svcs []ingress.L4Service
+	var passthroughBackends []*ingress.SSLPassthroughBackend
+	err := checkStreamRouteBackends(route.kind, backendRefs)
+	if err == nil && resolvedRefs.Status == metav1.ConditionTrue {
+		svcs, passthroughBackends, err = n.streamRouteServices(t, route, attachments, backendRefs[0], sni)
+	}
+	t.addRoute(route, err, resolvedRefs)
+
+	if err != nil {
+		klog.Warningf("%v %v/%v cannot be served: %v", route.kind, route.object.GetNamespace(), route.object.GetName(), err)
+		return
+	}
+	t.streamServices = append(t.streamServices, svcs...)
+	t.passthroughBackends = append(t.passthroughBackends, passthroughBackends...)
+}
+
+// checkStreamRouteBackends returns an error if the backends of a TCPRoute or
+// a TLSRoute cannot be served
+func checkStreamRouteBackends(kind string, backendRefs []gatewayv1.BackendRef) error {
+	if len(backendRefs) != 1 {
+		return errUnsupported{fmt.Sprintf("%vs must have exactly one backendRef", kind)}
+	}
+	if backendRefs[0].Port == nil {
+		return errUnsupported{fmt.Sprintf("the port of the backend %v is required", backendRefs[0].Name)}
+	}
+	return nil
+}
+
+// streamRouteServices returns the TCP services of a Route for the ports of
+// its Listeners, and claims their hostnames, empty for the connections
+// without a matching server name. The hostnames of the HTTPS port are served
+// by SSL passthrough backends, they cannot be empty or wildcards.
+func (n *NGINXController) streamRouteServices(t *gatewayTranslation, route *gatewayRoute, attachments []routeAttachment,
+	backendRef gatewayv1.BackendRef, sni bool,
+) ([]ingress.L4Service, []*ingress.SSLPassthroughBackend, error) {
+	key := fmt.Sprintf("%v/%v/%v", route.kind, route.object.GetNamespace(), route.object.GetName())
+	hostnames := map[int32][]string{}
+	for _, attachment := range attachments {
+		port := int32(attachment.listener.Port)
+		if !sni {
+			hostnames[port] = append(hostnames[port], "")
+			continue
+		}
+		for _, hostname := range attachment.hostnames {
+			hostnames[port] = append(hostnames[port], strings.ToLower(hostname))
+		}
+	}
+
+	ports := make([]int, 0, len(hostnames))
+	for port := range hostnames {
+		for _, hostname := range hostnames[port] {
+			if owner, ok := t.streamClaims[port][hostname]; ok && owner != key {
+				if hostname == "" {
+					return nil, nil, fmt.Errorf("port %d is already used by %v", port, owner)
+				}
+				return nil, nil, fmt.Errorf("hostname %v of port %d is already used by %v", hostname, port, owner)
+			}
+			if int(port) == n.cfg.ListenPorts.HTTPS && (hostname == "" || strings.HasPrefix(hostname, "*.")) {
+				return nil, nil, errUnsupported{fmt.Sprintf("%vs on the HTTPS port %d require hostnames without wildcard", route.kind, port)}
+			}
+		}
+		ports = append(ports, int(port))
+	}
+	sort.Ints(ports)
+
+	svc, err := n.gatewayStreamService(route.object.GetNamespace(), backendRef)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	svcs := make([]ingress.L4Service, 0, len(ports))
+	var passthroughBackends []*ingress.SSLPassthroughBackend
+	for _, port := range ports {
+		portHostnames := uniqueStrings(hostnames[int32(port)])
+		if t.streamClaims[int32(port)] == nil {
+			t.streamClaims[int32(port)] = map[string]string{}
+		}
+		for _, hostname := range portHostnames {
+			t.streamClaims[int32(port)][hostname] = key
+		}
+
+		if port == n.cfg.ListenPorts.HTTPS {
+			for _, hostname := range portHostnames {
+				passthroughBackends = append(passthroughBackends, &ingress.SSLPassthroughBackend{
+					Hostname:  hostname,
+					Port:      svc.Backend.Port,
+					Service:   svc.Service,
+					Endpoints: svc.Endpoints,
+				})
+			}
+			continue
+		}
+
+		portSvc := *svc
+		portSvc.Port = port
+		// the connections without a matching server name are routed to the
+		// Route without hostnames
+		if !sets.NewString(portHostnames...).Has("") {
+			sort.Strings(portHostnames)
+			portSvc.Hostnames = portHostnames
+		}
+		svcs = append(svcs, portSvc)
+	}
+
+	return svcs, passthroughBackends, nil
+}
+
+// gatewayPassthroughBackends returns the SSL passthrough backends of the
+// TLSRoutes of the HTTPS port whose hostnames are not served by an Ingress.
+// The servers of the Ingresses take precedence.
+func gatewayPassthroughBackends(hosts sets.Set[string], backends []*ingress.SSLPassthroughBackend) []*ingress.SSLPassthroughBackend {
+	passthroughBackends := make([]*ingress.SSLPassthroughBackend, 0, len(backends))
+	for _, backend := range backends {
+		if hosts.Has(backend.Hostname) {
+			klog.Warningf("Hostname %v of the HTTPS port is served by an Ingress, ignoring its TLSRoute", backend.Hostname)
+			continue
+		}
+		passthroughBackends = append(passthroughBackends, backend)
+	}
+	return passthroughBackends
+}
+
+// gatewayStreamService returns the TCP service of the backend of a Route
+func (n *NGINXController) gatewayStreamService(namespace string, ref gatewayv1.BackendRef) (*ingress.L4Service, error) {
+	svcKey := fmt.Sprintf("%v/%v", namespace, ref.Name)
+	svc, err := n.store.GetService(svcKey)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Service %q: %w", svcKey, err)
+	}
+
+	svcPort := strconv.Itoa(int(*ref.Port))
+	return &ingress.L4Service{
+		Backend: ingress.L4Backend{
+			Name:      string(ref.Name),
+			Namespace: namespace,
+			Port:      intstr.FromString(svcPort),
+			Protocol:  apiv1.ProtocolTCP,
+		},
+		Endpoints: n.getStreamServiceEndpoints(svc, svcPort, apiv1.ProtocolTCP),
+		Service:   svc,
+	}, nil
+}
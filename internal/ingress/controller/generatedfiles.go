@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customerrorpages"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/njs"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+// writeGeneratedFiles writes the files generated from the annotations which
+// are referenced by the locations of the configuration. The lock of the
+// generated files must be held until the configuration is tested.
+func (n *NGINXController) writeGeneratedFiles(pcfg *ingress.Configuration) error {
+	for _, server := range pcfg.Servers {
+		for _, location := range server.Locations {
+			if err := modsecurity.WriteWAFPolicyRules(&location.ModSecurity); err != nil {
+				return err
+			}
+			if err := customerrorpages.WritePages(&location.CustomErrorPages); err != nil {
+				return err
+			}
+			if err := njs.WriteScripts(&location.NJS); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// generatedFiles returns the files and directories generated from the
+// annotations which are referenced by the locations of the configuration
+func generatedFiles(pcfg *ingress.Configuration) []string {
+	files := sets.New[string]()
+	for _, server := range pcfg.Servers {
+		for _, location := range server.Locations {
+			files.Insert(location.ModSecurity.WAFPolicySetupFile, location.ModSecurity.WAFPolicyExclusionsFile)
+			files.Insert(location.CustomErrorPages.Directory, location.NJS.Directory)
+		}
+	}
+	files.Delete("")
+
+	return sets.List(files)
+}
+
+// removeUnusedGeneratedFiles removes the files generated from the
+// annotations which are not referenced by the running configuration nor by
+// the configuration snapshots, which are rolled back with their files
+func (n *NGINXController) removeUnusedGeneratedFiles(pcfg *ingress.Configuration) {
+	n.generatedFilesLock.Lock()
+	defer n.generatedFilesLock.Unlock()
+
+	used := sets.New(generatedFiles(pcfg)...)
+	if n.snapshots != nil {
+		snapshots, err := n.snapshots.List()
+		if err != nil {
+			// the files of the snapshots cannot be told apart from the unused ones
+			klog.Warningf("Error listing the configuration snapshots, the unused generated files are kept: %v", err)
+			return
+		}
+		for _, s := range snapshots {
+			used.Insert(s.GeneratedFiles...)
+		}
+	}
+
+	removeUnusedFiles(file.ModSecurityDirectory, used)
+	removeUnusedFiles(file.ErrorPagesDirectory, used)
+	removeUnusedFiles(file.NJSDirectory, used)
+}
+
+// removeUnusedFiles removes the entries of the directory which are not used
+func removeUnusedFiles(directory string, used sets.Set[string]) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("Error listing the generated files of %v: %v", directory, err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(directory, entry.Name())
+		if used.Has(path) {
+			continue
+		}
+		klog.V(3).InfoS("Removing unused generated file", "path", path)
+		if err := os.RemoveAll(path); err != nil {
+			klog.Warningf("Error removing the generated file %v: %v", path, err)
+		}
+	}
+}
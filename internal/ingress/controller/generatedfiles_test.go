@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customerrorpages"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/njs"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+func TestGeneratedFiles(t *testing.T) {
+	pcfg := &ingress.Configuration{
+		Servers: []*ingress.Server{
+			{
+				Locations: []*ingress.Location{
+					{
+						ModSecurity: modsecurity.Config{
+							WAFPolicySetupFile:      "/etc/nginx/modsecurity/default-policy-setup.conf",
+							WAFPolicyExclusionsFile: "/etc/nginx/modsecurity/default-policy-exclusions.conf",
+						},
+						CustomErrorPages: customerrorpages.Config{Directory: "/etc/nginx/error-pages/default-pages-0a1b2c"},
+					},
+					{
+						NJS: njs.Config{Directory: "/etc/ingress-controller/njs/default-scripts-3d4e5f"},
+					},
+					{},
+				},
+			},
+		},
+	}
+
+	expected := []string{
+		"/etc/ingress-controller/njs/default-scripts-3d4e5f",
+		"/etc/nginx/error-pages/default-pages-0a1b2c",
+		"/etc/nginx/modsecurity/default-policy-exclusions.conf",
+		"/etc/nginx/modsecurity/default-policy-setup.conf",
+	}
+	if files := generatedFiles(pcfg); !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v but got %v", expected, files)
+	}
+}
+
+func TestRemoveUnusedFiles(t *testing.T) {
+	directory := t.TempDir()
+	for _, name := range []string{"used.conf", "unused.conf"} {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte("rules"), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(directory, "unused"), 0o700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	removeUnusedFiles(directory, sets.New[string](filepath.Join(directory, "used.conf")))
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "used.conf" {
+		t.Errorf("expected only the used file to be kept but got %v", entries)
+	}
+
+	// a missing directory has no unused files
+	removeUnusedFiles(filepath.Join(directory, "missing"), sets.New[string]())
+}
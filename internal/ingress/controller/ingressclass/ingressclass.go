@@ -27,6 +27,11 @@ const (
 
 	// DefaultAnnotationValue defines the default annotation value for the ingress-nginx controller
 	DefaultAnnotationValue = "nginx"
+
+	// ConfigMapAnnotation references, in the form namespace/name, the
+	// ConfigMap overriding the global configuration for the Ingresses of an
+	// IngressClass
+	ConfigMapAnnotation = "ingress.nginx.kubernetes.io/configmap"
 )
 
 // Configuration defines the various aspects of IngressClass parsing
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// testErrorLocationRegex matches the file and the line of the directive
+// rejected by nginx -t
+var testErrorLocationRegex = regexp.MustCompile(`in (\S+):(\d+)`)
+
+// testError is returned when NGINX rejects a configuration
+type testError struct {
+	err    error
+	output string
+	// file is the path of the tested configuration
+	file   string
+	config []byte
+}
+
+func (e *testError) Error() string {
+	// this error is different from the rest because it must be clear why nginx is not working
+	return fmt.Sprintf(`
+-------------------------------------------------------------------------------
+Error: %v
+%v
+-------------------------------------------------------------------------------
+`, e.err, e.output)
+}
+
+// invalidIngress returns the Ingress that produced the directive rejected by
+// NGINX, or nil when the error cannot be attributed to a single Ingress
+func invalidIngress(e *testError, servers []*ingress.Server) *ingress.Ingress {
+	m := testErrorLocationRegex.FindStringSubmatch(e.output)
+	if m == nil || m[1] != e.file {
+		return nil
+	}
+
+	line, err := strconv.Atoi(m[2])
+	lines := strings.Split(string(e.config), "\n")
+	if err != nil || line < 1 || line > len(lines) {
+		return nil
+	}
+
+	// the closest marker before the line tells which part of the
+	// configuration it belongs to
+	serverLevel := false
+	for i := line - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(l, "## start location ") && !serverLevel:
+			idx := strings.LastIndex(l, " of ingress ")
+			if idx == -1 {
+				return nil
+			}
+			return ingressByKey(servers, l[idx+len(" of ingress "):])
+		case strings.HasPrefix(l, "## end location "):
+			serverLevel = true
+		case strings.HasPrefix(l, "## start server "):
+			return serverSnippetOwner(servers, strings.TrimPrefix(l, "## start server "))
+		case strings.HasPrefix(l, "## end server "):
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ingressByKey returns the Ingress with the namespace/name key used by the
+// locations of the servers
+func ingressByKey(servers []*ingress.Server, key string) *ingress.Ingress {
+	for _, s := range servers {
+		for _, loc := range s.Locations {
+			if loc.Ingress != nil && fmt.Sprintf("%v/%v", loc.Ingress.Namespace, loc.Ingress.Name) == key {
+				return loc.Ingress
+			}
+		}
+	}
+
+	return nil
+}
+
+// serverSnippetOwner returns the Ingress whose server-snippet annotation is
+// used by the server. Other server level directives are not attributed to
+// an Ingress.
+func serverSnippetOwner(servers []*ingress.Server, hostname string) *ingress.Ingress {
+	for _, s := range servers {
+		if s.Hostname != hostname || s.ServerSnippet == "" {
+			continue
+		}
+
+		for _, loc := range s.Locations {
+			ing := loc.Ingress
+			if ing != nil && ing.ParsedAnnotations != nil && ing.ParsedAnnotations.ServerSnippet == s.ServerSnippet {
+				return ing
+			}
+		}
+	}
+
+	return nil
+}
+
+// excludeInvalidIngresses removes the Ingresses rejected by NGINX from the
+// Ingresses to configure. They are configured again once updated.
+func (n *NGINXController) excludeInvalidIngresses(ings []*ingress.Ingress) []*ingress.Ingress {
+	if len(n.invalidIngresses) == 0 {
+		return ings
+	}
+
+	invalid := make(map[string]string)
+	valid := make([]*ingress.Ingress, 0, len(ings))
+	for _, ing := range ings {
+		key := fmt.Sprintf("%v/%v", ing.Namespace, ing.Name)
+		if resourceVersion, ok := n.invalidIngresses[key]; ok && resourceVersion == ing.ResourceVersion {
+			invalid[key] = resourceVersion
+			continue
+		}
+
+		valid = append(valid, ing)
+	}
+
+	n.invalidIngresses = invalid
+	return valid
+}
+
+// reportInvalidIngress excludes the Ingress that produced the configuration
+// rejected by NGINX until it is updated, and emits a warning Event on it, so
+// a single invalid Ingress does not block the updates of the other ones
+func (n *NGINXController) reportInvalidIngress(e *testError, servers []*ingress.Server) {
+	ing := invalidIngress(e, servers)
+	if ing == nil {
+		return
+	}
+
+	key := fmt.Sprintf("%v/%v", ing.Namespace, ing.Name)
+	klog.Warningf("Excluding Ingress %q from the configuration until it is updated, NGINX rejected it: %v", key, e.output)
+
+	if n.invalidIngresses == nil {
+		n.invalidIngresses = make(map[string]string)
+	}
+	n.invalidIngresses[key] = ing.ResourceVersion
+
+	n.recorder.Eventf(&ing.Ingress, apiv1.EventTypeWarning, "InvalidConfiguration",
+		"Excluded from the configuration until updated, NGINX rejected it: %v", strings.TrimSpace(e.output))
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+const invalidTestConfig = `http {
+	## start server example.com
+	server {
+		server_name example.com ;
+		# Custom code snippet configured for host example.com
+		invalid_server_directive;
+		## start location / of ingress default/example
+		location / {
+			set $namespace      "default";
+			invalid_location_directive;
+		}
+		## end location / of ingress default/example
+		## start location /other of ingress default/other
+		location /other {
+			set $namespace      "default";
+		}
+		## end location /other of ingress default/other
+		invalid_directive_after_locations;
+	}
+	## end server example.com
+	invalid_http_directive;
+}
+`
+
+func newInvalidTestIngress(name, serverSnippet string) *ingress.Ingress {
+	return &ingress.Ingress{
+		Ingress: networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault, ResourceVersion: "1"},
+		},
+		ParsedAnnotations: &annotations.Ingress{ServerSnippet: serverSnippet},
+	}
+}
+
+func TestInvalidIngress(t *testing.T) {
+	example := newInvalidTestIngress("example", "")
+	other := newInvalidTestIngress("other", "invalid_server_directive;")
+	servers := []*ingress.Server{
+		{
+			Hostname:      "example.com",
+			ServerSnippet: "invalid_server_directive;",
+			Locations: []*ingress.Location{
+				{Path: "/", Ingress: example},
+				{Path: "/other", Ingress: other},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		output   string
+		expected *ingress.Ingress
+	}{
+		{"location", `nginx: [emerg] unknown directive "invalid_location_directive" in /tmp/nginx/nginx-cfg1:10`, example},
+		{"server snippet", `nginx: [emerg] unknown directive "invalid_server_directive" in /tmp/nginx/nginx-cfg1:6`, other},
+		{"after the locations", `nginx: [emerg] unknown directive "invalid_directive_after_locations" in /tmp/nginx/nginx-cfg1:18`, other},
+		{"outside of the servers", `nginx: [emerg] unknown directive "invalid_http_directive" in /tmp/nginx/nginx-cfg1:21`, nil},
+		{"other file", `nginx: [emerg] unknown directive "invalid" in /etc/nginx/modsecurity/modsecurity.conf:10`, nil},
+		{"no location", `nginx: [emerg] could not build server_names_hash`, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &testError{
+				err:    errors.New("exit status 1"),
+				output: tc.output,
+				file:   "/tmp/nginx/nginx-cfg1",
+				config: []byte(invalidTestConfig),
+			}
+
+			if actual := invalidIngress(e, servers); actual != tc.expected {
+				t.Errorf("expected Ingress %v but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReportInvalidIngress(t *testing.T) {
+	example := newInvalidTestIngress("example", "")
+	other := newInvalidTestIngress("other", "")
+	servers := []*ingress.Server{
+		{
+			Hostname: "example.com",
+			Locations: []*ingress.Location{
+				{Path: "/", Ingress: example},
+				{Path: "/other", Ingress: other},
+			},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{recorder: recorder}
+
+	n.reportInvalidIngress(&testError{
+		err:    errors.New("exit status 1"),
+		output: `nginx: [emerg] unknown directive "invalid_location_directive" in /tmp/nginx/nginx-cfg1:10`,
+		file:   "/tmp/nginx/nginx-cfg1",
+		config: []byte(invalidTestConfig),
+	}, servers)
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning InvalidConfiguration") || !strings.Contains(event, "invalid_location_directive") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Fatalf("expected an Event on the invalid Ingress")
+	}
+
+	ings := n.excludeInvalidIngresses([]*ingress.Ingress{example, other})
+	if len(ings) != 1 || ings[0] != other {
+		t.Errorf("expected the invalid Ingress to be excluded but got %v", ings)
+	}
+
+	// the Ingress is configured again once updated
+	updated := newInvalidTestIngress("example", "")
+	updated.ResourceVersion = "2"
+	ings = n.excludeInvalidIngresses([]*ingress.Ingress{updated, other})
+	if len(ings) != 2 {
+		t.Errorf("expected the updated Ingress to be configured again but got %v", ings)
+	}
+	if len(n.invalidIngresses) != 0 {
+		t.Errorf("expected no invalid Ingress but got %v", n.invalidIngresses)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	networking "k8s.io/api/networking/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
 )
 
@@ -106,3 +107,14 @@ func needsRewrite(location *ingress.Location) bool {
 
 	return false
 }
+
+// isLiteralCaseInsensitivePath returns true if the path matches the requests
+// regardless of their case and is not a regular expression. These paths are
+// lowercased, so that the same paths with different cases are one location.
+func isLiteralCaseInsensitivePath(pathType *networking.PathType, config rewrite.Config) bool {
+	if !config.CaseInsensitivePaths || config.UseRegex || config.Target != "" {
+		return false
+	}
+
+	return pathType != nil && (*pathType == pathTypePrefix || *pathType == pathTypeExact)
+}
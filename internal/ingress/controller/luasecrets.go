@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/klog/v2"
+)
+
+// getLuaSecrets returns the values of the Secrets referenced by the
+// configuration ConfigMap, by name in the Lua modules. The values which
+// cannot be read are left out, the Lua modules then disable the features
+// needing them.
+func (n *NGINXController) getLuaSecrets() map[string]string {
+	cfg := n.store.GetBackendConfiguration()
+
+	values := make(map[string]string)
+	for _, luaSecret := range cfg.LuaSecrets() {
+		secret, err := n.store.GetSecret(luaSecret.Secret)
+		if err != nil {
+			klog.Warningf("Error reading the Secret %v of %v: %v", luaSecret.Secret, luaSecret.Name, err)
+			continue
+		}
+
+		value := secret.Data[luaSecret.Key]
+		if len(value) == 0 {
+			klog.Warningf("The Secret %v of %v has no %v key", luaSecret.Secret, luaSecret.Name, luaSecret.Key)
+			continue
+		}
+
+		values[luaSecret.Name] = string(value)
+	}
+
+	return values
+}
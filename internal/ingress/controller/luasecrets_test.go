@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func TestGetLuaSecrets(t *testing.T) {
+	n := &NGINXController{
+		store: &fakeIngressStore{
+			configuration: ngx_config.Configuration{
+				CanaryStickySecret:   "default/canary",
+				BotDetectionSecret:   "default/bot-detection",
+				CrowdSecAPIKeySecret: "default/crowdsec",
+				CSRFSecret:           "default/csrf",
+			},
+			secrets: map[string]*corev1.Secret{
+				"default/canary":        {Data: map[string][]byte{"secret": []byte("signing-key")}},
+				"default/bot-detection": {Data: map[string][]byte{"secret": []byte("challenge-key")}},
+				"default/crowdsec":      {Data: map[string][]byte{"api-key": []byte("bouncer-key")}},
+				"default/csrf":          {Data: map[string][]byte{"secret": []byte("token-key")}},
+			},
+		},
+	}
+
+	expected := map[string]string{
+		"canary_sticky_secret": "signing-key",
+		"bot_detection_secret": "challenge-key",
+		"crowdsec_api_key":     "bouncer-key",
+		"csrf_secret":          "token-key",
+	}
+	if values := n.getLuaSecrets(); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v but returned %v", expected, values)
+	}
+
+	n.store = &fakeIngressStore{
+		configuration: ngx_config.Configuration{CanaryStickySecret: "default/canary"},
+		secrets: map[string]*corev1.Secret{
+			"default/canary": {Data: map[string][]byte{"key": []byte("signing-key")}},
+		},
+	}
+	if values := n.getLuaSecrets(); len(values) != 0 {
+		t.Errorf("expected no values without the secret key but returned %v", values)
+	}
+
+	n.store = &fakeIngressStore{
+		configuration: ngx_config.Configuration{CanaryStickySecret: "default/missing"},
+	}
+	if values := n.getLuaSecrets(); len(values) != 0 {
+		t.Errorf("expected no values without the Secret but returned %v", values)
+	}
+}
@@ -18,42 +18,50 @@ package controller
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
-	"strconv"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"text/template"
 	"time"
 	"unicode"
 
-	proxyproto "github.com/armon/go-proxyproto"
 	"github.com/eapache/channels"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/flowcontrol"
-	"k8s.io/ingress-nginx/pkg/tcpproxy"
 
 	adm_controller "k8s.io/ingress-nginx/internal/admission/controller"
+	"k8s.io/ingress-nginx/internal/ingress/acme"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/process"
+	"k8s.io/ingress-nginx/internal/ingress/controller/snapshot"
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
 	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/ingress/rollout"
 	"k8s.io/ingress-nginx/internal/ingress/status"
+	"k8s.io/ingress-nginx/internal/k8s"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/net/dns"
 	"k8s.io/ingress-nginx/internal/net/ssl"
@@ -86,7 +94,9 @@ func NewNGINXController(config *Configuration, mc metric.Collector) *NGINXContro
 	}
 
 	n := &NGINXController{
-		isIPV6Enabled: ing_net.IsIPv6Enabled(),
+		isIPV6Enabled:   ing_net.IsIPv6Enabled(),
+		isNJSAvailable:  nginx.IsModuleAvailable(nginx.NJSModule),
+		isZstdAvailable: nginx.IsModuleAvailable(nginx.ZstdModule),
 
 		resolver:         h,
 		cfg:              config,
@@ -106,13 +116,19 @@ func NewNGINXController(config *Configuration, mc metric.Collector) *NGINXContro
 
 		runningConfig: new(ingress.Configuration),
 
-		Proxy: &tcpproxy.TCPProxy{},
-
 		metricCollector: mc,
 
 		command: NewNginxCommand(),
 	}
 
+	if n.cfg.AdmissionValidation != nil && n.cfg.AdmissionValidation.Mode == ValidationModeSandbox {
+		n.sandbox = newSandbox(NewNginxCommand().Binary, n.cfg.AdmissionValidation)
+	}
+
+	if n.cfg.AdmissionCacheSize > 0 {
+		n.admissionCache = newAdmissionCache(n.cfg.AdmissionCacheSize)
+	}
+
 	if n.cfg.ValidationWebhook != "" {
 		n.validationWebhookServer = &http.Server{
 			Addr: config.ValidationWebhook,
@@ -127,6 +143,13 @@ func NewNGINXController(config *Configuration, mc metric.Collector) *NGINXContro
 		}
 	}
 
+	// the claims of the hosts are stored with the other objects of the
+	// leader election
+	var hostClaims string
+	if config.HostOwnershipPolicy != "" && config.HostOwnershipPolicy != store.HostOwnershipMerge {
+		hostClaims = k8s.IngressPodDetails.Namespace + "/" + config.ElectionID + store.HostClaimsConfigMapSuffix
+	}
+
 	n.store = store.New(
 		config.Namespace,
 		config.WatchNamespaceSelector,
@@ -136,13 +159,30 @@ func NewNGINXController(config *Configuration, mc metric.Collector) *NGINXContro
 		config.DefaultSSLCertificate,
 		config.ResyncPeriod,
 		config.Client,
+		config.WatchReferencedSecrets,
+		config.StreamRouteClient,
+		config.ClassParamsClient,
+		config.GatewayClient,
+		config.EnableExperimentalGatewayAPI,
+		config.EnableReferenceGrants,
+		config.HostOwnershipClient,
+		config.HostOwnershipPolicy,
+		hostClaims,
+		config.WAFPolicyClient,
+		config.DenylistClient,
+		config.RedirectMapClient,
 		n.updateCh,
 		config.DisableCatchAll,
 		config.DeepInspector,
 		config.IngressClassConfiguration,
+		config.Sharding,
 		config.DisableSyncEvents)
 
-	n.syncQueue = task.NewTaskQueue(n.syncIngress)
+	if config.ConfigurationSnapshots > 0 {
+		n.snapshots = snapshot.NewStore(file.SnapshotsDirectory, config.ConfigurationSnapshots)
+	}
+
+	n.syncQueue = task.NewDebouncedTaskQueue(n.syncIngress, config.SyncQuietPeriod, config.SyncMaxDelay)
 
 	if config.UpdateStatus {
 		n.syncStatus = status.NewStatusSyncer(status.Config{
@@ -157,6 +197,37 @@ func NewNGINXController(config *Configuration, mc metric.Collector) *NGINXContro
 		klog.Warning("Update of Ingress status is disabled (flag --update-status)")
 	}
 
+	if config.EnableCanaryRollout {
+		if config.EnableMetrics && config.MetricsGatherer != nil {
+			n.canaryRollout = rollout.NewController(rollout.Config{
+				Client:          config.Client,
+				IngressLister:   n.store,
+				Gatherer:        config.MetricsGatherer,
+				PeerMetricsPort: config.ListenPorts.Health,
+				CanaryLabelLimited: func() bool {
+					cfg := n.store.GetBackendConfiguration()
+					return cfg.MetricsLabelLimited("canary")
+				},
+				Recorder: n.recorder,
+			})
+		} else {
+			klog.Warning("Progressive canary rollout requires metrics (flag --enable-metrics), disabling it")
+		}
+	}
+
+	if config.EnableACME {
+		n.acmeController = acme.NewController(acme.Config{
+			Client:         config.Client,
+			IngressLister:  n.store,
+			DirectoryURL:   config.ACMEDirectoryURL,
+			Email:          config.ACMEEmail,
+			Namespace:      k8s.IngressPodDetails.Namespace,
+			Name:           config.ElectionID,
+			ChallengeStore: acme.NewNGINXChallengeStore(),
+			Recorder:       n.recorder,
+		})
+	}
+
 	onTemplateChange := func() {
 		template, err := ngx_template.NewTemplate(nginx.TemplatePath)
 		if err != nil {
@@ -229,6 +300,30 @@ type NGINXController struct {
 
 	syncStatus status.Syncer
 
+	// isLeader is set while the controller is the elected leader, or
+	// always when the leader election is disabled. The status of the
+	// resources is only written by the leader.
+	isLeader atomic.Bool
+
+	// generatedFilesLock keeps the files generated from the annotations
+	// while the candidate configurations referencing them are tested
+	generatedFilesLock sync.Mutex
+
+	canaryRollout rollout.Controller
+
+	acmeController acme.Controller
+
+	// ocspFailing contains the hosts reported as failing OCSP stapling
+	ocspFailing sets.Set[string]
+
+	// expiringCertificates contains the certificates reported as expiring
+	expiringCertificates sets.Set[string]
+
+	// invalidIngresses contains the resource version of the Ingresses
+	// excluded from the configuration after NGINX rejected it, by
+	// namespace/name
+	invalidIngresses map[string]string
+
 	syncRateLimiter flowcontrol.RateLimiter
 
 	workersReloading bool
@@ -247,15 +342,32 @@ type NGINXController struct {
 	// runningConfig contains the running configuration in the Backend
 	runningConfig *ingress.Configuration
 
+	// renderedConfigurationHash is the hash of the configuration files of
+	// the last reload
+	renderedConfigurationHash [sha256.Size]byte
+
+	// syncLock serializes the syncs and the rollbacks to a snapshot
+	syncLock sync.Mutex
+
+	// snapshots keeps the last configurations applied, nil when disabled
+	snapshots *snapshot.Store
+
+	// rolledBack is the ID of the snapshot the configuration was rolled
+	// back to, the syncs are paused until they are resumed
+	rolledBack string
+
 	t ngx_template.Writer
 
 	resolver []net.IP
 
 	isIPV6Enabled bool
 
-	isShuttingDown bool
+	// isNJSAvailable and isZstdAvailable are true when the NGINX image was
+	// built with the njs and Zstandard modules
+	isNJSAvailable  bool
+	isZstdAvailable bool
 
-	Proxy *tcpproxy.TCPProxy
+	isShuttingDown bool
 
 	store store.Storer
 
@@ -264,6 +376,14 @@ type NGINXController struct {
 	validationWebhookServer *http.Server
 
 	command NginxExecTester
+
+	// sandbox tests the candidate configurations of the admission controller
+	// in the sandbox validation mode, nil otherwise
+	sandbox *sandbox
+
+	// admissionCache keeps the results of the admission controller, nil when
+	// disabled
+	admissionCache *admissionCache
 }
 
 // Start starts a new NGINX master process running in the foreground.
@@ -289,6 +409,22 @@ func (n *NGINXController) Start() {
 					go n.syncStatus.Run(stopCh)
 				}
 
+				n.isLeader.Store(true)
+				// write the status of the resources the previous leader
+				// could have left behind
+				n.syncQueue.EnqueueTask(task.GetDummyObject("leader-elected"))
+
+				if n.canaryRollout != nil {
+					go n.canaryRollout.Run(stopCh)
+				}
+
+				if n.acmeController != nil {
+					go n.acmeController.Run(stopCh)
+				}
+
+				go wait.Until(n.checkCertificateExpiry, certificateExpiryCheckInterval, stopCh)
+				go wait.Until(n.checkOCSPStatus, ocspCheckInterval, stopCh)
+
 				n.metricCollector.OnStartedLeading(electionID)
 				// manually update SSL expiration metrics
 				// (to not wait for a reload)
@@ -296,11 +432,34 @@ func (n *NGINXController) Start() {
 				n.metricCollector.SetSSLInfo(n.runningConfig.Servers)
 			},
 			OnStoppedLeading: func() {
+				n.isLeader.Store(false)
 				n.metricCollector.OnStoppedLeading(electionID)
 			},
 		})
 	}
 
+	if n.cfg.DisableLeaderElection && n.canaryRollout != nil {
+		go n.canaryRollout.Run(n.stopCh)
+	}
+
+	if n.cfg.DisableLeaderElection {
+		n.isLeader.Store(true)
+		go wait.Until(n.checkCertificateExpiry, certificateExpiryCheckInterval, n.stopCh)
+		go wait.Until(n.checkOCSPStatus, ocspCheckInterval, n.stopCh)
+	}
+
+	if n.acmeController != nil {
+		go n.acmeController.RunChallenges(n.stopCh)
+		if n.cfg.DisableLeaderElection {
+			go n.acmeController.Run(n.stopCh)
+		}
+	}
+
+	if nginx.MaxmindUpdateInterval > 0 && nginx.MaxmindEditionIDs != "" &&
+		(nginx.MaxmindLicenseKey != "" || nginx.MaxmindMirror != "") {
+		go nginx.UpdateGeoLite2DB(n.stopCh)
+	}
+
 	cmd := n.command.ExecCommand()
 
 	// put NGINX in another process group to prevent it
@@ -310,10 +469,6 @@ func (n *NGINXController) Start() {
 		Pgid:    0,
 	}
 
-	if n.cfg.EnableSSLPassthrough {
-		n.setupSSLProxy()
-	}
-
 	klog.InfoS("Starting NGINX process")
 	n.start(cmd)
 
@@ -333,6 +488,8 @@ func (n *NGINXController) Start() {
 		}
 	}()
 
+	go n.serveAdmin()
+
 	if n.validationWebhookServer != nil {
 		klog.InfoS("Starting validation webhook", "address", n.validationWebhookServer.Addr,
 			"certPath", n.cfg.ValidationWebhookCertPath, "keyPath", n.cfg.ValidationWebhookKeyPath)
@@ -361,6 +518,9 @@ func (n *NGINXController) Start() {
 
 			if evt, ok := event.(store.Event); ok {
 				klog.V(3).InfoS("Event received", "type", evt.Type, "object", evt.Obj)
+				if n.admissionCache != nil {
+					n.admissionCache.invalidate()
+				}
 				if evt.Type == store.ConfigurationEvent {
 					// TODO: is this necessary? Consider removing this special case
 					n.syncQueue.EnqueueTask(task.GetDummyObject("configmap-change"))
@@ -405,9 +565,16 @@ func (n *NGINXController) Stop() error {
 		}
 	}
 
+	// NGINX is stopped without waiting for the requests in flight past their
+	// grace period
+	signal := "quit"
+	if n.drainEnabled() && !n.drain() {
+		signal = "stop"
+	}
+
 	// send stop signal to NGINX
 	klog.InfoS("Stopping NGINX process")
-	cmd := n.command.ExecCommand("-s", "quit")
+	cmd := n.command.ExecCommand("-s", signal)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	err := cmd.Run()
@@ -455,44 +622,6 @@ func (n *NGINXController) DefaultEndpoint() ingress.Endpoint {
 //
 //nolint:gocritic // the cfg shouldn't be changed, and shouldn't be mutated by other processes while being rendered.
 func (n *NGINXController) generateTemplate(cfg ngx_config.Configuration, ingressCfg ingress.Configuration) ([]byte, error) {
-	if n.cfg.EnableSSLPassthrough {
-		servers := []*tcpproxy.TCPServer{}
-		for _, pb := range ingressCfg.PassthroughBackends {
-			svc := pb.Service
-			if svc == nil {
-				klog.Warningf("Missing Service for SSL Passthrough backend %q", pb.Backend)
-				continue
-			}
-			port, err := strconv.Atoi(pb.Port.String()) // #nosec
-			if err != nil {
-				for _, sp := range svc.Spec.Ports {
-					if sp.Name == pb.Port.String() {
-						port = int(sp.Port)
-						break
-					}
-				}
-			} else {
-				for _, sp := range svc.Spec.Ports {
-					//nolint:gosec // Ignore G109 error
-					if sp.Port == int32(port) {
-						port = int(sp.Port)
-						break
-					}
-				}
-			}
-
-			// TODO: Allow PassthroughBackends to specify they support proxy-protocol
-			servers = append(servers, &tcpproxy.TCPServer{
-				Hostname:      pb.Hostname,
-				IP:            svc.Spec.ClusterIP,
-				Port:          port,
-				ProxyProtocol: false,
-			})
-		}
-
-		n.Proxy.ServerList = servers
-	}
-
 	// NGINX cannot resize the hash tables used to store server names. For
 	// this reason we check if the current size is correct for the host
 	// names defined in the Ingress rules and adjust the value if
@@ -595,6 +724,7 @@ func (n *NGINXController) generateTemplate(cfg ngx_config.Configuration, ingress
 	cfg.SSLDHParam = sslDHParam
 
 	cfg.DefaultSSLCertificate = n.getDefaultSSLCertificate()
+	cfg.SSLRejectHandshake = ingressCfg.SSLRejectHandshake
 
 	if n.cfg.IsChroot {
 		if cfg.AccessLogPath == "/var/log/nginx/access.log" {
@@ -620,8 +750,12 @@ func (n *NGINXController) generateTemplate(cfg ngx_config.Configuration, ingress
 		NginxStatusIpv6Whitelist: cfg.NginxStatusIpv6Whitelist,
 		RedirectServers:          utilingress.BuildRedirects(ingressCfg.Servers),
 		IsSSLPassthroughEnabled:  n.cfg.EnableSSLPassthrough,
+		IsNJSAvailable:           n.isNJSAvailable,
+		IsZstdAvailable:          n.isZstdAvailable,
 		ListenPorts:              n.cfg.ListenPorts,
 		EnableMetrics:            n.cfg.EnableMetrics,
+		EnableACME:               n.cfg.EnableACME,
+		EnableDrain:              n.drainEnabled(),
 		MaxmindEditionFiles:      n.cfg.MaxmindEditionFiles,
 		HealthzURI:               nginx.HealthPath,
 		MonitorMaxBatchSize:      n.cfg.MonitorMaxBatchSize,
@@ -632,6 +766,15 @@ func (n *NGINXController) generateTemplate(cfg ngx_config.Configuration, ingress
 		StreamSnippets:           append(ingressCfg.StreamSnippets, cfg.StreamSnippet),
 	}
 
+	if n.snapshots != nil {
+		tc.AdminSocket = nginx.AdminSocket
+		tc.SnapshotsPath = nginx.SnapshotsPath
+	}
+
+	if n.cfg.OTLPLogsEndpoint != "" || n.cfg.SyslogForwardAddress != "" {
+		tc.LogForwardingAddress = n.cfg.InternalLoggerAddress
+	}
+
 	tc.Cfg.Checksum = ingressCfg.ConfigurationChecksum
 
 	return n.t.Write(tc)
@@ -655,15 +798,12 @@ func (n *NGINXController) testTemplate(cfg []byte) error {
 	}
 	out, err := n.command.Test(tmpfile.Name())
 	if err != nil {
-		// this error is different from the rest because it must be clear why nginx is not working
-		oe := fmt.Sprintf(`
--------------------------------------------------------------------------------
-Error: %v
-%v
--------------------------------------------------------------------------------
-`, err, string(out))
-
-		return errors.New(oe)
+		return &testError{
+			err:    err,
+			output: string(out),
+			file:   tmpfile.Name(),
+			config: cfg,
+		}
 	}
 
 	os.Remove(tmpfile.Name())
@@ -673,47 +813,68 @@ Error: %v
 // OnUpdate is called by the synchronization loop whenever configuration
 // changes were detected. The received backend Configuration is merged with the
 // configuration ConfigMap before generating the final configuration file.
-// Returns nil in case the backend was successfully reloaded.
+// Returns true in case the backend was successfully reloaded, and false when
+// the reload was skipped because the rendered configuration did not change.
 //
 //nolint:gocritic // the cfg shouldn't be changed, and shouldn't be mutated by other processes while being rendered.
-func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
+func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) (bool, error) {
 	cfg := n.store.GetBackendConfiguration()
 	cfg.Resolver = n.resolver
 
 	workerSerialReloads := cfg.WorkerSerialReloads
 	if workerSerialReloads && n.workersReloading {
-		return errors.New("worker reload already in progress, requeuing reload")
+		return false, errors.New("worker reload already in progress, requeuing reload")
 	}
 
 	content, err := n.generateTemplate(cfg, ingressCfg)
 	if err != nil {
-		return err
+		return false, &reloadError{reason: reloadFailureTemplate, err: err}
 	}
 
-	err = createOpentelemetryCfg(&cfg)
+	otelContent, err := buildOpentelemetryCfg(&cfg)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	// changes of the model that do not change the rendered configuration,
+	// like some annotations, do not require a reload. The session ticket key
+	// and the DH parameters are only referenced by path in nginx.conf, but
+	// NGINX reads them again on reload.
+	hash := renderedConfigurationHash(content, otelContent, []byte(cfg.SSLSessionTicketKey), n.sslDHParam(cfg.SSLDHParam))
+	if hash == n.renderedConfigurationHash {
+		// keep the checksum of the running model in nginx.conf up to date
+		return false, os.WriteFile(cfgPath, content, file.ReadWriteByUser)
 	}
 
-	err = n.testTemplate(content)
+	err = os.WriteFile(cfg.OpentelemetryConfig, otelContent, file.ReadWriteByUser)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	n.generatedFilesLock.Lock()
+	err = n.writeGeneratedFiles(&ingressCfg)
+	if err == nil {
+		err = n.testTemplate(content)
+	}
+	n.generatedFilesLock.Unlock()
+	if err != nil {
+		return false, err
 	}
 
 	if klog.V(2).Enabled() {
 		src, err := os.ReadFile(cfgPath)
 		if err != nil {
-			return err
+			return false, err
 		}
 		if !bytes.Equal(src, content) {
 			tmpfile, err := os.CreateTemp("", "new-nginx-cfg")
 			if err != nil {
-				return err
+				return false, err
 			}
 			defer tmpfile.Close()
 			err = os.WriteFile(tmpfile.Name(), content, file.ReadWriteByUser)
 			if err != nil {
-				return err
+				return false, err
 			}
 			//nolint:gosec //Ignore G204 error
 			diffOutput, err := exec.Command("diff", "-I", "'# Configuration.*'", "-u", cfgPath, tmpfile.Name()).CombinedOutput()
@@ -739,20 +900,60 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 
 	err = os.WriteFile(cfgPath, content, file.ReadWriteByUser)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	o, err := n.command.ExecCommand("-s", "reload").CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("%v\n%v", err, string(o))
+		return false, &reloadError{reason: reloadFailureSignal, err: fmt.Errorf("%v\n%v", err, string(o))}
 	}
+	n.renderedConfigurationHash = hash
+	n.removeUnusedGeneratedFiles(&ingressCfg)
 
 	// Reload status checking runs in a separate goroutine to avoid blocking the sync queue
 	if workerSerialReloads {
 		go n.awaitWorkersReload()
 	}
 
-	return nil
+	return true, nil
+}
+
+// reasons of the failures of the reloads
+const (
+	reloadFailureTemplate = "template"
+	reloadFailureTest     = "test"
+	reloadFailureSignal   = "signal"
+	reloadFailureOther    = "other"
+)
+
+// reloadError is a failure of a reload with its reason. The failures of
+// nginx -t are reported as testError.
+type reloadError struct {
+	reason string
+	err    error
+}
+
+func (e *reloadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reloadError) Unwrap() error {
+	return e.err
+}
+
+// reloadFailureReason returns the reason of the failure of a reload
+func reloadFailureReason(err error) string {
+	var re *reloadError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+
+	var te *testError
+	if errors.As(err, &te) {
+		return reloadFailureTest
+	}
+
+	return reloadFailureOther
 }
 
 // awaitWorkersReload checks if the number of workers has returned to the expected count
@@ -807,53 +1008,6 @@ func nextPowerOf2(v int) int {
 	return v
 }
 
-func (n *NGINXController) setupSSLProxy() {
-	cfg := n.store.GetBackendConfiguration()
-	sslPort := n.cfg.ListenPorts.HTTPS
-	proxyPort := n.cfg.ListenPorts.SSLProxy
-
-	klog.InfoS("Starting TLS proxy for SSL Passthrough")
-	n.Proxy = &tcpproxy.TCPProxy{
-		Default: &tcpproxy.TCPServer{
-			Hostname:      "localhost",
-			IP:            "127.0.0.1",
-			Port:          proxyPort,
-			ProxyProtocol: true,
-		},
-	}
-
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", sslPort))
-	if err != nil {
-		klog.Fatalf("%v", err)
-	}
-
-	proxyList := &proxyproto.Listener{Listener: listener, ProxyHeaderTimeout: cfg.ProxyProtocolHeaderTimeout}
-
-	// accept TCP connections on the configured HTTPS port
-	go func() {
-		for {
-			var conn net.Conn
-			var err error
-
-			if n.store.GetBackendConfiguration().UseProxyProtocol {
-				// wrap the listener in order to decode Proxy
-				// Protocol before handling the connection
-				conn, err = proxyList.Accept()
-			} else {
-				conn, err = listener.Accept()
-			}
-
-			if err != nil {
-				klog.Warningf("Error accepting TCP connection: %v", err)
-				continue
-			}
-
-			klog.V(3).InfoS("Handling TCP connection", "remote", conn.RemoteAddr(), "local", conn.LocalAddr())
-			go n.Proxy.Handle(conn)
-		}
-	}()
-}
-
 // configureDynamically encodes new Backends in JSON format and POSTs the
 // payload to an internal HTTP endpoint handled by Lua.
 func (n *NGINXController) configureDynamically(pcfg *ingress.Configuration) error {
@@ -865,9 +1019,17 @@ func (n *NGINXController) configureDynamically(pcfg *ingress.Configuration) erro
 		}
 	}
 
-	streamConfigurationChanged := !reflect.DeepEqual(n.runningConfig.TCPEndpoints, pcfg.TCPEndpoints) || !reflect.DeepEqual(n.runningConfig.UDPEndpoints, pcfg.UDPEndpoints)
+	var sslPassthroughBackends, runningSSLPassthroughBackends []ingress.Backend
+	if n.cfg.EnableSSLPassthrough {
+		sslPassthroughBackends = getSSLPassthroughBackends(pcfg)
+		runningSSLPassthroughBackends = getSSLPassthroughBackends(n.runningConfig)
+	}
+
+	streamConfigurationChanged := !reflect.DeepEqual(n.runningConfig.TCPEndpoints, pcfg.TCPEndpoints) ||
+		!reflect.DeepEqual(n.runningConfig.UDPEndpoints, pcfg.UDPEndpoints) ||
+		!reflect.DeepEqual(runningSSLPassthroughBackends, sslPassthroughBackends)
 	if streamConfigurationChanged {
-		err := updateStreamConfiguration(pcfg.TCPEndpoints, pcfg.UDPEndpoints)
+		err := updateStreamConfiguration(pcfg.TCPEndpoints, pcfg.UDPEndpoints, sslPassthroughBackends)
 		if err != nil {
 			return err
 		}
@@ -881,42 +1043,115 @@ func (n *NGINXController) configureDynamically(pcfg *ingress.Configuration) erro
 		}
 	}
 
+	if n.runningConfig.DynamicConfigChecksum != pcfg.DynamicConfigChecksum {
+		cfg := n.store.GetBackendConfiguration()
+		err := configureGeneral(cfg.Dynamic())
+		if err != nil {
+			return err
+		}
+	}
+
+	if !maps.Equal(n.runningConfig.LuaSecrets, pcfg.LuaSecrets) {
+		err := configureLuaSecrets(pcfg.LuaSecrets)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !slices.Equal(n.runningConfig.Denylist, pcfg.Denylist) {
+		err := configureDenylist(pcfg.Denylist)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func updateStreamConfiguration(tcpEndpoints, udpEndpoints []ingress.L4Service) error {
-	streams := make([]ingress.Backend, 0)
-	for i := range tcpEndpoints {
-		ep := &tcpEndpoints[i]
-		var service *apiv1.Service
-		if ep.Service != nil {
-			service = &apiv1.Service{Spec: ep.Service.Spec}
+// getSSLPassthroughBackends returns the stream backends of the SSL passthrough
+// servers. NGINX proxies the TLS connections with the server name of a
+// passthrough server to the Endpoints of its backend, or to its own Endpoints
+// when it has no backend.
+func getSSLPassthroughBackends(pcfg *ingress.Configuration) []ingress.Backend {
+	backends := make(map[string]*ingress.Backend, len(pcfg.Backends))
+	for _, backend := range pcfg.Backends {
+		backends[backend.Name] = backend
+	}
+
+	streams := make([]ingress.Backend, 0, len(pcfg.PassthroughBackends))
+	for _, pb := range pcfg.PassthroughBackends {
+		endpoints, service := pb.Endpoints, pb.Service
+		if pb.Backend != "" {
+			backend, ok := backends[pb.Backend]
+			if !ok {
+				klog.Warningf("Missing backend %q for SSL Passthrough server %q", pb.Backend, pb.Hostname)
+				continue
+			}
+			endpoints, service = backend.Endpoints, backend.Service
 		}
 
-		key := fmt.Sprintf("tcp-%v-%v-%v", ep.Backend.Namespace, ep.Backend.Name, ep.Backend.Port.String())
-		streams = append(streams, ingress.Backend{
-			Name:      key,
-			Endpoints: ep.Endpoints,
-			Port:      intstr.FromInt(ep.Port),
-			Service:   service,
-		})
-	}
-	for i := range udpEndpoints {
-		ep := &udpEndpoints[i]
-		var service *apiv1.Service
-		if ep.Service != nil {
-			service = &apiv1.Service{Spec: ep.Service.Spec}
+		if service != nil {
+			service = &apiv1.Service{Spec: service.Spec}
 		}
 
-		key := fmt.Sprintf("udp-%v-%v-%v", ep.Backend.Namespace, ep.Backend.Name, ep.Backend.Port.String())
 		streams = append(streams, ingress.Backend{
-			Name:      key,
-			Endpoints: ep.Endpoints,
-			Port:      intstr.FromInt(ep.Port),
+			Name:      sslPassthroughBackendPrefix + strings.ToLower(pb.Hostname),
+			Endpoints: endpoints,
+			Port:      pb.Port,
 			Service:   service,
 		})
 	}
 
+	return streams
+}
+
+func updateStreamConfiguration(tcpEndpoints, udpEndpoints []ingress.L4Service, sslPassthroughBackends []ingress.Backend) error {
+	return configureStreams(streamBackends(tcpEndpoints, udpEndpoints, sslPassthroughBackends))
+}
+
+// streamBackends returns the backends of the TCP and UDP services and of the
+// SSL passthrough servers configured in the stream block
+func streamBackends(tcpEndpoints, udpEndpoints []ingress.L4Service, sslPassthroughBackends []ingress.Backend) []ingress.Backend {
+	streams := make([]ingress.Backend, 0, len(sslPassthroughBackends))
+	streams = append(streams, sslPassthroughBackends...)
+	var addStreams func(proto string, services []ingress.L4Service)
+	addStreams = func(proto string, services []ingress.L4Service) {
+		for i := range services {
+			ep := &services[i]
+			addStreams(proto, ep.SNIServices)
+
+			// the port only routes the TLS connections based on their
+			// server name
+			if ep.Backend.Name == "" {
+				continue
+			}
+
+			var service *apiv1.Service
+			if ep.Service != nil {
+				service = &apiv1.Service{Spec: ep.Service.Spec}
+			}
+
+			key := fmt.Sprintf("%v-%v-%v-%v", proto, ep.Backend.Namespace, ep.Backend.Name, ep.Backend.Port.String())
+			stream := ingress.Backend{
+				Name:      key,
+				Endpoints: ep.Endpoints,
+				Port:      intstr.FromInt(ep.Port),
+				Service:   service,
+			}
+			if hashBy := streamSessionAffinityHashBy(ep.Backend.SessionAffinity); hashBy != "" {
+				stream.LoadBalancing = "chash"
+				stream.UpstreamHashBy = ingress.UpstreamHashByConfig{UpstreamHashBy: hashBy}
+			}
+			streams = append(streams, stream)
+		}
+	}
+	addStreams("tcp", tcpEndpoints)
+	addStreams("udp", udpEndpoints)
+
+	return streams
+}
+
+func configureStreams(streams []ingress.Backend) error {
 	buf, err := json.Marshal(streams)
 	if err != nil {
 		return err
@@ -942,6 +1177,20 @@ func updateStreamConfiguration(tcpEndpoints, udpEndpoints []ingress.L4Service) e
 }
 
 func configureBackends(rawBackends []*ingress.Backend) error {
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/backends", "application/json", luaBackends(rawBackends))
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code: %d", statusCode)
+	}
+
+	return nil
+}
+
+// luaBackends returns the backends with only the fields used by Lua
+func luaBackends(rawBackends []*ingress.Backend) []*ingress.Backend {
 	backends := make([]*ingress.Backend, len(rawBackends))
 
 	for i, backend := range rawBackends {
@@ -956,6 +1205,9 @@ func configureBackends(rawBackends []*ingress.Backend) error {
 			SessionAffinity:      backend.SessionAffinity,
 			UpstreamHashBy:       backend.UpstreamHashBy,
 			LoadBalancing:        backend.LoadBalancing,
+			SlowStartSeconds:     backend.SlowStartSeconds,
+			OutlierDetection:     backend.OutlierDetection,
+			HealthCheck:          backend.HealthCheck,
 			Service:              service,
 			NoServer:             backend.NoServer,
 			TrafficShapingPolicy: backend.TrafficShapingPolicy,
@@ -974,7 +1226,24 @@ func configureBackends(rawBackends []*ingress.Backend) error {
 		backends[i] = luaBackend
 	}
 
-	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/backends", "application/json", backends)
+	return backends
+}
+
+type sslConfiguration struct {
+	Certificates map[string]string `json:"certificates"`
+	// ECDSACertificates contains the ECDSA certificates of the certificates
+	// with both an RSA and an ECDSA certificate
+	ECDSACertificates map[string]string `json:"ecdsaCertificates,omitempty"`
+	// KeyIDs contains the identifiers of the private keys of the certificates
+	// kept by the external key provider
+	KeyIDs  map[string]string `json:"keyIds,omitempty"`
+	Servers map[string]string `json:"servers"`
+}
+
+// configureGeneral POSTs the keys of the configuration ConfigMap applied
+// without reloading NGINX to an internal HTTP endpoint that is handled by Lua
+func configureGeneral(dynamicCfg ngx_config.DynamicConfiguration) error {
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/general", "application/json", dynamicCfg)
 	if err != nil {
 		return err
 	}
@@ -986,17 +1255,40 @@ func configureBackends(rawBackends []*ingress.Backend) error {
 	return nil
 }
 
-type sslConfiguration struct {
-	Certificates map[string]string `json:"certificates"`
-	Servers      map[string]string `json:"servers"`
+// configureLuaSecrets POSTs the values of the Secrets read by the Lua modules
+// to an internal HTTP endpoint that is handled by Lua, which does not return
+// them
+func configureLuaSecrets(secrets map[string]string) error {
+	if secrets == nil {
+		secrets = map[string]string{}
+	}
+
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/secrets", "application/json", secrets)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code: %d", statusCode)
+	}
+
+	return nil
 }
 
 // configureCertificates JSON encodes certificates and POSTs it to an internal HTTP endpoint
 // that is handled by Lua
 func configureCertificates(rawServers []*ingress.Server) error {
+	return configureSSLServers(sslServers(rawServers))
+}
+
+// sslServers returns the certificates of the servers and the certificate of
+// every server name
+func sslServers(rawServers []*ingress.Server) *sslConfiguration {
 	configuration := &sslConfiguration{
-		Certificates: map[string]string{},
-		Servers:      map[string]string{},
+		Certificates:      map[string]string{},
+		ECDSACertificates: map[string]string{},
+		KeyIDs:            map[string]string{},
+		Servers:           map[string]string{},
 	}
 
 	configure := func(hostname string, sslCert *ingress.SSLCert) {
@@ -1007,6 +1299,12 @@ func configureCertificates(rawServers []*ingress.Server) error {
 
 			if _, ok := configuration.Certificates[uid]; !ok {
 				configuration.Certificates[uid] = sslCert.PemCertKey
+				if sslCert.ECDSAPemCertKey != "" {
+					configuration.ECDSACertificates[uid] = sslCert.ECDSAPemCertKey
+				}
+				if sslCert.KeyID != "" {
+					configuration.KeyIDs[uid] = sslCert.KeyID
+				}
 			}
 		}
 
@@ -1030,6 +1328,10 @@ func configureCertificates(rawServers []*ingress.Server) error {
 		configure(redirect.From, redirect.SSLCert)
 	}
 
+	return configuration
+}
+
+func configureSSLServers(configuration *sslConfiguration) error {
 	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/servers", "application/json", configuration)
 	if err != nil {
 		return err
@@ -1065,18 +1367,53 @@ ratio = {{ .OtelSamplerRatio }}
 parent_based = {{ .OtelSamplerParentBased }}
 `
 
-func createOpentelemetryCfg(cfg *ngx_config.Configuration) error {
+func buildOpentelemetryCfg(cfg *ngx_config.Configuration) ([]byte, error) {
 	tmpl, err := template.New("otel").Parse(otelTmpl)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tmplBuf := bytes.NewBuffer(make([]byte, 0))
 	err = tmpl.Execute(tmplBuf, cfg)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return tmplBuf.Bytes(), nil
+}
+
+// configurationChecksumRegex matches the checksum of the model in nginx.conf
+var configurationChecksumRegex = regexp.MustCompile(`(?m)^\s*# Configuration checksum: .*$`)
+
+// renderedConfigurationHash returns the hash of the configuration files read
+// by NGINX on reload. The checksum of the model is ignored, it also changes
+// with the changes applied dynamically.
+func renderedConfigurationHash(files ...[]byte) [sha256.Size]byte {
+	h := sha256.New()
+	for _, content := range files {
+		h.Write(configurationChecksumRegex.ReplaceAll(content, nil))
+		// separate the files
+		h.Write([]byte{0})
+	}
+
+	var hash [sha256.Size]byte
+	copy(hash[:], h.Sum(nil))
+
+	return hash
+}
+
+// sslDHParam returns the DH parameters of the Secret configured in
+// ssl-dh-param, or nil when they cannot be read
+func (n *NGINXController) sslDHParam(secretName string) []byte {
+	if secretName == "" {
+		return nil
+	}
+
+	secret, err := n.store.GetSecret(secretName)
+	if err != nil {
+		return nil
 	}
 
-	return os.WriteFile(cfg.OpentelemetryConfig, tmplBuf.Bytes(), file.ReadWriteByUser)
+	return secret.Data["dhparam.pem"]
 }
 
 func cleanTempNginxCfg() error {
@@ -30,6 +30,7 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"k8s.io/ingress-nginx/internal/nginx"
@@ -410,6 +411,109 @@ func TestCleanTempNginxCfg(t *testing.T) {
 	}
 }
 
+func TestGetSSLPassthroughBackends(t *testing.T) {
+	endpoints := []ingress.Endpoint{{Address: "10.0.0.1", Port: "8443"}}
+	pcfg := &ingress.Configuration{
+		Backends: []*ingress.Backend{
+			{
+				Name:           "default-app-443",
+				SSLPassthrough: true,
+				Endpoints:      endpoints,
+				Service:        &apiv1.Service{Spec: apiv1.ServiceSpec{ClusterIP: "10.96.0.10"}},
+			},
+		},
+		PassthroughBackends: []*ingress.SSLPassthroughBackend{
+			{Backend: "default-app-443", Hostname: "App.example.com", Port: intstr.FromInt(443)},
+			{Backend: "default-missing-443", Hostname: "missing.example.com", Port: intstr.FromInt(443)},
+			{Hostname: "db.example.com", Port: intstr.FromInt(5432), Endpoints: []ingress.Endpoint{{Address: "10.0.0.2", Port: "5432"}}},
+		},
+	}
+
+	backends := getSSLPassthroughBackends(pcfg)
+	if len(backends) != 2 {
+		t.Fatalf("expected two SSL passthrough backends but got %d", len(backends))
+	}
+	if backends[1].Name != "ssl-passthrough-db.example.com" || len(backends[1].Endpoints) != 1 || backends[1].Endpoints[0].Address != "10.0.0.2" {
+		t.Errorf("expected the endpoints of the SSL passthrough server without backend but got %+v", backends[1])
+	}
+	if backends[0].Name != "ssl-passthrough-app.example.com" {
+		t.Errorf("expected the SSL passthrough backend to be named after the hostname but got %q", backends[0].Name)
+	}
+	if len(backends[0].Endpoints) != 1 || backends[0].Endpoints[0] != endpoints[0] {
+		t.Errorf("expected the endpoints of the backend but got %v", backends[0].Endpoints)
+	}
+	if backends[0].Service == nil || backends[0].Service.Spec.ClusterIP != "10.96.0.10" {
+		t.Errorf("expected the spec of the Service of the backend")
+	}
+}
+
+func TestRenderedConfigurationHash(t *testing.T) {
+	conf := []byte("# Configuration checksum: 1234\n\nhttp {\n}\n")
+	otel := []byte("exporter = \"otlp\"\n")
+
+	hash := renderedConfigurationHash(conf, otel)
+
+	sameConf := []byte("# Configuration checksum: 5678\n\nhttp {\n}\n")
+	if renderedConfigurationHash(sameConf, otel) != hash {
+		t.Errorf("expected the checksum of the model to be ignored")
+	}
+
+	changedConf := []byte("# Configuration checksum: 1234\n\nhttp {\n\tgzip on;\n}\n")
+	if renderedConfigurationHash(changedConf, otel) == hash {
+		t.Errorf("expected a different hash when nginx.conf changes")
+	}
+
+	if renderedConfigurationHash(conf, []byte("")) == hash {
+		t.Errorf("expected a different hash when the OpenTelemetry configuration changes")
+	}
+
+	// the content is not moved from a file to the other
+	if renderedConfigurationHash(append(conf, otel...), []byte("")) == hash {
+		t.Errorf("expected a different hash when the content of the files is moved")
+	}
+}
+
+func TestSSLDHParam(t *testing.T) {
+	n := &NGINXController{
+		store: &fakeIngressStore{
+			secrets: map[string]*apiv1.Secret{
+				"default/dhparam": {Data: map[string][]byte{"dhparam.pem": []byte("params")}},
+			},
+		},
+	}
+
+	if dh := n.sslDHParam("default/dhparam"); string(dh) != "params" {
+		t.Errorf("expected the DH parameters of the Secret but returned %q", dh)
+	}
+
+	if dh := n.sslDHParam("default/missing"); dh != nil {
+		t.Errorf("expected no DH parameters for a missing Secret but returned %q", dh)
+	}
+
+	if dh := n.sslDHParam(""); dh != nil {
+		t.Errorf("expected no DH parameters without a Secret but returned %q", dh)
+	}
+}
+
+func TestReloadFailureReason(t *testing.T) {
+	testCases := []struct {
+		err    error
+		reason string
+	}{
+		{&reloadError{reason: reloadFailureTemplate, err: fmt.Errorf("invalid template")}, reloadFailureTemplate},
+		{&reloadError{reason: reloadFailureSignal, err: fmt.Errorf("exit status 1")}, reloadFailureSignal},
+		{&testError{err: fmt.Errorf("exit status 1")}, reloadFailureTest},
+		{fmt.Errorf("requeuing reload: %w", &testError{err: fmt.Errorf("exit status 1")}), reloadFailureTest},
+		{fmt.Errorf("worker reload already in progress"), reloadFailureOther},
+	}
+
+	for _, tc := range testCases {
+		if reason := reloadFailureReason(tc.err); reason != tc.reason {
+			t.Errorf("expected reason %v for %v but got %v", tc.reason, tc.err, reason)
+		}
+	}
+}
+
 //nolint:unparam // Ingnore `network` always receives `"tcp"` error
 func tryListen(network, address string) (l net.Listener, err error) {
 	condFunc := func() (bool, error) {
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// ocspCheckInterval defines how often the OCSP stapling status of the hosts
+// is checked
+var ocspCheckInterval = time.Minute
+
+// ocspFailureThreshold defines the number of consecutive failed OCSP fetches
+// after which a warning Event is emitted for a host
+const ocspFailureThreshold = 3
+
+// checkOCSPStatus emits warning Events for the hosts whose OCSP response
+// cannot be fetched anymore, when OCSP stapling is enabled. It runs on the
+// leader only, to emit a single Event per host.
+func (n *NGINXController) checkOCSPStatus() {
+	if !n.store.GetBackendConfiguration().EnableOCSP {
+		n.ocspFailing = nil
+		return
+	}
+
+	status, err := nginx.GetOCSPStatus()
+	if err != nil {
+		klog.Warningf("Unexpected error obtaining the OCSP status: %v", err)
+		return
+	}
+
+	n.reportOCSPFailures(status)
+}
+
+// reportOCSPFailures emits a warning Event on the Ingresses of every host
+// reaching ocspFailureThreshold consecutive failed OCSP fetches. The Event is
+// emitted once, until an OCSP response is fetched again for the host.
+func (n *NGINXController) reportOCSPFailures(status []nginx.OCSPStatus) {
+	failing := sets.New[string]()
+
+	for i := range status {
+		s := &status[i]
+		if s.Failures < ocspFailureThreshold {
+			continue
+		}
+
+		failing.Insert(s.Hostname)
+		if n.ocspFailing.Has(s.Hostname) {
+			continue
+		}
+
+		message := fmt.Sprintf("OCSP stapling failed %v times in a row for host %v: %v", s.Failures, s.Hostname, s.Error)
+		klog.Warning(message)
+
+		reported := false
+		for _, ing := range n.store.ListIngresses() {
+			for _, tls := range ing.Spec.TLS {
+				if sets.New(tls.Hosts...).Has(s.Hostname) {
+					n.recorder.Event(&ing.Ingress, apiv1.EventTypeWarning, "OCSP", message)
+					reported = true
+					break
+				}
+			}
+		}
+
+		// the default certificate, or a host without Ingress anymore
+		if !reported {
+			n.recorder.Event(k8s.IngressPodDetails, apiv1.EventTypeWarning, "OCSP", message)
+		}
+	}
+
+	n.ocspFailing = failing
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+func TestReportOCSPFailures(t *testing.T) {
+	k8s.IngressPodDetails = &k8s.PodInfo{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx", Namespace: metav1.NamespaceDefault},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{
+		recorder: recorder,
+		store: &fakeIngressStore{
+			ingresses: []*ingress.Ingress{
+				{
+					Ingress: networking.Ingress{
+						ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: metav1.NamespaceDefault},
+						Spec: networking.IngressSpec{
+							TLS: []networking.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "example"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	events := func() []string {
+		var events []string
+		for {
+			select {
+			case e := <-recorder.Events:
+				events = append(events, e)
+			default:
+				return events
+			}
+		}
+	}
+
+	n.reportOCSPFailures([]nginx.OCSPStatus{
+		{Hostname: "example.com", Failures: 2, Error: "timeout"},
+		{Hostname: "_", Success: true},
+	})
+	if e := events(); len(e) != 0 {
+		t.Errorf("expected no Event below the threshold but %v returned", e)
+	}
+
+	failing := []nginx.OCSPStatus{
+		{Hostname: "example.com", Failures: 3, Error: "timeout"},
+		{Hostname: "_", Failures: 5, Error: "timeout"},
+	}
+	n.reportOCSPFailures(failing)
+	expected := []string{
+		"Warning OCSP OCSP stapling failed 3 times in a row for host example.com: timeout",
+		"Warning OCSP OCSP stapling failed 5 times in a row for host _: timeout",
+	}
+	if e := events(); len(e) != 2 || e[0] != expected[0] || e[1] != expected[1] {
+		t.Errorf("expected Events %v but %v returned", expected, e)
+	}
+
+	failing[0].Failures = 4
+	n.reportOCSPFailures(failing)
+	if e := events(); len(e) != 0 {
+		t.Errorf("expected no new Event for hosts already reported but %v returned", e)
+	}
+
+	n.reportOCSPFailures([]nginx.OCSPStatus{
+		{Hostname: "example.com", Success: true},
+		{Hostname: "_", Failures: 6, Error: "timeout"},
+	})
+	failing[0].Failures = 3
+	failing[1].Failures = 7
+	n.reportOCSPFailures(failing)
+	if e := events(); len(e) != 1 || e[0] != expected[0] {
+		t.Errorf("expected Event %v after the host recovered but %v returned", expected[0], e)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
+	"k8s.io/ingress-nginx/internal/ingress/controller/cachepurge"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// cacheKeyMatchers returns by cache zone the matchers of the keys of the
+// responses selected by the request, from the cache keys of the locations of
+// the servers of its host
+func cacheKeyMatchers(servers []*ingress.Server, r *cachepurge.Request) map[string][]*regexp.Regexp {
+	host := strings.ToLower(r.Host)
+	matchers := map[string][]*regexp.Regexp{}
+	for _, server := range servers {
+		wildcard, isWildcard := strings.CutPrefix(server.Hostname, "*")
+		if server.Hostname != host && (!isWildcard || !strings.HasSuffix(host, wildcard)) {
+			continue
+		}
+
+		for _, location := range server.Locations {
+			zone := location.ProxyCache.Zone
+			if zone == "" || location.Ingress == nil {
+				continue
+			}
+
+			key := proxycache.ScopedKey(location.Ingress.Namespace, location.Ingress.Name, location.ProxyCache.Key)
+			if matcher := r.Matcher(key); matcher != nil {
+				matchers[zone] = append(matchers[zone], matcher)
+			}
+		}
+	}
+
+	return matchers
+}
+
+// purgeCache removes the cached responses selected by the request from the
+// cache zones of the configuration, matching their keys with the cache keys
+// of the locations of the servers
+func purgeCache(cfg *config.Configuration, servers []*ingress.Server, r *cachepurge.Request) (*cachepurge.Result, error) {
+	matchers := cacheKeyMatchers(servers, r)
+
+	zoneExists := false
+	result := &cachepurge.Result{}
+	for _, zone := range cfg.ProxyCacheZones {
+		if r.Zone != "" && zone.Name != r.Zone {
+			continue
+		}
+		zoneExists = true
+
+		if len(matchers[zone.Name]) == 0 {
+			continue
+		}
+
+		purged, err := cachepurge.Purge(filepath.Join(cfg.ProxyCachePath, zone.Name), matchers[zone.Name])
+		result.Purged += purged
+		if err != nil {
+			return result, fmt.Errorf("purging the cache zone %v: %w", zone.Name, err)
+		}
+	}
+
+	if r.Zone != "" && !zoneExists {
+		return nil, fmt.Errorf("unknown cache zone %v", r.Zone)
+	}
+
+	return result, nil
+}
+
+// handlePurge purges the cached responses of a host, of a path or of the
+// paths with a prefix, in the cache of this replica:
+//
+//	POST /purge {"host": "example.com", "path": "/assets/", "prefix": true}
+func (n *NGINXController) handlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request := &cachepurge.Request{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decoding the purge request: %v", err), http.StatusBadRequest)
+		return
+	}
+	err = request.Validate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the syncs replace the running configuration, they never modify it
+	n.syncLock.Lock()
+	runningConfig := n.runningConfig
+	n.syncLock.Unlock()
+
+	cfg := n.store.GetBackendConfiguration()
+	result, err := purgeCache(&cfg, runningConfig.Servers, request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	klog.InfoS("Purged cached responses", "host", request.Host, "path", request.Path, "prefix", request.Prefix, "zone", request.Zone, "purged", result.Purged)
+
+	w.Header().Set("Content-Type", "application/json")
+	//nolint:errcheck // the client went away
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
+	"k8s.io/ingress-nginx/internal/ingress/controller/cachepurge"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+func TestPurgeCache(t *testing.T) {
+	cfg := config.NewDefault()
+	cfg.ProxyCachePath = t.TempDir()
+	cfg.ProxyCacheZones = []config.ProxyCacheZone{{Name: "static"}, {Name: "api"}}
+
+	for _, zone := range []string{"static", "api"} {
+		path := filepath.Join(cfg.ProxyCachePath, zone, "c", "ab", "0123456789abcdef0123456789abcabc")
+		err := os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			t.Fatalf("unexpected error creating the cache directory: %v", err)
+		}
+		err = os.WriteFile(path, []byte("\x05\x00\nKEY: example.com:default/shop:httpsexample.com/"+zone+"\n"), 0o600)
+		if err != nil {
+			t.Fatalf("unexpected error writing the cache file: %v", err)
+		}
+	}
+
+	shop := &ingress.Ingress{
+		Ingress: networking.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shop"}},
+	}
+	servers := []*ingress.Server{
+		{
+			Hostname: "example.com",
+			Locations: []*ingress.Location{
+				{Path: "/static", Ingress: shop, ProxyCache: proxycache.Config{Zone: "static", Key: proxycache.DefaultKey}},
+				{Path: "/api", Ingress: shop, ProxyCache: proxycache.Config{Zone: "api", Key: proxycache.DefaultKey}},
+			},
+		},
+		{
+			Hostname:  "other.example.com",
+			Locations: []*ingress.Location{{Path: "/", Ingress: shop, ProxyCache: proxycache.Config{Zone: "api", Key: "$uri"}}},
+		},
+	}
+
+	result, err := purgeCache(&cfg, servers, &cachepurge.Request{Host: "other.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error purging: %v", err)
+	}
+	if result.Purged != 0 {
+		t.Errorf("expected no purged response of another host but returned %v", result.Purged)
+	}
+
+	_, err = purgeCache(&cfg, servers, &cachepurge.Request{Host: "example.com", Zone: "unknown"})
+	if err == nil {
+		t.Errorf("expected an error purging an unknown cache zone")
+	}
+
+	result, err = purgeCache(&cfg, servers, &cachepurge.Request{Host: "example.com", Zone: "api"})
+	if err != nil {
+		t.Fatalf("unexpected error purging: %v", err)
+	}
+	if result.Purged != 1 {
+		t.Errorf("expected 1 purged response in the api zone but returned %v", result.Purged)
+	}
+
+	result, err = purgeCache(&cfg, servers, &cachepurge.Request{Host: "example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error purging: %v", err)
+	}
+	if result.Purged != 1 {
+		t.Errorf("expected 1 purged response left in all the zones but returned %v", result.Purged)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding splits the Ingresses of an IngressClass between several
+// ingress controllers, each of them owning a deterministic subset
+package sharding
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	networking "k8s.io/api/networking/v1"
+)
+
+// Configuration defines the shard of the Ingresses owned by the controller
+type Configuration struct {
+	// Shards is the number of shards, 0 or 1 disables the sharding
+	Shards int
+	// Index is the shard owned by the controller, between 0 and Shards - 1
+	Index int
+	// Label is the label of the Ingresses with the index of the shard they
+	// are assigned to. The Ingresses without it are assigned by the hash of
+	// their namespace and name.
+	Label string
+}
+
+// Enabled returns true if the Ingresses are split between several shards
+func (c *Configuration) Enabled() bool {
+	return c != nil && c.Shards > 1
+}
+
+// Owns returns true if the Ingress belongs to the shard of the controller
+func (c *Configuration) Owns(ing *networking.Ingress) bool {
+	if !c.Enabled() {
+		return true
+	}
+
+	return c.shard(ing) == c.Index
+}
+
+func (c *Configuration) shard(ing *networking.Ingress) int {
+	if c.Label != "" {
+		if value, ok := ing.Labels[c.Label]; ok {
+			shard, err := strconv.Atoi(value)
+			if err == nil && shard >= 0 && shard < c.Shards {
+				return shard
+			}
+		}
+	}
+
+	h := fnv.New32a()
+	// the hash of a string never fails
+	//nolint:errcheck // see above
+	h.Write([]byte(ing.Namespace + "/" + ing.Name))
+
+	return int(h.Sum32() % uint32(c.Shards))
+}
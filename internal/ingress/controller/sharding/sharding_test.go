@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"fmt"
+	"testing"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newIngress(name string, labels map[string]string) *networking.Ingress {
+	return &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    labels,
+		},
+	}
+}
+
+func TestOwnsDisabled(t *testing.T) {
+	for _, c := range []*Configuration{nil, {}, {Shards: 1}} {
+		if !c.Owns(newIngress("example", nil)) {
+			t.Errorf("expected configuration %v to own every Ingress", c)
+		}
+	}
+}
+
+func TestOwnsByHash(t *testing.T) {
+	shards := make([]*Configuration, 3)
+	for i := range shards {
+		shards[i] = &Configuration{Shards: 3, Index: i}
+	}
+
+	counts := make([]int, len(shards))
+	for i := 0; i < 300; i++ {
+		ing := newIngress(fmt.Sprintf("ingress-%d", i), nil)
+
+		owners := 0
+		for j, shard := range shards {
+			if shard.Owns(ing) {
+				owners++
+				counts[j]++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("expected Ingress %v to be owned by a single shard but got %d", ing.Name, owners)
+		}
+	}
+
+	for i, count := range counts {
+		if count == 0 {
+			t.Errorf("expected shard %d to own some Ingresses", i)
+		}
+	}
+}
+
+func TestOwnsByLabel(t *testing.T) {
+	c := &Configuration{Shards: 3, Index: 2, Label: "ingress.example.com/shard"}
+
+	testCases := []struct {
+		labels map[string]string
+		owned  bool
+	}{
+		{map[string]string{"ingress.example.com/shard": "2"}, true},
+		{map[string]string{"ingress.example.com/shard": "1"}, false},
+	}
+
+	for _, tc := range testCases {
+		if owned := c.Owns(newIngress("example", tc.labels)); owned != tc.owned {
+			t.Errorf("expected the Ingress with labels %v to be owned: %v but got %v", tc.labels, tc.owned, owned)
+		}
+	}
+
+	// invalid shards fall back to the hash of the namespace and name
+	ing := newIngress("example", map[string]string{"ingress.example.com/shard": "7"})
+	if c.Owns(ing) != (c.shard(newIngress("example", nil)) == 2) {
+		t.Errorf("expected an invalid shard label to be ignored")
+	}
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snapshot keeps the last configurations applied by the ingress
+// controller on disk, to inspect them and to roll back to one of them.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+const (
+	// idLayout is the layout of the creation time used as snapshot ID, it
+	// sorts as the creation time
+	idLayout = "20060102-150405.000000000"
+
+	metadataFile      = "metadata.json"
+	nginxConfFile     = "nginx.conf"
+	configurationFile = "configuration.json"
+)
+
+var idRegex = regexp.MustCompile(`^\d{8}-\d{6}\.\d{9}$`)
+
+// Snapshot describes a configuration applied by the ingress controller
+type Snapshot struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Checksum is the checksum of the configuration model
+	Checksum string `json:"checksum"`
+	Servers  int    `json:"servers"`
+	Backends int    `json:"backends"`
+	// GeneratedFiles are the files generated from the annotations which are
+	// referenced by the NGINX configuration, kept with the snapshot
+	GeneratedFiles []string `json:"generatedFiles,omitempty"`
+}
+
+// Store keeps the last snapshots in a directory, with a subdirectory per
+// snapshot holding its metadata, the NGINX configuration and the
+// configuration applied dynamically
+type Store struct {
+	directory string
+	max       int
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewStore returns a Store keeping the last max snapshots in the directory
+func NewStore(directory string, max int) *Store {
+	return &Store{
+		directory: directory,
+		max:       max,
+		now:       time.Now,
+	}
+}
+
+// Save keeps a new snapshot with the NGINX configuration and the
+// configuration applied dynamically,
+// removing the oldest snapshots beyond the maximum
+func (s *Store) Save(snapshot *Snapshot, nginxConf, configuration []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot.CreatedAt = s.now().UTC()
+	snapshot.ID = snapshot.CreatedAt.Format(idLayout)
+
+	metadata, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(s.directory, snapshot.ID)
+	err = os.MkdirAll(dir, file.ReadWriteByUser)
+	if err != nil {
+		return fmt.Errorf("creating directory %v: %w", dir, err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, nginxConfFile), nginxConf, file.ReadWriteByUser)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, configurationFile), configuration, file.ReadWriteByUser)
+	if err != nil {
+		return err
+	}
+
+	// written last, the snapshots without metadata are incomplete
+	err = os.WriteFile(filepath.Join(dir, metadataFile), metadata, file.ReadWriteByUser)
+	if err != nil {
+		return err
+	}
+
+	return s.prune()
+}
+
+// ids returns the IDs of the snapshots in the directory, oldest first
+func (s *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && idRegex.MatchString(entry.Name()) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+func (s *Store) prune() error {
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+
+	for len(ids) > s.max {
+		err = os.RemoveAll(filepath.Join(s.directory, ids[0]))
+		if err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+
+	return nil
+}
+
+// List returns the snapshots, newest first
+func (s *Store) List() ([]*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*Snapshot, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		snapshot, err := s.get(ids[i])
+		if err != nil {
+			// incomplete snapshot
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// Get returns the snapshot with the ID
+func (s *Store) Get(id string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(id)
+}
+
+func (s *Store) get(id string) (*Snapshot, error) {
+	if !idRegex.MatchString(id) {
+		return nil, fmt.Errorf("invalid snapshot ID %q", id)
+	}
+
+	metadata, err := os.ReadFile(filepath.Join(s.directory, id, metadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot %q not found", id)
+		}
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	err = json.Unmarshal(metadata, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata of snapshot %q: %w", id, err)
+	}
+
+	return snapshot, nil
+}
+
+// Files returns the NGINX configuration and the configuration applied
+// dynamically of the snapshot
+func (s *Store) Files(id string) (nginxConf, configuration []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.get(id); err != nil {
+		return nil, nil, err
+	}
+
+	nginxConf, err = os.ReadFile(filepath.Join(s.directory, id, nginxConfFile))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configuration, err = os.ReadFile(filepath.Join(s.directory, id, configurationFile))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nginxConf, configuration, nil
+}
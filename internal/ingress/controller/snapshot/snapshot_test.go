@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, max int) *Store {
+	s := NewStore(t.TempDir(), max)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return s
+}
+
+func TestSaveAndList(t *testing.T) {
+	s := newTestStore(t, 2)
+
+	for i, checksum := range []string{"1", "2", "3"} {
+		err := s.Save(&Snapshot{Checksum: checksum, Servers: i, GeneratedFiles: []string{"/etc/nginx/error-pages/" + checksum}}, []byte("conf "+checksum), []byte("{}"))
+		if err != nil {
+			t.Fatalf("unexpected error saving snapshot: %v", err)
+		}
+	}
+
+	snapshots, err := s.List()
+	if err != nil {
+		t.Fatalf("unexpected error listing snapshots: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected the oldest snapshot to be removed but got %d snapshots", len(snapshots))
+	}
+	if snapshots[0].Checksum != "3" || snapshots[1].Checksum != "2" {
+		t.Errorf("expected the newest snapshots first but got %v and %v", snapshots[0].Checksum, snapshots[1].Checksum)
+	}
+	if len(snapshots[1].GeneratedFiles) != 1 || snapshots[1].GeneratedFiles[0] != "/etc/nginx/error-pages/2" {
+		t.Errorf("expected the generated files of the snapshot but got %v", snapshots[1].GeneratedFiles)
+	}
+	if snapshots[0].ID != "20240501-100003.000000000" {
+		t.Errorf("unexpected snapshot ID %v", snapshots[0].ID)
+	}
+
+	nginxConf, configuration, err := s.Files(snapshots[1].ID)
+	if err != nil {
+		t.Fatalf("unexpected error reading snapshot files: %v", err)
+	}
+	if string(nginxConf) != "conf 2" || string(configuration) != "{}" {
+		t.Errorf("unexpected snapshot files %q and %q", nginxConf, configuration)
+	}
+}
+
+func TestListIgnoresIncompleteSnapshots(t *testing.T) {
+	s := newTestStore(t, 2)
+
+	err := os.MkdirAll(filepath.Join(s.directory, "20240501-100000.000000000"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := s.List()
+	if err != nil {
+		t.Fatalf("unexpected error listing snapshots: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("expected no snapshots but got %v", snapshots)
+	}
+}
+
+func TestGetInvalidID(t *testing.T) {
+	s := newTestStore(t, 2)
+
+	for _, id := range []string{"../../etc", "", "20240501-100000.000000000"} {
+		if _, err := s.Get(id); err == nil {
+			t.Errorf("expected an error getting snapshot %q", id)
+		}
+		if _, _, err := s.Files(id); err == nil {
+			t.Errorf("expected an error reading the files of snapshot %q", id)
+		}
+	}
+}
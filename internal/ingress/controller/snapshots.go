@@ -0,0 +1,247 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/controller/snapshot"
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/internal/task"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+// snapshotConfiguration is the configuration applied dynamically kept in a
+// snapshot, as sent to Lua. The values of the Lua secrets are not kept on
+// disk, the current ones are kept on rollback.
+type snapshotConfiguration struct {
+	Backends []*ingress.Backend              `json:"backends"`
+	Streams  []ingress.Backend               `json:"streams"`
+	Servers  *sslConfiguration               `json:"servers"`
+	General  ngx_config.DynamicConfiguration `json:"general"`
+	Denylist []ingress.DenylistEntry         `json:"denylist"`
+}
+
+// saveSnapshot keeps the configuration applied, with the configuration
+// applied dynamically
+func (n *NGINXController) saveSnapshot(pcfg *ingress.Configuration) {
+	nginxConf, err := os.ReadFile(cfgPath)
+	if err != nil {
+		klog.ErrorS(err, "Error reading the NGINX configuration to snapshot")
+		return
+	}
+
+	var sslPassthroughBackends []ingress.Backend
+	if n.cfg.EnableSSLPassthrough {
+		sslPassthroughBackends = getSSLPassthroughBackends(pcfg)
+	}
+
+	cfg := n.store.GetBackendConfiguration()
+	configuration, err := json.Marshal(&snapshotConfiguration{
+		Backends: luaBackends(pcfg.Backends),
+		Streams:  streamBackends(pcfg.TCPEndpoints, pcfg.UDPEndpoints, sslPassthroughBackends),
+		Servers:  sslServers(pcfg.Servers),
+		General:  cfg.Dynamic(),
+		Denylist: pcfg.Denylist,
+	})
+	if err != nil {
+		klog.ErrorS(err, "Error encoding the configuration to snapshot")
+		return
+	}
+
+	s := &snapshot.Snapshot{
+		Checksum:       pcfg.ConfigurationChecksum,
+		Servers:        len(pcfg.Servers),
+		Backends:       len(pcfg.Backends),
+		GeneratedFiles: generatedFiles(pcfg),
+	}
+	err = n.snapshots.Save(s, nginxConf, configuration)
+	if err != nil {
+		klog.ErrorS(err, "Error saving the configuration snapshot")
+		return
+	}
+
+	klog.V(2).InfoS("Configuration snapshot saved", "id", s.ID)
+}
+
+// rollback applies the configuration of the snapshot and pauses the syncs, so
+// the configuration is kept until the syncs are resumed
+func (n *NGINXController) rollback(id string) error {
+	n.syncLock.Lock()
+	defer n.syncLock.Unlock()
+
+	nginxConf, configuration, err := n.snapshots.Files(id)
+	if err != nil {
+		return err
+	}
+
+	snapshotCfg := &snapshotConfiguration{}
+	err = json.Unmarshal(configuration, snapshotCfg)
+	if err != nil {
+		return fmt.Errorf("invalid configuration of snapshot %q: %w", id, err)
+	}
+
+	s, err := n.snapshots.Get(id)
+	if err != nil {
+		return err
+	}
+	for _, f := range s.GeneratedFiles {
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("missing file %v of snapshot %q: %w", f, id, err)
+		}
+	}
+
+	err = n.testTemplate(nginxConf)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(cfgPath, nginxConf, file.ReadWriteByUser)
+	if err != nil {
+		return err
+	}
+
+	o, err := n.command.ExecCommand("-s", "reload").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v\n%v", err, string(o))
+	}
+
+	err = applySnapshotConfiguration(snapshotCfg)
+	if err != nil {
+		return err
+	}
+
+	n.rolledBack = id
+	klog.InfoS("Configuration rolled back to snapshot, syncs paused", "id", id)
+
+	return nil
+}
+
+// applySnapshotConfiguration applies all the configuration of a snapshot
+// applied dynamically: the backends, the TCP, UDP and SSL passthrough streams,
+// the certificates, the general configuration and the denylist
+func applySnapshotConfiguration(snapshotCfg *snapshotConfiguration) error {
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/backends", "application/json", snapshotCfg.Backends)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code configuring the backends: %d", statusCode)
+	}
+
+	err = configureStreams(snapshotCfg.Streams)
+	if err != nil {
+		return err
+	}
+
+	if snapshotCfg.Servers != nil {
+		err = configureSSLServers(snapshotCfg.Servers)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = configureGeneral(snapshotCfg.General)
+	if err != nil {
+		return err
+	}
+
+	return configureDenylist(snapshotCfg.Denylist)
+}
+
+// resume resumes the syncs paused by a rollback, applying the current
+// configuration again
+func (n *NGINXController) resume() {
+	n.syncLock.Lock()
+	defer n.syncLock.Unlock()
+
+	if n.rolledBack == "" {
+		return
+	}
+
+	klog.InfoS("Resuming syncs after rollback", "id", n.rolledBack)
+	n.rolledBack = ""
+	n.runningConfig = new(ingress.Configuration)
+	n.renderedConfigurationHash = [len(n.renderedConfigurationHash)]byte{}
+
+	n.syncQueue.EnqueueTask(task.GetDummyObject("resume"))
+}
+
+// handleSnapshots lists the snapshots, returns the NGINX configuration of a
+// snapshot, rolls back to a snapshot and resumes the syncs:
+//
+//	GET  /snapshots
+//	GET  /snapshots/<id>
+//	POST /snapshots/<id>/rollback
+//	POST /snapshots/resume
+func (n *NGINXController) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	if n.snapshots == nil {
+		http.Error(w, "configuration snapshots are disabled (flag --configuration-snapshots)", http.StatusNotFound)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, nginx.SnapshotsPath), "/")
+	id, action, _ := strings.Cut(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		snapshots, err := n.snapshots.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck // the client went away
+		json.NewEncoder(w).Encode(snapshots)
+
+	case r.Method == http.MethodGet && action == "":
+		nginxConf, _, err := n.snapshots.Files(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		//nolint:errcheck // the client went away
+		w.Write(nginxConf)
+
+	case r.Method == http.MethodPost && path == "resume":
+		n.resume()
+		fmt.Fprintln(w, "syncs resumed")
+
+	case r.Method == http.MethodPost && action == "rollback":
+		err := n.rollback(id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("rolling back to snapshot %v: %v", id, err), http.StatusUnprocessableEntity)
+			return
+		}
+
+		fmt.Fprintf(w, "configuration rolled back to snapshot %v, syncs paused until they are resumed\n", id)
+
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
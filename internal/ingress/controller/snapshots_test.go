@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/snapshot"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+func TestHandleSnapshots(t *testing.T) {
+	n := &NGINXController{}
+
+	w := httptest.NewRecorder()
+	n.handleSnapshots(w, httptest.NewRequest(http.MethodGet, "/snapshots", http.NoBody))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %v when the snapshots are disabled but got %v", http.StatusNotFound, w.Code)
+	}
+
+	n.snapshots = snapshot.NewStore(t.TempDir(), 3)
+	s := &snapshot.Snapshot{Checksum: "1234"}
+	err := n.snapshots.Save(s, []byte("events {}"), []byte("[]"))
+	if err != nil {
+		t.Fatalf("unexpected error saving snapshot: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	n.handleSnapshots(w, httptest.NewRequest(http.MethodGet, "/snapshots", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %v but got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+	var snapshots []*snapshot.Snapshot
+	err = json.Unmarshal(w.Body.Bytes(), &snapshots)
+	if err != nil {
+		t.Fatalf("unexpected error decoding snapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].ID != s.ID || snapshots[0].Checksum != "1234" {
+		t.Errorf("unexpected snapshots %v", snapshots)
+	}
+
+	w = httptest.NewRecorder()
+	n.handleSnapshots(w, httptest.NewRequest(http.MethodGet, "/snapshots/"+s.ID, http.NoBody))
+	if w.Code != http.StatusOK || w.Body.String() != "events {}" {
+		t.Errorf("expected the NGINX configuration of the snapshot but got %v: %v", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	n.handleSnapshots(w, httptest.NewRequest(http.MethodGet, "/snapshots/unknown", http.NoBody))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %v for an unknown snapshot but got %v", http.StatusNotFound, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	n.handleSnapshots(w, httptest.NewRequest(http.MethodPost, "/snapshots/unknown/rollback", http.NoBody))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %v rolling back to an unknown snapshot but got %v", http.StatusUnprocessableEntity, w.Code)
+	}
+	if n.rolledBack != "" {
+		t.Errorf("expected the syncs not to be paused after a failed rollback")
+	}
+}
+
+func TestSnapshotConfiguration(t *testing.T) {
+	tcp := []ingress.L4Service{
+		{
+			Port:      5432,
+			Backend:   ingress.L4Backend{Name: "db", Namespace: "default", Port: intstr.FromInt(5432), Protocol: apiv1.ProtocolTCP},
+			Endpoints: []ingress.Endpoint{{Address: "db.example.com", Port: "5432"}},
+			Service:   &apiv1.Service{Spec: apiv1.ServiceSpec{Type: apiv1.ServiceTypeExternalName, ExternalName: "db.example.com"}},
+		},
+	}
+	servers := []*ingress.Server{
+		{Hostname: "example.com", SSLCert: &ingress.SSLCert{UID: "1", PemCertKey: "pem"}},
+	}
+
+	expected := &snapshotConfiguration{
+		Backends: []*ingress.Backend{},
+		Streams:  streamBackends(tcp, nil, nil),
+		Servers:  sslServers(servers),
+		Denylist: []ingress.DenylistEntry{{CIDR: "192.0.2.1"}},
+	}
+
+	configuration, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("unexpected error encoding the configuration: %v", err)
+	}
+
+	snapshotCfg := &snapshotConfiguration{}
+	err = json.Unmarshal(configuration, snapshotCfg)
+	if err != nil {
+		t.Fatalf("unexpected error decoding the configuration: %v", err)
+	}
+
+	if !reflect.DeepEqual(snapshotCfg.Streams, expected.Streams) || !reflect.DeepEqual(snapshotCfg.Denylist, expected.Denylist) {
+		t.Errorf("expected %+v but got %+v", expected, snapshotCfg)
+	}
+	if snapshotCfg.Streams[0].Service == nil || snapshotCfg.Streams[0].Service.Spec.ExternalName != "db.example.com" {
+		t.Errorf("expected the Service of the TCP stream to be kept but got %+v", snapshotCfg.Streams[0].Service)
+	}
+	if snapshotCfg.Servers.Certificates["1"] != "pem" || snapshotCfg.Servers.Servers["example.com"] != "1" {
+		t.Errorf("expected the certificates to be kept but got %+v", snapshotCfg.Servers)
+	}
+}
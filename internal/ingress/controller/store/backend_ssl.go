@@ -81,6 +81,7 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 
 	cert, okcert := secret.Data[apiv1.TLSCertKey]
 	key, okkey := secret.Data[apiv1.TLSPrivateKeyKey]
+	keyID, okkeyid := secret.Data[ssl.KeyIDKey]
 	ca := secret.Data["ca.crt"]
 
 	crl := secret.Data["ca.crl"]
@@ -92,6 +93,18 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 
 	var sslCert *ingress.SSLCert
 	switch {
+	case okcert && okkeyid && !okkey:
+		// the private key is kept by the external key provider
+		if cert == nil {
+			return nil, fmt.Errorf("key 'tls.crt' missing from Secret %q", secretName)
+		}
+
+		sslCert, err = ssl.CreateKeylessSSLCert(cert, strings.TrimSpace(string(keyID)), string(secret.UID))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected error creating SSL Cert: %v", err)
+		}
+
+		klog.V(3).InfoS("Configuring Secret for TLS encryption with an external private key", "secret", secretName, "CN", sslCert.CN)
 	case okcert && okkey:
 		if cert == nil {
 			return nil, fmt.Errorf("key 'tls.crt' missing from Secret %q", secretName)
@@ -101,11 +114,27 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 			return nil, fmt.Errorf("key 'tls.key' missing from Secret %q", secretName)
 		}
 
+		if okkeyid {
+			return nil, fmt.Errorf("keys %q and %q cannot be both present in Secret %q", apiv1.TLSPrivateKeyKey, ssl.KeyIDKey, secretName)
+		}
+
 		sslCert, err = ssl.CreateSSLCert(cert, key, string(secret.UID))
 		if err != nil {
 			return nil, fmt.Errorf("unexpected error creating SSL Cert: %v", err)
 		}
 
+		ecdsaCert, okECDSACert := secret.Data[ssl.ECDSACertKey]
+		ecdsaKey, okECDSAKey := secret.Data[ssl.ECDSAPrivateKeyKey]
+		if okECDSACert != okECDSAKey {
+			return nil, fmt.Errorf("keys %q and %q must be both present in Secret %q", ssl.ECDSACertKey, ssl.ECDSAPrivateKeyKey, secretName)
+		}
+		if okECDSACert {
+			err = ssl.AddECDSACert(sslCert, ecdsaCert, ecdsaKey)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected error adding ECDSA SSL Cert: %v", err)
+			}
+		}
+
 		if len(ca) > 0 {
 			caCert, err := ssl.CheckCACert(ca)
 			if err != nil {
@@ -178,14 +207,25 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 	sslCert.Name = secret.Name
 	sslCert.Namespace = secret.Namespace
 
-	// the default SSL certificate needs to be present on disk
-	if secretName == s.defaultSSLCertificate {
+	// the default SSL certificates and the certificates of the StreamRoutes
+	// and the Gateways need to be present on disk
+	if secretName == s.defaultSSLCertificate || s.isStreamRouteSecret(secretName) || s.isClassParamsSecret(secretName) ||
+		s.isGatewaySecret(secretName) {
 		path, err := ssl.StoreSSLCertOnDisk(nsSecName, sslCert)
 		if err != nil {
 			return nil, fmt.Errorf("storing default SSL Certificate: %w", err)
 		}
 
 		sslCert.PemFileName = path
+
+		if sslCert.ECDSAPemCertKey != "" {
+			path, err = ssl.StoreECDSACertOnDisk(nsSecName, sslCert)
+			if err != nil {
+				return nil, fmt.Errorf("storing default ECDSA SSL Certificate: %w", err)
+			}
+
+			sslCert.ECDSAPemFileName = path
+		}
 	}
 
 	return sslCert, nil
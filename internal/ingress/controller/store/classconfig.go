@@ -0,0 +1,313 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/eapache/channels"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// ClassConfiguration contains the configuration of the Ingresses of an
+// IngressClass referencing a ConfigMap with the annotation
+// ingress.nginx.kubernetes.io/configmap, or a NginxIngressClassParams in its
+// spec.parameters. The keys of the ConfigMap and the config of the
+// parameters override the global configuration for the defaults of the
+// locations and the annotation policies, and the snippets are added to the
+// servers and the locations of the Ingresses of the class. The parameters
+// take precedence over the ConfigMap.
+type ClassConfiguration struct {
+	// ConfigMap is the key of the ConfigMap of the class
+	ConfigMap string
+	// Parameters is the name of the NginxIngressClassParams of the class
+	Parameters string
+
+	Backend                 defaults.Backend
+	Security                defaults.SecurityConfiguration
+	AllowSnippetAnnotations bool
+	DefaultSSLCertificate   string
+	ServerSnippet           string
+	LocationSnippet         string
+
+	// extractor parses the annotations with the defaults of the class
+	extractor annotations.Extractor
+}
+
+// classResolver resolves the defaults of the locations with the ones of an
+// IngressClass
+type classResolver struct {
+	*k8sStore
+	backend  defaults.Backend
+	security defaults.SecurityConfiguration
+}
+
+// GetDefaultBackend returns the defaults of the locations of the class
+func (r classResolver) GetDefaultBackend() defaults.Backend {
+	return r.backend
+}
+
+// GetSecurityConfiguration returns the annotation policies of the class
+func (r classResolver) GetSecurityConfiguration() defaults.SecurityConfiguration {
+	return r.security
+}
+
+// GetClassConfiguration returns the configuration of the IngressClass of the
+// Ingress, nil when it references neither a ConfigMap nor parameters. An
+// error is returned when the ConfigMap of the class cannot be read.
+func (s *k8sStore) GetClassConfiguration(ing *networkingv1.Ingress) (*ClassConfiguration, error) {
+	if ing.Spec.IngressClassName == nil {
+		return nil, nil
+	}
+
+	ic, err := s.GetIngressClassByName(*ing.Spec.IngressClassName)
+	if err != nil {
+		return nil, nil
+	}
+
+	params, err := s.getIngressClassParams(ic)
+	if err != nil {
+		klog.Warningf("Error reading the parameters of IngressClass %v, ignoring them: %v", ic.Name, err)
+	}
+
+	key := ic.Annotations[ingressclass.ConfigMapAnnotation]
+	if key == "" && params == nil {
+		return nil, nil
+	}
+
+	paramsName := ""
+	if params != nil {
+		paramsName = params.Name
+	}
+
+	s.classConfigsMu.Lock()
+	defer s.classConfigsMu.Unlock()
+
+	if classConfig, ok := s.classConfigs[ic.Name]; ok && classConfig.ConfigMap == key && classConfig.Parameters == paramsName {
+		return classConfig, nil
+	}
+
+	s.backendConfigMu.RLock()
+	data := make(map[string]string, len(s.backendConfigData))
+	for k, v := range s.backendConfigData {
+		data[k] = v
+	}
+	s.backendConfigMu.RUnlock()
+
+	classConfig := &ClassConfiguration{
+		ConfigMap:  key,
+		Parameters: paramsName,
+	}
+
+	if key != "" {
+		cm, err := s.GetConfigMap(key)
+		if err != nil {
+			return nil, fmt.Errorf("reading ConfigMap %v of IngressClass %v: %w", key, ic.Name, err)
+		}
+
+		for k, v := range cm.Data {
+			data[k] = v
+		}
+		classConfig.ServerSnippet = cm.Data["server-snippet"]
+		classConfig.LocationSnippet = cm.Data["location-snippet"]
+	}
+
+	if params != nil {
+		for k, v := range params.Spec.Config {
+			data[k] = v
+		}
+		if params.Spec.AllowSnippetAnnotations != nil {
+			data["allow-snippet-annotations"] = strconv.FormatBool(*params.Spec.AllowSnippetAnnotations)
+		}
+		if params.Spec.AnnotationsRiskLevel != "" {
+			data["annotations-risk-level"] = params.Spec.AnnotationsRiskLevel
+		}
+		if params.Spec.ServerSnippet != "" {
+			classConfig.ServerSnippet = params.Spec.ServerSnippet
+		}
+		if params.Spec.LocationSnippet != "" {
+			classConfig.LocationSnippet = params.Spec.LocationSnippet
+		}
+		classConfig.DefaultSSLCertificate = params.Spec.DefaultSSLCertificate
+	}
+
+	cfg := ngx_template.ReadConfig(data)
+	classConfig.Backend = cfg.Backend
+	classConfig.Security = defaults.SecurityConfiguration{
+		AllowCrossNamespaceResources: cfg.AllowCrossNamespaceResources,
+		ReferenceGrants:              s.referenceGrants,
+		AnnotationsRiskLevel:         cfg.AnnotationsRiskLevel,
+	}
+	classConfig.AllowSnippetAnnotations = cfg.AllowSnippetAnnotations
+	classConfig.extractor = annotations.NewAnnotationExtractor(classResolver{
+		k8sStore: s,
+		backend:  classConfig.Backend,
+		security: classConfig.Security,
+	})
+	s.classConfigs[ic.Name] = classConfig
+
+	return classConfig, nil
+}
+
+// ExtractAnnotations parses the annotations of the Ingress with the defaults
+// of its IngressClass, adding the snippets of the class. When the ConfigMap
+// of the class cannot be read, a warning Event is emitted on the Ingress and
+// the global configuration is used.
+func (s *k8sStore) ExtractAnnotations(ing *networkingv1.Ingress) (*annotations.Ingress, error) {
+	classConfig, err := s.GetClassConfiguration(ing)
+	if err != nil {
+		klog.Warningf("Error reading the configuration of the IngressClass of ingress %v/%v, using the global configuration: %v", ing.Namespace, ing.Name, err)
+		if s.recorder != nil {
+			s.recorder.Eventf(ing, corev1.EventTypeWarning, "ClassConfiguration", "Using the global configuration: %v", err)
+		}
+	}
+	if classConfig == nil {
+		return s.annotations.Extract(ing)
+	}
+
+	parsed, err := classConfig.extractor.Extract(ing)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.ClassServerSnippet = classConfig.ServerSnippet
+	parsed.ClassLocationSnippet = classConfig.LocationSnippet
+	parsed.ClassAllowSnippetAnnotations = &classConfig.AllowSnippetAnnotations
+	parsed.ClassDefaultSSLCertificate = classConfig.DefaultSSLCertificate
+
+	return parsed, nil
+}
+
+// isClassConfigMap returns true if an IngressClass references the ConfigMap
+func (s *k8sStore) isClassConfigMap(key string) bool {
+	if s.listers.IngressClass.Store == nil {
+		return false
+	}
+
+	for _, item := range s.listers.IngressClass.List() {
+		ic, ok := item.(*networkingv1.IngressClass)
+		if ok && ic.Annotations[ingressclass.ConfigMapAnnotation] == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resetClassConfigurations forgets the configurations of the IngressClasses,
+// after a change of their ConfigMaps or of the global configuration
+func (s *k8sStore) resetClassConfigurations() {
+	s.classConfigsMu.Lock()
+	defer s.classConfigsMu.Unlock()
+
+	s.classConfigs = map[string]*ClassConfiguration{}
+}
+
+// syncClassIngresses parses again the annotations of the Ingresses of the
+// IngressClass, after a change of its ConfigMap
+func (s *k8sStore) syncClassIngresses(className string) {
+	for _, item := range s.listers.IngressWithAnnotation.List() {
+		ing, err := s.getIngress(k8s.MetaNamespaceKey(item))
+		if err != nil {
+			continue
+		}
+
+		if ing.Spec.IngressClassName != nil && *ing.Spec.IngressClassName == className {
+			s.syncIngress(ing)
+		}
+	}
+}
+
+// getIngressClassParams returns the NginxIngressClassParams referenced by the
+// parameters of the IngressClass, nil when it references other parameters
+func (s *k8sStore) getIngressClassParams(ic *networkingv1.IngressClass) (*v1alpha1.NginxIngressClassParams, error) {
+	ref := ic.Spec.Parameters
+	if ref == nil || ref.APIGroup == nil || *ref.APIGroup != v1alpha1.GroupName || ref.Kind != v1alpha1.NginxIngressClassParamsKind {
+		return nil, nil
+	}
+
+	if ref.Scope != nil && *ref.Scope != networkingv1.IngressClassParametersReferenceScopeCluster {
+		return nil, fmt.Errorf("%v is cluster scoped, the scope of the parameters must be %v",
+			v1alpha1.NginxIngressClassParamsKind, networkingv1.IngressClassParametersReferenceScopeCluster)
+	}
+
+	if s.listers.IngressClassParams.Store == nil {
+		return nil, fmt.Errorf("the %v are not watched, use the flag --enable-ingress-class-params", v1alpha1.NginxIngressClassParamsKind)
+	}
+
+	return s.listers.IngressClassParams.ByKey(ref.Name)
+}
+
+// isClassParamsSecret returns true if a NginxIngressClassParams uses the
+// Secret matching key as default certificate
+func (s *k8sStore) isClassParamsSecret(key string) bool {
+	if s.listers.IngressClassParams.Store == nil {
+		return false
+	}
+
+	for _, obj := range s.listers.IngressClassParams.List() {
+		params, err := toIngressClassParams(obj)
+		if err == nil && params.Spec.DefaultSSLCertificate == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handleClassParamsEvent synchronizes the default certificate of the
+// NginxIngressClassParams and parses again the annotations of the Ingresses
+// of the IngressClasses referencing it
+func (s *k8sStore) handleClassParamsEvent(obj interface{}, updateCh *channels.RingChannel) {
+	params, err := toIngressClassParams(obj)
+	if err != nil {
+		klog.Errorf("unexpected NginxIngressClassParams: %v", err)
+		return
+	}
+
+	if params.Spec.DefaultSSLCertificate != "" {
+		s.syncSecret(params.Spec.DefaultSSLCertificate)
+	}
+
+	s.resetClassConfigurations()
+
+	if s.listers.IngressClass.Store != nil {
+		for _, item := range s.listers.IngressClass.List() {
+			ic, ok := item.(*networkingv1.IngressClass)
+			if !ok || ic.Spec.Parameters == nil || ic.Spec.Parameters.Kind != v1alpha1.NginxIngressClassParamsKind ||
+				ic.Spec.Parameters.Name != params.Name {
+				continue
+			}
+			s.syncClassIngresses(ic.Name)
+		}
+	}
+
+	updateCh.In() <- Event{
+		Type: UpdateEvent,
+		Obj:  obj,
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestExtractAnnotationsWithClassConfiguration(t *testing.T) {
+	globalData := map[string]string{
+		"proxy-body-size":    "2m",
+		"proxy-read-timeout": "30",
+	}
+
+	s := &k8sStore{
+		listers:           &Lister{},
+		backendConfig:     ngx_config.NewDefault(),
+		backendConfigMu:   &sync.RWMutex{},
+		backendConfigData: globalData,
+		classConfigs:      map[string]*ClassConfiguration{},
+	}
+	s.annotations = annotations.NewAnnotationExtractor(s)
+	s.listers.IngressClass.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	s.listers.ConfigMap.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	for _, ic := range []*networkingv1.IngressClass{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "internal",
+				Annotations: map[string]string{ingressclass.ConfigMapAnnotation: "default/internal"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "public"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "missing",
+				Annotations: map[string]string{ingressclass.ConfigMapAnnotation: "default/missing"},
+			},
+		},
+	} {
+		if err := s.listers.IngressClass.Add(ic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := s.listers.ConfigMap.Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "default"},
+		Data: map[string]string{
+			"proxy-body-size":  "20m",
+			"server-snippet":   "set $internal true;",
+			"location-snippet": "more_set_headers \"X-Internal: true\";",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.isClassConfigMap("default/internal") || s.isClassConfigMap("default/other") {
+		t.Errorf("expected only default/internal to be the ConfigMap of an IngressClass")
+	}
+
+	newIngress := func(class string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+			Spec:       networkingv1.IngressSpec{IngressClassName: &class},
+		}
+	}
+
+	parsed, err := s.ExtractAnnotations(newIngress("internal"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Proxy.BodySize != "20m" {
+		t.Errorf("expected the body size of the class but got %v", parsed.Proxy.BodySize)
+	}
+	if parsed.Proxy.ReadTimeout != 30 {
+		t.Errorf("expected the global read timeout but got %v", parsed.Proxy.ReadTimeout)
+	}
+	if parsed.ClassServerSnippet != "set $internal true;" || parsed.ClassLocationSnippet != "more_set_headers \"X-Internal: true\";" {
+		t.Errorf("expected the snippets of the class but got %q and %q", parsed.ClassServerSnippet, parsed.ClassLocationSnippet)
+	}
+
+	if classConfig, err := s.GetClassConfiguration(newIngress("public")); classConfig != nil || err != nil {
+		t.Errorf("expected no configuration for a class without ConfigMap but got %v, %v", classConfig, err)
+	}
+	parsed, err = s.ExtractAnnotations(newIngress("public"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.ClassServerSnippet != "" || parsed.ClassLocationSnippet != "" {
+		t.Errorf("expected no snippets for a class without ConfigMap")
+	}
+
+	recorder := record.NewFakeRecorder(1)
+	s.recorder = recorder
+	if _, err := s.GetClassConfiguration(newIngress("missing")); err == nil {
+		t.Errorf("expected an error for a class with a missing ConfigMap")
+	}
+	parsed, err = s.ExtractAnnotations(newIngress("missing"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Proxy.BodySize != s.GetDefaultBackend().ProxyBodySize {
+		t.Errorf("expected the global body size for a class with a missing ConfigMap but got %v", parsed.Proxy.BodySize)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning ClassConfiguration") || !strings.Contains(event, "default/missing") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Errorf("expected a warning Event for a class with a missing ConfigMap")
+	}
+}
+
+func TestExtractAnnotationsWithClassParameters(t *testing.T) {
+	s := &k8sStore{
+		listers:           &Lister{},
+		backendConfig:     ngx_config.NewDefault(),
+		backendConfigMu:   &sync.RWMutex{},
+		backendConfigData: map[string]string{"proxy-body-size": "2m"},
+		classConfigs:      map[string]*ClassConfiguration{},
+	}
+	s.annotations = annotations.NewAnnotationExtractor(s)
+	s.listers.IngressClass.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	s.listers.IngressClassParams.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	apiGroup := v1alpha1.GroupName
+	namespaceScope := networkingv1.IngressClassParametersReferenceScopeNamespace
+	for _, ic := range []*networkingv1.IngressClass{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "trusted"},
+			Spec: networkingv1.IngressClassSpec{
+				Parameters: &networkingv1.IngressClassParametersReference{
+					APIGroup: &apiGroup,
+					Kind:     v1alpha1.NginxIngressClassParamsKind,
+					Name:     "trusted",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "namespaced"},
+			Spec: networkingv1.IngressClassSpec{
+				Parameters: &networkingv1.IngressClassParametersReference{
+					APIGroup: &apiGroup,
+					Kind:     v1alpha1.NginxIngressClassParamsKind,
+					Name:     "trusted",
+					Scope:    &namespaceScope,
+				},
+			},
+		},
+	} {
+		if err := s.listers.IngressClass.Add(ic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	allowSnippets := true
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.NginxIngressClassParams{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: v1alpha1.NginxIngressClassParamsKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "trusted"},
+		Spec: v1alpha1.NginxIngressClassParamsSpec{
+			DefaultSSLCertificate:   "default/wildcard",
+			AllowSnippetAnnotations: &allowSnippets,
+			AnnotationsRiskLevel:    "Critical",
+			ServerSnippet:           "set $trusted true;",
+			Config:                  map[string]string{"proxy-body-size": "50m"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.listers.IngressClassParams.Add(&unstructured.Unstructured{Object: obj}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.isClassParamsSecret("default/wildcard") || s.isClassParamsSecret("default/other") {
+		t.Errorf("expected only default/wildcard to be the certificate of a NginxIngressClassParams")
+	}
+
+	newIngress := func(class string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+			Spec:       networkingv1.IngressSpec{IngressClassName: &class},
+		}
+	}
+
+	classConfig, err := s.GetClassConfiguration(newIngress("trusted"))
+	if err != nil || classConfig == nil {
+		t.Fatalf("expected the configuration of the parameters but got %v", err)
+	}
+	if classConfig.Security.AnnotationsRiskLevel != "Critical" {
+		t.Errorf("expected the annotations risk level of the parameters but got %v", classConfig.Security.AnnotationsRiskLevel)
+	}
+
+	parsed, err := s.ExtractAnnotations(newIngress("trusted"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Proxy.BodySize != "50m" {
+		t.Errorf("expected the body size of the parameters but got %v", parsed.Proxy.BodySize)
+	}
+	if parsed.ClassAllowSnippetAnnotations == nil || !*parsed.ClassAllowSnippetAnnotations {
+		t.Errorf("expected the snippet annotations to be allowed by the parameters")
+	}
+	if parsed.ClassDefaultSSLCertificate != "default/wildcard" {
+		t.Errorf("expected the default certificate of the parameters but got %q", parsed.ClassDefaultSSLCertificate)
+	}
+	if parsed.ClassServerSnippet != "set $trusted true;" {
+		t.Errorf("expected the server snippet of the parameters but got %q", parsed.ClassServerSnippet)
+	}
+
+	if classConfig, _ := s.GetClassConfiguration(newIngress("namespaced")); classConfig != nil {
+		t.Errorf("expected no configuration for namespaced parameters")
+	}
+}
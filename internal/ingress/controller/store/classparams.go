@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// IngressClassParamsLister makes a Store that lists NginxIngressClassParams.
+type IngressClassParamsLister struct {
+	cache.Store
+}
+
+// ByKey returns the NginxIngressClassParams matching key in the local Store.
+func (l IngressClassParamsLister) ByKey(key string) (*v1alpha1.NginxIngressClassParams, error) {
+	obj, exists, err := l.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, NotExistsError(key)
+	}
+	return toIngressClassParams(obj)
+}
+
+// toIngressClassParams converts an object of the dynamic informer to a
+// NginxIngressClassParams
+func toIngressClassParams(obj interface{}) (*v1alpha1.NginxIngressClassParams, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", obj)
+	}
+
+	params := &v1alpha1.NginxIngressClassParams{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), params); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
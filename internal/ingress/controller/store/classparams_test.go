@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestToIngressClassParams(t *testing.T) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.NginxIngressClassParams{
+		ObjectMeta: metav1.ObjectMeta{Name: "trusted"},
+		Spec:       v1alpha1.NginxIngressClassParamsSpec{DefaultSSLCertificate: "default/wildcard"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	params := &unstructured.Unstructured{Object: obj}
+
+	testCases := []struct {
+		title       string
+		obj         interface{}
+		expectedErr bool
+	}{
+		{"NginxIngressClassParams", params, false},
+		{"deleted NginxIngressClassParams", cache.DeletedFinalStateUnknown{Key: "trusted", Obj: params}, false},
+		{"unexpected type", &v1alpha1.NginxIngressClassParams{}, true},
+		{"invalid spec", &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "invalid"},
+			"spec":     map[string]interface{}{"allowSnippetAnnotations": "yes"},
+		}}, true},
+	}
+
+	for _, tc := range testCases {
+		result, err := toIngressClassParams(tc.obj)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%v: expected an error but got %+v", tc.title, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+			continue
+		}
+		if result.Name != "trusted" || result.Spec.DefaultSSLCertificate != "default/wildcard" {
+			t.Errorf("%v: expected the NginxIngressClassParams trusted but got %+v", tc.title, result)
+		}
+	}
+}
+
+func TestIngressClassParamsListerByKey(t *testing.T) {
+	lister := IngressClassParamsLister{cache.NewStore(cache.MetaNamespaceKeyFunc)}
+	if err := lister.Add(&unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "trusted"},
+	}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if params, err := lister.ByKey("trusted"); err != nil || params.Name != "trusted" {
+		t.Errorf("expected the NginxIngressClassParams trusted but got %+v, %v", params, err)
+	}
+
+	var notExists NotExistsError
+	if _, err := lister.ByKey("other"); !errors.As(err, &notExists) {
+		t.Errorf("expected a NotExistsError but got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// DenylistLister makes a Store that lists Denylists.
+type DenylistLister struct {
+	cache.Store
+}
+
+// List returns the Denylists of the local Store.
+func (l DenylistLister) List() []*v1alpha1.Denylist {
+	var denylists []*v1alpha1.Denylist
+	for _, obj := range l.Store.List() {
+		denylist := &v1alpha1.Denylist{}
+		if err := fromUnstructured(obj, denylist); err != nil {
+			klog.Errorf("unexpected Denylist: %v", err)
+			continue
+		}
+		denylists = append(denylists, denylist)
+	}
+	return denylists
+}
+
+// ListDenylists returns the Denylists of the store, none when they are not
+// watched.
+func (s *k8sStore) ListDenylists() []*v1alpha1.Denylist {
+	if s.listers.Denylist.Store == nil {
+		return nil
+	}
+
+	return s.listers.Denylist.List()
+}
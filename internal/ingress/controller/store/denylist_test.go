@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestDenylistListerList(t *testing.T) {
+	testCases := []struct {
+		title    string
+		objs     []interface{}
+		expected []string
+	}{
+		{"no Denylist", nil, nil},
+		{
+			"Denylists",
+			[]interface{}{
+				&v1alpha1.Denylist{
+					ObjectMeta: metav1.ObjectMeta{Name: "scanners", Namespace: "default"},
+					Spec:       v1alpha1.DenylistSpec{Entries: []v1alpha1.DenylistEntry{{CIDR: "192.0.2.0/24", Reason: "scanner"}}},
+				},
+			},
+			[]string{"192.0.2.0/24"},
+		},
+		{
+			"invalid Denylists are skipped",
+			[]interface{}{
+				map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "invalid", "namespace": "default"},
+					"spec":     map[string]interface{}{"entries": "192.0.2.1"},
+				},
+				&v1alpha1.Denylist{
+					ObjectMeta: metav1.ObjectMeta{Name: "abuse", Namespace: "default"},
+					Spec:       v1alpha1.DenylistSpec{Entries: []v1alpha1.DenylistEntry{{CIDR: "198.51.100.7/32"}}},
+				},
+			},
+			[]string{"198.51.100.7/32"},
+		},
+	}
+
+	for _, tc := range testCases {
+		lister := DenylistLister{cache.NewStore(cache.MetaNamespaceKeyFunc)}
+		for _, obj := range tc.objs {
+			content, ok := obj.(map[string]interface{})
+			if !ok {
+				var err error
+				content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+				if err != nil {
+					t.Fatalf("%v: unexpected error: %v", tc.title, err)
+				}
+			}
+			if err := lister.Add(&unstructured.Unstructured{Object: content}); err != nil {
+				t.Fatalf("%v: unexpected error: %v", tc.title, err)
+			}
+		}
+
+		var cidrs []string
+		for _, denylist := range lister.List() {
+			for _, entry := range denylist.Spec.Entries {
+				cidrs = append(cidrs, entry.CIDR)
+			}
+		}
+		if !reflect.DeepEqual(cidrs, tc.expected) {
+			t.Errorf("%v: expected %v but got %v", tc.title, tc.expected, cidrs)
+		}
+	}
+}
+
+func TestListDenylistsNotWatched(t *testing.T) {
+	s := &k8sStore{listers: &Lister{}}
+	if denylists := s.ListDenylists(); denylists != nil {
+		t.Errorf("expected no Denylist when they are not watched but got %v", denylists)
+	}
+}
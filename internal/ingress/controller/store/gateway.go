@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// GatewayGroupKind is the group and kind of the Gateways
+var GatewayGroupKind = schema.GroupKind{Group: gatewayv1.GroupName, Kind: "Gateway"}
+
+var (
+	// GatewayClassesResource is the resource of the GatewayClasses
+	GatewayClassesResource = gatewayv1.SchemeGroupVersion.WithResource("gatewayclasses")
+	// GatewaysResource is the resource of the Gateways
+	GatewaysResource = gatewayv1.SchemeGroupVersion.WithResource("gateways")
+	// HTTPRoutesResource is the resource of the HTTPRoutes
+	HTTPRoutesResource = gatewayv1.SchemeGroupVersion.WithResource("httproutes")
+	// TLSRoutesResource is the resource of the TLSRoutes
+	TLSRoutesResource = gatewayv1alpha2.SchemeGroupVersion.WithResource("tlsroutes")
+	// TCPRoutesResource is the resource of the TCPRoutes
+	TCPRoutesResource = gatewayv1alpha2.SchemeGroupVersion.WithResource("tcproutes")
+	// ReferenceGrantsResource is the resource of the ReferenceGrants
+	ReferenceGrantsResource = gatewayv1beta1.SchemeGroupVersion.WithResource("referencegrants")
+)
+
+// GatewayLister makes a Store that lists the Gateway API objects of a
+// resource.
+type GatewayLister struct {
+	cache.Store
+}
+
+// fromUnstructured converts an object of a dynamic informer to a Gateway API
+// type
+func fromUnstructured(obj, into interface{}) error {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected type: %T", obj)
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), into)
+}
+
+// ListGatewayClasses returns the GatewayClasses of the local Store.
+func (gl *GatewayLister) ListGatewayClasses() []*gatewayv1.GatewayClass {
+	var classes []*gatewayv1.GatewayClass
+	for _, obj := range gl.Store.List() {
+		class := &gatewayv1.GatewayClass{}
+		if err := fromUnstructured(obj, class); err != nil {
+			klog.Warningf("Error converting GatewayClass: %v", err)
+			continue
+		}
+		classes = append(classes, class)
+	}
+
+	return classes
+}
+
+// ListGateways returns the Gateways of the local Store.
+func (gl *GatewayLister) ListGateways() []*gatewayv1.Gateway {
+	var gateways []*gatewayv1.Gateway
+	for _, obj := range gl.Store.List() {
+		gateway := &gatewayv1.Gateway{}
+		if err := fromUnstructured(obj, gateway); err != nil {
+			klog.Warningf("Error converting Gateway: %v", err)
+			continue
+		}
+		gateways = append(gateways, gateway)
+	}
+
+	return gateways
+}
+
+// ListHTTPRoutes returns the HTTPRoutes of the local Store.
+func (gl *GatewayLister) ListHTTPRoutes() []*gatewayv1.HTTPRoute {
+	var routes []*gatewayv1.HTTPRoute
+	for _, obj := range gl.Store.List() {
+		route := &gatewayv1.HTTPRoute{}
+		if err := fromUnstructured(obj, route); err != nil {
+			klog.Warningf("Error converting HTTPRoute: %v", err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// ListTLSRoutes returns the TLSRoutes of the local Store.
+func (gl *GatewayLister) ListTLSRoutes() []*gatewayv1alpha2.TLSRoute {
+	var routes []*gatewayv1alpha2.TLSRoute
+	for _, obj := range gl.Store.List() {
+		route := &gatewayv1alpha2.TLSRoute{}
+		if err := fromUnstructured(obj, route); err != nil {
+			klog.Warningf("Error converting TLSRoute: %v", err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// ListTCPRoutes returns the TCPRoutes of the local Store.
+func (gl *GatewayLister) ListTCPRoutes() []*gatewayv1alpha2.TCPRoute {
+	var routes []*gatewayv1alpha2.TCPRoute
+	for _, obj := range gl.Store.List() {
+		route := &gatewayv1alpha2.TCPRoute{}
+		if err := fromUnstructured(obj, route); err != nil {
+			klog.Warningf("Error converting TCPRoute: %v", err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// ListReferenceGrants returns the ReferenceGrants of the local Store.
+func (gl *GatewayLister) ListReferenceGrants() []*gatewayv1beta1.ReferenceGrant {
+	var grants []*gatewayv1beta1.ReferenceGrant
+	for _, obj := range gl.Store.List() {
+		grant := &gatewayv1beta1.ReferenceGrant{}
+		if err := fromUnstructured(obj, grant); err != nil {
+			klog.Warningf("Error converting ReferenceGrant: %v", err)
+			continue
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants
+}
@@ -0,0 +1,466 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// The host ownership policies decide which namespaces can define the rules
+// of a host, the rules of the other namespaces are dropped.
+const (
+	// HostOwnershipMerge merges the rules of all the Ingresses of a host
+	HostOwnershipMerge = "merge"
+	// HostOwnershipFirstClaim reserves a host to the namespace of its oldest
+	// Ingress
+	HostOwnershipFirstClaim = "first-claim"
+	// HostOwnershipCRD reserves the hosts of the HostOwnerships to their
+	// namespaces, the other hosts to the namespace of their oldest Ingress
+	HostOwnershipCRD = "crd"
+	// HostOwnershipNamespaceSuffixes reserves the hosts matching the
+	// namespace-host-suffixes of the configmap to their namespaces, the other
+	// hosts to the namespace of their oldest Ingress
+	HostOwnershipNamespaceSuffixes = "namespace-suffixes"
+)
+
+// HostOwnershipPolicies are the valid host ownership policies
+var HostOwnershipPolicies = []string{
+	HostOwnershipMerge,
+	HostOwnershipFirstClaim,
+	HostOwnershipCRD,
+	HostOwnershipNamespaceSuffixes,
+}
+
+// HostOwnershipLister makes a Store that lists HostOwnerships.
+type HostOwnershipLister struct {
+	cache.Store
+}
+
+// List returns the HostOwnerships of the local Store.
+func (l HostOwnershipLister) List() []*v1alpha1.HostOwnership {
+	var ownerships []*v1alpha1.HostOwnership
+	for _, obj := range l.Store.List() {
+		ownership := &v1alpha1.HostOwnership{}
+		if err := fromUnstructured(obj, ownership); err != nil {
+			klog.Errorf("unexpected HostOwnership: %v", err)
+			continue
+		}
+		ownerships = append(ownerships, ownership)
+	}
+	return ownerships
+}
+
+// hostReservation reserves a domain, its subdomains or both to some
+// namespaces
+type hostReservation struct {
+	domain     string
+	apex       bool
+	subdomains bool
+	namespaces sets.Set[string]
+}
+
+// matches returns true if the reservation applies to the host
+func (r hostReservation) matches(host string) bool {
+	return (r.apex && host == r.domain) || (r.subdomains && strings.HasSuffix(host, "."+r.domain))
+}
+
+// hostPolicy decides which namespaces can use the hosts
+type hostPolicy struct {
+	reservations []hostReservation
+	// restricted are the namespaces which can only use their reserved hosts
+	restricted sets.Set[string]
+	// claims are the namespaces owning the hosts which are not reserved, by
+	// host
+	claims map[string]string
+}
+
+// reserved returns the namespaces of the most specific reservations of the
+// host, nil if the host is not reserved
+func (p *hostPolicy) reserved(host string) sets.Set[string] {
+	var namespaces sets.Set[string]
+	longest := -1
+	for _, r := range p.reservations {
+		if !r.matches(host) || len(r.domain) < longest {
+			continue
+		}
+		if len(r.domain) > longest {
+			namespaces = sets.New[string]()
+			longest = len(r.domain)
+		}
+		namespaces = namespaces.Union(r.namespaces)
+	}
+	return namespaces
+}
+
+// allowed returns an error if the namespace cannot use the host
+func (p *hostPolicy) allowed(host, namespace string) error {
+	if namespaces := p.reserved(host); namespaces != nil {
+		if namespaces.Has(namespace) {
+			return nil
+		}
+		return fmt.Errorf("host %q is reserved to the namespaces %v", host, strings.Join(sets.List(namespaces), ", "))
+	}
+
+	if p.restricted.Has(namespace) {
+		return fmt.Errorf("host %q does not match the host suffixes of the namespace %v", host, namespace)
+	}
+
+	if owner, ok := p.claims[host]; ok && owner != namespace {
+		return fmt.Errorf("host %q is owned by the namespace %v", host, owner)
+	}
+
+	return nil
+}
+
+// HostClaimsConfigMapSuffix is appended to the election ID to name the
+// ConfigMap storing the claims of the hosts, in the namespace of the
+// controller
+const HostClaimsConfigMapSuffix = "-host-claims"
+
+// hostClaimsKey is the key of the ConfigMap with the claims of the hosts, a
+// JSON object of the namespaces by host
+const hostClaimsKey = "claims"
+
+// catchAllHost is the host of the rules without host and of the default
+// backends, served by the catch-all server
+const catchAllHost = "_"
+
+// hostOwnership stores the claims of the hosts in a ConfigMap, so the hosts
+// stay owned by their namespace across the restarts and the replicas
+type hostOwnership struct {
+	client    clientset.Interface
+	namespace string
+	name      string
+
+	lock sync.Mutex
+	// conflicts are the conflicts already reported, ns/name/host
+	conflicts sets.Set[string]
+}
+
+// hostOwnershipResult is the host ownership policy applied to the Ingresses
+type hostOwnershipResult struct {
+	policy *hostPolicy
+	// ingresses are the Ingresses without the rules of the hosts they cannot
+	// use
+	ingresses []*ingress.Ingress
+	conflicts []hostConflict
+}
+
+// hostConflict is a host an Ingress cannot use
+type hostConflict struct {
+	ing  *ingress.Ingress
+	host string
+	err  error
+}
+
+// storedHostClaims returns the claims of the hosts of the ConfigMap
+func (s *k8sStore) storedHostClaims() map[string]string {
+	claims := map[string]string{}
+	if s.hostOwnership.name == "" {
+		return claims
+	}
+
+	cm, err := s.listers.HostClaims.ByKey(s.hostOwnership.namespace + "/" + s.hostOwnership.name)
+	if err != nil {
+		return claims
+	}
+	if data := cm.Data[hostClaimsKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &claims); err != nil {
+			klog.Warningf("Ignoring the invalid claims of the hosts of ConfigMap %v/%v: %v", cm.Namespace, cm.Name, err)
+			return map[string]string{}
+		}
+	}
+	return claims
+}
+
+// storeHostClaims replaces the claims of the hosts of the ConfigMap
+func (s *k8sStore) storeHostClaims(claims map[string]string) error {
+	if s.hostOwnership.client == nil || s.hostOwnership.name == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+
+	configMaps := s.hostOwnership.client.CoreV1().ConfigMaps(s.hostOwnership.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(context.TODO(), s.hostOwnership.name, metav1.GetOptions{})
+		if k8sErrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      s.hostOwnership.name,
+					Namespace: s.hostOwnership.namespace,
+				},
+				Data: map[string]string{hostClaimsKey: string(data)},
+			}
+			_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[hostClaimsKey] = string(data)
+		_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// hostPolicy returns the current policy of the hosts, with the claims
+func (s *k8sStore) hostPolicy(claims map[string]string) *hostPolicy {
+	policy := &hostPolicy{
+		restricted: sets.New[string](),
+		claims:     claims,
+	}
+	switch s.hostOwnershipPolicy {
+	case HostOwnershipCRD:
+		for _, ownership := range s.listers.HostOwnership.List() {
+			namespaces := sets.New[string](ownership.Spec.Namespaces...)
+			for _, host := range ownership.Spec.Hosts {
+				// *.example.com only reserves the subdomains of example.com
+				if domain, ok := strings.CutPrefix(host, "*."); ok {
+					policy.reservations = append(policy.reservations,
+						hostReservation{domain: domain, subdomains: true, namespaces: namespaces})
+					continue
+				}
+				policy.reservations = append(policy.reservations,
+					hostReservation{domain: host, apex: true, namespaces: namespaces})
+			}
+		}
+	case HostOwnershipNamespaceSuffixes:
+		for namespace, suffixes := range s.GetBackendConfiguration().NamespaceHostSuffixes {
+			policy.restricted.Insert(namespace)
+			for _, suffix := range suffixes {
+				suffix = strings.TrimPrefix(strings.TrimPrefix(suffix, "*"), ".")
+				if suffix == "" {
+					continue
+				}
+				policy.reservations = append(policy.reservations,
+					hostReservation{domain: suffix, apex: true, subdomains: true, namespaces: sets.New[string](namespace)})
+			}
+		}
+	}
+
+	return policy
+}
+
+// ruleHost returns the host of a rule, the catch-all host without host
+func ruleHost(host string) string {
+	if host == "" {
+		return catchAllHost
+	}
+	return host
+}
+
+// ingressHosts returns the hosts of the rules, of the default backend and of
+// the TLS sections of the Ingress
+func ingressHosts(ing *networking.Ingress) []string {
+	hosts := sets.New[string]()
+	if ing.Spec.DefaultBackend != nil {
+		hosts.Insert(catchAllHost)
+	}
+	for i := range ing.Spec.Rules {
+		hosts.Insert(ruleHost(ing.Spec.Rules[i].Host))
+	}
+	for i := range ing.Spec.TLS {
+		for _, host := range ing.Spec.TLS[i].Hosts {
+			if host != "" {
+				hosts.Insert(host)
+			}
+		}
+	}
+	return sets.List(hosts)
+}
+
+// withoutHosts returns a copy of the Ingress without the rules and the TLS
+// hosts of the hosts, and without default backend with the catch-all host
+func withoutHosts(ing *ingress.Ingress, hosts sets.Set[string]) *ingress.Ingress {
+	filtered := &ingress.Ingress{
+		Ingress:           *ing.Ingress.DeepCopy(),
+		ParsedAnnotations: ing.ParsedAnnotations,
+	}
+
+	var rules []networking.IngressRule
+	for i := range filtered.Spec.Rules {
+		if !hosts.Has(ruleHost(filtered.Spec.Rules[i].Host)) {
+			rules = append(rules, filtered.Spec.Rules[i])
+		}
+	}
+	filtered.Spec.Rules = rules
+
+	if hosts.Has(catchAllHost) {
+		filtered.Spec.DefaultBackend = nil
+	}
+
+	var tls []networking.IngressTLS
+	for i := range filtered.Spec.TLS {
+		t := filtered.Spec.TLS[i]
+		if len(t.Hosts) == 0 {
+			tls = append(tls, t)
+			continue
+		}
+
+		var tlsHosts []string
+		for _, host := range t.Hosts {
+			if !hosts.Has(host) {
+				tlsHosts = append(tlsHosts, host)
+			}
+		}
+		if len(tlsHosts) > 0 {
+			t.Hosts = tlsHosts
+			tls = append(tls, t)
+		}
+	}
+	filtered.Spec.TLS = tls
+
+	return filtered
+}
+
+// evaluateHostOwnership applies the host ownership policy to the Ingresses,
+// sorted oldest first. The hosts which are not reserved are claimed by the
+// namespace of their oldest Ingress and stay owned by it as long as it uses
+// them, the claims of the ConfigMap are kept while they are used.
+func (s *k8sStore) evaluateHostOwnership(ingresses []*ingress.Ingress) *hostOwnershipResult {
+	policy := s.hostPolicy(s.storedHostClaims())
+
+	// release the claims of the hosts no longer used by their namespace
+	used := map[string]sets.Set[string]{}
+	for _, ing := range ingresses {
+		for _, host := range ingressHosts(&ing.Ingress) {
+			if used[host] == nil {
+				used[host] = sets.New[string]()
+			}
+			used[host].Insert(ing.Namespace)
+		}
+	}
+	for host, namespace := range policy.claims {
+		if !used[host].Has(namespace) || policy.reserved(host) != nil {
+			delete(policy.claims, host)
+		}
+	}
+
+	result := &hostOwnershipResult{
+		policy:    policy,
+		ingresses: make([]*ingress.Ingress, 0, len(ingresses)),
+	}
+	for _, ing := range ingresses {
+		denied := sets.New[string]()
+		for _, host := range ingressHosts(&ing.Ingress) {
+			if err := policy.allowed(host, ing.Namespace); err != nil {
+				denied.Insert(host)
+				result.conflicts = append(result.conflicts, hostConflict{ing: ing, host: host, err: err})
+				continue
+			}
+
+			if policy.reserved(host) == nil && !policy.restricted.Has(ing.Namespace) {
+				if _, ok := policy.claims[host]; !ok {
+					policy.claims[host] = ing.Namespace
+				}
+			}
+		}
+
+		if denied.Len() == 0 {
+			result.ingresses = append(result.ingresses, ing)
+			continue
+		}
+		result.ingresses = append(result.ingresses, withoutHosts(ing, denied))
+	}
+
+	return result
+}
+
+// applyHostOwnership drops the rules of the hosts the Ingresses, sorted
+// oldest first, cannot use with the host ownership policy
+func (s *k8sStore) applyHostOwnership(ingresses []*ingress.Ingress) []*ingress.Ingress {
+	if s.hostOwnershipPolicy == "" || s.hostOwnershipPolicy == HostOwnershipMerge {
+		return ingresses
+	}
+
+	return s.evaluateHostOwnership(ingresses).ingresses
+}
+
+// SyncHostOwnership reports the conflicts of the host ownership policy and
+// stores the claims of the hosts in the ConfigMap.
+func (s *k8sStore) SyncHostOwnership() error {
+	if s.hostOwnershipPolicy == "" || s.hostOwnershipPolicy == HostOwnershipMerge {
+		return nil
+	}
+
+	result := s.evaluateHostOwnership(s.sortedIngresses())
+
+	s.hostOwnership.lock.Lock()
+	conflicts := sets.New[string]()
+	for _, c := range result.conflicts {
+		conflict := fmt.Sprintf("%v/%v/%v", c.ing.Namespace, c.ing.Name, c.host)
+		conflicts.Insert(conflict)
+		if s.hostOwnership.conflicts.Has(conflict) {
+			continue
+		}
+		klog.Warningf("Ignoring the rules of ingress %v/%v: %v", c.ing.Namespace, c.ing.Name, c.err)
+		if s.recorder != nil {
+			s.recorder.Eventf(&c.ing.Ingress, corev1.EventTypeWarning, "HostNotAllowed", "Ignoring the rules: %v", c.err)
+		}
+	}
+	s.hostOwnership.conflicts = conflicts
+	s.hostOwnership.lock.Unlock()
+
+	if reflect.DeepEqual(result.policy.claims, s.storedHostClaims()) {
+		return nil
+	}
+	return s.storeHostClaims(result.policy.claims)
+}
+
+// CheckHostOwnership returns an error if the host ownership policy does not
+// permit the namespace of the Ingress to use its hosts.
+func (s *k8sStore) CheckHostOwnership(ing *networking.Ingress) error {
+	if s.hostOwnershipPolicy == "" || s.hostOwnershipPolicy == HostOwnershipMerge {
+		return nil
+	}
+
+	policy := s.evaluateHostOwnership(s.sortedIngresses()).policy
+	for _, host := range ingressHosts(ing) {
+		if err := policy.allowed(host, ing.Namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func newHostOwnershipStore(t *testing.T, policy string, ingresses ...*ingress.Ingress) *k8sStore {
+	return newHostOwnershipStoreWithClient(t, fake.NewSimpleClientset(), policy, ingresses...)
+}
+
+func newHostOwnershipStoreWithClient(t *testing.T, client *fake.Clientset, policy string, ingresses ...*ingress.Ingress) *k8sStore {
+	s := &k8sStore{
+		listers:             &Lister{},
+		backendConfig:       ngx_config.NewDefault(),
+		backendConfigMu:     &sync.RWMutex{},
+		hostOwnershipPolicy: policy,
+		hostOwnership: &hostOwnership{
+			client:    client,
+			namespace: "ingress-nginx",
+			name:      "ingress-controller-leader" + HostClaimsConfigMapSuffix,
+			conflicts: sets.New[string](),
+		},
+	}
+	s.listers.HostOwnership.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	s.listers.HostClaims.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	// the informer of the ConfigMap of the claims
+	if cm, err := client.CoreV1().ConfigMaps("ingress-nginx").Get(context.TODO(), s.hostOwnership.name, metav1.GetOptions{}); err == nil {
+		if err := s.listers.HostClaims.Add(cm); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	s.listers.IngressWithAnnotation.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, ing := range ingresses {
+		if err := s.listers.IngressWithAnnotation.Add(ing); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return s
+}
+
+// syncHostOwnership stores the claims of the hosts and updates the local
+// copy of their ConfigMap
+func syncHostOwnership(t *testing.T, s *k8sStore) {
+	if err := s.SyncHostOwnership(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cm, err := s.hostOwnership.client.CoreV1().ConfigMaps(s.hostOwnership.namespace).Get(context.TODO(), s.hostOwnership.name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.listers.HostClaims.Update(cm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func newHostIngress(namespace, name string, age time.Duration, hosts ...string) *ingress.Ingress {
+	ing := &ingress.Ingress{
+		Ingress: networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(-age)),
+			},
+		},
+	}
+	for _, host := range hosts {
+		ing.Spec.Rules = append(ing.Spec.Rules, networking.IngressRule{Host: host})
+	}
+	return ing
+}
+
+func ingressRuleHosts(ingresses []*ingress.Ingress) map[string][]string {
+	hosts := map[string][]string{}
+	for _, ing := range ingresses {
+		key := ing.Namespace + "/" + ing.Name
+		hosts[key] = []string{}
+		for i := range ing.Spec.Rules {
+			hosts[key] = append(hosts[key], ing.Spec.Rules[i].Host)
+		}
+	}
+	return hosts
+}
+
+func TestHostOwnershipFirstClaim(t *testing.T) {
+	owner := newHostIngress("team", "app", time.Hour, "app.example.com")
+	tenant := newHostIngress("tenant", "app", time.Minute, "app.example.com", "tenant.example.com")
+	tenant.Spec.TLS = []networking.IngressTLS{{Hosts: []string{"app.example.com", "tenant.example.com"}, SecretName: "tls"}}
+	s := newHostOwnershipStore(t, HostOwnershipFirstClaim, owner, tenant)
+
+	expected := map[string][]string{
+		"team/app":   {"app.example.com"},
+		"tenant/app": {"tenant.example.com"},
+	}
+	ingresses := s.ListIngresses()
+	if hosts := ingressRuleHosts(ingresses); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v but got %v", expected, hosts)
+	}
+	for _, ing := range ingresses {
+		if ing.Namespace == "tenant" && !reflect.DeepEqual(ing.Spec.TLS[0].Hosts, []string{"tenant.example.com"}) {
+			t.Errorf("expected the TLS hosts of the tenant to be filtered but got %v", ing.Spec.TLS[0].Hosts)
+		}
+	}
+	if len(tenant.Spec.Rules) != 2 {
+		t.Errorf("expected the Ingress of the store not to be modified")
+	}
+
+	if err := s.CheckHostOwnership(&tenant.Ingress); err == nil {
+		t.Errorf("expected an error for a host owned by another namespace")
+	}
+	if err := s.CheckHostOwnership(&newHostIngress("team", "other", 0, "app.example.com").Ingress); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	syncHostOwnership(t, s)
+	expectedClaims := map[string]string{"app.example.com": "team", "tenant.example.com": "tenant"}
+	if claims := s.storedHostClaims(); !reflect.DeepEqual(claims, expectedClaims) {
+		t.Errorf("expected the claims %v but got %v", expectedClaims, claims)
+	}
+
+	// the host is released once its owner no longer uses it
+	if err := s.listers.IngressWithAnnotation.Delete(owner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = map[string][]string{
+		"tenant/app": {"app.example.com", "tenant.example.com"},
+	}
+	if hosts := ingressRuleHosts(s.ListIngresses()); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v but got %v", expected, hosts)
+	}
+	syncHostOwnership(t, s)
+
+	// and the claim of the new owner survives the return of an older Ingress,
+	// even after a restart of the controller
+	s = newHostOwnershipStoreWithClient(t, s.hostOwnership.client.(*fake.Clientset), HostOwnershipFirstClaim, owner, tenant)
+	expected = map[string][]string{
+		"team/app":   {},
+		"tenant/app": {"app.example.com", "tenant.example.com"},
+	}
+	if hosts := ingressRuleHosts(s.ListIngresses()); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v but got %v", expected, hosts)
+	}
+}
+
+func TestHostOwnershipListIngressesWithoutClaims(t *testing.T) {
+	owner := newHostIngress("team", "app", time.Hour, "app.example.com")
+	tenant := newHostIngress("tenant", "app", time.Minute, "app.example.com")
+	s := newHostOwnershipStore(t, HostOwnershipFirstClaim, owner, tenant)
+
+	s.ListIngresses()
+	if _, err := s.hostOwnership.client.CoreV1().ConfigMaps(s.hostOwnership.namespace).Get(context.TODO(), s.hostOwnership.name, metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the claims not to be stored when listing the Ingresses")
+	}
+
+	// the claims are only kept once stored
+	if err := s.listers.IngressWithAnnotation.Delete(owner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.ListIngresses()
+	if err := s.listers.IngressWithAnnotation.Add(owner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"team/app":   {"app.example.com"},
+		"tenant/app": {},
+	}
+	if hosts := ingressRuleHosts(s.ListIngresses()); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v but got %v", expected, hosts)
+	}
+}
+
+func TestHostOwnershipCatchAll(t *testing.T) {
+	owner := newHostIngress("team", "default", time.Hour, "")
+	tenant := newHostIngress("tenant", "default", time.Minute, "", "tenant.example.com")
+	tenant.Spec.DefaultBackend = &networking.IngressBackend{
+		Service: &networking.IngressServiceBackend{Name: "app", Port: networking.ServiceBackendPort{Number: 80}},
+	}
+	s := newHostOwnershipStore(t, HostOwnershipFirstClaim, owner, tenant)
+
+	expected := map[string][]string{
+		"team/default":   {""},
+		"tenant/default": {"tenant.example.com"},
+	}
+	ingresses := s.ListIngresses()
+	if hosts := ingressRuleHosts(ingresses); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v but got %v", expected, hosts)
+	}
+	for _, ing := range ingresses {
+		if ing.Namespace == "tenant" && ing.Spec.DefaultBackend != nil {
+			t.Errorf("expected the default backend of the tenant to be dropped")
+		}
+	}
+
+	if err := s.CheckHostOwnership(&newHostIngress("tenant", "other", 0, "").Ingress); err == nil {
+		t.Errorf("expected an error for the catch-all host owned by another namespace")
+	}
+}
+
+func TestHostOwnershipMerge(t *testing.T) {
+	s := newHostOwnershipStore(t, HostOwnershipMerge,
+		newHostIngress("team", "app", time.Hour, "app.example.com"),
+		newHostIngress("tenant", "app", time.Minute, "app.example.com"))
+
+	expected := map[string][]string{
+		"team/app":   {"app.example.com"},
+		"tenant/app": {"app.example.com"},
+	}
+	if hosts := ingressRuleHosts(s.ListIngresses()); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v but got %v", expected, hosts)
+	}
+}
+
+func TestHostOwnershipCRD(t *testing.T) {
+	s := newHostOwnershipStore(t, HostOwnershipCRD)
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.HostOwnership{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-com"},
+		Spec: v1alpha1.HostOwnershipSpec{
+			Hosts:      []string{"*.example.com", "example.org"},
+			Namespaces: []string{"team"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.listers.HostOwnership.Add(&unstructured.Unstructured{Object: obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		namespace string
+		host      string
+		allowed   bool
+	}{
+		{"team", "app.example.com", true},
+		{"team", "*.example.com", true},
+		{"team", "example.org", true},
+		{"tenant", "app.example.com", false},
+		{"tenant", "*.example.com", false},
+		{"tenant", "example.org", false},
+		{"tenant", "example.com", true},
+		{"tenant", "app.example.org", true},
+	}
+
+	for _, tc := range testCases {
+		err := s.CheckHostOwnership(&newHostIngress(tc.namespace, "app", 0, tc.host).Ingress)
+		if allowed := err == nil; allowed != tc.allowed {
+			t.Errorf("%v in %v: expected allowed to be %v but got %v", tc.host, tc.namespace, tc.allowed, err)
+		}
+	}
+}
+
+func TestHostOwnershipNamespaceSuffixes(t *testing.T) {
+	s := newHostOwnershipStore(t, HostOwnershipNamespaceSuffixes)
+	s.backendConfig.NamespaceHostSuffixes = map[string][]string{
+		"team":   {"example.com"},
+		"shared": {"*.shared.example.com", "example.org"},
+	}
+
+	testCases := []struct {
+		namespace string
+		host      string
+		allowed   bool
+	}{
+		{"team", "example.com", true},
+		{"team", "app.example.com", true},
+		{"team", "app.example.org", false},
+		{"team", "app.shared.example.com", false},
+		{"shared", "app.shared.example.com", true},
+		{"shared", "example.org", true},
+		{"shared", "app.example.com", false},
+		{"tenant", "app.example.com", false},
+		{"tenant", "app.example.net", true},
+	}
+
+	for _, tc := range testCases {
+		err := s.CheckHostOwnership(&newHostIngress(tc.namespace, "app", 0, tc.host).Ingress)
+		if allowed := err == nil; allowed != tc.allowed {
+			t.Errorf("%v in %v: expected allowed to be %v but got %v", tc.host, tc.namespace, tc.allowed, err)
+		}
+	}
+}
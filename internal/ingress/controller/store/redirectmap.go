@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+
+	"github.com/eapache/channels"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// RedirectMapLister makes a Store that lists RedirectMaps.
+type RedirectMapLister struct {
+	cache.Store
+}
+
+// ByKey returns the RedirectMap matching key in the local Store.
+func (l RedirectMapLister) ByKey(key string) (*v1alpha1.RedirectMap, error) {
+	obj, exists, err := l.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, NotExistsError(key)
+	}
+
+	rm := &v1alpha1.RedirectMap{}
+	if err := fromUnstructured(obj, rm); err != nil {
+		return nil, err
+	}
+	return rm, nil
+}
+
+// GetRedirectMap returns the RedirectMap matching key.
+func (s *k8sStore) GetRedirectMap(key string) (*v1alpha1.RedirectMap, error) {
+	if s.listers.RedirectMap.Store == nil {
+		return nil, fmt.Errorf("the RedirectMaps are not watched, use the flag --enable-redirect-maps")
+	}
+
+	return s.listers.RedirectMap.ByKey(key)
+}
+
+// handleRedirectMapEvent parses again the annotations of the Ingresses
+// referencing the RedirectMap
+func (s *k8sStore) handleRedirectMapEvent(obj interface{}, updateCh *channels.RingChannel) {
+	rm := &v1alpha1.RedirectMap{}
+	if err := fromUnstructured(obj, rm); err != nil {
+		klog.Errorf("unexpected RedirectMap: %v", err)
+		return
+	}
+
+	annotation := parser.GetAnnotationWithPrefix(redirectmap.RedirectMapAnnotation)
+	synced := false
+	for _, item := range s.listers.IngressWithAnnotation.List() {
+		ing, err := s.getIngress(k8s.MetaNamespaceKey(item))
+		if err != nil {
+			continue
+		}
+
+		if ing.Namespace == rm.Namespace && ing.Annotations[annotation] == rm.Name {
+			klog.InfoS("RedirectMap used in ingress annotations was changed. Parsing", "redirectMap", k8s.MetaNamespaceKey(rm), "ingress", klog.KObj(ing))
+			s.syncIngress(ing)
+			synced = true
+		}
+	}
+
+	if synced {
+		updateCh.In() <- Event{
+			Type: UpdateEvent,
+			Obj:  obj,
+		}
+	}
+}
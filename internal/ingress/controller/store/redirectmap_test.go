@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestRedirectMapListerByKey(t *testing.T) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.RedirectMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default"},
+		Spec: v1alpha1.RedirectMapSpec{
+			Redirects: []v1alpha1.Redirect{{Source: "/about-us", Target: "https://example.com/company"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lister := RedirectMapLister{cache.NewStore(cache.MetaNamespaceKeyFunc)}
+	for _, content := range []map[string]interface{}{
+		obj,
+		{
+			"metadata": map[string]interface{}{"name": "invalid", "namespace": "default"},
+			"spec":     map[string]interface{}{"statusCode": "moved"},
+		},
+	} {
+		if err := lister.Add(&unstructured.Unstructured{Object: content}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		title       string
+		key         string
+		expectedErr bool
+		notExists   bool
+	}{
+		{"RedirectMap", "default/legacy", false, false},
+		{"missing RedirectMap", "default/other", true, true},
+		{"invalid RedirectMap", "default/invalid", true, false},
+	}
+
+	for _, tc := range testCases {
+		rm, err := lister.ByKey(tc.key)
+		if !tc.expectedErr {
+			if err != nil || len(rm.Spec.Redirects) != 1 || rm.Spec.Redirects[0].Source != "/about-us" {
+				t.Errorf("%v: expected the redirects of the RedirectMap but got %+v, %v", tc.title, rm, err)
+			}
+			continue
+		}
+
+		var notExists NotExistsError
+		if err == nil || errors.As(err, &notExists) != tc.notExists {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+		}
+	}
+}
+
+func TestGetRedirectMapNotWatched(t *testing.T) {
+	s := &k8sStore{listers: &Lister{}}
+	if _, err := s.GetRedirectMap("default/legacy"); err == nil {
+		t.Errorf("expected an error when the RedirectMaps are not watched")
+	}
+}
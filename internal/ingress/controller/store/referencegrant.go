@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"github.com/eapache/channels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	klog "k8s.io/klog/v2"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+// secretGroupKind is the group and kind of the Secrets in the ReferenceGrants
+var secretGroupKind = schema.GroupKind{Kind: "Secret"}
+
+// referenceGrantAnnotations are the annotations referencing the Secrets of
+// other namespaces when a ReferenceGrant permits it
+var referenceGrantAnnotations = sets.New[string]("auth-tls-secret", "proxy-ssl-secret")
+
+// referenceGranted returns true if one of the ReferenceGrants of the
+// namespace of the object to permits the objects of the kind from of the
+// namespace fromNamespace to reference it.
+func referenceGranted(grants []*gatewayv1beta1.ReferenceGrant, from schema.GroupKind, fromNamespace string,
+	to schema.GroupKind, toNamespace, toName string,
+) bool {
+	if fromNamespace == toNamespace {
+		return true
+	}
+
+	for _, grant := range grants {
+		if grant.Namespace != toNamespace {
+			continue
+		}
+
+		fromGranted := false
+		for _, f := range grant.Spec.From {
+			if string(f.Group) == from.Group && string(f.Kind) == from.Kind && string(f.Namespace) == fromNamespace {
+				fromGranted = true
+				break
+			}
+		}
+		if !fromGranted {
+			continue
+		}
+
+		for _, t := range grant.Spec.To {
+			if string(t.Group) == to.Group && string(t.Kind) == to.Kind && (t.Name == nil || *t.Name == "" || string(*t.Name) == toName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsSecretReferenceGranted returns true if a ReferenceGrant permits the
+// objects of the kind from of the namespace fromNamespace to reference the
+// Secret, namespace/name.
+func (s *k8sStore) IsSecretReferenceGranted(from schema.GroupKind, fromNamespace, secret string) bool {
+	namespace, name, err := k8s.ParseNameNS(secret)
+	if err != nil {
+		return false
+	}
+
+	return referenceGranted(s.ListReferenceGrants(), from, fromNamespace, secretGroupKind, namespace, name)
+}
+
+// ListReferenceGrants returns the list of ReferenceGrants
+func (s *k8sStore) ListReferenceGrants() []*gatewayv1beta1.ReferenceGrant {
+	if s.informers.ReferenceGrant == nil {
+		return nil
+	}
+
+	return s.listers.ReferenceGrant.ListReferenceGrants()
+}
+
+// handleReferenceGrantEvent synchronizes the Ingresses and the Gateways of
+// the namespaces of a ReferenceGrant again, with the references it permits.
+func (s *k8sStore) handleReferenceGrantEvent(obj interface{}, updateCh *channels.RingChannel) {
+	grant := &gatewayv1beta1.ReferenceGrant{}
+	if err := fromUnstructured(obj, grant); err != nil {
+		klog.Errorf("unexpected ReferenceGrant: %v", err)
+		return
+	}
+
+	for _, gateway := range s.ListGateways() {
+		for _, key := range s.gatewaySecrets(gateway) {
+			s.syncSecret(key)
+		}
+	}
+
+	if s.GetSecurityConfiguration().ReferenceGrants {
+		namespaces := sets.New[string]()
+		for _, from := range grant.Spec.From {
+			if string(from.Group) == resolver.IngressGroupKind.Group && string(from.Kind) == resolver.IngressGroupKind.Kind {
+				namespaces.Insert(string(from.Namespace))
+			}
+		}
+
+		for _, item := range s.listers.IngressWithAnnotation.List() {
+			ing, err := s.getIngress(k8s.MetaNamespaceKey(item))
+			if err != nil || !namespaces.Has(ing.Namespace) {
+				continue
+			}
+
+			s.updateSecretIngressMap(ing)
+			s.syncSecrets(ing)
+			s.syncIngress(ing)
+		}
+	}
+
+	updateCh.In() <- Event{
+		Type: UpdateEvent,
+		Obj:  obj,
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestReferenceGranted(t *testing.T) {
+	name := gatewayv1beta1.ObjectName("tls")
+	grants := []*gatewayv1beta1.ReferenceGrant{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingresses", Namespace: "certificates"},
+			Spec: gatewayv1beta1.ReferenceGrantSpec{
+				From: []gatewayv1beta1.ReferenceGrantFrom{{Group: "networking.k8s.io", Kind: "Ingress", Namespace: "apps"}},
+				To:   []gatewayv1beta1.ReferenceGrantTo{{Kind: "Secret", Name: &name}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "gateways", Namespace: "certificates"},
+			Spec: gatewayv1beta1.ReferenceGrantSpec{
+				From: []gatewayv1beta1.ReferenceGrantFrom{{Group: gatewayv1.GroupName, Kind: "Gateway", Namespace: "infra"}},
+				To:   []gatewayv1beta1.ReferenceGrantTo{{Kind: "Secret"}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		fromIngress   bool
+		fromNamespace string
+		toNamespace   string
+		toName        string
+		expected      bool
+	}{
+		{"same namespace", true, "apps", "apps", "tls", true},
+		{"granted secret", true, "apps", "certificates", "tls", true},
+		{"other secret", true, "apps", "certificates", "other", false},
+		{"other namespace", true, "other", "certificates", "tls", false},
+		{"other kind", true, "infra", "certificates", "tls", false},
+		{"all the secrets", false, "infra", "certificates", "other", true},
+		{"namespace without grants", false, "infra", "other", "tls", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			from := GatewayGroupKind
+			if tc.fromIngress {
+				from = resolver.IngressGroupKind
+			}
+
+			granted := referenceGranted(grants, from, tc.fromNamespace, secretGroupKind, tc.toNamespace, tc.toName)
+			if granted != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, granted)
+			}
+		})
+	}
+}
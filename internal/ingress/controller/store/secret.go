@@ -24,11 +24,19 @@ import (
 // SecretLister makes a Store that lists Secrets.
 type SecretLister struct {
 	cache.Store
+
+	// watcher, when set, watches the Secrets on demand instead of Store
+	watcher *secretWatcher
 }
 
 // ByKey returns the Secret matching key in the local Secret Store.
 func (sl *SecretLister) ByKey(key string) (*apiv1.Secret, error) {
-	s, exists, err := sl.GetByKey(key)
+	getByKey := sl.GetByKey
+	if sl.watcher != nil {
+		getByKey = sl.watcher.GetByKey
+	}
+
+	s, exists, err := getByKey(key)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// secretWatchSyncTimeout is the time to wait for a Secret to be listed
+	// when it is watched
+	secretWatchSyncTimeout = 10 * time.Second
+	// secretWatchPruneInterval is the interval to stop watching the Secrets
+	// no longer referenced
+	secretWatchPruneInterval = time.Minute
+)
+
+// secretWatcher watches the Secrets on demand, each with an informer
+// restricted to its name by a field selector, instead of caching all the
+// Secrets of the watched namespaces. A Secret is watched when it is looked
+// up for the first time, and stops being watched when it is neither
+// referenced nor looked up anymore. A Secret failing to be listed, e.g.
+// forbidden, is looked up without waiting until it is listed by the retries
+// of its informer.
+type secretWatcher struct {
+	client       clientset.Interface
+	namespace    string
+	resyncPeriod time.Duration
+
+	// handler receives the events of the watched Secrets, except the
+	// addition of a Secret when it starts being watched
+	handler cache.ResourceEventHandler
+	// isReferenced returns true if the Secret matching key is referenced
+	// by an Ingress or another resource of the controller
+	isReferenced func(key string) bool
+
+	mu      sync.Mutex
+	watches map[string]*secretWatch
+}
+
+type secretWatch struct {
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+	// synced is closed when the Secret was listed, or failed to be
+	synced     chan struct{}
+	syncedOnce sync.Once
+	// err is the error listing the Secret when it failed to be, guarded by
+	// the mu of the watcher
+	err error
+	// used is true when the Secret was looked up since the last prune
+	used bool
+}
+
+func newSecretWatcher(client clientset.Interface, namespace string, resyncPeriod time.Duration) *secretWatcher {
+	return &secretWatcher{
+		client:       client,
+		namespace:    namespace,
+		resyncPeriod: resyncPeriod,
+		isReferenced: func(string) bool { return false },
+		watches:      map[string]*secretWatch{},
+	}
+}
+
+// Run prunes the Secrets no longer referenced until stopCh is closed, and
+// then stops watching all the Secrets.
+func (w *secretWatcher) Run(stopCh <-chan struct{}) {
+	wait.Until(w.prune, secretWatchPruneInterval, stopCh)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, sw := range w.watches {
+		close(sw.stopCh)
+		delete(w.watches, key)
+	}
+}
+
+// GetByKey returns the Secret matching key, watching it if it is not
+// watched yet.
+func (w *secretWatcher) GetByKey(key string) (item interface{}, exists bool, err error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if w.namespace != corev1.NamespaceAll && namespace != w.namespace {
+		return nil, false, nil
+	}
+
+	w.mu.Lock()
+	sw, ok := w.watches[key]
+	if !ok {
+		sw = w.watch(key, namespace, name)
+	}
+	sw.used = true
+	w.mu.Unlock()
+
+	<-sw.synced
+	if !sw.informer.HasSynced() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return nil, false, fmt.Errorf("error listing Secret %v: %w", key, sw.err)
+	}
+
+	return sw.informer.GetStore().GetByKey(key)
+}
+
+// watch starts watching the Secret matching key, it must be called with mu
+// locked.
+func (w *secretWatcher) watch(key, namespace, name string) *secretWatch {
+	klog.V(3).InfoS("Watching Secret", "name", key)
+
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+			options.FieldSelector = selector
+			return w.client.CoreV1().Secrets(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return w.client.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+		},
+	}, &corev1.Secret{}, w.resyncPeriod, cache.Indexers{})
+
+	sw := &secretWatch{
+		informer: informer,
+		stopCh:   make(chan struct{}),
+		synced:   make(chan struct{}),
+	}
+
+	if w.handler != nil {
+		// the Secret is looked up when it starts being watched, its
+		// addition is not an event
+		handler := cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if !isInInitialList {
+					w.handler.OnAdd(obj, false)
+				}
+			},
+			UpdateFunc: w.handler.OnUpdate,
+			DeleteFunc: w.handler.OnDelete,
+		}
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			klog.Errorf("Error adding secret event handler: %v", err)
+		}
+	}
+
+	// the lookups stop waiting for the Secret when it fails to be listed,
+	// the informer keeps retrying with backoff
+	err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		cache.DefaultWatchErrorHandler(r, err)
+		if !informer.HasSynced() {
+			w.syncFailed(sw, err)
+		}
+	})
+	if err != nil {
+		klog.Errorf("Error setting secret watch error handler: %v", err)
+	}
+
+	w.watches[key] = sw
+
+	go informer.Run(sw.stopCh)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), secretWatchSyncTimeout)
+		defer cancel()
+
+		if cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			sw.syncedOnce.Do(func() { close(sw.synced) })
+			return
+		}
+
+		klog.Warningf("Timed out waiting for Secret %v to sync", key)
+		w.syncFailed(sw, fmt.Errorf("timed out waiting for the Secret to sync"))
+	}()
+
+	return sw
+}
+
+// syncFailed records err as the error listing the Secret of sw and stops
+// the lookups waiting for it.
+func (w *secretWatcher) syncFailed(sw *secretWatch, err error) {
+	sw.syncedOnce.Do(func() {
+		w.mu.Lock()
+		sw.err = err
+		w.mu.Unlock()
+
+		close(sw.synced)
+	})
+}
+
+// prune stops watching the Secrets which are neither referenced nor looked
+// up since the last prune.
+func (w *secretWatcher) prune() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, sw := range w.watches {
+		if sw.used || w.isReferenced(key) {
+			sw.used = false
+			continue
+		}
+
+		klog.V(3).InfoS("Stopping to watch Secret", "name", key)
+		close(sw.stopCh)
+		delete(w.watches, key)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func TestSecretWatcher(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "default"}}
+	client := fake.NewSimpleClientset(secret)
+
+	added := make(chan interface{}, 1)
+	updated := make(chan interface{}, 1)
+	w := newSecretWatcher(client, "default", 0)
+	w.handler = cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { added <- obj },
+		UpdateFunc: func(_, cur interface{}) { updated <- cur },
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go w.Run(stopCh)
+
+	obj, exists, err := w.GetByKey("default/tls")
+	if err != nil || !exists {
+		t.Fatalf("expected Secret default/tls to exist, got %v, %v", exists, err)
+	}
+	if obj.(*corev1.Secret).Name != "tls" {
+		t.Errorf("expected Secret tls, got %v", obj.(*corev1.Secret).Name)
+	}
+
+	_, exists, err = w.GetByKey("other/tls")
+	if err != nil || exists {
+		t.Errorf("expected Secret of another namespace not to exist, got %v, %v", exists, err)
+	}
+
+	updatedSecret := secret.DeepCopy()
+	updatedSecret.Data = map[string][]byte{"tls.crt": []byte("crt")}
+	if _, err := client.CoreV1().Secrets("default").Update(context.TODO(), updatedSecret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error updating Secret: %v", err)
+	}
+
+	select {
+	case <-updated:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected an update event of Secret default/tls")
+	}
+	select {
+	case <-added:
+		t.Errorf("expected no add event when the Secret starts being watched")
+	default:
+	}
+
+	referenced := true
+	w.isReferenced = func(string) bool { return referenced }
+
+	w.prune()
+	w.prune()
+	if len(w.watches) != 1 {
+		t.Errorf("expected the referenced Secret to be watched")
+	}
+
+	referenced = false
+	w.prune()
+	if len(w.watches) != 0 {
+		t.Errorf("expected the Secret no longer referenced not to be watched")
+	}
+}
+
+func TestSecretWatcherForbiddenSecret(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "secrets", func(k8stesting.Action) (bool, k8sruntime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "tls", nil)
+	})
+
+	w := newSecretWatcher(client, "default", 0)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go w.Run(stopCh)
+
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		if _, exists, err := w.GetByKey("default/tls"); err == nil || exists {
+			t.Errorf("expected an error looking up a forbidden Secret, got %v, %v", exists, err)
+		}
+		if elapsed := time.Since(start); elapsed >= secretWatchSyncTimeout {
+			t.Errorf("expected the lookup of a forbidden Secret not to wait for the sync timeout, took %v", elapsed)
+		}
+	}
+
+	if len(w.watches) != 1 {
+		t.Errorf("expected the forbidden Secret to be watched once, got %v watches", len(w.watches))
+	}
+}
+
+func TestSecretWatcherKeepsLuaSecrets(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "canary-sticky", Namespace: "default"}}
+	client := fake.NewSimpleClientset(secret)
+
+	backendConfig := ngx_config.NewDefault()
+	backendConfig.CanaryStickySecret = "default/canary-sticky"
+	s := &k8sStore{
+		informers:        &Informer{},
+		listers:          &Lister{},
+		backendConfig:    backendConfig,
+		backendConfigMu:  &sync.RWMutex{},
+		secretIngressMap: NewObjectRefMap(),
+	}
+
+	w := newSecretWatcher(client, "default", 0)
+	w.isReferenced = s.isSecretReferenced
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go w.Run(stopCh)
+
+	if _, exists, err := w.GetByKey("default/canary-sticky"); err != nil || !exists {
+		t.Fatalf("expected Secret default/canary-sticky to exist, got %v, %v", exists, err)
+	}
+
+	w.prune()
+	w.prune()
+	if len(w.watches) != 1 {
+		t.Errorf("expected the Secret read by Lua to be watched")
+	}
+}
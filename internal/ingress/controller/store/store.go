@@ -32,10 +32,15 @@ import (
 	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -43,6 +48,8 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/ingress-nginx/internal/ingress/inspector"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 
 	"k8s.io/ingress-nginx/internal/nginx"
 	"k8s.io/ingress-nginx/pkg/util/file"
@@ -52,12 +59,14 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+	"k8s.io/ingress-nginx/internal/ingress/controller/sharding"
 	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 	"k8s.io/ingress-nginx/internal/k8s"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 )
 
 // IngressFilterFunc decides if an Ingress should be omitted or not
@@ -87,6 +96,39 @@ type Storer interface {
 	// ListIngresses returns a list of all Ingresses in the store.
 	ListIngresses() []*ingress.Ingress
 
+	// ListStreamRoutes returns a list of all StreamRoutes in the store.
+	ListStreamRoutes() []*v1alpha1.StreamRoute
+
+	// ListGatewayClasses returns a list of all GatewayClasses in the store.
+	ListGatewayClasses() []*gatewayv1.GatewayClass
+
+	// ListGateways returns a list of all Gateways in the store.
+	ListGateways() []*gatewayv1.Gateway
+
+	// ListHTTPRoutes returns a list of all HTTPRoutes in the store.
+	ListHTTPRoutes() []*gatewayv1.HTTPRoute
+
+	// ListTLSRoutes returns a list of all TLSRoutes in the store.
+	ListTLSRoutes() []*gatewayv1alpha2.TLSRoute
+
+	// ListTCPRoutes returns a list of all TCPRoutes in the store.
+	ListTCPRoutes() []*gatewayv1alpha2.TCPRoute
+
+	// IsSecretReferenceGranted returns true if a ReferenceGrant permits the
+	// objects of a kind and namespace to reference the Secret matching key.
+	IsSecretReferenceGranted(from schema.GroupKind, fromNamespace, secret string) bool
+
+	// CheckHostOwnership returns an error if the host ownership policy does
+	// not permit the namespace of the Ingress to use its hosts.
+	CheckHostOwnership(ing *networkingv1.Ingress) error
+
+	// SyncHostOwnership reports the conflicts of the host ownership policy
+	// and stores the claims of the hosts.
+	SyncHostOwnership() error
+
+	// ListDenylists returns a list of all Denylists in the store.
+	ListDenylists() []*v1alpha1.Denylist
+
 	// GetLocalSSLCert returns the local copy of a SSLCert
 	GetLocalSSLCert(name string) (*ingress.SSLCert, error)
 
@@ -106,6 +148,13 @@ type Storer interface {
 
 	// GetIngressClass validates given ingress against ingress class configuration and returns the ingress class.
 	GetIngressClass(ing *networkingv1.Ingress, icConfig *ingressclass.Configuration) (string, error)
+
+	// ListIngressClasses returns the IngressClasses of the controller.
+	ListIngressClasses() []*networkingv1.IngressClass
+
+	// ExtractAnnotations parses the annotations of the Ingress with the
+	// configuration of its IngressClass
+	ExtractAnnotations(ing *networkingv1.Ingress) (*annotations.Ingress, error)
 }
 
 // EventType type of event associated with an informer
@@ -137,6 +186,24 @@ type Informer struct {
 	Secret        cache.SharedIndexInformer
 	ConfigMap     cache.SharedIndexInformer
 	Namespace     cache.SharedIndexInformer
+	StreamRoute   cache.SharedIndexInformer
+
+	IngressClassParams cache.SharedIndexInformer
+
+	GatewayClass cache.SharedIndexInformer
+	Gateway      cache.SharedIndexInformer
+	HTTPRoute    cache.SharedIndexInformer
+	TLSRoute     cache.SharedIndexInformer
+	TCPRoute     cache.SharedIndexInformer
+
+	ReferenceGrant cache.SharedIndexInformer
+
+	HostOwnership cache.SharedIndexInformer
+	HostClaims    cache.SharedIndexInformer
+
+	WAFPolicy   cache.SharedIndexInformer
+	Denylist    cache.SharedIndexInformer
+	RedirectMap cache.SharedIndexInformer
 }
 
 // Lister contains object listers (stores).
@@ -149,6 +216,19 @@ type Lister struct {
 	ConfigMap             ConfigMapLister
 	Namespace             NamespaceLister
 	IngressWithAnnotation IngressWithAnnotationsLister
+	StreamRoute           StreamRouteLister
+	IngressClassParams    IngressClassParamsLister
+	GatewayClass          GatewayLister
+	Gateway               GatewayLister
+	HTTPRoute             GatewayLister
+	TLSRoute              GatewayLister
+	TCPRoute              GatewayLister
+	ReferenceGrant        GatewayLister
+	HostOwnership         HostOwnershipLister
+	HostClaims            ConfigMapLister
+	WAFPolicy             WAFPolicyLister
+	Denylist              DenylistLister
+	RedirectMap           RedirectMapLister
 }
 
 // NotExistsError is returned when an object does not exist in a local store.
@@ -161,7 +241,10 @@ func (e NotExistsError) Error() string {
 
 // Run initiates the synchronization of the informers against the API server.
 func (i *Informer) Run(stopCh chan struct{}) {
-	go i.Secret.Run(stopCh)
+	// the Secrets are not cached when only the referenced ones are watched
+	if i.Secret != nil {
+		go i.Secret.Run(stopCh)
+	}
 	go i.EndpointSlice.Run(stopCh)
 	if i.IngressClass != nil {
 		go i.IngressClass.Run(stopCh)
@@ -173,15 +256,101 @@ func (i *Informer) Run(stopCh chan struct{}) {
 	// from the queue
 	if !cache.WaitForCacheSync(stopCh,
 		i.Service.HasSynced,
-		i.Secret.HasSynced,
 		i.ConfigMap.HasSynced,
 	) {
 		runtime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 	}
+	if i.Secret != nil && !cache.WaitForCacheSync(stopCh, i.Secret.HasSynced) {
+		runtime.HandleError(fmt.Errorf("timed out waiting for secret caches to sync"))
+	}
 	if i.IngressClass != nil && !cache.WaitForCacheSync(stopCh, i.IngressClass.HasSynced) {
 		runtime.HandleError(fmt.Errorf("timed out waiting for ingress classcaches to sync"))
 	}
 
+	if i.StreamRoute != nil {
+		go i.StreamRoute.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.StreamRoute.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for stream route caches to sync"))
+		}
+	}
+
+	if i.IngressClassParams != nil {
+		go i.IngressClassParams.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.IngressClassParams.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for ingress class params caches to sync"))
+		}
+	}
+
+	if i.Gateway != nil {
+		go i.GatewayClass.Run(stopCh)
+		go i.Gateway.Run(stopCh)
+		go i.HTTPRoute.Run(stopCh)
+		go i.ReferenceGrant.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh,
+			i.GatewayClass.HasSynced,
+			i.Gateway.HasSynced,
+			i.HTTPRoute.HasSynced,
+			i.ReferenceGrant.HasSynced,
+		) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for gateway api caches to sync"))
+		}
+	}
+
+	if i.TLSRoute != nil {
+		go i.TLSRoute.Run(stopCh)
+		go i.TCPRoute.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh,
+			i.TLSRoute.HasSynced,
+			i.TCPRoute.HasSynced,
+		) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for gateway api experimental caches to sync"))
+		}
+	}
+
+	if i.HostOwnership != nil {
+		go i.HostOwnership.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.HostOwnership.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for host ownership caches to sync"))
+		}
+	}
+
+	if i.HostClaims != nil {
+		go i.HostClaims.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.HostClaims.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for host claims caches to sync"))
+		}
+	}
+
+	if i.WAFPolicy != nil {
+		go i.WAFPolicy.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.WAFPolicy.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for WAF policy caches to sync"))
+		}
+	}
+
+	if i.Denylist != nil {
+		go i.Denylist.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.Denylist.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for denylist caches to sync"))
+		}
+	}
+
+	if i.RedirectMap != nil {
+		go i.RedirectMap.Run(stopCh)
+
+		if !cache.WaitForCacheSync(stopCh, i.RedirectMap.HasSynced) {
+			runtime.HandleError(fmt.Errorf("timed out waiting for redirect map caches to sync"))
+		}
+	}
+
 	// when limit controller scope to one namespace, skip sync namespaces at cluster scope
 	if i.Namespace != nil {
 		go i.Namespace.Run(stopCh)
@@ -239,9 +408,35 @@ type k8sStore struct {
 	// backendConfigMu protects against simultaneous read/write of backendConfig
 	backendConfigMu *sync.RWMutex
 
+	// backendConfigData contains the data of the configmap, overridden by
+	// the ConfigMaps of the IngressClasses
+	backendConfigData map[string]string
+
+	// classConfigs contains the configurations of the IngressClasses with a
+	// ConfigMap, by name
+	classConfigs   map[string]*ClassConfiguration
+	classConfigsMu sync.Mutex
+
 	defaultSSLCertificate string
+
+	// secretWatcher watches the Secrets on demand when only the referenced
+	// ones are watched, nil otherwise
+	secretWatcher *secretWatcher
+
+	// referenceGrants authorizes the references of the annotations to the
+	// Secrets of other namespaces with the ReferenceGrants
+	referenceGrants bool
+
+	// hostOwnershipPolicy decides which namespaces can use the hosts
+	hostOwnershipPolicy string
+	hostOwnership       *hostOwnership
+
+	recorder record.EventRecorder
 }
 
+// errNotOwned is returned for the Ingresses owned by another shard
+var errNotOwned = fmt.Errorf("ingress is owned by another shard")
+
 // New creates a new object store to be used in the ingress controller.
 //
 //nolint:gocyclo // Ignore function complexity error.
@@ -251,10 +446,23 @@ func New(
 	configmap, tcp, udp, defaultSSLCertificate string,
 	resyncPeriod time.Duration,
 	client clientset.Interface,
+	watchReferencedSecrets bool,
+	streamRouteClient dynamic.Interface,
+	classParamsClient dynamic.Interface,
+	gatewayClient dynamic.Interface,
+	experimentalGatewayAPI bool,
+	referenceGrants bool,
+	hostOwnershipClient dynamic.Interface,
+	hostOwnershipPolicy string,
+	hostClaims string,
+	wafPolicyClient dynamic.Interface,
+	denylistClient dynamic.Interface,
+	redirectMapClient dynamic.Interface,
 	updateCh *channels.RingChannel,
 	disableCatchAll bool,
 	deepInspector bool,
 	icConfig *ingressclass.Configuration,
+	shard *sharding.Configuration,
 	disableSyncEvents bool,
 ) Storer {
 	store := &k8sStore{
@@ -267,6 +475,12 @@ func New(
 		backendConfigMu:       &sync.RWMutex{},
 		secretIngressMap:      NewObjectRefMap(),
 		defaultSSLCertificate: defaultSSLCertificate,
+		classConfigs:          map[string]*ClassConfiguration{},
+		hostOwnershipPolicy:   hostOwnershipPolicy,
+		hostOwnership: &hostOwnership{
+			client:    client,
+			conflicts: sets.New[string](),
+		},
 	}
 
 	eventBroadcaster := record.NewBroadcaster()
@@ -279,6 +493,7 @@ func New(
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
 		Component: "nginx-ingress-controller",
 	})
+	store.recorder = recorder
 
 	// k8sStore fulfills resolver.Resolver interface
 	store.annotations = annotations.NewAnnotationExtractor(store)
@@ -340,8 +555,14 @@ func New(
 	store.informers.EndpointSlice = infFactory.Discovery().V1().EndpointSlices().Informer()
 	store.listers.EndpointSlice.Store = store.informers.EndpointSlice.GetStore()
 
-	store.informers.Secret = infFactorySecrets.Core().V1().Secrets().Informer()
-	store.listers.Secret.Store = store.informers.Secret.GetStore()
+	if watchReferencedSecrets {
+		store.secretWatcher = newSecretWatcher(client, namespace, resyncPeriod)
+		store.secretWatcher.isReferenced = store.isSecretReferenced
+		store.listers.Secret.watcher = store.secretWatcher
+	} else {
+		store.informers.Secret = infFactorySecrets.Core().V1().Secrets().Informer()
+		store.listers.Secret.Store = store.informers.Secret.GetStore()
+	}
 
 	store.informers.ConfigMap = infFactoryConfigmaps.Core().V1().ConfigMaps().Informer()
 	store.listers.ConfigMap.Store = store.informers.ConfigMap.GetStore()
@@ -360,6 +581,115 @@ func New(
 		store.listers.Namespace.Store = store.informers.Namespace.GetStore()
 	}
 
+	// StreamRoutes are only watched when enabled, there is no typed client
+	// for them so a dynamic informer is used
+	if streamRouteClient != nil {
+		infFactoryStreamRoutes := dynamicinformer.NewFilteredDynamicSharedInformerFactory(streamRouteClient,
+			resyncPeriod, namespace, nil)
+
+		store.informers.StreamRoute = infFactoryStreamRoutes.ForResource(v1alpha1.StreamRoutesResource).Informer()
+		store.listers.StreamRoute.Store = store.informers.StreamRoute.GetStore()
+	}
+
+	// NginxIngressClassParams are cluster scoped, they are watched in all
+	// the namespaces
+	if classParamsClient != nil {
+		infFactoryClassParams := dynamicinformer.NewDynamicSharedInformerFactory(classParamsClient, resyncPeriod)
+
+		store.informers.IngressClassParams = infFactoryClassParams.ForResource(v1alpha1.NginxIngressClassParamsResource).Informer()
+		store.listers.IngressClassParams.Store = store.informers.IngressClassParams.GetStore()
+	}
+
+	// HostOwnerships are cluster scoped, they are watched in all the
+	// namespaces
+	if hostOwnershipClient != nil {
+		infFactoryHostOwnerships := dynamicinformer.NewDynamicSharedInformerFactory(hostOwnershipClient, resyncPeriod)
+
+		store.informers.HostOwnership = infFactoryHostOwnerships.ForResource(v1alpha1.HostOwnershipsResource).Informer()
+		store.listers.HostOwnership.Store = store.informers.HostOwnership.GetStore()
+	}
+
+	// the claims of the hosts are stored in a ConfigMap of the namespace of
+	// the controller, which may not be watched
+	store.listers.HostClaims.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	if hostClaims != "" {
+		ns, name, err := k8s.ParseNameNS(hostClaims)
+		if err != nil {
+			klog.Warningf("Unexpected error parsing the host claims ConfigMap %v: %v", hostClaims, err)
+		} else {
+			store.hostOwnership.namespace = ns
+			store.hostOwnership.name = name
+
+			infFactoryHostClaims := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod,
+				informers.WithNamespace(ns),
+				informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+					options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+				}))
+
+			store.informers.HostClaims = infFactoryHostClaims.Core().V1().ConfigMaps().Informer()
+			store.listers.HostClaims.Store = store.informers.HostClaims.GetStore()
+		}
+	}
+
+	// WAFPolicies are referenced by the annotations of the Ingresses of
+	// their namespace
+	if wafPolicyClient != nil {
+		infFactoryWAFPolicies := dynamicinformer.NewFilteredDynamicSharedInformerFactory(wafPolicyClient,
+			resyncPeriod, namespace, nil)
+
+		store.informers.WAFPolicy = infFactoryWAFPolicies.ForResource(v1alpha1.WAFPoliciesResource).Informer()
+		store.listers.WAFPolicy.Store = store.informers.WAFPolicy.GetStore()
+	}
+
+	// Denylists are cluster scoped, they are watched in all the namespaces
+	if denylistClient != nil {
+		infFactoryDenylists := dynamicinformer.NewDynamicSharedInformerFactory(denylistClient, resyncPeriod)
+
+		store.informers.Denylist = infFactoryDenylists.ForResource(v1alpha1.DenylistsResource).Informer()
+		store.listers.Denylist.Store = store.informers.Denylist.GetStore()
+	}
+
+	// RedirectMaps are referenced by the annotations of the Ingresses of
+	// their namespace
+	if redirectMapClient != nil {
+		infFactoryRedirectMaps := dynamicinformer.NewFilteredDynamicSharedInformerFactory(redirectMapClient,
+			resyncPeriod, namespace, nil)
+
+		store.informers.RedirectMap = infFactoryRedirectMaps.ForResource(v1alpha1.RedirectMapsResource).Informer()
+		store.listers.RedirectMap.Store = store.informers.RedirectMap.GetStore()
+	}
+
+	// the Gateway API objects are watched with dynamic informers, the
+	// GatewayClasses are cluster scoped
+	if gatewayClient != nil {
+		infFactoryGatewayClasses := dynamicinformer.NewDynamicSharedInformerFactory(gatewayClient, resyncPeriod)
+		infFactoryGateways := dynamicinformer.NewFilteredDynamicSharedInformerFactory(gatewayClient,
+			resyncPeriod, namespace, nil)
+
+		store.informers.GatewayClass = infFactoryGatewayClasses.ForResource(GatewayClassesResource).Informer()
+		store.listers.GatewayClass.Store = store.informers.GatewayClass.GetStore()
+
+		store.informers.Gateway = infFactoryGateways.ForResource(GatewaysResource).Informer()
+		store.listers.Gateway.Store = store.informers.Gateway.GetStore()
+
+		store.informers.HTTPRoute = infFactoryGateways.ForResource(HTTPRoutesResource).Informer()
+		store.listers.HTTPRoute.Store = store.informers.HTTPRoute.GetStore()
+
+		store.informers.ReferenceGrant = infFactoryGateways.ForResource(ReferenceGrantsResource).Informer()
+		store.listers.ReferenceGrant.Store = store.informers.ReferenceGrant.GetStore()
+		store.referenceGrants = referenceGrants
+
+		// the TLSRoutes and TCPRoutes are only installed with the
+		// experimental channel of the Gateway API
+		if experimentalGatewayAPI {
+			store.informers.TLSRoute = infFactoryGateways.ForResource(TLSRoutesResource).Informer()
+			store.listers.TLSRoute.Store = store.informers.TLSRoute.GetStore()
+
+			store.informers.TCPRoute = infFactoryGateways.ForResource(TCPRoutesResource).Informer()
+			store.listers.TCPRoute.Store = store.informers.TCPRoute.GetStore()
+		}
+	}
+
 	watchedNamespace := func(namespace string) bool {
 		if namespaceSelector == nil || namespaceSelector.Empty() {
 			return true
@@ -404,6 +734,10 @@ func New(
 			return
 		}
 
+		if !shard.Owns(ing) {
+			return
+		}
+
 		if hasCatchAllIngressRule(ing.Spec) && disableCatchAll {
 			klog.InfoS("Ignoring delete for catch-all because of --disable-catch-all", "ingress", klog.KObj(ing))
 			return
@@ -437,6 +771,11 @@ func New(
 				return
 			}
 
+			if !shard.Owns(ing) {
+				klog.V(3).InfoS("Ignoring ingress owned by another shard", "ingress", klog.KObj(ing))
+				return
+			}
+
 			klog.InfoS("Found valid IngressClass", "ingress", klog.KObj(ing), "ingressclass", ic)
 
 			if deepInspector {
@@ -476,6 +815,13 @@ func New(
 				_, errOld = store.GetIngressClass(oldIng, icConfig)
 				classCur, errCur = store.GetIngressClass(curIng, icConfig)
 			}
+			// a change of the shard label moves the Ingress to another shard
+			if errOld == nil && !shard.Owns(oldIng) {
+				errOld = errNotOwned
+			}
+			if errCur == nil && !shard.Owns(curIng) {
+				errCur = errNotOwned
+			}
 			switch {
 			case errOld != nil && errCur == nil:
 				if hasCatchAllIngressRule(curIng.Spec) && disableCatchAll {
@@ -486,7 +832,7 @@ func New(
 				klog.InfoS("creating ingress", "ingress", klog.KObj(curIng), "ingressclass", classCur)
 				recorder.Eventf(curIng, corev1.EventTypeNormal, "Sync", "Scheduled for sync")
 			case errOld == nil && errCur != nil:
-				klog.InfoS("removing ingress because of unknown ingressclass", "ingress", klog.KObj(curIng))
+				klog.InfoS("removing ingress because of unknown ingressclass or shard", "ingress", klog.KObj(curIng), "error", errCur)
 				ingDeleteHandler(old)
 				return
 			case errCur == nil && !reflect.DeepEqual(old, cur):
@@ -520,6 +866,69 @@ func New(
 		},
 	}
 
+	// syncNamespaceIngresses adds the Ingresses of a namespace starting to
+	// match the namespace selector, and removes the Ingresses of a namespace
+	// not matching it anymore, without waiting for a change of the Ingresses
+	syncNamespaceIngresses := func(namespace string, watched bool) {
+		for _, item := range store.listers.Ingress.List() {
+			ing, ok := item.(*networkingv1.Ingress)
+			if !ok || ing.Namespace != namespace {
+				continue
+			}
+
+			if watched {
+				ingEventHandler.OnAdd(ing, false)
+				continue
+			}
+
+			key := k8s.MetaNamespaceKey(ing)
+			if _, exists, _ := store.listers.IngressWithAnnotation.GetByKey(key); !exists {
+				continue
+			}
+
+			klog.InfoS("Removing ingress because its namespace does not match the namespace selector anymore", "ingress", klog.KObj(ing))
+			if err := store.listers.IngressWithAnnotation.Delete(ing); err != nil {
+				klog.ErrorS(err, "Error while deleting ingress from store", "ingress", klog.KObj(ing))
+				continue
+			}
+			store.secretIngressMap.Delete(key)
+
+			updateCh.In() <- Event{
+				Type: DeleteEvent,
+				Obj:  ing,
+			}
+		}
+	}
+
+	namespaceEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ns, ok := obj.(*corev1.Namespace)
+			if !ok || !namespaceSelector.Matches(labels.Set(ns.Labels)) {
+				return
+			}
+
+			syncNamespaceIngresses(ns.Name, true)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			oldNs, ok := old.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+			curNs, ok := cur.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+
+			watched := namespaceSelector.Matches(labels.Set(curNs.Labels))
+			if namespaceSelector.Matches(labels.Set(oldNs.Labels)) == watched {
+				return
+			}
+
+			klog.InfoS("Namespace selector match changed", "namespace", curNs.Name, "watched", watched)
+			syncNamespaceIngresses(curNs.Name, watched)
+		},
+	}
+
 	ingressClassEventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			ingressclass, ok := obj.(*networkingv1.IngressClass)
@@ -578,14 +987,19 @@ func New(
 				klog.InfoS("ignoring ingressclass as the spec.controller is not the same of this ingress", "ingressclass", klog.KObj(cic))
 				return
 			}
-			// TODO: In a future we might be interested in parse parameters and use as
-			// current IngressClass for this case, crossing with configmap
-			if !reflect.DeepEqual(cic.Spec.Parameters, oic.Spec.Parameters) {
+			if !reflect.DeepEqual(cic.Spec.Parameters, oic.Spec.Parameters) ||
+				!reflect.DeepEqual(cic.Annotations, oic.Annotations) {
 				err := store.listers.IngressClass.Update(cic)
 				if err != nil {
 					klog.InfoS("error updating ingressclass in store", "ingressclass", klog.KObj(cic), "error", err)
 					return
 				}
+
+				if cic.Annotations[ingressclass.ConfigMapAnnotation] != oic.Annotations[ingressclass.ConfigMapAnnotation] ||
+					!reflect.DeepEqual(cic.Spec.Parameters, oic.Spec.Parameters) {
+					store.resetClassConfigurations()
+					store.syncClassIngresses(cic.Name)
+				}
 				updateCh.In() <- Event{
 					Type: UpdateEvent,
 					Obj:  cur,
@@ -602,8 +1016,12 @@ func New(
 			}
 			key := k8s.MetaNamespaceKey(sec)
 
-			if store.defaultSSLCertificate == key {
-				store.syncSecret(store.defaultSSLCertificate)
+			if store.defaultSSLCertificate == key || store.isStreamRouteSecret(key) || store.isClassParamsSecret(key) || store.isGatewaySecret(key) {
+				store.syncSecret(key)
+			}
+
+			if store.isLuaSecret(key) {
+				store.sendDummyEvent()
 			}
 
 			// find references in ingresses and update local ssl certs
@@ -636,8 +1054,12 @@ func New(
 					return
 				}
 
-				if store.defaultSSLCertificate == key {
-					store.syncSecret(store.defaultSSLCertificate)
+				if store.defaultSSLCertificate == key || store.isStreamRouteSecret(key) || store.isClassParamsSecret(key) || store.isGatewaySecret(key) {
+					store.syncSecret(key)
+				}
+
+				if store.isLuaSecret(key) {
+					store.sendDummyEvent()
 				}
 
 				// find references in ingresses and update local ssl certs
@@ -682,6 +1104,10 @@ func New(
 
 			key := k8s.MetaNamespaceKey(sec)
 
+			if store.isLuaSecret(key) {
+				store.sendDummyEvent()
+			}
+
 			// find references in ingresses
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 				klog.InfoS("secret was deleted and it is used in ingress annotations. Parsing", "secret", key)
@@ -745,9 +1171,16 @@ func New(
 			recorder.Eventf(cfgMap, corev1.EventTypeNormal, eventName, fmt.Sprintf("ConfigMap %v", key))
 			if key == configmap {
 				store.setConfig(cfgMap)
+				store.resetClassConfigurations()
 			}
 		}
 
+		if store.isClassConfigMap(key) {
+			triggerUpdate = true
+			recorder.Eventf(cfgMap, corev1.EventTypeNormal, eventName, fmt.Sprintf("ConfigMap %v", key))
+			store.resetClassConfigurations()
+		}
+
 		ings := store.listers.IngressWithAnnotation.List()
 		for _, ingKey := range ings {
 			key := k8s.MetaNamespaceKey(ingKey)
@@ -844,6 +1277,182 @@ func New(
 		},
 	}
 
+	streamRouteEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			route, err := toStreamRoute(obj)
+			if err != nil {
+				klog.Errorf("unexpected StreamRoute: %v", err)
+				return
+			}
+			store.syncStreamRouteSecret(route)
+			updateCh.In() <- Event{
+				Type: CreateEvent,
+				Obj:  obj,
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+
+			route, err := toStreamRoute(cur)
+			if err != nil {
+				klog.Errorf("unexpected StreamRoute: %v", err)
+				return
+			}
+			store.syncStreamRouteSecret(route)
+			updateCh.In() <- Event{
+				Type: UpdateEvent,
+				Obj:  cur,
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			updateCh.In() <- Event{
+				Type: DeleteEvent,
+				Obj:  obj,
+			}
+		},
+	}
+
+	classParamsEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.handleClassParamsEvent(obj, updateCh)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+			store.handleClassParamsEvent(cur, updateCh)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.handleClassParamsEvent(obj, updateCh)
+		},
+	}
+
+	gatewayEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.syncGatewaySecrets(obj)
+			updateCh.In() <- Event{
+				Type: CreateEvent,
+				Obj:  obj,
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+
+			store.syncGatewaySecrets(cur)
+			updateCh.In() <- Event{
+				Type: UpdateEvent,
+				Obj:  cur,
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			updateCh.In() <- Event{
+				Type: DeleteEvent,
+				Obj:  obj,
+			}
+		},
+	}
+
+	referenceGrantEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.handleReferenceGrantEvent(obj, updateCh)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+
+			store.handleReferenceGrantEvent(cur, updateCh)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.handleReferenceGrantEvent(obj, updateCh)
+		},
+	}
+
+	wafPolicyEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.handleWAFPolicyEvent(obj, updateCh)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+			store.handleWAFPolicyEvent(cur, updateCh)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.handleWAFPolicyEvent(obj, updateCh)
+		},
+	}
+
+	redirectMapEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.handleRedirectMapEvent(obj, updateCh)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+			store.handleRedirectMapEvent(cur, updateCh)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.handleRedirectMapEvent(obj, updateCh)
+		},
+	}
+
+	// the denylists are applied without reloading NGINX
+	denylistEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			updateCh.In() <- Event{
+				Type: UpdateEvent,
+				Obj:  obj,
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+
+			updateCh.In() <- Event{
+				Type: UpdateEvent,
+				Obj:  cur,
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			updateCh.In() <- Event{
+				Type: DeleteEvent,
+				Obj:  obj,
+			}
+		},
+	}
+
+	hostOwnershipEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			updateCh.In() <- Event{
+				Type: ConfigurationEvent,
+				Obj:  obj,
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+
+			updateCh.In() <- Event{
+				Type: ConfigurationEvent,
+				Obj:  cur,
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			updateCh.In() <- Event{
+				Type: ConfigurationEvent,
+				Obj:  obj,
+			}
+		},
+	}
+
 	if _, err := store.informers.Ingress.AddEventHandler(ingEventHandler); err != nil {
 		klog.Errorf("Error adding ingress event handler: %v", err)
 	}
@@ -855,7 +1464,9 @@ func New(
 	if _, err := store.informers.EndpointSlice.AddEventHandler(epsEventHandler); err != nil {
 		klog.Errorf("Error adding endpoint slice event handler: %v", err)
 	}
-	if _, err := store.informers.Secret.AddEventHandler(secrEventHandler); err != nil {
+	if store.secretWatcher != nil {
+		store.secretWatcher.handler = secrEventHandler
+	} else if _, err := store.informers.Secret.AddEventHandler(secrEventHandler); err != nil {
 		klog.Errorf("Error adding secret event handler: %v", err)
 	}
 	if _, err := store.informers.ConfigMap.AddEventHandler(cmEventHandler); err != nil {
@@ -864,6 +1475,72 @@ func New(
 	if _, err := store.informers.Service.AddEventHandler(serviceHandler); err != nil {
 		klog.Errorf("Error adding service event handler: %v", err)
 	}
+	if store.informers.Namespace != nil {
+		if _, err := store.informers.Namespace.AddEventHandler(namespaceEventHandler); err != nil {
+			klog.Errorf("Error adding namespace event handler: %v", err)
+		}
+	}
+	if store.informers.StreamRoute != nil {
+		if _, err := store.informers.StreamRoute.AddEventHandler(streamRouteEventHandler); err != nil {
+			klog.Errorf("Error adding stream route event handler: %v", err)
+		}
+	}
+	if store.informers.IngressClassParams != nil {
+		if _, err := store.informers.IngressClassParams.AddEventHandler(classParamsEventHandler); err != nil {
+			klog.Errorf("Error adding ingress class params event handler: %v", err)
+		}
+	}
+	if store.informers.Gateway != nil {
+		for _, informer := range []cache.SharedIndexInformer{
+			store.informers.GatewayClass,
+			store.informers.Gateway,
+			store.informers.HTTPRoute,
+		} {
+			if _, err := informer.AddEventHandler(gatewayEventHandler); err != nil {
+				klog.Errorf("Error adding gateway api event handler: %v", err)
+			}
+		}
+	}
+	if store.informers.ReferenceGrant != nil {
+		if _, err := store.informers.ReferenceGrant.AddEventHandler(referenceGrantEventHandler); err != nil {
+			klog.Errorf("Error adding reference grant event handler: %v", err)
+		}
+	}
+	if store.informers.HostOwnership != nil {
+		if _, err := store.informers.HostOwnership.AddEventHandler(hostOwnershipEventHandler); err != nil {
+			klog.Errorf("Error adding host ownership event handler: %v", err)
+		}
+	}
+	if store.informers.HostClaims != nil {
+		if _, err := store.informers.HostClaims.AddEventHandler(hostOwnershipEventHandler); err != nil {
+			klog.Errorf("Error adding host claims event handler: %v", err)
+		}
+	}
+	if store.informers.WAFPolicy != nil {
+		if _, err := store.informers.WAFPolicy.AddEventHandler(wafPolicyEventHandler); err != nil {
+			klog.Errorf("Error adding WAF policy event handler: %v", err)
+		}
+	}
+	if store.informers.Denylist != nil {
+		if _, err := store.informers.Denylist.AddEventHandler(denylistEventHandler); err != nil {
+			klog.Errorf("Error adding denylist event handler: %v", err)
+		}
+	}
+	if store.informers.RedirectMap != nil {
+		if _, err := store.informers.RedirectMap.AddEventHandler(redirectMapEventHandler); err != nil {
+			klog.Errorf("Error adding redirect map event handler: %v", err)
+		}
+	}
+	if store.informers.TLSRoute != nil {
+		for _, informer := range []cache.SharedIndexInformer{
+			store.informers.TLSRoute,
+			store.informers.TCPRoute,
+		} {
+			if _, err := informer.AddEventHandler(gatewayEventHandler); err != nil {
+				klog.Errorf("Error adding gateway api event handler: %v", err)
+			}
+		}
+	}
 
 	// do not wait for informers to read the configmap configuration
 	ns, name, err := k8s.ParseNameNS(configmap)
@@ -933,7 +1610,7 @@ func (s *k8sStore) syncIngress(ing *networkingv1.Ingress) {
 
 	k8s.SetDefaultNGINXPathType(copyIng)
 
-	parsed, err := s.annotations.Extract(ing)
+	parsed, err := s.ExtractAnnotations(ing)
 	if err != nil {
 		klog.Error(err)
 		return
@@ -977,13 +1654,20 @@ func (s *k8sStore) updateSecretIngressMap(ing *networkingv1.Ingress) {
 		"secure-verify-ca-secret",
 	}
 
-	secConfig := s.GetSecurityConfiguration().AllowCrossNamespaceResources
+	secConfig := s.GetSecurityConfiguration()
 	for _, ann := range secretAnnotations {
-		secrKey, err := objectRefAnnotationNsKey(ann, ing, secConfig)
+		// the references of the annotations authorized by the
+		// ReferenceGrants are checked once their key is known
+		granted := secConfig.ReferenceGrants && referenceGrantAnnotations.Has(ann)
+		secrKey, err := objectRefAnnotationNsKey(ann, ing, secConfig.AllowCrossNamespaceResources || granted)
 		if err != nil && !errors.IsMissingAnnotations(err) {
 			klog.Errorf("error reading secret reference in annotation %q: %s", ann, err)
 			continue
 		}
+		if granted && secrKey != "" && !s.IsSecretReferenceGranted(resolver.IngressGroupKind, ing.Namespace, secrKey) {
+			klog.Errorf("error reading secret reference in annotation %q: no ReferenceGrant permits the reference to secret %v", ann, secrKey)
+			continue
+		}
 		if secrKey != "" {
 			refSecrets = append(refSecrets, secrKey)
 		}
@@ -1016,6 +1700,18 @@ func objectRefAnnotationNsKey(ann string, ing *networkingv1.Ingress, allowCrossN
 	return annValue, nil
 }
 
+// isSecretReferenced returns true if the Secret matching key is referenced by
+// an Ingress, a StreamRoute, an IngressClass, a Gateway or is the default SSL
+// certificate.
+func (s *k8sStore) isSecretReferenced(key string) bool {
+	return s.defaultSSLCertificate == key ||
+		len(s.secretIngressMap.Reference(key)) > 0 ||
+		s.isStreamRouteSecret(key) ||
+		s.isClassParamsSecret(key) ||
+		s.isGatewaySecret(key) ||
+		s.isLuaSecret(key)
+}
+
 // syncSecrets synchronizes data from all Secrets referenced by the given
 // Ingress with the local store and file system.
 func (s *k8sStore) syncSecrets(ing *networkingv1.Ingress) {
@@ -1025,6 +1721,155 @@ func (s *k8sStore) syncSecrets(ing *networkingv1.Ingress) {
 	}
 }
 
+// syncStreamRouteSecret synchronizes the Secret used by a StreamRoute to
+// terminate TLS with the local store and file system.
+func (s *k8sStore) syncStreamRouteSecret(route *v1alpha1.StreamRoute) {
+	if route.Spec.TLS == nil || route.Spec.TLS.SecretName == "" {
+		return
+	}
+
+	s.syncSecret(fmt.Sprintf("%v/%v", route.Namespace, route.Spec.TLS.SecretName))
+}
+
+// isStreamRouteSecret returns true if a StreamRoute terminates TLS with the
+// Secret matching key.
+func (s *k8sStore) isStreamRouteSecret(key string) bool {
+	for _, route := range s.ListStreamRoutes() {
+		if route.Spec.TLS != nil && fmt.Sprintf("%v/%v", route.Namespace, route.Spec.TLS.SecretName) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLuaSecret returns true when the Secret is referenced by the
+// configuration ConfigMap for the Lua modules
+func (s *k8sStore) isLuaSecret(key string) bool {
+	cfg := s.GetBackendConfiguration()
+	for _, luaSecret := range cfg.LuaSecrets() {
+		if luaSecret.Secret == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListStreamRoutes returns the list of StreamRoutes
+func (s *k8sStore) ListStreamRoutes() []*v1alpha1.StreamRoute {
+	if s.informers.StreamRoute == nil {
+		return nil
+	}
+
+	return s.listers.StreamRoute.List()
+}
+
+// syncGatewaySecrets synchronizes the Secrets of the certificates of the
+// Listeners of a Gateway with the local store and file system.
+func (s *k8sStore) syncGatewaySecrets(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || u.GetKind() != "Gateway" {
+		return
+	}
+
+	gateway := &gatewayv1.Gateway{}
+	if err := fromUnstructured(obj, gateway); err != nil {
+		klog.Errorf("unexpected Gateway: %v", err)
+		return
+	}
+
+	for _, key := range s.gatewaySecrets(gateway) {
+		s.syncSecret(key)
+	}
+}
+
+// isGatewaySecret returns true if a Listener of a Gateway terminates TLS
+// with the Secret matching key.
+func (s *k8sStore) isGatewaySecret(key string) bool {
+	for _, gateway := range s.ListGateways() {
+		for _, secretKey := range s.gatewaySecrets(gateway) {
+			if secretKey == key {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// gatewaySecrets returns the keys of the Secrets referenced by the Listeners
+// of a Gateway, the Secrets of other namespaces when a ReferenceGrant permits
+// it
+func (s *k8sStore) gatewaySecrets(gateway *gatewayv1.Gateway) []string {
+	var keys []string
+	for i := range gateway.Spec.Listeners {
+		listener := &gateway.Spec.Listeners[i]
+		if listener.TLS == nil {
+			continue
+		}
+
+		for _, ref := range listener.TLS.CertificateRefs {
+			namespace := gateway.Namespace
+			if ref.Namespace != nil {
+				namespace = string(*ref.Namespace)
+			}
+			key := fmt.Sprintf("%v/%v", namespace, ref.Name)
+			if !s.IsSecretReferenceGranted(GatewayGroupKind, gateway.Namespace, key) {
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// ListGatewayClasses returns the list of GatewayClasses
+func (s *k8sStore) ListGatewayClasses() []*gatewayv1.GatewayClass {
+	if s.informers.GatewayClass == nil {
+		return nil
+	}
+
+	return s.listers.GatewayClass.ListGatewayClasses()
+}
+
+// ListGateways returns the list of Gateways
+func (s *k8sStore) ListGateways() []*gatewayv1.Gateway {
+	if s.informers.Gateway == nil {
+		return nil
+	}
+
+	return s.listers.Gateway.ListGateways()
+}
+
+// ListHTTPRoutes returns the list of HTTPRoutes
+func (s *k8sStore) ListHTTPRoutes() []*gatewayv1.HTTPRoute {
+	if s.informers.HTTPRoute == nil {
+		return nil
+	}
+
+	return s.listers.HTTPRoute.ListHTTPRoutes()
+}
+
+// ListTLSRoutes returns the list of TLSRoutes
+func (s *k8sStore) ListTLSRoutes() []*gatewayv1alpha2.TLSRoute {
+	if s.informers.TLSRoute == nil {
+		return nil
+	}
+
+	return s.listers.TLSRoute.ListTLSRoutes()
+}
+
+// ListTCPRoutes returns the list of TCPRoutes
+func (s *k8sStore) ListTCPRoutes() []*gatewayv1alpha2.TCPRoute {
+	if s.informers.TCPRoute == nil {
+		return nil
+	}
+
+	return s.listers.TCPRoute.ListTCPRoutes()
+}
+
 // GetSecret returns the Secret matching key.
 func (s *k8sStore) GetSecret(key string) (*corev1.Secret, error) {
 	return s.listers.Secret.ByKey(key)
@@ -1073,6 +1918,34 @@ func (s *k8sStore) GetIngressClass(ing *networkingv1.Ingress, icConfig *ingressc
 	return "", fmt.Errorf("ingress does not contain a valid IngressClass")
 }
 
+// GetIngressClassByName returns the IngressClass matching name.
+func (s *k8sStore) GetIngressClassByName(name string) (*networkingv1.IngressClass, error) {
+	if s.listers.IngressClass.Store == nil {
+		return nil, NotExistsError(name)
+	}
+	return s.listers.IngressClass.ByKey(name)
+}
+
+// ListIngressClasses returns the IngressClasses of the controller, sorted by
+// name. Only the IngressClasses of the controller are kept in the store.
+func (s *k8sStore) ListIngressClasses() []*networkingv1.IngressClass {
+	if s.listers.IngressClass.Store == nil {
+		return nil
+	}
+
+	var classes []*networkingv1.IngressClass
+	for _, item := range s.listers.IngressClass.List() {
+		if ic, ok := item.(*networkingv1.IngressClass); ok {
+			classes = append(classes, ic)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+
+	return classes
+}
+
 // getIngress returns the Ingress matching key.
 func (s *k8sStore) getIngress(key string) (*networkingv1.Ingress, error) {
 	ing, err := s.listers.IngressWithAnnotation.ByKey(key)
@@ -1100,6 +1973,11 @@ func sortIngressSlice(ingresses []*ingress.Ingress) {
 
 // ListIngresses returns the list of Ingresses
 func (s *k8sStore) ListIngresses() []*ingress.Ingress {
+	return s.applyHostOwnership(s.sortedIngresses())
+}
+
+// sortedIngresses returns the Ingresses of the store, oldest first
+func (s *k8sStore) sortedIngresses() []*ingress.Ingress {
 	// filter ingress rules
 	ingresses := make([]*ingress.Ingress, 0)
 	for _, item := range s.listers.IngressWithAnnotation.List() {
@@ -1140,14 +2018,22 @@ func (s *k8sStore) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error
 		return nil, err
 	}
 
-	return &resolver.AuthSSLCert{
+	authCert := &resolver.AuthSSLCert{
 		Secret:      name,
 		CAFileName:  cert.CAFileName,
 		CASHA:       cert.CASHA,
 		CRLFileName: cert.CRLFileName,
 		CRLSHA:      cert.CRLSHA,
 		PemFileName: cert.PemFileName,
-	}, nil
+	}
+
+	for _, ca := range cert.CACertificate {
+		if authCert.CAExpireTime.IsZero() || ca.NotAfter.Before(authCert.CAExpireTime) {
+			authCert.CAExpireTime = ca.NotAfter
+		}
+	}
+
+	return authCert, nil
 }
 
 func (s *k8sStore) writeSSLSessionTicketKey(cmap *corev1.ConfigMap, fileName string) {
@@ -1196,6 +2082,7 @@ func (s *k8sStore) GetSecurityConfiguration() defaults.SecurityConfiguration {
 
 	secConfig := defaults.SecurityConfiguration{
 		AllowCrossNamespaceResources: s.backendConfig.AllowCrossNamespaceResources,
+		ReferenceGrants:              s.referenceGrants,
 		AnnotationsRiskLevel:         s.backendConfig.AnnotationsRiskLevel,
 	}
 	return secConfig
@@ -1210,10 +2097,15 @@ func (s *k8sStore) setConfig(cmap *corev1.ConfigMap) {
 	}
 
 	s.backendConfig = ngx_template.ReadConfig(cmap.Data)
+	s.backendConfigData = cmap.Data
 	if s.backendConfig.UseGeoIP2 && !nginx.GeoLite2DBExists() {
 		klog.Warning("The GeoIP2 feature is enabled but the databases are missing. Disabling")
 		s.backendConfig.UseGeoIP2 = false
 	}
+	if s.backendConfig.UseGeoIP2 && s.backendConfig.GeoIP2AutoReloadMinutes == 0 && nginx.MaxmindUpdateInterval > 0 {
+		// NGINX reopens the updated databases without a reload
+		s.backendConfig.GeoIP2AutoReloadMinutes = 1
+	}
 
 	s.writeSSLSessionTicketKey(cmap, "/etc/ingress-controller/tickets.key")
 }
@@ -1221,6 +2113,10 @@ func (s *k8sStore) setConfig(cmap *corev1.ConfigMap) {
 // Run initiates the synchronization of the informers and the initial
 // synchronization of the secrets.
 func (s *k8sStore) Run(stopCh chan struct{}) {
+	if s.secretWatcher != nil {
+		go s.secretWatcher.Run(stopCh)
+	}
+
 	// start informers
 	s.informers.Run(stopCh)
 }
@@ -126,10 +126,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -212,10 +225,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -320,10 +346,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -440,10 +479,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			ingressClassconfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -574,10 +626,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			ingressClassconfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -678,10 +743,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -776,10 +854,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -866,10 +957,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -966,10 +1070,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -1093,10 +1210,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -1221,10 +1351,23 @@ func TestStore(t *testing.T) {
 			"",
 			10*time.Minute,
 			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
 			updateCh,
 			false,
 			true,
 			DefaultClassConfig,
+			nil,
 			false)
 
 		storer.Run(stopCh)
@@ -1274,6 +1417,97 @@ func TestStore(t *testing.T) {
 			t.Errorf("expected 0 events of type Delete but %v occurred", del)
 		}
 	})
+	t.Run("should add and remove the ingresses of a namespace when its labels change", func(t *testing.T) {
+		ns := createNamespace(clientSet, t)
+		defer deleteNamespace(ns, clientSet, t)
+		ic := createIngressClass(clientSet, t, ingressclass.DefaultControllerName)
+		defer deleteIngressClass(ic, clientSet, t)
+		createConfigMap(clientSet, ns, t)
+
+		stopCh := make(chan struct{})
+		updateCh := channels.NewRingChannel(1024)
+
+		go func(ch *channels.RingChannel) {
+			for {
+				<-ch.Out()
+			}
+		}(updateCh)
+
+		namespaceSelector, err := labels.Parse("foo=bar")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		storer := New(
+			ns,
+			namespaceSelector,
+			fmt.Sprintf("%v/config", ns),
+			fmt.Sprintf("%v/tcp", ns),
+			fmt.Sprintf("%v/udp", ns),
+			"",
+			10*time.Minute,
+			clientSet,
+			false,
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			"",
+			"",
+			nil,
+			nil,
+			nil,
+			updateCh,
+			false,
+			true,
+			DefaultClassConfig,
+			nil,
+			false)
+
+		storer.Run(stopCh)
+
+		validSpec := commonIngressSpec
+		validSpec.IngressClassName = &ic
+		ing := ensureIngress(&networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy",
+				Namespace: ns,
+			},
+			Spec: validSpec,
+		}, clientSet, t)
+		defer deleteIngress(ing, clientSet, t)
+
+		time.Sleep(1 * time.Second)
+
+		if ings := storer.ListIngresses(); len(ings) != 0 {
+			t.Errorf("expected 0 ingresses before labeling the namespace but got %v", len(ings))
+		}
+
+		setNamespaceLabels := func(nsLabels map[string]string) {
+			namespace, err := clientSet.CoreV1().Namespaces().Get(context.TODO(), ns, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting the namespace: %v", err)
+			}
+			namespace.Labels = nsLabels
+			_, err = clientSet.CoreV1().Namespaces().Update(context.TODO(), namespace, metav1.UpdateOptions{})
+			if err != nil {
+				t.Fatalf("error updating the namespace: %v", err)
+			}
+			time.Sleep(1 * time.Second)
+		}
+
+		setNamespaceLabels(map[string]string{"foo": "bar"})
+		if ings := storer.ListIngresses(); len(ings) != 1 {
+			t.Errorf("expected 1 ingress after labeling the namespace but got %v", len(ings))
+		}
+
+		setNamespaceLabels(nil)
+		if ings := storer.ListIngresses(); len(ings) != 0 {
+			t.Errorf("expected 0 ingresses after removing the label of the namespace but got %v", len(ings))
+		}
+	})
+
 	// test add ingress with secret it doesn't exists and then add secret
 	// check secret is generated on fs
 	// check ocsp
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// StreamRouteLister makes a Store that lists StreamRoutes.
+type StreamRouteLister struct {
+	cache.Store
+}
+
+// List returns the StreamRoutes of the local StreamRoute Store.
+func (sl *StreamRouteLister) List() []*v1alpha1.StreamRoute {
+	var routes []*v1alpha1.StreamRoute
+	for _, obj := range sl.Store.List() {
+		route, err := toStreamRoute(obj)
+		if err != nil {
+			klog.Warningf("Error converting StreamRoute: %v", err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// toStreamRoute converts an object of the dynamic informer to a StreamRoute
+func toStreamRoute(obj interface{}) (*v1alpha1.StreamRoute, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", obj)
+	}
+
+	route := &v1alpha1.StreamRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), route); err != nil {
+		return nil, err
+	}
+
+	return route, nil
+}
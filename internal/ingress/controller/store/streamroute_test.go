@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func newStreamRouteObject(t *testing.T, name string, port int32, protocol apiv1.Protocol) *unstructured.Unstructured {
+	t.Helper()
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.StreamRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1alpha1.StreamRouteSpec{Port: port, Protocol: protocol},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestToStreamRoute(t *testing.T) {
+	route := newStreamRouteObject(t, "postgres", 5432, apiv1.ProtocolTCP)
+
+	testCases := []struct {
+		title       string
+		obj         interface{}
+		expectedErr bool
+	}{
+		{"StreamRoute", route, false},
+		{"deleted StreamRoute", cache.DeletedFinalStateUnknown{Key: "default/postgres", Obj: route}, false},
+		{"unexpected type", &v1alpha1.StreamRoute{}, true},
+		{"invalid port", &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "invalid", "namespace": "default"},
+			"spec":     map[string]interface{}{"port": "postgres"},
+		}}, true},
+	}
+
+	for _, tc := range testCases {
+		result, err := toStreamRoute(tc.obj)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%v: expected an error but got %+v", tc.title, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+			continue
+		}
+		if result.Name != "postgres" || result.Spec.Port != 5432 || result.Spec.Protocol != apiv1.ProtocolTCP {
+			t.Errorf("%v: expected the StreamRoute postgres but got %+v", tc.title, result)
+		}
+	}
+}
+
+func TestStreamRouteListerList(t *testing.T) {
+	lister := &StreamRouteLister{cache.NewStore(cache.MetaNamespaceKeyFunc)}
+	for _, obj := range []*unstructured.Unstructured{
+		newStreamRouteObject(t, "dns", 53, apiv1.ProtocolUDP),
+		{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "invalid", "namespace": "default"},
+			"spec":     map[string]interface{}{"port": "dns"},
+		}},
+	} {
+		if err := lister.Add(obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var names []string
+	for _, route := range lister.List() {
+		names = append(names, route.Name)
+	}
+	if expected := []string{"dns"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the valid StreamRoutes %v but got %v", expected, names)
+	}
+}
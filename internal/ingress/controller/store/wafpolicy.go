@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+
+	"github.com/eapache/channels"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// WAFPolicyLister makes a Store that lists WAFPolicies.
+type WAFPolicyLister struct {
+	cache.Store
+}
+
+// ByKey returns the WAFPolicy matching key in the local Store.
+func (l WAFPolicyLister) ByKey(key string) (*v1alpha1.WAFPolicy, error) {
+	obj, exists, err := l.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, NotExistsError(key)
+	}
+	return toWAFPolicy(obj)
+}
+
+// toWAFPolicy converts an object of the dynamic informer to a WAFPolicy
+func toWAFPolicy(obj interface{}) (*v1alpha1.WAFPolicy, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", obj)
+	}
+
+	policy := &v1alpha1.WAFPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// GetWAFPolicy returns the WAFPolicy matching key.
+func (s *k8sStore) GetWAFPolicy(key string) (*v1alpha1.WAFPolicy, error) {
+	if s.listers.WAFPolicy.Store == nil {
+		return nil, fmt.Errorf("the WAFPolicies are not watched, use the flag --enable-waf-policies")
+	}
+
+	return s.listers.WAFPolicy.ByKey(key)
+}
+
+// handleWAFPolicyEvent parses again the annotations of the Ingresses
+// referencing the WAFPolicy
+func (s *k8sStore) handleWAFPolicyEvent(obj interface{}, updateCh *channels.RingChannel) {
+	policy, err := toWAFPolicy(obj)
+	if err != nil {
+		klog.Errorf("unexpected WAFPolicy: %v", err)
+		return
+	}
+
+	annotation := parser.GetAnnotationWithPrefix(modsecurity.WAFPolicyAnnotation)
+	synced := false
+	for _, item := range s.listers.IngressWithAnnotation.List() {
+		ing, err := s.getIngress(k8s.MetaNamespaceKey(item))
+		if err != nil {
+			continue
+		}
+
+		if ing.Namespace == policy.Namespace && ing.Annotations[annotation] == policy.Name {
+			klog.InfoS("WAFPolicy used in ingress annotations was changed. Parsing", "policy", k8s.MetaNamespaceKey(policy), "ingress", klog.KObj(ing))
+			s.syncIngress(ing)
+			synced = true
+		}
+	}
+
+	if synced {
+		updateCh.In() <- Event{
+			Type: UpdateEvent,
+			Obj:  obj,
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func TestToWAFPolicy(t *testing.T) {
+	paranoiaLevel := int32(2)
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.WAFPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "strict", Namespace: "default"},
+		Spec:       v1alpha1.WAFPolicySpec{ParanoiaLevel: &paranoiaLevel},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := &unstructured.Unstructured{Object: obj}
+
+	testCases := []struct {
+		title       string
+		obj         interface{}
+		expectedErr bool
+	}{
+		{"WAFPolicy", policy, false},
+		{"deleted WAFPolicy", cache.DeletedFinalStateUnknown{Key: "default/strict", Obj: policy}, false},
+		{"unexpected type", &v1alpha1.WAFPolicy{}, true},
+		{"invalid paranoia level", &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "invalid", "namespace": "default"},
+			"spec":     map[string]interface{}{"paranoiaLevel": "high"},
+		}}, true},
+	}
+
+	for _, tc := range testCases {
+		result, err := toWAFPolicy(tc.obj)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%v: expected an error but got %+v", tc.title, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+			continue
+		}
+		if result.Name != "strict" || result.Spec.ParanoiaLevel == nil || *result.Spec.ParanoiaLevel != paranoiaLevel {
+			t.Errorf("%v: expected the WAFPolicy strict but got %+v", tc.title, result)
+		}
+	}
+}
+
+func TestGetWAFPolicy(t *testing.T) {
+	s := &k8sStore{listers: &Lister{}}
+	if _, err := s.GetWAFPolicy("default/strict"); err == nil {
+		t.Errorf("expected an error when the WAFPolicies are not watched")
+	}
+
+	s.listers.WAFPolicy.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	if err := s.listers.WAFPolicy.Add(&unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "strict", "namespace": "default"},
+	}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if policy, err := s.GetWAFPolicy("default/strict"); err != nil || policy.Name != "strict" {
+		t.Errorf("expected the WAFPolicy strict but got %+v, %v", policy, err)
+	}
+
+	var notExists NotExistsError
+	if _, err := s.GetWAFPolicy("default/other"); !errors.As(err, &notExists) {
+		t.Errorf("expected a NotExistsError but got %v", err)
+	}
+}
@@ -0,0 +1,401 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+// streamRouteProtocol returns the protocol of a StreamRoute, TCP by default
+func streamRouteProtocol(route *v1alpha1.StreamRoute) apiv1.Protocol {
+	if route.Spec.Protocol == "" {
+		return apiv1.ProtocolTCP
+	}
+	return route.Spec.Protocol
+}
+
+// streamRouteOlder returns true if the StreamRoute a was created before b.
+// StreamRoutes being created, without a creation timestamp yet, are the
+// newest ones. A port used by several StreamRoutes belongs to the oldest one.
+func streamRouteOlder(a, b *v1alpha1.StreamRoute) bool {
+	if a.CreationTimestamp.IsZero() != b.CreationTimestamp.IsZero() {
+		return b.CreationTimestamp.IsZero()
+	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return k8s.MetaNamespaceKey(a) < k8s.MetaNamespaceKey(b)
+}
+
+// streamRouteProxyProtocol returns the PROXY protocol configuration of a
+// StreamRoute
+func streamRouteProxyProtocol(route *v1alpha1.StreamRoute) ingress.ProxyProtocol {
+	if route.Spec.ProxyProtocol == nil {
+		return ingress.ProxyProtocol{}
+	}
+	return ingress.ProxyProtocol{
+		Decode: route.Spec.ProxyProtocol.Decode,
+		Encode: route.Spec.ProxyProtocol.Encode,
+	}
+}
+
+// streamRoutesShareable returns true if both StreamRoutes can use the same
+// port, routing the connections based on their server name. At most one of
+// them can have no hostnames and their hostnames cannot overlap.
+func streamRoutesShareable(a, b *v1alpha1.StreamRoute) bool {
+	if len(a.Spec.Hostnames) == 0 && len(b.Spec.Hostnames) == 0 {
+		return false
+	}
+	if a.Spec.TLS != nil || b.Spec.TLS != nil {
+		return false
+	}
+	if streamRouteProxyProtocol(a) != streamRouteProxyProtocol(b) {
+		return false
+	}
+
+	hostnames := sets.NewString()
+	for _, hostname := range a.Spec.Hostnames {
+		hostnames.Insert(strings.ToLower(hostname))
+	}
+	for _, hostname := range b.Spec.Hostnames {
+		if hostnames.Has(strings.ToLower(hostname)) {
+			return false
+		}
+	}
+	return true
+}
+
+// streamSessionAffinityHashBy returns the key of the consistent hashing of the
+// connections of a stream backend with session affinity. The protocol of the
+// 5-tuple is the one of the backend.
+func streamSessionAffinityHashBy(affinity string) string {
+	switch affinity {
+	case string(v1alpha1.StreamRouteSessionAffinityClientIP):
+		return "$remote_addr"
+	case string(v1alpha1.StreamRouteSessionAffinityFiveTuple):
+		return "$remote_addr:$remote_port-$server_addr:$server_port"
+	default:
+		return ""
+	}
+}
+
+// getConfigMapStreamPorts returns the ports used by the stream services of
+// the TCP or UDP ConfigMap.
+func (n *NGINXController) getConfigMapStreamPorts(proto apiv1.Protocol) (string, sets.Int) {
+	configmapName := n.cfg.TCPConfigMapName
+	if proto == apiv1.ProtocolUDP {
+		configmapName = n.cfg.UDPConfigMapName
+	}
+
+	ports := sets.NewInt()
+	if configmapName == "" {
+		return configmapName, ports
+	}
+
+	configmap, err := n.store.GetConfigMap(configmapName)
+	if err != nil {
+		return configmapName, ports
+	}
+
+	for port := range configmap.Data {
+		if externalPort, err := strconv.Atoi(port); err == nil {
+			ports.Insert(externalPort)
+		}
+	}
+	return configmapName, ports
+}
+
+// CheckStreamRoute returns an error if the StreamRoute is invalid or if its
+// port is already used by another stream service.
+func (n *NGINXController) CheckStreamRoute(route *v1alpha1.StreamRoute) error {
+	if !n.cfg.EnableStreamRoutes {
+		return nil
+	}
+
+	proto := streamRouteProtocol(route)
+	if proto != apiv1.ProtocolTCP && proto != apiv1.ProtocolUDP {
+		return fmt.Errorf("invalid protocol %q, only TCP and UDP are supported", route.Spec.Protocol)
+	}
+
+	port := int(route.Spec.Port)
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %d", port)
+	}
+	if n.reservedStreamPorts().Has(port) {
+		return fmt.Errorf("port %d is reserved for the ingress controller", port)
+	}
+
+	if route.Spec.Backend.Service == "" {
+		return fmt.Errorf("the backend service is required")
+	}
+	if svcPort := route.Spec.Backend.Port.String(); svcPort == "" || svcPort == "0" {
+		return fmt.Errorf("the backend port is required")
+	}
+
+	if proto != apiv1.ProtocolTCP {
+		if pp := route.Spec.ProxyProtocol; pp != nil && (pp.Decode || pp.Encode) {
+			return fmt.Errorf("the PROXY protocol is only supported with TCP")
+		}
+		if route.Spec.TLS != nil {
+			return fmt.Errorf("TLS termination is only supported with TCP")
+		}
+	}
+	if route.Spec.TLS != nil && route.Spec.TLS.SecretName == "" {
+		return fmt.Errorf("the TLS secret name is required")
+	}
+
+	switch route.Spec.SessionAffinity {
+	case "", v1alpha1.StreamRouteSessionAffinityNone, v1alpha1.StreamRouteSessionAffinityClientIP, v1alpha1.StreamRouteSessionAffinityFiveTuple:
+	default:
+		return fmt.Errorf("invalid session affinity %q, only None, ClientIP and FiveTuple are supported", route.Spec.SessionAffinity)
+	}
+
+	if len(route.Spec.Hostnames) > 0 {
+		if proto != apiv1.ProtocolTCP {
+			return fmt.Errorf("routing based on the server name is only supported with TCP")
+		}
+		if route.Spec.TLS != nil {
+			return fmt.Errorf("hostnames cannot be used with TLS termination")
+		}
+	}
+	for _, hostname := range route.Spec.Hostnames {
+		errs := validation.IsDNS1123Subdomain(strings.ToLower(hostname))
+		if strings.HasPrefix(hostname, "*.") {
+			errs = validation.IsWildcardDNS1123Subdomain(strings.ToLower(hostname))
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid hostname %q: %v", hostname, strings.Join(errs, ", "))
+		}
+	}
+
+	if configmapName, ports := n.getConfigMapStreamPorts(proto); ports.Has(port) {
+		return fmt.Errorf("%v port %d is already used by the ConfigMap %q", proto, port, configmapName)
+	}
+
+	key := k8s.MetaNamespaceKey(route)
+	for _, other := range n.store.ListStreamRoutes() {
+		otherKey := k8s.MetaNamespaceKey(other)
+		if otherKey == key || streamRouteProtocol(other) != proto || other.Spec.Port != route.Spec.Port {
+			continue
+		}
+		if streamRouteOlder(other, route) && !streamRoutesShareable(other, route) {
+			return fmt.Errorf("%v port %d is already used by the StreamRoute %q", proto, port, otherKey)
+		}
+	}
+
+	return nil
+}
+
+// getStreamRouteService returns the stream service of a valid StreamRoute.
+// It fails if the Service or the certificate used to terminate TLS is not
+// available. A Service without active Endpoint is kept like in the ConfigMaps.
+func (n *NGINXController) getStreamRouteService(route *v1alpha1.StreamRoute) (*ingress.L4Service, error) {
+	proto := streamRouteProtocol(route)
+	svcKey := fmt.Sprintf("%v/%v", route.Namespace, route.Spec.Backend.Service)
+	svc, err := n.store.GetService(svcKey)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Service %q: %w", svcKey, err)
+	}
+
+	svcPort := route.Spec.Backend.Port.String()
+	endps := n.getStreamServiceEndpoints(svc, svcPort, proto)
+
+	l4Service := &ingress.L4Service{
+		Port: int(route.Spec.Port),
+		Backend: ingress.L4Backend{
+			Name:      route.Spec.Backend.Service,
+			Namespace: route.Namespace,
+			Port:      intstr.FromString(svcPort),
+			Protocol:  proto,
+		},
+		Endpoints: endps,
+		Service:   svc,
+	}
+
+	l4Service.Backend.ProxyProtocol = streamRouteProxyProtocol(route)
+	if route.Spec.SessionAffinity != v1alpha1.StreamRouteSessionAffinityNone {
+		l4Service.Backend.SessionAffinity = string(route.Spec.SessionAffinity)
+	}
+	if len(route.Spec.Hostnames) > 0 {
+		hostnames := make([]string, 0, len(route.Spec.Hostnames))
+		for _, hostname := range route.Spec.Hostnames {
+			hostnames = append(hostnames, strings.ToLower(hostname))
+		}
+		sort.Strings(hostnames)
+		l4Service.Hostnames = hostnames
+	}
+
+	if route.Spec.TLS != nil {
+		secretKey := fmt.Sprintf("%v/%v", route.Namespace, route.Spec.TLS.SecretName)
+		cert, err := n.store.GetLocalSSLCert(secretKey)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the certificate of Secret %q: %w", secretKey, err)
+		}
+		if cert.PemFileName == "" {
+			return nil, fmt.Errorf("the certificate of Secret %q is not available", secretKey)
+		}
+		l4Service.Backend.SSLCert = cert
+	}
+
+	return l4Service, nil
+}
+
+// getStreamRouteServices returns the TCP or UDP services defined by the
+// StreamRoutes. Invalid StreamRoutes are ignored.
+func (n *NGINXController) getStreamRouteServices(proto apiv1.Protocol) []ingress.L4Service {
+	svcs := []ingress.L4Service{}
+	for _, route := range n.store.ListStreamRoutes() {
+		if streamRouteProtocol(route) != proto {
+			continue
+		}
+
+		key := k8s.MetaNamespaceKey(route)
+		if err := n.CheckStreamRoute(route); err != nil {
+			klog.Warningf("Ignoring invalid StreamRoute %q: %v", key, err)
+			continue
+		}
+
+		svc, err := n.getStreamRouteService(route)
+		if err != nil {
+			klog.Warningf("Ignoring StreamRoute %q: %v", key, err)
+			continue
+		}
+
+		svcs = append(svcs, *svc)
+	}
+
+	return groupSNIServices(proto, svcs)
+}
+
+// groupSNIServices groups the stream services with hostnames by port in the
+// SNIServices of the service using the port.
+func groupSNIServices(proto apiv1.Protocol, services []ingress.L4Service) []ingress.L4Service {
+	svcs := []ingress.L4Service{}
+	sniSvcs := map[int][]ingress.L4Service{}
+	for i := range services {
+		if len(services[i].Hostnames) > 0 {
+			sniSvcs[services[i].Port] = append(sniSvcs[services[i].Port], services[i])
+			continue
+		}
+		svcs = append(svcs, services[i])
+	}
+
+	for port, routes := range sniSvcs {
+		sort.SliceStable(routes, func(i, j int) bool {
+			return routes[i].Hostnames[0] < routes[j].Hostnames[0]
+		})
+
+		found := false
+		for i := range svcs {
+			if svcs[i].Port == port {
+				svcs[i].SNIServices = routes
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		// the connections without a matching server name are closed
+		svcs = append(svcs, ingress.L4Service{
+			Port: port,
+			Backend: ingress.L4Backend{
+				Protocol:      proto,
+				ProxyProtocol: routes[0].Backend.ProxyProtocol,
+			},
+			SNIServices: routes,
+		})
+	}
+
+	return svcs
+}
+
+// syncStreamRouteStatus reports in the Accepted condition of the StreamRoutes
+// if they are exposed by the ingress controller, or why they are not.
+func (n *NGINXController) syncStreamRouteStatus() {
+	for _, route := range n.store.ListStreamRoutes() {
+		condition := metav1.Condition{
+			Type:               v1alpha1.StreamRouteConditionAccepted,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: route.Generation,
+			Reason:             v1alpha1.StreamRouteReasonAccepted,
+			Message:            "The StreamRoute is exposed by the ingress controller",
+		}
+
+		if err := n.CheckStreamRoute(route); err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = v1alpha1.StreamRouteReasonInvalid
+			condition.Message = err.Error()
+		} else if svc, err := n.getStreamRouteService(route); err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = v1alpha1.StreamRouteReasonBackendNotReady
+			condition.Message = err.Error()
+		} else if len(svc.Endpoints) == 0 {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = v1alpha1.StreamRouteReasonBackendNotReady
+			condition.Message = fmt.Sprintf("service %q does not have any active Endpoint for %v port %v",
+				route.Spec.Backend.Service, svc.Backend.Protocol, svc.Backend.Port.String())
+		}
+
+		current := meta.FindStatusCondition(route.Status.Conditions, condition.Type)
+		if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+			current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration &&
+			route.Status.ObservedGeneration == route.Generation {
+			continue
+		}
+
+		meta.SetStatusCondition(&route.Status.Conditions, condition)
+		route.Status.ObservedGeneration = route.Generation
+		if err := n.updateStreamRouteStatus(route); err != nil {
+			klog.Warningf("Error updating the status of StreamRoute %q: %v", k8s.MetaNamespaceKey(route), err)
+		}
+	}
+}
+
+func (n *NGINXController) updateStreamRouteStatus(route *v1alpha1.StreamRoute) error {
+	if n.cfg.StreamRouteClient == nil {
+		return nil
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(route)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.cfg.StreamRouteClient.Resource(v1alpha1.StreamRoutesResource).Namespace(route.Namespace).
+		UpdateStatus(context.TODO(), &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
+)
+
+func buildStreamRoute(name string, port int32, created time.Time) *v1alpha1.StreamRoute {
+	return &v1alpha1.StreamRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: v1alpha1.StreamRouteSpec{
+			Port: port,
+			Backend: v1alpha1.StreamRouteBackend{
+				Service: "example",
+				Port:    intstr.FromInt(8080),
+			},
+		},
+	}
+}
+
+func TestCheckStreamRoute(t *testing.T) {
+	now := time.Now()
+	existing := buildStreamRoute("existing", 9000, now.Add(-time.Hour))
+	existingSNI := buildStreamRoute("existing-sni", 9443, now.Add(-time.Hour))
+	existingSNI.Spec.Hostnames = []string{"db.example.com", "*.cache.example.com"}
+
+	n := &NGINXController{
+		cfg: &Configuration{
+			EnableStreamRoutes: true,
+			ListenPorts: &ngx_config.ListenPorts{
+				HTTP:     80,
+				HTTPS:    443,
+				SSLProxy: 442,
+				Health:   10254,
+				Default:  8181,
+			},
+		},
+		store: &fakeIngressStore{
+			streamRoutes: []*v1alpha1.StreamRoute{existing, existingSNI},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		route   func() *v1alpha1.StreamRoute
+		wantErr bool
+	}{
+		{
+			name: "valid TCP stream route",
+			route: func() *v1alpha1.StreamRoute {
+				return buildStreamRoute("new", 9001, time.Time{})
+			},
+		},
+		{
+			name: "valid UDP stream route on the port of a TCP stream route",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9000, time.Time{})
+				route.Spec.Protocol = apiv1.ProtocolUDP
+				return route
+			},
+		},
+		{
+			name: "update of the existing stream route",
+			route: func() *v1alpha1.StreamRoute {
+				return existing.DeepCopy()
+			},
+		},
+		{
+			name: "port already used by an older stream route",
+			route: func() *v1alpha1.StreamRoute {
+				return buildStreamRoute("new", 9000, time.Time{})
+			},
+			wantErr: true,
+		},
+		{
+			name: "port reserved for the ingress controller",
+			route: func() *v1alpha1.StreamRoute {
+				return buildStreamRoute("new", 443, time.Time{})
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid protocol",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Protocol = apiv1.ProtocolSCTP
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "TLS termination of a UDP stream route",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Protocol = apiv1.ProtocolUDP
+				route.Spec.TLS = &v1alpha1.StreamRouteTLS{SecretName: "example-tls"}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "PROXY protocol of a UDP stream route",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Protocol = apiv1.ProtocolUDP
+				route.Spec.ProxyProtocol = &v1alpha1.StreamRouteProxyProtocol{Decode: true}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "other hostnames on the port of a stream route with hostnames",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9443, time.Time{})
+				route.Spec.Hostnames = []string{"queue.example.com"}
+				return route
+			},
+		},
+		{
+			name: "default stream route of a port with hostnames",
+			route: func() *v1alpha1.StreamRoute {
+				return buildStreamRoute("new", 9443, time.Time{})
+			},
+		},
+		{
+			name: "hostname already used on the port",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9443, time.Time{})
+				route.Spec.Hostnames = []string{"DB.example.com"}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "different PROXY protocol on a port with hostnames",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9443, time.Time{})
+				route.Spec.Hostnames = []string{"queue.example.com"}
+				route.Spec.ProxyProtocol = &v1alpha1.StreamRouteProxyProtocol{Encode: true}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "hostnames with TLS termination",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Hostnames = []string{"queue.example.com"}
+				route.Spec.TLS = &v1alpha1.StreamRouteTLS{SecretName: "example-tls"}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "hostnames of a UDP stream route",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Protocol = apiv1.ProtocolUDP
+				route.Spec.Hostnames = []string{"queue.example.com"}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid hostname",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Hostnames = []string{"queue_example.com"}
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "session affinity of a UDP stream route",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Protocol = apiv1.ProtocolUDP
+				route.Spec.SessionAffinity = v1alpha1.StreamRouteSessionAffinityFiveTuple
+				return route
+			},
+		},
+		{
+			name: "invalid session affinity",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.SessionAffinity = "Cookie"
+				return route
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing backend service",
+			route: func() *v1alpha1.StreamRoute {
+				route := buildStreamRoute("new", 9001, time.Time{})
+				route.Spec.Backend.Service = ""
+				return route
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := n.CheckStreamRoute(tc.route())
+			if tc.wantErr && err == nil {
+				t.Errorf("expected an error")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestStreamRouteOlder(t *testing.T) {
+	now := time.Now()
+	older := buildStreamRoute("b", 9000, now.Add(-time.Hour))
+	newer := buildStreamRoute("a", 9000, now)
+	sameTime := buildStreamRoute("c", 9000, now)
+	creating := buildStreamRoute("d", 9000, time.Time{})
+
+	if !streamRouteOlder(older, newer) || streamRouteOlder(newer, older) {
+		t.Errorf("expected the stream route created first to be the older one")
+	}
+	if !streamRouteOlder(newer, sameTime) {
+		t.Errorf("expected the stream routes created at the same time to be ordered by key")
+	}
+	if !streamRouteOlder(newer, creating) || streamRouteOlder(creating, older) {
+		t.Errorf("expected the stream route being created to be the newest one")
+	}
+}
+
+func TestStreamSessionAffinityHashBy(t *testing.T) {
+	testCases := map[string]string{
+		"": "",
+		string(v1alpha1.StreamRouteSessionAffinityNone):      "",
+		string(v1alpha1.StreamRouteSessionAffinityClientIP):  "$remote_addr",
+		string(v1alpha1.StreamRouteSessionAffinityFiveTuple): "$remote_addr:$remote_port-$server_addr:$server_port",
+	}
+
+	for affinity, expected := range testCases {
+		if hashBy := streamSessionAffinityHashBy(affinity); hashBy != expected {
+			t.Errorf("expected %q as hash key of session affinity %q but got %q", expected, affinity, hashBy)
+		}
+	}
+}
@@ -19,19 +19,21 @@ package template
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"k8s.io/klog/v2"
 
-	"github.com/mitchellh/hashstructure/v2"
 	"github.com/mitchellh/mapstructure"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentelemetry"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
@@ -69,21 +71,52 @@ const (
 	luaSharedDictsKey             = "lua-shared-dicts"
 	plugins                       = "plugins"
 	debugConnections              = "debug-connections"
+	metricsDropLabels             = "metrics-drop-labels"
+	metricsLimitLabels            = "metrics-limit-labels"
 	workerSerialReloads           = "enable-serial-reloads"
+	namespaceHostSuffixes         = "namespace-host-suffixes"
+	opentelemetrySpanAttributes   = "opentelemetry-span-attributes"
+	opentelemetryPropagator       = "opentelemetry-propagator"
+	logFormatJSONFields           = "log-format-json-fields"
+	requestIDFormat               = "request-id-format"
+	requestIDPrefix               = "request-id-prefix"
+	requestIDTrustedCIDRs         = "request-id-trusted-cidrs"
+	botDetectionReputationCIDRs   = "bot-detection-reputation-cidrs"
+	crowdSecLAPIURL               = "crowdsec-lapi-url"
+	autoBanStatusCodes            = "auto-ban-status-codes"
+	proxyCacheZones               = "proxy-cache-zones"
 )
 
 var (
 	validRedirectCodes    = sets.NewInt([]int{301, 302, 307, 308}...)
 	dictSizeRegex         = regexp.MustCompile(`^(\d+)([kKmM])?$`)
+	logFieldNameRegex     = regexp.MustCompile(`^[A-Za-z0-9_.@\-]+$`)
+	logFieldValueRegex    = regexp.MustCompile(`^[^"'\\\x00-\x1f]*$`)
+	requestIDPrefixRegex  = regexp.MustCompile(`^[A-Za-z0-9_.:\-]*$`)
+	cacheZoneNameRegex    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	cacheSizeRegex        = regexp.MustCompile(`^(\d+)([kKmMgG])?$`)
+	cacheInactiveRegex    = regexp.MustCompile(`^\d+[smhd]$`)
 	defaultLuaSharedDicts = map[string]int{
 		"configuration_data":            20480,
 		"certificate_data":              20480,
 		"balancer_ewma":                 10240,
 		"balancer_ewma_last_touched_at": 10240,
 		"balancer_ewma_locks":           1024,
+		"balancer_least_conn":           1024,
+		"balancer_bounded_load":         1024,
+		"balancer_outlier":              1024,
+		"balancer_healthcheck":          1024,
 		"certificate_servers":           5120,
 		"ocsp_response_cache":           5120, // keep this same as certificate_servers
+		"ocsp_status":                   1024,
 		"global_throttle_cache":         10240,
+		"websocket_connections":         1024,
+		"acme_challenges":               1024,
+		"drain":                         1024,
+		"bot_detection":                 5120,
+		"denylist":                      1024,
+		"crowdsec_cache":                5120,
+		"auto_ban":                      5120,
 	}
 	defaultGlobalAuthRedirectParam = "rd"
 )
@@ -93,6 +126,17 @@ const (
 	maxNumberOfLuaDicts   = 100
 )
 
+const (
+	// defaultCacheInactive is the time after which the responses of a cache
+	// zone not requested are removed
+	defaultCacheInactive = "60m"
+	// cachedBytesPerKeysMegabyte is the size of the cached responses whose
+	// keys are stored in a megabyte of keys zone, about 8000 keys of 16KiB
+	// responses
+	cachedBytesPerKeysMegabyte = 128 * 1024 * 1024
+	maxKeysZoneMegabytes       = 1024
+)
+
 // ReadConfig obtains the configuration defined by the user merged with the defaults.
 //
 //nolint:gocyclo // Ignore function complexity error
@@ -121,6 +165,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 	allowedResponseHeaders := make([]string, 0)
 	luaSharedDicts := make(map[string]int)
 	debugConnectionsList := make([]string, 0)
+	namespaceHostSuffixesMap := make(map[string][]string)
 
 	// parse lua shared dict values
 	if val, ok := conf[luaSharedDictsKey]; ok {
@@ -439,6 +484,120 @@ func ReadConfig(src map[string]string) config.Configuration {
 		to.DebugConnections = debugConnectionsList
 	}
 
+	if val, ok := conf[metricsDropLabels]; ok {
+		delete(conf, metricsDropLabels)
+		to.MetricsDropLabels = splitAndTrimSpace(val, ",")
+	}
+
+	if val, ok := conf[metricsLimitLabels]; ok {
+		delete(conf, metricsLimitLabels)
+		to.MetricsLimitLabels = splitAndTrimSpace(val, ",")
+	}
+
+	if val, ok := conf[namespaceHostSuffixes]; ok {
+		delete(conf, namespaceHostSuffixes)
+		for _, i := range splitAndTrimSpace(val, ",") {
+			namespace, suffixes, found := strings.Cut(i, ":")
+			namespace = strings.TrimSpace(namespace)
+			if !found || namespace == "" {
+				klog.Warningf("Ignoring poorly formatted value %v of %v, expecting namespace: suffixes", i, namespaceHostSuffixes)
+				continue
+			}
+			namespaceHostSuffixesMap[namespace] = append(namespaceHostSuffixesMap[namespace], strings.Fields(suffixes)...)
+		}
+	}
+
+	if val, ok := conf[opentelemetrySpanAttributes]; ok {
+		delete(conf, opentelemetrySpanAttributes)
+		to.OpentelemetrySpanAttributes = opentelemetry.ParseSpanAttributes(val)
+	}
+
+	if val, ok := conf[opentelemetryPropagator]; ok {
+		delete(conf, opentelemetryPropagator)
+		val = strings.TrimSpace(val)
+		if slices.Contains(opentelemetry.Propagators, val) {
+			to.OpentelemetryPropagator = val
+		} else {
+			klog.Warningf("%v is not a valid value for %v, using %v", val, opentelemetryPropagator, to.OpentelemetryPropagator)
+		}
+	}
+
+	if val, ok := conf[logFormatJSONFields]; ok {
+		delete(conf, logFormatJSONFields)
+		to.LogFormatJSONFields = parseLogFields(val)
+	}
+
+	if val, ok := conf[requestIDFormat]; ok {
+		delete(conf, requestIDFormat)
+		val = strings.TrimSpace(val)
+		if slices.Contains(config.RequestIDFormats, val) {
+			to.RequestIDFormat = val
+		} else {
+			klog.Warningf("%v is not a valid value for %v, using %v", val, requestIDFormat, to.RequestIDFormat)
+		}
+	}
+
+	if val, ok := conf[requestIDPrefix]; ok {
+		delete(conf, requestIDPrefix)
+		val = strings.TrimSpace(val)
+		if requestIDPrefixRegex.MatchString(val) {
+			to.RequestIDPrefix = val
+		} else {
+			klog.Warningf("Ignoring %v %q: only letters, digits and the _.:- characters are allowed", requestIDPrefix, val)
+		}
+	}
+
+	if val, ok := conf[requestIDTrustedCIDRs]; ok {
+		delete(conf, requestIDTrustedCIDRs)
+		for _, cidr := range splitAndTrimSpace(val, ",") {
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				klog.Warningf("Ignoring the address %q of %v: it is not an IP address or a CIDR", cidr, requestIDTrustedCIDRs)
+				continue
+			}
+			to.RequestIDTrustedCIDRs = append(to.RequestIDTrustedCIDRs, cidr)
+		}
+	}
+
+	if val, ok := conf[botDetectionReputationCIDRs]; ok {
+		delete(conf, botDetectionReputationCIDRs)
+		for _, cidr := range splitAndTrimSpace(val, ",") {
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				klog.Warningf("Ignoring the address %q of %v: it is not an IP address or a CIDR", cidr, botDetectionReputationCIDRs)
+				continue
+			}
+			to.BotDetectionReputationCIDRs = append(to.BotDetectionReputationCIDRs, cidr)
+		}
+	}
+
+	if val, ok := conf[autoBanStatusCodes]; ok {
+		delete(conf, autoBanStatusCodes)
+		codes := []int{}
+		for _, i := range splitAndTrimSpace(val, ",") {
+			code, err := strconv.Atoi(i)
+			if err != nil || code < 400 || code > 599 {
+				klog.Warningf("Ignoring the status code %q of %v: it is not a 4xx or 5xx status code", i, autoBanStatusCodes)
+				continue
+			}
+			codes = append(codes, code)
+		}
+		to.AutoBanStatusCodes = codes
+	}
+
+	if val, ok := conf[proxyCacheZones]; ok {
+		delete(conf, proxyCacheZones)
+		to.ProxyCacheZones = parseProxyCacheZones(val)
+	}
+
+	if val, ok := conf[crowdSecLAPIURL]; ok {
+		delete(conf, crowdSecLAPIURL)
+		val = strings.TrimSuffix(strings.TrimSpace(val), "/")
+		if u, err := url.Parse(val); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			klog.Warningf("Ignoring %v %q: it is not an http or https URL", crowdSecLAPIURL, val)
+		} else {
+			to.CrowdSecLAPIURL = val
+		}
+	}
+
 	to.CustomHTTPErrors = filterErrors(errors)
 	to.SkipAccessLogURLs = skipUrls
 	to.DenylistSourceRange = denyList
@@ -453,6 +612,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 	to.ProxyStreamResponses = streamResponses
 	to.DisableIpv6DNS = !ing_net.IsIPv6Enabled()
 	to.LuaSharedDicts = luaSharedDicts
+	to.NamespaceHostSuffixes = namespaceHostSuffixesMap
 	to.Backend.AllowedResponseHeaders = allowedResponseHeaders
 
 	decoderConfig := &mapstructure.DecoderConfig{
@@ -471,18 +631,111 @@ func ReadConfig(src map[string]string) config.Configuration {
 		klog.Warningf("unexpected error merging defaults: %v", err)
 	}
 
-	hash, err := hashstructure.Hash(to, hashstructure.FormatV1, &hashstructure.HashOptions{
-		TagName: "json",
-	})
+	if to.EnableCrowdSec && to.CrowdSecLAPIURL == "" {
+		klog.Warningf("Disabling the CrowdSec bouncer: %v is not set", crowdSecLAPIURL)
+		to.EnableCrowdSec = false
+	}
+	if to.EnableCrowdSec && to.CrowdSecAPIKeySecret == "" {
+		klog.Warningf("Disabling the CrowdSec bouncer: crowdsec-api-key-secret is not set")
+		to.EnableCrowdSec = false
+	}
+
+	err = to.UpdateChecksums()
 	if err != nil {
 		klog.Warningf("unexpected error obtaining hash: %v", err)
 	}
 
-	to.Checksum = fmt.Sprintf("%v", hash)
-
 	return to
 }
 
+// parseProxyCacheZones parses a comma separated list of name:max-size or
+// name:max-size:inactive cache zones, sizing their keys zones
+func parseProxyCacheZones(val string) []config.ProxyCacheZone {
+	zones := []config.ProxyCacheZone{}
+	names := sets.Set[string]{}
+	for _, entry := range splitAndTrimSpace(val, ",") {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			klog.Warningf("Ignoring the cache zone %q of %v: it is not name:max-size or name:max-size:inactive", entry, proxyCacheZones)
+			continue
+		}
+
+		zone := config.ProxyCacheZone{Name: parts[0], MaxSize: parts[1], Inactive: defaultCacheInactive}
+		if len(parts) == 3 {
+			zone.Inactive = parts[2]
+		}
+
+		if !cacheZoneNameRegex.MatchString(zone.Name) || names.Has(zone.Name) {
+			klog.Warningf("Ignoring the cache zone %q of %v: invalid or duplicate name", entry, proxyCacheZones)
+			continue
+		}
+		maxSize := cacheSizeToBytes(zone.MaxSize)
+		if maxSize <= 0 || !cacheInactiveRegex.MatchString(zone.Inactive) {
+			klog.Warningf("Ignoring the cache zone %q of %v: invalid size or inactive time", entry, proxyCacheZones)
+			continue
+		}
+
+		keysZone := (maxSize + cachedBytesPerKeysMegabyte - 1) / cachedBytesPerKeysMegabyte
+		if keysZone > maxKeysZoneMegabytes {
+			keysZone = maxKeysZoneMegabytes
+		}
+		zone.KeysZoneSize = fmt.Sprintf("%dm", keysZone)
+
+		names.Insert(zone.Name)
+		zones = append(zones, zone)
+	}
+
+	return zones
+}
+
+// cacheSizeToBytes returns the size in bytes of a size like 10g, -1 when it is invalid
+func cacheSizeToBytes(size string) int64 {
+	sizeMatch := cacheSizeRegex.FindStringSubmatch(size)
+	if sizeMatch == nil {
+		return -1
+	}
+	value, err := strconv.ParseInt(sizeMatch[1], 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	switch strings.ToLower(sizeMatch[2]) {
+	case "k":
+		value *= 1024
+	case "m":
+		value *= 1024 * 1024
+	case "g":
+		value *= 1024 * 1024 * 1024
+	}
+
+	return value
+}
+
+// parseLogFields parses a comma separated list of name=value fields of the
+// JSON access log. The values cannot contain quotes, backslashes or control
+// characters, the JSON escaping only applies to the variables.
+func parseLogFields(val string) []config.LogField {
+	var fields []config.LogField
+	names := map[string]int{}
+	for _, i := range splitAndTrimSpace(val, ",") {
+		name, value, found := strings.Cut(i, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !found || !logFieldNameRegex.MatchString(name) || !logFieldValueRegex.MatchString(value) {
+			klog.Warningf("Ignoring invalid value %v of %v, expecting name=value", i, logFormatJSONFields)
+			continue
+		}
+
+		if idx, ok := names[name]; ok {
+			fields[idx].Value = value
+			continue
+		}
+		names[name] = len(fields)
+		fields = append(fields, config.LogField{Name: name, Value: value})
+	}
+
+	return fields
+}
+
 func filterErrors(codes []int) []int {
 	var fa []int
 	for _, code := range codes {
@@ -17,13 +17,11 @@ limitations under the License.
 package template
 
 import (
-	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/kylelemons/godebug/pretty"
-	"github.com/mitchellh/hashstructure/v2"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
@@ -77,6 +75,11 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 		"disable-ipv6-dns":              "true",
 		"default-type":                  "text/plain",
 		"debug-connections":             "127.0.0.1,1.1.1.1/24,::1",
+		"metrics-drop-labels":           "path, canary",
+		"metrics-limit-labels":          "host, path",
+		"metrics-max-label-values":      "500",
+		"opentelemetry-span-attributes": "deployment.environment=production, user_agent=$http_user_agent",
+		"opentelemetry-propagator":      "b3multi",
 	}
 	def := config.NewDefault()
 	def.CustomHTTPErrors = []int{300, 400}
@@ -103,14 +106,15 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 	def.DisableIpv6DNS = true
 	def.DefaultType = "text/plain"
 	def.DebugConnections = []string{"127.0.0.1", "1.1.1.1/24", "::1"}
+	def.MetricsDropLabels = []string{"path", "canary"}
+	def.MetricsLimitLabels = []string{"host", "path"}
+	def.MetricsMaxLabelValues = 500
+	def.OpentelemetrySpanAttributes = map[string]string{"deployment.environment": "production", "user_agent": "$http_user_agent"}
+	def.OpentelemetryPropagator = "b3multi"
 
-	hash, err := hashstructure.Hash(def, hashstructure.FormatV1, &hashstructure.HashOptions{
-		TagName: "json",
-	})
-	if err != nil {
+	if err := def.UpdateChecksums(); err != nil {
 		t.Fatalf("unexpected error obtaining hash: %v", err)
 	}
-	def.Checksum = fmt.Sprintf("%v", hash)
 
 	to := ReadConfig(conf)
 	if diff := pretty.Compare(to, def); diff != "" {
@@ -134,13 +138,9 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 	def.LuaSharedDicts = defaultLuaSharedDicts
 	def.DisableIpv6DNS = true
 
-	hash, err = hashstructure.Hash(def, hashstructure.FormatV1, &hashstructure.HashOptions{
-		TagName: "json",
-	})
-	if err != nil {
+	if err := def.UpdateChecksums(); err != nil {
 		t.Fatalf("unexpected error obtaining hash: %v", err)
 	}
-	def.Checksum = fmt.Sprintf("%v", hash)
 
 	to = ReadConfig(map[string]string{
 		"disable-ipv6-dns": "true",
@@ -155,13 +155,9 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 	def.WhitelistSourceRange = []string{"1.1.1.1/32"}
 	def.DisableIpv6DNS = true
 
-	hash, err = hashstructure.Hash(def, hashstructure.FormatV1, &hashstructure.HashOptions{
-		TagName: "json",
-	})
-	if err != nil {
+	if err := def.UpdateChecksums(); err != nil {
 		t.Fatalf("unexpected error obtaining hash: %v", err)
 	}
-	def.Checksum = fmt.Sprintf("%v", hash)
 
 	to = ReadConfig(map[string]string{
 		"denylist-source-range":  "2.2.2.2/32",
@@ -174,6 +170,35 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 	}
 }
 
+func TestDynamicKeysChecksum(t *testing.T) {
+	base := ReadConfig(map[string]string{})
+
+	to := ReadConfig(map[string]string{
+		"hsts-max-age":                     "3600",
+		"global-rate-limit-memcached-host": "memcached.default.svc",
+		"auto-ban-threshold":               "5",
+	})
+	if to.Checksum != base.Checksum {
+		t.Errorf("expected the checksum not to change with the dynamic keys")
+	}
+	if to.DynamicChecksum == base.DynamicChecksum {
+		t.Errorf("expected the dynamic checksum to change with the dynamic keys")
+	}
+	if to.Dynamic().HSTSMaxAge != "3600" || to.Dynamic().GlobalThrottle.Memcached.Host != "memcached.default.svc" {
+		t.Errorf("unexpected dynamic configuration %+v", to.Dynamic())
+	}
+
+	to = ReadConfig(map[string]string{
+		"proxy-body-size": "8m",
+	})
+	if to.Checksum == base.Checksum {
+		t.Errorf("expected the checksum to change with the keys requiring a reload")
+	}
+	if to.DynamicChecksum != base.DynamicChecksum {
+		t.Errorf("expected the dynamic checksum not to change with the keys requiring a reload")
+	}
+}
+
 func TestGlobalExternalAuthURLParsing(t *testing.T) {
 	errorURL := ""
 	validURL := "http://bar.foo.com/external-auth"
@@ -422,6 +447,52 @@ func TestLuaSharedDictsParsing(t *testing.T) {
 	}
 }
 
+func TestLogFormatJSONFieldsParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"log-format-json-fields": `time=$time_iso8601, status=$status, bad"name=1, request=$request, quote=a"b, status=$upstream_status`,
+	})
+
+	expected := []config.LogField{
+		{Name: "time", Value: "$time_iso8601"},
+		{Name: "status", Value: "$upstream_status"},
+		{Name: "request", Value: "$request"},
+	}
+	if !reflect.DeepEqual(to.LogFormatJSONFields, expected) {
+		t.Errorf("expected %v but returned %v", expected, to.LogFormatJSONFields)
+	}
+}
+
+func TestRequestIDParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"request-id-format":        "uuid7",
+		"request-id-prefix":        "prod-eu1-",
+		"request-id-trusted-cidrs": "10.0.0.0/8, 192.168.1.1, not-an-address",
+	})
+
+	if to.RequestIDFormat != config.RequestIDFormatUUID7 {
+		t.Errorf("expected the uuid7 format but returned %v", to.RequestIDFormat)
+	}
+	if to.RequestIDPrefix != "prod-eu1-" {
+		t.Errorf("expected the prod-eu1- prefix but returned %v", to.RequestIDPrefix)
+	}
+	expected := []string{"10.0.0.0/8", "192.168.1.1"}
+	if !reflect.DeepEqual(to.RequestIDTrustedCIDRs, expected) {
+		t.Errorf("expected %v but returned %v", expected, to.RequestIDTrustedCIDRs)
+	}
+
+	to = ReadConfig(map[string]string{
+		"request-id-format": "uuid1",
+		"request-id-prefix": "$host",
+	})
+
+	if to.RequestIDFormat != config.RequestIDFormatHex {
+		t.Errorf("expected the default hex format but returned %v", to.RequestIDFormat)
+	}
+	if to.RequestIDPrefix != "" {
+		t.Errorf("expected no prefix but returned %v", to.RequestIDPrefix)
+	}
+}
+
 func TestSplitAndTrimSpace(t *testing.T) {
 	testsCases := []struct {
 		name   string
@@ -530,3 +601,99 @@ func TestDictKbToStr(t *testing.T) {
 		}
 	}
 }
+
+func TestBotDetectionReputationCIDRsParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"bot-detection-secret":           "default/bot-detection",
+		"bot-detection-reputation-cidrs": "203.0.113.0/24, 2001:db8::1, 198.51.100.300",
+	})
+
+	if to.BotDetectionSecret != "default/bot-detection" {
+		t.Errorf("expected the default/bot-detection Secret but returned %v", to.BotDetectionSecret)
+	}
+	expected := []string{"203.0.113.0/24", "2001:db8::1"}
+	if !reflect.DeepEqual(to.BotDetectionReputationCIDRs, expected) {
+		t.Errorf("expected %v but returned %v", expected, to.BotDetectionReputationCIDRs)
+	}
+}
+
+func TestAutoBanStatusCodesParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{})
+	if !reflect.DeepEqual(to.AutoBanStatusCodes, []int{401, 403, 429}) {
+		t.Errorf("expected the default status codes but returned %v", to.AutoBanStatusCodes)
+	}
+
+	to = ReadConfig(map[string]string{
+		"enable-auto-ban":       "true",
+		"auto-ban-status-codes": "401, 404, 200, abc",
+	})
+	if !reflect.DeepEqual(to.AutoBanStatusCodes, []int{401, 404}) {
+		t.Errorf("expected [401 404] but returned %v", to.AutoBanStatusCodes)
+	}
+	if !to.Dynamic().AutoBan.Enabled || !reflect.DeepEqual(to.Dynamic().AutoBan.StatusCodes, []int{401, 404}) {
+		t.Errorf("unexpected dynamic configuration %+v", to.Dynamic().AutoBan)
+	}
+}
+
+func TestCrowdSecParsing(t *testing.T) {
+	testCases := map[string]struct {
+		conf    map[string]string
+		enabled bool
+		lapiURL string
+	}{
+		"enabled with a Local API": {
+			conf: map[string]string{
+				"enable-crowdsec":         "true",
+				"crowdsec-lapi-url":       "http://crowdsec-service.crowdsec:8080/",
+				"crowdsec-api-key-secret": "crowdsec/bouncer",
+			},
+			enabled: true,
+			lapiURL: "http://crowdsec-service.crowdsec:8080",
+		},
+		"enabled without API key": {
+			conf:    map[string]string{"enable-crowdsec": "true", "crowdsec-lapi-url": "http://crowdsec-service.crowdsec:8080"},
+			lapiURL: "http://crowdsec-service.crowdsec:8080",
+		},
+		"enabled without Local API": {
+			conf: map[string]string{"enable-crowdsec": "true"},
+		},
+		"enabled with an invalid Local API": {
+			conf: map[string]string{"enable-crowdsec": "true", "crowdsec-lapi-url": "crowdsec-service:8080"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			to := ReadConfig(tc.conf)
+			if to.EnableCrowdSec != tc.enabled {
+				t.Errorf("expected enable-crowdsec %v but returned %v", tc.enabled, to.EnableCrowdSec)
+			}
+			if to.CrowdSecLAPIURL != tc.lapiURL {
+				t.Errorf("expected the Local API %q but returned %q", tc.lapiURL, to.CrowdSecLAPIURL)
+			}
+		})
+	}
+}
+
+func TestProxyCacheZonesParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{})
+	if len(to.ProxyCacheZones) != 0 || to.ProxyCachePath != "/tmp/nginx/proxy-cache" {
+		t.Errorf("unexpected default cache zones %v in %v", to.ProxyCacheZones, to.ProxyCachePath)
+	}
+
+	to = ReadConfig(map[string]string{
+		"proxy-cache-zones": "static:10g:7d, api:100m, static:1g, Invalid:1g, broken, huge:1000g, bad_size:10x",
+		"proxy-cache-path":  "/var/cache/nginx",
+	})
+	expected := []config.ProxyCacheZone{
+		{Name: "static", MaxSize: "10g", Inactive: "7d", KeysZoneSize: "80m"},
+		{Name: "api", MaxSize: "100m", Inactive: "60m", KeysZoneSize: "1m"},
+		{Name: "huge", MaxSize: "1000g", Inactive: "60m", KeysZoneSize: "1024m"},
+	}
+	if !reflect.DeepEqual(to.ProxyCacheZones, expected) {
+		t.Errorf("expected %v but returned %v", expected, to.ProxyCacheZones)
+	}
+	if to.ProxyCachePath != "/var/cache/nginx" {
+		t.Errorf("expected /var/cache/nginx but returned %v", to.ProxyCachePath)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// serverBlock is a rendered server block and the hash of everything it
+// was rendered from
+type serverBlock struct {
+	hash uint64
+	text string
+}
+
+// serverBlockKey holds everything a server block depends on. The Ingress of
+// the locations is not part of the hash of the server, the information the
+// template reads from it is hashed instead.
+type serverBlockKey struct {
+	Global         uint64
+	Server         *ingress.Server
+	Ingresses      []*ingressInformation
+	SSLPassthrough []bool
+}
+
+// serverBlockCache keeps the server blocks of the last rendering, only the
+// servers whose configuration changed since are rendered again
+type serverBlockCache struct {
+	// hash of the parts of the configuration shared by all the server blocks
+	global   uint64
+	disabled bool
+	// backends with SSL passthrough enabled, they change the proxy_pass of
+	// their locations
+	sslPassthroughBackends sets.Set[string]
+
+	// server blocks of the previous and of the current rendering, indexed by
+	// hostname
+	previous map[string]serverBlock
+	current  map[string]serverBlock
+	// blocks are the server blocks of the servers of the current rendering
+	blocks map[*ingress.Server]string
+
+	rendered int
+	reused   int
+}
+
+// begin prepares the cache for a new rendering of the configuration
+func (c *serverBlockCache) begin(conf *config.TemplateConfig) {
+	// the server blocks do not depend on the endpoints nor on the stream
+	// section of the configuration
+	global := *conf
+	global.Servers = nil
+	global.Backends = nil
+	global.PassthroughBackends = nil
+	global.TCPBackends = nil
+	global.UDPBackends = nil
+	global.RedirectServers = nil
+	global.PublishService = nil
+
+	hash, err := hashstructure.Hash(global, hashstructure.FormatV1, &hashstructure.HashOptions{
+		TagName: "json",
+	})
+	if err != nil {
+		klog.Warningf("unexpected error hashing configuration, rendering all the server blocks: %v", err)
+	}
+
+	c.global = hash
+	c.disabled = err != nil
+	c.sslPassthroughBackends = sets.Set[string]{}
+	for _, backend := range conf.Backends {
+		if backend.SSLPassthrough {
+			c.sslPassthroughBackends.Insert(backend.Name)
+		}
+	}
+
+	c.current = make(map[string]serverBlock, len(conf.Servers))
+	c.blocks = make(map[*ingress.Server]string, len(conf.Servers))
+	c.rendered = 0
+	c.reused = 0
+}
+
+// commit replaces the server blocks of the previous rendering with the ones
+// of the current rendering, dropping the blocks of the removed servers
+func (c *serverBlockCache) commit() {
+	klog.V(3).InfoS("Rendered server blocks", "rendered", c.rendered, "reused", c.reused)
+
+	c.previous = c.current
+	c.current = nil
+	c.blocks = nil
+}
+
+// hash returns the hash of everything the server block of the server depends on
+func (c *serverBlockCache) hash(server *ingress.Server) (uint64, bool) {
+	if c.disabled {
+		return 0, false
+	}
+
+	key := serverBlockKey{
+		Global: c.global,
+		Server: server,
+	}
+	for _, location := range server.Locations {
+		key.Ingresses = append(key.Ingresses, getIngressInformation(location.Ingress, server.Hostname, location.IngressPath))
+		key.SSLPassthrough = append(key.SSLPassthrough, c.sslPassthroughBackends.Has(location.Backend))
+	}
+
+	hash, err := hashstructure.Hash(key, hashstructure.FormatV1, &hashstructure.HashOptions{
+		TagName: "json",
+	})
+	if err != nil {
+		klog.Warningf("unexpected error hashing server %v, rendering its server block: %v", server.Hostname, err)
+		return 0, false
+	}
+
+	return hash, true
+}
+
+// serverBlockResult is the outcome of the rendering of a server block by a
+// worker
+type serverBlockResult struct {
+	hash      uint64
+	cacheable bool
+	reused    bool
+	text      string
+	err       error
+}
+
+// renderServerBlocks renders the server blocks of the servers changed since
+// the previous rendering concurrently, before the configuration is rendered.
+// The template then writes them in the order of the servers.
+func (t *Template) renderServerBlocks(conf *config.TemplateConfig) error {
+	results := make([]serverBlockResult, len(conf.Servers))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(conf.Servers) {
+		workers = len(conf.Servers)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = t.renderServerBlock(conf, conf.Servers[i])
+			}
+		}()
+	}
+
+	for i := range conf.Servers {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, result := range results {
+		if result.err != nil {
+			return result.err
+		}
+
+		server := conf.Servers[i]
+		t.serverBlocks.blocks[server] = result.text
+		if result.cacheable {
+			t.serverBlocks.current[server.Hostname] = serverBlock{hash: result.hash, text: result.text}
+		}
+
+		if result.reused {
+			t.serverBlocks.reused++
+		} else {
+			t.serverBlocks.rendered++
+		}
+	}
+
+	return nil
+}
+
+// renderServerBlock returns the server block of the server, rendering it only
+// when it changed since the previous rendering of the configuration. It is
+// called concurrently, it must not change the cache.
+func (t *Template) renderServerBlock(conf *config.TemplateConfig, server *ingress.Server) serverBlockResult {
+	hash, cacheable := t.serverBlocks.hash(server)
+	if cacheable {
+		if block, ok := t.serverBlocks.previous[server.Hostname]; ok && block.hash == hash {
+			return serverBlockResult{hash: hash, cacheable: true, reused: true, text: block.text}
+		}
+	}
+
+	buf := t.bp.Get()
+	defer t.bp.Put(buf)
+
+	err := t.tmpl.ExecuteTemplate(buf, "SERVER_BLOCK", struct{ First, Second interface{} }{*conf, server})
+	if err != nil {
+		return serverBlockResult{err: err}
+	}
+
+	return serverBlockResult{hash: hash, cacheable: cacheable, text: buf.String()}
+}
+
+// buildServerBlock returns the server block of the server rendered by
+// renderServerBlocks
+func (t *Template) buildServerBlock(all config.TemplateConfig, server *ingress.Server) (string, error) {
+	if text, ok := t.serverBlocks.blocks[server]; ok {
+		return text, nil
+	}
+
+	// the server is not part of the rendered configuration
+	result := t.renderServerBlock(&all, server)
+	return result.text, result.err
+}
@@ -25,26 +25,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"math/big"
 	"net"
 	"net/url"
 	"os"
+	"path"
 	"reflect"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	text_template "text/template"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
+	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customerrorpages"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentelemetry"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/trailingslash"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
+	"k8s.io/ingress-nginx/internal/nginx"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
+	"k8s.io/ingress-nginx/pkg/logs"
 )
 
 const (
@@ -78,6 +90,10 @@ type Template struct {
 	tmpl *text_template.Template
 
 	bp *BufferPool
+
+	// mu serializes the renderings, they share the cache of server blocks
+	mu           sync.Mutex
+	serverBlocks serverBlockCache
 }
 
 // NewTemplate returns a new Template instance or an
@@ -88,15 +104,18 @@ func NewTemplate(file string) (*Template, error) {
 		return nil, fmt.Errorf("unexpected error reading template %s: %w", file, err)
 	}
 
-	tmpl, err := text_template.New("nginx.tmpl").Funcs(funcMap).Parse(string(data))
+	t := &Template{
+		bp: NewBufferPool(defBufferSize),
+	}
+
+	t.tmpl, err = text_template.New("nginx.tmpl").Funcs(funcMap).Funcs(text_template.FuncMap{
+		"buildServerBlock": t.buildServerBlock,
+	}).Parse(string(data))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Template{
-		tmpl: tmpl,
-		bp:   NewBufferPool(defBufferSize),
-	}, nil
+	return t, nil
 }
 
 // 1. Removes carriage return symbol (\r)
@@ -183,6 +202,9 @@ func cleanConf(in, out *bytes.Buffer) error {
 // Write populates a buffer using a template with NGINX configuration
 // and the servers and upstreams created by Ingress rules
 func (t *Template) Write(conf *config.TemplateConfig) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	tmplBuf := t.bp.Get()
 	defer t.bp.Put(tmplBuf)
 
@@ -197,10 +219,17 @@ func (t *Template) Write(conf *config.TemplateConfig) ([]byte, error) {
 		klog.InfoS("NGINX", "configuration", string(b))
 	}
 
-	err := t.tmpl.Execute(tmplBuf, *conf)
+	t.serverBlocks.begin(conf)
+	err := t.renderServerBlocks(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.tmpl.Execute(tmplBuf, *conf)
 	if err != nil {
 		return nil, err
 	}
+	t.serverBlocks.commit()
 
 	// squeezes multiple adjacent empty lines to be single
 	// spaced this is to avoid the use of regular expressions
@@ -244,6 +273,8 @@ var funcMap = text_template.FuncMap{
 	"buildRateLimit":                  buildRateLimit,
 	"configForLua":                    configForLua,
 	"locationConfigForLua":            locationConfigForLua,
+	"locationPluginsForLua":           locationPluginsForLua,
+	"buildIngressPlugins":             buildIngressPlugins,
 	"buildResolvers":                  buildResolvers,
 	"buildUpstreamName":               buildUpstreamName,
 	"isLocationInLocationList":        isLocationInLocationList,
@@ -281,8 +312,34 @@ var funcMap = text_template.FuncMap{
 	"buildModSecurityForLocation":        buildModSecurityForLocation,
 	"buildMirrorLocations":               buildMirrorLocations,
 	"shouldLoadAuthDigestModule":         shouldLoadAuthDigestModule,
+	"shouldLoadNJSModule":                shouldLoadNJSModule,
+	"buildNJSForLocation":                buildNJSForLocation,
+	"buildProxyCachePaths":               buildProxyCachePaths,
+	"buildProxyCache":                    buildProxyCache,
+	"shouldLoadBrotliModule":             shouldLoadBrotliModule,
+	"buildBrotliForLocation":             buildBrotliForLocation,
+	"shouldLoadZstdModule":               shouldLoadZstdModule,
+	"buildZstdForLocation":               buildZstdForLocation,
+	"buildMapHashBucketSize":             buildMapHashBucketSize,
+	"buildMapHashMaxSize":                buildMapHashMaxSize,
+	"buildRedirectMaps":                  buildRedirectMaps,
+	"buildRedirectMapForServer":          buildRedirectMapForServer,
+	"buildConditionalRedirects":          buildConditionalRedirects,
+	"buildTrailingSlashRedirect":         buildTrailingSlashRedirect,
+	"buildCustomErrorPages":              buildCustomErrorPages,
+	"buildCustomErrorPagesLocations":     buildCustomErrorPagesLocations,
+	"buildMaintenanceAllowlists":         buildMaintenanceAllowlists,
+	"buildMaintenanceMode":               buildMaintenanceMode,
+	"buildGeoIP2Databases":               buildGeoIP2Databases,
 	"buildServerName":                    buildServerName,
 	"buildCorsOriginRegex":               buildCorsOriginRegex,
+	"buildLogFormatJSON":                 buildLogFormatJSON,
+	"buildAccessLogFormats":              buildAccessLogFormats,
+	"buildLocationAccessLog":             buildLocationAccessLog,
+	"buildServerErrorLog":                buildServerErrorLog,
+	"buildRequestID":                     buildRequestID,
+	"buildGeneratedRequestID":            buildGeneratedRequestID,
+	"buildAccessLogSampling":             buildAccessLogSampling,
 }
 
 // escapeLiteralDollar will replace the $ character with ${literal_dollar}
@@ -377,6 +434,18 @@ func configForLua(input interface{}) string {
 		return "{}"
 	}
 
+	reputationCIDRs, err := convertGoSliceIntoLuaTable(all.Cfg.BotDetectionReputationCIDRs, false)
+	if err != nil {
+		klog.Errorf("failed to convert %v into Lua table: %q", all.Cfg.BotDetectionReputationCIDRs, err)
+		reputationCIDRs = "{}"
+	}
+
+	autoBanStatusCodes, err := convertGoSliceIntoLuaTable(all.Cfg.AutoBanStatusCodes, false)
+	if err != nil {
+		klog.Errorf("failed to convert %v into Lua table: %q", all.Cfg.AutoBanStatusCodes, err)
+		autoBanStatusCodes = "{}"
+	}
+
 	return fmt.Sprintf(`{
 		use_forwarded_headers = %t,
 		use_proxy_protocol = %t,
@@ -389,12 +458,24 @@ func configForLua(input interface{}) string {
 		hsts_include_subdomains = %t,
 		hsts_preload = %t,
 
+		drain = %t,
+
 		global_throttle = {
 			memcached = {
 				host = "%v", port = %d, connect_timeout = %d, max_idle_timeout = %d, pool_size = %d,
 			},
 			status_code = %d,
-		}
+		},
+
+		bot_detection = { reputation_cidrs = %v },
+
+		crowdsec = {
+			enabled = %t, lapi_url = %q, cache_ttl = %d, timeout = %d,
+		},
+
+		auto_ban = {
+			enabled = %t, status_codes = %v, threshold = %d, window = %d, duration = %d,
+		},
 	}`,
 		all.Cfg.UseForwardedHeaders,
 		all.Cfg.UseProxyProtocol,
@@ -408,12 +489,27 @@ func configForLua(input interface{}) string {
 		all.Cfg.HSTSIncludeSubdomains,
 		all.Cfg.HSTSPreload,
 
+		all.EnableDrain,
+
 		all.Cfg.GlobalRateLimitMemcachedHost,
 		all.Cfg.GlobalRateLimitMemcachedPort,
 		all.Cfg.GlobalRateLimitMemcachedConnectTimeout,
 		all.Cfg.GlobalRateLimitMemcachedMaxIdleTimeout,
 		all.Cfg.GlobalRateLimitMemcachedPoolSize,
 		all.Cfg.GlobalRateLimitStatusCode,
+
+		reputationCIDRs,
+
+		all.Cfg.EnableCrowdSec,
+		all.Cfg.CrowdSecLAPIURL,
+		all.Cfg.CrowdSecCacheTTL,
+		all.Cfg.CrowdSecTimeout,
+
+		all.Cfg.EnableAutoBan,
+		autoBanStatusCodes,
+		all.Cfg.AutoBanThreshold,
+		all.Cfg.AutoBanWindow,
+		all.Cfg.AutoBanDuration,
 	)
 }
 
@@ -437,6 +533,21 @@ func locationConfigForLua(l, a interface{}) string {
 		ignoredCIDRs = "{}"
 	}
 
+	csrfTrustedOrigins, err := convertGoSliceIntoLuaTable(location.CSRF.TrustedOrigins, false)
+	if err != nil {
+		klog.Errorf("failed to convert %v into Lua table: %q", location.CSRF.TrustedOrigins, err)
+		csrfTrustedOrigins = "{}"
+	}
+
+	geoCodes := func(codes []string) string {
+		luaTable, err := convertGoSliceIntoLuaTable(codes, false)
+		if err != nil {
+			klog.Errorf("failed to convert %v into Lua table: %q", codes, err)
+			return "{}"
+		}
+		return luaTable
+	}
+
 	return fmt.Sprintf(`{
 		force_ssl_redirect = %t,
 		ssl_redirect = %t,
@@ -444,6 +555,17 @@ func locationConfigForLua(l, a interface{}) string {
 		preserve_trailing_slash = %t,
 		use_port_in_redirects = %t,
 		global_throttle = { namespace = "%v", limit = %d, window_size = %d, key = %v, ignored_cidrs = %v },
+		websocket = { read_timeout = %d, send_timeout = %d, max_connections = %d },
+		bot_detection = { enabled = %t, challenge = "%v", challenge_score = %d, block_score = %d, rate_limit = %d },
+		csrf = { enabled = %t, header_name = "%v", trusted_origins = %v },
+		normalization = {
+			merge_slashes = %t, percent_encoding = %t, reject_encoded_traversal = %t, duplicate_headers = %t,
+		},
+		strict_request_validation = %t,
+		csp_nonce = %t,
+		geo_access = {
+			allow_countries = %v, deny_countries = %v, allow_asns = %v, deny_asns = %v, status_code = %d,
+		},
 	}`,
 		location.Rewrite.ForceSSLRedirect,
 		location.Rewrite.SSLRedirect,
@@ -455,6 +577,28 @@ func locationConfigForLua(l, a interface{}) string {
 		location.GlobalRateLimit.WindowSize,
 		parseComplexNginxVarIntoLuaTable(location.GlobalRateLimit.Key),
 		ignoredCIDRs,
+		location.WebSocket.ReadTimeout,
+		location.WebSocket.SendTimeout,
+		location.WebSocket.MaxConnections,
+		location.BotDetection.Enabled,
+		location.BotDetection.Challenge,
+		location.BotDetection.ChallengeScore,
+		location.BotDetection.BlockScore,
+		location.BotDetection.RateLimit,
+		location.CSRF.Enabled,
+		location.CSRF.HeaderName,
+		csrfTrustedOrigins,
+		location.Normalization.MergeSlashes,
+		location.Normalization.PercentEncoding,
+		location.Normalization.RejectEncodedTraversal,
+		location.Normalization.DuplicateHeaders,
+		location.StrictRequestValidation,
+		location.SecurityHeaders.Nonce,
+		geoCodes(location.GeoAccess.AllowCountries),
+		geoCodes(location.GeoAccess.DenyCountries),
+		geoCodes(location.GeoAccess.AllowASNs),
+		geoCodes(location.GeoAccess.DenyASNs),
+		location.GeoAccess.StatusCode,
 	)
 }
 
@@ -503,8 +647,9 @@ func needsRewrite(location *ingress.Location) bool {
 	return false
 }
 
-// enforceRegexModifier checks if the "rewrite-target" or "use-regex" annotation
-// is used on any location path within a server
+// enforceRegexModifier checks if the "rewrite-target", "use-regex" or
+// "use-case-insensitive-paths" annotation is used on any location path within
+// a server
 func enforceRegexModifier(input interface{}) bool {
 	locations, ok := input.([]*ingress.Location)
 	if !ok {
@@ -513,7 +658,7 @@ func enforceRegexModifier(input interface{}) bool {
 	}
 
 	for _, location := range locations {
-		if needsRewrite(location) || location.Rewrite.UseRegex {
+		if needsRewrite(location) || location.Rewrite.UseRegex || location.Rewrite.CaseInsensitivePaths {
 			return true
 		}
 	}
@@ -935,7 +1080,11 @@ func isLocationAllowed(input interface{}) bool {
 	return loc.Denied == nil
 }
 
-var denyPathSlugMap = map[string]string{}
+var (
+	denyPathSlugMap = map[string]string{}
+	// server blocks are rendered concurrently
+	denyPathSlugMapLock sync.Mutex
+)
 
 // buildDenyVariable returns a nginx variable for a location in a
 // server to be used in the whitelist check
@@ -949,6 +1098,9 @@ func buildDenyVariable(a interface{}) string {
 		return ""
 	}
 
+	denyPathSlugMapLock.Lock()
+	defer denyPathSlugMapLock.Unlock()
+
 	if _, ok := denyPathSlugMap[l]; !ok {
 		denyPathSlugMap[l] = randomString()
 	}
@@ -1319,11 +1471,19 @@ func buildCustomErrorLocationsPerServer(input interface{}) []errorLocation {
 	return errorLocations
 }
 
-func opentelemetryPropagateContext(location *ingress.Location) string {
+func opentelemetryPropagateContext(location *ingress.Location, propagator string) string {
 	if location == nil {
 		return ""
 	}
-	return "opentelemetry_propagate;"
+
+	switch propagator {
+	case opentelemetry.PropagatorNone:
+		return ""
+	case opentelemetry.PropagatorB3, opentelemetry.PropagatorB3Multi:
+		return "opentelemetry_propagate " + propagator + ";"
+	default:
+		return "opentelemetry_propagate;"
+	}
 }
 
 // shouldLoadModSecurityModule determines whether or not the ModSecurity module needs to be loaded.
@@ -1490,9 +1650,9 @@ func httpsListener(addresses []string, co string, tc *config.TemplateConfig) []s
 				lo = append(lo, fmt.Sprintf("%v:%v", address, tc.ListenPorts.SSLProxy))
 			}
 
-			if !strings.Contains(co, "proxy_protocol") {
-				lo = append(lo, "proxy_protocol")
-			}
+			// the connections are passed by the stream server of the HTTPS
+			// port, which already read the PROXY protocol header
+			co = strings.TrimSpace(strings.Replace(co, "proxy_protocol", "", 1))
 		} else {
 			if address == "" {
 				lo = append(lo, fmt.Sprintf("%v", tc.ListenPorts.HTTPS))
@@ -1509,7 +1669,9 @@ func httpsListener(addresses []string, co string, tc *config.TemplateConfig) []s
 	return out
 }
 
-func buildOpentelemetryForLocation(isOTEnabled, isOTTrustSet bool, location *ingress.Location) string {
+func buildOpentelemetryForLocation(cfg config.Configuration, location *ingress.Location) string {
+	isOTEnabled := cfg.EnableOpentelemetry
+	isOTTrustSet := cfg.OpentelemetryTrustIncomingSpan
 	isOTEnabledInLoc := location.Opentelemetry.Enabled
 	isOTSetInLoc := location.Opentelemetry.Set
 
@@ -1521,9 +1683,14 @@ func buildOpentelemetryForLocation(isOTEnabled, isOTTrustSet bool, location *ing
 		return ""
 	}
 
-	opc := opentelemetryPropagateContext(location)
-	if opc != "" {
-		opc = fmt.Sprintf("opentelemetry on;\n%v", opc)
+	propagator := cfg.OpentelemetryPropagator
+	if location.Opentelemetry.Propagator != "" {
+		propagator = location.Opentelemetry.Propagator
+	}
+
+	opc := "opentelemetry on;"
+	if propagate := opentelemetryPropagateContext(location, propagator); propagate != "" {
+		opc += "\n" + propagate
 	}
 
 	if location.Opentelemetry.OperationName != "" {
@@ -1536,6 +1703,35 @@ func buildOpentelemetryForLocation(isOTEnabled, isOTTrustSet bool, location *ing
 	} else {
 		opc += "\nopentelemetry_trust_incoming_spans on;"
 	}
+
+	forwardBaggage := cfg.OpentelemetryForwardBaggage
+	if location.Opentelemetry.BaggageSet {
+		forwardBaggage = location.Opentelemetry.ForwardBaggage
+	}
+	if !forwardBaggage {
+		opc += "\n" + proxySetHeader(location) + " baggage \"\";"
+	}
+
+	attributes := map[string]string{}
+	if cfg.OpentelemetryRouteOperationName {
+		route := escapeLiteralDollar(location.Path)
+		if location.Opentelemetry.OperationName == "" {
+			opc += "\nopentelemetry_operation_name " + quote("$request_method "+route) + ";"
+		}
+		attributes["http.route"] = route
+	}
+	maps.Copy(attributes, cfg.OpentelemetrySpanAttributes)
+	maps.Copy(attributes, location.Opentelemetry.SpanAttributes)
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		opc += fmt.Sprintf("\nopentelemetry_attribute %v %v;", quote(key), quote(attributes[key]))
+	}
+
 	return opc
 }
 
@@ -1606,11 +1802,21 @@ func buildModSecurityForLocation(cfg config.Configuration, location *ingress.Loc
 `)
 	}
 
+	if location.ModSecurity.WAFPolicySetupFile != "" {
+		buffer.WriteString(fmt.Sprintf(`modsecurity_rules_file %v;
+`, location.ModSecurity.WAFPolicySetupFile))
+	}
+
 	if !cfg.EnableOWASPCoreRules && location.ModSecurity.OWASPRules {
 		buffer.WriteString(`modsecurity_rules_file /etc/nginx/owasp-modsecurity-crs/nginx-modsecurity.conf;
 `)
 	}
 
+	if location.ModSecurity.WAFPolicyExclusionsFile != "" {
+		buffer.WriteString(fmt.Sprintf(`modsecurity_rules_file %v;
+`, location.ModSecurity.WAFPolicyExclusionsFile))
+	}
+
 	return buffer.String()
 }
 
@@ -1631,11 +1837,29 @@ func buildMirrorLocations(locs []*ingress.Location) string {
 		mapped.Insert(loc.Mirror.Source)
 		buffer.WriteString(fmt.Sprintf(`location = %v {
 internal;
-proxy_set_header Host "%v";
+`, loc.Mirror.Source))
+
+		if loc.Mirror.Percentage < 100 {
+			buffer.WriteString(fmt.Sprintf(`rewrite_by_lua_block {
+if math.random(100) > %v then
+return ngx.exit(ngx.HTTP_NO_CONTENT)
+end
+}
+`, loc.Mirror.Percentage))
+		}
+
+		if loc.Mirror.Timeout > 0 {
+			buffer.WriteString(fmt.Sprintf(`proxy_connect_timeout %[1]vs;
+proxy_send_timeout %[1]vs;
+proxy_read_timeout %[1]vs;
+`, loc.Mirror.Timeout))
+		}
+
+		buffer.WriteString(fmt.Sprintf(`proxy_set_header Host "%v";
 proxy_pass "%v";
 }
 
-`, loc.Mirror.Source, loc.Mirror.Host, loc.Mirror.Target))
+`, loc.Mirror.Host, loc.Mirror.Target))
 	}
 
 	return buffer.String()
@@ -1664,6 +1888,244 @@ func shouldLoadAuthDigestModule(s interface{}) bool {
 	return false
 }
 
+// shouldLoadNJSModule returns true when a location imports njs scripts, the
+// scripts are ignored on the NGINX images built without the njs module
+func shouldLoadNJSModule(available bool, s interface{}) bool {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.NJS.Directory == "" {
+				continue
+			}
+			if !available {
+				klog.Warningf("Ignoring the njs scripts of the locations: the NGINX image was built without the %v module", nginx.NJSModule)
+				return false
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildNJSForLocation returns the directives importing the njs scripts of
+// the location, from the directory they were written to, and setting the
+// variables of the location with their functions
+func buildNJSForLocation(available bool, location *ingress.Location) string {
+	if location.NJS.Directory == "" || !available {
+		return ""
+	}
+
+	var buffer bytes.Buffer
+	// the scripts can import each other relatively to their directory
+	buffer.WriteString(fmt.Sprintf("js_path %q;\n", location.NJS.Directory))
+	for _, module := range location.NJS.Modules {
+		buffer.WriteString(fmt.Sprintf("js_import %v from %v.js;\n", module, module))
+	}
+	for _, variable := range location.NJS.Variables {
+		buffer.WriteString(fmt.Sprintf("js_set $njs_%v %v.%v;\n", variable.Name, variable.Module, variable.Function))
+	}
+
+	return buffer.String()
+}
+
+// buildProxyCachePaths returns the proxy_cache_path directives of the cache
+// zones of the configuration, every zone caching in its own directory
+func buildProxyCachePaths(cfg config.Configuration) []string {
+	paths := []string{}
+	for _, zone := range cfg.ProxyCacheZones {
+		paths = append(paths, fmt.Sprintf("proxy_cache_path %v levels=1:2 keys_zone=cache_%v:%v max_size=%v inactive=%v use_temp_path=off;",
+			path.Join(cfg.ProxyCachePath, zone.Name), zone.Name, zone.KeysZoneSize, zone.MaxSize, zone.Inactive))
+	}
+
+	return paths
+}
+
+// buildProxyCache returns the directives caching the responses of the
+// location in its cache zone
+func buildProxyCache(cfg config.Configuration, location *ingress.Location) string {
+	proxyCache := location.ProxyCache
+	if proxyCache.Zone == "" {
+		return ""
+	}
+
+	zoneExists := false
+	for _, zone := range cfg.ProxyCacheZones {
+		if zone.Name == proxyCache.Zone {
+			zoneExists = true
+			break
+		}
+	}
+	if !zoneExists {
+		klog.Warningf("the cache zone %q of the location %v is not one of the proxy-cache-zones, its responses are not cached", proxyCache.Zone, location.Path)
+		return ""
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("proxy_cache cache_%v;\n", proxyCache.Zone))
+	namespace, name := "", ""
+	if location.Ingress != nil {
+		namespace, name = location.Ingress.Namespace, location.Ingress.Name
+	}
+	buffer.WriteString(fmt.Sprintf("proxy_cache_key \"%v\";\n", proxycache.ScopedKey(namespace, name, proxyCache.Key)))
+	for _, valid := range proxyCache.Valid {
+		buffer.WriteString(fmt.Sprintf("proxy_cache_valid %v;\n", valid))
+	}
+	if len(proxyCache.Bypass) > 0 {
+		bypass := strings.Join(proxyCache.Bypass, " ")
+		buffer.WriteString(fmt.Sprintf("proxy_cache_bypass %v;\n", bypass))
+		buffer.WriteString(fmt.Sprintf("proxy_no_cache %v;\n", bypass))
+	}
+	// without stale conditions, the stale-while-revalidate and stale-if-error
+	// extensions of the Cache-Control header of the responses apply
+	if len(proxyCache.UseStale) > 0 {
+		buffer.WriteString(fmt.Sprintf("proxy_cache_use_stale %v;\n", strings.Join(proxyCache.UseStale, " ")))
+	}
+	if proxyCache.BackgroundUpdate {
+		buffer.WriteString("proxy_cache_background_update on;\n")
+	}
+	buffer.WriteString("proxy_cache_lock on;\n")
+	buffer.WriteString("more_set_headers \"X-Cache-Status: $upstream_cache_status\";\n")
+
+	return buffer.String()
+}
+
+// shouldLoadBrotliModule returns true when Brotli is enabled globally or in
+// a location
+func shouldLoadBrotliModule(c, s interface{}) bool {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return false
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	if cfg.EnableBrotli {
+		return true
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.Brotli.Enable {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compression describes the settings of a compression module, brotli or
+// zstd, in the ConfigMap or in a location
+type compression struct {
+	enable    bool
+	enableSet bool
+	level     int
+	minLength int
+	types     string
+}
+
+// buildCompressionForLocation returns the directives of the compression
+// module overriding the settings of the ConfigMap in the location
+func buildCompressionForLocation(module string, global, location compression) string {
+	enabled := global.enable
+	if location.enableSet {
+		enabled = location.enable
+	}
+
+	if !enabled {
+		// the module is not loaded when the compression is disabled globally
+		// and in all the locations
+		if global.enable {
+			return fmt.Sprintf("%v off;\n", module)
+		}
+		return ""
+	}
+
+	// the locations of the ConfigMap settings inherit them from the http block
+	if global.enable && location.level == 0 && location.types == "" {
+		return ""
+	}
+
+	level := global.level
+	if location.level != 0 {
+		level = location.level
+	}
+	types := global.types
+	if location.types != "" {
+		types = location.types
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("%v on;\n", module))
+	buffer.WriteString(fmt.Sprintf("%v_comp_level %v;\n", module, level))
+	buffer.WriteString(fmt.Sprintf("%v_min_length %v;\n", module, global.minLength))
+	buffer.WriteString(fmt.Sprintf("%v_types %v;\n", module, types))
+
+	return buffer.String()
+}
+
+// buildBrotliForLocation returns the directives overriding the Brotli
+// compression of the ConfigMap in the location
+func buildBrotliForLocation(cfg config.Configuration, location *ingress.Location) string {
+	return buildCompressionForLocation("brotli",
+		compression{enable: cfg.EnableBrotli, level: cfg.BrotliLevel, minLength: cfg.BrotliMinLength, types: cfg.BrotliTypes},
+		compression{enable: location.Brotli.Enable, enableSet: location.Brotli.EnableSet, level: location.Brotli.Level, types: location.Brotli.Types})
+}
+
+// shouldLoadZstdModule returns true when Zstandard is enabled globally or in
+// a location, the compression is ignored on the NGINX images built without
+// the Zstandard module
+func shouldLoadZstdModule(available bool, c, s interface{}) bool {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return false
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	enabled := cfg.EnableZstd
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			enabled = enabled || location.Zstd.Enable
+		}
+	}
+
+	if enabled && !available {
+		klog.Warningf("Ignoring the Zstandard compression: the NGINX image was built without the %v module", nginx.ZstdModule)
+		return false
+	}
+
+	return enabled
+}
+
+// buildZstdForLocation returns the directives overriding the Zstandard
+// compression of the ConfigMap in the location
+func buildZstdForLocation(available bool, cfg config.Configuration, location *ingress.Location) string {
+	if !available {
+		return ""
+	}
+
+	return buildCompressionForLocation("zstd",
+		compression{enable: cfg.EnableZstd, level: cfg.ZstdLevel, minLength: cfg.ZstdMinLength, types: cfg.ZstdTypes},
+		compression{enable: location.Zstd.Enable, enableSet: location.Zstd.EnableSet, level: location.Zstd.Level, types: location.Zstd.Types})
+}
+
 // buildServerName ensures wildcard hostnames are valid
 func buildServerName(hostname string) string {
 	if !strings.HasPrefix(hostname, "*") {
@@ -1696,6 +2158,77 @@ func parseComplexNginxVarIntoLuaTable(ngxVar string) string {
 	return luaTable
 }
 
+// locationPluginsForLua returns the Lua table of the plugins configured by the
+// annotations of the location: false for the disabled plugins, true for the
+// plugins enabled without configuration and the JSON configuration of the
+// others, decoded by plugins.lua
+func locationPluginsForLua(l interface{}) string {
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was given", l)
+		return "{}"
+	}
+
+	if len(location.Plugins.Plugins) == 0 {
+		return "{}"
+	}
+
+	luaTable := "{ "
+	for _, plugin := range location.Plugins.Plugins {
+		value := "false"
+		switch {
+		case plugin.Enabled && plugin.Config != "":
+			// the compact JSON has no line breaks, only the quotes and the
+			// backslashes must be escaped
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(plugin.Config) + `"`
+		case plugin.Enabled:
+			value = "true"
+		}
+		luaTable += fmt.Sprintf(`["%v"] = %v, `, plugin.Name, value)
+	}
+	luaTable += "}"
+
+	return luaTable
+}
+
+// buildIngressPlugins returns the Lua table of the plugins enabled by the
+// annotations of the locations and not in the plugins of the ConfigMap, which
+// are loaded after them, sorted by name
+func buildIngressPlugins(c, s interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return "{}"
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return "{}"
+	}
+
+	loaded := sets.New(cfg.Plugins...)
+	names := sets.Set[string]{}
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			for _, plugin := range location.Plugins.Plugins {
+				if plugin.Enabled && !loaded.Has(plugin.Name) {
+					names.Insert(plugin.Name)
+				}
+			}
+		}
+	}
+
+	sortedNames := sets.List(names)
+	luaTable, err := convertGoSliceIntoLuaTable(sortedNames, false)
+	if err != nil {
+		klog.Errorf("failed to convert %v into Lua table: %q", sortedNames, err)
+		return "{}"
+	}
+
+	return luaTable
+}
+
 func convertGoSliceIntoLuaTable(goSliceInterface interface{}, emptyStringAsNil bool) (string, error) {
 	goSlice := reflect.ValueOf(goSliceInterface)
 	kind := goSlice.Kind()
@@ -1724,28 +2257,636 @@ func convertGoSliceIntoLuaTable(goSliceInterface interface{}, emptyStringAsNil b
 	}
 }
 
-func buildOriginRegex(origin string) string {
-	origin = regexp.QuoteMeta(origin)
-	origin = strings.Replace(origin, "\\*", `[A-Za-z0-9\-]+`, 1)
-	return fmt.Sprintf("(%s)", origin)
+// buildCorsOriginRegex returns the conditions setting $cors to true when the
+// origin of the request is allowed. The origins are matched exactly, and the
+// regexes of the configuration as they are. Unless all the origins are
+// allowed, the responses vary with the origin of the request and are sent
+// with the Vary header, whether the origin is allowed or not. The header is
+// set with more_set_headers, as add_header in the location would drop the
+// add_header directives of the server.
+func buildCorsOriginRegex(corsConfig cors.Config) string {
+	if len(corsConfig.CorsAllowOrigin) == 1 && corsConfig.CorsAllowOrigin[0] == "*" {
+		return "set $http_origin *;\nset $cors 'true';"
+	}
+
+	conditions := []string{`more_set_headers "Vary: $cors_vary";`}
+
+	origins := []string{}
+	for _, origin := range corsConfig.CorsAllowOrigin {
+		origin = strings.TrimSpace(origin)
+		if origin != "" {
+			origins = append(origins, regexp.QuoteMeta(origin))
+		}
+	}
+	if len(origins) > 0 {
+		conditions = append(conditions, fmt.Sprintf(`if ($http_origin ~* "^(%s)$") { set $cors 'true'; }`, strings.Join(origins, "|")))
+	}
+
+	for _, originRegex := range corsConfig.CorsAllowOriginRegex {
+		conditions = append(conditions, fmt.Sprintf(`if ($http_origin ~ "%s") { set $cors 'true'; }`, originRegex))
+	}
+
+	return strings.Join(conditions, "\n")
 }
 
-func buildCorsOriginRegex(corsOrigins []string) string {
-	if len(corsOrigins) == 1 && corsOrigins[0] == "*" {
-		return "set $http_origin *;\nset $cors 'true';"
+const accessLogFormat = "upstreaminfo"
+
+// buildLogFormatJSON returns the JSON access log format of the fields, the
+// fields of a location replace the fields of the same name and the other ones
+// are added at the end
+func buildLogFormatJSON(fields []config.LogField, locationFields map[string]string) string {
+	entries := make([]string, 0, len(fields)+len(locationFields))
+	for _, field := range fields {
+		value := field.Value
+		if v, ok := locationFields[field.Name]; ok {
+			value = v
+		}
+		entries = append(entries, fmt.Sprintf(`"%v":"%v"`, field.Name, value))
 	}
 
-	originsRegex := "if ($http_origin ~* ("
-	for i, origin := range corsOrigins {
-		originTrimmed := strings.TrimSpace(origin)
-		if originTrimmed != "" {
-			builtOrigin := buildOriginRegex(originTrimmed)
-			originsRegex += builtOrigin
-			if i != len(corsOrigins)-1 {
-				originsRegex += "|"
-			}
+	names := make([]string, 0, len(locationFields))
+	for name := range locationFields {
+		if !slices.ContainsFunc(fields, func(field config.LogField) bool { return field.Name == name }) {
+			names = append(names, name)
 		}
 	}
-	originsRegex += ")$ ) { set $cors 'true'; }"
-	return originsRegex
+	sort.Strings(names)
+	for _, name := range names {
+		entries = append(entries, fmt.Sprintf(`"%v":"%v"`, name, locationFields[name]))
+	}
+
+	return "{" + strings.Join(entries, ",") + "}"
+}
+
+// accessLogFormatName returns the name of the access log format of the
+// fields of a location
+func accessLogFormatName(locationFields map[string]string) string {
+	if len(locationFields) == 0 {
+		return accessLogFormat
+	}
+
+	names := make([]string, 0, len(locationFields))
+	for name := range locationFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hasher := sha1.New() // #nosec
+	for _, name := range names {
+		fmt.Fprintf(hasher, "%v=%v\n", name, locationFields[name])
+	}
+	return accessLogFormat + "_" + hex.EncodeToString(hasher.Sum(nil))[:12]
+}
+
+// buildAccessLogFormats returns the JSON access log formats of the locations
+// adding fields to the access log schema
+func buildAccessLogFormats(c, s interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return ""
+	}
+
+	if len(cfg.LogFormatJSONFields) == 0 {
+		return ""
+	}
+
+	formats := map[string]string{}
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if len(location.Logs.Fields) == 0 {
+				continue
+			}
+			formats[accessLogFormatName(location.Logs.Fields)] = buildLogFormatJSON(cfg.LogFormatJSONFields, location.Logs.Fields)
+		}
+	}
+
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBufferString("")
+	for _, name := range names {
+		fmt.Fprintf(buf, "log_format %v escape=json '%v';\n", name, formats[name])
+	}
+	return buf.String()
+}
+
+// buildLocationAccessLog returns the access_log directives of the locations
+// adding fields to the access log schema
+func buildLocationAccessLog(a, l interface{}) string {
+	all, ok := a.(config.TemplateConfig)
+	if !ok {
+		klog.Errorf("expected a 'config.TemplateConfig' type but %T was returned", a)
+		return ""
+	}
+	cfg := all.Cfg
+
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	if len(cfg.LogFormatJSONFields) == 0 || len(location.Logs.Fields) == 0 || !location.Logs.Access ||
+		cfg.DisableAccessLog || cfg.DisableHTTPAccessLog {
+		return ""
+	}
+
+	format := accessLogFormatName(location.Logs.Fields)
+	var accessLog string
+	if cfg.EnableSyslog {
+		accessLog = fmt.Sprintf("access_log syslog:server=%v:%v %v if=$loggable_sampled;", cfg.SyslogHost, cfg.SyslogPort, format)
+	} else {
+		destination := cfg.HTTPAccessLogPath
+		if destination == "" {
+			destination = cfg.AccessLogPath
+		}
+		accessLog = strings.Join(strings.Fields(fmt.Sprintf("access_log %v %v %v if=$loggable_sampled;", destination, format, cfg.AccessLogParams)), " ")
+	}
+
+	if all.LogForwardingAddress != "" {
+		accessLog += fmt.Sprintf("\naccess_log syslog:server=%v,tag=%v %v if=$loggable_sampled;", all.LogForwardingAddress, logs.AccessLogTag, format)
+	}
+	return accessLog
+}
+
+// buildServerErrorLog returns the error_log directives of the server with the
+// severity of its error-log-level annotation. They replace all the error_log
+// directives of the http block, which are repeated with this severity.
+func buildServerErrorLog(a, s interface{}) string {
+	all, ok := a.(config.TemplateConfig)
+	if !ok {
+		klog.Errorf("expected a 'config.TemplateConfig' type but %T was returned", a)
+		return ""
+	}
+	cfg := all.Cfg
+
+	server, ok := s.(*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Server' type but %T was returned", s)
+		return ""
+	}
+
+	if server.ErrorLogLevel == "" || server.ErrorLogLevel == cfg.ErrorLogLevel {
+		return ""
+	}
+
+	var errorLog string
+	if cfg.EnableSyslog {
+		errorLog = fmt.Sprintf("error_log syslog:server=%v:%v %v;", cfg.SyslogHost, cfg.SyslogPort, server.ErrorLogLevel)
+	} else {
+		errorLog = fmt.Sprintf("error_log %v %v;", cfg.ErrorLogPath, server.ErrorLogLevel)
+	}
+
+	if all.LogForwardingAddress != "" {
+		errorLog += fmt.Sprintf("\nerror_log syslog:server=%v,tag=%v %v;", all.LogForwardingAddress, logs.ErrorLogTag, server.ErrorLogLevel)
+	}
+	return errorLog
+}
+
+// buildRequestID returns the value of the request IDs generated for the
+// requests without a trusted X-Request-ID header
+func buildRequestID(c interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	if cfg.RequestIDFormat == config.RequestIDFormatUUID4 || cfg.RequestIDFormat == config.RequestIDFormatUUID7 {
+		return fmt.Sprintf("%q", cfg.RequestIDPrefix+"$generated_request_id")
+	}
+	return fmt.Sprintf("%q", cfg.RequestIDPrefix+"$request_id")
+}
+
+// buildGeneratedRequestID returns the Lua block generating the UUID request
+// IDs of the requests of a server
+func buildGeneratedRequestID(c interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	if !cfg.GenerateRequestID ||
+		(cfg.RequestIDFormat != config.RequestIDFormatUUID4 && cfg.RequestIDFormat != config.RequestIDFormatUUID7) {
+		return ""
+	}
+
+	return fmt.Sprintf(`set_by_lua_block $generated_request_id {
+            return request_id.generate(%q)
+        }`, cfg.RequestIDFormat)
+}
+
+// buildAccessLogSampling returns the Lua call sampling the access log of the
+// location, if its sample rate is lower than 1
+func buildAccessLogSampling(c, l interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	rate := cfg.AccessLogSampleRate
+	if location.Logs.SampleRateSet {
+		rate = location.Logs.SampleRate
+	}
+
+	if !location.Logs.Access || rate >= 1 {
+		return ""
+	}
+
+	return fmt.Sprintf("access_log_sampling.header({ rate = %v, min_status = %d, slow_threshold = %v })",
+		max(rate, 0), cfg.AccessLogSampleMinStatus, cfg.AccessLogSampleSlowThreshold)
+}
+
+// redirectMaps returns the RedirectMaps of the servers with redirects, once
+// each
+func redirectMaps(servers []*ingress.Server) []*redirectmap.Config {
+	names := sets.Set[string]{}
+	configs := []*redirectmap.Config{}
+	for _, server := range servers {
+		if len(server.RedirectMap.Redirects) == 0 || names.Has(server.RedirectMap.Name) {
+			continue
+		}
+
+		names.Insert(server.RedirectMap.Name)
+		configs = append(configs, &server.RedirectMap)
+	}
+
+	return configs
+}
+
+// redirectMapVariable returns the variable of the nginx map of a RedirectMap
+func redirectMapVariable(name string) string {
+	hasher := sha1.New() // #nosec
+	hasher.Write([]byte(name))
+	return "$redirect_map_" + hex.EncodeToString(hasher.Sum(nil))[:16]
+}
+
+// buildMapHashBucketSize returns the map_hash_bucket_size of the
+// configuration, increased to fit the longest source of the RedirectMaps
+func buildMapHashBucketSize(c, s interface{}) int {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return 0
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
+		return cfg.MapHashBucketSize
+	}
+
+	// a bucket holds the pointers of an element and of the end of the
+	// bucket, and the key of the element aligned on the pointers
+	bucketSize := cfg.MapHashBucketSize
+	for _, rm := range redirectMaps(servers) {
+		for _, redirect := range rm.Redirects {
+			needed := 16 + (len(redirect.Source)+2+7)/8*8
+			for bucketSize < needed {
+				bucketSize *= 2
+			}
+		}
+	}
+
+	return bucketSize
+}
+
+// buildMapHashMaxSize returns the map_hash_max_size fitting the sources of
+// the largest RedirectMap, 2048 by default
+func buildMapHashMaxSize(s interface{}) int {
+	maxSize := 2048
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
+		return maxSize
+	}
+
+	for _, rm := range redirectMaps(servers) {
+		for maxSize < 4*len(rm.Redirects) {
+			maxSize *= 2
+		}
+	}
+
+	return maxSize
+}
+
+// buildRedirectMaps returns the nginx maps of the paths of the RedirectMaps
+// of the servers to their targets
+func buildRedirectMaps(s interface{}) []string {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
+		return []string{}
+	}
+
+	maps := []string{}
+	for _, rm := range redirectMaps(servers) {
+		buffer := bytes.NewBufferString(fmt.Sprintf(`# RedirectMap %v
+    map $uri %v {
+        default "";
+`, rm.Name, redirectMapVariable(rm.Name)))
+		// the sources and targets cannot contain quotes, escapes or variables
+		for _, redirect := range rm.Redirects {
+			buffer.WriteString(fmt.Sprintf(`        "%v" "%v";
+`, redirect.Source, redirect.Target))
+		}
+		buffer.WriteString("    }")
+
+		maps = append(maps, buffer.String())
+	}
+
+	return maps
+}
+
+// buildRedirectMapForServer returns the redirect of the paths of the
+// RedirectMap of the server
+func buildRedirectMapForServer(s interface{}) string {
+	server, ok := s.(*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Server' type but %T was returned", s)
+		return ""
+	}
+
+	if len(server.RedirectMap.Redirects) == 0 {
+		return ""
+	}
+
+	variable := redirectMapVariable(server.RedirectMap.Name)
+	target := variable
+	if server.RedirectMap.PreserveQueryString {
+		target += "$is_args$args"
+	}
+
+	return fmt.Sprintf(`if (%v != "") {
+            return %v %v;
+        }`, variable, server.RedirectMap.StatusCode, target)
+}
+
+// buildConditionalRedirects returns the if blocks redirecting the requests
+// of the location matching the conditions, in order
+func buildConditionalRedirects(l interface{}) string {
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	var buffer bytes.Buffer
+	for _, rule := range location.ConditionalRedirects.Rules {
+		// NGINX unescapes the backslashes of the quoted strings
+		buffer.WriteString(fmt.Sprintf(`if (%v %v "%v") {
+                return %v %v;
+            }
+`, rule.Variable, rule.Operator, strings.ReplaceAll(rule.Value, `\`, `\\`), rule.StatusCode, rule.Target))
+	}
+
+	return buffer.String()
+}
+
+// buildTrailingSlashRedirect returns the if block redirecting the requests
+// of the location between the /path and /path/ forms of their path. The
+// path of the request URI keeps the encoding of the client, the scheme and
+// the host are the ones sent to the backends.
+func buildTrailingSlashRedirect(l interface{}) string {
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	switch location.TrailingSlash.Mode {
+	case trailingslash.ModeAdd:
+		// the last segment of the path is not empty and is not a file name
+		return fmt.Sprintf(`if ($request_uri ~ "^([^?]*/[^/?.]+)([?].*)?$") {
+                return %v $pass_access_scheme://$best_http_host$1/$2;
+            }`, location.TrailingSlash.Code)
+	case trailingslash.ModeRemove:
+		// the root path keeps its slash
+		return fmt.Sprintf(`if ($request_uri ~ "^([^?]*[^/?])/+([?].*)?$") {
+                return %v $pass_access_scheme://$best_http_host$1$2;
+            }`, location.TrailingSlash.Code)
+	default:
+		return ""
+	}
+}
+
+// customErrorPageLocation returns the name of the location serving the error
+// page of a status code of the directory of a ConfigMap
+func customErrorPageLocation(directory string, code int) string {
+	return fmt.Sprintf("@custom_error_page_%v_%v", path.Base(directory), code)
+}
+
+// buildCustomErrorPages returns the error_page directives of the location
+// serving the error pages of its custom-error-pages annotation. They precede
+// the ones of the custom-http-errors annotation.
+func buildCustomErrorPages(l interface{}) string {
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	if len(location.CustomErrorPages.Pages) == 0 {
+		return ""
+	}
+
+	directives := []string{}
+	// the custom-http-errors annotation already intercepts the errors
+	if len(location.CustomHTTPErrors) == 0 && !location.DisableProxyInterceptErrors {
+		directives = append(directives, "proxy_intercept_errors on;")
+	}
+	for _, page := range location.CustomErrorPages.Pages {
+		directives = append(directives, fmt.Sprintf("error_page %v %v;", page.Code, customErrorPageLocation(location.CustomErrorPages.Directory, page.Code)))
+	}
+
+	return strings.Join(directives, "\n            ")
+}
+
+// buildCustomErrorPagesLocations returns the locations of the server serving
+// the error pages of the ConfigMaps of its locations, in the format of the
+// Accept header of the requests when a page has both formats
+func buildCustomErrorPagesLocations(s interface{}, modsecurityEnabled bool) string {
+	server, ok := s.(*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Server' type but %T was returned", s)
+		return ""
+	}
+
+	pages := map[string][]customerrorpages.Page{}
+	for _, location := range server.Locations {
+		if len(location.CustomErrorPages.Pages) != 0 {
+			pages[location.CustomErrorPages.Directory] = location.CustomErrorPages.Pages
+		}
+	}
+
+	directories := make([]string, 0, len(pages))
+	for directory := range pages {
+		directories = append(directories, directory)
+	}
+	sort.Strings(directories)
+
+	var buffer bytes.Buffer
+	for _, directory := range directories {
+		for _, page := range pages[directory] {
+			extension := "$custom_error_page_extension"
+			switch {
+			case !page.JSON:
+				extension = ".html"
+			case !page.HTML:
+				extension = ".json"
+			}
+
+			buffer.WriteString(fmt.Sprintf("location %v {\n            internal;\n", customErrorPageLocation(directory, page.Code)))
+			// the error pages are not inspected, or they might be blocked
+			if modsecurityEnabled {
+				buffer.WriteString("            modsecurity off;\n")
+			}
+			buffer.WriteString(fmt.Sprintf(`            root %v;
+            try_files /%v%v =%v;
+        }
+        `, directory, page.Code, extension, page.Code))
+		}
+	}
+
+	return buffer.String()
+}
+
+// buildMaintenanceAllowlists returns the geo blocks of the allowlists of the
+// locations in maintenance, one per allowlist
+func buildMaintenanceAllowlists(s interface{}) []string {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
+		return []string{}
+	}
+
+	allowlists := map[string][]string{}
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.Maintenance.Enabled && location.Maintenance.ID != "" {
+				allowlists[location.Maintenance.ID] = location.Maintenance.Allowlist
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(allowlists))
+	for id := range allowlists {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	geos := []string{}
+	for _, id := range ids {
+		buffer := bytes.NewBufferString(fmt.Sprintf(`geo $remote_addr $maintenance_allowed_%v {
+        default 0;
+`, id))
+		for _, cidr := range allowlists[id] {
+			buffer.WriteString(fmt.Sprintf(`        %v 1;
+`, cidr))
+		}
+		buffer.WriteString("    }")
+
+		geos = append(geos, buffer.String())
+	}
+
+	return geos
+}
+
+// buildMaintenanceMode returns the 503 response of the requests of the
+// location in maintenance which are not in its allowlist
+func buildMaintenanceMode(l interface{}) string {
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	if !location.Maintenance.Enabled {
+		return ""
+	}
+
+	if location.Maintenance.ID == "" {
+		return "return 503;"
+	}
+
+	return fmt.Sprintf(`if ($maintenance_allowed_%v = 0) {
+                return 503;
+            }`, location.Maintenance.ID)
+}
+
+// buildGeoIP2Databases returns the geoip2 blocks of the databases of the
+// providers other than MaxMind, setting the variables of the MaxMind databases
+// from their fields
+func buildGeoIP2Databases(f, r interface{}) string {
+	files, ok := f.(*[]string)
+	if !ok {
+		klog.Errorf("expected a '*[]string' type but %T was returned", f)
+		return ""
+	}
+	if files == nil {
+		return ""
+	}
+	autoReload, ok := r.(int)
+	if !ok {
+		klog.Errorf("expected an 'int' type but %T was returned", r)
+		return ""
+	}
+
+	buffer := new(bytes.Buffer)
+	ipinfoASN := false
+	for _, layout := range nginx.GeoIP2DatabaseLayouts(*files) {
+		buffer.WriteString(fmt.Sprintf(`
+    # %v database
+    geoip2 /etc/ingress-controller/geoip/%v {
+`, layout.Provider, layout.File))
+		if autoReload > 0 {
+			buffer.WriteString(fmt.Sprintf(`        auto_reload %vm;
+`, autoReload))
+		}
+		for _, v := range layout.Variables {
+			if v.Name == nginx.IPinfoASNVariable {
+				ipinfoASN = true
+			}
+			if v.Default != "" {
+				buffer.WriteString(fmt.Sprintf(`        $%v source=$remote_addr default=%v %v;
+`, v.Name, v.Default, v.Path))
+				continue
+			}
+			buffer.WriteString(fmt.Sprintf(`        $%v source=$remote_addr %v;
+`, v.Name, v.Path))
+		}
+		buffer.WriteString(`    }
+`)
+	}
+
+	if ipinfoASN {
+		// the autonomous systems of IPinfo are prefixed with AS
+		buffer.WriteString(fmt.Sprintf(`
+    map $%v $geoip2_asn {
+        ~^AS(\d+)$ $1;
+        default 0;
+    }
+`, nginx.IPinfoASNVariable))
+	}
+
+	return buffer.String()
 }
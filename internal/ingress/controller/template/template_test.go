@@ -33,12 +33,26 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/brotli"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/conditionalredirect"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customerrorpages"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/maintenance"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/njs"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/opentelemetry"
+	pluginsconfig "k8s.io/ingress-nginx/internal/ingress/annotations/plugins"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/trailingslash"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/zstd"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/nginx"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
@@ -744,6 +758,180 @@ func TestTemplateWithData(t *testing.T) {
 	if !strings.Contains(string(rt), "listen 2.2.2.2") {
 		t.Errorf("invalid NGINX template, expected IPV4 listen address not present")
 	}
+
+	dat.Cfg.LogFormatJSONFields = []config.LogField{{Name: "status", Value: "$status"}}
+	rt, err = ngxTpl.Write(&dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), `log_format upstreaminfo escape=json '{"status":"$status"}';`) {
+		t.Errorf("invalid NGINX template, expected JSON access log format not present")
+	}
+}
+
+func TestTemplateWithCrowdSec(t *testing.T) {
+	data, err := os.ReadFile("../../../../test/data/config.json")
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+	dat.ListenPorts = &config.ListenPorts{}
+	dat.Cfg.DefaultSSLCertificate = &ingress.SSLCert{}
+
+	ngxTpl, err := NewTemplate(nginx.TemplatePath)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	rt, err := ngxTpl.Write(&dat)
+	if err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if strings.Contains(string(rt), "lua_ingress.access()") {
+		t.Errorf("unexpected CrowdSec bouncer in the locations")
+	}
+
+	dat.Cfg.EnableCrowdSec = true
+	rt, err = ngxTpl.Write(&dat)
+	if err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if !strings.Contains(string(rt), "lua_ingress.access()") || !strings.Contains(string(rt), "ngx.exit(ngx.DECLINED)") {
+		t.Errorf("expected the CrowdSec bouncer in the access phase of the locations")
+	}
+}
+
+func TestTemplateStreamProxyProtocol(t *testing.T) {
+	data, err := os.ReadFile("../../../../test/data/config.json")
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate(nginx.TemplatePath)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		global   bool
+		encode   bool
+		expected bool
+	}{
+		{"disabled", false, false, false},
+		{"enabled for the service", false, true, true},
+		{"enabled for all the services", true, false, true},
+		{"enabled for the service and all the services", true, true, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var dat config.TemplateConfig
+			if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+				t.Fatalf("unexpected error unmarshalling json: %v", err)
+			}
+			dat.ListenPorts = &config.ListenPorts{}
+			dat.Cfg.DefaultSSLCertificate = &ingress.SSLCert{}
+			dat.Cfg.ProxyStreamProxyProtocol = tc.global
+			dat.TCPBackends = []ingress.L4Service{
+				{
+					Port: 9000,
+					Backend: ingress.L4Backend{
+						Name:          "db",
+						Namespace:     "default",
+						Port:          intstr.FromInt(5432),
+						ProxyProtocol: ingress.ProxyProtocol{Encode: tc.encode},
+					},
+				},
+			}
+
+			rt, err := ngxTpl.Write(&dat)
+			if err != nil {
+				t.Fatalf("unexpected error writing template: %v", err)
+			}
+			if got := strings.Contains(string(rt), "proxy_protocol          on;"); got != tc.expected {
+				t.Errorf("expected the PROXY protocol to be sent to the TCP service: %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTemplateServerBlockCache(t *testing.T) {
+	data, err := os.ReadFile("../../../../test/data/config.json")
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+	dat.ListenPorts = &config.ListenPorts{}
+	dat.Cfg.DefaultSSLCertificate = &ingress.SSLCert{}
+
+	ngxTpl, err := NewTemplate(nginx.TemplatePath)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	first, err := ngxTpl.Write(&dat)
+	if err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if ngxTpl.serverBlocks.rendered != len(dat.Servers) || ngxTpl.serverBlocks.reused != 0 {
+		t.Errorf("expected %v server blocks rendered but %v were rendered and %v reused",
+			len(dat.Servers), ngxTpl.serverBlocks.rendered, ngxTpl.serverBlocks.reused)
+	}
+
+	// changing the endpoints does not render the server blocks again
+	dat.Backends = append(dat.Backends, &ingress.Backend{Name: "new-backend"})
+	second, err := ngxTpl.Write(&dat)
+	if err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if ngxTpl.serverBlocks.rendered != 0 || ngxTpl.serverBlocks.reused != len(dat.Servers) {
+		t.Errorf("expected %v server blocks reused but %v were rendered and %v reused",
+			len(dat.Servers), ngxTpl.serverBlocks.rendered, ngxTpl.serverBlocks.reused)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("expected the same configuration when the server blocks are reused")
+	}
+
+	// only the changed server is rendered again
+	dat.Servers[1].Aliases = append(dat.Servers[1].Aliases, "alias.example.com")
+	third, err := ngxTpl.Write(&dat)
+	if err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if ngxTpl.serverBlocks.rendered != 1 || ngxTpl.serverBlocks.reused != len(dat.Servers)-1 {
+		t.Errorf("expected 1 server block rendered but %v were rendered and %v reused",
+			ngxTpl.serverBlocks.rendered, ngxTpl.serverBlocks.reused)
+	}
+	if !strings.Contains(string(third), "alias.example.com") {
+		t.Errorf("expected the alias of the changed server in the configuration")
+	}
+
+	// a change of the global configuration renders all the server blocks again
+	dat.Cfg.ServerSnippet = "# server snippet"
+	if _, err := ngxTpl.Write(&dat); err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if ngxTpl.serverBlocks.rendered != len(dat.Servers) {
+		t.Errorf("expected %v server blocks rendered but %v were rendered",
+			len(dat.Servers), ngxTpl.serverBlocks.rendered)
+	}
+
+	// the server blocks of the removed servers are dropped
+	dat.Servers = dat.Servers[:2]
+	if _, err := ngxTpl.Write(&dat); err != nil {
+		t.Fatalf("unexpected error writing template: %v", err)
+	}
+	if len(ngxTpl.serverBlocks.previous) != 2 {
+		t.Errorf("expected 2 cached server blocks but there are %v", len(ngxTpl.serverBlocks.previous))
+	}
 }
 
 func BenchmarkTemplateWithData(b *testing.B) {
@@ -1145,7 +1333,21 @@ func TestOpentelemetryPropagateContext(t *testing.T) {
 	}
 
 	for loc, expectedDirective := range tests {
-		actualDirective := opentelemetryPropagateContext(loc)
+		actualDirective := opentelemetryPropagateContext(loc, opentelemetry.PropagatorW3C)
+		if actualDirective != expectedDirective {
+			t.Errorf("Expected %v but returned %v", expectedDirective, actualDirective)
+		}
+	}
+
+	propagators := map[string]string{
+		opentelemetry.PropagatorW3C:     "opentelemetry_propagate;",
+		opentelemetry.PropagatorB3:      "opentelemetry_propagate b3;",
+		opentelemetry.PropagatorB3Multi: "opentelemetry_propagate b3multi;",
+		opentelemetry.PropagatorNone:    "",
+	}
+
+	for propagator, expectedDirective := range propagators {
+		actualDirective := opentelemetryPropagateContext(&ingress.Location{}, propagator)
 		if actualDirective != expectedDirective {
 			t.Errorf("Expected %v but returned %v", expectedDirective, actualDirective)
 		}
@@ -1676,6 +1878,23 @@ func TestEnforceRegexModifier(t *testing.T) {
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
+
+	locs = []*ingress.Location{
+		{
+			Path:     "/ok",
+			PathType: &pathPrefix,
+		},
+		{
+			Rewrite: rewrite.Config{
+				CaseInsensitivePaths: true,
+			},
+			Path:     "/products",
+			PathType: &pathPrefix,
+		},
+	}
+	if !enforceRegexModifier(locs) {
+		t.Errorf("Expected the case insensitive paths to enforce the regex modifier")
+	}
 }
 
 //nolint:dupl // Ignore dupl errors for similar test case
@@ -1772,7 +1991,12 @@ opentelemetry_trust_incoming_spans off;`
 			il.Opentelemetry.TrustEnabled = *testCase.isTrustInLoc
 		}
 
-		actual := buildOpentelemetryForLocation(testCase.globalOT, testCase.globalTrust, il)
+		cfg := config.Configuration{
+			EnableOpentelemetry:            testCase.globalOT,
+			OpentelemetryTrustIncomingSpan: testCase.globalTrust,
+			OpentelemetryForwardBaggage:    true,
+		}
+		actual := buildOpentelemetryForLocation(cfg, il)
 
 		if testCase.expected != actual {
 			t.Errorf("%v: expected '%v' but returned '%v'", testCase.description, testCase.expected, actual)
@@ -1780,6 +2004,96 @@ opentelemetry_trust_incoming_spans off;`
 	}
 }
 
+func TestOpentelemetryForLocationSpanAttributes(t *testing.T) {
+	cfg := config.Configuration{
+		EnableOpentelemetry:             true,
+		OpentelemetryTrustIncomingSpan:  true,
+		OpentelemetrySpanAttributes:     map[string]string{"deployment.environment": "production", "team": "platform"},
+		OpentelemetryRouteOperationName: true,
+		OpentelemetryForwardBaggage:     true,
+	}
+
+	il := &ingress.Location{
+		Path: "/api/v[0-9]+$",
+		Opentelemetry: opentelemetry.Config{
+			SpanAttributes: map[string]string{"team": "payments"},
+		},
+	}
+
+	expected := `opentelemetry on;
+opentelemetry_propagate;
+opentelemetry_trust_incoming_spans on;
+opentelemetry_operation_name "$request_method /api/v[0-9]+${literal_dollar}";
+opentelemetry_attribute "deployment.environment" "production";
+opentelemetry_attribute "http.route" "/api/v[0-9]+${literal_dollar}";
+opentelemetry_attribute "team" "payments";`
+	if actual := buildOpentelemetryForLocation(cfg, il); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	il.Opentelemetry.OperationName = "payments"
+	expected = `opentelemetry on;
+opentelemetry_propagate;
+opentelemetry_operation_name payments;
+opentelemetry_trust_incoming_spans on;
+opentelemetry_attribute "deployment.environment" "production";
+opentelemetry_attribute "http.route" "/api/v[0-9]+${literal_dollar}";
+opentelemetry_attribute "team" "payments";`
+	if actual := buildOpentelemetryForLocation(cfg, il); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestOpentelemetryForLocationPropagation(t *testing.T) {
+	cfg := config.Configuration{
+		EnableOpentelemetry:            true,
+		OpentelemetryTrustIncomingSpan: true,
+		OpentelemetryPropagator:        opentelemetry.PropagatorB3,
+		OpentelemetryForwardBaggage:    false,
+	}
+
+	testCases := []struct {
+		description string
+		location    *ingress.Location
+		expected    string
+	}{
+		{
+			"global propagator, baggage stripped",
+			&ingress.Location{},
+			`opentelemetry on;
+opentelemetry_propagate b3;
+opentelemetry_trust_incoming_spans on;
+proxy_set_header baggage "";`,
+		},
+		{
+			"propagator and baggage set in location",
+			&ingress.Location{
+				Opentelemetry: opentelemetry.Config{Propagator: opentelemetry.PropagatorB3Multi, BaggageSet: true, ForwardBaggage: true},
+			},
+			`opentelemetry on;
+opentelemetry_propagate b3multi;
+opentelemetry_trust_incoming_spans on;`,
+		},
+		{
+			"no propagation, gRPC location",
+			&ingress.Location{
+				BackendProtocol: grpcProtocol,
+				Opentelemetry:   opentelemetry.Config{Propagator: opentelemetry.PropagatorNone},
+			},
+			`opentelemetry on;
+opentelemetry_trust_incoming_spans on;
+grpc_set_header baggage "";`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := buildOpentelemetryForLocation(cfg, testCase.location)
+		if testCase.expected != actual {
+			t.Errorf("%v: expected '%v' but returned '%v'", testCase.description, testCase.expected, actual)
+		}
+	}
+}
+
 //nolint:dupl // Ignore dupl errors for similar test case
 func TestShouldLoadOpentelemetryModule(t *testing.T) {
 	// ### Invalid argument type tests ###
@@ -1926,6 +2240,83 @@ func TestBuildServerName(t *testing.T) {
 	}
 }
 
+func TestBuildHTTPSListener(t *testing.T) {
+	testCases := []struct {
+		title         string
+		passthrough   bool
+		proxyProtocol bool
+		expected      string
+	}{
+		{"default", false, false, "listen 443  ssl;"},
+		{"proxy protocol", false, true, "listen 443 proxy_protocol ssl;"},
+		{"ssl passthrough", true, false, "listen 442  ssl;"},
+		{"ssl passthrough with proxy protocol", true, true, "listen 442  ssl;"},
+	}
+
+	for _, testCase := range testCases {
+		tc := config.TemplateConfig{
+			IsSSLPassthroughEnabled: testCase.passthrough,
+			ListenPorts:             &config.ListenPorts{HTTPS: 443, SSLProxy: 442},
+			Cfg:                     config.Configuration{UseProxyProtocol: testCase.proxyProtocol},
+		}
+
+		result := buildHTTPSListener(tc, "foo.bar")
+		if result != testCase.expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", testCase.title, testCase.expected, result)
+		}
+	}
+}
+
+func TestBuildMirrorLocations(t *testing.T) {
+	testCases := []struct {
+		title     string
+		locations []*ingress.Location
+		expected  string
+	}{
+		{"no mirror", []*ingress.Location{{}}, ""},
+		{
+			"mirror all requests",
+			[]*ingress.Location{{Mirror: mirror.Config{Source: "/_mirror-uid", Target: "https://test.env.com$request_uri", Host: "test.env.com", Percentage: 100}}},
+			`location = /_mirror-uid {
+internal;
+proxy_set_header Host "test.env.com";
+proxy_pass "https://test.env.com$request_uri";
+}
+
+`,
+		},
+		{
+			"mirror sampled requests with timeout",
+			[]*ingress.Location{
+				{Mirror: mirror.Config{Source: "/_mirror-uid", Target: "https://test.env.com$request_uri", Host: "test.env.com", Percentage: 10, Timeout: 2}},
+				{Mirror: mirror.Config{Source: "/_mirror-uid", Target: "https://test.env.com$request_uri", Host: "test.env.com", Percentage: 10, Timeout: 2}},
+			},
+			`location = /_mirror-uid {
+internal;
+rewrite_by_lua_block {
+if math.random(100) > 10 then
+return ngx.exit(ngx.HTTP_NO_CONTENT)
+end
+}
+proxy_connect_timeout 2s;
+proxy_send_timeout 2s;
+proxy_read_timeout 2s;
+proxy_set_header Host "test.env.com";
+proxy_pass "https://test.env.com$request_uri";
+}
+
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := buildMirrorLocations(testCase.locations)
+		if result != testCase.expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", testCase.title, testCase.expected, result)
+		}
+	}
+}
+
 func TestParseComplexNginxVarIntoLuaTable(t *testing.T) {
 	testCases := []struct {
 		ngxVar           string
@@ -2039,3 +2430,631 @@ func TestCleanConf(t *testing.T) {
 		t.Errorf("cleanConf result don't match with expected: %s", diff)
 	}
 }
+
+func TestBuildLogFormatJSON(t *testing.T) {
+	fields := []config.LogField{
+		{Name: "time", Value: "$time_iso8601"},
+		{Name: "status", Value: "$status"},
+		{Name: "team", Value: "platform"},
+	}
+
+	expected := `{"time":"$time_iso8601","status":"$status","team":"platform"}`
+	if actual := buildLogFormatJSON(fields, nil); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	expected = `{"time":"$time_iso8601","status":"$status","team":"payments","tier":"backend"}`
+	if actual := buildLogFormatJSON(fields, map[string]string{"tier": "backend", "team": "payments"}); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestBuildAccessLogFormats(t *testing.T) {
+	cfg := config.Configuration{
+		LogFormatJSONFields: []config.LogField{{Name: "status", Value: "$status"}},
+		AccessLogPath:       "/var/log/nginx/access.log",
+		AccessLogParams:     "buffer=16k",
+	}
+	payments := &ingress.Location{Logs: log.Config{Access: true, Fields: map[string]string{"team": "payments"}}}
+	servers := []*ingress.Server{
+		{Locations: []*ingress.Location{payments, {Logs: log.Config{Access: true}}}},
+		{Locations: []*ingress.Location{{Logs: log.Config{Access: true, Fields: map[string]string{"team": "payments"}}}}},
+	}
+
+	name := accessLogFormatName(payments.Logs.Fields)
+	expected := fmt.Sprintf("log_format %v escape=json '{\"status\":\"$status\",\"team\":\"payments\"}';\n", name)
+	if actual := buildAccessLogFormats(cfg, servers); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	all := config.TemplateConfig{Cfg: cfg}
+	expected = fmt.Sprintf("access_log /var/log/nginx/access.log %v buffer=16k if=$loggable_sampled;", name)
+	if actual := buildLocationAccessLog(all, payments); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	all.LogForwardingAddress = "127.0.0.1:11514"
+	expected += fmt.Sprintf("\naccess_log syslog:server=127.0.0.1:11514,tag=nginx_access %v if=$loggable_sampled;", name)
+	if actual := buildLocationAccessLog(all, payments); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if actual := buildLocationAccessLog(all, servers[0].Locations[1]); actual != "" {
+		t.Errorf("expected no access log for the location without fields but returned '%v'", actual)
+	}
+
+	cfg.LogFormatJSONFields = nil
+	if actual := buildAccessLogFormats(cfg, servers); actual != "" {
+		t.Errorf("expected no access log format without schema but returned '%v'", actual)
+	}
+}
+
+func TestBuildAccessLogSampling(t *testing.T) {
+	cfg := config.Configuration{
+		AccessLogSampleRate:          1,
+		AccessLogSampleMinStatus:     500,
+		AccessLogSampleSlowThreshold: 2.5,
+	}
+
+	testCases := []struct {
+		description string
+		rate        float32
+		location    *ingress.Location
+		expected    string
+	}{
+		{"not sampled", 1, &ingress.Location{Logs: log.Config{Access: true}}, ""},
+		{"sampled globally", 0.01, &ingress.Location{Logs: log.Config{Access: true}},
+			"access_log_sampling.header({ rate = 0.01, min_status = 500, slow_threshold = 2.5 })"},
+		{"sampled in location", 1, &ingress.Location{Logs: log.Config{Access: true, SampleRate: 0.1, SampleRateSet: true}},
+			"access_log_sampling.header({ rate = 0.1, min_status = 500, slow_threshold = 2.5 })"},
+		{"not sampled in location", 0.01, &ingress.Location{Logs: log.Config{Access: true, SampleRate: 1, SampleRateSet: true}}, ""},
+		{"access log disabled", 0.01, &ingress.Location{Logs: log.Config{Access: false}}, ""},
+	}
+
+	for _, testCase := range testCases {
+		cfg.AccessLogSampleRate = testCase.rate
+		if actual := buildAccessLogSampling(cfg, testCase.location); actual != testCase.expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", testCase.description, testCase.expected, actual)
+		}
+	}
+}
+
+func TestBuildServerErrorLog(t *testing.T) {
+	all := config.TemplateConfig{Cfg: config.Configuration{ErrorLogPath: "/var/log/nginx/error.log", ErrorLogLevel: "notice"}}
+	server := &ingress.Server{Hostname: "noisy.example.com", ErrorLogLevel: "info"}
+
+	expected := "error_log /var/log/nginx/error.log info;"
+	if actual := buildServerErrorLog(all, server); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	all.LogForwardingAddress = "127.0.0.1:11514"
+	expected += "\nerror_log syslog:server=127.0.0.1:11514,tag=nginx_error info;"
+	if actual := buildServerErrorLog(all, server); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	all.Cfg.EnableSyslog = true
+	all.Cfg.SyslogHost = "syslog"
+	all.Cfg.SyslogPort = 514
+	all.LogForwardingAddress = ""
+	expected = "error_log syslog:server=syslog:514 info;"
+	if actual := buildServerErrorLog(all, server); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	server.ErrorLogLevel = "notice"
+	if actual := buildServerErrorLog(all, server); actual != "" {
+		t.Errorf("expected no error log for the level of the configuration but returned '%v'", actual)
+	}
+}
+
+func TestBuildRequestID(t *testing.T) {
+	cfg := config.NewDefault()
+
+	if actual := buildRequestID(cfg); actual != `"$request_id"` {
+		t.Errorf("expected the NGINX request ID but returned '%v'", actual)
+	}
+	if actual := buildGeneratedRequestID(cfg); actual != "" {
+		t.Errorf("expected no Lua block for the hex format but returned '%v'", actual)
+	}
+
+	cfg.RequestIDFormat = config.RequestIDFormatUUID7
+	cfg.RequestIDPrefix = "prod-eu1-"
+
+	if actual := buildRequestID(cfg); actual != `"prod-eu1-$generated_request_id"` {
+		t.Errorf("expected the prefixed generated request ID but returned '%v'", actual)
+	}
+	if actual := buildGeneratedRequestID(cfg); !strings.Contains(actual, `request_id.generate("uuid7")`) {
+		t.Errorf("expected the Lua block generating the uuid7 request IDs but returned '%v'", actual)
+	}
+
+	cfg.GenerateRequestID = false
+	if actual := buildGeneratedRequestID(cfg); actual != "" {
+		t.Errorf("expected no Lua block without request ID generation but returned '%v'", actual)
+	}
+}
+
+func TestBuildCorsOriginRegex(t *testing.T) {
+	testCases := []struct {
+		description string
+		config      cors.Config
+		expected    string
+	}{
+		{
+			"any origin",
+			cors.Config{CorsAllowOrigin: []string{"*"}},
+			"set $http_origin *;\nset $cors 'true';",
+		},
+		{
+			"exact origins",
+			cors.Config{CorsAllowOrigin: []string{"https://app.example.com", "https://admin.example.com:8443"}},
+			`more_set_headers "Vary: $cors_vary";` + "\n" +
+				`if ($http_origin ~* "^(https://app\.example\.com|https://admin\.example\.com:8443)$") { set $cors 'true'; }`,
+		},
+		{
+			"exact origins and regexes",
+			cors.Config{
+				CorsAllowOrigin:      []string{"https://app.example.com"},
+				CorsAllowOriginRegex: []string{`^https://[a-z0-9-]+\.example\.com$`},
+			},
+			`more_set_headers "Vary: $cors_vary";` + "\n" +
+				`if ($http_origin ~* "^(https://app\.example\.com)$") { set $cors 'true'; }` + "\n" +
+				`if ($http_origin ~ "^https://[a-z0-9-]+\.example\.com$") { set $cors 'true'; }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		if actual := buildCorsOriginRegex(tc.config); actual != tc.expected {
+			t.Errorf("%s: expected '%v' but returned '%v'", tc.description, tc.expected, actual)
+		}
+	}
+}
+
+func TestLocationPluginsForLua(t *testing.T) {
+	testCases := []struct {
+		description string
+		location    *ingress.Location
+		expected    string
+	}{
+		{
+			"without plugins",
+			&ingress.Location{},
+			"{}",
+		},
+		{
+			"disabled, enabled and configured plugins",
+			&ingress.Location{Plugins: pluginsconfig.Config{Plugins: []pluginsconfig.Plugin{
+				{Name: "hello_world"},
+				{Name: "open_idc", Enabled: true, Config: `{"issuer":"https://sso.example.com","realm":"a\"b"}`},
+				{Name: "tracing", Enabled: true},
+			}}},
+			`{ ["hello_world"] = false, ["open_idc"] = "{\"issuer\":\"https://sso.example.com\",\"realm\":\"a\\\"b\"}", ["tracing"] = true, }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		if actual := locationPluginsForLua(tc.location); actual != tc.expected {
+			t.Errorf("%s: expected '%v' but returned '%v'", tc.description, tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildIngressPlugins(t *testing.T) {
+	servers := []*ingress.Server{
+		{
+			Locations: []*ingress.Location{
+				{Plugins: pluginsconfig.Config{Plugins: []pluginsconfig.Plugin{{Name: "tracing", Enabled: true}, {Name: "hello_world"}}}},
+				{Plugins: pluginsconfig.Config{Plugins: []pluginsconfig.Plugin{{Name: "auth", Enabled: true, Config: "{}"}}}},
+			},
+		},
+		{
+			Locations: []*ingress.Location{
+				{Plugins: pluginsconfig.Config{Plugins: []pluginsconfig.Plugin{{Name: "open_idc", Enabled: true}, {Name: "tracing", Enabled: true}}}},
+			},
+		},
+	}
+
+	expected := `{ "auth", "tracing", }`
+	if actual := buildIngressPlugins(config.Configuration{Plugins: []string{"open_idc"}}, servers); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	expected = "{}"
+	if actual := buildIngressPlugins(config.Configuration{}, &ingress.Ingress{}); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestBuildNJSForLocation(t *testing.T) {
+	if actual := buildNJSForLocation(true, &ingress.Location{}); actual != "" {
+		t.Errorf("expected no directives but returned '%v'", actual)
+	}
+
+	location := &ingress.Location{
+		NJS: njs.Config{
+			ConfigMap: "default/scripts",
+			Directory: "/etc/ingress-controller/njs/default-scripts-0a1b2c3d4e5f",
+			Modules:   []string{"tenant", "utils"},
+			Variables: []njs.Variable{{Name: "tenant", Module: "tenant", Function: "get"}},
+		},
+	}
+	expected := `js_path "/etc/ingress-controller/njs/default-scripts-0a1b2c3d4e5f";
+js_import tenant from tenant.js;
+js_import utils from utils.js;
+js_set $njs_tenant tenant.get;
+`
+	if actual := buildNJSForLocation(true, location); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if shouldLoadNJSModule(true, []*ingress.Server{{Locations: []*ingress.Location{{}}}}) {
+		t.Errorf("expected the njs module not to be loaded without scripts")
+	}
+	if !shouldLoadNJSModule(true, []*ingress.Server{{Locations: []*ingress.Location{{}, location}}}) {
+		t.Errorf("expected the njs module to be loaded for the scripts of a location")
+	}
+
+	if shouldLoadNJSModule(false, []*ingress.Server{{Locations: []*ingress.Location{location}}}) {
+		t.Errorf("expected the njs module not to be loaded on an NGINX image without it")
+	}
+	if actual := buildNJSForLocation(false, location); actual != "" {
+		t.Errorf("expected no directives on an NGINX image without the njs module but returned '%v'", actual)
+	}
+}
+
+func TestBuildProxyCache(t *testing.T) {
+	cfg := config.NewDefault()
+	cfg.ProxyCacheZones = []config.ProxyCacheZone{{Name: "static", MaxSize: "10g", Inactive: "7d", KeysZoneSize: "80m"}}
+
+	expectedPaths := []string{
+		"proxy_cache_path /tmp/nginx/proxy-cache/static levels=1:2 keys_zone=cache_static:80m max_size=10g inactive=7d use_temp_path=off;",
+	}
+	if actual := buildProxyCachePaths(cfg); !reflect.DeepEqual(actual, expectedPaths) {
+		t.Errorf("expected '%v' but returned '%v'", expectedPaths, actual)
+	}
+
+	if actual := buildProxyCache(cfg, &ingress.Location{}); actual != "" {
+		t.Errorf("expected no directives but returned '%v'", actual)
+	}
+
+	location := &ingress.Location{
+		Path: "/assets",
+		Ingress: &ingress.Ingress{
+			Ingress: networking.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shop"}},
+		},
+		ProxyCache: proxycache.Config{
+			Zone:             "static",
+			Valid:            []string{"200 302 10m", "404 1m"},
+			Key:              proxycache.DefaultKey,
+			Bypass:           []string{"$http_authorization", "$cookie_session"},
+			UseStale:         []string{"error", "timeout", "updating"},
+			BackgroundUpdate: true,
+		},
+	}
+	expected := `proxy_cache cache_static;
+proxy_cache_key "$host:default/shop:$scheme$host$request_uri";
+proxy_cache_valid 200 302 10m;
+proxy_cache_valid 404 1m;
+proxy_cache_bypass $http_authorization $cookie_session;
+proxy_no_cache $http_authorization $cookie_session;
+proxy_cache_use_stale error timeout updating;
+proxy_cache_background_update on;
+proxy_cache_lock on;
+more_set_headers "X-Cache-Status: $upstream_cache_status";
+`
+	if actual := buildProxyCache(cfg, location); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	location.ProxyCache.UseStale = nil
+	location.ProxyCache.BackgroundUpdate = false
+	if actual := buildProxyCache(cfg, location); strings.Contains(actual, "proxy_cache_use_stale") || strings.Contains(actual, "proxy_cache_background_update") {
+		t.Errorf("expected the stale responses to be served as allowed by the responses but returned '%v'", actual)
+	}
+
+	location.ProxyCache.Zone = "unknown"
+	if actual := buildProxyCache(cfg, location); actual != "" {
+		t.Errorf("expected no directives for an unknown zone but returned '%v'", actual)
+	}
+}
+
+func TestBuildBrotliForLocation(t *testing.T) {
+	cfg := config.NewDefault()
+	enabled := &ingress.Location{Brotli: brotli.Config{Enable: true, EnableSet: true, Level: 9, Types: "text/html"}}
+	disabled := &ingress.Location{Brotli: brotli.Config{EnableSet: true}}
+
+	if shouldLoadBrotliModule(cfg, []*ingress.Server{{Locations: []*ingress.Location{{}, disabled}}}) {
+		t.Errorf("expected the Brotli module not to be loaded without Brotli")
+	}
+	if !shouldLoadBrotliModule(cfg, []*ingress.Server{{Locations: []*ingress.Location{{}, enabled}}}) {
+		t.Errorf("expected the Brotli module to be loaded for the Brotli of a location")
+	}
+
+	testCases := []struct {
+		title        string
+		enableBrotli bool
+		location     *ingress.Location
+		expected     string
+	}{
+		{"disabled globally", false, &ingress.Location{}, ""},
+		{"disabled globally and in the location", false, disabled, ""},
+		{"enabled in the location", false, enabled, "brotli on;\nbrotli_comp_level 9;\nbrotli_min_length 20;\nbrotli_types text/html;\n"},
+		{"enabled globally", true, &ingress.Location{}, ""},
+		{"enabled globally and in the location", true, &ingress.Location{Brotli: brotli.Config{Enable: true, EnableSet: true}}, ""},
+		{"disabled in the location", true, disabled, "brotli off;\n"},
+		{"level of the location", true, &ingress.Location{Brotli: brotli.Config{Level: 11}}, fmt.Sprintf("brotli on;\nbrotli_comp_level 11;\nbrotli_min_length 20;\nbrotli_types %v;\n", cfg.BrotliTypes)},
+	}
+
+	for _, testCase := range testCases {
+		cfg.EnableBrotli = testCase.enableBrotli
+		if actual := buildBrotliForLocation(cfg, testCase.location); actual != testCase.expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", testCase.title, testCase.expected, actual)
+		}
+	}
+}
+
+func TestBuildZstdForLocation(t *testing.T) {
+	cfg := config.NewDefault()
+	location := &ingress.Location{Zstd: zstd.Config{Enable: true, EnableSet: true, Level: 19, Types: "application/json"}}
+
+	if shouldLoadZstdModule(true, cfg, []*ingress.Server{{Locations: []*ingress.Location{{}}}}) {
+		t.Errorf("expected the Zstandard module not to be loaded without zstd")
+	}
+	if !shouldLoadZstdModule(true, cfg, []*ingress.Server{{Locations: []*ingress.Location{{}, location}}}) {
+		t.Errorf("expected the Zstandard module to be loaded for the zstd of a location")
+	}
+
+	expected := "zstd on;\nzstd_comp_level 19;\nzstd_min_length 20;\nzstd_types application/json;\n"
+	if actual := buildZstdForLocation(true, cfg, location); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	cfg.EnableZstd = true
+	expected = "zstd off;\n"
+	if actual := buildZstdForLocation(true, cfg, &ingress.Location{Zstd: zstd.Config{EnableSet: true}}); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if shouldLoadZstdModule(false, cfg, []*ingress.Server{{Locations: []*ingress.Location{location}}}) {
+		t.Errorf("expected the Zstandard module not to be loaded on an NGINX image without it")
+	}
+	if actual := buildZstdForLocation(false, cfg, location); actual != "" {
+		t.Errorf("expected no directives on an NGINX image without the Zstandard module but returned '%v'", actual)
+	}
+}
+
+func TestBuildRedirectMaps(t *testing.T) {
+	legacy := redirectmap.Config{
+		Name:       "default/legacy",
+		StatusCode: 301,
+		Redirects: []redirectmap.Redirect{
+			{Source: "/about-us", Target: "https://example.com/company"},
+			{Source: "/jobs", Target: "/careers"},
+		},
+	}
+	servers := []*ingress.Server{
+		{Hostname: "old.example.com", RedirectMap: legacy},
+		{Hostname: "www.old.example.com", RedirectMap: legacy},
+		{Hostname: "example.com"},
+	}
+
+	variable := redirectMapVariable("default/legacy")
+	expected := []string{`# RedirectMap default/legacy
+    map $uri ` + variable + ` {
+        default "";
+        "/about-us" "https://example.com/company";
+        "/jobs" "/careers";
+    }`}
+	if actual := buildRedirectMaps(servers); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	expectedRedirect := `if (` + variable + ` != "") {
+            return 301 ` + variable + `;
+        }`
+	if actual := buildRedirectMapForServer(servers[0]); actual != expectedRedirect {
+		t.Errorf("expected '%v' but returned '%v'", expectedRedirect, actual)
+	}
+	if actual := buildRedirectMapForServer(servers[2]); actual != "" {
+		t.Errorf("expected no redirect but returned '%v'", actual)
+	}
+
+	servers[0].RedirectMap.PreserveQueryString = true
+	if actual := buildRedirectMapForServer(servers[0]); !strings.Contains(actual, "return 301 "+variable+"$is_args$args;") {
+		t.Errorf("expected the query string to be preserved but returned '%v'", actual)
+	}
+
+	cfg := config.NewDefault()
+	if actual := buildMapHashBucketSize(cfg, servers); actual != cfg.MapHashBucketSize {
+		t.Errorf("expected a map_hash_bucket_size of %v but returned %v", cfg.MapHashBucketSize, actual)
+	}
+	servers[2].RedirectMap = redirectmap.Config{
+		Name:      "default/long",
+		Redirects: []redirectmap.Redirect{{Source: "/" + strings.Repeat("a", 100), Target: "/"}},
+	}
+	if actual := buildMapHashBucketSize(cfg, servers); actual != 128 {
+		t.Errorf("expected a map_hash_bucket_size of 128 but returned %v", actual)
+	}
+
+	if actual := buildMapHashMaxSize(servers); actual != 2048 {
+		t.Errorf("expected a map_hash_max_size of 2048 but returned %v", actual)
+	}
+	for i := 0; i < 1000; i++ {
+		legacy.Redirects = append(legacy.Redirects, redirectmap.Redirect{Source: fmt.Sprintf("/page-%v", i), Target: "/"})
+	}
+	servers[0].RedirectMap = legacy
+	if actual := buildMapHashMaxSize(servers); actual != 4096 {
+		t.Errorf("expected a map_hash_max_size of 4096 but returned %v", actual)
+	}
+}
+
+func TestBuildConditionalRedirects(t *testing.T) {
+	location := &ingress.Location{ConditionalRedirects: conditionalredirect.Config{Rules: []conditionalredirect.Rule{
+		{Variable: "$http_user_agent", Operator: "~*", Value: `Mobile\b`, Target: "https://m.example.com$request_uri", StatusCode: 302},
+		{Variable: "$cookie_consent", Operator: "=", Target: "/consent", StatusCode: 307},
+	}}}
+
+	expected := `if ($http_user_agent ~* "Mobile\\b") {
+                return 302 https://m.example.com$request_uri;
+            }
+if ($cookie_consent = "") {
+                return 307 /consent;
+            }
+`
+	if actual := buildConditionalRedirects(location); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if actual := buildConditionalRedirects(&ingress.Location{}); actual != "" {
+		t.Errorf("expected no redirect but returned '%v'", actual)
+	}
+}
+
+func TestBuildTrailingSlashRedirect(t *testing.T) {
+	testCases := []struct {
+		config   trailingslash.Config
+		expected string
+	}{
+		{trailingslash.Config{}, ""},
+		{trailingslash.Config{Mode: trailingslash.ModeAdd, Code: 308}, `if ($request_uri ~ "^([^?]*/[^/?.]+)([?].*)?$") {
+                return 308 $pass_access_scheme://$best_http_host$1/$2;
+            }`},
+		{trailingslash.Config{Mode: trailingslash.ModeRemove, Code: 301}, `if ($request_uri ~ "^([^?]*[^/?])/+([?].*)?$") {
+                return 301 $pass_access_scheme://$best_http_host$1$2;
+            }`},
+	}
+
+	for _, tc := range testCases {
+		if actual := buildTrailingSlashRedirect(&ingress.Location{TrailingSlash: tc.config}); actual != tc.expected {
+			t.Errorf("expected '%v' but returned '%v'", tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildCustomErrorPages(t *testing.T) {
+	pages := customerrorpages.Config{
+		ConfigMap: "default/error-pages",
+		Directory: "/etc/ingress-controller/errorpages/default-error-pages-0a1b2c3d4e5f",
+		Pages: []customerrorpages.Page{
+			{Code: 404, HTML: true},
+			{Code: 503, HTML: true, JSON: true},
+		},
+	}
+
+	location := &ingress.Location{CustomErrorPages: pages}
+	expected := `proxy_intercept_errors on;
+            error_page 404 @custom_error_page_default-error-pages-0a1b2c3d4e5f_404;
+            error_page 503 @custom_error_page_default-error-pages-0a1b2c3d4e5f_503;`
+	if actual := buildCustomErrorPages(location); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	location.CustomHTTPErrors = []int{503}
+	expected = `error_page 404 @custom_error_page_default-error-pages-0a1b2c3d4e5f_404;
+            error_page 503 @custom_error_page_default-error-pages-0a1b2c3d4e5f_503;`
+	if actual := buildCustomErrorPages(location); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if actual := buildCustomErrorPages(&ingress.Location{}); actual != "" {
+		t.Errorf("expected no error pages but returned '%v'", actual)
+	}
+
+	server := &ingress.Server{Locations: []*ingress.Location{
+		{Path: "/", CustomErrorPages: pages},
+		{Path: "/api", CustomErrorPages: pages},
+		{Path: "/static"},
+	}}
+	expected = `location @custom_error_page_default-error-pages-0a1b2c3d4e5f_404 {
+            internal;
+            root /etc/ingress-controller/errorpages/default-error-pages-0a1b2c3d4e5f;
+            try_files /404.html =404;
+        }
+        location @custom_error_page_default-error-pages-0a1b2c3d4e5f_503 {
+            internal;
+            root /etc/ingress-controller/errorpages/default-error-pages-0a1b2c3d4e5f;
+            try_files /503$custom_error_page_extension =503;
+        }
+        `
+	if actual := buildCustomErrorPagesLocations(server, false); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestBuildMaintenanceMode(t *testing.T) {
+	allowlisted := maintenance.Config{Enabled: true, Allowlist: []string{"10.0.0.0/8", "192.168.1.10"}, ID: "fc4d5d677fd8e876"}
+
+	testCases := []struct {
+		config   maintenance.Config
+		expected string
+	}{
+		{maintenance.Config{}, ""},
+		{maintenance.Config{Enabled: true}, "return 503;"},
+		{allowlisted, `if ($maintenance_allowed_fc4d5d677fd8e876 = 0) {
+                return 503;
+            }`},
+	}
+	for _, tc := range testCases {
+		if actual := buildMaintenanceMode(&ingress.Location{Maintenance: tc.config}); actual != tc.expected {
+			t.Errorf("expected '%v' but returned '%v'", tc.expected, actual)
+		}
+	}
+
+	servers := []*ingress.Server{
+		{Locations: []*ingress.Location{
+			{Path: "/", Maintenance: allowlisted},
+			{Path: "/api", Maintenance: maintenance.Config{Enabled: true}},
+		}},
+		{Locations: []*ingress.Location{
+			{Path: "/", Maintenance: allowlisted},
+		}},
+	}
+	expected := []string{`geo $remote_addr $maintenance_allowed_fc4d5d677fd8e876 {
+        default 0;
+        10.0.0.0/8 1;
+        192.168.1.10 1;
+    }`}
+	if actual := buildMaintenanceAllowlists(servers); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestBuildGeoIP2Databases(t *testing.T) {
+	files := []string{"GeoLite2-City.mmdb", "dbip-country-lite.mmdb", "ipinfo-asn.mmdb"}
+
+	expected := `
+    # dbip database
+    geoip2 /etc/ingress-controller/geoip/dbip-country-lite.mmdb {
+        auto_reload 5m;
+        $geoip2_country_code source=$remote_addr country iso_code;
+        $geoip2_country_name source=$remote_addr country names en;
+        $geoip2_continent_code source=$remote_addr continent code;
+        $geoip2_continent_name source=$remote_addr continent names en;
+    }
+
+    # ipinfo database
+    geoip2 /etc/ingress-controller/geoip/ipinfo-asn.mmdb {
+        auto_reload 5m;
+        $geoip2_ipinfo_asn source=$remote_addr asn;
+        $geoip2_org source=$remote_addr name;
+    }
+
+    map $geoip2_ipinfo_asn $geoip2_asn {
+        ~^AS(\d+)$ $1;
+        default 0;
+    }
+`
+	if actual := buildGeoIP2Databases(&files, 5); actual != expected {
+		t.Errorf("Expected\n%v\nbut returned\n%v", expected, actual)
+	}
+
+	files = []string{"GeoLite2-City.mmdb", "GeoLite2-ASN.mmdb"}
+	if actual := buildGeoIP2Databases(&files, 0); actual != "" {
+		t.Errorf("Expected no geoip2 block for the MaxMind databases but returned\n%v", actual)
+	}
+
+	if actual := buildGeoIP2Databases((*[]string)(nil), 0); actual != "" {
+		t.Errorf("Expected '' but returned '%v'", actual)
+	}
+}
@@ -18,6 +18,7 @@ package controller
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -28,6 +29,7 @@ import (
 	api "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
 	klog "k8s.io/klog/v2"
 )
@@ -73,6 +75,19 @@ func upstreamServiceNameAndPort(service *networking.IngressServiceBackend) (stri
 	return "", intstr.IntOrString{}
 }
 
+// backendEndpoints returns the endpoints of the backends, formatted as
+// the upstream addresses reported by NGINX
+func backendEndpoints(backends []*ingress.Backend) sets.Set[string] {
+	endpoints := sets.New[string]()
+	for _, backend := range backends {
+		for i := range backend.Endpoints {
+			endpoints.Insert(net.JoinHostPort(backend.Endpoints[i].Address, backend.Endpoints[i].Port))
+		}
+	}
+
+	return endpoints
+}
+
 // sysctlSomaxconn returns the maximum number of connections that can be queued
 // for acceptance (value of net.core.somaxconn)
 // http://nginx.org/en/docs/http/ngx_http_core_module.html#listen
@@ -0,0 +1,360 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/ingress-nginx/pkg/util/file"
+)
+
+// Validation modes of the candidate configurations of the admission controller
+const (
+	// ValidationModeFull tests the configuration with nginx -t
+	ValidationModeFull = "full"
+	// ValidationModeSandbox tests the configuration with nginx -t in an
+	// isolated temporary prefix, with time and CPU limits and a maximum
+	// number of concurrent tests
+	ValidationModeSandbox = "sandbox"
+	// ValidationModeSyntax only checks the syntax of the configuration,
+	// without running nginx
+	ValidationModeSyntax = "syntax"
+)
+
+// ValidationModes are the validation modes of the admission controller
+var ValidationModes = []string{ValidationModeFull, ValidationModeSandbox, ValidationModeSyntax}
+
+// AdmissionValidationConfiguration defines how the admission controller tests
+// the candidate configurations
+type AdmissionValidationConfiguration struct {
+	// Mode is one of ValidationModes
+	Mode string
+	// Timeout is the maximum duration of a sandbox test, including the wait
+	// for a free slot
+	Timeout time.Duration
+	// CPULimit is the CPU time limit, in seconds, of the nginx -t processes
+	// of the sandbox tests, 0 disables the limit
+	CPULimit int
+	// Concurrency is the maximum number of concurrent sandbox tests
+	Concurrency int
+}
+
+// sandboxLimitError is returned when a sandbox test is aborted by the limits
+// of the sandbox, the configuration is not known to be invalid
+type sandboxLimitError struct {
+	msg string
+	// queued is true when the test did not start, waiting for a free slot
+	// of the sandbox
+	queued bool
+}
+
+func (e *sandboxLimitError) Error() string {
+	return e.msg
+}
+
+// sandbox tests the configurations in isolated temporary prefixes
+type sandbox struct {
+	binary   string
+	timeout  time.Duration
+	cpuLimit int
+	// slots limits the number of concurrent tests
+	slots chan struct{}
+}
+
+func newSandbox(binary string, cfg *AdmissionValidationConfiguration) *sandbox {
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &sandbox{
+		binary:   binary,
+		timeout:  cfg.Timeout,
+		cpuLimit: cfg.CPULimit,
+		slots:    make(chan struct{}, concurrency),
+	}
+}
+
+// test runs nginx -t against the configuration in a new temporary prefix,
+// removed afterwards. The error log of the startup is written to the prefix
+// too, so the test does not touch the files of the running NGINX.
+func (s *sandbox) test(cfg []byte) error {
+	if len(cfg) == 0 {
+		return fmt.Errorf("invalid NGINX configuration (empty)")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return &sandboxLimitError{
+			msg:    fmt.Sprintf("too many concurrent tests of the NGINX configuration, no test finished within %v", s.timeout),
+			queued: true,
+		}
+	}
+
+	prefix, err := os.MkdirTemp(os.TempDir()+"/nginx", "sandbox-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(prefix)
+
+	cfgFile := filepath.Join(prefix, "nginx.conf")
+	err = os.WriteFile(cfgFile, cfg, file.ReadWriteByUser)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-p", prefix + "/", "-e", filepath.Join(prefix, "error.log"), "-c", cfgFile, "-t"}
+	var cmd *exec.Cmd
+	if s.cpuLimit > 0 {
+		// the shell sets the CPU time limit of the process it replaces
+		// itself with, the binary and its arguments are $0 and $@
+		script := fmt.Sprintf(`ulimit -t %d && exec "$0" "$@"`, s.cpuLimit)
+		//nolint:gosec // Ignore G204 error
+		cmd = exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, s.binary}, args...)...)
+	} else {
+		//nolint:gosec // Ignore G204 error
+		cmd = exec.CommandContext(ctx, s.binary, args...)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &sandboxLimitError{msg: fmt.Sprintf("the test of the NGINX configuration did not finish within %v", s.timeout)}
+	}
+	if err != nil {
+		return &testError{
+			err:    err,
+			output: string(out),
+			file:   cfgFile,
+			config: cfg,
+		}
+	}
+
+	return nil
+}
+
+// validateTemplate tests the candidate configuration of the admission
+// controller with its validation mode
+func (n *NGINXController) validateTemplate(cfg []byte) error {
+	if n.cfg.AdmissionValidation == nil {
+		return n.testTemplate(cfg)
+	}
+
+	switch n.cfg.AdmissionValidation.Mode {
+	case ValidationModeSyntax:
+		return checkSyntax(cfg)
+	case ValidationModeSandbox:
+		return n.sandbox.test(cfg)
+	default:
+		return n.testTemplate(cfg)
+	}
+}
+
+// checkSyntax checks the syntax of an NGINX configuration the way NGINX
+// parses it: the directives end with a semicolon or a block, the blocks and
+// the quotes are closed. The names and the arguments of the directives are
+// not checked, it does not replace nginx -t.
+func checkSyntax(cfg []byte) error {
+	if len(cfg) == 0 {
+		return fmt.Errorf("invalid NGINX configuration (empty)")
+	}
+
+	p := &syntaxParser{cfg: cfg, line: 1}
+	return p.parse()
+}
+
+type syntaxParser struct {
+	cfg  []byte
+	pos  int
+	line int
+}
+
+func (p *syntaxParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid NGINX configuration, line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *syntaxParser) next() byte {
+	c := p.cfg[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *syntaxParser) parse() error {
+	depth := 0
+	// words are the name and the arguments of the current directive
+	var words []string
+
+	for p.pos < len(p.cfg) {
+		c := p.next()
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		case c == '#':
+			p.skipLine()
+		case c == ';':
+			if len(words) == 0 {
+				return p.errorf(`unexpected ";"`)
+			}
+			words = nil
+		case c == '{':
+			if len(words) == 0 {
+				return p.errorf(`unexpected "{"`)
+			}
+			if strings.HasSuffix(words[0], "_by_lua_block") {
+				if err := p.skipLuaBlock(); err != nil {
+					return err
+				}
+			} else {
+				depth++
+			}
+			words = nil
+		case c == '}':
+			if len(words) != 0 {
+				return p.errorf(`unexpected "}", directive %q is not terminated by ";"`, words[0])
+			}
+			if depth == 0 {
+				return p.errorf(`unexpected "}"`)
+			}
+			depth--
+		case c == '"' || c == '\'':
+			word, err := p.quoted(c)
+			if err != nil {
+				return err
+			}
+			words = append(words, word)
+		default:
+			p.pos--
+			words = append(words, p.word())
+		}
+	}
+
+	if len(words) != 0 {
+		return p.errorf(`unexpected end of file, directive %q is not terminated by ";"`, words[0])
+	}
+	if depth != 0 {
+		return p.errorf(`unexpected end of file, expecting "}"`)
+	}
+
+	return nil
+}
+
+func (p *syntaxParser) skipLine() {
+	for p.pos < len(p.cfg) && p.cfg[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// word reads an unquoted word, variables like ${name} included
+func (p *syntaxParser) word() string {
+	start := p.pos
+	for p.pos < len(p.cfg) {
+		c := p.cfg[p.pos]
+		switch c {
+		case ' ', '\t', '\r', '\n', ';', '{', '}':
+			if c == '{' && p.pos > start && p.cfg[p.pos-1] == '$' {
+				p.skipVariable()
+				continue
+			}
+			return string(p.cfg[start:p.pos])
+		case '\\':
+			if p.pos+1 < len(p.cfg) {
+				p.pos++
+			}
+		}
+		p.next()
+	}
+	return string(p.cfg[start:p.pos])
+}
+
+func (p *syntaxParser) skipVariable() {
+	for p.pos < len(p.cfg) && p.cfg[p.pos] != '}' {
+		p.next()
+	}
+	if p.pos < len(p.cfg) {
+		p.pos++
+	}
+}
+
+// quoted reads a word quoted with the quote character
+func (p *syntaxParser) quoted(quote byte) (string, error) {
+	line := p.line
+	start := p.pos
+	for p.pos < len(p.cfg) {
+		c := p.next()
+		switch c {
+		case '\\':
+			if p.pos < len(p.cfg) {
+				p.next()
+			}
+		case quote:
+			return string(p.cfg[start : p.pos-1]), nil
+		}
+	}
+
+	p.line = line
+	return "", p.errorf("unexpected end of file, unterminated quoted string")
+}
+
+// skipLuaBlock skips the Lua code of a *_by_lua_block directive up to its
+// closing brace, ignoring the braces of the Lua strings and comments
+func (p *syntaxParser) skipLuaBlock() error {
+	line := p.line
+	depth := 1
+	for p.pos < len(p.cfg) {
+		c := p.next()
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case c == '"' || c == '\'':
+			if _, err := p.quoted(c); err != nil {
+				return err
+			}
+		case c == '-' && p.pos < len(p.cfg) && p.cfg[p.pos] == '-':
+			p.skipLine()
+		case c == '[' && p.pos < len(p.cfg) && p.cfg[p.pos] == '[':
+			end := strings.Index(string(p.cfg[p.pos:]), "]]")
+			if end < 0 {
+				p.line = line
+				return p.errorf("unexpected end of file, unterminated Lua long string")
+			}
+			for i := 0; i < end+2; i++ {
+				p.next()
+			}
+		}
+	}
+
+	p.line = line
+	return p.errorf(`unexpected end of file, Lua block expecting "}"`)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	testCases := map[string]struct {
+		cfg   string
+		valid bool
+	}{
+		"directives and blocks": {
+			cfg: `worker_processes 1;
+http {
+    # comment with { and ;
+    server {
+        listen 80;
+        location / {
+            return 200 "ok;}";
+        }
+    }
+}`,
+			valid: true,
+		},
+		"variables with braces": {
+			cfg:   `http { set $a "x"; return 200 ${a}b; }`,
+			valid: true,
+		},
+		"lua block": {
+			cfg: `http {
+    init_by_lua_block {
+        local t = { a = "}" } -- }
+        local s = [[ { ]]
+    }
+}`,
+			valid: true,
+		},
+		"missing semicolon": {
+			cfg: `http { server { listen 80 } }`,
+		},
+		"unclosed block": {
+			cfg: `http { server { listen 80; }`,
+		},
+		"unexpected closing brace": {
+			cfg: `http { } }`,
+		},
+		"unterminated quote": {
+			cfg: `http { return 200 "ok; }`,
+		},
+		"block without directive": {
+			cfg: `http { { } }`,
+		},
+		"unclosed lua block": {
+			cfg: `http { init_by_lua_block { local t = { } }`,
+		},
+		"empty": {},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := checkSyntax([]byte(tc.cfg))
+			if tc.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestCheckSyntaxLine(t *testing.T) {
+	err := checkSyntax([]byte("http {\n    listen 80\n}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error at line 3, got %v", err)
+	}
+}
+
+// fakeNginx writes a script standing for the nginx binary
+func fakeNginx(t *testing.T, script string) string {
+	t.Helper()
+
+	binary := filepath.Join(t.TempDir(), "nginx")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"+script+"\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
+func TestSandbox(t *testing.T) {
+	if err := os.MkdirAll(os.TempDir()+"/nginx", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the arguments are -p prefix -e error_log -c file -t
+	binary := fakeNginx(t, `test "$1" = "-p" && test -f "$2/nginx.conf" && grep -q valid "$6" && ! grep -q invalid "$6"`)
+	s := newSandbox(binary, &AdmissionValidationConfiguration{Timeout: 5 * time.Second, CPULimit: 1, Concurrency: 1})
+
+	if err := s.test([]byte("valid")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := s.test([]byte("invalid"))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	terr, ok := err.(*testError)
+	if !ok {
+		t.Fatalf("expected a testError, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(terr.file)); !os.IsNotExist(err) {
+		t.Errorf("expected the prefix %v to be removed", filepath.Dir(terr.file))
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	if err := os.MkdirAll(os.TempDir()+"/nginx", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSandbox(fakeNginx(t, "sleep 1"), &AdmissionValidationConfiguration{Timeout: 200 * time.Millisecond, Concurrency: 1})
+	if err := s.test([]byte("valid")); err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+
+	s = newSandbox(fakeNginx(t, "sleep 0.3"), &AdmissionValidationConfiguration{Timeout: 450 * time.Millisecond, Concurrency: 1})
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.test([]byte("valid"))
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected one of the concurrent tests to fail waiting for a slot, got %v", errs)
+	}
+}
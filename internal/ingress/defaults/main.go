@@ -179,6 +179,14 @@ type Backend struct {
 
 	// AllowedResponseHeaders allows to define allow response headers for custom header annotation
 	AllowedResponseHeaders []string `json:"global-allowed-response-headers"`
+
+	// SecurityHeaders is the preset of the security headers of the responses:
+	// none, standard or strict
+	SecurityHeaders string `json:"security-headers"`
+
+	// StrictRequestValidation rejects the ambiguous requests which could be
+	// smuggled through chained proxies with a 400 response
+	StrictRequestValidation bool `json:"strict-request-validation"`
 }
 
 type SecurityConfiguration struct {
@@ -188,6 +196,12 @@ type SecurityConfiguration struct {
 	// This valid will default to `false` on future releases
 	AllowCrossNamespaceResources bool `json:"allow-cross-namespace-resources"`
 
+	// ReferenceGrants authorizes the references of the annotations
+	// proxy-ssl-secret and auth-tls-secret to the Secrets of other namespaces
+	// with the ReferenceGrants of the Gateway API, instead of
+	// AllowCrossNamespaceResources
+	ReferenceGrants bool `json:"-"`
+
 	// AnnotationsRiskLevel represents the risk accepted on an annotation. If the risk is, for instance `Medium`, annotations
 	// with risk High and Critical will not be accepted
 	AnnotationsRiskLevel string `json:"annotations-risk-level"`
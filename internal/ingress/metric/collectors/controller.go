@@ -18,6 +18,8 @@ package collectors
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -28,13 +30,30 @@ import (
 )
 
 var (
-	operation        = []string{"controller_namespace", "controller_class", "controller_pod"}
-	ingressOperation = []string{"controller_namespace", "controller_class", "controller_pod", "namespace", "ingress"}
-	sslLabelHost     = []string{"namespace", "class", "host", "secret_name", "identifier"}
-	sslInfoLabels    = []string{"namespace", "class", "host", "secret_name", "identifier", "issuer_organization", "issuer_common_name", "serial_number", "public_key_algorithm"}
-	orphanityLabels  = []string{"controller_namespace", "controller_class", "controller_pod", "namespace", "ingress", "type"}
+	operation           = []string{"controller_namespace", "controller_class", "controller_pod"}
+	ingressOperation    = []string{"controller_namespace", "controller_class", "controller_pod", "namespace", "ingress"}
+	sslLabelHost        = []string{"namespace", "class", "host", "secret_name", "identifier"}
+	sslInfoLabels       = []string{"namespace", "class", "host", "secret_name", "identifier", "issuer_organization", "issuer_common_name", "serial_number", "public_key_algorithm"}
+	orphanityLabels     = []string{"controller_namespace", "controller_class", "controller_pod", "namespace", "ingress", "type"}
+	sslExpireDaysLabels = []string{"host", "namespace", "secret_name", "type"}
+	reloadFailureLabels = []string{"reason"}
 )
 
+// durationBuckets are the buckets of the build and reload durations of the
+// configuration, from 10ms to 20s
+var durationBuckets = prometheus.ExponentialBuckets(0.01, 2, 12)
+
+// certificateExpiry describes the expiration of a certificate served by a host
+type certificateExpiry struct {
+	host       string
+	namespace  string
+	secretName string
+	// certificateType is "server", "default" for the certificate of the
+	// catch-all server, or "ca" for the CA of the client certificates
+	certificateType string
+	expireTime      time.Time
+}
+
 // Controller defines base metrics about the ingress controller
 type Controller struct {
 	prometheus.Collector
@@ -45,12 +64,24 @@ type Controller struct {
 
 	reloadOperation             *prometheus.CounterVec
 	reloadOperationErrors       *prometheus.CounterVec
+	reloadSkipped               *prometheus.CounterVec
+	reloadFailures              *prometheus.CounterVec
+	reloadDuration              prometheus.Histogram
+	reloadQueueDepth            prometheus.Gauge
+	configBuildDuration         prometheus.Histogram
 	checkIngressOperation       *prometheus.CounterVec
 	checkIngressOperationErrors *prometheus.CounterVec
 	sslExpireTime               *prometheus.GaugeVec
 	sslInfo                     *prometheus.GaugeVec
 	OrphanIngress               *prometheus.GaugeVec
 
+	// sslExpireDays is computed on every scrape from certificates, replaced
+	// on every sync
+	sslExpireDays    *prometheus.Desc
+	certificates     []certificateExpiry
+	certificatesLock sync.Mutex
+	now              func() time.Time
+
 	constLabels prometheus.Labels
 	labels      prometheus.Labels
 
@@ -131,6 +162,49 @@ func NewController(pod, namespace, class string) *Controller {
 			},
 			operation,
 		),
+		reloadSkipped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: PrometheusNamespace,
+				Name:      "reload_skipped",
+				Help:      `Cumulative number of Ingress controller reload operations skipped because the rendered configuration did not change`,
+			},
+			operation,
+		),
+		reloadFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "reload_failures",
+				Help:        `Cumulative number of failed configuration reloads. 'reason' is 'template' for the rendering of the configuration, 'test' for the nginx -t test, 'signal' for the reload signal or 'other'`,
+				ConstLabels: constLabels,
+			},
+			reloadFailureLabels,
+		),
+		reloadDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "reload_duration_seconds",
+				Help:        "The time spent rendering, testing and reloading the configuration of NGINX",
+				ConstLabels: constLabels,
+				Buckets:     durationBuckets,
+			},
+		),
+		reloadQueueDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "reload_queue_depth",
+				Help:        "The number of changes waiting for the synchronization of the configuration",
+				ConstLabels: constLabels,
+			},
+		),
+		configBuildDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "config_build_duration_seconds",
+				Help:        "The time spent building the configuration model from the Kubernetes objects",
+				ConstLabels: constLabels,
+				Buckets:     durationBuckets,
+			},
+		),
 		checkIngressOperationErrors: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: PrometheusNamespace,
@@ -182,6 +256,11 @@ func NewController(pod, namespace, class string) *Controller {
 			},
 			orphanityLabels,
 		),
+		sslExpireDays: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "ssl_certificate_expire_days"),
+			"Number of days until the expiration of the certificates served by the hosts. 'type' is 'server', 'default' for the default certificate or 'ca' for the CA of the client certificates",
+			sslExpireDaysLabels, constLabels),
+		now: time.Now,
 	}
 
 	return cm
@@ -197,6 +276,32 @@ func (cm *Controller) IncReloadErrorCount() {
 	cm.reloadOperationErrors.With(cm.constLabels).Inc()
 }
 
+// IncReloadSkippedCount increment the skipped reload counter
+func (cm *Controller) IncReloadSkippedCount() {
+	cm.reloadSkipped.With(cm.constLabels).Inc()
+}
+
+// IncReloadFailureCount increment the reload failure counter of the reason
+func (cm *Controller) IncReloadFailureCount(reason string) {
+	cm.reloadFailures.WithLabelValues(reason).Inc()
+}
+
+// ObserveReloadDuration records the duration of a reload, in seconds
+func (cm *Controller) ObserveReloadDuration(seconds float64) {
+	cm.reloadDuration.Observe(seconds)
+}
+
+// ObserveConfigBuildDuration records the duration of the build of the
+// configuration model, in seconds
+func (cm *Controller) ObserveConfigBuildDuration(seconds float64) {
+	cm.configBuildDuration.Observe(seconds)
+}
+
+// SetReloadQueueDepth sets the number of changes waiting to be synchronized
+func (cm *Controller) SetReloadQueueDepth(depth int) {
+	cm.reloadQueueDepth.Set(float64(depth))
+}
+
 // OnStartedLeading indicates the pod was elected as the leader
 func (cm *Controller) OnStartedLeading(electionID string) {
 	cm.leaderElection.WithLabelValues(electionID).Set(1.0)
@@ -267,6 +372,11 @@ func (cm *Controller) Describe(ch chan<- *prometheus.Desc) {
 	cm.configSuccessTime.Describe(ch)
 	cm.reloadOperation.Describe(ch)
 	cm.reloadOperationErrors.Describe(ch)
+	cm.reloadSkipped.Describe(ch)
+	cm.reloadFailures.Describe(ch)
+	cm.reloadDuration.Describe(ch)
+	cm.reloadQueueDepth.Describe(ch)
+	cm.configBuildDuration.Describe(ch)
 	cm.checkIngressOperation.Describe(ch)
 	cm.checkIngressOperationErrors.Describe(ch)
 	cm.sslExpireTime.Describe(ch)
@@ -274,6 +384,7 @@ func (cm *Controller) Describe(ch chan<- *prometheus.Desc) {
 	cm.leaderElection.Describe(ch)
 	cm.buildInfo.Describe(ch)
 	cm.OrphanIngress.Describe(ch)
+	ch <- cm.sslExpireDays
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -283,6 +394,11 @@ func (cm *Controller) Collect(ch chan<- prometheus.Metric) {
 	cm.configSuccessTime.Collect(ch)
 	cm.reloadOperation.Collect(ch)
 	cm.reloadOperationErrors.Collect(ch)
+	cm.reloadSkipped.Collect(ch)
+	cm.reloadFailures.Collect(ch)
+	cm.reloadDuration.Collect(ch)
+	cm.reloadQueueDepth.Collect(ch)
+	cm.configBuildDuration.Collect(ch)
 	cm.checkIngressOperation.Collect(ch)
 	cm.checkIngressOperationErrors.Collect(ch)
 	cm.sslExpireTime.Collect(ch)
@@ -290,6 +406,57 @@ func (cm *Controller) Collect(ch chan<- prometheus.Metric) {
 	cm.leaderElection.Collect(ch)
 	cm.buildInfo.Collect(ch)
 	cm.OrphanIngress.Collect(ch)
+
+	cm.certificatesLock.Lock()
+	defer cm.certificatesLock.Unlock()
+
+	for _, c := range cm.certificates {
+		days := c.expireTime.Sub(cm.now()).Hours() / 24
+		ch <- prometheus.MustNewConstMetric(cm.sslExpireDays, prometheus.GaugeValue, days,
+			c.host, c.namespace, c.secretName, c.certificateType)
+	}
+}
+
+// SetSSLExpireDays sets the certificates served by the hosts, whose number
+// of days until the expiration is computed on every scrape
+func (cm *Controller) SetSSLExpireDays(servers []*ingress.Server) {
+	certificates := []certificateExpiry{}
+	for _, s := range servers {
+		if s.Hostname == "" {
+			continue
+		}
+
+		if s.SSLCert != nil && s.SSLCert.ExpireTime.Unix() > 0 {
+			certificateType := "server"
+			if s.Hostname == "_" {
+				certificateType = "default"
+			}
+
+			certificates = append(certificates, certificateExpiry{
+				host:            s.Hostname,
+				namespace:       s.SSLCert.Namespace,
+				secretName:      s.SSLCert.Name,
+				certificateType: certificateType,
+				expireTime:      s.SSLCert.ExpireTime,
+			})
+		}
+
+		if s.CertificateAuth.CAExpireTime.Unix() > 0 {
+			namespace, name, _ := strings.Cut(s.CertificateAuth.Secret, "/")
+			certificates = append(certificates, certificateExpiry{
+				host:            s.Hostname,
+				namespace:       namespace,
+				secretName:      name,
+				certificateType: "ca",
+				expireTime:      s.CertificateAuth.CAExpireTime,
+			})
+		}
+	}
+
+	cm.certificatesLock.Lock()
+	defer cm.certificatesLock.Unlock()
+
+	cm.certificates = certificates
 }
 
 // SetSSLExpireTime sets the expiration time of SSL Certificates
@@ -24,6 +24,8 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
 )
 
@@ -73,6 +75,37 @@ func TestControllerCounters(t *testing.T) {
 			`,
 			metrics: []string{"nginx_ingress_controller_errors"},
 		},
+		{
+			name: "single increase in skipped reload count should return 1",
+			test: func(cm *Controller) {
+				cm.IncReloadSkippedCount()
+			},
+			want: `
+				# HELP nginx_ingress_controller_reload_skipped Cumulative number of Ingress controller reload operations skipped because the rendered configuration did not change
+				# TYPE nginx_ingress_controller_reload_skipped counter
+				nginx_ingress_controller_reload_skipped{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_reload_skipped"},
+		},
+		{
+			name: "should count the reload failures by reason",
+			test: func(cm *Controller) {
+				cm.IncReloadFailureCount("test")
+				cm.IncReloadFailureCount("test")
+				cm.IncReloadFailureCount("signal")
+				cm.SetReloadQueueDepth(3)
+			},
+			want: `
+				# HELP nginx_ingress_controller_reload_failures Cumulative number of failed configuration reloads. 'reason' is 'template' for the rendering of the configuration, 'test' for the nginx -t test, 'signal' for the reload signal or 'other'
+				# TYPE nginx_ingress_controller_reload_failures counter
+				nginx_ingress_controller_reload_failures{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="signal"} 1
+				nginx_ingress_controller_reload_failures{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="test"} 2
+				# HELP nginx_ingress_controller_reload_queue_depth The number of changes waiting for the synchronization of the configuration
+				# TYPE nginx_ingress_controller_reload_queue_depth gauge
+				nginx_ingress_controller_reload_queue_depth{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 3
+			`,
+			metrics: []string{"nginx_ingress_controller_reload_failures", "nginx_ingress_controller_reload_queue_depth"},
+		},
 		{
 			name: "should set SSL certificates metrics",
 			test: func(cm *Controller) {
@@ -331,3 +364,64 @@ func TestRemoveAllSSLMetrics(t *testing.T) {
 
 	reg.Unregister(cm)
 }
+
+func TestSSLExpireDays(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	cm := NewController("pod", "default", "nginx")
+	cm.now = func() time.Time { return now }
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(cm); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+
+	servers := []*ingress.Server{
+		{
+			Hostname: "_",
+			SSLCert:  &ingress.SSLCert{ExpireTime: now.Add(365 * 24 * time.Hour)},
+		},
+		{
+			Hostname: "demo",
+			SSLCert: &ingress.SSLCert{
+				Name:       "demo-tls",
+				Namespace:  "demo",
+				ExpireTime: now.Add(36 * time.Hour),
+			},
+			CertificateAuth: authtls.Config{
+				AuthSSLCert: resolver.AuthSSLCert{
+					Secret:       "demo/demo-ca",
+					CAExpireTime: now.Add(-24 * time.Hour),
+				},
+			},
+		},
+		{
+			Hostname: "invalid",
+			SSLCert:  &ingress.SSLCert{},
+		},
+	}
+	cm.SetSSLExpireDays(servers)
+
+	want := `
+		# HELP nginx_ingress_controller_ssl_certificate_expire_days Number of days until the expiration of the certificates served by the hosts. 'type' is 'server', 'default' for the default certificate or 'ca' for the CA of the client certificates
+		# TYPE nginx_ingress_controller_ssl_certificate_expire_days gauge
+		nginx_ingress_controller_ssl_certificate_expire_days{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="_",namespace="",secret_name="",type="default"} 365
+		nginx_ingress_controller_ssl_certificate_expire_days{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="demo",namespace="demo",secret_name="demo-ca",type="ca"} -1
+		nginx_ingress_controller_ssl_certificate_expire_days{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="demo",namespace="demo",secret_name="demo-tls",type="server"} 1.5
+	`
+	if err := GatherAndCompare(cm, want, []string{"nginx_ingress_controller_ssl_certificate_expire_days"}, reg); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+
+	// the certificates of the previous sync are not exposed anymore
+	cm.SetSSLExpireDays(servers[:1])
+	want = `
+		# HELP nginx_ingress_controller_ssl_certificate_expire_days Number of days until the expiration of the certificates served by the hosts. 'type' is 'server', 'default' for the default certificate or 'ca' for the CA of the client certificates
+		# TYPE nginx_ingress_controller_ssl_certificate_expire_days gauge
+		nginx_ingress_controller_ssl_certificate_expire_days{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="_",namespace="",secret_name="",type="default"} 365
+	`
+	if err := GatherAndCompare(cm, want, []string{"nginx_ingress_controller_ssl_certificate_expire_days"}, reg); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+
+	reg.Unregister(cm)
+}
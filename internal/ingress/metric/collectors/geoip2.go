@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// GeoIP2Collector exposes the age of the GeoIP2 databases, read from the disk
+// on every scrape
+type GeoIP2Collector struct {
+	prometheus.Collector
+
+	buildTimestamp  *prometheus.Desc
+	age             *prometheus.Desc
+	updateTimestamp *prometheus.Desc
+
+	getDatabases func() []nginx.GeoIP2Database
+	now          func() time.Time
+}
+
+// NewGeoIP2Collector creates a new GeoIP2Collector instance
+func NewGeoIP2Collector(pod, namespace, class string) *GeoIP2Collector {
+	constLabels := prometheus.Labels{
+		"controller_namespace": namespace,
+		"controller_class":     class,
+		"controller_pod":       pod,
+	}
+
+	return &GeoIP2Collector{
+		buildTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "geoip2_database_build_timestamp_seconds"),
+			"Unix time the GeoIP2 database of the edition was built",
+			[]string{"edition"}, constLabels),
+
+		age: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "geoip2_database_age_seconds"),
+			"Time since the GeoIP2 database of the edition was built",
+			[]string{"edition"}, constLabels),
+
+		updateTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "geoip2_database_update_timestamp_seconds"),
+			"Unix time the GeoIP2 database of the edition was last written to disk",
+			[]string{"edition"}, constLabels),
+
+		getDatabases: nginx.GetGeoIP2Databases,
+		now:          time.Now,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *GeoIP2Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.buildTimestamp
+	ch <- c.age
+	ch <- c.updateTimestamp
+}
+
+// Collect implements prometheus.Collector
+func (c *GeoIP2Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, db := range c.getDatabases() {
+		age := c.now().Sub(time.Unix(db.BuildEpoch, 0)).Seconds()
+
+		ch <- prometheus.MustNewConstMetric(c.buildTimestamp, prometheus.GaugeValue, float64(db.BuildEpoch), db.Edition)
+		ch <- prometheus.MustNewConstMetric(c.age, prometheus.GaugeValue, age, db.Edition)
+		ch <- prometheus.MustNewConstMetric(c.updateTimestamp, prometheus.GaugeValue, float64(db.UpdatedAt), db.Edition)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+func TestGeoIP2Collector(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	gc := NewGeoIP2Collector("pod", "default", "nginx")
+	gc.now = func() time.Time { return now }
+	gc.getDatabases = func() []nginx.GeoIP2Database {
+		return []nginx.GeoIP2Database{
+			{
+				Edition:    "GeoLite2-ASN",
+				BuildEpoch: now.Add(-24 * time.Hour).Unix(),
+				UpdatedAt:  now.Add(-time.Hour).Unix(),
+			},
+			{
+				Edition:    "GeoLite2-City",
+				BuildEpoch: now.Add(-7 * 24 * time.Hour).Unix(),
+				UpdatedAt:  now.Add(-7 * 24 * time.Hour).Unix(),
+			},
+		}
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(gc); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+
+	want := `
+		# HELP nginx_ingress_controller_geoip2_database_age_seconds Time since the GeoIP2 database of the edition was built
+		# TYPE nginx_ingress_controller_geoip2_database_age_seconds gauge
+		nginx_ingress_controller_geoip2_database_age_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",edition="GeoLite2-ASN"} 86400
+		nginx_ingress_controller_geoip2_database_age_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",edition="GeoLite2-City"} 604800
+		# HELP nginx_ingress_controller_geoip2_database_build_timestamp_seconds Unix time the GeoIP2 database of the edition was built
+		# TYPE nginx_ingress_controller_geoip2_database_build_timestamp_seconds gauge
+		nginx_ingress_controller_geoip2_database_build_timestamp_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",edition="GeoLite2-ASN"} 1.6999136e+09
+		nginx_ingress_controller_geoip2_database_build_timestamp_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",edition="GeoLite2-City"} 1.6993952e+09
+		# HELP nginx_ingress_controller_geoip2_database_update_timestamp_seconds Unix time the GeoIP2 database of the edition was last written to disk
+		# TYPE nginx_ingress_controller_geoip2_database_update_timestamp_seconds gauge
+		nginx_ingress_controller_geoip2_database_update_timestamp_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",edition="GeoLite2-ASN"} 1.6999964e+09
+		nginx_ingress_controller_geoip2_database_update_timestamp_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",edition="GeoLite2-City"} 1.6993952e+09
+	`
+
+	metrics := []string{
+		"nginx_ingress_controller_geoip2_database_age_seconds",
+		"nginx_ingress_controller_geoip2_database_build_timestamp_seconds",
+		"nginx_ingress_controller_geoip2_database_update_timestamp_seconds",
+	}
+	if err := GatherAndCompare(gc, want, metrics, reg); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+
+	reg.Unregister(gc)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// OCSPCollector exposes the OCSP stapling status of the hosts, read from the
+// NGINX status server on every scrape
+type OCSPCollector struct {
+	prometheus.Collector
+
+	fetchSuccess  *prometheus.Desc
+	fetchFailures *prometheus.Desc
+	responseAge   *prometheus.Desc
+	nextUpdate    *prometheus.Desc
+
+	getStatus func() ([]nginx.OCSPStatus, error)
+	now       func() time.Time
+}
+
+// NewOCSPCollector creates a new OCSPCollector instance
+func NewOCSPCollector(pod, namespace, class string) *OCSPCollector {
+	constLabels := prometheus.Labels{
+		"controller_namespace": namespace,
+		"controller_class":     class,
+		"controller_pod":       pod,
+	}
+
+	return &OCSPCollector{
+		fetchSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "ocsp_fetch_success"),
+			"Whether the last OCSP fetch for the certificate of the host returned a valid response",
+			[]string{"host"}, constLabels),
+
+		fetchFailures: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "ocsp_fetch_failures"),
+			"Number of consecutive failed OCSP fetches for the certificate of the host",
+			[]string{"host"}, constLabels),
+
+		responseAge: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "ocsp_response_age_seconds"),
+			"Time since the OCSP response stapled for the host was produced",
+			[]string{"host"}, constLabels),
+
+		nextUpdate: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, "", "ocsp_next_update_timestamp_seconds"),
+			"Unix time the OCSP response stapled for the host expires",
+			[]string{"host"}, constLabels),
+
+		getStatus: nginx.GetOCSPStatus,
+		now:       time.Now,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *OCSPCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.fetchSuccess
+	ch <- c.fetchFailures
+	ch <- c.responseAge
+	ch <- c.nextUpdate
+}
+
+// Collect implements prometheus.Collector
+func (c *OCSPCollector) Collect(ch chan<- prometheus.Metric) {
+	status, err := c.getStatus()
+	if err != nil {
+		klog.Warningf("unexpected error obtaining the OCSP status: %v", err)
+		return
+	}
+
+	for i := range status {
+		s := &status[i]
+
+		success := 0.0
+		if s.Success {
+			success = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.fetchSuccess, prometheus.GaugeValue, success, s.Hostname)
+		ch <- prometheus.MustNewConstMetric(c.fetchFailures, prometheus.GaugeValue, float64(s.Failures), s.Hostname)
+
+		// no valid response was fetched yet
+		if s.ThisUpdate != 0 {
+			age := c.now().Sub(time.Unix(s.ThisUpdate, 0)).Seconds()
+			ch <- prometheus.MustNewConstMetric(c.responseAge, prometheus.GaugeValue, age, s.Hostname)
+		}
+		if s.NextUpdate != 0 {
+			ch <- prometheus.MustNewConstMetric(c.nextUpdate, prometheus.GaugeValue, float64(s.NextUpdate), s.Hostname)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+func TestOCSPCollector(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	oc := NewOCSPCollector("pod", "default", "nginx")
+	oc.now = func() time.Time { return now }
+	oc.getStatus = func() ([]nginx.OCSPStatus, error) {
+		return []nginx.OCSPStatus{
+			{
+				Hostname:   "good.example.com",
+				Success:    true,
+				FetchedAt:  now.Unix(),
+				ThisUpdate: now.Add(-time.Hour).Unix(),
+				NextUpdate: now.Add(24 * time.Hour).Unix(),
+			},
+			{
+				Hostname:   "stale.example.com",
+				Error:      "could not get OCSP response: timeout",
+				FetchedAt:  now.Unix(),
+				ThisUpdate: now.Add(-48 * time.Hour).Unix(),
+				Failures:   3,
+			},
+			{
+				Hostname:  "failing.example.com",
+				Error:     "OCSP response validation failed: certificate status \"revoked\"",
+				FetchedAt: now.Unix(),
+				Failures:  1,
+			},
+		}, nil
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(oc); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+
+	want := `
+		# HELP nginx_ingress_controller_ocsp_fetch_failures Number of consecutive failed OCSP fetches for the certificate of the host
+		# TYPE nginx_ingress_controller_ocsp_fetch_failures gauge
+		nginx_ingress_controller_ocsp_fetch_failures{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="failing.example.com"} 1
+		nginx_ingress_controller_ocsp_fetch_failures{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="good.example.com"} 0
+		nginx_ingress_controller_ocsp_fetch_failures{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="stale.example.com"} 3
+		# HELP nginx_ingress_controller_ocsp_fetch_success Whether the last OCSP fetch for the certificate of the host returned a valid response
+		# TYPE nginx_ingress_controller_ocsp_fetch_success gauge
+		nginx_ingress_controller_ocsp_fetch_success{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="failing.example.com"} 0
+		nginx_ingress_controller_ocsp_fetch_success{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="good.example.com"} 1
+		nginx_ingress_controller_ocsp_fetch_success{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="stale.example.com"} 0
+		# HELP nginx_ingress_controller_ocsp_next_update_timestamp_seconds Unix time the OCSP response stapled for the host expires
+		# TYPE nginx_ingress_controller_ocsp_next_update_timestamp_seconds gauge
+		nginx_ingress_controller_ocsp_next_update_timestamp_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="good.example.com"} 1.7000864e+09
+		# HELP nginx_ingress_controller_ocsp_response_age_seconds Time since the OCSP response stapled for the host was produced
+		# TYPE nginx_ingress_controller_ocsp_response_age_seconds gauge
+		nginx_ingress_controller_ocsp_response_age_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="good.example.com"} 3600
+		nginx_ingress_controller_ocsp_response_age_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="stale.example.com"} 172800
+	`
+
+	metrics := []string{
+		"nginx_ingress_controller_ocsp_fetch_failures",
+		"nginx_ingress_controller_ocsp_fetch_success",
+		"nginx_ingress_controller_ocsp_next_update_timestamp_seconds",
+		"nginx_ingress_controller_ocsp_response_age_seconds",
+	}
+	if err := GatherAndCompare(oc, want, metrics, reg); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+
+	reg.Unregister(oc)
+}
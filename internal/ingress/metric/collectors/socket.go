@@ -22,7 +22,9 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/prometheus/client_golang/prometheus"
@@ -49,6 +51,56 @@ type socketData struct {
 	Service      string  `json:"service"`
 	Canary       string  `json:"canary"`
 	Path         string  `json:"path"`
+
+	CanaryBackend string `json:"canaryBackend"`
+	Variant       string `json:"variant"`
+
+	UpstreamAddr string  `json:"upstreamAddr"`
+	EWMAScore    float64 `json:"ewmaScore"`
+
+	// TraceID is the trace ID of the request when tracing is enabled
+	TraceID string `json:"traceId"`
+
+	// Endpoints are the upstream attempts of the request when the endpoint
+	// metrics are enabled
+	Endpoints []endpointData `json:"endpoints"`
+
+	// Denial is the reason the request was denied by the ingress controller,
+	// empty when it was not denied
+	Denial string `json:"denial"`
+
+	// ClientBanned is true when the response of the request banned its client
+	ClientBanned bool `json:"clientBanned"`
+
+	// RequestViolation is the reason the request was denied by the strict
+	// request validation, empty when it was valid
+	RequestViolation string `json:"requestViolation"`
+
+	// Stream is set instead of the request fields for the sessions of the
+	// TCP and UDP services
+	Stream *streamData `json:"stream"`
+}
+
+// endpointData is an upstream attempt of a request
+type endpointData struct {
+	Address string `json:"address"`
+	Status  string `json:"status"`
+	// ConnectTime is -1 when the connection to the endpoint failed
+	ConnectTime  float64 `json:"connectTime"`
+	ResponseTime float64 `json:"responseTime"`
+}
+
+// streamData is a session of a TCP or UDP service
+type streamData struct {
+	Protocol  string `json:"protocol"`
+	Port      string `json:"port"`
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Status    string `json:"status"`
+
+	BytesReceived float64 `json:"bytesReceived"`
+	BytesSent     float64 `json:"bytesSent"`
+	SessionTime   float64 `json:"sessionTime"`
 }
 
 // HistogramBuckets allow customizing prometheus histogram buckets values
@@ -56,8 +108,23 @@ type HistogramBuckets struct {
 	TimeBuckets   []float64
 	LengthBuckets []float64
 	SizeBuckets   []float64
+
+	// NativeHistogramBucketFactor also emits the duration and size
+	// histograms as native histograms with this growth factor between
+	// buckets, 0 disabling them
+	NativeHistogramBucketFactor float64
 }
 
+const (
+	// nativeHistogramMaxBuckets is the maximum number of buckets of the
+	// native histograms, their resolution is reduced beyond
+	nativeHistogramMaxBuckets = 160
+	// nativeHistogramMinResetDuration is the minimum duration between the
+	// resets of the native histograms exceeding their maximum number of
+	// buckets
+	nativeHistogramMinResetDuration = time.Hour
+)
+
 type metricMapping map[string]prometheus.Collector
 
 // SocketCollector stores prometheus metrics and ingress meta-data
@@ -76,6 +143,25 @@ type SocketCollector struct {
 
 	requests *prometheus.CounterVec
 
+	deniedRequests *prometheus.CounterVec
+	clientBans     *prometheus.CounterVec
+
+	invalidRequests *prometheus.CounterVec
+
+	canaryRequests    *prometheus.CounterVec
+	canaryRequestTime *prometheus.HistogramVec
+
+	ewmaScore *prometheus.GaugeVec
+
+	endpointResponseTime    *prometheus.HistogramVec
+	endpointResponses       *prometheus.CounterVec
+	endpointConnectFailures *prometheus.CounterVec
+
+	streamConnections   *prometheus.CounterVec
+	streamBytesReceived *prometheus.CounterVec
+	streamBytesSent     *prometheus.CounterVec
+	streamSessionTime   *prometheus.HistogramVec
+
 	listener net.Listener
 
 	metricMapping metricMapping
@@ -83,7 +169,31 @@ type SocketCollector struct {
 	hosts sets.Set[string]
 
 	metricsPerHost      bool
+	metricsPerPath      bool
 	reportStatusClasses bool
+
+	// maxPaths caps the distinct paths of the metrics of an Ingress, 0
+	// meaning no limit
+	maxPaths  int
+	pathsLock sync.Mutex
+	// paths are the paths of the metrics, by namespace/ingress
+	paths map[string]sets.Set[string]
+
+	labelsLock sync.Mutex
+	// dropLabels are the labels whose values are dropped
+	dropLabels sets.Set[string]
+	// cappedLabels are the labels whose distinct values are capped
+	cappedLabels sets.Set[string]
+	// maxLabelValues caps the distinct values of the limited labels, 0
+	// meaning no limit
+	maxLabelValues int
+	// labelValues are the values of the limited labels, by label, with the
+	// namespace/ingress of the metrics using them
+	labelValues map[string]map[string]sets.Set[string]
+
+	endpointsLock sync.Mutex
+	// endpoints are the values of the endpoint label of the metrics
+	endpoints sets.Set[string]
 }
 
 var requestTags = []string{
@@ -98,13 +208,98 @@ var requestTags = []string{
 	"canary",
 }
 
+// overflowLabelValue is the value of the labels of the requests beyond the
+// maximum number of values of the label
+const overflowLabelValue = "overflow"
+
+// otherPath is the path label of the requests of the paths of an Ingress
+// beyond the maximum number of paths
+const otherPath = "other"
+
+// deniedTags are the labels of the requests denied by the ingress controller.
+// 'reason' is 'limit-req', 'limit-conn', 'global-throttle', 'source-range',
+// 'auth', 'auto-ban', 'csrf' or 'invalid-request'.
+var deniedTags = []string{
+	"namespace",
+	"ingress",
+	"reason",
+}
+
+// banTags are the labels of the clients banned by the automatic banning, the
+// Ingress of the request whose response banned them
+var banTags = []string{
+	"namespace",
+	"ingress",
+}
+
+// invalidRequestTags are the labels of the requests denied by the strict
+// request validation. 'violation' is 'length-conflict', 'line-folding',
+// 'invalid-header-name', 'absolute-uri-mismatch' or 'too-many-headers'.
+var invalidRequestTags = []string{
+	"namespace",
+	"ingress",
+	"violation",
+}
+
+// canaryTags are the labels of the metrics comparing the stable and canary
+// variants of a location. They are only reported for locations with a canary
+// to avoid increasing the cardinality of the rest of the request metrics.
+var canaryTags = []string{
+	"status",
+
+	"namespace",
+	"ingress",
+	"service",
+	"canary",
+	"variant",
+}
+
+// ewmaTags are the labels of the ewma score of the endpoints. The metric is
+// only reported when enable-ewma-metrics is set, as it has one series per endpoint.
+var ewmaTags = []string{
+	"namespace",
+	"ingress",
+	"service",
+	"endpoint",
+}
+
+// endpointTags are the labels of the metrics of the upstream attempts. The
+// metrics are only reported when enable-endpoint-metrics is set, as they have
+// series per endpoint.
+var endpointTags = []string{
+	"namespace",
+	"ingress",
+	"service",
+	"endpoint",
+}
+
+// endpointStatusTags are the labels of the responses of the endpoints
+var endpointStatusTags = append(append([]string{}, endpointTags...), "status")
+
+// streamTags are the labels of the metrics of the sessions of the TCP and UDP
+// services, by exposed port and backing service
+var streamTags = []string{
+	"protocol",
+	"port",
+	"namespace",
+	"service",
+}
+
+// streamStatusTags are the labels of the connections of the TCP and UDP
+// services
+var streamStatusTags = append(append([]string{}, streamTags...), "status")
+
+// streamSessionBuckets are the buckets of the duration of the sessions of the
+// TCP and UDP services, which are much longer than the requests
+var streamSessionBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600}
+
 // DefObjectives was removed in https://github.com/prometheus/client_golang/pull/262
 // updating the library to latest version changed the output of the metrics
 var defObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
 
 // NewSocketCollector creates a new SocketCollector instance using
 // the ingress watch namespace and class used by the controller
-func NewSocketCollector(pod, namespace, class string, metricsPerHost, reportStatusClasses bool, buckets HistogramBuckets, excludeMetrics []string) (*SocketCollector, error) {
+func NewSocketCollector(pod, namespace, class string, metricsPerHost, metricsPerPath, reportStatusClasses bool, maxPaths int, buckets HistogramBuckets, excludeMetrics []string) (*SocketCollector, error) {
 	socket := "/tmp/nginx/prometheus-nginx.socket"
 	// unix sockets must be unlink()ed before being used
 	//nolint:errcheck // Ignore unlink error
@@ -127,6 +322,9 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost, reportStat
 	}
 
 	requestTags := requestTags
+	if !metricsPerPath {
+		requestTags = withoutTag(requestTags, "path")
+	}
 	if metricsPerHost {
 		requestTags = append(requestTags, "host")
 	}
@@ -145,80 +343,88 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost, reportStat
 		listener: listener,
 
 		metricsPerHost:      metricsPerHost,
+		metricsPerPath:      metricsPerPath,
 		reportStatusClasses: reportStatusClasses,
 
+		maxPaths: maxPaths,
+		paths:    map[string]sets.Set[string]{},
+
+		dropLabels:   sets.New[string](),
+		cappedLabels: sets.New[string](),
+		labelValues:  map[string]map[string]sets.Set[string]{},
+
 		connectTime: histogramMetric(
-			&prometheus.HistogramOpts{
+			withNativeHistogram(&prometheus.HistogramOpts{
 				Name:        "connect_duration_seconds",
 				Help:        "The time spent on establishing a connection with the upstream server",
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 				Buckets:     buckets.TimeBuckets,
-			},
+			}, buckets),
 			requestTags,
 			em,
 			mm,
 		),
 
 		headerTime: histogramMetric(
-			&prometheus.HistogramOpts{
+			withNativeHistogram(&prometheus.HistogramOpts{
 				Name:        "header_duration_seconds",
 				Help:        "The time spent on receiving first header from the upstream server",
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 				Buckets:     buckets.TimeBuckets,
-			},
+			}, buckets),
 			requestTags,
 			em,
 			mm,
 		),
 		responseTime: histogramMetric(
-			&prometheus.HistogramOpts{
+			withNativeHistogram(&prometheus.HistogramOpts{
 				Name:        "response_duration_seconds",
 				Help:        "The time spent on receiving the response from the upstream server",
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 				Buckets:     buckets.TimeBuckets,
-			},
+			}, buckets),
 			requestTags,
 			em,
 			mm,
 		),
 
 		requestTime: histogramMetric(
-			&prometheus.HistogramOpts{
+			withNativeHistogram(&prometheus.HistogramOpts{
 				Name:        "request_duration_seconds",
 				Help:        "The request processing time in milliseconds",
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 				Buckets:     buckets.TimeBuckets,
-			},
+			}, buckets),
 			requestTags,
 			em,
 			mm,
 		),
 
 		responseLength: histogramMetric(
-			&prometheus.HistogramOpts{
+			withNativeHistogram(&prometheus.HistogramOpts{
 				Name:        "response_size",
 				Help:        "The response length (including request line, header, and request body)",
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 				Buckets:     buckets.LengthBuckets,
-			},
+			}, buckets),
 			requestTags,
 			em,
 			mm,
 		),
 
 		requestLength: histogramMetric(
-			&prometheus.HistogramOpts{
+			withNativeHistogram(&prometheus.HistogramOpts{
 				Name:        "request_size",
 				Help:        "The request length (including request line, header, and request body)",
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 				Buckets:     buckets.LengthBuckets,
-			},
+			}, buckets),
 			requestTags,
 			em,
 			mm,
@@ -236,6 +442,42 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost, reportStat
 			mm,
 		),
 
+		deniedRequests: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "denied_requests",
+				Help:        "The total number of client requests denied by the rate limits, the source ranges or the authentication of the Ingress",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			deniedTags,
+			em,
+			mm,
+		),
+
+		clientBans: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "client_bans",
+				Help:        "The total number of clients banned by the automatic banning, by Ingress of the request whose response banned them",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			banTags,
+			em,
+			mm,
+		),
+
+		invalidRequests: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "invalid_requests",
+				Help:        "The total number of client requests denied by the strict request validation, by violation",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			invalidRequestTags,
+			em,
+			mm,
+		),
+
 		bytesSent: histogramMetric(
 			&prometheus.HistogramOpts{
 				Name:        "bytes_sent",
@@ -249,6 +491,129 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost, reportStat
 			mm,
 		),
 
+		canaryRequests: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "canary_requests",
+				Help:        "The total number of client requests to locations with a canary, by variant",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			canaryTags,
+			em,
+			mm,
+		),
+
+		canaryRequestTime: histogramMetric(
+			&prometheus.HistogramOpts{
+				Name:        "canary_request_duration_seconds",
+				Help:        "The request processing time of locations with a canary, by variant",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+				Buckets:     buckets.TimeBuckets,
+			},
+			canaryTags,
+			em,
+			mm,
+		),
+
+		ewmaScore: gaugeMetric(
+			&prometheus.GaugeOpts{
+				Name:        "ewma_score",
+				Help:        "The ewma score of the endpoint, in seconds, when it was last picked by the ewma load balancer",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			ewmaTags,
+			em,
+			mm,
+		),
+
+		endpointResponseTime: histogramMetric(
+			withNativeHistogram(&prometheus.HistogramOpts{
+				Name:        "upstream_endpoint_response_duration_seconds",
+				Help:        "The time spent on receiving the response from the endpoint, per upstream attempt",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+				Buckets:     buckets.TimeBuckets,
+			}, buckets),
+			endpointTags,
+			em,
+			mm,
+		),
+
+		endpointResponses: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "upstream_endpoint_responses",
+				Help:        "The total number of responses of the endpoint, per upstream attempt",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			endpointStatusTags,
+			em,
+			mm,
+		),
+
+		endpointConnectFailures: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "upstream_endpoint_connect_failures",
+				Help:        "The total number of failed connections to the endpoint",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			endpointTags,
+			em,
+			mm,
+		),
+
+		streamConnections: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "stream_connections",
+				Help:        "The total number of connections of the TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			streamStatusTags,
+			em,
+			mm,
+		),
+
+		streamBytesReceived: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "stream_bytes_received",
+				Help:        "The total number of bytes received from the clients of the TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			streamTags,
+			em,
+			mm,
+		),
+
+		streamBytesSent: counterMetric(
+			&prometheus.CounterOpts{
+				Name:        "stream_bytes_sent",
+				Help:        "The total number of bytes sent to the clients of the TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			streamTags,
+			em,
+			mm,
+		),
+
+		streamSessionTime: histogramMetric(
+			withNativeHistogram(&prometheus.HistogramOpts{
+				Name:        "stream_session_duration_seconds",
+				Help:        "The duration of the sessions of the TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+				Buckets:     streamSessionBuckets,
+			}, buckets),
+			streamTags,
+			em,
+			mm,
+		),
+
 		upstreamLatency: summaryMetric(
 			&prometheus.SummaryOpts{
 				Name:        "ingress_upstream_latency_seconds",
@@ -267,6 +632,28 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost, reportStat
 	return sc, nil
 }
 
+// withNativeHistogram also emits the histogram as a native histogram when
+// the bucket factor of the native histograms is set
+func withNativeHistogram(opts *prometheus.HistogramOpts, buckets HistogramBuckets) *prometheus.HistogramOpts {
+	if buckets.NativeHistogramBucketFactor > 1 {
+		opts.NativeHistogramBucketFactor = buckets.NativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = nativeHistogramMaxBuckets
+		opts.NativeHistogramMinResetDuration = nativeHistogramMinResetDuration
+	}
+	return opts
+}
+
+// withoutTag returns a copy of the tags without the tag
+func withoutTag(tags []string, tag string) []string {
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t != tag {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
 func containsMetric(excludeMetrics map[string]struct{}, name string) bool {
 	if _, ok := excludeMetrics[name]; ok {
 		klog.V(3).InfoS("Skipping metric", "metric", name)
@@ -299,6 +686,18 @@ func counterMetric(opts *prometheus.CounterOpts, requestTags []string, excludeMe
 	return m
 }
 
+func gaugeMetric(opts *prometheus.GaugeOpts, requestTags []string, excludeMetrics map[string]struct{}, metricMapping metricMapping) *prometheus.GaugeVec {
+	if containsMetric(excludeMetrics, opts.Name) {
+		return nil
+	}
+	m := prometheus.NewGaugeVec(
+		*opts,
+		requestTags,
+	)
+	metricMapping[prometheus.BuildFQName(PrometheusNamespace, "", opts.Name)] = m
+	return m
+}
+
 func histogramMetric(opts *prometheus.HistogramOpts, requestTags []string, excludeMetrics map[string]struct{}, metricMapping metricMapping) *prometheus.HistogramVec {
 	if containsMetric(excludeMetrics, opts.Name) {
 		return nil
@@ -324,6 +723,11 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 
 	for i := range statsBatch {
 		stats := &statsBatch[i]
+		if stats.Stream != nil {
+			sc.observeStream(stats.Stream)
+			continue
+		}
+
 		if sc.metricsPerHost && !sc.hosts.Has(stats.Host) {
 			klog.V(3).InfoS("Skipping metric for host not being served", "host", stats.Host)
 			continue
@@ -353,6 +757,14 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			"method":    stats.Method,
 			"path":      stats.Path,
 		}
+		if sc.metricsPerPath {
+			path := sc.metricPath(stats)
+			requestLabels["path"] = path
+			collectorLabels["path"] = path
+		} else {
+			delete(requestLabels, "path")
+			delete(collectorLabels, "path")
+		}
 		if sc.metricsPerHost {
 			requestLabels["host"] = stats.Host
 			collectorLabels["host"] = stats.Host
@@ -365,6 +777,8 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			"canary":    stats.Canary,
 		}
 
+		sc.limitLabels(requestLabels, collectorLabels, latencyLabels)
+
 		if sc.requests != nil {
 			requestsMetric, err := sc.requests.GetMetricWith(collectorLabels)
 			if err != nil {
@@ -374,13 +788,86 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			}
 		}
 
+		if stats.Denial != "" && sc.deniedRequests != nil {
+			deniedLabels := prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+				"reason":    stats.Denial,
+			}
+			sc.limitLabels(deniedLabels)
+
+			deniedRequestsMetric, err := sc.deniedRequests.GetMetricWith(deniedLabels)
+			if err != nil {
+				klog.ErrorS(err, "Error fetching denied requests metric")
+			} else {
+				deniedRequestsMetric.Inc()
+			}
+		}
+
+		if stats.ClientBanned && sc.clientBans != nil {
+			banLabels := prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+			}
+			sc.limitLabels(banLabels)
+
+			clientBansMetric, err := sc.clientBans.GetMetricWith(banLabels)
+			if err != nil {
+				klog.ErrorS(err, "Error fetching client bans metric")
+			} else {
+				clientBansMetric.Inc()
+			}
+		}
+
+		if stats.RequestViolation != "" && sc.invalidRequests != nil {
+			invalidLabels := prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+				"violation": stats.RequestViolation,
+			}
+			sc.limitLabels(invalidLabels)
+
+			invalidRequestsMetric, err := sc.invalidRequests.GetMetricWith(invalidLabels)
+			if err != nil {
+				klog.ErrorS(err, "Error fetching invalid requests metric")
+			} else {
+				invalidRequestsMetric.Inc()
+			}
+		}
+
+		if stats.Variant != "" && stats.Variant != "-" {
+			sc.observeCanary(stats)
+		}
+
+		for j := range stats.Endpoints {
+			sc.observeEndpoint(stats, &stats.Endpoints[j])
+		}
+
+		if stats.UpstreamAddr != "" && sc.ewmaScore != nil {
+			ewmaLabels := prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+				"service":   stats.Service,
+				"endpoint":  stats.UpstreamAddr,
+			}
+			sc.limitLabels(ewmaLabels)
+			sc.trackEndpoint(ewmaLabels)
+
+			ewmaScoreMetric, err := sc.ewmaScore.GetMetricWith(ewmaLabels)
+			if err != nil {
+				klog.ErrorS(err, "Error fetching ewma score metric")
+			} else {
+				ewmaScoreMetric.Set(stats.EWMAScore)
+			}
+		}
+
 		if stats.Latency != -1 {
 			if sc.connectTime != nil {
 				connectTimeMetric, err := sc.connectTime.GetMetricWith(requestLabels)
 				if err != nil {
 					klog.ErrorS(err, "Error fetching connect time metric")
 				} else {
-					connectTimeMetric.Observe(stats.Latency)
+					observe(connectTimeMetric, stats.Latency, stats.TraceID)
 				}
 			}
 
@@ -399,7 +886,7 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			if err != nil {
 				klog.ErrorS(err, "Error fetching header time metric")
 			} else {
-				headerTimeMetric.Observe(stats.HeaderTime)
+				observe(headerTimeMetric, stats.HeaderTime, stats.TraceID)
 			}
 		}
 
@@ -408,7 +895,7 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			if err != nil {
 				klog.ErrorS(err, "Error fetching request duration metric")
 			} else {
-				requestTimeMetric.Observe(stats.RequestTime)
+				observe(requestTimeMetric, stats.RequestTime, stats.TraceID)
 			}
 		}
 
@@ -426,7 +913,7 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			if err != nil {
 				klog.ErrorS(err, "Error fetching upstream response time metric")
 			} else {
-				responseTimeMetric.Observe(stats.ResponseTime)
+				observe(responseTimeMetric, stats.ResponseTime, stats.TraceID)
 			}
 		}
 
@@ -452,6 +939,302 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 	}
 }
 
+// SetLabelLimits sets the labels whose values are dropped, the labels whose
+// distinct values are capped and the maximum number of their values. The
+// values already seen are forgotten when the limits change.
+func (sc *SocketCollector) SetLabelLimits(dropLabels, limitLabels []string, maxLabelValues int) {
+	sc.labelsLock.Lock()
+	defer sc.labelsLock.Unlock()
+
+	drop := sets.New[string](dropLabels...)
+	limit := sets.New[string](limitLabels...)
+	if drop.Equal(sc.dropLabels) && limit.Equal(sc.cappedLabels) && maxLabelValues == sc.maxLabelValues {
+		return
+	}
+
+	sc.dropLabels = drop
+	sc.cappedLabels = limit
+	sc.maxLabelValues = maxLabelValues
+	sc.labelValues = map[string]map[string]sets.Set[string]{}
+}
+
+// limitLabels drops the values of the dropped labels, and replaces the values
+// of the limited labels beyond their maximum number of values with
+// overflowLabelValue
+func (sc *SocketCollector) limitLabels(labelSets ...prometheus.Labels) {
+	sc.labelsLock.Lock()
+	defer sc.labelsLock.Unlock()
+
+	for _, labels := range labelSets {
+		// the values are released when the metrics of the Ingress are removed
+		ingress := fmt.Sprintf("%v/%v", labels["namespace"], labels["ingress"])
+
+		for name, value := range labels {
+			if sc.dropLabels.Has(name) {
+				labels[name] = ""
+				continue
+			}
+
+			if sc.maxLabelValues <= 0 || !sc.cappedLabels.Has(name) {
+				continue
+			}
+
+			values, ok := sc.labelValues[name]
+			if !ok {
+				values = map[string]sets.Set[string]{}
+				sc.labelValues[name] = values
+			}
+			if ingresses, ok := values[value]; ok {
+				ingresses.Insert(ingress)
+				continue
+			}
+			if len(values) >= sc.maxLabelValues {
+				labels[name] = overflowLabelValue
+				continue
+			}
+			values[value] = sets.New[string](ingress)
+		}
+	}
+}
+
+// releaseLabelValues forgets the values of the limited labels only used by
+// the metrics of the removed Ingresses
+func (sc *SocketCollector) releaseLabelValues(ingresses []string) {
+	sc.labelsLock.Lock()
+	defer sc.labelsLock.Unlock()
+
+	for _, values := range sc.labelValues {
+		for value, valueIngresses := range values {
+			valueIngresses.Delete(ingresses...)
+			if valueIngresses.Len() == 0 {
+				delete(values, value)
+			}
+		}
+	}
+}
+
+// observe records the value in a histogram, with an exemplar carrying the
+// trace ID of the request when it is traced
+func observe(o prometheus.Observer, value float64, traceID string) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && traceID != "" {
+		eo.ObserveWithExemplar(value, prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	o.Observe(value)
+}
+
+// metricPath returns the path label of the request, the paths of an Ingress
+// beyond the first maxPaths being reported as otherPath
+func (sc *SocketCollector) metricPath(stats *socketData) string {
+	if sc.maxPaths <= 0 {
+		return stats.Path
+	}
+
+	sc.pathsLock.Lock()
+	defer sc.pathsLock.Unlock()
+
+	key := fmt.Sprintf("%v/%v", stats.Namespace, stats.Ingress)
+	paths, ok := sc.paths[key]
+	if !ok {
+		paths = sets.New[string]()
+		sc.paths[key] = paths
+	}
+
+	if paths.Has(stats.Path) {
+		return stats.Path
+	}
+	if paths.Len() >= sc.maxPaths {
+		return otherPath
+	}
+
+	paths.Insert(stats.Path)
+	return stats.Path
+}
+
+// observeEndpoint updates the metrics of an upstream attempt of the request
+func (sc *SocketCollector) observeEndpoint(stats *socketData, endpoint *endpointData) {
+	labels := prometheus.Labels{
+		"namespace": stats.Namespace,
+		"ingress":   stats.Ingress,
+		"service":   stats.Service,
+		"endpoint":  endpoint.Address,
+	}
+	sc.limitLabels(labels)
+	sc.trackEndpoint(labels)
+
+	if endpoint.ConnectTime == -1 {
+		if sc.endpointConnectFailures != nil {
+			connectFailuresMetric, err := sc.endpointConnectFailures.GetMetricWith(labels)
+			if err != nil {
+				klog.ErrorS(err, "Error fetching endpoint connect failures metric")
+			} else {
+				connectFailuresMetric.Inc()
+			}
+		}
+		return
+	}
+
+	if endpoint.ResponseTime != -1 && sc.endpointResponseTime != nil {
+		responseTimeMetric, err := sc.endpointResponseTime.GetMetricWith(labels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching endpoint response time metric")
+		} else {
+			observe(responseTimeMetric, endpoint.ResponseTime, stats.TraceID)
+		}
+	}
+
+	if sc.endpointResponses != nil {
+		status := endpoint.Status
+		if sc.reportStatusClasses && status != "" && status != "-" {
+			status = fmt.Sprintf("%cxx", status[0])
+		}
+
+		statusLabels := prometheus.Labels{"status": status}
+		sc.limitLabels(statusLabels)
+		for name, value := range labels {
+			statusLabels[name] = value
+		}
+
+		responsesMetric, err := sc.endpointResponses.GetMetricWith(statusLabels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching endpoint responses metric")
+		} else {
+			responsesMetric.Inc()
+		}
+	}
+}
+
+// trackEndpoint records the endpoint of the metrics, so their series are
+// deleted when the endpoint leaves the backends
+func (sc *SocketCollector) trackEndpoint(labels prometheus.Labels) {
+	sc.endpointsLock.Lock()
+	defer sc.endpointsLock.Unlock()
+
+	if sc.endpoints == nil {
+		sc.endpoints = sets.New[string]()
+	}
+	sc.endpoints.Insert(labels["endpoint"])
+}
+
+// SetEndpoints deletes the series of the metrics of the endpoints which are
+// not part of the backends anymore
+func (sc *SocketCollector) SetEndpoints(endpoints sets.Set[string]) {
+	sc.endpointsLock.Lock()
+	defer sc.endpointsLock.Unlock()
+
+	for endpoint := range sc.endpoints {
+		if endpoints.Has(endpoint) || endpoint == overflowLabelValue || endpoint == "" {
+			continue
+		}
+
+		klog.V(2).InfoS("Removing the metrics of the endpoint", "endpoint", endpoint)
+		labels := prometheus.Labels{"endpoint": endpoint}
+		if sc.ewmaScore != nil {
+			sc.ewmaScore.DeletePartialMatch(labels)
+		}
+		if sc.endpointResponseTime != nil {
+			sc.endpointResponseTime.DeletePartialMatch(labels)
+		}
+		if sc.endpointResponses != nil {
+			sc.endpointResponses.DeletePartialMatch(labels)
+		}
+		if sc.endpointConnectFailures != nil {
+			sc.endpointConnectFailures.DeletePartialMatch(labels)
+		}
+		sc.endpoints.Delete(endpoint)
+
+		sc.labelsLock.Lock()
+		if values, ok := sc.labelValues["endpoint"]; ok {
+			delete(values, endpoint)
+		}
+		sc.labelsLock.Unlock()
+	}
+}
+
+// observeStream updates the metrics of a session of a TCP or UDP service
+func (sc *SocketCollector) observeStream(stream *streamData) {
+	labels := prometheus.Labels{
+		"protocol":  stream.Protocol,
+		"port":      stream.Port,
+		"namespace": stream.Namespace,
+		"service":   stream.Service,
+	}
+	sc.limitLabels(labels)
+
+	if sc.streamConnections != nil {
+		statusLabels := prometheus.Labels{"status": stream.Status}
+		sc.limitLabels(statusLabels)
+		for name, value := range labels {
+			statusLabels[name] = value
+		}
+
+		connectionsMetric, err := sc.streamConnections.GetMetricWith(statusLabels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching stream connections metric")
+		} else {
+			connectionsMetric.Inc()
+		}
+	}
+
+	if stream.BytesReceived >= 0 && sc.streamBytesReceived != nil {
+		bytesReceivedMetric, err := sc.streamBytesReceived.GetMetricWith(labels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching stream bytes received metric")
+		} else {
+			bytesReceivedMetric.Add(stream.BytesReceived)
+		}
+	}
+
+	if stream.BytesSent >= 0 && sc.streamBytesSent != nil {
+		bytesSentMetric, err := sc.streamBytesSent.GetMetricWith(labels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching stream bytes sent metric")
+		} else {
+			bytesSentMetric.Add(stream.BytesSent)
+		}
+	}
+
+	if stream.SessionTime != -1 && sc.streamSessionTime != nil {
+		sessionTimeMetric, err := sc.streamSessionTime.GetMetricWith(labels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching stream session duration metric")
+		} else {
+			sessionTimeMetric.Observe(stream.SessionTime)
+		}
+	}
+}
+
+// observeCanary updates the metrics comparing the stable and canary variants
+func (sc *SocketCollector) observeCanary(stats *socketData) {
+	canaryLabels := prometheus.Labels{
+		"status":    stats.Status,
+		"namespace": stats.Namespace,
+		"ingress":   stats.Ingress,
+		"service":   stats.Service,
+		"canary":    stats.CanaryBackend,
+		"variant":   stats.Variant,
+	}
+	sc.limitLabels(canaryLabels)
+
+	if sc.canaryRequests != nil {
+		canaryRequestsMetric, err := sc.canaryRequests.GetMetricWith(canaryLabels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching canary requests metric")
+		} else {
+			canaryRequestsMetric.Inc()
+		}
+	}
+
+	if stats.RequestTime != -1 && sc.canaryRequestTime != nil {
+		canaryRequestTimeMetric, err := sc.canaryRequestTime.GetMetricWith(canaryLabels)
+		if err != nil {
+			klog.ErrorS(err, "Error fetching canary request duration metric")
+		} else {
+			observe(canaryRequestTimeMetric, stats.RequestTime, stats.TraceID)
+		}
+	}
+}
+
 // Start listen for connections in the unix socket and spawns a goroutine to process the content
 func (sc *SocketCollector) Start() {
 	for {
@@ -481,6 +1264,13 @@ func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus
 
 	// 1. remove metrics of removed ingresses
 	klog.V(2).InfoS("removing metrics", "ingresses", ingresses)
+	sc.pathsLock.Lock()
+	for _, ing := range ingresses {
+		delete(sc.paths, ing)
+	}
+	sc.pathsLock.Unlock()
+	sc.releaseLabelValues(ingresses)
+
 	for _, mf := range mfs {
 		metricName := mf.GetName()
 		metric, ok := sc.metricMapping[metricName]
@@ -535,6 +1325,12 @@ func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus
 					klog.V(2).InfoS("metric not removed", "name", metricName, "ingress", ingKey, "labels", labels)
 				}
 			}
+
+			if g, ok := metric.(*prometheus.GaugeVec); ok {
+				if removed := g.Delete(labels); !removed {
+					klog.V(2).InfoS("metric not removed", "name", metricName, "ingress", ingKey, "labels", labels)
+				}
+			}
 		}
 	}
 }
@@ -19,6 +19,7 @@ package collectors
 import (
 	"fmt"
 	"net"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -73,14 +74,10 @@ func TestNewUDPLogListener(t *testing.T) {
 }
 
 func TestCollector(t *testing.T) {
-	buckets := struct {
-		TimeBuckets   []float64
-		LengthBuckets []float64
-		SizeBuckets   []float64
-	}{
-		prometheus.DefBuckets,
-		prometheus.LinearBuckets(10, 10, 10),
-		prometheus.ExponentialBuckets(10, 10, 7),
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
 	}
 
 	cases := []struct {
@@ -275,6 +272,105 @@ func TestCollector(t *testing.T) {
 			`,
 		},
 
+		{
+			name: "valid metric objects with a canary variant should update canary metrics",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/admin",
+				"requestTime":60.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"canary":"",
+				"canaryBackend":"test-app-production-test-app-canary-80",
+				"variant":"stable"
+			},{
+				"host":"testshop.com",
+				"status":"500",
+				"method":"GET",
+				"path":"/admin",
+				"requestTime":60.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"canary":"test-app-production-test-app-canary-80",
+				"canaryBackend":"test-app-production-test-app-canary-80",
+				"variant":"canary"
+			},{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/",
+				"requestTime":60.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"canary":"",
+				"canaryBackend":"-",
+				"variant":"-"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_canary_requests"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_canary_requests The total number of client requests to locations with a canary, by variant
+				# TYPE nginx_ingress_controller_canary_requests counter
+				nginx_ingress_controller_canary_requests{canary="test-app-production-test-app-canary-80",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app",status="200",variant="stable"} 1
+				nginx_ingress_controller_canary_requests{canary="test-app-production-test-app-canary-80",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app",status="500",variant="canary"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+
+		{
+			name: "valid metric objects with an ewma score should update the ewma score metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/admin",
+				"requestTime":60.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"canary":"",
+				"upstreamAddr":"10.0.0.1:8080",
+				"ewmaScore":0.5
+			},{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/admin",
+				"requestTime":60.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"canary":"",
+				"upstreamAddr":"10.0.0.1:8080",
+				"ewmaScore":0.25
+			},{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/",
+				"requestTime":60.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"canary":""
+			}]`},
+			metrics: []string{"nginx_ingress_controller_ewma_score"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_ewma_score The ewma score of the endpoint, in seconds, when it was last picked by the ewma load balancer
+				# TYPE nginx_ingress_controller_ewma_score gauge
+				nginx_ingress_controller_ewma_score{controller_class="ingress",controller_namespace="default",controller_pod="pod",endpoint="10.0.0.1:8080",ingress="web-yml",namespace="test-app-production",service="test-app"} 0.25
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+
 		{
 			name: "multiple messages should increase prometheus metric by two",
 			data: []string{`[{
@@ -594,7 +690,7 @@ func TestCollector(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			registry := prometheus.NewPedanticRegistry()
 
-			sc, err := NewSocketCollector("pod", "default", "ingress", true, c.useStatusClasses, buckets, c.excludeMetrics)
+			sc, err := NewSocketCollector("pod", "default", "ingress", true, true, c.useStatusClasses, 0, buckets, c.excludeMetrics)
 			if err != nil {
 				t.Errorf("%v: unexpected error creating new SocketCollector: %v", c.name, err)
 			}
@@ -628,3 +724,470 @@ func TestCollector(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectorMaxPaths(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 2, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	for _, path := range []string{"/a", "/b", "/c", "/a", "/d"} {
+		sc.handleMessage([]byte(fmt.Sprintf(`[{"status":"200","method":"GET","path":%q,"namespace":"default","ingress":"api","service":"api","canary":"","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1}]`, path)))
+	}
+
+	want := `
+		# HELP nginx_ingress_controller_requests The total number of client requests
+		# TYPE nginx_ingress_controller_requests counter
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",method="GET",namespace="default",path="/a",service="api",status="200"} 2
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",method="GET",namespace="default",path="/b",service="api",status="200"} 1
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",method="GET",namespace="default",path="other",service="api",status="200"} 2
+	`
+	if err := GatherAndCompare(sc, want, []string{"nginx_ingress_controller_requests"}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorExemplars(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"status":"200","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","canary":"","requestTime":0.2,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}]`))
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error gathering metrics: %v", err)
+	}
+
+	var traceIDs []string
+	for _, mf := range mfs {
+		if mf.GetName() != "nginx_ingress_controller_request_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, b := range m.GetHistogram().GetBucket() {
+				if e := b.GetExemplar(); e != nil {
+					for _, l := range e.GetLabel() {
+						traceIDs = append(traceIDs, l.GetName()+"="+l.GetValue())
+					}
+				}
+			}
+		}
+	}
+
+	if len(traceIDs) != 1 || traceIDs[0] != "trace_id=4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected an exemplar with the trace ID but got %v", traceIDs)
+	}
+}
+
+func TestCollectorNativeHistograms(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:                 prometheus.DefBuckets,
+		LengthBuckets:               prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:                 prometheus.ExponentialBuckets(10, 10, 7),
+		NativeHistogramBucketFactor: 1.1,
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"status":"200","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","canary":"","requestTime":0.2,"requestLength":120,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1}]`))
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error gathering metrics: %v", err)
+	}
+
+	native := map[string]bool{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			if h := m.GetHistogram(); h != nil {
+				native[mf.GetName()] = len(h.GetPositiveSpan()) > 0 && len(h.GetBucket()) > 0
+			}
+		}
+	}
+
+	expected := map[string]bool{
+		"nginx_ingress_controller_request_duration_seconds": true,
+		"nginx_ingress_controller_request_size":             true,
+	}
+	if !reflect.DeepEqual(native, expected) {
+		t.Errorf("expected native and classic histograms %v but got %v", expected, native)
+	}
+}
+
+func TestCollectorLabelLimits(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.SetLabelLimits([]string{"path", "canary"}, []string{"method"}, 2)
+
+	for _, method := range []string{"GET", "POST", "FOO", "BAR", "GET"} {
+		sc.handleMessage([]byte(fmt.Sprintf(`[{"status":"200","method":%q,"path":"/api","namespace":"default","ingress":"api","service":"api","canary":"api-canary","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1}]`, method)))
+	}
+
+	want := `
+		# HELP nginx_ingress_controller_requests The total number of client requests
+		# TYPE nginx_ingress_controller_requests counter
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",method="GET",namespace="default",path="",service="api",status="200"} 2
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",method="POST",namespace="default",path="",service="api",status="200"} 1
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",method="overflow",namespace="default",path="",service="api",status="200"} 2
+	`
+	if err := GatherAndCompare(sc, want, []string{"nginx_ingress_controller_requests"}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorLabelLimitsRemovedIngresses(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.SetLabelLimits(nil, []string{"ingress", "service"}, 2)
+
+	serve := func(ingress, status string) {
+		sc.handleMessage([]byte(fmt.Sprintf(`[{"status":%q,"method":"GET","path":"/","namespace":"default","ingress":%q,"service":%q,"canary":"","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1}]`, status, ingress, ingress)))
+	}
+
+	// the status is not capped
+	serve("a", "200")
+	serve("a", "404")
+	serve("a", "500")
+	serve("b", "200")
+	serve("c", "200")
+
+	// the values of a removed Ingress are released for the new Ingresses
+	sc.RemoveMetrics([]string{"default/a"}, registry)
+	serve("c", "200")
+
+	want := `
+		# HELP nginx_ingress_controller_requests The total number of client requests
+		# TYPE nginx_ingress_controller_requests counter
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="b",method="GET",namespace="default",path="/",service="b",status="200"} 1
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="c",method="GET",namespace="default",path="/",service="c",status="200"} 1
+		nginx_ingress_controller_requests{canary="",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="overflow",method="GET",namespace="default",path="/",service="overflow",status="200"} 1
+	`
+	if err := GatherAndCompare(sc, want, []string{"nginx_ingress_controller_requests"}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorRemovedEndpoints(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	for _, endpoint := range []string{"10.0.0.1:8080", "10.0.0.2:8080"} {
+		sc.handleMessage([]byte(fmt.Sprintf(`[{"status":"200","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","canary":"","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"upstreamAddr":%q,"ewmaScore":0.25}]`, endpoint)))
+	}
+
+	sc.SetEndpoints(sets.New[string]("10.0.0.2:8080"))
+
+	want := `
+		# HELP nginx_ingress_controller_ewma_score The ewma score of the endpoint, in seconds, when it was last picked by the ewma load balancer
+		# TYPE nginx_ingress_controller_ewma_score gauge
+		nginx_ingress_controller_ewma_score{controller_class="ingress",controller_namespace="default",controller_pod="pod",endpoint="10.0.0.2:8080",ingress="api",namespace="default",service="api"} 0.25
+	`
+	if err := GatherAndCompare(sc, want, []string{"nginx_ingress_controller_ewma_score"}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorEndpoints(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"status":"200","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","canary":"","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,
+		"endpoints":[{"address":"10.0.0.1:8080","status":"502","connectTime":-1,"responseTime":0.003},{"address":"10.0.0.2:8080","status":"200","connectTime":0.001,"responseTime":0.12}]}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_upstream_endpoint_connect_failures The total number of failed connections to the endpoint
+		# TYPE nginx_ingress_controller_upstream_endpoint_connect_failures counter
+		nginx_ingress_controller_upstream_endpoint_connect_failures{controller_class="ingress",controller_namespace="default",controller_pod="pod",endpoint="10.0.0.1:8080",ingress="api",namespace="default",service="api"} 1
+		# HELP nginx_ingress_controller_upstream_endpoint_responses The total number of responses of the endpoint, per upstream attempt
+		# TYPE nginx_ingress_controller_upstream_endpoint_responses counter
+		nginx_ingress_controller_upstream_endpoint_responses{controller_class="ingress",controller_namespace="default",controller_pod="pod",endpoint="10.0.0.2:8080",ingress="api",namespace="default",service="api",status="200"} 1
+	`
+	if err := GatherAndCompare(sc, want, []string{
+		"nginx_ingress_controller_upstream_endpoint_connect_failures",
+		"nginx_ingress_controller_upstream_endpoint_responses",
+	}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+
+	// the series of the endpoints which left the backends are removed
+	sc.SetEndpoints(sets.New[string]("10.0.0.2:8080"))
+
+	want = `
+		# HELP nginx_ingress_controller_upstream_endpoint_responses The total number of responses of the endpoint, per upstream attempt
+		# TYPE nginx_ingress_controller_upstream_endpoint_responses counter
+		nginx_ingress_controller_upstream_endpoint_responses{controller_class="ingress",controller_namespace="default",controller_pod="pod",endpoint="10.0.0.2:8080",ingress="api",namespace="default",service="api",status="200"} 1
+	`
+	if err := GatherAndCompare(sc, want, []string{
+		"nginx_ingress_controller_upstream_endpoint_connect_failures",
+		"nginx_ingress_controller_upstream_endpoint_responses",
+	}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorStream(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	// the sessions are not skipped by the metrics per host, they have no host
+	sc, err := NewSocketCollector("pod", "default", "ingress", true, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"stream":{"protocol":"TCP","port":"5432","namespace":"default","service":"postgres","status":"200","bytesReceived":1024,"bytesSent":4096,"sessionTime":12.5}},
+		{"stream":{"protocol":"TCP","port":"5432","namespace":"default","service":"postgres","status":"502","bytesReceived":0,"bytesSent":0,"sessionTime":0.001}}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_stream_bytes_received The total number of bytes received from the clients of the TCP and UDP services
+		# TYPE nginx_ingress_controller_stream_bytes_received counter
+		nginx_ingress_controller_stream_bytes_received{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres"} 1024
+		# HELP nginx_ingress_controller_stream_bytes_sent The total number of bytes sent to the clients of the TCP and UDP services
+		# TYPE nginx_ingress_controller_stream_bytes_sent counter
+		nginx_ingress_controller_stream_bytes_sent{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres"} 4096
+		# HELP nginx_ingress_controller_stream_connections The total number of connections of the TCP and UDP services
+		# TYPE nginx_ingress_controller_stream_connections counter
+		nginx_ingress_controller_stream_connections{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",status="200"} 1
+		nginx_ingress_controller_stream_connections{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",status="502"} 1
+		# HELP nginx_ingress_controller_stream_session_duration_seconds The duration of the sessions of the TCP and UDP services
+		# TYPE nginx_ingress_controller_stream_session_duration_seconds histogram
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="0.1"} 1
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="0.5"} 1
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="1"} 1
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="5"} 1
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="10"} 1
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="30"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="60"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="300"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="900"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="1800"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="3600"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres",le="+Inf"} 2
+		nginx_ingress_controller_stream_session_duration_seconds_sum{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres"} 12.501
+		nginx_ingress_controller_stream_session_duration_seconds_count{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="TCP",service="postgres"} 2
+	`
+	if err := GatherAndCompare(sc, want, []string{
+		"nginx_ingress_controller_stream_bytes_received",
+		"nginx_ingress_controller_stream_bytes_sent",
+		"nginx_ingress_controller_stream_connections",
+		"nginx_ingress_controller_stream_session_duration_seconds",
+	}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorDeniedRequests(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"status":"503","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"denial":"limit-req"},
+		{"status":"503","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"denial":"limit-req"},
+		{"status":"403","method":"GET","path":"/","namespace":"default","ingress":"admin","service":"admin","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"denial":"source-range"},
+		{"status":"403","method":"GET","path":"/","namespace":"default","ingress":"admin","service":"admin","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_denied_requests The total number of client requests denied by the rate limits, the source ranges or the authentication of the Ingress
+		# TYPE nginx_ingress_controller_denied_requests counter
+		nginx_ingress_controller_denied_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="admin",namespace="default",reason="source-range"} 1
+		nginx_ingress_controller_denied_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="default",reason="limit-req"} 2
+	`
+	if err := GatherAndCompare(sc, want, []string{"nginx_ingress_controller_denied_requests"}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorClientBans(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"status":"401","method":"POST","path":"/login","namespace":"default","ingress":"api","service":"api","requestTime":0.1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"clientBanned":true},
+		{"status":"401","method":"POST","path":"/login","namespace":"default","ingress":"api","service":"api","requestTime":0.1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1},
+		{"status":"403","method":"GET","path":"/","namespace":"default","ingress":"api","service":"api","requestTime":-1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"denial":"auto-ban"}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_client_bans The total number of clients banned by the automatic banning, by Ingress of the request whose response banned them
+		# TYPE nginx_ingress_controller_client_bans counter
+		nginx_ingress_controller_client_bans{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="default"} 1
+		# HELP nginx_ingress_controller_denied_requests The total number of client requests denied by the rate limits, the source ranges or the authentication of the Ingress
+		# TYPE nginx_ingress_controller_denied_requests counter
+		nginx_ingress_controller_denied_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="default",reason="auto-ban"} 1
+	`
+	if err := GatherAndCompare(sc, want, []string{
+		"nginx_ingress_controller_client_bans",
+		"nginx_ingress_controller_denied_requests",
+	}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorInvalidRequests(t *testing.T) {
+	buckets := HistogramBuckets{
+		TimeBuckets:   prometheus.DefBuckets,
+		LengthBuckets: prometheus.LinearBuckets(10, 10, 10),
+		SizeBuckets:   prometheus.ExponentialBuckets(10, 10, 7),
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress", false, true, false, 0, buckets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Fatalf("registering collector failed: %s", err)
+	}
+
+	sc.handleMessage([]byte(`[{"status":"400","method":"POST","path":"/","namespace":"default","ingress":"api","service":"api","requestTime":0.1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1,"denial":"invalid-request","requestViolation":"length-conflict"},
+		{"status":"200","method":"POST","path":"/","namespace":"default","ingress":"api","service":"api","requestTime":0.1,"requestLength":-1,"responseLength":-1,"upstreamLatency":-1,"upstreamHeaderTime":-1,"upstreamResponseTime":-1}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_invalid_requests The total number of client requests denied by the strict request validation, by violation
+		# TYPE nginx_ingress_controller_invalid_requests counter
+		nginx_ingress_controller_invalid_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="default",violation="length-conflict"} 1
+	`
+	if err := GatherAndCompare(sc, want, []string{"nginx_ingress_controller_invalid_requests"}, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
@@ -41,6 +41,21 @@ func (dc DummyCollector) IncReloadCount() {}
 // IncReloadErrorCount dummy implementation
 func (dc DummyCollector) IncReloadErrorCount() {}
 
+// IncReloadSkippedCount dummy implementation
+func (dc DummyCollector) IncReloadSkippedCount() {}
+
+// IncReloadFailureCount dummy implementation
+func (dc DummyCollector) IncReloadFailureCount(string) {}
+
+// ObserveReloadDuration dummy implementation
+func (dc DummyCollector) ObserveReloadDuration(float64) {}
+
+// ObserveConfigBuildDuration dummy implementation
+func (dc DummyCollector) ObserveConfigBuildDuration(float64) {}
+
+// SetReloadQueueDepth dummy implementation
+func (dc DummyCollector) SetReloadQueueDepth(int) {}
+
 // IncOrphanIngress dummy implementation
 func (dc DummyCollector) IncOrphanIngress(string, string, string) {}
 
@@ -68,9 +83,18 @@ func (dc DummyCollector) SetSSLInfo([]*ingress.Server) {}
 // SetSSLExpireTime dummy implementation
 func (dc DummyCollector) SetSSLExpireTime([]*ingress.Server) {}
 
+// SetSSLExpireDays dummy implementation
+func (dc DummyCollector) SetSSLExpireDays([]*ingress.Server) {}
+
 // SetHosts dummy implementation
 func (dc DummyCollector) SetHosts(_ sets.Set[string]) {}
 
+// SetEndpoints dummy implementation
+func (dc DummyCollector) SetEndpoints(_ sets.Set[string]) {}
+
+// SetLabelLimits dummy implementation
+func (dc DummyCollector) SetLabelLimits(_, _ []string, _ int) {}
+
 // OnStartedLeading indicates the pod is not the current leader
 func (dc DummyCollector) OnStartedLeading(_ string) {}
 
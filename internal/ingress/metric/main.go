@@ -35,6 +35,12 @@ type Collector interface {
 
 	IncReloadCount()
 	IncReloadErrorCount()
+	IncReloadSkippedCount()
+	IncReloadFailureCount(reason string)
+
+	ObserveReloadDuration(seconds float64)
+	ObserveConfigBuildDuration(seconds float64)
+	SetReloadQueueDepth(depth int)
 
 	SetAdmissionMetrics(float64, float64, float64, float64, float64, float64)
 
@@ -49,11 +55,21 @@ type Collector interface {
 	RemoveMetrics(ingresses, certificates []string)
 
 	SetSSLExpireTime([]*ingress.Server)
+	SetSSLExpireDays([]*ingress.Server)
 	SetSSLInfo(servers []*ingress.Server)
 
 	// SetHosts sets the hostnames that are being served by the ingress controller
 	SetHosts(set sets.Set[string])
 
+	// SetEndpoints sets the endpoints of the backends, the metrics of the
+	// other endpoints are removed
+	SetEndpoints(set sets.Set[string])
+
+	// SetLabelLimits sets the labels of the request metrics whose values are
+	// dropped, and the labels whose values are capped with the maximum number
+	// of their values
+	SetLabelLimits(dropLabels, limitLabels []string, maxLabelValues int)
+
 	Start(string)
 	Stop(string)
 }
@@ -67,11 +83,15 @@ type collector struct {
 
 	socket *collectors.SocketCollector
 
+	ocsp *collectors.OCSPCollector
+
+	geoip2 *collectors.GeoIP2Collector
+
 	registry *prometheus.Registry
 }
 
 // NewCollector creates a new metric collector the for ingress controller
-func NewCollector(metricsPerHost, reportStatusClasses bool, registry *prometheus.Registry, ingressclass string, buckets collectors.HistogramBuckets, excludedSocketMetrics []string) (Collector, error) {
+func NewCollector(metricsPerHost, metricsPerPath, reportStatusClasses bool, metricsMaxPaths int, registry *prometheus.Registry, ingressclass string, buckets collectors.HistogramBuckets, excludedSocketMetrics []string) (Collector, error) {
 	podNamespace := os.Getenv("POD_NAMESPACE")
 	if podNamespace == "" {
 		podNamespace = "default"
@@ -89,7 +109,7 @@ func NewCollector(metricsPerHost, reportStatusClasses bool, registry *prometheus
 		return nil, err
 	}
 
-	s, err := collectors.NewSocketCollector(podName, podNamespace, ingressclass, metricsPerHost, reportStatusClasses, buckets, excludedSocketMetrics)
+	s, err := collectors.NewSocketCollector(podName, podNamespace, ingressclass, metricsPerHost, metricsPerPath, reportStatusClasses, metricsMaxPaths, buckets, excludedSocketMetrics)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +118,10 @@ func NewCollector(metricsPerHost, reportStatusClasses bool, registry *prometheus
 
 	am := collectors.NewAdmissionCollector(podName, podNamespace, ingressclass)
 
+	oc := collectors.NewOCSPCollector(podName, podNamespace, ingressclass)
+
+	gc := collectors.NewGeoIP2Collector(podName, podNamespace, ingressclass)
+
 	return Collector(&collector{
 		nginxStatus:  nc,
 		nginxProcess: pc,
@@ -107,6 +131,10 @@ func NewCollector(metricsPerHost, reportStatusClasses bool, registry *prometheus
 
 		socket: s,
 
+		ocsp: oc,
+
+		geoip2: gc,
+
 		registry: registry,
 	}), nil
 }
@@ -131,6 +159,26 @@ func (c *collector) IncReloadErrorCount() {
 	c.ingressController.IncReloadErrorCount()
 }
 
+func (c *collector) IncReloadSkippedCount() {
+	c.ingressController.IncReloadSkippedCount()
+}
+
+func (c *collector) IncReloadFailureCount(reason string) {
+	c.ingressController.IncReloadFailureCount(reason)
+}
+
+func (c *collector) ObserveReloadDuration(seconds float64) {
+	c.ingressController.ObserveReloadDuration(seconds)
+}
+
+func (c *collector) ObserveConfigBuildDuration(seconds float64) {
+	c.ingressController.ObserveConfigBuildDuration(seconds)
+}
+
+func (c *collector) SetReloadQueueDepth(depth int) {
+	c.ingressController.SetReloadQueueDepth(depth)
+}
+
 func (c *collector) RemoveMetrics(ingresses, certificates []string) {
 	c.socket.RemoveMetrics(ingresses, c.registry)
 	c.ingressController.RemoveMetrics(certificates, c.registry)
@@ -144,6 +192,8 @@ func (c *collector) Start(admissionStatus string) {
 	}
 	c.registry.MustRegister(c.ingressController)
 	c.registry.MustRegister(c.socket)
+	c.registry.MustRegister(c.ocsp)
+	c.registry.MustRegister(c.geoip2)
 
 	// the default nginx.conf does not contains
 	// a server section with the status port
@@ -163,6 +213,8 @@ func (c *collector) Stop(admissionStatus string) {
 	}
 	c.registry.Unregister(c.ingressController)
 	c.registry.Unregister(c.socket)
+	c.registry.Unregister(c.ocsp)
+	c.registry.Unregister(c.geoip2)
 
 	c.nginxStatus.Stop()
 	c.nginxProcess.Stop()
@@ -178,6 +230,10 @@ func (c *collector) SetSSLExpireTime(servers []*ingress.Server) {
 	c.ingressController.SetSSLExpireTime(servers)
 }
 
+func (c *collector) SetSSLExpireDays(servers []*ingress.Server) {
+	c.ingressController.SetSSLExpireDays(servers)
+}
+
 func (c *collector) SetSSLInfo(servers []*ingress.Server) {
 	klog.V(2).Infof("Updating ssl certificate info metrics")
 	c.ingressController.SetSSLInfo(servers)
@@ -195,6 +251,14 @@ func (c *collector) SetHosts(hosts sets.Set[string]) {
 	c.socket.SetHosts(hosts)
 }
 
+func (c *collector) SetEndpoints(endpoints sets.Set[string]) {
+	c.socket.SetEndpoints(endpoints)
+}
+
+func (c *collector) SetLabelLimits(dropLabels, limitLabels []string, maxLabelValues int) {
+	c.socket.SetLabelLimits(dropLabels, limitLabels, maxLabelValues)
+}
+
 func (c *collector) SetAdmissionMetrics(testedIngressLength, testedIngressTime, renderingIngressLength, renderingIngressTime, testedConfigurationSize, admissionTime float64) {
 	c.admissionController.SetAdmissionMetrics(
 		testedIngressLength,
@@ -17,10 +17,20 @@ limitations under the License.
 package resolver
 
 import (
+	"time"
+
 	apiv1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 )
 
+// IngressGroupKind is the group and kind of the Ingresses in the
+// ReferenceGrants
+var IngressGroupKind = schema.GroupKind{Group: networking.GroupName, Kind: "Ingress"}
+
 // Resolver is an interface that knows how to extract information from a controller
 type Resolver interface {
 	// GetDefaultBackend returns the backend that must be used as default
@@ -44,6 +54,30 @@ type Resolver interface {
 
 	// GetService searches for services containing the namespace and name using a the character /
 	GetService(string) (*apiv1.Service, error)
+
+	// IsSecretReferenceGranted returns true if a ReferenceGrant permits the
+	// objects of a kind and namespace to reference the secret, namespace/name
+	IsSecretReferenceGranted(from schema.GroupKind, fromNamespace, secret string) bool
+
+	// GetWAFPolicy searches for the WAFPolicy matching the namespace and name
+	// using the character /
+	GetWAFPolicy(string) (*v1alpha1.WAFPolicy, error)
+
+	// GetRedirectMap searches for the RedirectMap matching the namespace and
+	// name using the character /
+	GetRedirectMap(string) (*v1alpha1.RedirectMap, error)
+}
+
+// IsCrossNamespaceSecretAllowed returns true if the Ingress can reference the
+// secret, namespace/name, of another namespace in the annotations authorized
+// by the ReferenceGrants
+func IsCrossNamespaceSecretAllowed(r Resolver, ing *networking.Ingress, secret string) bool {
+	secCfg := r.GetSecurityConfiguration()
+	if !secCfg.ReferenceGrants {
+		return secCfg.AllowCrossNamespaceResources
+	}
+
+	return r.IsSecretReferenceGranted(IngressGroupKind, ing.Namespace, secret)
 }
 
 // AuthSSLCert contains the necessary information to do certificate based
@@ -61,6 +95,8 @@ type AuthSSLCert struct {
 	CRLSHA string `json:"crlSha"`
 	// PemFileName contains the path to the secrets 'tls.crt' and 'tls.key'
 	PemFileName string `json:"pemFilename"`
+	// CAExpireTime contains the expiration of the first CA certificate of 'ca.crt' to expire
+	CAExpireTime time.Time `json:"caExpires"`
 }
 
 // Equal tests for equality between two AuthSSLCert types
@@ -20,8 +20,10 @@ import (
 	"errors"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/pkg/apis/nginxingress/v1alpha1"
 )
 
 // Mock implements the Resolver interface
@@ -29,6 +31,10 @@ type Mock struct {
 	ConfigMaps           map[string]*apiv1.ConfigMap
 	AnnotationsRiskLevel string
 	AllowCrossNamespace  bool
+	ReferenceGrants      bool
+	GrantedSecrets       []string
+	WAFPolicies          map[string]*v1alpha1.WAFPolicy
+	RedirectMaps         map[string]*v1alpha1.RedirectMap
 }
 
 // GetDefaultBackend returns the backend that must be used as default
@@ -44,6 +50,7 @@ func (m Mock) GetSecurityConfiguration() defaults.SecurityConfiguration {
 	return defaults.SecurityConfiguration{
 		AnnotationsRiskLevel:         defRisk,
 		AllowCrossNamespaceResources: m.AllowCrossNamespace,
+		ReferenceGrants:              m.ReferenceGrants,
 	}
 }
 
@@ -72,3 +79,29 @@ func (m Mock) GetConfigMap(name string) (*apiv1.ConfigMap, error) {
 	}
 	return nil, errors.New("no configmap")
 }
+
+// IsSecretReferenceGranted returns true if the secret is one of the granted secrets
+func (m Mock) IsSecretReferenceGranted(_ schema.GroupKind, _, secret string) bool {
+	for _, granted := range m.GrantedSecrets {
+		if granted == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWAFPolicy searches for WAFPolicies contenating the namespace and name using a the character /
+func (m Mock) GetWAFPolicy(name string) (*v1alpha1.WAFPolicy, error) {
+	if v, ok := m.WAFPolicies[name]; ok {
+		return v, nil
+	}
+	return nil, errors.New("no WAFPolicy")
+}
+
+// GetRedirectMap searches for RedirectMaps contenating the namespace and name using a the character /
+func (m Mock) GetRedirectMap(name string) (*v1alpha1.RedirectMap, error) {
+	if v, ok := m.RedirectMaps[name]; ok {
+		return v, nil
+	}
+	return nil, errors.New("no RedirectMap")
+}
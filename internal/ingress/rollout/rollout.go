@@ -0,0 +1,512 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	apiv1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+// SyncInterval defines the time interval in which the canary
+// Ingresses are checked for a pending rollout step.
+var SyncInterval = 10 * time.Second
+
+const defaultWeightTotal = 100
+
+// scrapeTimeout is the time limit to read the metrics of another replica
+const scrapeTimeout = 5 * time.Second
+
+var (
+	requestsMetric        = collectors.PrometheusNamespace + "_requests"
+	requestDurationMetric = collectors.PrometheusNamespace + "_request_duration_seconds"
+)
+
+// Controller progressively increments the weight of canary Ingresses
+type Controller interface {
+	Run(chan struct{})
+}
+
+type ingressLister interface {
+	// ListIngresses returns the list of Ingresses
+	ListIngresses() []*ingress.Ingress
+}
+
+// Config is a structure that implements Client interfaces
+type Config struct {
+	Client clientset.Interface
+
+	IngressLister ingressLister
+
+	// Gatherer exposes the metrics of the socket collector
+	Gatherer prometheus.Gatherer
+
+	// PeerMetricsPort is the port of the metrics endpoint of the other
+	// replicas of the controller. The traffic they served is added to
+	// the traffic served by this replica. When 0 only the traffic served
+	// by this replica is evaluated.
+	PeerMetricsPort int
+
+	// CanaryLabelLimited returns true when the values of the canary label
+	// of the metrics are dropped or capped, the traffic of the canaries
+	// cannot be evaluated and the rollouts are suspended
+	CanaryLabelLimited func() bool
+
+	Recorder record.EventRecorder
+}
+
+// sample contains the counters of the traffic served by a canary backend
+type sample struct {
+	requests      float64
+	errors        float64
+	durationSum   float64
+	durationCount float64
+}
+
+func (s sample) add(o sample) sample {
+	return sample{
+		requests:      s.requests + o.requests,
+		errors:        s.errors + o.errors,
+		durationSum:   s.durationSum + o.durationSum,
+		durationCount: s.durationCount + o.durationCount,
+	}
+}
+
+func (s sample) sub(o sample) sample {
+	// counters are reset when the metrics of an Ingress are removed
+	if s.requests < o.requests || s.durationCount < o.durationCount {
+		return s
+	}
+
+	return sample{
+		requests:      s.requests - o.requests,
+		errors:        s.errors - o.errors,
+		durationSum:   s.durationSum - o.durationSum,
+		durationCount: s.durationCount - o.durationCount,
+	}
+}
+
+// state keeps track of the last rollout step of a canary Ingress
+type state struct {
+	lastStep time.Time
+	// samples contains the traffic served by the canary, by replica
+	samples map[string]sample
+	// waiting is true when the canary did not serve enough requests since
+	// the last step
+	waiting bool
+}
+
+type controller struct {
+	Config
+
+	states map[string]*state
+	// suspended is true when the rollouts are suspended because the canary
+	// label of the metrics is limited
+	suspended bool
+
+	httpClient *http.Client
+
+	now func() time.Time
+}
+
+// NewController returns a new progressive canary rollout controller
+func NewController(config Config) Controller {
+	return &controller{
+		Config:     config,
+		states:     make(map[string]*state),
+		httpClient: &http.Client{Timeout: scrapeTimeout},
+		now:        time.Now,
+	}
+}
+
+// Run starts the loop checking the canary Ingresses until stopCh is closed.
+// It must only run in the leader to avoid competing updates of the weight.
+func (c *controller) Run(stopCh chan struct{}) {
+	wait.Until(c.sync, SyncInterval, stopCh)
+}
+
+func (c *controller) sync() {
+	if c.CanaryLabelLimited != nil && c.CanaryLabelLimited() {
+		if !c.suspended {
+			klog.Error("Progressive canary rollouts suspended: the values of the canary label of the metrics are dropped or capped " +
+				"(metrics-drop-labels, metrics-limit-labels), the traffic served by the canaries cannot be evaluated")
+			c.suspended = true
+		}
+		// the traffic is evaluated again from the next steps
+		c.states = make(map[string]*state)
+		return
+	}
+	if c.suspended {
+		klog.Info("Progressive canary rollouts resumed")
+		c.suspended = false
+	}
+
+	samples, err := c.gather()
+	if err != nil {
+		klog.ErrorS(err, "Error gathering canary metrics")
+		return
+	}
+
+	now := c.now()
+	active := sets.New[string]()
+
+	for _, ing := range c.IngressLister.ListIngresses() {
+		if ing.ParsedAnnotations == nil {
+			continue
+		}
+
+		cfg := ing.ParsedAnnotations.Canary
+		if !cfg.Enabled || cfg.Rollout.Step <= 0 || cfg.Rollout.Paused {
+			continue
+		}
+
+		weightTotal := cfg.WeightTotal
+		if weightTotal <= 0 {
+			weightTotal = defaultWeightTotal
+		}
+
+		if cfg.Weight >= weightTotal {
+			continue
+		}
+
+		key := k8s.MetaNamespaceKey(ing)
+		active.Insert(key)
+
+		upstreams := canaryUpstreams(&ing.Ingress)
+		current := make(map[string]sample, len(samples))
+		for pod, podSamples := range samples {
+			s := sample{}
+			for _, upstream := range upstreams {
+				s = s.add(podSamples[upstream])
+			}
+			current[pod] = s
+		}
+
+		st, ok := c.states[key]
+		if !ok {
+			c.states[key] = &state{lastStep: now, samples: current}
+			continue
+		}
+
+		if now.Sub(st.lastStep) < cfg.Rollout.Interval {
+			continue
+		}
+
+		// the counters of every replica are compared with their own
+		// previous values, a replica restarting only resets its counters
+		delta := sample{}
+		for pod, s := range current {
+			delta = delta.add(s.sub(st.samples[pod]))
+		}
+
+		// without canary traffic the weight is kept, the traffic served
+		// from the last step is evaluated on the next syncs
+		if cfg.Weight > 0 && delta.requests < float64(max(cfg.Rollout.MinRequests, 1)) {
+			if !st.waiting {
+				st.waiting = true
+				klog.InfoS("Canary rollout waiting for traffic", "ingress", key, "requests", delta.requests, "minRequests", cfg.Rollout.MinRequests)
+				c.event(ing, apiv1.EventTypeNormal, fmt.Sprintf("Canary rollout waiting for %d requests, the canary served %.0f", cfg.Rollout.MinRequests, delta.requests))
+			}
+			continue
+		}
+		st.lastStep = now
+		st.samples = current
+		st.waiting = false
+
+		if reason := violation(&cfg.Rollout, delta); reason != "" {
+			c.fail(ing, &cfg.Rollout, reason)
+			continue
+		}
+
+		weight := cfg.Weight + cfg.Rollout.Step
+		if weight > weightTotal {
+			weight = weightTotal
+		}
+
+		err := c.patchAnnotations(ing, map[string]string{
+			parser.GetAnnotationWithPrefix("canary-weight"): strconv.Itoa(weight),
+		})
+		if err != nil {
+			klog.ErrorS(err, "Error updating canary weight", "ingress", key)
+			continue
+		}
+
+		klog.InfoS("Canary weight incremented", "ingress", key, "weight", weight, "weightTotal", weightTotal)
+		c.event(ing, apiv1.EventTypeNormal, fmt.Sprintf("Canary weight set to %d/%d", weight, weightTotal))
+	}
+
+	for key := range c.states {
+		if !active.Has(key) {
+			delete(c.states, key)
+		}
+	}
+}
+
+// violation returns the reason why the traffic served by the canary
+// during the last rollout step does not meet the configured SLO
+func violation(cfg *canary.RolloutConfig, delta sample) string {
+	if cfg.MaxErrorRate > 0 && delta.requests > 0 {
+		errorRate := delta.errors / delta.requests * 100
+		if errorRate > float64(cfg.MaxErrorRate) {
+			return fmt.Sprintf("error rate %.2f%% is above %.2f%%", errorRate, cfg.MaxErrorRate)
+		}
+	}
+
+	if cfg.MaxLatency > 0 && delta.durationCount > 0 {
+		latency := time.Duration(delta.durationSum / delta.durationCount * float64(time.Second))
+		if latency > cfg.MaxLatency {
+			return fmt.Sprintf("average latency %v is above %v", latency, cfg.MaxLatency)
+		}
+	}
+
+	return ""
+}
+
+func (c *controller) fail(ing *ingress.Ingress, cfg *canary.RolloutConfig, reason string) {
+	key := k8s.MetaNamespaceKey(ing)
+
+	annotations := map[string]string{
+		parser.GetAnnotationWithPrefix("canary-rollout-paused"): "true",
+	}
+
+	message := fmt.Sprintf("Canary rollout paused: %v", reason)
+	if cfg.OnFailure == canary.RolloutOnFailureRollback {
+		annotations[parser.GetAnnotationWithPrefix("canary-weight")] = "0"
+		message = fmt.Sprintf("Canary rolled back: %v", reason)
+	}
+
+	if err := c.patchAnnotations(ing, annotations); err != nil {
+		klog.ErrorS(err, "Error stopping canary rollout", "ingress", key)
+		return
+	}
+
+	klog.InfoS("Canary rollout stopped", "ingress", key, "reason", reason, "action", cfg.OnFailure)
+	c.event(ing, apiv1.EventTypeWarning, message)
+}
+
+func (c *controller) patchAnnotations(ing *ingress.Ingress, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Client.NetworkingV1().Ingresses(ing.Namespace).Patch(
+		context.TODO(), ing.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (c *controller) event(ing *ingress.Ingress, eventType, message string) {
+	if c.Recorder == nil {
+		return
+	}
+
+	c.Recorder.Event(&ing.Ingress, eventType, "CanaryRollout", message)
+}
+
+// gather returns the traffic served by every canary backend, indexed by
+// the name of the replica of the controller and then by the name of the
+// upstream used in the canary label of the metrics. The rollout step is
+// skipped when the metrics of one of the replicas cannot be read, so the
+// SLO is never evaluated on a part of the traffic.
+func (c *controller) gather() (map[string]map[string]sample, error) {
+	mfs, err := c.Gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := map[string]map[string]sample{
+		localPodName(): collect(mfs),
+	}
+
+	if c.PeerMetricsPort <= 0 || k8s.IngressPodDetails == nil {
+		return samples, nil
+	}
+
+	pods, err := c.Client.CoreV1().Pods(k8s.IngressPodDetails.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(k8s.IngressPodDetails.Labels).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing the replicas of the controller: %w", err)
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Name == k8s.IngressPodDetails.Name || pod.Status.Phase != apiv1.PodRunning ||
+			pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		peerMfs, err := c.scrape(pod.Status.PodIP)
+		if err != nil {
+			return nil, fmt.Errorf("reading the metrics of the replica %v: %w", pod.Name, err)
+		}
+
+		samples[pod.Name] = collect(peerMfs)
+	}
+
+	return samples, nil
+}
+
+// scrape reads the metrics exposed by another replica of the controller
+func (c *controller) scrape(ip string) ([]*dto.MetricFamily, error) {
+	url := fmt.Sprintf("http://%v/metrics", net.JoinHostPort(ip, strconv.Itoa(c.PeerMetricsPort)))
+
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mfs := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		mfs = append(mfs, mf)
+	}
+
+	return mfs, nil
+}
+
+func localPodName() string {
+	if k8s.IngressPodDetails == nil {
+		return ""
+	}
+
+	return k8s.IngressPodDetails.Name
+}
+
+// collect returns the traffic served by every canary backend in the
+// metrics of a replica, indexed by the name of the upstream
+func collect(mfs []*dto.MetricFamily) map[string]sample {
+	samples := make(map[string]sample)
+
+	for _, mf := range mfs {
+		switch mf.GetName() {
+		case requestsMetric:
+			for _, m := range mf.GetMetric() {
+				upstream := labelValue(m, "canary")
+				if upstream == "" || upstream == "-" {
+					continue
+				}
+
+				s := samples[upstream]
+				value := m.GetCounter().GetValue()
+				s.requests += value
+				if strings.HasPrefix(labelValue(m, "status"), "5") {
+					s.errors += value
+				}
+				samples[upstream] = s
+			}
+		case requestDurationMetric:
+			for _, m := range mf.GetMetric() {
+				upstream := labelValue(m, "canary")
+				if upstream == "" || upstream == "-" {
+					continue
+				}
+
+				s := samples[upstream]
+				s.durationSum += m.GetHistogram().GetSampleSum()
+				s.durationCount += float64(m.GetHistogram().GetSampleCount())
+				samples[upstream] = s
+			}
+		}
+	}
+
+	return samples
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+
+	return ""
+}
+
+// canaryUpstreams returns the names of the upstreams used by the
+// backends of a canary Ingress
+func canaryUpstreams(ing *networking.Ingress) []string {
+	upstreams := sets.New[string]()
+
+	if ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+		upstreams.Insert(upstreamName(ing.Namespace, ing.Spec.DefaultBackend.Service))
+	}
+
+	for i := range ing.Spec.Rules {
+		rule := &ing.Spec.Rules[i]
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for j := range rule.HTTP.Paths {
+			if rule.HTTP.Paths[j].Backend.Service == nil {
+				continue
+			}
+
+			upstreams.Insert(upstreamName(ing.Namespace, rule.HTTP.Paths[j].Backend.Service))
+		}
+	}
+
+	return sets.List(upstreams)
+}
+
+func upstreamName(namespace string, service *networking.IngressServiceBackend) string {
+	if service.Port.Number > 0 {
+		return fmt.Sprintf("%s-%s-%d", namespace, service.Name, service.Port.Number)
+	}
+
+	return fmt.Sprintf("%s-%s-%s", namespace, service.Name, service.Port.Name)
+}
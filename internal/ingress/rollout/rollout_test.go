@@ -0,0 +1,398 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	apiv1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
+)
+
+type testIngressLister struct {
+	ingresses []*ingress.Ingress
+}
+
+func (l testIngressLister) ListIngresses() []*ingress.Ingress {
+	return l.ingresses
+}
+
+func buildCanaryIngress(cfg canary.Config) *ingress.Ingress {
+	return &ingress.Ingress{
+		Ingress: networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "canary",
+				Namespace: metav1.NamespaceDefault,
+			},
+			Spec: networking.IngressSpec{
+				DefaultBackend: &networking.IngressBackend{
+					Service: &networking.IngressServiceBackend{
+						Name: "canary-svc",
+						Port: networking.ServiceBackendPort{Number: 80},
+					},
+				},
+			},
+		},
+		ParsedAnnotations: &annotations.Ingress{
+			Canary: cfg,
+		},
+	}
+}
+
+type testMetrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newTestMetrics() *testMetrics {
+	m := &testMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: collectors.PrometheusNamespace,
+			Name:      "requests",
+		}, []string{"status", "canary"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: collectors.PrometheusNamespace,
+			Name:      "request_duration_seconds",
+		}, []string{"status", "canary"}),
+	}
+	m.registry.MustRegister(m.requests, m.duration)
+	return m
+}
+
+func (m *testMetrics) serve(status string, count int, duration float64) {
+	for i := 0; i < count; i++ {
+		m.requests.WithLabelValues(status, "default-canary-svc-80").Inc()
+		m.duration.WithLabelValues(status, "default-canary-svc-80").Observe(duration)
+	}
+}
+
+func TestRollout(t *testing.T) {
+	rolloutCfg := canary.RolloutConfig{
+		Step:         10,
+		Interval:     time.Minute,
+		MaxErrorRate: 5,
+		MaxLatency:   time.Second,
+		OnFailure:    canary.RolloutOnFailurePause,
+	}
+
+	testCases := []struct {
+		name                string
+		onFailure           string
+		minRequests         int
+		weight              int
+		serve               func(*testMetrics)
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:   "healthy canary is promoted",
+			weight: 20,
+			serve: func(m *testMetrics) {
+				m.serve("200", 100, 0.1)
+				m.serve("500", 1, 0.1)
+			},
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/canary-weight": "30",
+			},
+		},
+		{
+			name:   "weight is capped to the weight total",
+			weight: 95,
+			serve: func(m *testMetrics) {
+				m.serve("200", 100, 0.1)
+			},
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/canary-weight": "100",
+			},
+		},
+		{
+			name:   "high error rate pauses the rollout",
+			weight: 20,
+			serve: func(m *testMetrics) {
+				m.serve("200", 90, 0.1)
+				m.serve("503", 10, 0.1)
+			},
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/canary-rollout-paused": "true",
+			},
+		},
+		{
+			name:                "canary without traffic keeps its weight",
+			weight:              20,
+			serve:               func(*testMetrics) {},
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:        "canary with too few requests keeps its weight",
+			minRequests: 50,
+			weight:      20,
+			serve: func(m *testMetrics) {
+				m.serve("200", 20, 0.1)
+			},
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:   "canary without weight is promoted without traffic",
+			weight: 0,
+			serve:  func(*testMetrics) {},
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/canary-weight": "10",
+			},
+		},
+		{
+			name:      "high latency rolls back the canary",
+			onFailure: canary.RolloutOnFailureRollback,
+			weight:    20,
+			serve: func(m *testMetrics) {
+				m.serve("200", 10, 2)
+			},
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/canary-rollout-paused": "true",
+				"nginx.ingress.kubernetes.io/canary-weight":         "0",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := rolloutCfg
+			if tc.onFailure != "" {
+				cfg.OnFailure = tc.onFailure
+			}
+			cfg.MinRequests = tc.minRequests
+
+			ing := buildCanaryIngress(canary.Config{
+				Enabled:     true,
+				Weight:      tc.weight,
+				WeightTotal: 100,
+				Rollout:     cfg,
+			})
+
+			client := testclient.NewSimpleClientset(&ing.Ingress)
+			metrics := newTestMetrics()
+
+			now := time.Now()
+			c := NewController(Config{
+				Client:        client,
+				IngressLister: testIngressLister{ingresses: []*ingress.Ingress{ing}},
+				Gatherer:      metrics.registry,
+			}).(*controller)
+			c.now = func() time.Time { return now }
+
+			// the first sync records the initial state of the canary
+			c.sync()
+
+			tc.serve(metrics)
+
+			now = now.Add(30 * time.Second)
+			c.sync()
+
+			updated, err := client.NetworkingV1().Ingresses(ing.Namespace).Get(context.TODO(), ing.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(updated.Annotations) != 0 {
+				t.Fatalf("expected no changes before the rollout interval but got %v", updated.Annotations)
+			}
+
+			now = now.Add(time.Minute)
+			c.sync()
+
+			updated, err = client.NetworkingV1().Ingresses(ing.Namespace).Get(context.TODO(), ing.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for k, v := range tc.expectedAnnotations {
+				if updated.Annotations[k] != v {
+					t.Errorf("expected annotation %v to be %v but got %v", k, v, updated.Annotations[k])
+				}
+			}
+			if len(updated.Annotations) != len(tc.expectedAnnotations) {
+				t.Errorf("expected annotations %v but got %v", tc.expectedAnnotations, updated.Annotations)
+			}
+		})
+	}
+}
+
+func TestRolloutSkipsPausedCanaries(t *testing.T) {
+	ing := buildCanaryIngress(canary.Config{
+		Enabled:     true,
+		Weight:      20,
+		WeightTotal: 100,
+		Rollout: canary.RolloutConfig{
+			Step:     10,
+			Interval: time.Minute,
+			Paused:   true,
+		},
+	})
+
+	client := testclient.NewSimpleClientset(&ing.Ingress)
+	now := time.Now()
+	c := NewController(Config{
+		Client:        client,
+		IngressLister: testIngressLister{ingresses: []*ingress.Ingress{ing}},
+		Gatherer:      newTestMetrics().registry,
+	}).(*controller)
+	c.now = func() time.Time { return now }
+
+	c.sync()
+	now = now.Add(2 * time.Minute)
+	c.sync()
+
+	if len(c.states) != 0 {
+		t.Errorf("expected no tracked canaries but got %v", len(c.states))
+	}
+
+	updated, err := client.NetworkingV1().Ingresses(ing.Namespace).Get(context.TODO(), ing.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updated.Annotations) != 0 {
+		t.Errorf("expected no changes but got %v", updated.Annotations)
+	}
+}
+
+func TestRolloutSuspendedWhenCanaryLabelLimited(t *testing.T) {
+	ing := buildCanaryIngress(canary.Config{
+		Enabled:     true,
+		Weight:      20,
+		WeightTotal: 100,
+		Rollout: canary.RolloutConfig{
+			Step:     10,
+			Interval: time.Minute,
+		},
+	})
+
+	client := testclient.NewSimpleClientset(&ing.Ingress)
+	metrics := newTestMetrics()
+	limited := true
+	now := time.Now()
+	c := NewController(Config{
+		Client:             client,
+		IngressLister:      testIngressLister{ingresses: []*ingress.Ingress{ing}},
+		Gatherer:           metrics.registry,
+		CanaryLabelLimited: func() bool { return limited },
+	}).(*controller)
+	c.now = func() time.Time { return now }
+
+	c.sync()
+	metrics.serve("200", 100, 0.1)
+	now = now.Add(2 * time.Minute)
+	c.sync()
+
+	if !c.suspended || len(c.states) != 0 {
+		t.Errorf("expected the rollouts to be suspended but got %v tracked canaries", len(c.states))
+	}
+	updated, err := client.NetworkingV1().Ingresses(ing.Namespace).Get(context.TODO(), ing.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updated.Annotations) != 0 {
+		t.Errorf("expected no changes but got %v", updated.Annotations)
+	}
+
+	limited = false
+	c.sync()
+	if c.suspended || len(c.states) != 1 {
+		t.Errorf("expected the rollouts to be resumed but got %v tracked canaries", len(c.states))
+	}
+}
+
+func TestRolloutAggregatesReplicas(t *testing.T) {
+	ing := buildCanaryIngress(canary.Config{
+		Enabled:     true,
+		Weight:      20,
+		WeightTotal: 100,
+		Rollout: canary.RolloutConfig{
+			Step:         10,
+			Interval:     time.Minute,
+			MaxErrorRate: 5,
+			OnFailure:    canary.RolloutOnFailurePause,
+		},
+	})
+
+	peerMetrics := newTestMetrics()
+	server := httptest.NewServer(promhttp.HandlerFor(peerMetrics.registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	peerPort, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	podLabels := map[string]string{"app": "ingress-nginx"}
+	podDetails := k8s.IngressPodDetails
+	k8s.IngressPodDetails = &k8s.PodInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "controller-a", Namespace: "ingress-nginx", Labels: podLabels},
+	}
+	defer func() { k8s.IngressPodDetails = podDetails }()
+
+	peer := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "controller-b", Namespace: "ingress-nginx", Labels: podLabels},
+		Status:     apiv1.PodStatus{Phase: apiv1.PodRunning, PodIP: host},
+	}
+
+	client := testclient.NewSimpleClientset(&ing.Ingress, peer)
+	metrics := newTestMetrics()
+
+	now := time.Now()
+	c := NewController(Config{
+		Client:          client,
+		IngressLister:   testIngressLister{ingresses: []*ingress.Ingress{ing}},
+		Gatherer:        metrics.registry,
+		PeerMetricsPort: peerPort,
+	}).(*controller)
+	c.now = func() time.Time { return now }
+
+	c.sync()
+
+	// the errors are only served by the other replica
+	metrics.serve("200", 100, 0.1)
+	peerMetrics.serve("503", 20, 0.1)
+
+	now = now.Add(2 * time.Minute)
+	c.sync()
+
+	updated, err := client.NetworkingV1().Ingresses(ing.Namespace).Get(context.TODO(), ing.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Annotations["nginx.ingress.kubernetes.io/canary-rollout-paused"] != "true" {
+		t.Errorf("expected the rollout to be paused but got %v", updated.Annotations)
+	}
+}
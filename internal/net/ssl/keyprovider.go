@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// KeyProvider gives access to private keys kept outside of the Secrets, like
+// in an HSM or a cloud KMS. The keys never leave the provider, it only signs
+// on behalf of its clients.
+type KeyProvider interface {
+	// Signer returns the signer of the private key identified by keyID
+	Signer(keyID string) (crypto.Signer, error)
+}
+
+// DefaultKeyProvider is the provider of the private keys referenced by the
+// tls.key-id key of the TLS Secrets. Secrets referencing a key are rejected
+// when it is not set.
+var DefaultKeyProvider KeyProvider
+
+// agentKeyProvider talks to a local agent, usually a sidecar holding the
+// credentials of the HSM or KMS, over a unix socket:
+//
+//	GET  /v1/keys/<id>       returns {"publicKey": "<PEM encoded public key>"}
+//	POST /v1/keys/<id>/sign  receives {"digest": "<base64>", "hash": "SHA-256"}
+//	                         and returns {"signature": "<base64>"}
+type agentKeyProvider struct {
+	client *http.Client
+}
+
+// NewAgentKeyProvider returns a KeyProvider delegating the signatures to the
+// agent listening on the unix socket
+func NewAgentKeyProvider(socket string) KeyProvider {
+	return &agentKeyProvider{
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+type agentPublicKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+type agentSignRequest struct {
+	Digest string `json:"digest"`
+	Hash   string `json:"hash"`
+	PSS    bool   `json:"pss,omitempty"`
+}
+
+type agentSignResponse struct {
+	Signature string `json:"signature"`
+}
+
+func (p *agentKeyProvider) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	// the host is ignored, the connections always go to the socket
+	req, err := http.NewRequestWithContext(context.Background(), method, "http://agent"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %v from the key agent", res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Signer returns the signer of the private key identified by keyID
+func (p *agentKeyProvider) Signer(keyID string) (crypto.Signer, error) {
+	var res agentPublicKeyResponse
+	err := p.do(http.MethodGet, "/v1/keys/"+url.PathEscape(keyID), nil, &res)
+	if err != nil {
+		return nil, fmt.Errorf("getting the public key %q: %w", keyID, err)
+	}
+
+	block, _ := pem.Decode([]byte(res.PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("no valid PEM formatted public key returned for key %q", keyID)
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the public key %q: %w", keyID, err)
+	}
+
+	return &agentSigner{provider: p, keyID: keyID, public: public}, nil
+}
+
+type agentSigner struct {
+	provider *agentKeyProvider
+	keyID    string
+	public   crypto.PublicKey
+}
+
+func (s *agentSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *agentSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	_, pss := opts.(*rsa.PSSOptions)
+
+	var res agentSignResponse
+	err := s.provider.do(http.MethodPost, "/v1/keys/"+url.PathEscape(s.keyID)+"/sign", &agentSignRequest{
+		Digest: base64.StdEncoding.EncodeToString(digest),
+		Hash:   opts.HashFunc().String(),
+		PSS:    pss,
+	}, &res)
+	if err != nil {
+		return nil, fmt.Errorf("signing with key %q: %w", s.keyID, err)
+	}
+
+	return base64.StdEncoding.DecodeString(res.Signature)
+}
+
+// checkKeyOwnership verifies the signer holds the private key of the
+// certificate signing a random digest
+func checkKeyOwnership(cert *x509.Certificate, signer crypto.Signer) error {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	digest := sha256.Sum256(nonce)
+
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+
+	switch public := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(public, digest[:], signature) {
+			err = fmt.Errorf("invalid ECDSA signature")
+		}
+	default:
+		err = fmt.Errorf("unsupported public key algorithm %v", cert.PublicKeyAlgorithm)
+	}
+	if err != nil {
+		return fmt.Errorf("certificate and private key does not have a matching public key: %v", err)
+	}
+
+	return nil
+}
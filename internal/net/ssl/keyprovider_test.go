@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFakeKeyAgent serves the key agent API on a unix socket, signing with key
+func newFakeKeyAgent(t *testing.T, keyID string, key *rsa.PrivateKey) string {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("unexpected error listening on %v: %v", socket, err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error encoding the public key: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/keys/"+keyID:
+			_ = json.NewEncoder(w).Encode(&agentPublicKeyResponse{
+				PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/keys/"+keyID+"/sign":
+			var req agentSignRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Hash != crypto.SHA256.String() {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			digest, err := base64.StdEncoding.DecodeString(req.Digest)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			signature, err := key.Sign(rand.Reader, digest, crypto.SHA256)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&agentSignResponse{Signature: base64.StdEncoding.EncodeToString(signature)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return socket
+}
+
+func TestCreateKeylessSSLCert(t *testing.T) {
+	cert, _, err := generateRSACerts("echoheaders")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+	otherKey, err := newPrivateKey()
+	if err != nil {
+		t.Fatalf("unexpected error creating private key: %v", err)
+	}
+
+	defer func() { DefaultKeyProvider = nil }()
+
+	c := encodeCertPEM(cert.Cert)
+
+	DefaultKeyProvider = nil
+	if _, err := CreateKeylessSSLCert(c, "echoheaders", FakeSSLCertificateUID); err == nil {
+		t.Errorf("expected an error without key provider")
+	}
+
+	DefaultKeyProvider = NewAgentKeyProvider(newFakeKeyAgent(t, "echoheaders", cert.Key))
+
+	sslCert, err := CreateKeylessSSLCert(c, "echoheaders", FakeSSLCertificateUID)
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+	if sslCert.KeyID != "echoheaders" {
+		t.Errorf("expected key id echoheaders but returned %v", sslCert.KeyID)
+	}
+	if sslCert.PemCertKey != string(c) {
+		t.Errorf("expected only the certificate but returned %v", sslCert.PemCertKey)
+	}
+	if strings.Join(sslCert.CN, ",") != "echoheaders" {
+		t.Errorf("expected common name echoheaders but returned %v", sslCert.CN)
+	}
+
+	if _, err := CreateKeylessSSLCert(c, "unknown", FakeSSLCertificateUID); err == nil {
+		t.Errorf("expected an error with an unknown key")
+	}
+
+	DefaultKeyProvider = NewAgentKeyProvider(newFakeKeyAgent(t, "echoheaders", otherKey))
+	if _, err := CreateKeylessSSLCert(c, "echoheaders", FakeSSLCertificateUID); err == nil {
+		t.Errorf("expected an error with a key not matching the certificate")
+	}
+}
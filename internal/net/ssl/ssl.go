@@ -56,6 +56,17 @@ var oidExtensionSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
 
 const (
 	fakeCertificateName = "default-fake-certificate" //#nosec G101
+
+	// ECDSACertKey is the key of the ECDSA certificate of a Secret also
+	// containing an RSA certificate in tls.crt
+	ECDSACertKey = "tls-ecdsa.crt"
+	// ECDSAPrivateKeyKey is the key of the private key of the ECDSA
+	// certificate of a Secret
+	ECDSAPrivateKeyKey = "tls-ecdsa.key" //#nosec G101
+
+	// KeyIDKey is the key of a Secret identifying the private key of tls.crt
+	// in the external key provider, replacing tls.key
+	KeyIDKey = "tls.key-id"
 )
 
 // getPemFileName returns absolute file path and file name of pem cert related to given fullSecretName
@@ -64,8 +75,9 @@ func getPemFileName(fullSecretName string) (filePath, pemName string) {
 	return fmt.Sprintf("%v/%v", file.DefaultSSLDirectory, pemName), pemName
 }
 
-// CreateSSLCert validates cert and key, extracts common names and returns corresponding SSLCert object
-func CreateSSLCert(cert, key []byte, uid string) (*ingress.SSLCert, error) {
+// createPemCert validates cert and returns it, with the certificate chain
+// completed if enabled
+func createPemCert(cert []byte) (*bytes.Buffer, *x509.Certificate, error) {
 	var pemCertBuffer bytes.Buffer
 	pemCertBuffer.Write(cert)
 
@@ -79,27 +91,84 @@ func CreateSSLCert(cert, key []byte, uid string) (*ingress.SSLCert, error) {
 		}
 	}
 
-	pemCertBuffer.WriteString("\n")
-	pemCertBuffer.Write(key)
-
 	pemBlock, _ := pem.Decode(pemCertBuffer.Bytes())
 	if pemBlock == nil {
-		return nil, fmt.Errorf("no valid PEM formatted block found")
+		return nil, nil, fmt.Errorf("no valid PEM formatted block found")
 	}
 
 	if pemBlock.Type != "CERTIFICATE" {
-		return nil, fmt.Errorf("no certificate PEM data found, make sure certificate content starts with 'BEGIN CERTIFICATE'")
+		return nil, nil, fmt.Errorf("no certificate PEM data found, make sure certificate content starts with 'BEGIN CERTIFICATE'")
 	}
 
 	pemCert, err := x509.ParseCertificate(pemBlock.Bytes)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return &pemCertBuffer, pemCert, nil
+}
+
+// createPemCertKey validates cert and key and returns the certificate and key
+// concatenated, with the certificate chain completed if enabled
+func createPemCertKey(cert, key []byte) (string, *x509.Certificate, error) {
+	pemCertBuffer, pemCert, err := createPemCert(cert)
+	if err != nil {
+		return "", nil, err
 	}
 
 	if _, err := tls.X509KeyPair(cert, key); err != nil {
-		return nil, fmt.Errorf("certificate and private key does not have a matching public key: %v", err)
+		return "", nil, fmt.Errorf("certificate and private key does not have a matching public key: %v", err)
+	}
+
+	pemCertBuffer.WriteString("\n")
+	pemCertBuffer.Write(key)
+
+	return pemCertBuffer.String(), pemCert, nil
+}
+
+// CreateSSLCert validates cert and key, extracts common names and returns corresponding SSLCert object
+func CreateSSLCert(cert, key []byte, uid string) (*ingress.SSLCert, error) {
+	pemCertKey, pemCert, err := createPemCertKey(cert, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSSLCert(pemCert, pemCertKey, uid), nil
+}
+
+// CreateKeylessSSLCert validates cert and the private key identified by keyID
+// in the DefaultKeyProvider and returns the corresponding SSLCert object. The
+// private key is not part of the SSLCert, NGINX loads it through the OpenSSL
+// engine configured with ssl-key-engine.
+func CreateKeylessSSLCert(cert []byte, keyID, uid string) (*ingress.SSLCert, error) {
+	if DefaultKeyProvider == nil {
+		return nil, fmt.Errorf("no key provider configured for the key %q", keyID)
+	}
+
+	pemCertBuffer, pemCert, err := createPemCert(cert)
+	if err != nil {
+		return nil, err
 	}
 
+	signer, err := DefaultKeyProvider.Signer(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkKeyOwnership(pemCert, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	sslCert := newSSLCert(pemCert, pemCertBuffer.String(), uid)
+	sslCert.KeyID = keyID
+
+	return sslCert, nil
+}
+
+// newSSLCert extracts the common names of the certificate and returns the
+// corresponding SSLCert object
+func newSSLCert(pemCert *x509.Certificate, pemCertKey, uid string) *ingress.SSLCert {
 	cn := sets.NewString(pemCert.Subject.CommonName)
 	for _, dns := range pemCert.DNSNames {
 		if !cn.Has(dns) {
@@ -132,9 +201,38 @@ func CreateSSLCert(cert, key []byte, uid string) (*ingress.SSLCert, error) {
 		PemSHA:      hex.EncodeToString(hasher.Sum(nil)),
 		CN:          cn.List(),
 		ExpireTime:  pemCert.NotAfter,
-		PemCertKey:  pemCertBuffer.String(),
+		PemCertKey:  pemCertKey,
 		UID:         uid,
-	}, nil
+	}
+}
+
+// AddECDSACert validates the ECDSA cert and key and adds them to an SSLCert
+// with an RSA certificate. NGINX serves the certificate supported by the
+// client.
+func AddECDSACert(sslCert *ingress.SSLCert, cert, key []byte) error {
+	if sslCert.Certificate == nil || sslCert.Certificate.PublicKeyAlgorithm != x509.RSA {
+		return fmt.Errorf("an ECDSA certificate can only be added to an RSA certificate")
+	}
+
+	pemCertKey, pemCert, err := createPemCertKey(cert, key)
+	if err != nil {
+		return err
+	}
+
+	if pemCert.PublicKeyAlgorithm != x509.ECDSA {
+		return fmt.Errorf("the certificate in %v is not an ECDSA certificate", ECDSACertKey)
+	}
+
+	hasher := sha1.New() // #nosec
+	hasher.Write(pemCert.Raw)
+
+	sslCert.ECDSAPemCertKey = pemCertKey
+	sslCert.ECDSAPemSHA = hex.EncodeToString(hasher.Sum(nil))
+	if pemCert.NotAfter.Before(sslCert.ExpireTime) {
+		sslCert.ExpireTime = pemCert.NotAfter
+	}
+
+	return nil
 }
 
 // CreateCACert is similar to CreateSSLCert but it creates instance of SSLCert only based on given ca after
@@ -188,6 +286,12 @@ func StoreSSLCertOnDisk(name string, sslCert *ingress.SSLCert) (string, error) {
 	return pemFileName, nil
 }
 
+// StoreECDSACertOnDisk creates a -ecdsa.pem file with content ECDSAPemCertKey
+// from the given sslCert
+func StoreECDSACertOnDisk(name string, sslCert *ingress.SSLCert) (string, error) {
+	return StoreSSLCertOnDisk(name+"-ecdsa", &ingress.SSLCert{PemCertKey: sslCert.ECDSAPemCertKey})
+}
+
 // ConfigureCACertWithCertAndKey appends ca into existing PEM file consisting of cert and key
 // and sets relevant fields in sslCert object
 func ConfigureCACertWithCertAndKey(_ string, ca []byte, sslCert *ingress.SSLCert) error {
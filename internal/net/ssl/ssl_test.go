@@ -19,6 +19,8 @@ package ssl
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -38,6 +40,7 @@ import (
 	"time"
 
 	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/ingress-nginx/pkg/apis/ingress"
 	"k8s.io/ingress-nginx/pkg/util/file"
 )
 
@@ -270,6 +273,60 @@ func TestCreateSSLCert(t *testing.T) {
 	}
 }
 
+func TestAddECDSACert(t *testing.T) {
+	rsaCert, ca, err := generateRSACerts("echoheaders")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	sslCert, err := CreateSSLCert(encodeCertPEM(rsaCert.Cert), encodePrivateKeyPEM(rsaCert.Key), FakeSSLCertificateUID)
+	if err != nil {
+		t.Fatalf("unexpected error checking SSL certificate: %v", err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error creating ECDSA key: %v", err)
+	}
+	config := certutil.Config{
+		CommonName: "echoheaders",
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	ecdsaCert, err := newSignedCert(&config, ecdsaKey, ca.Cert, ca.Key)
+	if err != nil {
+		t.Fatalf("unexpected error signing ECDSA certificate: %v", err)
+	}
+	ecdsaKeyDER, err := x509.MarshalECPrivateKey(ecdsaKey)
+	if err != nil {
+		t.Fatalf("unexpected error encoding ECDSA key: %v", err)
+	}
+	c := encodeCertPEM(ecdsaCert)
+	k := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecdsaKeyDER})
+
+	err = AddECDSACert(sslCert, c, k)
+	if err != nil {
+		t.Fatalf("unexpected error adding ECDSA certificate: %v", err)
+	}
+	if sslCert.ECDSAPemCertKey != string(c)+"\n"+string(k) {
+		t.Errorf("expected concatenated ECDSA PEM cert and key but returned %v", sslCert.ECDSAPemCertKey)
+	}
+	if sslCert.ECDSAPemSHA == "" {
+		t.Errorf("expected the sha of the ECDSA certificate")
+	}
+
+	ecdsaSSLCert, err := CreateSSLCert(c, k, FakeSSLCertificateUID)
+	if err != nil {
+		t.Fatalf("unexpected error checking SSL certificate: %v", err)
+	}
+	if err := AddECDSACert(ecdsaSSLCert, c, k); err == nil {
+		t.Errorf("expected an error adding an ECDSA certificate to an ECDSA certificate")
+	}
+
+	if err := AddECDSACert(&ingress.SSLCert{Certificate: rsaCert.Cert}, encodeCertPEM(rsaCert.Cert), encodePrivateKeyPEM(rsaCert.Key)); err == nil {
+		t.Errorf("expected an error adding an RSA certificate as ECDSA certificate")
+	}
+}
+
 type keyPair struct {
 	Key  *rsa.PrivateKey
 	Cert *x509.Certificate
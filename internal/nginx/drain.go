@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DrainPath defines the path of the connection draining in the NGINX status
+// server
+var DrainPath = "/configuration/drain"
+
+// ActiveRequests is the number of requests in flight per protocol
+type ActiveRequests struct {
+	HTTP      int `json:"http"`
+	WebSocket int `json:"websocket"`
+	GRPC      int `json:"grpc"`
+}
+
+// StartDrain rejects the new requests, the requests in flight are served
+// until NGINX stops
+func StartDrain() error {
+	statusCode, _, err := NewPostStatusRequest(DrainPath, "application/json", nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status code %v starting to drain", statusCode)
+	}
+
+	return nil
+}
+
+// GetActiveRequests returns the number of requests in flight per protocol
+func GetActiveRequests() (*ActiveRequests, error) {
+	statusCode, data, err := NewGetStatusRequest(DrainPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v getting the active requests", statusCode)
+	}
+
+	var requests ActiveRequests
+	err = json.Unmarshal(data, &requests)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requests, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// withStatusServer serves the NGINX status server of the test with handler
+func withStatusServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	statusPort := StatusPort
+	StatusPort, err = strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { StatusPort = statusPort })
+}
+
+func TestStartDrain(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{name: "draining", statusCode: http.StatusCreated},
+		{name: "unexpected status code", statusCode: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStatusServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != DrainPath {
+					t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.statusCode)
+			})
+
+			if err := StartDrain(); (err != nil) != tt.wantErr {
+				t.Errorf("StartDrain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetActiveRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       *ActiveRequests
+		wantErr    bool
+	}{
+		{
+			name:       "active requests",
+			statusCode: http.StatusOK,
+			body:       `{"http":3,"websocket":2,"grpc":1}`,
+			want:       &ActiveRequests{HTTP: 3, WebSocket: 2, GRPC: 1},
+		},
+		{name: "unexpected status code", statusCode: http.StatusNotFound, wantErr: true},
+		{name: "invalid body", statusCode: http.StatusOK, body: `{"http":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStatusServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != DrainPath {
+					t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.statusCode)
+				if _, err := w.Write([]byte(tt.body)); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+
+			got, err := GetActiveRequests()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetActiveRequests() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetActiveRequests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
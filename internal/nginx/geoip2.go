@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import "strings"
+
+// the providers of the GeoIP2 databases
+const (
+	GeoIP2ProviderMaxMind = "maxmind"
+	GeoIP2ProviderDBIP    = "dbip"
+	GeoIP2ProviderIPinfo  = "ipinfo"
+)
+
+// GeoIP2Variable is an NGINX variable set from a field of a GeoIP2 database
+type GeoIP2Variable struct {
+	Name string
+	// Path is the path of the field in the database, like "country iso_code"
+	Path string
+	// Default is the value of the variable when the address is not found
+	Default string
+}
+
+// geoIP2Edition describes the databases of an edition
+type geoIP2Edition struct {
+	Provider string
+	// Variables are the variables set from the database, the variables of
+	// the MaxMind databases are the ones of the NGINX template
+	Variables []GeoIP2Variable
+}
+
+// dbipCountryVariables are the variables of the DB-IP databases with the
+// country, they use the layout of the MaxMind databases
+var dbipCountryVariables = []GeoIP2Variable{
+	{Name: "geoip2_country_code", Path: "country iso_code"},
+	{Name: "geoip2_country_name", Path: "country names en"},
+	{Name: "geoip2_continent_code", Path: "continent code"},
+	{Name: "geoip2_continent_name", Path: "continent names en"},
+}
+
+// IPinfoASNVariable is the autonomous system of the IPinfo databases, like
+// AS15169, mapped to the number of $geoip2_asn
+const IPinfoASNVariable = "geoip2_ipinfo_asn"
+
+var geoIP2Editions = map[string]geoIP2Edition{
+	"GeoIP2-Anonymous-IP":    {Provider: GeoIP2ProviderMaxMind},
+	"GeoIP2-Country":         {Provider: GeoIP2ProviderMaxMind},
+	"GeoIP2-City":            {Provider: GeoIP2ProviderMaxMind},
+	"GeoIP2-Connection-Type": {Provider: GeoIP2ProviderMaxMind},
+	"GeoIP2-Domain":          {Provider: GeoIP2ProviderMaxMind},
+	"GeoIP2-ISP":             {Provider: GeoIP2ProviderMaxMind},
+	"GeoIP2-ASN":             {Provider: GeoIP2ProviderMaxMind},
+	"GeoLite2-ASN":           {Provider: GeoIP2ProviderMaxMind},
+	"GeoLite2-Country":       {Provider: GeoIP2ProviderMaxMind},
+	"GeoLite2-City":          {Provider: GeoIP2ProviderMaxMind},
+
+	"dbip-country-lite": {
+		Provider:  GeoIP2ProviderDBIP,
+		Variables: dbipCountryVariables,
+	},
+	"dbip-city-lite": {
+		Provider: GeoIP2ProviderDBIP,
+		Variables: []GeoIP2Variable{
+			{Name: "geoip2_city_country_code", Path: "country iso_code"},
+			{Name: "geoip2_city_country_name", Path: "country names en"},
+			{Name: "geoip2_city_continent_code", Path: "continent code"},
+			{Name: "geoip2_city_continent_name", Path: "continent names en"},
+			{Name: "geoip2_city", Path: "city names en"},
+			{Name: "geoip2_region_name", Path: "subdivisions 0 names en"},
+			{Name: "geoip2_latitude", Path: "location latitude"},
+			{Name: "geoip2_longitude", Path: "location longitude"},
+		},
+	},
+	"dbip-asn-lite": {
+		Provider: GeoIP2ProviderDBIP,
+		Variables: []GeoIP2Variable{
+			{Name: "geoip2_asn", Path: "autonomous_system_number", Default: "0"},
+			{Name: "geoip2_org", Path: "autonomous_system_organization"},
+		},
+	},
+
+	// the fields of the IPinfo databases are not nested
+	"ipinfo-lite": {
+		Provider: GeoIP2ProviderIPinfo,
+		Variables: []GeoIP2Variable{
+			{Name: "geoip2_country_code", Path: "country_code"},
+			{Name: "geoip2_country_name", Path: "country"},
+			{Name: "geoip2_continent_code", Path: "continent_code"},
+			{Name: "geoip2_continent_name", Path: "continent"},
+			{Name: IPinfoASNVariable, Path: "asn"},
+			{Name: "geoip2_org", Path: "as_name"},
+		},
+	},
+	"ipinfo-country": {
+		Provider: GeoIP2ProviderIPinfo,
+		Variables: []GeoIP2Variable{
+			{Name: "geoip2_country_code", Path: "country"},
+			{Name: "geoip2_country_name", Path: "country_name"},
+			{Name: "geoip2_continent_code", Path: "continent"},
+			{Name: "geoip2_continent_name", Path: "continent_name"},
+		},
+	},
+	"ipinfo-asn": {
+		Provider: GeoIP2ProviderIPinfo,
+		Variables: []GeoIP2Variable{
+			{Name: IPinfoASNVariable, Path: "asn"},
+			{Name: "geoip2_org", Path: "name"},
+		},
+	},
+	"ipinfo-country-asn": {
+		Provider: GeoIP2ProviderIPinfo,
+		Variables: []GeoIP2Variable{
+			{Name: "geoip2_country_code", Path: "country"},
+			{Name: "geoip2_country_name", Path: "country_name"},
+			{Name: "geoip2_continent_code", Path: "continent"},
+			{Name: "geoip2_continent_name", Path: "continent_name"},
+			{Name: IPinfoASNVariable, Path: "asn"},
+			{Name: "geoip2_org", Path: "as_name"},
+		},
+	},
+}
+
+// GeoIP2DatabaseLayout is a database file of a provider other than MaxMind,
+// with the variables it sets
+type GeoIP2DatabaseLayout struct {
+	File      string
+	Provider  string
+	Variables []GeoIP2Variable
+}
+
+// GeoIP2DatabaseLayouts returns the layouts of the database files of the
+// providers other than MaxMind
+func GeoIP2DatabaseLayouts(files []string) []GeoIP2DatabaseLayout {
+	layouts := []GeoIP2DatabaseLayout{}
+	for _, file := range files {
+		edition, ok := geoIP2Editions[strings.TrimSuffix(file, dbExtension)]
+		if !ok || edition.Provider == GeoIP2ProviderMaxMind {
+			continue
+		}
+
+		layouts = append(layouts, GeoIP2DatabaseLayout{
+			File:      file,
+			Provider:  edition.Provider,
+			Variables: edition.Variables,
+		})
+	}
+
+	return layouts
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGeoIP2DatabaseLayouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []GeoIP2DatabaseLayout
+	}{
+		{
+			name:  "no database",
+			files: nil,
+			want:  []GeoIP2DatabaseLayout{},
+		},
+		{
+			name:  "MaxMind databases",
+			files: []string{"GeoLite2-City.mmdb", "GeoIP2-ASN.mmdb"},
+			want:  []GeoIP2DatabaseLayout{},
+		},
+		{
+			name:  "unknown database",
+			files: []string{"custom-country.mmdb"},
+			want:  []GeoIP2DatabaseLayout{},
+		},
+		{
+			name:  "DB-IP and IPinfo databases",
+			files: []string{"GeoLite2-City.mmdb", "dbip-country-lite.mmdb", "ipinfo-asn.mmdb"},
+			want: []GeoIP2DatabaseLayout{
+				{File: "dbip-country-lite.mmdb", Provider: GeoIP2ProviderDBIP, Variables: dbipCountryVariables},
+				{
+					File:     "ipinfo-asn.mmdb",
+					Provider: GeoIP2ProviderIPinfo,
+					Variables: []GeoIP2Variable{
+						{Name: IPinfoASNVariable, Path: "asn"},
+						{Name: "geoip2_org", Path: "name"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GeoIP2DatabaseLayouts(tt.files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GeoIP2DatabaseLayouts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoIP2EditionsVariables(t *testing.T) {
+	for name, edition := range geoIP2Editions {
+		if edition.Provider == GeoIP2ProviderMaxMind {
+			if len(edition.Variables) != 0 {
+				t.Errorf("expected the MaxMind edition %v to use the variables of the template", name)
+			}
+			continue
+		}
+
+		if len(edition.Variables) == 0 {
+			t.Errorf("expected variables for the %v edition %v", edition.Provider, name)
+		}
+		seen := map[string]bool{}
+		for _, variable := range edition.Variables {
+			if variable.Name == "" || variable.Path == "" || seen[variable.Name] {
+				t.Errorf("invalid or duplicated variable %+v of the edition %v", variable, name)
+			}
+			seen[variable.Name] = true
+		}
+	}
+}
@@ -18,12 +18,15 @@ package nginx
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -42,6 +45,22 @@ var ProfilerAddress = "127.0.0.1"
 // TemplatePath path of the NGINX template
 var TemplatePath = "/etc/nginx/template/nginx.tmpl"
 
+// ModulesPath defines the directory of the dynamic modules of the NGINX image
+var ModulesPath = "/etc/nginx/modules"
+
+// NJSModule is the dynamic module running the njs scripts
+const NJSModule = "ngx_http_js_module"
+
+// ZstdModule is the dynamic module compressing the responses with Zstandard
+const ZstdModule = "ngx_http_zstd_filter_module"
+
+// IsModuleAvailable returns true when the dynamic module of the name was built
+// in the NGINX image the controller runs on
+func IsModuleAvailable(name string) bool {
+	_, err := os.Stat(filepath.Join(ModulesPath, name+".so"))
+	return err == nil
+}
+
 // PID defines the location of the pid file used by NGINX
 var PID = "/tmp/nginx/nginx.pid"
 
@@ -61,6 +80,21 @@ var StatusPath = "/nginx_status"
 // StreamPort defines the port used by NGINX for the NGINX stream configuration socket
 var StreamPort = 10247
 
+// AdminSocket defines the unix socket used by the ingress controller for the
+// administrative requests of the dbg command, like rendering the configuration
+// of an Ingress without applying it. It is only reachable from inside the pod.
+var AdminSocket = "/tmp/nginx/admin.sock"
+
+// DryRunPath defines the path used to render the configuration of an Ingress
+var DryRunPath = "/dry-run"
+
+// SnapshotsPath defines the path used to list the configuration snapshots and
+// to roll back to one of them
+var SnapshotsPath = "/snapshots"
+
+// PurgePath defines the path used to purge the responses of the proxy cache
+var PurgePath = "/purge"
+
 // NewGetStatusRequest creates a new GET request to the internal NGINX status server
 func NewGetStatusRequest(path string) (statusCode int, data []byte, err error) {
 	url := fmt.Sprintf("http://127.0.0.1:%v%v", StatusPort, path)
@@ -104,6 +138,45 @@ func NewPostStatusRequest(path, contentType string, data interface{}) (statusCod
 	return res.StatusCode, body, nil
 }
 
+// NewAdminRequest sends a request to the admin socket of the ingress controller
+func NewAdminRequest(method, path, contentType string, data []byte) (statusCode int, body []byte, err error) {
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", AdminSocket)
+			},
+		},
+	}
+
+	req, err := http.NewRequest(method, "http://localhost"+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode, body, nil
+}
+
+// NewDryRunRequest sends an Ingress manifest, in YAML or JSON, to the dry-run
+// endpoint of the admin socket of the ingress controller
+func NewDryRunRequest(manifest []byte) (statusCode int, body []byte, err error) {
+	return NewAdminRequest(http.MethodPost, DryRunPath, "application/yaml", manifest)
+}
+
 // GetServerBlock takes an nginx.conf file and a host and tries to find the server block for that host
 func GetServerBlock(conf, host string) (string, error) {
 	startMsg := fmt.Sprintf("## start server %v\n", host)
@@ -52,11 +52,19 @@ var MaxmindRetriesCount = 1
 // MaxmindRetriesTimeout maxmind download retries timeout in seconds, 0 - do not retry to download if something went wrong
 var MaxmindRetriesTimeout = time.Second * 0
 
+// MaxmindUpdateInterval interval between the updates of the GeoIP2 databases, 0 - the databases are only downloaded at startup
+var MaxmindUpdateInterval = time.Duration(0)
+
 // minimumRetriesCount minimum value of the MaxmindRetriesCount parameter. If MaxmindRetriesCount less than minimumRetriesCount, it will be set to minimumRetriesCount
 const minimumRetriesCount = 1
 
+var geoIPPath = "/etc/ingress-controller/geoip"
+
+// geoIP2CheckAddress is looked up in the downloaded databases before they
+// replace the current ones
+var geoIP2CheckAddress = net.ParseIP("1.1.1.1")
+
 const (
-	geoIPPath   = "/etc/ingress-controller/geoip"
 	dbExtension = ".mmdb"
 
 	maxmindURL = "https://download.maxmind.com/app/geoip_download?license_key=%v&edition_id=%v&suffix=tar.gz"
@@ -104,6 +112,12 @@ func DownloadGeoLite2DB(attempts int, period time.Duration) error {
 	lastErr = wait.ExponentialBackoff(defaultRetry, func() (bool, error) {
 		var dlError error
 		for _, dbName := range strings.Split(MaxmindEditionIDs, ",") {
+			// the databases of the other providers are only downloaded
+			// from a mirror, or mounted
+			if MaxmindMirror == "" && geoIP2Editions[dbName].Provider != GeoIP2ProviderMaxMind {
+				klog.InfoS("skipping download of GeoIP2 database without mirror", "database", dbName)
+				continue
+			}
 			dlError = downloadDatabase(dbName)
 			if dlError != nil {
 				break
@@ -179,19 +193,7 @@ func downloadDatabase(dbName string) error {
 			if !strings.HasSuffix(header.Name, mmdbFile) {
 				continue
 			}
-			return func() error {
-				outFile, err := os.Create(path.Join(geoIPPath, mmdbFile))
-				if err != nil {
-					return err
-				}
-
-				defer outFile.Close()
-
-				if _, err := io.CopyN(outFile, tarReader, header.Size); err != nil {
-					return err
-				}
-				return nil
-			}()
+			return replaceDatabase(mmdbFile, io.LimitReader(tarReader, header.Size))
 		}
 	}
 
@@ -199,24 +201,122 @@ func downloadDatabase(dbName string) error {
 		fmt.Sprintf(maxmindURL, "XXXXXXX", dbName), mmdbFile)
 }
 
-// ValidateGeoLite2DBEditions check provided Maxmind database editions names
-func ValidateGeoLite2DBEditions() error {
-	allowedEditions := map[string]bool{
-		"GeoIP2-Anonymous-IP":    true,
-		"GeoIP2-Country":         true,
-		"GeoIP2-City":            true,
-		"GeoIP2-Connection-Type": true,
-		"GeoIP2-Domain":          true,
-		"GeoIP2-ISP":             true,
-		"GeoIP2-ASN":             true,
-		"GeoLite2-ASN":           true,
-		"GeoLite2-Country":       true,
-		"GeoLite2-City":          true,
+// replaceDatabase writes the database to a temporary file and renames it to
+// the database file, NGINX never reads a partially written database. The
+// invalid databases, the ones of another edition and the ones older than the
+// current database are discarded.
+func replaceDatabase(mmdbFile string, r io.Reader) error {
+	tmpFile, err := os.CreateTemp(geoIPPath, mmdbFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := io.Copy(tmpFile, r); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0o644); err != nil {
+		return err
+	}
+
+	metadata, err := ReadGeoIP2Metadata(tmpFile.Name())
+	if err != nil {
+		return err
+	}
+
+	// the database_type of the other providers is not the edition name
+	edition := strings.TrimSuffix(mmdbFile, dbExtension)
+	if geoIP2Editions[edition].Provider == GeoIP2ProviderMaxMind && metadata.DatabaseType != edition {
+		return fmt.Errorf("the database %v is a %v database, expected %v", mmdbFile, metadata.DatabaseType, edition)
+	}
+
+	if _, err := LookupGeoIP2Database(tmpFile.Name(), geoIP2CheckAddress); err != nil {
+		return err
+	}
+
+	dbFile := path.Join(geoIPPath, mmdbFile)
+	if current, err := ReadGeoIP2Metadata(dbFile); err == nil && current.BuildEpoch >= metadata.BuildEpoch {
+		klog.V(2).InfoS("GeoIP2 database is up to date", "database", mmdbFile, "build", time.Unix(int64(current.BuildEpoch), 0))
+		return nil
 	}
 
+	if err := os.Rename(tmpFile.Name(), dbFile); err != nil {
+		return err
+	}
+
+	klog.InfoS("updated GeoIP2 database", "database", mmdbFile, "build", time.Unix(int64(metadata.BuildEpoch), 0))
+	return nil
+}
+
+// UpdateGeoLite2DB downloads the databases every MaxmindUpdateInterval until
+// the channel is closed. NGINX reopens the updated databases with the
+// auto_reload of the GeoIP2 module, without a reload of the configuration.
+func UpdateGeoLite2DB(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(MaxmindUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			klog.V(2).InfoS("updating maxmind GeoIP2 databases")
+			if err := DownloadGeoLite2DB(MaxmindRetriesCount, MaxmindRetriesTimeout); err != nil {
+				klog.ErrorS(err, "unexpected error updating GeoIP2 database")
+			}
+		}
+	}
+}
+
+// GeoIP2Database describes a GeoIP2 database on disk
+type GeoIP2Database struct {
+	Edition string
+	// BuildEpoch is the Unix time the database was built by MaxMind
+	BuildEpoch int64
+	// UpdatedAt is the Unix time the database was written to disk
+	UpdatedAt int64
+}
+
+// GetGeoIP2Databases returns the GeoIP2 databases of the configured editions
+// present on disk, the invalid databases are skipped
+func GetGeoIP2Databases() []GeoIP2Database {
+	databases := []GeoIP2Database{}
+	if MaxmindEditionIDs == "" {
+		return databases
+	}
+
+	for _, dbName := range strings.Split(MaxmindEditionIDs, ",") {
+		dbFile := path.Join(geoIPPath, dbName+dbExtension)
+		info, err := os.Stat(dbFile)
+		if err != nil {
+			continue
+		}
+
+		metadata, err := ReadGeoIP2Metadata(dbFile)
+		if err != nil {
+			klog.Warningf("unexpected error reading GeoIP2 database: %v", err)
+			continue
+		}
+
+		databases = append(databases, GeoIP2Database{
+			Edition:    dbName,
+			BuildEpoch: int64(metadata.BuildEpoch),
+			UpdatedAt:  info.ModTime().Unix(),
+		})
+	}
+
+	return databases
+}
+
+// ValidateGeoLite2DBEditions check provided database editions names, of MaxMind or the other GeoIP2 providers
+func ValidateGeoLite2DBEditions() error {
 	for _, edition := range strings.Split(MaxmindEditionIDs, ",") {
-		if !allowedEditions[edition] {
-			return fmt.Errorf("unknown Maxmind GeoIP2 edition name: '%s'", edition)
+		if _, ok := geoIP2Editions[edition]; !ok {
+			return fmt.Errorf("unknown GeoIP2 edition name: '%s'", edition)
 		}
 	}
 	return nil
@@ -73,3 +73,21 @@ func TestGeoLite2DBExists(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateGeoLite2DBEditions(t *testing.T) {
+	defer resetForTesting()
+
+	for editions, valid := range map[string]bool{
+		"GeoLite2-City,GeoLite2-ASN":         true,
+		"dbip-city-lite,dbip-asn-lite":       true,
+		"ipinfo-lite":                        true,
+		"ipinfo-country,GeoIP2-Anonymous-IP": true,
+		"GeoLite2-Region":                    false,
+		"ipinfo-city":                        false,
+	} {
+		MaxmindEditionIDs = editions
+		if err := ValidateGeoLite2DBEditions(); (err == nil) != valid {
+			t.Errorf("ValidateGeoLite2DBEditions() with %v returned %v", editions, err)
+		}
+	}
+}
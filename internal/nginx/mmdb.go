@@ -0,0 +1,423 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataStartMarker precedes the metadata at the end of the MaxMind
+// databases, see https://maxmind.github.io/MaxMind-DB/#database-metadata
+var metadataStartMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+const (
+	// maxMetadataSize is the maximum size of the metadata section
+	maxMetadataSize = 128 * 1024
+	// maxDecodeDepth is the maximum depth of the maps, arrays and pointers of
+	// the metadata and the data section
+	maxDecodeDepth = 32
+	// dataSectionSeparatorSize is the size of the zeros between the search
+	// tree and the data section
+	dataSectionSeparatorSize = 16
+)
+
+// the types of the data section format of the MaxMind databases
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// GeoIP2Metadata describes a MaxMind database
+type GeoIP2Metadata struct {
+	// DatabaseType is the edition of the database, like GeoLite2-City
+	DatabaseType string
+	// BuildEpoch is the Unix time the database was built
+	BuildEpoch uint64
+	NodeCount  uint64
+	// RecordSize is the size in bits of the records of the search tree
+	RecordSize uint64
+	// IPVersion is 6 when the search tree contains IPv6 addresses
+	IPVersion uint64
+}
+
+// ReadGeoIP2Metadata returns the metadata of a MaxMind database, or an error
+// if the file is not a MaxMind database
+func ReadGeoIP2Metadata(filename string) (*GeoIP2Metadata, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	metadata, _, err := readGeoIP2Metadata(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+
+	return metadata, nil
+}
+
+// readGeoIP2Metadata returns the metadata of a MaxMind database and the
+// offset of the metadata, which ends the data section
+func readGeoIP2Metadata(f *os.File) (*GeoIP2Metadata, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := info.Size()
+	if size > maxMetadataSize {
+		size = maxMetadataSize
+	}
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, info.Size()-size); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+
+	start := bytes.LastIndex(data, metadataStartMarker)
+	if start == -1 {
+		return nil, 0, fmt.Errorf("not a MaxMind database")
+	}
+
+	fieldsData := data[start+len(metadataStartMarker):]
+	d := &mmdbDecoder{r: bytes.NewReader(fieldsData), size: int64(len(fieldsData))}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid metadata of the MaxMind database: %w", err)
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid metadata of the MaxMind database: not a map")
+	}
+
+	metadata := &GeoIP2Metadata{}
+	metadata.DatabaseType, _ = fields["database_type"].(string)
+	metadata.BuildEpoch, _ = fields["build_epoch"].(uint64)
+	metadata.NodeCount, _ = fields["node_count"].(uint64)
+	metadata.RecordSize, _ = fields["record_size"].(uint64)
+	metadata.IPVersion, _ = fields["ip_version"].(uint64)
+	if metadata.DatabaseType == "" || metadata.BuildEpoch == 0 || metadata.NodeCount == 0 {
+		return nil, 0, fmt.Errorf("invalid metadata of the MaxMind database: missing database_type, build_epoch or node_count")
+	}
+
+	return metadata, info.Size() - size + int64(start), nil
+}
+
+// LookupGeoIP2Database returns the data of an address in a MaxMind
+// database, or nil if the database does not contain the address. It walks
+// the search tree and decodes the data section like the GeoIP2 module of
+// NGINX, the databases it cannot read return an error.
+func LookupGeoIP2Database(filename string, ip net.IP) (interface{}, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	metadata, metadataStart, err := readGeoIP2Metadata(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+
+	if metadata.RecordSize != 24 && metadata.RecordSize != 28 && metadata.RecordSize != 32 {
+		return nil, fmt.Errorf("%v: unsupported record size %v", filename, metadata.RecordSize)
+	}
+
+	var address net.IP
+	switch metadata.IPVersion {
+	case 4:
+		address = ip.To4()
+		if address == nil {
+			return nil, fmt.Errorf("%v: the database does not contain IPv6 addresses", filename)
+		}
+	case 6:
+		// the IPv4 addresses are in the ::/96 subtree
+		address = ip.To16()
+		if ip4 := ip.To4(); ip4 != nil {
+			address = append(make(net.IP, net.IPv6len-net.IPv4len), ip4...)
+		}
+	default:
+		return nil, fmt.Errorf("%v: unsupported IP version %v", filename, metadata.IPVersion)
+	}
+
+	nodeSize := int64(metadata.RecordSize) / 4
+	if metadata.NodeCount > uint64(metadataStart)/uint64(nodeSize) {
+		return nil, fmt.Errorf("%v: the search tree is larger than the database", filename)
+	}
+	treeSize := int64(metadata.NodeCount) * nodeSize
+	dataStart := treeSize + dataSectionSeparatorSize
+	if dataStart > metadataStart {
+		return nil, fmt.Errorf("%v: the search tree is larger than the database", filename)
+	}
+	dataSize := metadataStart - dataStart
+
+	separator := make([]byte, dataSectionSeparatorSize)
+	if _, err := f.ReadAt(separator, treeSize); err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	if !bytes.Equal(separator, make([]byte, dataSectionSeparatorSize)) {
+		return nil, fmt.Errorf("%v: missing data section separator after the search tree", filename)
+	}
+
+	node := make([]byte, nodeSize)
+	record := uint64(0)
+	for i := 0; i < len(address)*8 && record < metadata.NodeCount; i++ {
+		if _, err := f.ReadAt(node, int64(record)*nodeSize); err != nil {
+			return nil, fmt.Errorf("%v: %w", filename, err)
+		}
+		record = readRecord(node, metadata.RecordSize, address[i/8]>>(7-i%8)&1)
+	}
+
+	switch {
+	case record == metadata.NodeCount:
+		return nil, nil
+	case record < metadata.NodeCount:
+		return nil, fmt.Errorf("%v: the search tree is deeper than the addresses", filename)
+	}
+
+	offset := record - metadata.NodeCount - dataSectionSeparatorSize
+	if record < metadata.NodeCount+dataSectionSeparatorSize || offset >= uint64(dataSize) {
+		return nil, fmt.Errorf("%v: the record %v is outside of the data section", filename, record)
+	}
+
+	d := &mmdbDecoder{
+		r:        io.NewSectionReader(f, dataStart, dataSize),
+		size:     dataSize,
+		offset:   int64(offset),
+		pointers: true,
+	}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%v: invalid data section: %w", filename, err)
+	}
+
+	return value, nil
+}
+
+// readRecord returns the left record of a node of the search tree when the
+// bit is 0, and the right one otherwise
+func readRecord(node []byte, recordSize uint64, bit byte) uint64 {
+	uint24 := func(b []byte) uint64 {
+		return uint64(b[0])<<16 | uint64(b[1])<<8 | uint64(b[2])
+	}
+
+	switch recordSize {
+	case 24:
+		if bit == 0 {
+			return uint24(node[0:3])
+		}
+		return uint24(node[3:6])
+	case 28:
+		// the middle byte holds the most significant bits of both records
+		if bit == 0 {
+			return uint64(node[3]&0xf0)<<20 | uint24(node[0:3])
+		}
+		return uint64(node[3]&0x0f)<<24 | uint24(node[4:7])
+	default:
+		if bit == 0 {
+			return uint64(binary.BigEndian.Uint32(node[0:4]))
+		}
+		return uint64(binary.BigEndian.Uint32(node[4:8]))
+	}
+}
+
+// mmdbDecoder decodes the data section format of the MaxMind databases, the
+// pointers are only allowed in the data section
+type mmdbDecoder struct {
+	r        io.ReaderAt
+	size     int64
+	offset   int64
+	pointers bool
+}
+
+func (d *mmdbDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.offset+int64(n) > d.size {
+		return nil, fmt.Errorf("unexpected end of the data")
+	}
+	b := make([]byte, n)
+	if _, err := d.r.ReadAt(b, d.offset); err != nil {
+		return nil, err
+	}
+	d.offset += int64(n)
+	return b, nil
+}
+
+// pointer decodes the value a pointer points to, the size bits of the
+// control byte are part of the offset
+func (d *mmdbDecoder) pointer(control byte, depth int) (interface{}, error) {
+	b, err := d.next(int(control>>3&0x3) + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := int64(control & 0x7)
+	for _, v := range b {
+		offset = offset<<8 | int64(v)
+	}
+	switch len(b) {
+	case 2:
+		offset += 2048
+	case 3:
+		offset += 526336
+	case 4:
+		offset = int64(binary.BigEndian.Uint32(b))
+	}
+
+	target := &mmdbDecoder{r: d.r, size: d.size, offset: offset, pointers: true}
+	return target.decode(depth + 1)
+}
+
+func (d *mmdbDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("the data is nested too deeply")
+	}
+
+	control, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	dataType := int(control[0] >> 5)
+	if dataType == mmdbExtended {
+		extended, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		dataType = 7 + int(extended[0])
+	}
+	if dataType == mmdbPointer {
+		if !d.pointers {
+			return nil, fmt.Errorf("unexpected pointer in the metadata")
+		}
+		return d.pointer(control[0], depth)
+	}
+
+	size := int(control[0] & 0x1f)
+	if size >= 29 {
+		extra, err := d.next(size - 28)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 29:
+			size = 29 + int(extra[0])
+		case 30:
+			size = 285 + int(extra[0])<<8 | int(extra[1])
+		default:
+			size = 65821 + (int(extra[0])<<16 | int(extra[1])<<8 | int(extra[2]))
+		}
+	}
+
+	switch dataType {
+	case mmdbString:
+		b, err := d.next(size)
+		return string(b), err
+	case mmdbBytes, mmdbUint128:
+		return d.next(size)
+	case mmdbDouble:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case mmdbFloat:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		if size > 8 {
+			return nil, fmt.Errorf("invalid size %v of an unsigned integer", size)
+		}
+		b, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		value := uint64(0)
+		for _, v := range b {
+			value = value<<8 | uint64(v)
+		}
+		return value, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, fmt.Errorf("invalid size %v of a signed integer", size)
+		}
+		b, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		value := uint32(0)
+		for _, v := range b {
+			value = value<<8 | uint32(v)
+		}
+		return int32(value), nil
+	case mmdbBool:
+		return size != 0, nil
+	case mmdbMap:
+		fields := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("the keys of the maps must be strings")
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = value
+		}
+		return fields, nil
+	case mmdbArray:
+		values := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unexpected type %v in the data", dataType)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testDatabase returns a MaxMind database of the edition built at the Unix
+// time. The search tree has a single node, the addresses starting with a 0
+// bit point to {"country": {"iso_code": "AU"}} and the other ones are not in
+// the database.
+func testDatabase(edition string, buildEpoch uint64) []byte {
+	var b bytes.Buffer
+
+	str := func(s string) {
+		b.WriteByte(byte(mmdbString<<5 | len(s)))
+		b.WriteString(s)
+	}
+
+	// search tree with 24 bit records, the data of the left record is after
+	// the pointed to map, the right record is the node count
+	b.Write([]byte{0, 0, 17 + 13, 0, 0, 1})
+	b.Write(make([]byte, dataSectionSeparatorSize))
+
+	// data section
+	b.WriteByte(byte(mmdbMap<<5 | 1))
+	str("iso_code")
+	str("AU")
+	b.WriteByte(byte(mmdbMap<<5 | 1))
+	str("country")
+	b.WriteByte(byte(mmdbPointer << 5))
+	b.WriteByte(0)
+
+	b.Write(metadataStartMarker)
+
+	b.WriteByte(byte(mmdbMap<<5 | 6))
+	str("database_type")
+	str(edition)
+	str("build_epoch")
+	// uint64 is an extended type
+	b.WriteByte(8)
+	b.WriteByte(mmdbUint64 - 7)
+	_ = binary.Write(&b, binary.BigEndian, buildEpoch)
+	str("node_count")
+	b.WriteByte(byte(mmdbUint32<<5 | 1))
+	b.WriteByte(1)
+	str("record_size")
+	b.WriteByte(byte(mmdbUint16<<5 | 1))
+	b.WriteByte(24)
+	str("ip_version")
+	b.WriteByte(byte(mmdbUint16<<5 | 1))
+	b.WriteByte(4)
+	str("languages")
+	// array is an extended type
+	b.WriteByte(2)
+	b.WriteByte(mmdbArray - 7)
+	str("en")
+	str("de")
+
+	return b.Bytes()
+}
+
+func TestReadGeoIP2Metadata(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    *GeoIP2Metadata
+	}{
+		{
+			name:    "valid database",
+			content: testDatabase("GeoLite2-City", 1700000000),
+			want: &GeoIP2Metadata{
+				DatabaseType: "GeoLite2-City",
+				BuildEpoch:   1700000000,
+				NodeCount:    1,
+				RecordSize:   24,
+				IPVersion:    4,
+			},
+		},
+		{
+			name:    "not a database",
+			content: []byte("<html>Too many requests</html>"),
+		},
+		{
+			name:    "truncated metadata",
+			content: testDatabase("GeoLite2-City", 1700000000)[:40],
+		},
+		{
+			name:    "missing build epoch",
+			content: testDatabase("GeoLite2-City", 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "test.mmdb")
+			if err := os.WriteFile(filename, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadGeoIP2Metadata(filename)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLookupGeoIP2Database(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.mmdb")
+
+	corrupt := testDatabase("GeoLite2-City", 1700000000)
+	// the left record points outside of the data section
+	corrupt[2] = 0xff
+	noSeparator := testDatabase("GeoLite2-City", 1700000000)
+	noSeparator[6] = 1
+
+	tests := []struct {
+		name    string
+		content []byte
+		ip      string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:    "found",
+			content: testDatabase("GeoLite2-City", 1700000000),
+			ip:      "1.1.1.1",
+			want:    map[string]interface{}{"country": map[string]interface{}{"iso_code": "AU"}},
+		},
+		{
+			name:    "not found",
+			content: testDatabase("GeoLite2-City", 1700000000),
+			ip:      "192.168.0.1",
+		},
+		{
+			name:    "record outside of the data section",
+			content: corrupt,
+			ip:      "1.1.1.1",
+			wantErr: true,
+		},
+		{
+			name:    "missing data section separator",
+			content: noSeparator,
+			ip:      "1.1.1.1",
+			wantErr: true,
+		},
+		{
+			name:    "IPv6 address in an IPv4 database",
+			content: testDatabase("GeoLite2-City", 1700000000),
+			ip:      "2001:db8::1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filename, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LookupGeoIP2Database(filename, net.ParseIP(tt.ip))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplaceDatabase(t *testing.T) {
+	defer func(p string) { geoIPPath = p }(geoIPPath)
+	geoIPPath = t.TempDir()
+
+	corrupt := testDatabase("GeoLite2-City", 1700700000)
+	corrupt[2] = 0xff
+
+	buildEpoch := func() uint64 {
+		metadata, err := ReadGeoIP2Metadata(filepath.Join(geoIPPath, "GeoLite2-City.mmdb"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return metadata.BuildEpoch
+	}
+
+	steps := []struct {
+		content []byte
+		wantErr bool
+		want    uint64
+	}{
+		{content: testDatabase("GeoLite2-City", 1700000000), want: 1700000000},
+		{content: testDatabase("GeoLite2-City", 1700600000), want: 1700600000},
+		// an older database does not replace the current one
+		{content: testDatabase("GeoLite2-City", 1700000000), want: 1700600000},
+		{content: []byte("invalid"), wantErr: true, want: 1700600000},
+		// a database of another edition does not replace the current one
+		{content: testDatabase("GeoLite2-Country", 1700700000), wantErr: true, want: 1700600000},
+		// a database the lookup fails on does not replace the current one
+		{content: corrupt, wantErr: true, want: 1700600000},
+	}
+
+	for i, step := range steps {
+		err := replaceDatabase("GeoLite2-City.mmdb", bytes.NewReader(step.content))
+		if step.wantErr != (err != nil) {
+			t.Errorf("step %v: unexpected error: %v", i, err)
+		}
+		if got := buildEpoch(); got != step.want {
+			t.Errorf("step %v: expected the database built at %v, got %v", i, step.want, got)
+		}
+	}
+
+	files, err := os.ReadDir(geoIPPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".tmp") {
+			t.Errorf("unexpected temporary file %v", f.Name())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// OCSPPath defines the path of the OCSP stapling status of the hosts in the
+// NGINX status server
+var OCSPPath = "/configuration/ocsp"
+
+// OCSPStatus describes the last OCSP fetch of the certificate of a host
+type OCSPStatus struct {
+	Hostname string `json:"hostname"`
+	// Success indicates if the last fetch returned a valid OCSP response
+	Success bool `json:"success"`
+	// Error describes why the last fetch failed
+	Error string `json:"error,omitempty"`
+	// FetchedAt is the Unix time of the last fetch
+	FetchedAt int64 `json:"fetchedAt"`
+	// ThisUpdate is the Unix time the last valid OCSP response was produced
+	ThisUpdate int64 `json:"thisUpdate,omitempty"`
+	// NextUpdate is the Unix time the last valid OCSP response expires, zero
+	// when the responder does not set it
+	NextUpdate int64 `json:"nextUpdate,omitempty"`
+	// Failures is the number of consecutive failed fetches
+	Failures int `json:"failures"`
+}
+
+// GetOCSPStatus returns the OCSP stapling status of the hosts with a fetched
+// OCSP response
+func GetOCSPStatus() ([]OCSPStatus, error) {
+	statusCode, data, err := NewGetStatusRequest(OCSPPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v getting the OCSP status", statusCode)
+	}
+
+	var status []OCSPStatus
+	err = json.Unmarshal(data, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGetOCSPStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       []OCSPStatus
+		wantErr    bool
+	}{
+		{
+			name:       "fetched responses",
+			statusCode: http.StatusOK,
+			body: `[{"hostname":"app.example.com","success":true,"fetchedAt":1700000000,"thisUpdate":1699990000,"nextUpdate":1700600000,"failures":0},` +
+				`{"hostname":"shop.example.com","success":false,"error":"responder unavailable","fetchedAt":1700000000,"failures":3}]`,
+			want: []OCSPStatus{
+				{Hostname: "app.example.com", Success: true, FetchedAt: 1700000000, ThisUpdate: 1699990000, NextUpdate: 1700600000},
+				{Hostname: "shop.example.com", Error: "responder unavailable", FetchedAt: 1700000000, Failures: 3},
+			},
+		},
+		{name: "no fetched response", statusCode: http.StatusOK, body: `[]`, want: []OCSPStatus{}},
+		{name: "unexpected status code", statusCode: http.StatusServiceUnavailable, wantErr: true},
+		{name: "invalid body", statusCode: http.StatusOK, body: `{"hostname":"app.example.com"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStatusServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != OCSPPath {
+					t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.statusCode)
+				if _, err := w.Write([]byte(tt.body)); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+
+			got, err := GetOCSPStatus()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetOCSPStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOCSPStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package task
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/klog/v2"
@@ -45,6 +46,13 @@ type Queue struct {
 	fn func(obj interface{}) (interface{}, error)
 	// lastSync is the Unix epoch time of the last execution of 'sync'
 	lastSync int64
+	// quietPeriod is the time without new elements the worker waits for
+	// before calling 'sync', to coalesce bursts of changes. The wait is
+	// bounded by maxDelay.
+	quietPeriod time.Duration
+	maxDelay    time.Duration
+	// lastEnqueue is the Unix epoch time of the last enqueued element
+	lastEnqueue int64
 }
 
 // Element represents one item of the queue
@@ -88,6 +96,7 @@ func (t *Queue) enqueue(obj interface{}, skippable bool) {
 		klog.ErrorS(err, "creating object key", "item", obj)
 		return
 	}
+	atomic.StoreInt64(&t.lastEnqueue, time.Now().UnixNano())
 	t.queue.Add(Element{
 		Key:       key,
 		Timestamp: ts,
@@ -113,7 +122,6 @@ func (t *Queue) worker() {
 			}
 			return
 		}
-		ts := time.Now().UnixNano()
 
 		item, ok := key.(Element)
 		if !ok {
@@ -126,6 +134,10 @@ func (t *Queue) worker() {
 			continue
 		}
 
+		// changes enqueued while waiting are covered by this sync
+		t.waitForQuietPeriod()
+		ts := time.Now().UnixNano()
+
 		klog.V(3).InfoS("syncing", "key", item.Key)
 		if err := t.sync(key); err != nil {
 			klog.ErrorS(err, "requeuing", "key", item.Key)
@@ -142,6 +154,33 @@ func (t *Queue) worker() {
 	}
 }
 
+// waitForQuietPeriod waits until no element was enqueued during the quiet
+// period, or until the maximum delay elapsed
+func (t *Queue) waitForQuietPeriod() {
+	if t.quietPeriod <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(t.maxDelay)
+	for !t.IsShuttingDown() {
+		now := time.Now()
+		quietUntil := time.Unix(0, atomic.LoadInt64(&t.lastEnqueue)).Add(t.quietPeriod)
+		if !now.Before(quietUntil) {
+			return
+		}
+		if !now.Before(deadline) {
+			klog.V(3).InfoS("maximum sync delay reached, syncing before the quiet period", "maxDelay", t.maxDelay)
+			return
+		}
+
+		wait := quietUntil.Sub(now)
+		if remaining := deadline.Sub(now); remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+	}
+}
+
 func isClosed(ch <-chan bool) bool {
 	select {
 	case <-ch:
@@ -152,6 +191,11 @@ func isClosed(ch <-chan bool) bool {
 	return false
 }
 
+// Len returns the number of elements waiting in the queue
+func (t *Queue) Len() int {
+	return t.queue.Len()
+}
+
 // Shutdown shuts down the work queue and waits for the worker to ACK
 func (t *Queue) Shutdown() {
 	t.queue.ShutDown()
@@ -185,6 +229,17 @@ func NewCustomTaskQueue(syncFn func(interface{}) error, fn func(interface{}) (in
 	return q
 }
 
+// NewDebouncedTaskQueue creates a new task queue with the given sync function.
+// The sync function is called once no element was enqueued during the quiet
+// period, delaying it for at most maxDelay.
+func NewDebouncedTaskQueue(syncFn func(interface{}) error, quietPeriod, maxDelay time.Duration) *Queue {
+	q := NewCustomTaskQueue(syncFn, nil)
+	q.quietPeriod = quietPeriod
+	q.maxDelay = maxDelay
+
+	return q
+}
+
 // GetDummyObject returns a valid object that can be used in the Queue
 func GetDummyObject(name string) *metav1.ObjectMeta {
 	return &metav1.ObjectMeta{
@@ -157,3 +157,52 @@ func TestSkipEnqueue(t *testing.T) {
 	// shutdown queue before exit
 	q.Shutdown()
 }
+
+func TestDebounce(t *testing.T) {
+	// initialize result
+	atomic.StoreUint32(&sr, 0)
+	q := NewDebouncedTaskQueue(mockSynFn, 50*time.Millisecond, time.Second)
+	stopCh := make(chan struct{})
+	// run queue
+	go q.Run(time.Second, stopCh)
+	// mock object which will be enqueue
+	mo := GetDummyObject("testKey")
+	// a burst of changes shorter than the quiet period between each other
+	for i := 0; i < 5; i++ {
+		q.EnqueueSkippableTask(mo)
+		time.Sleep(time.Millisecond * 20)
+	}
+	if atomic.LoadUint32(&sr) != 0 {
+		t.Errorf("sr should be 0 before the quiet period, but is %d", sr)
+	}
+	// wait for the quiet period and 'mockSynFn'
+	time.Sleep(time.Millisecond * 100)
+	if atomic.LoadUint32(&sr) != 1 {
+		t.Errorf("sr should be 1, but is %d", sr)
+	}
+
+	// shutdown queue before exit
+	q.Shutdown()
+}
+
+func TestDebounceMaxDelay(t *testing.T) {
+	// initialize result
+	atomic.StoreUint32(&sr, 0)
+	q := NewDebouncedTaskQueue(mockSynFn, 50*time.Millisecond, 100*time.Millisecond)
+	stopCh := make(chan struct{})
+	// run queue
+	go q.Run(time.Second, stopCh)
+	// mock object which will be enqueue
+	mo := GetDummyObject("testKey")
+	// changes keep arriving for longer than the maximum delay
+	for i := 0; i < 10; i++ {
+		q.EnqueueSkippableTask(mo)
+		time.Sleep(time.Millisecond * 20)
+	}
+	if atomic.LoadUint32(&sr) == 0 {
+		t.Errorf("sr should not be 0 after the maximum delay")
+	}
+
+	// shutdown queue before exit
+	q.Shutdown()
+}
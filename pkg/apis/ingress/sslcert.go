@@ -63,6 +63,21 @@ type SSLCert struct {
 
 	// UID unique identifier of the Kubernetes Secret
 	UID string `json:"uid"`
+
+	// ECDSAPemCertKey contains the ECDSA certificate and key concatenated,
+	// when the Secret contains both an RSA and an ECDSA certificate
+	ECDSAPemCertKey string `json:"ecdsaPemCertKey,omitempty"`
+
+	// ECDSAPemFileName contains the path to the file with the ECDSA
+	// certificate and key concatenated
+	ECDSAPemFileName string `json:"ecdsaPemFileName,omitempty"`
+
+	// ECDSAPemSHA contains the sha1 of the ECDSA certificate
+	ECDSAPemSHA string `json:"ecdsaPemSha,omitempty"`
+
+	// KeyID identifies the private key in the external key provider. When
+	// set, PemCertKey only contains the certificate
+	KeyID string `json:"keyId,omitempty"`
 }
 
 // GetObjectKind implements the ObjectKind interface as a noop
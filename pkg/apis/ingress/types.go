@@ -25,22 +25,40 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/botdetection"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/brotli"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/conditionalredirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/csrf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customerrorpages"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/fastcgi"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoaccess"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/globalratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/healthcheck"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipallowlist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipdenylist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/maintenance"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/njs"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/normalization"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/opentelemetry"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/outlierdetection"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/plugins"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/securityheaders"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/trailingslash"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/websocket"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/zstd"
 )
 
 // TODO: The API shouldn't be importing structs from annotation code. Instead we probably want a conversion from internal
@@ -68,12 +86,39 @@ type Configuration struct {
 	// BackendConfigChecksum contains the particular checksum of a Configuration object
 	BackendConfigChecksum string `json:"BackendConfigChecksum,omitempty"`
 
+	// DynamicConfigChecksum contains the checksum of the keys of the
+	// configuration ConfigMap applied without reloading NGINX
+	DynamicConfigChecksum string `json:"DynamicConfigChecksum,omitempty"`
+
 	// ConfigurationChecksum contains the particular checksum of a Configuration object
 	ConfigurationChecksum string `json:"configurationChecksum,omitempty"`
 
 	DefaultSSLCertificate *SSLCert `json:"-"`
 
+	// SSLRejectHandshake indicates the default server rejects the TLS handshakes
+	// for unknown server names instead of presenting the default certificate
+	SSLRejectHandshake bool `json:"sslRejectHandshake,omitempty"`
+
 	StreamSnippets []string `json:"StreamSnippets"`
+
+	// Denylist contains the addresses denied to all the servers, applied
+	// without reloading NGINX
+	// +optional
+	Denylist []DenylistEntry `json:"denylist,omitempty"`
+
+	// LuaSecrets contains the values of the Secrets referenced by the
+	// configuration ConfigMap read by the Lua modules, applied without
+	// reloading NGINX. They are never rendered in nginx.conf.
+	// +optional
+	LuaSecrets map[string]string `json:"-"`
+}
+
+// DenylistEntry is an IP address or a CIDR denied to all the servers
+type DenylistEntry struct {
+	CIDR string `json:"cidr"`
+	// ExpiresAt is the Unix time at which the address is no longer denied,
+	// 0 when it is denied forever
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
 }
 
 // Backend describes one or more remote server/s (endpoints) associated with a service
@@ -93,6 +138,12 @@ type Backend struct {
 	UpstreamHashBy UpstreamHashByConfig `json:"upstreamHashByConfig,omitempty"`
 	// LB algorithm configuration per ingress
 	LoadBalancing string `json:"load-balance,omitempty"`
+	// Window in seconds during which the traffic sent to new endpoints is ramped up
+	SlowStartSeconds int `json:"slowStartSeconds,omitempty"`
+	// Passive outlier detection of the endpoints
+	OutlierDetection outlierdetection.Config `json:"outlierDetection,omitempty"`
+	// Active health checks of the endpoints
+	HealthCheck healthcheck.Config `json:"healthCheck,omitempty"`
 	// Denotes if a backend has no server. The backend instead shares a server with another backend and acts as an
 	// alternative backend.
 	// This can be used to share multiple upstreams in the sam nginx server block.
@@ -126,6 +177,20 @@ type TrafficShapingPolicy struct {
 	HeaderPattern string `json:"headerPattern"`
 	// Cookie on which to redirect requests to this backend
 	Cookie string `json:"cookie"`
+	// Query parameter on which to redirect requests to this backend
+	Query string `json:"query"`
+	// QueryValue on which to redirect requests to this backend
+	QueryValue string `json:"queryValue"`
+	// Sticky persists the weighted decision per client in a signed cookie
+	Sticky bool `json:"sticky"`
+	// StickyCookie is the name of the cookie holding the sticky assignment
+	StickyCookie string `json:"stickyCookie"`
+	// StickyMaxAge is the time in seconds the sticky assignment is kept
+	StickyMaxAge int `json:"stickyMaxAge"`
+	// GeoCountries are the country codes of the clients redirected to this backend
+	GeoCountries []string `json:"geoCountries,omitempty"`
+	// GeoContinents are the continent codes of the clients redirected to this backend
+	GeoContinents []string `json:"geoContinents,omitempty"`
 }
 
 // HashInclude defines if a field should be used or not to calculate the hash
@@ -149,6 +214,7 @@ type SessionAffinityConfig struct {
 	AffinityType          string                `json:"name"`
 	AffinityMode          string                `json:"mode"`
 	CookieSessionAffinity CookieSessionAffinity `json:"cookieSessionAffinity"`
+	HeaderSessionAffinity HeaderSessionAffinity `json:"headerSessionAffinity"`
 }
 
 // CookieSessionAffinity defines the structure used in Affinity configured by Cookies.
@@ -166,11 +232,19 @@ type CookieSessionAffinity struct {
 	ChangeOnFailure         bool                `json:"change_on_failure,omitempty"`
 }
 
+// HeaderSessionAffinity defines the structure used in Affinity configured by a request header.
+// +k8s:deepcopy-gen=true
+type HeaderSessionAffinity struct {
+	Name string `json:"name"`
+}
+
 // UpstreamHashByConfig described setting from the upstream-hash-by* annotations.
 type UpstreamHashByConfig struct {
 	UpstreamHashBy           string `json:"upstream-hash-by,omitempty"`
 	UpstreamHashBySubset     bool   `json:"upstream-hash-by-subset,omitempty"`
 	UpstreamHashBySubsetSize int    `json:"upstream-hash-by-subset-size,omitempty"`
+
+	UpstreamHashByBoundedLoadFactor float32 `json:"upstream-hash-by-bounded-load-factor,omitempty"`
 }
 
 // Endpoint describes a kubernetes endpoint in a backend
@@ -213,8 +287,14 @@ type Server struct {
 	// SSLPreferServerCiphers indicates that server ciphers should be preferred
 	// over client ciphers when using the TLS protocols.
 	SSLPreferServerCiphers string `json:"sslPreferServerCiphers,omitempty"`
+	// ErrorLogLevel is the severity of the error_log of the server, overriding
+	// the error-log-level of the configuration.
+	ErrorLogLevel string `json:"errorLogLevel,omitempty"`
 	// AuthTLSError contains the reason why the access to a server should be denied
 	AuthTLSError string `json:"authTLSError,omitempty"`
+	// RedirectMap contains the redirects of the paths of the server
+	// +optional
+	RedirectMap redirectmap.Config `json:"redirectMap"`
 }
 
 // Location describes an URI inside a server.
@@ -280,6 +360,9 @@ type Location struct {
 	// original location.
 	// +optional
 	HTTP2PushPreload bool `json:"http2PushPreload,omitempty"`
+	// GRPCWeb enables the translation of gRPC-Web requests to gRPC
+	// +optional
+	GRPCWeb bool `json:"grpcWeb,omitempty"`
 	// RateLimit describes a limit in the number of connections per IP
 	// address or connections per second.
 	// The Redirect annotation precedes RateLimit
@@ -360,6 +443,64 @@ type Location struct {
 	// Mirror allows you to mirror traffic to a "test" backend
 	// +optional
 	Mirror mirror.Config `json:"mirror,omitempty"`
+	// WebSocket configures the timeouts and the connection limit of the WebSocket connections
+	// +optional
+	WebSocket websocket.Config `json:"webSocket,omitempty"`
+	// BotDetection scores the requests as bots and challenges or blocks them
+	// +optional
+	BotDetection botdetection.Config `json:"botDetection,omitempty"`
+	// CSRF checks the origin and the token of the requests with an unsafe method
+	// +optional
+	CSRF csrf.Config `json:"csrf,omitempty"`
+	// Normalization redirects, denies or rewrites the requests whose path or
+	// headers are not normalized
+	// +optional
+	Normalization normalization.Config `json:"normalization,omitempty"`
+	// StrictRequestValidation rejects the ambiguous requests which could be
+	// smuggled through chained proxies
+	// +optional
+	StrictRequestValidation bool `json:"strictRequestValidation,omitempty"`
+	// SecurityHeaders are the security headers of the responses
+	// +optional
+	SecurityHeaders securityheaders.Config `json:"securityHeaders,omitempty"`
+	// Plugins enables, disables and configures the Lua plugins of the location
+	// +optional
+	Plugins plugins.Config `json:"plugins,omitempty"`
+	// NJS imports the njs scripts of a ConfigMap and sets variables with
+	// their functions
+	// +optional
+	NJS njs.Config `json:"njs,omitempty"`
+	// ProxyCache caches the responses of the location in a cache zone
+	// +optional
+	ProxyCache proxycache.Config `json:"proxyCache,omitempty"`
+	// Brotli overrides the Brotli compression of the ConfigMap for the
+	// location
+	// +optional
+	Brotli brotli.Config `json:"brotli,omitempty"`
+	// Zstd overrides the Zstandard compression of the ConfigMap for the
+	// location
+	// +optional
+	Zstd zstd.Config `json:"zstd,omitempty"`
+	// ConditionalRedirects redirects the requests of the location matching a
+	// condition on a header, a cookie or a query parameter
+	// +optional
+	ConditionalRedirects conditionalredirect.Config `json:"conditionalRedirects,omitempty"`
+	// TrailingSlash redirects the requests of the location between the
+	// /path and /path/ forms of their path
+	// +optional
+	TrailingSlash trailingslash.Config `json:"trailingSlash,omitempty"`
+	// CustomErrorPages are the error pages of a ConfigMap served by NGINX
+	// instead of the errors of the location
+	// +optional
+	CustomErrorPages customerrorpages.Config `json:"customErrorPages,omitempty"`
+	// Maintenance answers the requests of the location with a 503 response,
+	// apart from the ones of its allowlist
+	// +optional
+	Maintenance maintenance.Config `json:"maintenance,omitempty"`
+	// GeoAccess allows or denies the requests of the location by the country
+	// and the autonomous system of the client
+	// +optional
+	GeoAccess geoaccess.Config `json:"geoAccess,omitempty"`
 	// Opentelemetry allows the global opentelemetry setting to be overridden for a location
 	// +optional
 	Opentelemetry opentelemetry.Config `json:"opentelemetry"`
@@ -376,6 +517,9 @@ type SSLPassthroughBackend struct {
 	Backend string `json:"namespace,omitempty"`
 	// Hostname returns the FQDN of the server
 	Hostname string `json:"hostname"`
+	// Endpoints are the endpoints of the servers without backend, like the
+	// TLSRoutes of the HTTPS port
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
 }
 
 // L4Service describes a L4 Ingress service.
@@ -388,6 +532,15 @@ type L4Service struct {
 	Endpoints []Endpoint `json:"endpoints,omitempty"`
 	// k8s Service
 	Service *apiv1.Service `json:"-"`
+	// Hostnames matched with the server name of the TLS connections to
+	// select this service when it is part of the SNIServices of a port
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+	// SNIServices receive the TLS connections whose server name matches one
+	// of their hostnames. The other connections are sent to the Backend, if
+	// any.
+	// +optional
+	SNIServices []L4Service `json:"sniServices,omitempty"`
 }
 
 // L4Backend describes the kubernetes service behind L4 Ingress service
@@ -398,6 +551,13 @@ type L4Backend struct {
 	Protocol  apiv1.Protocol     `json:"protocol"`
 	// +optional
 	ProxyProtocol ProxyProtocol `json:"proxyProtocol"`
+	// SSLCert is the certificate used to terminate TLS
+	// +optional
+	SSLCert *SSLCert `json:"-"`
+	// SessionAffinity pins the connections of a client to an endpoint,
+	// ClientIP or FiveTuple
+	// +optional
+	SessionAffinity string `json:"sessionAffinity,omitempty"`
 }
 
 // ProxyProtocol describes the proxy protocol configuration
@@ -17,6 +17,9 @@ limitations under the License.
 package ingress
 
 import (
+	"maps"
+	"slices"
+
 	"k8s.io/ingress-nginx/pkg/util/sets"
 )
 
@@ -33,6 +36,10 @@ func (c1 *Configuration) Equal(c2 *Configuration) bool {
 		return false
 	}
 
+	if c1.SSLRejectHandshake != c2.SSLRejectHandshake {
+		return false
+	}
+
 	match := compareBackends(c1.Backends, c2.Backends)
 	if !match {
 		return false
@@ -76,6 +83,19 @@ func (c1 *Configuration) Equal(c2 *Configuration) bool {
 		}
 	}
 
+	if c1.DynamicConfigChecksum != c2.DynamicConfigChecksum {
+		return false
+	}
+
+	// the entries of the denylist are sorted
+	if !slices.Equal(c1.Denylist, c2.Denylist) {
+		return false
+	}
+
+	if !maps.Equal(c1.LuaSecrets, c2.LuaSecrets) {
+		return false
+	}
+
 	return c1.BackendConfigChecksum == c2.BackendConfigChecksum
 }
 
@@ -121,6 +141,15 @@ func (b *Backend) Equal(newB *Backend) bool {
 	if b.LoadBalancing != newB.LoadBalancing {
 		return false
 	}
+	if b.SlowStartSeconds != newB.SlowStartSeconds {
+		return false
+	}
+	if !(&b.OutlierDetection).Equal(&newB.OutlierDetection) {
+		return false
+	}
+	if !(&b.HealthCheck).Equal(&newB.HealthCheck) {
+		return false
+	}
 
 	match := compareEndpoints(b.Endpoints, newB.Endpoints)
 	if !match {
@@ -151,10 +180,25 @@ func (sac1 *SessionAffinityConfig) Equal(sac2 *SessionAffinityConfig) bool {
 	if !(&sac1.CookieSessionAffinity).Equal(&sac2.CookieSessionAffinity) {
 		return false
 	}
+	if !(&sac1.HeaderSessionAffinity).Equal(&sac2.HeaderSessionAffinity) {
+		return false
+	}
 
 	return true
 }
 
+// Equal tests for equality between two HeaderSessionAffinity types
+func (hsa1 *HeaderSessionAffinity) Equal(hsa2 *HeaderSessionAffinity) bool {
+	if hsa1 == hsa2 {
+		return true
+	}
+	if hsa1 == nil || hsa2 == nil {
+		return false
+	}
+
+	return hsa1.Name == hsa2.Name
+}
+
 // Equal tests for equality between two CookieSessionAffinity types
 func (csa1 *CookieSessionAffinity) Equal(csa2 *CookieSessionAffinity) bool {
 	if csa1 == csa2 {
@@ -208,6 +252,9 @@ func (u1 *UpstreamHashByConfig) Equal(u2 *UpstreamHashByConfig) bool {
 	if u1.UpstreamHashBySubsetSize != u2.UpstreamHashBySubsetSize {
 		return false
 	}
+	if u1.UpstreamHashByBoundedLoadFactor != u2.UpstreamHashByBoundedLoadFactor {
+		return false
+	}
 
 	return true
 }
@@ -259,6 +306,27 @@ func (tsp1 *TrafficShapingPolicy) Equal(tsp2 *TrafficShapingPolicy) bool {
 	if tsp1.Cookie != tsp2.Cookie {
 		return false
 	}
+	if tsp1.Query != tsp2.Query {
+		return false
+	}
+	if tsp1.QueryValue != tsp2.QueryValue {
+		return false
+	}
+	if tsp1.Sticky != tsp2.Sticky {
+		return false
+	}
+	if tsp1.StickyCookie != tsp2.StickyCookie {
+		return false
+	}
+	if tsp1.StickyMaxAge != tsp2.StickyMaxAge {
+		return false
+	}
+	if !sets.StringElementsMatch(tsp1.GeoCountries, tsp2.GeoCountries) {
+		return false
+	}
+	if !sets.StringElementsMatch(tsp1.GeoContinents, tsp2.GeoContinents) {
+		return false
+	}
 
 	return true
 }
@@ -313,9 +381,15 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.SSLPreferServerCiphers != s2.SSLPreferServerCiphers {
 		return false
 	}
+	if s1.ErrorLogLevel != s2.ErrorLogLevel {
+		return false
+	}
 	if s1.AuthTLSError != s2.AuthTLSError {
 		return false
 	}
+	if !(&s1.RedirectMap).Equal(&s2.RedirectMap) {
+		return false
+	}
 	if !(&s1.ProxySSL).Equal(&s2.ProxySSL) {
 		return false
 	}
@@ -387,6 +461,9 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if l1.HTTP2PushPreload != l2.HTTP2PushPreload {
 		return false
 	}
+	if l1.GRPCWeb != l2.GRPCWeb {
+		return false
+	}
 	if !(&l1.RateLimit).Equal(&l2.RateLimit) {
 		return false
 	}
@@ -465,6 +542,54 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if !l1.Mirror.Equal(&l2.Mirror) {
 		return false
 	}
+	if !(&l1.WebSocket).Equal(&l2.WebSocket) {
+		return false
+	}
+	if !(&l1.BotDetection).Equal(&l2.BotDetection) {
+		return false
+	}
+	if !(&l1.CSRF).Equal(&l2.CSRF) {
+		return false
+	}
+	if !(&l1.Normalization).Equal(&l2.Normalization) {
+		return false
+	}
+	if l1.StrictRequestValidation != l2.StrictRequestValidation {
+		return false
+	}
+	if !(&l1.SecurityHeaders).Equal(&l2.SecurityHeaders) {
+		return false
+	}
+	if !(&l1.Plugins).Equal(&l2.Plugins) {
+		return false
+	}
+	if !(&l1.NJS).Equal(&l2.NJS) {
+		return false
+	}
+	if !(&l1.ProxyCache).Equal(&l2.ProxyCache) {
+		return false
+	}
+	if !(&l1.Brotli).Equal(&l2.Brotli) {
+		return false
+	}
+	if !(&l1.Zstd).Equal(&l2.Zstd) {
+		return false
+	}
+	if !(&l1.ConditionalRedirects).Equal(&l2.ConditionalRedirects) {
+		return false
+	}
+	if !(&l1.TrailingSlash).Equal(&l2.TrailingSlash) {
+		return false
+	}
+	if !(&l1.CustomErrorPages).Equal(&l2.CustomErrorPages) {
+		return false
+	}
+	if !(&l1.Maintenance).Equal(&l2.Maintenance) {
+		return false
+	}
+	if !(&l1.GeoAccess).Equal(&l2.GeoAccess) {
+		return false
+	}
 
 	if l1.DisableProxyInterceptErrors != l2.DisableProxyInterceptErrors {
 		return false
@@ -520,6 +645,19 @@ func (e1 *L4Service) Equal(e2 *L4Service) bool {
 	if !(&e1.Backend).Equal(&e2.Backend) {
 		return false
 	}
+	if !sets.StringElementsMatch(e1.Hostnames, e2.Hostnames) {
+		return false
+	}
+	// the SNI services are sorted, comparing them with compareL4Service
+	// would be an initialization cycle
+	if len(e1.SNIServices) != len(e2.SNIServices) {
+		return false
+	}
+	for i := range e1.SNIServices {
+		if !(&e1.SNIServices[i]).Equal(&e2.SNIServices[i]) {
+			return false
+		}
+	}
 
 	return compareEndpoints(e1.Endpoints, e2.Endpoints)
 }
@@ -547,6 +685,12 @@ func (l4b1 *L4Backend) Equal(l4b2 *L4Backend) bool {
 	if l4b1.ProxyProtocol != l4b2.ProxyProtocol {
 		return false
 	}
+	if !l4b1.SSLCert.Equal(l4b2.SSLCert) {
+		return false
+	}
+	if l4b1.SessionAffinity != l4b2.SessionAffinity {
+		return false
+	}
 
 	return true
 }
@@ -580,6 +724,15 @@ func (s *SSLCert) Equal(newS *SSLCert) bool {
 	if s.PemCertKey != newS.PemCertKey {
 		return false
 	}
+	if s.ECDSAPemSHA != newS.ECDSAPemSHA {
+		return false
+	}
+	if s.ECDSAPemCertKey != newS.ECDSAPemCertKey {
+		return false
+	}
+	if s.KeyID != newS.KeyID {
+		return false
+	}
 	if s.UID != newS.UID {
 		return false
 	}
@@ -43,6 +43,8 @@ func (in *Backend) DeepCopyInto(out *Backend) {
 	}
 	in.SessionAffinity.DeepCopyInto(&out.SessionAffinity)
 	out.UpstreamHashBy = in.UpstreamHashBy
+	out.OutlierDetection = in.OutlierDetection
+	out.HealthCheck = in.HealthCheck
 	out.TrafficShapingPolicy = in.TrafficShapingPolicy
 	if in.AlternativeBackends != nil {
 		in, out := &in.AlternativeBackends, &out.AlternativeBackends
@@ -114,10 +116,27 @@ func (in *Endpoint) DeepCopy() *Endpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderSessionAffinity) DeepCopyInto(out *HeaderSessionAffinity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderSessionAffinity.
+func (in *HeaderSessionAffinity) DeepCopy() *HeaderSessionAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderSessionAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SessionAffinityConfig) DeepCopyInto(out *SessionAffinityConfig) {
 	*out = *in
 	in.CookieSessionAffinity.DeepCopyInto(&out.CookieSessionAffinity)
+	out.HeaderSessionAffinity = in.HeaderSessionAffinity
 	return
 }
 
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName is the name of the API group
+const GroupName = "nginxingress.k8s.io"
+
+// SchemeGroupVersion is the group version used to register these objects
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+// StreamRoutesResource is the resource of the StreamRoutes
+var StreamRoutesResource = SchemeGroupVersion.WithResource("streamroutes")
+
+// NginxIngressClassParamsKind is the kind referenced by the parameters of the
+// IngressClasses
+const NginxIngressClassParamsKind = "NginxIngressClassParams"
+
+// NginxIngressClassParamsResource is the resource of the NginxIngressClassParams
+var NginxIngressClassParamsResource = SchemeGroupVersion.WithResource("nginxingressclassparams")
+
+// HostOwnershipsResource is the resource of the HostOwnerships
+var HostOwnershipsResource = SchemeGroupVersion.WithResource("hostownerships")
+
+// WAFPoliciesResource is the resource of the WAFPolicies
+var WAFPoliciesResource = SchemeGroupVersion.WithResource("wafpolicies")
+
+// DenylistsResource is the resource of the Denylists
+var DenylistsResource = SchemeGroupVersion.WithResource("denylists")
+
+// RedirectMapsResource is the resource of the RedirectMaps
+var RedirectMapsResource = SchemeGroupVersion.WithResource("redirectmaps")
+
+var (
+	// SchemeBuilder registers the types of the API group
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme adds the types of the API group to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&StreamRoute{},
+		&StreamRouteList{},
+		&NginxIngressClassParams{},
+		&NginxIngressClassParamsList{},
+		&HostOwnership{},
+		&HostOwnershipList{},
+		&WAFPolicy{},
+		&WAFPolicyList{},
+		&Denylist{},
+		&DenylistList{},
+		&RedirectMap{},
+		&RedirectMapList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
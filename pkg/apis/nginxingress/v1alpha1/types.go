@@ -0,0 +1,401 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// StreamRouteConditionAccepted indicates if the StreamRoute is exposed
+	// by the ingress controller
+	StreamRouteConditionAccepted = "Accepted"
+
+	// StreamRouteReasonAccepted is used when the StreamRoute is exposed
+	StreamRouteReasonAccepted = "Accepted"
+	// StreamRouteReasonInvalid is used when the StreamRoute is invalid or its
+	// port is already used
+	StreamRouteReasonInvalid = "Invalid"
+	// StreamRouteReasonBackendNotReady is used when the Service, its Endpoints
+	// or the TLS certificate of the StreamRoute are not available
+	StreamRouteReasonBackendNotReady = "BackendNotReady"
+)
+
+// StreamRouteSessionAffinity defines how the connections of a client are
+// pinned to the endpoints of the backend
+type StreamRouteSessionAffinity string
+
+const (
+	// StreamRouteSessionAffinityNone balances the connections with round robin
+	StreamRouteSessionAffinityNone StreamRouteSessionAffinity = "None"
+	// StreamRouteSessionAffinityClientIP sends the connections of a client IP
+	// address to the same endpoint
+	StreamRouteSessionAffinityClientIP StreamRouteSessionAffinity = "ClientIP"
+	// StreamRouteSessionAffinityFiveTuple sends the connections with the same
+	// client and server addresses and ports to the same endpoint
+	StreamRouteSessionAffinityFiveTuple StreamRouteSessionAffinity = "FiveTuple"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StreamRoute exposes a TCP or UDP port of the ingress controller and proxies
+// the connections to a Service of the same namespace.
+type StreamRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StreamRouteSpec `json:"spec"`
+
+	// +optional
+	Status StreamRouteStatus `json:"status,omitempty"`
+}
+
+// StreamRouteSpec describes the port exposed by a StreamRoute
+type StreamRouteSpec struct {
+	// Port exposed by the ingress controller
+	Port int32 `json:"port"`
+
+	// Protocol of the port, TCP or UDP. Defaults to TCP
+	// +optional
+	Protocol apiv1.Protocol `json:"protocol,omitempty"`
+
+	// Backend receiving the connections
+	Backend StreamRouteBackend `json:"backend"`
+
+	// ProxyProtocol configures the PROXY protocol. Only valid for TCP
+	// +optional
+	ProxyProtocol *StreamRouteProxyProtocol `json:"proxyProtocol,omitempty"`
+
+	// TLS terminates the TLS connections. Only valid for TCP
+	// +optional
+	TLS *StreamRouteTLS `json:"tls,omitempty"`
+
+	// Hostnames routes the TLS connections to the backend based on the
+	// server name sent by the client, without terminating TLS. Several
+	// StreamRoutes with hostnames can share a port, a StreamRoute of the port
+	// without hostnames receiving the other connections. Only valid for TCP
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// SessionAffinity pins the connections to the endpoints of the backend
+	// with a hash of the client IP address, or of the client and server
+	// addresses and ports. Defaults to None
+	// +optional
+	SessionAffinity StreamRouteSessionAffinity `json:"sessionAffinity,omitempty"`
+}
+
+// StreamRouteBackend references a port of a Service
+type StreamRouteBackend struct {
+	// Service is the name of the Service
+	Service string `json:"service"`
+
+	// Port is the number or the name of the port of the Service
+	Port intstr.IntOrString `json:"port"`
+}
+
+// StreamRouteProxyProtocol configures the PROXY protocol of a StreamRoute
+type StreamRouteProxyProtocol struct {
+	// Decode expects the PROXY protocol header from the clients
+	// +optional
+	Decode bool `json:"decode,omitempty"`
+
+	// Encode sends the PROXY protocol header to the backend
+	// +optional
+	Encode bool `json:"encode,omitempty"`
+}
+
+// StreamRouteTLS configures the TLS termination of a StreamRoute
+type StreamRouteTLS struct {
+	// SecretName is the name of the Secret containing the certificate
+	SecretName string `json:"secretName"`
+}
+
+// StreamRouteStatus describes the state of a StreamRoute
+type StreamRouteStatus struct {
+	// ObservedGeneration is the generation of the StreamRoute observed by the
+	// ingress controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions of the StreamRoute
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StreamRouteList is a list of StreamRoutes
+type StreamRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []StreamRoute `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NginxIngressClassParams contains the defaults of the Ingresses of the
+// IngressClasses referencing it in spec.parameters.
+type NginxIngressClassParams struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NginxIngressClassParamsSpec `json:"spec"`
+}
+
+// NginxIngressClassParamsSpec describes the defaults of the Ingresses of an
+// IngressClass
+type NginxIngressClassParamsSpec struct {
+	// DefaultSSLCertificate is the namespace/name of the Secret used for the
+	// hosts of the TLS section of the Ingresses without a valid certificate,
+	// instead of the default certificate of the controller
+	// +optional
+	DefaultSSLCertificate string `json:"defaultSSLCertificate,omitempty"`
+
+	// AllowSnippetAnnotations overrides allow-snippet-annotations of the
+	// configmap for the Ingresses of the class
+	// +optional
+	AllowSnippetAnnotations *bool `json:"allowSnippetAnnotations,omitempty"`
+
+	// AnnotationsRiskLevel overrides annotations-risk-level of the configmap
+	// for the Ingresses of the class
+	// +optional
+	AnnotationsRiskLevel string `json:"annotationsRiskLevel,omitempty"`
+
+	// ServerSnippet is added to the servers of the Ingresses of the class
+	// +optional
+	ServerSnippet string `json:"serverSnippet,omitempty"`
+
+	// LocationSnippet is added to the locations of the Ingresses of the class
+	// +optional
+	LocationSnippet string `json:"locationSnippet,omitempty"`
+
+	// Config overrides the keys of the configmap used as defaults of the
+	// locations of the Ingresses of the class, like proxy-body-size
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NginxIngressClassParamsList is a list of NginxIngressClassParams
+type NginxIngressClassParamsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []NginxIngressClassParams `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HostOwnership reserves hostnames to the Ingresses of some namespaces, with
+// the crd host ownership policy of the controller.
+type HostOwnership struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HostOwnershipSpec `json:"spec"`
+}
+
+// HostOwnershipSpec describes the hostnames reserved by a HostOwnership
+type HostOwnershipSpec struct {
+	// Hosts are the reserved hostnames, *.example.com reserving all the
+	// subdomains of example.com
+	Hosts []string `json:"hosts"`
+
+	// Namespaces are the namespaces whose Ingresses can use the hostnames
+	Namespaces []string `json:"namespaces"`
+}
+
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HostOwnershipList is a list of HostOwnerships
+type HostOwnershipList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []HostOwnership `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// WAFPolicy tunes the OWASP Core Rule Set of the ModSecurity web application
+// firewall for the Ingresses of its namespace referencing it with the
+// modsecurity-waf-policy annotation.
+type WAFPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WAFPolicySpec `json:"spec"`
+}
+
+// WAFPolicySpec describes the tuning of the OWASP Core Rule Set of a WAFPolicy
+type WAFPolicySpec struct {
+	// ParanoiaLevel of the rules of the OWASP Core Rule Set, from 1 to 4
+	// +optional
+	ParanoiaLevel *int32 `json:"paranoiaLevel,omitempty"`
+
+	// InboundAnomalyThreshold is the anomaly score of the requests from
+	// which they are blocked
+	// +optional
+	InboundAnomalyThreshold *int32 `json:"inboundAnomalyThreshold,omitempty"`
+
+	// OutboundAnomalyThreshold is the anomaly score of the responses from
+	// which they are blocked
+	// +optional
+	OutboundAnomalyThreshold *int32 `json:"outboundAnomalyThreshold,omitempty"`
+
+	// RuleExclusions removes rules, or some of their targets
+	// +optional
+	RuleExclusions []WAFRuleExclusion `json:"ruleExclusions,omitempty"`
+
+	// DisabledPaths are the path prefixes of the requests not inspected
+	// +optional
+	DisabledPaths []string `json:"disabledPaths,omitempty"`
+}
+
+// WAFRuleExclusion removes the rules matching its IDs or tags
+type WAFRuleExclusion struct {
+	// IDs of the removed rules
+	// +optional
+	IDs []int64 `json:"ids,omitempty"`
+
+	// Tags of the removed rules, like attack-sqli
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// Targets only removes these targets of the rules, like ARGS:password,
+	// instead of the whole rules
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+
+	// Path only removes the rules for the requests of this path prefix
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// WAFPolicyList is a list of WAFPolicies
+type WAFPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []WAFPolicy `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Denylist denies the requests of some addresses to all the servers of the
+// controller, without reloading NGINX.
+type Denylist struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DenylistSpec `json:"spec"`
+}
+
+// DenylistSpec describes the addresses denied by a Denylist
+type DenylistSpec struct {
+	// Entries are the denied addresses
+	Entries []DenylistEntry `json:"entries"`
+}
+
+// DenylistEntry denies an IP address or a CIDR, until it expires
+type DenylistEntry struct {
+	// CIDR is the denied IP address or CIDR
+	CIDR string `json:"cidr"`
+
+	// ExpiresAt is the time at which the address is no longer denied, a
+	// temporary ban. The address is denied forever when it is not set
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Reason describes why the address is denied
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DenylistList is a list of Denylists
+type DenylistList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Denylist `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedirectMap redirects the paths of the hosts of the Ingresses of its
+// namespace referencing it with the redirect-map annotation, replacing one
+// Ingress per redirect in the migrations of legacy domains.
+type RedirectMap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RedirectMapSpec `json:"spec"`
+}
+
+// RedirectMapSpec describes the redirects of a RedirectMap
+type RedirectMapSpec struct {
+	// StatusCode of the redirects, 301, 302, 307 or 308. 301 by default
+	// +optional
+	StatusCode *int32 `json:"statusCode,omitempty"`
+
+	// PreserveQueryString appends the query string of the requests to the
+	// targets of the redirects
+	// +optional
+	PreserveQueryString bool `json:"preserveQueryString,omitempty"`
+
+	// Redirects are the targets of the paths
+	Redirects []Redirect `json:"redirects"`
+}
+
+// Redirect redirects the requests of a path
+type Redirect struct {
+	// Source is the exact path of the redirected requests
+	Source string `json:"source"`
+
+	// Target is the URL or the path the requests are redirected to
+	Target string `json:"target"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedirectMapList is a list of RedirectMaps
+type RedirectMapList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RedirectMap `json:"items"`
+}
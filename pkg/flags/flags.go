@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"time"
 
@@ -27,13 +28,17 @@ import (
 	"github.com/spf13/pflag"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/ingress-nginx/internal/ingress/acme"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/ingressclass"
+	"k8s.io/ingress-nginx/internal/ingress/controller/sharding"
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
 	"k8s.io/ingress-nginx/internal/ingress/status"
 	ing_net "k8s.io/ingress-nginx/internal/net"
+	"k8s.io/ingress-nginx/internal/net/ssl"
 	"k8s.io/ingress-nginx/internal/nginx"
 	klog "k8s.io/klog/v2"
 )
@@ -79,6 +84,54 @@ referenced in an Ingress Object should be the same value specified here to make
 		ingressClassByName = flags.Bool("ingress-class-by-name", false,
 			`Define if Ingress Controller should watch for Ingress Class by Name together with Controller Class.`)
 
+		shards = flags.Int("shards", 1,
+			`Number of shards the Ingresses of the class are split between, each shard being served by a different controller Deployment.
+An Ingress is assigned to a shard by the hash of its namespace and name, or by the value of the label --shard-label.`)
+
+		shardIndex = flags.Int("shard-index", 0,
+			`Shard of the Ingresses served by this controller, between 0 and --shards - 1.`)
+
+		shardLabel = flags.String("shard-label", "",
+			`Label of the Ingresses with the index of the shard they are assigned to, taking precedence over the hash of their namespace and name.`)
+
+		enableIngressClassParams = flags.Bool("enable-ingress-class-params", false,
+			`Watch the NginxIngressClassParams custom resources of the nginxingress.k8s.io API group
+referenced by the spec.parameters of the IngressClasses, defining the defaults of the Ingresses of each class.
+The NginxIngressClassParams CustomResourceDefinition must be installed.`)
+
+		enableWAFPolicies = flags.Bool("enable-waf-policies", false,
+			`Watch the WAFPolicy custom resources of the nginxingress.k8s.io API group referenced by the
+modsecurity-waf-policy annotation of the Ingresses, tuning the OWASP Core Rule Set of ModSecurity.
+The WAFPolicy CustomResourceDefinition must be installed.`)
+
+		enableDenylists = flags.Bool("enable-denylists", false,
+			`Watch the Denylist custom resources of the nginxingress.k8s.io API group, denying the requests of their
+addresses to all the servers without reloading NGINX. The Denylist CustomResourceDefinition must be installed.`)
+
+		enableRedirectMaps = flags.Bool("enable-redirect-maps", false,
+			`Watch the RedirectMap custom resources of the nginxingress.k8s.io API group referenced by the
+redirect-map annotation of the Ingresses, redirecting the paths of their hosts in bulk.
+The RedirectMap CustomResourceDefinition must be installed.`)
+
+		enableGatewayAPI = flags.Bool("enable-gateway-api", false,
+			`Watch the Gateways of the GatewayClasses with the --controller-class in spec.controllerName
+and their HTTPRoutes, serving them like Ingresses. The Gateway API CustomResourceDefinitions must be installed.`)
+
+		enableExperimentalGatewayAPI = flags.Bool("enable-experimental-gateway-api", false,
+			`Also serve the TLSRoutes and TCPRoutes of the experimental channel of the Gateway API as stream services.
+Requires --enable-gateway-api.`)
+
+		enableReferenceGrants = flags.Bool("enable-reference-grants", false,
+			`Authorize the references of the proxy-ssl-secret and auth-tls-secret annotations to the Secrets of other namespaces
+with the ReferenceGrants of the Gateway API, instead of the allow-cross-namespace-resources configuration.
+Requires --enable-gateway-api.`)
+
+		hostOwnershipPolicy = flags.String("host-ownership-policy", store.HostOwnershipMerge,
+			`Namespaces which can define the rules of a host: merge merges the rules of all the Ingresses of the host,
+first-claim reserves the host to the namespace of its oldest Ingress, crd reserves the hosts of the HostOwnerships
+to their namespaces and namespace-suffixes reserves the hosts matching the namespace-host-suffixes configuration to
+their namespaces, the other hosts being reserved to the namespace of their oldest Ingress.`)
+
 		configMap = flags.String("configmap", "",
 			`Name of the ConfigMap containing custom global configurations for the controller.`)
 
@@ -99,6 +152,11 @@ controller for servicing HTTP traffic.`)
 The key in the map indicates the external port to be used. The value is a
 reference to a Service in the form "namespace/name:port", where "port" can
 either be a port name or number.`)
+		enableStreamRoutes = flags.Bool("enable-stream-routes", false,
+			`Watch the StreamRoute custom resources of the nginxingress.k8s.io API group
+to define the TCP and UDP services to expose, in addition to the
+tcp-services-configmap and udp-services-configmap. The StreamRoute
+CustomResourceDefinition must be installed.`)
 
 		resyncPeriod = flags.Duration("sync-period", 0,
 			`Period at which the controller forces the repopulation of its local object stores. Disabled by default.`)
@@ -109,7 +167,12 @@ This includes Ingresses, Services and all configuration resources. All
 namespaces are watched if this parameter is left empty.`)
 
 		watchNamespaceSelector = flags.String("watch-namespace-selector", "",
-			`Selector selects namespaces the controller watches for updates to Kubernetes objects.`)
+			`Selector selects namespaces the controller watches for updates to Kubernetes objects.
+The Ingresses of a namespace are added when its labels start matching the selector and removed when they stop matching it, without restarting the controller.`)
+
+		watchReferencedSecrets = flags.Bool("watch-referenced-secrets", false,
+			`Only watch the Secrets referenced by the Ingresses and the other resources of the controller, each with a watch
+restricted to its name, instead of caching all the Secrets of the watched namespaces.`)
 
 		profiling = flags.Bool("profiling", true,
 			`Enable profiling via web interface host:port/debug/pprof/ .`)
@@ -166,9 +229,22 @@ Requires the update-status parameter.`)
 Certificates uploaded to Kubernetes must have the "Authority Information Access" X.509 v3
 extension for this to succeed.`)
 
+		sslKeyAgentSocket = flags.String("ssl-key-agent-socket", "",
+			`Path of the unix socket of the agent holding the private keys referenced by the
+tls.key-id key of the TLS Secrets, like a sidecar with access to an HSM or a cloud KMS.`)
+
 		syncRateLimit = flags.Float32("sync-rate-limit", 0.3,
 			`Define the sync frequency upper limit`)
 
+		syncQuietPeriod = flags.Duration("sync-quiet-period", 0,
+			`Time without changes to wait for before synchronizing the configuration, to coalesce the changes received in a burst. 0 synchronizes every change immediately.`)
+
+		syncMaxDelay = flags.Duration("sync-max-delay", 10*time.Second,
+			`Maximum time the synchronization of a change is delayed waiting for the quiet period.`)
+
+		configurationSnapshots = flags.Int("configuration-snapshots", 0,
+			`Number of the last configurations applied kept in /etc/ingress-controller/snapshots, to inspect them and roll back to one of them with the dbg command. 0 disables the snapshots.`)
+
 		publishStatusAddress = flags.String("publish-status-address", "",
 			`Customized address (or addresses, separated by comma) to set as the load-balancer status of Ingress objects this controller satisfies.
 Requires the update-status parameter.`)
@@ -177,6 +253,11 @@ Requires the update-status parameter.`)
 			`Enables the collection of NGINX metrics.`)
 		metricsPerHost = flags.Bool("metrics-per-host", true,
 			`Export metrics per-host.`)
+		metricsPerPath = flags.Bool("metrics-per-path", true,
+			`Label the request metrics with the matched path of the Ingresses.`)
+		metricsMaxPaths = flags.Int("metrics-max-paths", 0,
+			`Maximum number of distinct paths of the request metrics of an Ingress, the requests of the other paths
+being labeled with the "other" path. 0 means no limit.`)
 		reportStatusClasses = flags.Bool("report-status-classes", false,
 			`Use status classes (2xx, 3xx, 4xx and 5xx) instead of status codes in metrics.`)
 
@@ -186,6 +267,35 @@ Requires the update-status parameter.`)
 		excludeSocketMetrics = flags.StringSlice("exclude-socket-metrics", []string{}, "et of socket request metrics to exclude which won't be exported nor being calculated. E.g. 'nginx_ingress_controller_success,nginx_ingress_controller_header_duration_seconds'.")
 		monitorMaxBatchSize  = flags.Int("monitor-max-batch-size", 10000, "Max batch size of NGINX metrics.")
 
+		nativeHistogramBucketFactor = flags.Float64("native-histogram-bucket-factor", 0,
+			`Also emit the request and response duration and size histograms as Prometheus native histograms, with this
+growth factor between their buckets, e.g. 1.1. 0 disables the native histograms.`)
+
+		otlpMetricsEndpoint = flags.String("otlp-metrics-endpoint", "",
+			`URL of the OTLP/HTTP endpoint of an OpenTelemetry collector the metrics exposed on /metrics are also pushed to,
+e.g. http://otel-collector:4318/v1/metrics. The metrics are encoded in JSON.`)
+		otlpMetricsHeaders = flags.StringToString("otlp-metrics-headers", map[string]string{},
+			`Headers of the requests pushing the metrics to the OTLP endpoint, e.g. Authorization=Bearer token.`)
+		otlpMetricsInterval = flags.Duration("otlp-metrics-interval", 30*time.Second,
+			`Interval between the pushes of the metrics to the OTLP endpoint.`)
+
+		otlpLogsEndpoint = flags.String("otlp-logs-endpoint", "",
+			`URL of the OTLP/HTTP endpoint of an OpenTelemetry collector the access and error logs of NGINX are sent to,
+e.g. https://otel-collector:4318/v1/logs. The logs are encoded in JSON.`)
+		otlpLogsHeaders = flags.StringToString("otlp-logs-headers", map[string]string{},
+			`Headers of the requests sending the logs to the OTLP endpoint, e.g. Authorization=Bearer token.`)
+		syslogForwardAddress = flags.String("syslog-forward-address", "",
+			`Address, host:port, of a syslog server the access and error logs of NGINX are sent to over TCP, as RFC 5424 messages.`)
+		syslogForwardTLS = flags.Bool("syslog-forward-tls", false,
+			`Use TLS to send the logs to the --syslog-forward-address.`)
+		logForwardCAFile = flags.String("log-forward-ca-file", "",
+			`PEM file of the certificate authorities verifying the certificates of the OTLP and syslog endpoints of the logs,
+instead of the certificate authorities of the system.`)
+		logForwardBufferSize = flags.Int("log-forward-buffer-size", 10000,
+			`Number of lines of the access and error logs buffered to be sent, the lines are dropped when the buffer is full.`)
+		logForwardFlushInterval = flags.Duration("log-forward-flush-interval", time.Second,
+			`Maximum interval between the sends of the buffered lines of the logs.`)
+
 		httpPort  = flags.Int("http-port", 80, `Port to use for servicing HTTP traffic.`)
 		httpsPort = flags.Int("https-port", 443, `Port to use for servicing HTTPS traffic.`)
 
@@ -206,6 +316,22 @@ Takes the form "<host>:port". If not provided, no admission controller is starte
 			`The path of the validating webhook key PEM.`)
 		disableFullValidationTest = flags.Bool("disable-full-test", false,
 			`Disable full test of all merged ingresses at the admission stage and tests the template of the ingress being created or updated  (full test of all ingresses is enabled by default).`)
+		admissionDiffWarnings = flags.Bool("admission-diff-warnings", false,
+			`Add warnings summarizing the changes of the server blocks of the hosts of the validated Ingress to the successful validations of the admission controller.
+Ignored with --disable-full-test.`)
+		admissionValidationMode = flags.String("admission-validation-mode", controller.ValidationModeFull,
+			`Validation of the candidate configurations by the admission controller: full tests them with nginx -t,
+sandbox tests them with nginx -t in an isolated temporary prefix with the limits of the --admission-sandbox flags,
+syntax only checks their syntax without running nginx.`)
+		admissionSandboxTimeout = flags.Duration("admission-sandbox-timeout", 10*time.Second,
+			`Maximum duration of a test of the sandbox validation mode, including the wait for a free slot.`)
+		admissionSandboxCPULimit = flags.Int("admission-sandbox-cpu-limit", 5,
+			`CPU time limit, in seconds, of the nginx -t processes of the sandbox validation mode, 0 disables the limit.`)
+		admissionSandboxConcurrency = flags.Int("admission-sandbox-concurrency", 2,
+			`Maximum number of concurrent tests of the sandbox validation mode.`)
+		admissionCacheSize = flags.Int("admission-cache-size", 1024,
+			`Maximum number of results of the admission controller cached until the next change of the watched objects,
+so the repeated validations of an Ingress are not tested again. 0 disables the cache.`)
 
 		statusPort = flags.Int("status-port", 10246, `Port to use for the lua HTTP endpoint configuration.`)
 		streamPort = flags.Int("stream-port", 10247, "Port to use for the lua TCP/UDP endpoint configuration.")
@@ -221,6 +347,16 @@ Takes the form "<host>:port". If not provided, no admission controller is starte
 
 		postShutdownGracePeriod = flags.Int("post-shutdown-grace-period", 10, "Seconds to wait after the nginx process has stopped before controller exits.")
 
+		drainGracePeriodHTTP = flags.Int("drain-grace-period-http", 0,
+			`Seconds to serve the HTTP requests in flight on shutdown, after the shutdown grace period, while the new requests are rejected.
+Zero does not wait for the HTTP requests.`)
+		drainGracePeriodWebSocket = flags.Int("drain-grace-period-websocket", 0,
+			`Seconds to serve the WebSocket connections in flight on shutdown, after the shutdown grace period, while the new requests are rejected.
+Zero does not wait for the WebSocket connections.`)
+		drainGracePeriodGRPC = flags.Int("drain-grace-period-grpc", 0,
+			`Seconds to serve the gRPC requests and streams in flight on shutdown, after the shutdown grace period, while the new requests are rejected.
+Zero does not wait for the gRPC requests.`)
+
 		deepInspector = flags.Bool("deep-inspect", true, "Enables ingress object security deep inspector")
 
 		dynamicConfigurationRetries = flags.Int("dynamic-configuration-retries", 15, "Number of times to retry failed dynamic configuration before failing to sync an ingress.")
@@ -228,14 +364,29 @@ Takes the form "<host>:port". If not provided, no admission controller is starte
 		disableSyncEvents = flags.Bool("disable-sync-events", false, "Disables the creation of 'Sync' event resources")
 
 		enableTopologyAwareRouting = flags.Bool("enable-topology-aware-routing", false, "Enable topology aware routing feature, needs service object annotation service.kubernetes.io/topology-mode sets to auto.")
+
+		topologyAwareRoutingMinZoneEndpoints = flags.Int("topology-aware-routing-min-zone-endpoints", 1, "Minimum number of ready endpoints in the zone of the controller for topology aware routing to keep the traffic in the zone. The traffic spills over to all the zones otherwise.")
+
+		topologyAwareRoutingUseEndpointZone = flags.Bool("topology-aware-routing-use-endpoint-zone", false, "Use the zone of the endpoints for topology aware routing when the EndpointSlices have no topology hints. All the endpoints of these EndpointSlices are used otherwise.")
+
+		enableCanaryRollout = flags.Bool("enable-canary-rollout", false, "Enable the progressive rollout of canary Ingresses configured with the canary-rollout-step annotation. Requires --enable-metrics.")
+
+		enableACME = flags.Bool("enable-acme", false, "Obtain and renew the certificates of the Ingresses with the acme annotation from an ACME server, answering the HTTP-01 challenges in NGINX.")
+
+		acmeDirectoryURL = flags.String("acme-directory-url", acme.DefaultDirectoryURL, "Directory URL of the ACME server used with --enable-acme.")
+
+		acmeEmail = flags.String("acme-email", "", "Contact email of the ACME account used with --enable-acme.")
 	)
 
 	flags.StringVar(&nginx.MaxmindMirror, "maxmind-mirror", "", `Maxmind mirror url (example: http://geoip.local/databases.`)
 	flags.StringVar(&nginx.MaxmindLicenseKey, "maxmind-license-key", "", `Maxmind license key to download GeoLite2 Databases.
 https://blog.maxmind.com/2019/12/18/significant-changes-to-accessing-and-using-geolite2-databases .`)
-	flags.StringVar(&nginx.MaxmindEditionIDs, "maxmind-edition-ids", "GeoLite2-City,GeoLite2-ASN", `Maxmind edition ids to download GeoLite2 Databases.`)
+	flags.StringVar(&nginx.MaxmindEditionIDs, "maxmind-edition-ids", "GeoLite2-City,GeoLite2-ASN", `Maxmind edition ids to download GeoLite2 Databases.
+The DB-IP (dbip-country-lite, dbip-city-lite, dbip-asn-lite) and IPinfo (ipinfo-lite, ipinfo-country, ipinfo-asn, ipinfo-country-asn) databases are also supported, mounted or downloaded from --maxmind-mirror.`)
 	flags.IntVar(&nginx.MaxmindRetriesCount, "maxmind-retries-count", 1, "Number of attempts to download the GeoIP DB.")
 	flags.DurationVar(&nginx.MaxmindRetriesTimeout, "maxmind-retries-timeout", time.Second*0, "Maxmind downloading delay between 1st and 2nd attempt, 0s - do not retry to download if something went wrong.")
+	flags.DurationVar(&nginx.MaxmindUpdateInterval, "maxmind-update-interval", time.Duration(0), `Interval between the downloads of the updated GeoIP2 databases, 0s - only download the databases at startup.
+NGINX reopens the updated databases without a reload of the configuration.`)
 
 	flags.AddGoFlagSet(flag.CommandLine)
 	if err := flags.Parse(os.Args); err != nil {
@@ -298,6 +449,87 @@ https://blog.maxmind.com/2019/12/18/significant-changes-to-accessing-and-using-g
 		return false, nil, fmt.Errorf("flags --publish-service and --publish-status-address are mutually exclusive")
 	}
 
+	if *syncQuietPeriod < 0 || *syncMaxDelay < 0 {
+		return false, nil, fmt.Errorf("flags --sync-quiet-period and --sync-max-delay must not be negative")
+	}
+
+	if *shards < 1 || *shardIndex < 0 || *shardIndex >= *shards {
+		return false, nil, fmt.Errorf("flag --shard-index must be between 0 and --shards - 1, and --shards must be at least 1")
+	}
+
+	if *configurationSnapshots < 0 {
+		return false, nil, fmt.Errorf("flag --configuration-snapshots must not be negative")
+	}
+
+	if *nativeHistogramBucketFactor != 0 && *nativeHistogramBucketFactor <= 1 {
+		return false, nil, fmt.Errorf("flag --native-histogram-bucket-factor must be greater than 1")
+	}
+
+	if *metricsMaxPaths < 0 {
+		return false, nil, fmt.Errorf("flag --metrics-max-paths must not be negative")
+	}
+
+	if *otlpMetricsEndpoint != "" {
+		if u, err := url.Parse(*otlpMetricsEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return false, nil, fmt.Errorf("flag --otlp-metrics-endpoint must be an http or https URL")
+		}
+		if *otlpMetricsInterval <= 0 {
+			return false, nil, fmt.Errorf("flag --otlp-metrics-interval must be positive")
+		}
+	}
+
+	if *otlpLogsEndpoint != "" {
+		if u, err := url.Parse(*otlpLogsEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return false, nil, fmt.Errorf("flag --otlp-logs-endpoint must be an http or https URL")
+		}
+	}
+
+	if *syslogForwardAddress != "" {
+		if _, _, err := net.SplitHostPort(*syslogForwardAddress); err != nil {
+			return false, nil, fmt.Errorf("flag --syslog-forward-address must be a host:port address: %w", err)
+		}
+	}
+
+	if *logForwardBufferSize <= 0 {
+		return false, nil, fmt.Errorf("flag --log-forward-buffer-size must be positive")
+	}
+
+	if *logForwardFlushInterval <= 0 {
+		return false, nil, fmt.Errorf("flag --log-forward-flush-interval must be positive")
+	}
+
+	if *enableExperimentalGatewayAPI && !*enableGatewayAPI {
+		return false, nil, fmt.Errorf("flag --enable-experimental-gateway-api requires --enable-gateway-api")
+	}
+
+	if *enableReferenceGrants && !*enableGatewayAPI {
+		return false, nil, fmt.Errorf("flag --enable-reference-grants requires --enable-gateway-api")
+	}
+
+	if *drainGracePeriodHTTP < 0 || *drainGracePeriodWebSocket < 0 || *drainGracePeriodGRPC < 0 {
+		return false, nil, fmt.Errorf("flags --drain-grace-period-http, --drain-grace-period-websocket and --drain-grace-period-grpc must not be negative")
+	}
+
+	if !isOneOf(*hostOwnershipPolicy, store.HostOwnershipPolicies) {
+		return false, nil, fmt.Errorf("flag --host-ownership-policy must be one of %v", store.HostOwnershipPolicies)
+	}
+
+	if !isOneOf(*admissionValidationMode, controller.ValidationModes) {
+		return false, nil, fmt.Errorf("flag --admission-validation-mode must be one of %v", controller.ValidationModes)
+	}
+
+	if *admissionSandboxTimeout <= 0 || *admissionSandboxCPULimit < 0 || *admissionSandboxConcurrency < 1 {
+		return false, nil, fmt.Errorf("flag --admission-sandbox-timeout must be positive, --admission-sandbox-cpu-limit must not be negative and --admission-sandbox-concurrency must be at least 1")
+	}
+
+	if *admissionCacheSize < 0 {
+		return false, nil, fmt.Errorf("flag --admission-cache-size must not be negative")
+	}
+
+	if nginx.MaxmindUpdateInterval < 0 {
+		return false, nil, fmt.Errorf("flag --maxmind-update-interval must not be negative")
+	}
+
 	nginx.HealthPath = *defHealthzURL
 
 	if *defHealthCheckTimeout > 0 {
@@ -325,46 +557,94 @@ https://blog.maxmind.com/2019/12/18/significant-changes-to-accessing-and-using-g
 		TimeBuckets:   *timeBuckets,
 		LengthBuckets: *lengthBuckets,
 		SizeBuckets:   *sizeBuckets,
+
+		NativeHistogramBucketFactor: *nativeHistogramBucketFactor,
 	}
 
 	ngx_config.EnableSSLChainCompletion = *enableSSLChainCompletion
 
+	if *sslKeyAgentSocket != "" {
+		ssl.DefaultKeyProvider = ssl.NewAgentKeyProvider(*sslKeyAgentSocket)
+	}
+
 	config := &controller.Configuration{
-		APIServerHost:               *apiserverHost,
-		KubeConfigFile:              *kubeConfigFile,
-		UpdateStatus:                *updateStatus,
-		ElectionID:                  *electionID,
-		ElectionTTL:                 *electionTTL,
-		EnableProfiling:             *profiling,
-		EnableMetrics:               *enableMetrics,
-		MetricsPerHost:              *metricsPerHost,
-		MetricsBuckets:              histogramBuckets,
-		ReportStatusClasses:         *reportStatusClasses,
-		ExcludeSocketMetrics:        *excludeSocketMetrics,
-		MonitorMaxBatchSize:         *monitorMaxBatchSize,
-		DisableServiceExternalName:  *disableServiceExternalName,
-		EnableSSLPassthrough:        *enableSSLPassthrough,
-		DisableLeaderElection:       *disableLeaderElection,
-		ResyncPeriod:                *resyncPeriod,
-		DefaultService:              *defaultSvc,
-		Namespace:                   *watchNamespace,
-		WatchNamespaceSelector:      namespaceSelector,
-		ConfigMapName:               *configMap,
-		TCPConfigMapName:            *tcpConfigMapName,
-		UDPConfigMapName:            *udpConfigMapName,
-		DisableFullValidationTest:   *disableFullValidationTest,
-		DefaultSSLCertificate:       *defSSLCertificate,
-		DeepInspector:               *deepInspector,
-		PublishService:              *publishSvc,
-		PublishStatusAddress:        *publishStatusAddress,
-		UpdateStatusOnShutdown:      *updateStatusOnShutdown,
-		ShutdownGracePeriod:         *shutdownGracePeriod,
-		PostShutdownGracePeriod:     *postShutdownGracePeriod,
-		UseNodeInternalIP:           *useNodeInternalIP,
-		SyncRateLimit:               *syncRateLimit,
-		HealthCheckHost:             *healthzHost,
-		DynamicConfigurationRetries: *dynamicConfigurationRetries,
-		EnableTopologyAwareRouting:  *enableTopologyAwareRouting,
+		APIServerHost:                *apiserverHost,
+		KubeConfigFile:               *kubeConfigFile,
+		UpdateStatus:                 *updateStatus,
+		ElectionID:                   *electionID,
+		ElectionTTL:                  *electionTTL,
+		EnableProfiling:              *profiling,
+		EnableMetrics:                *enableMetrics,
+		MetricsPerHost:               *metricsPerHost,
+		MetricsPerPath:               *metricsPerPath,
+		MetricsMaxPaths:              *metricsMaxPaths,
+		MetricsBuckets:               histogramBuckets,
+		ReportStatusClasses:          *reportStatusClasses,
+		ExcludeSocketMetrics:         *excludeSocketMetrics,
+		MonitorMaxBatchSize:          *monitorMaxBatchSize,
+		OTLPMetricsEndpoint:          *otlpMetricsEndpoint,
+		OTLPMetricsHeaders:           *otlpMetricsHeaders,
+		OTLPMetricsInterval:          *otlpMetricsInterval,
+		OTLPLogsEndpoint:             *otlpLogsEndpoint,
+		OTLPLogsHeaders:              *otlpLogsHeaders,
+		SyslogForwardAddress:         *syslogForwardAddress,
+		SyslogForwardTLS:             *syslogForwardTLS,
+		LogForwardCAFile:             *logForwardCAFile,
+		LogForwardBufferSize:         *logForwardBufferSize,
+		LogForwardFlushInterval:      *logForwardFlushInterval,
+		DisableServiceExternalName:   *disableServiceExternalName,
+		EnableSSLPassthrough:         *enableSSLPassthrough,
+		DisableLeaderElection:        *disableLeaderElection,
+		ResyncPeriod:                 *resyncPeriod,
+		DefaultService:               *defaultSvc,
+		Namespace:                    *watchNamespace,
+		WatchNamespaceSelector:       namespaceSelector,
+		WatchReferencedSecrets:       *watchReferencedSecrets,
+		ConfigMapName:                *configMap,
+		TCPConfigMapName:             *tcpConfigMapName,
+		UDPConfigMapName:             *udpConfigMapName,
+		EnableStreamRoutes:           *enableStreamRoutes,
+		EnableIngressClassParams:     *enableIngressClassParams,
+		EnableWAFPolicies:            *enableWAFPolicies,
+		EnableDenylists:              *enableDenylists,
+		EnableRedirectMaps:           *enableRedirectMaps,
+		EnableGatewayAPI:             *enableGatewayAPI,
+		EnableExperimentalGatewayAPI: *enableExperimentalGatewayAPI,
+		EnableReferenceGrants:        *enableReferenceGrants,
+		HostOwnershipPolicy:          *hostOwnershipPolicy,
+		DisableFullValidationTest:    *disableFullValidationTest,
+		AdmissionDiffWarnings:        *admissionDiffWarnings,
+		AdmissionValidation: &controller.AdmissionValidationConfiguration{
+			Mode:        *admissionValidationMode,
+			Timeout:     *admissionSandboxTimeout,
+			CPULimit:    *admissionSandboxCPULimit,
+			Concurrency: *admissionSandboxConcurrency,
+		},
+		AdmissionCacheSize:                   *admissionCacheSize,
+		DefaultSSLCertificate:                *defSSLCertificate,
+		DeepInspector:                        *deepInspector,
+		PublishService:                       *publishSvc,
+		PublishStatusAddress:                 *publishStatusAddress,
+		UpdateStatusOnShutdown:               *updateStatusOnShutdown,
+		ShutdownGracePeriod:                  *shutdownGracePeriod,
+		PostShutdownGracePeriod:              *postShutdownGracePeriod,
+		DrainGracePeriodHTTP:                 *drainGracePeriodHTTP,
+		DrainGracePeriodWebSocket:            *drainGracePeriodWebSocket,
+		DrainGracePeriodGRPC:                 *drainGracePeriodGRPC,
+		UseNodeInternalIP:                    *useNodeInternalIP,
+		SyncRateLimit:                        *syncRateLimit,
+		SyncQuietPeriod:                      *syncQuietPeriod,
+		SyncMaxDelay:                         *syncMaxDelay,
+		ConfigurationSnapshots:               *configurationSnapshots,
+		HealthCheckHost:                      *healthzHost,
+		DynamicConfigurationRetries:          *dynamicConfigurationRetries,
+		EnableTopologyAwareRouting:           *enableTopologyAwareRouting,
+		TopologyAwareRoutingMinZoneEndpoints: *topologyAwareRoutingMinZoneEndpoints,
+		TopologyAwareRoutingUseEndpointZone:  *topologyAwareRoutingUseEndpointZone,
+		EnableCanaryRollout:                  *enableCanaryRollout,
+		EnableACME:                           *enableACME,
+		ACMEDirectoryURL:                     *acmeDirectoryURL,
+		ACMEEmail:                            *acmeEmail,
 		ListenPorts: &ngx_config.ListenPorts{
 			Default:  *defServerPort,
 			Health:   *healthzPort,
@@ -378,6 +658,11 @@ https://blog.maxmind.com/2019/12/18/significant-changes-to-accessing-and-using-g
 			WatchWithoutClass:  *watchWithoutClass,
 			IngressClassByName: *ingressClassByName,
 		},
+		Sharding: &sharding.Configuration{
+			Shards: *shards,
+			Index:  *shardIndex,
+			Label:  *shardLabel,
+		},
 		DisableCatchAll:           *disableCatchAll,
 		ValidationWebhook:         *validationWebhook,
 		ValidationWebhookCertPath: *validationWebhookCert,
@@ -407,6 +692,15 @@ https://blog.maxmind.com/2019/12/18/significant-changes-to-accessing-and-using-g
 	return false, config, err
 }
 
+func isOneOf(value string, values []string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
 // ResetForTesting clears all flag state and sets the usage function as directed.
 // After calling resetForTesting, parse errors in flag handling will not
 // exit the program.
@@ -212,3 +212,117 @@ func TestLeaderElectionTTLParseValueInHours(t *testing.T) {
 		t.Fatalf("Expected --election-ttl and conf.ElectionTTL as 1h, but found: %v", conf.ElectionTTL)
 	}
 }
+
+func TestSyncQuietPeriod(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--sync-quiet-period", "2s", "--sync-max-delay", "30s"}
+
+	_, conf, err := ParseFlags()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing default flags: %v", err)
+	}
+
+	if conf.SyncQuietPeriod != 2*time.Second || conf.SyncMaxDelay != 30*time.Second {
+		t.Fatalf("Expected a quiet period of 2s and a maximum delay of 30s, but found: %v and %v", conf.SyncQuietPeriod, conf.SyncMaxDelay)
+	}
+}
+
+func TestSyncQuietPeriodNegative(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--sync-quiet-period", "-1s"}
+
+	_, _, err := ParseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestShardIndexOutOfRange(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--shards", "3", "--shard-index", "3"}
+
+	_, _, err := ParseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestAdmissionValidationModeInvalid(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--admission-validation-mode", "strict"}
+
+	_, _, err := ParseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestHostOwnershipPolicyInvalid(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--host-ownership-policy", "last-claim"}
+
+	_, _, err := ParseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestNativeHistogramBucketFactorInvalid(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--native-histogram-bucket-factor", "0.5"}
+
+	_, _, err := ParseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestOTLPMetricsEndpointInvalid(t *testing.T) {
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "80", "--https-port", "443", "--otlp-metrics-endpoint", "otel-collector:4318"}
+
+	_, _, err := ParseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestLogForwardingFlagsInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"--otlp-logs-endpoint", "otel-collector:4318"},
+		{"--syslog-forward-address", "syslog"},
+		{"--log-forward-buffer-size", "0"},
+	} {
+		ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+		oldArgs := os.Args
+		os.Args = append([]string{"cmd", "--http-port", "80", "--https-port", "443"}, args...)
+
+		_, _, err := ParseFlags()
+		os.Args = oldArgs
+		if err == nil {
+			t.Fatalf("Expected an error parsing flags %v but none returned", args)
+		}
+	}
+}
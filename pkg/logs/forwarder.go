@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logs forwards the access and error logs of NGINX, received by the
+// internal syslog server of the controller, to remote collectors.
+package logs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AccessLogTag is the syslog tag of the access logs forwarded by NGINX
+	AccessLogTag = "nginx_access"
+	// ErrorLogTag is the syslog tag of the error logs forwarded by NGINX
+	ErrorLogTag = "nginx_error"
+)
+
+// maxBatchSize is the maximum number of records sent at once to a sink
+const maxBatchSize = 1000
+
+// Record is a line of the access or error log of NGINX
+type Record struct {
+	Time time.Time
+	// Severity is the syslog severity, 0 (emergency) to 7 (debug)
+	Severity int
+	// Tag is AccessLogTag or ErrorLogTag
+	Tag     string
+	Content string
+}
+
+// Sink sends the records to a collector
+type Sink interface {
+	Send(ctx context.Context, records []Record) error
+}
+
+// Forwarder buffers the records and sends them in batches to the sinks. The
+// records are dropped when the buffer is full, a slow collector does not
+// block NGINX.
+type Forwarder struct {
+	sinks    []Sink
+	records  chan Record
+	interval time.Duration
+	dropped  atomic.Uint64
+}
+
+// NewForwarder creates a forwarder buffering up to bufferSize records and
+// flushing them every interval
+func NewForwarder(bufferSize int, interval time.Duration, sinks ...Sink) *Forwarder {
+	return &Forwarder{
+		sinks:    sinks,
+		records:  make(chan Record, bufferSize),
+		interval: interval,
+	}
+}
+
+// Add buffers a record, without blocking
+func (f *Forwarder) Add(record Record) {
+	select {
+	case f.records <- record:
+	default:
+		f.dropped.Add(1)
+	}
+}
+
+// Start sends the buffered records every interval, or as soon as a batch is
+// full, until the context is done
+func (f *Forwarder) Start(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, maxBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case record := <-f.records:
+			batch = append(batch, record)
+			if len(batch) < maxBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		f.flush(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+func (f *Forwarder) flush(ctx context.Context, batch []Record) {
+	if dropped := f.dropped.Swap(0); dropped > 0 {
+		klog.Warningf("Dropped %d log records, the log forwarding buffer is full", dropped)
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	for _, sink := range f.sinks {
+		if err := sink.Send(ctx, batch); err != nil {
+			klog.ErrorS(err, "Error forwarding logs", "records", len(batch))
+		}
+	}
+}
+
+// RecordFromSyslog returns the record of a message of the internal syslog
+// server, if it is a forwarded access or error log. The RFC 3164 timestamps
+// of NGINX have a precision of a second, the records are timestamped when
+// they are received.
+func RecordFromSyslog(parts map[string]interface{}) (Record, bool) {
+	tag, ok := parts["tag"].(string)
+	if !ok || (tag != AccessLogTag && tag != ErrorLogTag) {
+		return Record{}, false
+	}
+
+	record := Record{Time: time.Now(), Tag: tag}
+	record.Content, _ = parts["content"].(string)
+	record.Severity, _ = parts["severity"].(int)
+
+	return record, true
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (s *fakeSink) Send(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *fakeSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+func TestForwarder(t *testing.T) {
+	sink := &fakeSink{}
+	forwarder := NewForwarder(2, 10*time.Millisecond, sink)
+
+	for i := 0; i < 3; i++ {
+		forwarder.Add(Record{Tag: AccessLogTag, Content: "GET / 200"})
+	}
+	if dropped := forwarder.dropped.Load(); dropped != 1 {
+		t.Errorf("expected 1 dropped record but got %v", dropped)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go forwarder.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sink.count() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 forwarded records but got %v", sink.count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRecordFromSyslog(t *testing.T) {
+	record, ok := RecordFromSyslog(map[string]interface{}{
+		"tag":      ErrorLogTag,
+		"content":  "upstream timed out",
+		"severity": 3,
+	})
+	if !ok {
+		t.Fatalf("expected a record for the error log")
+	}
+	if record.Content != "upstream timed out" || record.Severity != 3 || record.Time.IsZero() {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	if _, ok := RecordFromSyslog(map[string]interface{}{"tag": "nginx", "content": "GET / 200"}); ok {
+		t.Errorf("expected no record for the logs not forwarded")
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/ingress-nginx/version"
+)
+
+// otlpScope is the instrumentation scope of the logs sent to OTLP
+const otlpScope = "k8s.io/ingress-nginx"
+
+// severities are the OTLP SeverityNumber and SeverityText of the syslog
+// severities
+var severities = []struct {
+	number int
+	text   string
+}{
+	{21, "FATAL"}, // emergency
+	{21, "FATAL"}, // alert
+	{21, "FATAL"}, // critical
+	{17, "ERROR"},
+	{13, "WARN"},
+	{10, "INFO2"}, // notice
+	{9, "INFO"},
+	{5, "DEBUG"},
+}
+
+// OTLPSink sends the records to an OTLP collector, using the HTTP transport
+// with the JSON encoding
+type OTLPSink struct {
+	endpoint string
+	headers  map[string]string
+	// resource are the attributes of the resource of the logs
+	resource map[string]string
+	client   *http.Client
+}
+
+// NewOTLPSink creates a sink sending the records to the endpoint, e.g.
+// https://otel-collector:4318/v1/logs
+func NewOTLPSink(endpoint string, headers, resource map[string]string, tlsConfig *tls.Config) *OTLPSink {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &OTLPSink{
+		endpoint: endpoint,
+		headers:  headers,
+		resource: resource,
+		client:   &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// Send implements Sink
+func (s *OTLPSink) Send(ctx context.Context, records []Record) error {
+	body, err := json.Marshal(s.request(records))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %v from OTLP collector: %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+func (s *OTLPSink) request(records []Record) *otlpRequest {
+	logRecords := make([]otlpLogRecord, 0, len(records))
+	for _, record := range records {
+		severity := severities[min(max(record.Severity, 0), len(severities)-1)]
+		logType := "access"
+		if record.Tag == ErrorLogTag {
+			logType = "error"
+		}
+
+		logRecords = append(logRecords, otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(record.Time.UnixNano(), 10),
+			SeverityNumber: severity.number,
+			SeverityText:   severity.text,
+			Body:           otlpAttributeValue{StringValue: record.Content},
+			Attributes:     []otlpAttribute{{Key: "nginx.log.type", Value: otlpAttributeValue{StringValue: logType}}},
+		})
+	}
+
+	resource := make([]otlpAttribute, 0, len(s.resource))
+	for key, value := range s.resource {
+		resource = append(resource, otlpAttribute{Key: key, Value: otlpAttributeValue{StringValue: value}})
+	}
+	sort.Slice(resource, func(i, j int) bool { return resource[i].Key < resource[j].Key })
+
+	return &otlpRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{Attributes: resource},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpInstrumentationScope{Name: otlpScope, Version: version.RELEASE},
+				LogRecords: logRecords,
+			}},
+		}},
+	}
+}
+
+// The OTLP types below are the JSON encoding of the messages of
+// opentelemetry/proto/collector/logs/v1/logs_service.proto. The 64 bits
+// integers are encoded as strings.
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpInstrumentationScope `json:"scope"`
+	LogRecords []otlpLogRecord          `json:"logRecords"`
+}
+
+type otlpInstrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpAttribute struct {
+	Key   string             `json:"key"`
+	Value otlpAttributeValue `json:"value"`
+}
+
+type otlpAttributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string             `json:"timeUnixNano"`
+	SeverityNumber int                `json:"severityNumber"`
+	SeverityText   string             `json:"severityText"`
+	Body           otlpAttributeValue `json:"body"`
+	Attributes     []otlpAttribute    `json:"attributes"`
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPSink(t *testing.T) {
+	var body []byte
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	sink := NewOTLPSink(server.URL, map[string]string{"Authorization": "Bearer token"}, map[string]string{"service.name": "ingress-nginx"}, nil)
+	records := []Record{
+		{Time: time.Unix(1700000000, 5), Severity: 6, Tag: AccessLogTag, Content: "GET / 200"},
+		{Time: time.Unix(1700000001, 0), Severity: 3, Tag: ErrorLogTag, Content: "upstream timed out"},
+	}
+	if err := sink.Send(context.Background(), records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if authorization != "Bearer token" {
+		t.Errorf("expected the Authorization header but got %q", authorization)
+	}
+
+	var req otlpRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", body, err)
+	}
+
+	resource := req.ResourceLogs[0].Resource.Attributes
+	if len(resource) != 1 || resource[0].Key != "service.name" || resource[0].Value.StringValue != "ingress-nginx" {
+		t.Errorf("unexpected resource %+v", resource)
+	}
+
+	logRecords := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(logRecords) != 2 {
+		t.Fatalf("expected 2 log records but got %v", len(logRecords))
+	}
+	if r := logRecords[0]; r.TimeUnixNano != "1700000000000000005" || r.SeverityNumber != 9 || r.Body.StringValue != "GET / 200" ||
+		r.Attributes[0].Value.StringValue != "access" {
+		t.Errorf("unexpected access log record %+v", r)
+	}
+	if r := logRecords[1]; r.SeverityText != "ERROR" || r.Attributes[0].Value.StringValue != "error" {
+		t.Errorf("unexpected error log record %+v", r)
+	}
+}
+
+func TestOTLPSinkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink := NewOTLPSink(server.URL, nil, nil, nil)
+	if err := sink.Send(context.Background(), []Record{{Time: time.Now(), Content: "GET / 200"}}); err == nil {
+		t.Errorf("expected an error for the unavailable collector")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// syslogFacility is the local7 facility, the default facility of the syslog
+// logs of NGINX
+const syslogFacility = 23
+
+const syslogTimeout = 10 * time.Second
+
+// SyslogSink sends the records to a syslog server over TCP, optionally with
+// TLS, as RFC 5424 messages framed with their length as defined by RFC 5425
+type SyslogSink struct {
+	address   string
+	hostname  string
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates a sink sending the records to the address, with TLS
+// if tlsConfig is not nil. The hostname is the HOSTNAME of the messages.
+func NewSyslogSink(address, hostname string, tlsConfig *tls.Config) *SyslogSink {
+	if hostname == "" {
+		// the NILVALUE of RFC 5424
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		address:   address,
+		hostname:  hostname,
+		tlsConfig: tlsConfig,
+	}
+}
+
+// Send implements Sink. The connection is opened again by the next batch
+// after an error.
+func (s *SyslogSink) Send(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if _, err := s.conn.Write(s.frame(record)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SyslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	if s.tlsConfig == nil {
+		return dialer.DialContext(ctx, "tcp", s.address)
+	}
+
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+	return tlsDialer.DialContext(ctx, "tcp", s.address)
+}
+
+// frame returns the RFC 5425 frame of the record
+func (s *SyslogSink) frame(record Record) []byte {
+	msg := fmt.Sprintf("<%d>1 %s %s %s - - - %s",
+		syslogFacility*8+record.Severity, record.Time.UTC().Format(time.RFC3339Nano), s.hostname, record.Tag, record.Content)
+	return []byte(fmt.Sprintf("%d %s", len(msg), msg))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	messages := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil {
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			messages <- string(msg)
+		}
+	}()
+
+	sink := NewSyslogSink(listener.Addr().String(), "ingress-nginx-controller-abcde", nil)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []Record{
+		{Time: ts, Severity: 6, Tag: AccessLogTag, Content: "GET / 200"},
+		{Time: ts, Severity: 3, Tag: ErrorLogTag, Content: "upstream timed out"},
+	}
+	if err := sink.Send(context.Background(), records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"<190>1 2024-01-02T03:04:05Z ingress-nginx-controller-abcde nginx_access - - - GET / 200",
+		"<187>1 2024-01-02T03:04:05Z ingress-nginx-controller-abcde nginx_error - - - upstream timed out",
+	}
+	for _, e := range expected {
+		select {
+		case msg := <-messages:
+			if msg != e {
+				t.Errorf("expected %q but got %q", e, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the message %q", e)
+		}
+	}
+}
@@ -45,7 +45,8 @@ func RegisterMetrics(reg *prometheus.Registry, mux *http.ServeMux) {
 		"/metrics",
 		promhttp.InstrumentMetricHandler(
 			reg,
-			promhttp.HandlerFor(reg, promhttp.HandlerOpts{}),
+			// the exemplars are only exposed with the OpenMetrics format
+			promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}),
 		),
 	)
 }
@@ -0,0 +1,370 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/ingress-nginx/version"
+)
+
+// otlpScope is the instrumentation scope of the metrics pushed to OTLP
+const otlpScope = "k8s.io/ingress-nginx"
+
+// aggregationTemporalityCumulative is the CUMULATIVE AggregationTemporality
+// of OTLP, the temporality of the Prometheus counters and histograms
+const aggregationTemporalityCumulative = 2
+
+// OTLPExporter periodically pushes the metrics of a Prometheus gatherer to
+// an OTLP collector, using the HTTP transport with the JSON encoding. It
+// pushes the same metrics as the /metrics endpoint for the environments
+// without scrape access to the pods.
+type OTLPExporter struct {
+	gatherer prometheus.Gatherer
+	endpoint string
+	headers  map[string]string
+	interval time.Duration
+	// resource are the attributes of the resource of the metrics
+	resource map[string]string
+	client   *http.Client
+	// start is the start time of the cumulative metrics without created
+	// timestamp
+	start time.Time
+}
+
+// NewOTLPExporter creates an exporter pushing the metrics of the gatherer to
+// the endpoint, e.g. http://otel-collector:4318/v1/metrics, every interval
+func NewOTLPExporter(gatherer prometheus.Gatherer, endpoint string, headers map[string]string, interval time.Duration, resource map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		gatherer: gatherer,
+		endpoint: endpoint,
+		headers:  headers,
+		interval: interval,
+		resource: resource,
+		client:   &http.Client{Timeout: interval},
+		start:    time.Now(),
+	}
+}
+
+// Start pushes the metrics every interval until the context is done
+func (e *OTLPExporter) Start(ctx context.Context) {
+	klog.InfoS("Pushing metrics to OTLP collector", "endpoint", e.endpoint, "interval", e.interval)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Push(ctx); err != nil {
+				klog.ErrorS(err, "Error pushing metrics to OTLP collector", "endpoint", e.endpoint)
+			}
+		}
+	}
+}
+
+// Push gathers the metrics and sends them to the collector
+func (e *OTLPExporter) Push(ctx context.Context) error {
+	mfs, err := e.gatherer.Gather()
+	if err != nil {
+		// the metrics gathered despite the error are still pushed
+		klog.ErrorS(err, "Error gathering metrics")
+	}
+
+	body, err := json.Marshal(e.request(mfs, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+
+	return nil
+}
+
+// The OTLP types below are the JSON encoding of the messages of
+// opentelemetry/proto/collector/metrics/v1/metrics_service.proto. The 64 bits
+// integers are encoded as strings.
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpInstrumentationScope `json:"scope"`
+	Metrics []otlpMetric             `json:"metrics"`
+}
+
+type otlpInstrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpAttribute struct {
+	Key   string             `json:"key"`
+	Value otlpAttributeValue `json:"value"`
+}
+
+type otlpAttributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+	Summary     *otlpSummary   `json:"summary,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          float64         `json:"asDouble"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpSummary struct {
+	DataPoints []otlpSummaryDataPoint `json:"dataPoints"`
+}
+
+type otlpSummaryDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	QuantileValues    []otlpQuantile  `json:"quantileValues"`
+}
+
+type otlpQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// request converts the metric families to an OTLP export request
+func (e *OTLPExporter) request(mfs []*dto.MetricFamily, now time.Time) *otlpRequest {
+	metrics := make([]otlpMetric, 0, len(mfs))
+	for _, mf := range mfs {
+		if m := e.metric(mf, now); m != nil {
+			metrics = append(metrics, *m)
+		}
+	}
+
+	return &otlpRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{Attributes: attributes(e.resource)},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpInstrumentationScope{Name: otlpScope, Version: version.RELEASE},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+// metric converts a metric family, nil for the types without OTLP equivalent
+func (e *OTLPExporter) metric(mf *dto.MetricFamily, now time.Time) *otlpMetric {
+	m := &otlpMetric{
+		Name:        mf.GetName(),
+		Description: mf.GetHelp(),
+	}
+	timeUnixNano := unixNano(now)
+
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := &otlpSum{AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+		for _, metric := range mf.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, otlpNumberDataPoint{
+				Attributes:        labelAttributes(metric.GetLabel()),
+				StartTimeUnixNano: e.startTime(metric.GetCounter().GetCreatedTimestamp().AsTime()),
+				TimeUnixNano:      timeUnixNano,
+				AsDouble:          metric.GetCounter().GetValue(),
+			})
+		}
+		m.Sum = sum
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := &otlpGauge{}
+		for _, metric := range mf.GetMetric() {
+			value := metric.GetGauge().GetValue()
+			if mf.GetType() == dto.MetricType_UNTYPED {
+				value = metric.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, otlpNumberDataPoint{
+				Attributes:   labelAttributes(metric.GetLabel()),
+				TimeUnixNano: timeUnixNano,
+				AsDouble:     value,
+			})
+		}
+		m.Gauge = gauge
+	case dto.MetricType_HISTOGRAM:
+		histogram := &otlpHistogram{AggregationTemporality: aggregationTemporalityCumulative}
+		for _, metric := range mf.GetMetric() {
+			h := metric.GetHistogram()
+			bounds, counts := histogramBuckets(h)
+			histogram.DataPoints = append(histogram.DataPoints, otlpHistogramDataPoint{
+				Attributes:        labelAttributes(metric.GetLabel()),
+				StartTimeUnixNano: e.startTime(h.GetCreatedTimestamp().AsTime()),
+				TimeUnixNano:      timeUnixNano,
+				Count:             strconv.FormatUint(h.GetSampleCount(), 10),
+				Sum:               h.GetSampleSum(),
+				BucketCounts:      counts,
+				ExplicitBounds:    bounds,
+			})
+		}
+		m.Histogram = histogram
+	case dto.MetricType_SUMMARY:
+		summary := &otlpSummary{}
+		for _, metric := range mf.GetMetric() {
+			s := metric.GetSummary()
+			quantiles := make([]otlpQuantile, 0, len(s.GetQuantile()))
+			for _, q := range s.GetQuantile() {
+				if math.IsNaN(q.GetValue()) {
+					continue
+				}
+				quantiles = append(quantiles, otlpQuantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+			}
+			summary.DataPoints = append(summary.DataPoints, otlpSummaryDataPoint{
+				Attributes:        labelAttributes(metric.GetLabel()),
+				StartTimeUnixNano: e.startTime(s.GetCreatedTimestamp().AsTime()),
+				TimeUnixNano:      timeUnixNano,
+				Count:             strconv.FormatUint(s.GetSampleCount(), 10),
+				Sum:               s.GetSampleSum(),
+				QuantileValues:    quantiles,
+			})
+		}
+		m.Summary = summary
+	default:
+		return nil
+	}
+
+	return m
+}
+
+// startTime returns the created timestamp of a cumulative metric, or the
+// start of the exporter when the metric has none
+func (e *OTLPExporter) startTime(created time.Time) string {
+	if created.Unix() <= 0 {
+		return unixNano(e.start)
+	}
+	return unixNano(created)
+}
+
+// histogramBuckets converts the cumulative buckets of a Prometheus histogram
+// to the explicit bounds and the counts of the OTLP buckets, the last one
+// counting the observations above the last bound
+func histogramBuckets(h *dto.Histogram) (bounds []float64, counts []string) {
+	var previous uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		bounds = append(bounds, b.GetUpperBound())
+		counts = append(counts, strconv.FormatUint(b.GetCumulativeCount()-previous, 10))
+		previous = b.GetCumulativeCount()
+	}
+	counts = append(counts, strconv.FormatUint(h.GetSampleCount()-previous, 10))
+
+	return bounds, counts
+}
+
+// labelAttributes converts the labels of a metric, already sorted by name
+func labelAttributes(labels []*dto.LabelPair) []otlpAttribute {
+	result := make([]otlpAttribute, 0, len(labels))
+	for _, l := range labels {
+		result = append(result, otlpAttribute{Key: l.GetName(), Value: otlpAttributeValue{StringValue: l.GetValue()}})
+	}
+	return result
+}
+
+// attributes converts the attributes, sorted by key
+func attributes(attrs map[string]string) []otlpAttribute {
+	result := make([]otlpAttribute, 0, len(attrs))
+	for key, value := range attrs {
+		result = append(result, otlpAttribute{Key: key, Value: otlpAttributeValue{StringValue: value}})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
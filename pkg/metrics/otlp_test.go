@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestOTLPExporterPush(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests", Help: "The requests"}, []string{"status"})
+	requests.WithLabelValues("200").Add(3)
+	connections := prometheus.NewGauge(prometheus.GaugeOpts{Name: "connections", Help: "The connections"})
+	connections.Set(5)
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "duration_seconds", Help: "The duration", Buckets: []float64{0.1, 1}})
+	duration.Observe(0.05)
+	duration.Observe(0.5)
+	duration.Observe(0.7)
+	duration.Observe(2)
+	reg.MustRegister(requests, connections, duration)
+
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(reg, server.URL+"/v1/metrics", map[string]string{"Authorization": "Bearer token"}, time.Second, map[string]string{"service.name": "ingress-nginx"})
+	if err := exporter.Push(context.Background()); err != nil {
+		t.Fatalf("unexpected error pushing the metrics: %v", err)
+	}
+
+	if header.Get("Content-Type") != "application/json" || header.Get("Authorization") != "Bearer token" {
+		t.Errorf("unexpected headers %v", header)
+	}
+
+	var req otlpRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("unexpected error decoding the request: %v", err)
+	}
+
+	resource := req.ResourceMetrics[0].Resource.Attributes
+	if !reflect.DeepEqual(resource, []otlpAttribute{{Key: "service.name", Value: otlpAttributeValue{StringValue: "ingress-nginx"}}}) {
+		t.Errorf("unexpected resource attributes %v", resource)
+	}
+
+	metrics := map[string]otlpMetric{}
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	sum := metrics["requests"].Sum
+	if sum == nil || !sum.IsMonotonic || sum.AggregationTemporality != aggregationTemporalityCumulative {
+		t.Fatalf("expected a cumulative monotonic sum for requests but got %+v", metrics["requests"])
+	}
+	if sum.DataPoints[0].AsDouble != 3 || sum.DataPoints[0].Attributes[0].Key != "status" || sum.DataPoints[0].StartTimeUnixNano == "" {
+		t.Errorf("unexpected data point %+v", sum.DataPoints[0])
+	}
+
+	gauge := metrics["connections"].Gauge
+	if gauge == nil || gauge.DataPoints[0].AsDouble != 5 {
+		t.Errorf("expected a gauge of 5 for connections but got %+v", metrics["connections"])
+	}
+
+	histogram := metrics["duration_seconds"].Histogram
+	if histogram == nil {
+		t.Fatalf("expected a histogram for duration_seconds but got %+v", metrics["duration_seconds"])
+	}
+	dp := histogram.DataPoints[0]
+	if dp.Count != "4" || dp.Sum != 3.25 {
+		t.Errorf("unexpected count %v and sum %v", dp.Count, dp.Sum)
+	}
+	if !reflect.DeepEqual(dp.ExplicitBounds, []float64{0.1, 1}) || !reflect.DeepEqual(dp.BucketCounts, []string{"1", "2", "1"}) {
+		t.Errorf("unexpected buckets %v %v", dp.ExplicitBounds, dp.BucketCounts)
+	}
+}
+
+func TestOTLPExporterPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(prometheus.NewRegistry(), server.URL, nil, time.Second, nil)
+	if err := exporter.Push(context.Background()); err == nil {
+		t.Errorf("expected an error for a rejected push")
+	}
+}
@@ -31,11 +31,33 @@ const (
 	// The name of each file is <namespace>-<secret name>.pem. The content is the concatenated
 	// certificate and key.
 	DefaultSSLDirectory = "/etc/ingress-controller/ssl"
+
+	// SnapshotsDirectory defines the location where the snapshots of the
+	// configurations applied by the ingress controller are kept
+	SnapshotsDirectory = "/etc/ingress-controller/snapshots"
+
+	// ModSecurityDirectory defines the location where the ModSecurity rules
+	// files rendered from the WAFPolicies are written
+	ModSecurityDirectory = "/etc/ingress-controller/modsecurity"
+
+	// NJSDirectory defines the location where the njs scripts of the
+	// ConfigMaps referenced by the Ingresses are written, one directory per
+	// ConfigMap and version of its scripts
+	NJSDirectory = "/etc/ingress-controller/njs"
+
+	// ErrorPagesDirectory defines the location where the error pages of the
+	// ConfigMaps referenced by the Ingresses are written, one directory per
+	// ConfigMap and version of its pages
+	ErrorPagesDirectory = "/etc/ingress-controller/errorpages"
 )
 
 var directories = []string{
 	DefaultSSLDirectory,
 	AuthDirectory,
+	SnapshotsDirectory,
+	ModSecurityDirectory,
+	NJSDirectory,
+	ErrorPagesDirectory,
 }
 
 // CreateRequiredDirectories verifies if the required directories to
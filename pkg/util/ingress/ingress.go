@@ -121,6 +121,15 @@ func IsDynamicConfigurationEnough(newcfg, oldcfg *ingress.Configuration) bool {
 	copyOfRunningConfig.Backends = []*ingress.Backend{}
 	copyOfPcfg.Backends = []*ingress.Backend{}
 
+	copyOfRunningConfig.DynamicConfigChecksum = ""
+	copyOfPcfg.DynamicConfigChecksum = ""
+
+	copyOfRunningConfig.Denylist = nil
+	copyOfPcfg.Denylist = nil
+
+	copyOfRunningConfig.LuaSecrets = nil
+	copyOfPcfg.LuaSecrets = nil
+
 	clearL4serviceEndpoints(&copyOfRunningConfig)
 	clearL4serviceEndpoints(&copyOfPcfg)
 
@@ -133,28 +142,26 @@ func IsDynamicConfigurationEnough(newcfg, oldcfg *ingress.Configuration) bool {
 // clearL4serviceEndpoints is a helper function to clear endpoints from the ingress configuration since they should be ignored when
 // checking if the new configuration changes can be applied dynamically.
 func clearL4serviceEndpoints(config *ingress.Configuration) {
-	clearedTCPL4Services := make([]ingress.L4Service, 0, len(config.TCPEndpoints))
-	clearedUDPL4Services := make([]ingress.L4Service, 0, len(config.UDPEndpoints))
-	for i := range config.TCPEndpoints {
+	config.TCPEndpoints = clearL4Services(config.TCPEndpoints)
+	config.UDPEndpoints = clearL4Services(config.UDPEndpoints)
+}
+
+func clearL4Services(services []ingress.L4Service) []ingress.L4Service {
+	clearedL4Services := make([]ingress.L4Service, 0, len(services))
+	for i := range services {
 		copyofService := ingress.L4Service{
-			Port:      config.TCPEndpoints[i].Port,
-			Backend:   config.TCPEndpoints[i].Backend,
+			Port:      services[i].Port,
+			Backend:   services[i].Backend,
 			Endpoints: []ingress.Endpoint{},
 			Service:   nil,
+			Hostnames: services[i].Hostnames,
 		}
-		clearedTCPL4Services = append(clearedTCPL4Services, copyofService)
-	}
-	for i := range config.UDPEndpoints {
-		copyofService := ingress.L4Service{
-			Port:      config.UDPEndpoints[i].Port,
-			Backend:   config.UDPEndpoints[i].Backend,
-			Endpoints: []ingress.Endpoint{},
-			Service:   nil,
+		if len(services[i].SNIServices) > 0 {
+			copyofService.SNIServices = clearL4Services(services[i].SNIServices)
 		}
-		clearedUDPL4Services = append(clearedUDPL4Services, copyofService)
+		clearedL4Services = append(clearedL4Services, copyofService)
 	}
-	config.TCPEndpoints = clearedTCPL4Services
-	config.UDPEndpoints = clearedUDPL4Services
+	return clearedL4Services
 }
 
 // clearCertificates is a helper function to clear Certificates from the ingress configuration since they should be ignored when
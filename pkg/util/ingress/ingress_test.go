@@ -19,6 +19,9 @@ package ingress
 import (
 	"testing"
 
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
 	"k8s.io/ingress-nginx/pkg/apis/ingress"
 )
 
@@ -82,6 +85,26 @@ func TestIsDynamicConfigurationEnough(t *testing.T) {
 		t.Errorf("Expected to be dynamically configurable when only backends change")
 	}
 
+	newConfig = &ingress.Configuration{
+		Backends:              backends,
+		Servers:               servers,
+		DynamicConfigChecksum: "12345",
+	}
+
+	if !IsDynamicConfigurationEnough(newConfig, runningConfig) {
+		t.Errorf("Expected to be dynamically configurable when only the dynamic keys of the configuration change")
+	}
+
+	newConfig = &ingress.Configuration{
+		Backends: backends,
+		Servers:  servers,
+		Denylist: []ingress.DenylistEntry{{CIDR: "203.0.113.0/24"}},
+	}
+
+	if !IsDynamicConfigurationEnough(newConfig, runningConfig) {
+		t.Errorf("Expected to be dynamically configurable when only the denylist changes")
+	}
+
 	newServers := []*ingress.Server{{
 		Hostname: "myapp1.fake",
 		Locations: []*ingress.Location{
@@ -122,6 +145,38 @@ func TestIsDynamicConfigurationEnough(t *testing.T) {
 		t.Errorf("Expected to be dynamically configurable when backend and SSLCert changes")
 	}
 
+	tcpEndpoints := []ingress.L4Service{{
+		Port: 9000,
+		Backend: ingress.L4Backend{
+			Name:      "myapp",
+			Namespace: "fakenamespace",
+			Port:      intstr.FromInt(8080),
+			Protocol:  apiv1.ProtocolTCP,
+		},
+		Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "8080"}},
+	}}
+	streamConfig := &ingress.Configuration{
+		Backends:     backends,
+		Servers:      servers,
+		TCPEndpoints: tcpEndpoints,
+	}
+
+	newTCPEndpoints := []ingress.L4Service{tcpEndpoints[0]}
+	newTCPEndpoints[0].Endpoints = []ingress.Endpoint{}
+	newStreamConfig := &ingress.Configuration{
+		Backends:     backends,
+		Servers:      servers,
+		TCPEndpoints: newTCPEndpoints,
+	}
+	if !IsDynamicConfigurationEnough(newStreamConfig, streamConfig) {
+		t.Errorf("Expected to be dynamically configurable when only the endpoints of a stream service change")
+	}
+
+	newTCPEndpoints[0].Backend.ProxyProtocol.Decode = true
+	if IsDynamicConfigurationEnough(newStreamConfig, streamConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the PROXY protocol of a stream service changes")
+	}
+
 	if !runningConfig.Equal(commonConfig) {
 		t.Errorf("Expected running config to not change")
 	}
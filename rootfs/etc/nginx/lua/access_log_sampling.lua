@@ -0,0 +1,38 @@
+-- Access log sampling.
+--
+-- The header filter of the sampled locations decides whether the request is
+-- written to the access log: the requests with an error status, or whose
+-- response starts after the slow threshold, are always logged, the other ones
+-- with the sampling rate. The requests not sampled set $access_log_sampled to
+-- 0, the access_log directives are conditioned by $loggable_sampled.
+--
+local ngx = ngx
+local math_random = math.random
+
+local _M = {}
+
+-- sampled returns whether the request is written to the access log
+function _M.sampled(sampling)
+  if sampling.rate >= 1 then
+    return true
+  end
+
+  if ngx.status >= sampling.min_status then
+    return true
+  end
+
+  if sampling.slow_threshold > 0 and
+      ngx.now() - ngx.req.start_time() >= sampling.slow_threshold then
+    return true
+  end
+
+  return math_random() < sampling.rate
+end
+
+function _M.header(sampling)
+  if not _M.sampled(sampling) then
+    ngx.var.access_log_sampled = "0"
+  end
+end
+
+return _M
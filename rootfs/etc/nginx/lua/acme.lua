@@ -0,0 +1,48 @@
+-- ACME HTTP-01 challenges.
+--
+-- The controller obtains certificates from an ACME server when --enable-acme
+-- is set. While an order is pending, the key authorizations of its HTTP-01
+-- challenges are stored in a shared dictionary and served on
+-- /.well-known/acme-challenge/<token>, before any redirect or authentication
+-- of the location. Unknown tokens are left to the Ingresses, so other solvers
+-- like cert-manager keep working.
+--
+local ngx = ngx
+local string_sub = string.sub
+
+local CHALLENGE_PREFIX = "/.well-known/acme-challenge/"
+-- the controller removes the tokens once the challenges are validated, they
+-- expire anyway in case it does not
+local TOKEN_TTL = 3600
+
+local _M = {}
+
+local function dict()
+  return ngx.shared.acme_challenges
+end
+
+function _M.rewrite()
+  local uri = ngx.var.uri
+  if string_sub(uri, 1, #CHALLENGE_PREFIX) ~= CHALLENGE_PREFIX then
+    return
+  end
+
+  local key_authorization = dict():get(string_sub(uri, #CHALLENGE_PREFIX + 1))
+  if not key_authorization then
+    return
+  end
+
+  ngx.header["Content-Type"] = "text/plain"
+  ngx.print(key_authorization)
+  return ngx.exit(ngx.HTTP_OK)
+end
+
+function _M.set_challenge(token, key_authorization)
+  return dict():set(token, key_authorization, TOKEN_TTL)
+end
+
+function _M.remove_challenge(token)
+  dict():delete(token)
+end
+
+return _M
@@ -0,0 +1,98 @@
+-- Automatic banning of the abusive clients.
+--
+-- The responses of every client with the configured status codes are counted
+-- in a fixed window by the replica. A client reaching the threshold is banned
+-- for the configured duration, its requests to all the servers are denied in
+-- the rewrite phase. The requests of the banned clients are not counted, the
+-- ban is not extended while it lasts. The ban is checked again in the log
+-- phase, ngx.ctx is reset by the internal redirects of the error pages.
+--
+local ngx = ngx
+local type = type
+local ipairs = ipairs
+local tonumber = tonumber
+local tostring = tostring
+
+local _M = {}
+
+local function dict()
+  return ngx.shared.auto_ban
+end
+
+local function is_counted(status_codes, status)
+  if type(status_codes) ~= "table" then
+    return false
+  end
+
+  for _, code in ipairs(status_codes) do
+    if code == status then
+      return true
+    end
+  end
+
+  return false
+end
+
+function _M.is_banned(address)
+  return dict():get("ban:" .. address) ~= nil
+end
+
+-- ban denies the requests of the address for the duration of the
+-- configuration
+function _M.ban(config, address)
+  local ok, err = dict():safe_set("ban:" .. address, true, config.duration)
+  if not ok then
+    ngx.log(ngx.ERR, "could not ban ", address, ": ", tostring(err))
+    return
+  end
+
+  ngx.log(ngx.WARN, "banning ", address, " for ", config.duration, " seconds after ",
+    config.threshold, " responses within ", config.window, " seconds")
+  ngx.ctx.client_banned = true
+end
+
+function _M.rewrite(config)
+  if not config or not config.enabled then
+    return
+  end
+
+  local address = ngx.var.remote_addr
+  if _M.is_banned(address) then
+    ngx.ctx.auto_banned = true
+    return ngx.exit(ngx.HTTP_FORBIDDEN)
+  end
+end
+
+function _M.log(config)
+  if not config or not config.enabled then
+    return
+  end
+  if config.threshold <= 0 or config.window <= 0 or config.duration <= 0 then
+    return
+  end
+
+  local status = tonumber(ngx.var.status)
+  if not is_counted(config.status_codes, status) then
+    return
+  end
+
+  local address = ngx.var.remote_addr
+  if _M.is_banned(address) then
+    return
+  end
+
+  local responses, err = dict():incr("count:" .. address, 1, 0, config.window)
+  if not responses then
+    ngx.log(ngx.ERR, "auto_ban:incr failed ", tostring(err))
+    return
+  end
+
+  -- the responses of the concurrent requests may exceed the threshold, the
+  -- client is only banned once
+  if responses == config.threshold then
+    dict():delete("count:" .. address)
+    _M.ban(config, address)
+  end
+end
+
+return _M
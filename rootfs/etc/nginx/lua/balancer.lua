@@ -1,14 +1,20 @@
 local ngx_balancer = require("ngx.balancer")
 local cjson = require("cjson.safe")
 local util = require("util")
-local dns_lookup = require("util.dns").lookup
+local external_name = require("util.external_name")
 local configuration = require("configuration")
 local round_robin = require("balancer.round_robin")
 local chash = require("balancer.chash")
 local chashsubset = require("balancer.chashsubset")
 local sticky_balanced = require("balancer.sticky_balanced")
 local sticky_persistent = require("balancer.sticky_persistent")
+local sticky_header = require("balancer.sticky_header")
 local ewma = require("balancer.ewma")
+local least_conn = require("balancer.least_conn")
+local canary = require("balancer.canary")
+local outlier = require("balancer.outlier")
+local healthcheck = require("balancer.healthcheck")
+local websocket = require("websocket")
 local string = string
 local ipairs = ipairs
 local table = table
@@ -31,7 +37,9 @@ local IMPLEMENTATIONS = {
   chashsubset = chashsubset,
   sticky_balanced = sticky_balanced,
   sticky_persistent = sticky_persistent,
+  sticky_header = sticky_header,
   ewma = ewma,
+  least_conn = least_conn,
 }
 
 local PROHIBITED_LOCALHOST_PORT = configuration.prohibited_localhost_port or '10246'
@@ -41,11 +49,17 @@ local _M = {}
 local balancers = {}
 local backends_with_external_name = {}
 local backends_last_synced_at = 0
+local health_checks_version = 0
+local external_name_health_checks_version = 0
 
 local function get_implementation(backend)
   local name = backend["load-balance"] or DEFAULT_LB_ALG
 
   if backend["sessionAffinityConfig"] and
+     backend["sessionAffinityConfig"]["mode"] == "header" then
+    name = "sticky_header"
+
+  elseif backend["sessionAffinityConfig"] and
      backend["sessionAffinityConfig"]["name"] == "cookie" then
     if backend["sessionAffinityConfig"]["mode"] == "persistent" then
       name = "sticky_persistent"
@@ -72,19 +86,6 @@ local function get_implementation(backend)
   return implementation
 end
 
-local function resolve_external_names(original_backend)
-  local backend = util.deepcopy(original_backend)
-  local endpoints = {}
-  for _, endpoint in ipairs(backend.endpoints) do
-    local ips = dns_lookup(endpoint.address)
-    for _, ip in ipairs(ips) do
-      table.insert(endpoints, { address = ip, port = endpoint.port })
-    end
-  end
-  backend.endpoints = endpoints
-  return backend
-end
-
 local function format_ipv6_endpoints(endpoints)
   local formatted_endpoints = {}
   for _, endpoint in ipairs(endpoints) do
@@ -97,24 +98,25 @@ local function format_ipv6_endpoints(endpoints)
   return formatted_endpoints
 end
 
-local function is_backend_with_external_name(backend)
-  local serv_type = backend.service and backend.service.spec
-                      and backend.service.spec["type"]
-  return serv_type == "ExternalName"
-end
-
 local function sync_backend(backend)
   if not backend.endpoints or #backend.endpoints == 0 then
     balancers[backend.name] = nil
+    outlier.remove(backend.name)
+    healthcheck.remove(backend.name)
     return
   end
 
-  if is_backend_with_external_name(backend) then
-    backend = resolve_external_names(backend)
+  if external_name.is_backend_with_external_name(backend) then
+    backend = external_name.resolve(backend)
   end
 
   backend.endpoints = format_ipv6_endpoints(backend.endpoints)
 
+  healthcheck.sync(backend)
+  backend = healthcheck.filter(backend)
+
+  outlier.sync(backend)
+
   local implementation = get_implementation(backend)
   local balancer = balancers[backend.name]
 
@@ -137,15 +139,26 @@ local function sync_backend(backend)
   balancer:sync(backend)
 end
 
+-- the backends with external names are synced when their host names resolve
+-- to other addresses, or when an endpoint changes health status
 local function sync_backends_with_external_name()
+  local raw_health_checks_version = healthcheck.version()
+  local health_checks_changed = raw_health_checks_version ~= external_name_health_checks_version
+  external_name_health_checks_version = raw_health_checks_version
+
   for _, backend_with_external_name in pairs(backends_with_external_name) do
-    sync_backend(backend_with_external_name)
+    if health_checks_changed or external_name.is_outdated(backend_with_external_name) then
+      sync_backend(backend_with_external_name)
+    end
   end
 end
 
 local function sync_backends()
   local raw_backends_last_synced_at = configuration.get_raw_backends_last_synced_at()
-  if raw_backends_last_synced_at <= backends_last_synced_at then
+  -- the balancers are synced again when an endpoint changes health status
+  local raw_health_checks_version = healthcheck.version()
+  if raw_backends_last_synced_at <= backends_last_synced_at and
+     raw_health_checks_version == health_checks_version then
     return
   end
 
@@ -163,7 +176,7 @@ local function sync_backends()
 
   local balancers_to_keep = {}
   for _, new_backend in ipairs(new_backends) do
-    if is_backend_with_external_name(new_backend) then
+    if external_name.is_backend_with_external_name(new_backend) then
       local backend_with_external_name = util.deepcopy(new_backend)
       backends_with_external_name[backend_with_external_name.name] = backend_with_external_name
     else
@@ -175,10 +188,14 @@ local function sync_backends()
   for backend_name, _ in pairs(balancers) do
     if not balancers_to_keep[backend_name] then
       balancers[backend_name] = nil
+      outlier.remove(backend_name)
+      healthcheck.remove(backend_name)
       backends_with_external_name[backend_name] = nil
+      external_name.remove(backend_name)
     end
   end
   backends_last_synced_at = raw_backends_last_synced_at
+  health_checks_version = raw_health_checks_version
 end
 
 local function route_to_alternative_balancer(balancer)
@@ -255,15 +272,49 @@ local function route_to_alternative_balancer(balancer)
     end
   end
 
+  local target_query = traffic_shaping_policy.query
+  if target_query and #target_query > 0 then
+    local query = ngx.var["arg_" .. target_query]
+    if query then
+      if traffic_shaping_policy.queryValue
+         and #traffic_shaping_policy.queryValue > 0 then
+        if traffic_shaping_policy.queryValue == query then
+          return true
+        end
+      elseif query == "always" then
+        return true
+      elseif query == "never" then
+        return false
+      end
+    end
+  end
+
+  if canary.matches_geo(traffic_shaping_policy) then
+    return true
+  end
+
+  -- a canary scaled down to a weight of 0 serves none of the clients, even
+  -- the ones previously assigned to it
+  local sticky = traffic_shaping_policy.sticky and traffic_shaping_policy.weight > 0
+
+  if sticky then
+    local assignment = canary.get_assignment(traffic_shaping_policy, backend_name)
+    if assignment ~= nil then
+      return assignment
+    end
+  end
+
   local weightTotal = 100
   if traffic_shaping_policy.weightTotal ~= nil and traffic_shaping_policy.weightTotal > 100 then
     weightTotal = traffic_shaping_policy.weightTotal
   end
-  if math.random(weightTotal) <= traffic_shaping_policy.weight then
-    return true
+  local route_to_canary = math.random(weightTotal) <= traffic_shaping_policy.weight
+
+  if sticky then
+    canary.set_assignment(traffic_shaping_policy, backend_name, route_to_canary)
   end
 
-  return false
+  return route_to_canary
 end
 
 local function get_balancer_by_upstream_name(upstream_name)
@@ -282,14 +333,24 @@ local function get_balancer()
     return nil
   end
 
+  if balancer.alternative_backends then
+    -- remember which variant served the request so the canary metrics can
+    -- compare the stable and canary backends of the same location
+    ngx.ctx.canary_backend = balancer.alternative_backends[1]
+    ngx.ctx.canary_variant = "stable"
+  end
+
   if route_to_alternative_balancer(balancer) then
     local alternative_backend_name = balancer.alternative_backends[1]
     ngx.var.proxy_alternative_upstream_name = alternative_backend_name
+    ngx.ctx.canary_variant = "canary"
 
+    backend_name = alternative_backend_name
     balancer = balancers[alternative_backend_name]
   end
 
   ngx.ctx.balancer = balancer
+  ngx.ctx.balancer_backend_name = backend_name
 
   return balancer
 end
@@ -314,6 +375,8 @@ function _M.init_worker()
     ngx.log(ngx.ERR, "error when setting up timer.every for sync_backends_with_external_name: ",
             err)
   end
+
+  healthcheck.init_worker()
 end
 
 function _M.rewrite()
@@ -322,6 +385,11 @@ function _M.rewrite()
     ngx.status = ngx.HTTP_SERVICE_UNAVAILABLE
     return ngx.exit(ngx.status)
   end
+
+  -- balancers that need cosockets prepare the request here
+  if balancer.rewrite then
+    balancer:rewrite()
+  end
 end
 
 function _M.balance()
@@ -336,11 +404,28 @@ function _M.balance()
     return
   end
 
+  -- ask the balancer for another peer when the returned one is ejected, the
+  -- ejected peer is used as a last resort
+  local backend_name = ngx.ctx.balancer_backend_name
+  for _ = 1, outlier.max_tries(backend_name) do
+    if not outlier.is_ejected(peer) then
+      break
+    end
+
+    local next_peer = balancer:balance()
+    if not next_peer then
+      break
+    end
+    peer = next_peer
+  end
+
   if peer:match(PROHIBITED_PEER_PATTERN) then
     ngx.log(ngx.ERR, "attempted to proxy to self, balancer: ", balancer.name, ", peer: ", peer)
     return
   end
 
+  websocket.balance()
+
   ngx_balancer.set_more_tries(1)
 
   local ok, err = ngx_balancer.set_current_peer(peer)
@@ -356,6 +441,8 @@ function _M.log()
     return
   end
 
+  outlier.after_balance(ngx.ctx.balancer_backend_name)
+
   if not balancer.after_balance then
     return
   end
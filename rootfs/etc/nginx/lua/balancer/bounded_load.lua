@@ -0,0 +1,92 @@
+-- Consistent hashing with bounded loads.
+--
+-- Implements the algorithm described in
+-- https://research.google/pubs/pub46580/ on top of the chash balancers: the
+-- number of in-flight requests of every endpoint is tracked in a shared
+-- dictionary and an endpoint is skipped when its load is above
+-- ceil(load_factor * (total_load + 1) / endpoints), so hot keys spill over to
+-- the next endpoints of the ring instead of overloading a single pod.
+--
+local ngx = ngx
+local math = math
+local pairs = pairs
+local ipairs = ipairs
+local tostring = tostring
+local table_insert = table.insert
+
+local _M = {}
+
+local function load(endpoint_string)
+  return ngx.shared.balancer_bounded_load:get(endpoint_string) or 0
+end
+
+local function incr(endpoint_string, value)
+  local new_value, err = ngx.shared.balancer_bounded_load:incr(endpoint_string, value, 0)
+  if not new_value then
+    ngx.log(ngx.WARN, "balancer_bounded_load:incr failed " .. tostring(err))
+    return
+  end
+
+  if new_value < 0 then
+    ngx.shared.balancer_bounded_load:set(endpoint_string, 0)
+  end
+end
+
+-- capacity returns the maximum number of in-flight requests an endpoint of
+-- the given nodes can have before requests spill over to the next one.
+function _M.capacity(load_factor, nodes)
+  local total_load, endpoints_count = 0, 0
+  for endpoint_string, _ in pairs(nodes) do
+    total_load = total_load + load(endpoint_string)
+    endpoints_count = endpoints_count + 1
+  end
+
+  if endpoints_count == 0 then
+    return 0
+  end
+
+  return math.ceil(load_factor * (total_load + 1) / endpoints_count)
+end
+
+function _M.is_overloaded(endpoint_string, capacity)
+  return load(endpoint_string) >= capacity
+end
+
+-- acquire counts a request as in flight for the endpoint until release is
+-- called at the end of the request
+function _M.acquire(endpoint_string)
+  incr(endpoint_string, 1)
+
+  local acquired = ngx.ctx.balancer_bounded_load_acquired
+  if not acquired then
+    acquired = {}
+    ngx.ctx.balancer_bounded_load_acquired = acquired
+  end
+  table_insert(acquired, endpoint_string)
+end
+
+function _M.release()
+  local acquired = ngx.ctx.balancer_bounded_load_acquired
+  if not acquired then
+    return
+  end
+
+  for _, endpoint_string in ipairs(acquired) do
+    incr(endpoint_string, -1)
+  end
+
+  ngx.ctx.balancer_bounded_load_acquired = nil
+end
+
+-- parse_load_factor returns the load factor configured for the backend or nil
+-- when bounded loads are disabled
+function _M.parse_load_factor(backend)
+  local load_factor = backend["upstreamHashByConfig"]["upstream-hash-by-bounded-load-factor"]
+  if not load_factor or load_factor < 1 then
+    return nil
+  end
+
+  return load_factor
+end
+
+return _M
@@ -0,0 +1,165 @@
+-- Sticky canary assignment.
+--
+-- When a canary backend has a sticky traffic shaping policy, the weighted
+-- decision is made only once per client and persisted in a signed cookie, so
+-- subsequent requests keep being served by the same variant instead of
+-- flapping between stable and canary. The cookie is signed with the key of the
+-- Secret referenced by canary-sticky-secret, without it the decisions are not
+-- persisted.
+--
+-- Geo canary routing.
+--
+-- The requests whose client is located by the GeoIP2 databases in one of the
+-- countries or continents of the traffic shaping policy are routed to the
+-- canary.
+--
+local ck = require("resty.cookie")
+local configuration = require("configuration")
+
+local ngx = ngx
+local ipairs = ipairs
+local tonumber = tonumber
+local string_format = string.format
+
+local DEFAULT_COOKIE_PREFIX = "canary_"
+local COOKIE_VALUE_DELIMITER = "|"
+local ASSIGNMENT_CANARY = "canary"
+local ASSIGNMENT_STABLE = "stable"
+
+local _M = {}
+
+local function sign(key, backend_name, assignment)
+  local digest = ngx.hmac_sha1(key, backend_name .. COOKIE_VALUE_DELIMITER .. assignment)
+  return ngx.encode_base64(digest, true)
+end
+
+function _M.cookie_name(policy, backend_name)
+  if policy.stickyCookie and #policy.stickyCookie > 0 then
+    return policy.stickyCookie
+  end
+
+  return DEFAULT_COOKIE_PREFIX .. ngx.md5(backend_name):sub(1, 8)
+end
+
+-- get_assignment returns true when the client was assigned to the canary,
+-- false when it was assigned to the stable backend and nil when the request
+-- does not carry a valid assignment for the given canary backend.
+function _M.get_assignment(policy, backend_name)
+  local key = configuration.get_secret("canary_sticky_secret")
+  if not key then
+    return nil
+  end
+
+  local cookie, err = ck:new()
+  if not cookie then
+    ngx.log(ngx.ERR, err)
+    return nil
+  end
+
+  local raw_value = cookie:get(_M.cookie_name(policy, backend_name))
+  if not raw_value then
+    return nil
+  end
+
+  local assignment, signature = raw_value:match("^(%a+)|(.+)$")
+  if assignment ~= ASSIGNMENT_CANARY and assignment ~= ASSIGNMENT_STABLE then
+    return nil
+  end
+
+  if signature ~= sign(key, backend_name, assignment) then
+    ngx.log(ngx.INFO, string_format("ignoring canary assignment with invalid " ..
+                                    "signature for backend %s", backend_name))
+    return nil
+  end
+
+  return assignment == ASSIGNMENT_CANARY
+end
+
+-- geo_var returns the first non empty variable, the variables of the GeoIP2
+-- databases which are not loaded are not defined
+local function geo_var(...)
+  for _, name in ipairs({ ... }) do
+    local value = ngx.var[name]
+    if value and value ~= "" then
+      return value
+    end
+  end
+
+  return nil
+end
+
+local function contains(codes, code)
+  if not code then
+    return false
+  end
+
+  for _, c in ipairs(codes) do
+    if c == code then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- matches_geo returns true when the client of the request is located in one
+-- of the countries or continents of the policy
+function _M.matches_geo(policy)
+  local countries = policy.geoCountries
+  if countries and #countries > 0 then
+    local country = geo_var("geoip2_city_country_code", "geoip2_country_code")
+    if contains(countries, country) then
+      return true
+    end
+  end
+
+  local continents = policy.geoContinents
+  if continents and #continents > 0 then
+    local continent = geo_var("geoip2_city_continent_code", "geoip2_continent_code")
+    if contains(continents, continent) then
+      return true
+    end
+  end
+
+  return false
+end
+
+function _M.set_assignment(policy, backend_name, routed_to_canary)
+  local key = configuration.get_secret("canary_sticky_secret")
+  if not key then
+    return
+  end
+
+  local cookie, err = ck:new()
+  if not cookie then
+    ngx.log(ngx.ERR, err)
+    return
+  end
+
+  local assignment = ASSIGNMENT_STABLE
+  if routed_to_canary then
+    assignment = ASSIGNMENT_CANARY
+  end
+
+  local cookie_data = {
+    key = _M.cookie_name(policy, backend_name),
+    value = assignment .. COOKIE_VALUE_DELIMITER .. sign(key, backend_name, assignment),
+    path = "/",
+    httponly = true,
+    samesite = "Lax",
+    secure = ngx.var.https == "on",
+  }
+
+  local max_age = tonumber(policy.stickyMaxAge)
+  if max_age and max_age > 0 then
+    cookie_data.max_age = max_age
+  end
+
+  local ok
+  ok, err = cookie:set(cookie_data)
+  if not ok then
+    ngx.log(ngx.ERR, err)
+  end
+end
+
+return _M
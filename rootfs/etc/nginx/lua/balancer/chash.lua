@@ -1,6 +1,7 @@
 local balancer_resty = require("balancer.resty")
 local resty_chash = require("resty.chash")
 local util = require("util")
+local bounded_load = require("balancer.bounded_load")
 local ngx_log = ngx.log
 local ngx_ERR = ngx.ERR
 local setmetatable = setmetatable
@@ -18,6 +19,7 @@ function _M.new(self, backend)
   local o = {
     instance = self.factory:new(nodes),
     hash_by = complex_val,
+    bounded_load_factor = bounded_load.parse_load_factor(backend),
     traffic_shaping_policy = backend.trafficShapingPolicy,
     alternative_backends = backend.alternativeBackends,
   }
@@ -26,9 +28,44 @@ function _M.new(self, backend)
   return o
 end
 
+-- find_bounded walks the ring from the endpoint of the key until it finds
+-- one whose load is below the capacity
+local function find_bounded(self, key)
+  local capacity = bounded_load.capacity(self.bounded_load_factor, self.instance.nodes)
+  local endpoint, index = self.instance:find(key)
+  local first_endpoint = endpoint
+
+  for _ = 1, self.instance.npoints do
+    if not bounded_load.is_overloaded(endpoint, capacity) then
+      return endpoint
+    end
+
+    endpoint, index = self.instance:next(index)
+  end
+
+  -- every endpoint is at capacity, which can only happen while the loads are
+  -- being updated by other requests
+  return first_endpoint
+end
+
 function _M.balance(self)
   local key = util.generate_var_value(self.hash_by)
-  return self.instance:find(key)
+  if not self.bounded_load_factor then
+    return self.instance:find(key)
+  end
+
+  local endpoint = find_bounded(self, key)
+  bounded_load.acquire(endpoint)
+  return endpoint
+end
+
+function _M.after_balance(_)
+  bounded_load.release()
+end
+
+function _M.sync(self, backend)
+  self.bounded_load_factor = bounded_load.parse_load_factor(backend)
+  balancer_resty.sync(self, backend)
 end
 
 return _M
@@ -3,6 +3,7 @@
 
 local resty_chash = require("resty.chash")
 local util = require("util")
+local bounded_load = require("balancer.bounded_load")
 local ngx_log = ngx.log
 local ngx_ERR = ngx.ERR
 local setmetatable = setmetatable
@@ -59,6 +60,7 @@ function _M.new(self, backend)
   local o = {
     instance = resty_chash:new(subset_map),
     hash_by = complex_val,
+    bounded_load_factor = bounded_load.parse_load_factor(backend),
     subsets = subsets,
     current_endpoints = backend.endpoints,
     traffic_shaping_policy = backend.trafficShapingPolicy,
@@ -73,17 +75,60 @@ function _M.is_affinitized()
   return false
 end
 
+local function endpoint_string(endpoint)
+  return endpoint.address .. ":" .. endpoint.port
+end
+
+-- find_bounded walks the ring of subsets from the subset of the key until it
+-- finds one with an endpoint whose load is below the capacity
+local function find_bounded(self, key)
+  local capacity = bounded_load.capacity(self.bounded_load_factor,
+                                         util.get_nodes(self.current_endpoints))
+  local subset_id, index = self.instance:find(key)
+  local first_subset_id = subset_id
+
+  for _ = 1, self.instance.npoints do
+    local endpoints = self.subsets[subset_id]
+    local offset = math.random(#endpoints)
+    for i = 0, #endpoints - 1 do
+      local candidate = endpoint_string(endpoints[(offset + i) % #endpoints + 1])
+      if not bounded_load.is_overloaded(candidate, capacity) then
+        return candidate
+      end
+    end
+
+    subset_id, index = self.instance:next(index)
+  end
+
+  -- every endpoint is at capacity, which can only happen while the loads are
+  -- being updated by other requests
+  local endpoints = self.subsets[first_subset_id]
+  return endpoint_string(endpoints[math.random(#endpoints)])
+end
+
 function _M.balance(self)
   local key = util.generate_var_value(self.hash_by)
+  if self.bounded_load_factor then
+    local endpoint = find_bounded(self, key)
+    bounded_load.acquire(endpoint)
+    return endpoint
+  end
+
   local subset_id = self.instance:find(key)
   local endpoints = self.subsets[subset_id]
   local endpoint = endpoints[math.random(#endpoints)]
-  return endpoint.address .. ":" .. endpoint.port
+  return endpoint_string(endpoint)
+end
+
+function _M.after_balance(_)
+  bounded_load.release()
 end
 
 function _M.sync(self, backend)
   local subset_map
 
+  self.bounded_load_factor = bounded_load.parse_load_factor(backend)
+
   local changed = not util.deep_compare(self.current_endpoints, backend.endpoints)
   if not changed then
     return
@@ -6,6 +6,7 @@
 
 
 local resty_lock = require("resty.lock")
+local configuration = require("configuration")
 local util = require("util")
 local split = require("util.split")
 
@@ -23,6 +24,7 @@ local ngx_log = ngx.log
 local INFO = ngx.INFO
 
 local DECAY_TIME = 10 -- this value is in seconds
+local INITIAL_WEIGHT = 0 -- this value is in seconds
 local LOCK_KEY = ":ewma_key"
 local PICK_SET_SIZE = 2
 
@@ -53,10 +55,28 @@ local function unlock()
   return err
 end
 
+-- decay time and initial weight can be configured with the ewma-decay-time
+-- and ewma-initial-weight ConfigMap settings
+local function decay_time()
+  local value = tonumber(configuration.ewma_decay_time)
+  if not value or value <= 0 then
+    return DECAY_TIME
+  end
+  return value
+end
+
+local function initial_weight()
+  local value = tonumber(configuration.ewma_initial_weight)
+  if not value or value < 0 then
+    return INITIAL_WEIGHT
+  end
+  return value
+end
+
 local function decay_ewma(ewma, last_touched_at, rtt, now)
   local td = now - last_touched_at
   td = (td > 0) and td or 0
-  local weight = math.exp(-td/DECAY_TIME)
+  local weight = math.exp(-td/decay_time())
 
   ewma = ewma * weight + rtt * (1.0 - weight)
   return ewma
@@ -85,9 +105,17 @@ local function get_or_update_ewma(upstream, rtt, update)
   if update then
     lock_err = lock(upstream)
   end
-  local ewma = ngx.shared.balancer_ewma:get(upstream) or 0
+  local ewma = ngx.shared.balancer_ewma:get(upstream)
   if lock_err ~= nil then
-    return ewma, lock_err
+    return ewma or initial_weight(), lock_err
+  end
+
+  -- endpoints without any response time yet
+  if not ewma then
+    if not update then
+      return initial_weight(), nil
+    end
+    ewma = 0
   end
 
   local now = ngx.now()
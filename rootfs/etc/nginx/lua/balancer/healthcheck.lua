@@ -0,0 +1,328 @@
+-- Active health checks.
+--
+-- The first worker periodically checks the endpoints of the backends with
+-- healthCheck.type set, either opening a TCP connection, sending a GET
+-- request to healthCheck.path or calling the gRPC health service. An endpoint is unhealthy after
+-- healthCheck.unhealthyThreshold consecutive failed checks and healthy again
+-- after healthCheck.healthyThreshold consecutive successful checks. The
+-- results are shared with all the workers through a shared dictionary, and
+-- unhealthy endpoints are removed from the endpoints given to the balancers.
+-- At most MAX_CONCURRENT_CHECKS endpoints are checked at the same time, by a
+-- pool of light threads sharing the queue of the checks due.
+--
+local healthcheck_grpc = require("balancer.healthcheck_grpc")
+local util = require("util")
+
+local ngx = ngx
+local pairs = pairs
+local ipairs = ipairs
+local pcall = pcall
+local math = math
+local tonumber = tonumber
+local tostring = tostring
+local string_format = string.format
+local table_insert = table.insert
+
+-- measured in seconds
+local CHECK_INTERVAL = 1
+local MAX_CONCURRENT_CHECKS = 32
+
+local UNHEALTHY_PREFIX = "unhealthy:"
+local FAILURES_PREFIX = "failures:"
+local SUCCESSES_PREFIX = "successes:"
+-- incremented every time an endpoint changes status so the workers know they
+-- have to sync their balancers again
+local VERSION_KEY = "version"
+
+local _M = {}
+
+-- health check configuration and endpoints, indexed by backend name
+local backends = {}
+local checks_running = false
+
+local function dict()
+  return ngx.shared.balancer_healthcheck
+end
+
+local function get_endpoint_string(endpoint)
+  return endpoint.address .. ":" .. endpoint.port
+end
+
+-- endpoints shared between backends can be checked with different paths, so
+-- their status is tracked per backend
+local function get_key(backend_name, endpoint_string)
+  return backend_name .. "|" .. endpoint_string
+end
+
+local function forget(backend_name, endpoint_string)
+  local key = get_key(backend_name, endpoint_string)
+  dict():delete(UNHEALTHY_PREFIX .. key)
+  dict():delete(FAILURES_PREFIX .. key)
+  dict():delete(SUCCESSES_PREFIX .. key)
+end
+
+function _M.remove(backend_name)
+  local config = backends[backend_name]
+  if not config then
+    return
+  end
+
+  for _, endpoint in ipairs(config.endpoints) do
+    forget(backend_name, get_endpoint_string(endpoint))
+  end
+  backends[backend_name] = nil
+end
+
+-- sync registers the endpoints of the backend to check. It must be called
+-- with all the endpoints of the backend, before they are filtered.
+function _M.sync(backend)
+  local config = backend.healthCheck
+  if not config or not config.type or config.type == "" then
+    _M.remove(backend.name)
+    return
+  end
+
+  local previous = backends[backend.name]
+  if previous then
+    local _, endpoints_removed = util.diff_endpoints(previous.endpoints, backend.endpoints)
+    for _, endpoint_string in ipairs(endpoints_removed) do
+      forget(backend.name, endpoint_string)
+    end
+  end
+
+  backends[backend.name] = {
+    type = config.type,
+    path = config.path or "/",
+    interval = tonumber(config.interval) or 5,
+    timeout = tonumber(config.timeout) or 1,
+    healthy_threshold = tonumber(config.healthyThreshold) or 2,
+    unhealthy_threshold = tonumber(config.unhealthyThreshold) or 3,
+    grpc_service = config.grpcService or "",
+    endpoints = util.deepcopy(backend.endpoints),
+    checked_at = previous and previous.checked_at or 0,
+  }
+end
+
+function _M.version()
+  return dict():get(VERSION_KEY) or 0
+end
+
+function _M.is_healthy(backend_name, endpoint_string)
+  return not dict():get(UNHEALTHY_PREFIX .. get_key(backend_name, endpoint_string))
+end
+
+-- filter returns the backend without its unhealthy endpoints. When none of the
+-- endpoints is healthy the backend is returned as is, failing open.
+function _M.filter(backend)
+  if not backends[backend.name] then
+    return backend
+  end
+
+  local healthy_endpoints = {}
+  for _, endpoint in ipairs(backend.endpoints) do
+    if _M.is_healthy(backend.name, get_endpoint_string(endpoint)) then
+      table_insert(healthy_endpoints, endpoint)
+    end
+  end
+
+  if #healthy_endpoints == #backend.endpoints then
+    return backend
+  end
+
+  if #healthy_endpoints == 0 then
+    ngx.log(ngx.WARN, string_format("all the endpoints of backend %s are unhealthy, " ..
+                                    "ignoring the health checks", backend.name))
+    return backend
+  end
+
+  local filtered_backend = {}
+  for k, v in pairs(backend) do
+    filtered_backend[k] = v
+  end
+  filtered_backend.endpoints = healthy_endpoints
+
+  return filtered_backend
+end
+
+local function set_changed()
+  local _, err = dict():incr(VERSION_KEY, 1, 0)
+  if err then
+    ngx.log(ngx.ERR, "balancer_healthcheck:incr failed " .. tostring(err))
+  end
+end
+
+-- record updates the status of the endpoint with the result of a check
+function _M.record(backend_name, endpoint_string, healthy)
+  local config = backends[backend_name]
+  if not config then
+    return
+  end
+
+  local key = get_key(backend_name, endpoint_string)
+  local unhealthy = dict():get(UNHEALTHY_PREFIX .. key)
+
+  if healthy then
+    dict():delete(FAILURES_PREFIX .. key)
+    if not unhealthy then
+      return
+    end
+
+    local successes = dict():incr(SUCCESSES_PREFIX .. key, 1, 0)
+    if successes and successes >= config.healthy_threshold then
+      dict():delete(UNHEALTHY_PREFIX .. key)
+      dict():delete(SUCCESSES_PREFIX .. key)
+      set_changed()
+      ngx.log(ngx.NOTICE, string_format("endpoint %s of backend %s is healthy",
+                                        endpoint_string, backend_name))
+    end
+    return
+  end
+
+  dict():delete(SUCCESSES_PREFIX .. key)
+  if unhealthy then
+    return
+  end
+
+  local failures = dict():incr(FAILURES_PREFIX .. key, 1, 0)
+  if failures and failures >= config.unhealthy_threshold then
+    dict():set(UNHEALTHY_PREFIX .. key, true)
+    dict():delete(FAILURES_PREFIX .. key)
+    set_changed()
+    ngx.log(ngx.WARN, string_format("endpoint %s of backend %s is unhealthy after %s " ..
+                                    "failed health checks", endpoint_string, backend_name,
+                                    failures))
+  end
+end
+
+local function check(config, endpoint)
+  local sock = ngx.socket.tcp()
+  sock:settimeout(config.timeout * 1000)
+
+  -- IPv6 addresses are formatted with brackets for the balancers
+  local host = endpoint.address:gsub("^%[(.*)%]$", "%1")
+  local ok, err = sock:connect(host, tonumber(endpoint.port))
+  if not ok then
+    return false, err
+  end
+
+  if config.type == "tcp" then
+    sock:close()
+    return true
+  end
+
+  if config.type == "grpc" then
+    ok, err = healthcheck_grpc.check(sock, get_endpoint_string(endpoint), config.grpc_service)
+    sock:close()
+    return ok, err
+  end
+
+  local request = string_format("GET %s HTTP/1.0\r\nHost: %s\r\n" ..
+                                "User-Agent: ingress-nginx-healthcheck\r\n\r\n",
+                                config.path, get_endpoint_string(endpoint))
+  local bytes
+  bytes, err = sock:send(request)
+  if not bytes then
+    sock:close()
+    return false, err
+  end
+
+  local status_line
+  status_line, err = sock:receive("*l")
+  sock:close()
+  if not status_line then
+    return false, err
+  end
+
+  local status = tonumber(status_line:match("^HTTP/%d%.%d%s+(%d+)"))
+  if not status then
+    return false, "invalid status line: " .. status_line
+  end
+  if status < 200 or status >= 400 then
+    return false, "unexpected status " .. status
+  end
+
+  return true
+end
+
+local function check_endpoint(backend_name, config, endpoint)
+  local endpoint_string = get_endpoint_string(endpoint)
+  local healthy, err = check(config, endpoint)
+  if not healthy then
+    ngx.log(ngx.INFO, string_format("health check of endpoint %s of backend %s failed: %s",
+                                    endpoint_string, backend_name, tostring(err)))
+  end
+
+  _M.record(backend_name, endpoint_string, healthy)
+end
+
+-- check_queue runs the checks of the queue until it is empty, the light
+-- threads of the pool share the position in the queue
+local function check_queue(queue)
+  while queue.next <= #queue.checks do
+    local c = queue.checks[queue.next]
+    queue.next = queue.next + 1
+    check_endpoint(c.backend_name, c.config, c.endpoint)
+  end
+end
+
+-- run checks the endpoints of the backends whose interval elapsed
+function _M.run()
+  if checks_running then
+    return
+  end
+  checks_running = true
+
+  local now = ngx.now()
+  local queue = { checks = {}, next = 1 }
+  for backend_name, config in pairs(backends) do
+    if now - config.checked_at >= config.interval then
+      config.checked_at = now
+      for _, endpoint in ipairs(config.endpoints) do
+        table_insert(queue.checks, { backend_name = backend_name, config = config, endpoint = endpoint })
+      end
+    end
+  end
+
+  local threads = {}
+  for _ = 1, math.min(#queue.checks, MAX_CONCURRENT_CHECKS) do
+    local thread, err = ngx.thread.spawn(check_queue, queue)
+    if thread then
+      table_insert(threads, thread)
+    else
+      ngx.log(ngx.ERR, "failed to spawn health check thread: ", err)
+    end
+  end
+
+  for _, thread in ipairs(threads) do
+    ngx.thread.wait(thread)
+  end
+
+  checks_running = false
+end
+
+local function run_checks(premature)
+  if premature then
+    return
+  end
+
+  local ok, err = pcall(_M.run)
+  if not ok then
+    checks_running = false
+    ngx.log(ngx.ERR, "failed to run health checks: ", err)
+  end
+end
+
+function _M.init_worker()
+  -- the results are shared through the dictionary, a single worker checks
+  -- the endpoints
+  if ngx.worker.id() ~= 0 then
+    return
+  end
+
+  local ok, err = ngx.timer.every(CHECK_INTERVAL, run_checks)
+  if not ok then
+    ngx.log(ngx.ERR, "error when setting up timer.every for health checks: ", err)
+  end
+end
+
+return _M
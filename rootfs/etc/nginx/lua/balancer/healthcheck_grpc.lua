@@ -0,0 +1,199 @@
+-- gRPC health checks.
+--
+-- Calls the Check method of the standard grpc.health.v1.Health service over
+-- cleartext HTTP/2 and expects the SERVING status. Only the part of HTTP/2
+-- the call needs is implemented: the request headers are sent as literals
+-- without compression and the status is read from the response message, the
+-- response headers are not decoded.
+--
+local bit = require("bit")
+
+local math_floor = math.floor
+local string_byte = string.byte
+local string_char = string.char
+local string_sub = string.sub
+local table_concat = table.concat
+local table_insert = table.insert
+
+local PREFACE = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+local CHECK_PATH = "/grpc.health.v1.Health/Check"
+
+local FRAME_DATA = 0x0
+local FRAME_HEADERS = 0x1
+local FRAME_RST_STREAM = 0x3
+local FRAME_SETTINGS = 0x4
+local FRAME_GOAWAY = 0x7
+
+local FLAG_ACK = 0x1
+local FLAG_END_STREAM = 0x1
+local FLAG_END_HEADERS = 0x4
+
+local STREAM_ID = 1
+
+-- grpc.health.v1.HealthCheckResponse.ServingStatus
+local SERVING = 1
+
+-- the response message is small, it has to be received after a few frames
+local MAX_FRAMES = 16
+
+local _M = {}
+
+local function uint32(value)
+  return string_char(bit.band(bit.rshift(value, 24), 0xff), bit.band(bit.rshift(value, 16), 0xff),
+                     bit.band(bit.rshift(value, 8), 0xff), bit.band(value, 0xff))
+end
+
+local function frame(frame_type, flags, stream_id, payload)
+  local length = #payload
+  return string_char(bit.band(bit.rshift(length, 16), 0xff), bit.band(bit.rshift(length, 8), 0xff),
+                     bit.band(length, 0xff), frame_type, flags) .. uint32(stream_id) .. payload
+end
+
+-- continuation bytes of the HPACK integers and protobuf varints
+local function continuation(value, bytes)
+  while value >= 128 do
+    table_insert(bytes, string_char(value % 128 + 128))
+    value = math_floor(value / 128)
+  end
+  table_insert(bytes, string_char(value))
+  return table_concat(bytes)
+end
+
+local function hpack_integer(value, prefix_bits)
+  local max = 2 ^ prefix_bits - 1
+  if value < max then
+    return string_char(value)
+  end
+  return continuation(value - max, { string_char(max) })
+end
+
+local function hpack_string(value)
+  return hpack_integer(#value, 7) .. value
+end
+
+-- literal header field without indexing, named by its index in the static table
+local function hpack_literal(name_index, value)
+  return hpack_integer(name_index, 4) .. hpack_string(value)
+end
+
+local function request_headers(authority)
+  return table_concat({
+    string_char(0x83), -- :method POST
+    string_char(0x86), -- :scheme http
+    hpack_literal(4, CHECK_PATH), -- :path
+    hpack_literal(1, authority), -- :authority
+    hpack_literal(31, "application/grpc"), -- content-type
+    hpack_literal(58, "ingress-nginx-healthcheck"), -- user-agent
+    string_char(0) .. hpack_string("te") .. hpack_string("trailers"),
+  })
+end
+
+-- request_message encodes a length-prefixed HealthCheckRequest
+local function request_message(service)
+  local message = ""
+  if service and service ~= "" then
+    message = string_char(0x0a) .. continuation(#service, {}) .. service
+  end
+  return string_char(0) .. uint32(#message) .. message
+end
+
+-- serving_status decodes the status of a HealthCheckResponse, its only field
+local function serving_status(message)
+  if string_byte(message, 1) ~= 0x08 then
+    return 0
+  end
+  return string_byte(message, 2) or 0
+end
+
+local function receive_frame(sock)
+  local header, err = sock:receive(9)
+  if not header then
+    return nil, err
+  end
+
+  local l1, l2, l3, frame_type, flags, s1, s2, s3, s4 = string_byte(header, 1, 9)
+  local length = l1 * 65536 + l2 * 256 + l3
+
+  local payload = ""
+  if length > 0 then
+    payload, err = sock:receive(length)
+    if not payload then
+      return nil, err
+    end
+  end
+
+  return {
+    type = frame_type,
+    flags = flags,
+    stream_id = bit.band(s1, 0x7f) * 16777216 + s2 * 65536 + s3 * 256 + s4,
+    payload = payload,
+  }
+end
+
+-- response_status returns the status of the length-prefixed response message,
+-- or nil when it was not fully received yet
+local function response_status(data)
+  if #data < 5 then
+    return nil
+  end
+
+  local _, m1, m2, m3, m4 = string_byte(data, 1, 5)
+  local length = m1 * 16777216 + m2 * 65536 + m3 * 256 + m4
+  if #data < 5 + length then
+    return nil
+  end
+
+  return serving_status(string_sub(data, 6, 5 + length))
+end
+
+-- check calls the health service on the connected socket
+function _M.check(sock, authority, service)
+  local request = table_concat({
+    PREFACE,
+    frame(FRAME_SETTINGS, 0, 0, ""),
+    frame(FRAME_HEADERS, FLAG_END_HEADERS, STREAM_ID, request_headers(authority)),
+    frame(FRAME_DATA, FLAG_END_STREAM, STREAM_ID, request_message(service)),
+  })
+
+  local bytes, err = sock:send(request)
+  if not bytes then
+    return false, err
+  end
+
+  local data = ""
+  for _ = 1, MAX_FRAMES do
+    local f
+    f, err = receive_frame(sock)
+    if not f then
+      return false, err
+    end
+
+    if f.type == FRAME_SETTINGS and bit.band(f.flags, FLAG_ACK) == 0 then
+      sock:send(frame(FRAME_SETTINGS, FLAG_ACK, 0, ""))
+    elseif f.type == FRAME_GOAWAY then
+      return false, "connection closed by the server"
+    elseif f.stream_id == STREAM_ID then
+      if f.type == FRAME_RST_STREAM then
+        return false, "stream reset by the server"
+      end
+
+      if f.type == FRAME_DATA then
+        data = data .. f.payload
+        local status = response_status(data)
+        if status then
+          if status ~= SERVING then
+            return false, "unexpected serving status " .. status
+          end
+          return true
+        end
+      elseif f.type == FRAME_HEADERS and bit.band(f.flags, FLAG_END_STREAM) ~= 0 then
+        -- trailers without a response message, e.g. an unknown service
+        return false, "call failed without a response"
+      end
+    end
+  end
+
+  return false, "no response received"
+end
+
+return _M
@@ -0,0 +1,167 @@
+-- Least connections load balancing.
+--
+-- The number of in-flight requests of every endpoint is tracked in a shared
+-- dictionary, so all the workers pick the endpoint with the lowest number of
+-- requests being processed. This works better than round robin for backends
+-- with highly variable request durations. The counters are kept by backend,
+-- an endpoint shared by several backends has a counter for each of them.
+--
+local slow_start = require("balancer.slow_start")
+local util = require("util")
+
+local ngx = ngx
+local math = math
+local ipairs = ipairs
+local tostring = tostring
+local setmetatable = setmetatable
+local string_format = string.format
+local table_insert = table.insert
+local ngx_log = ngx.log
+local INFO = ngx.INFO
+
+local _M = { name = "least_conn" }
+
+local function get_upstream_name(upstream)
+  return upstream.address .. ":" .. upstream.port
+end
+
+-- counter_key returns the key of the counter of an endpoint in a backend
+local function counter_key(backend_name, endpoint_string)
+  return backend_name .. "|" .. endpoint_string
+end
+
+local function in_flight(key)
+  return ngx.shared.balancer_least_conn:get(key) or 0
+end
+
+local function incr(key, value)
+  local new_value, err, forcible = ngx.shared.balancer_least_conn:incr(key, value, 0)
+  if not new_value then
+    ngx.log(ngx.WARN, "balancer_least_conn:incr failed " .. tostring(err))
+    return
+  end
+  if forcible then
+    ngx.log(ngx.WARN, "balancer_least_conn:incr valid items forcibly overwritten")
+  end
+
+  -- the counter of an endpoint is removed when it leaves the backend, requests
+  -- that were in flight at that time must not make it negative
+  if new_value < 0 then
+    ngx.shared.balancer_least_conn:set(key, 0)
+  end
+end
+
+-- pick returns the endpoint with the lowest number of in-flight requests.
+-- It starts from a random position to spread the ties between endpoints.
+-- The load of slow starting endpoints is scaled up so they receive a share of
+-- the requests proportional to their ramp.
+local function pick(self, peers)
+  local offset = math.random(#peers)
+  local endpoint, lowest
+
+  for i = 0, #peers - 1 do
+    local peer = peers[(offset + i) % #peers + 1]
+    local endpoint_string = get_upstream_name(peer)
+    local count = (in_flight(counter_key(self.backend_name, endpoint_string)) + 1) /
+      self.slow_start:factor(endpoint_string)
+    if not lowest or count < lowest then
+      endpoint, lowest = peer, count
+    end
+  end
+
+  return endpoint
+end
+
+function _M.is_affinitized()
+  return false
+end
+
+function _M.balance(self)
+  local peers = self.peers
+
+  local tried_endpoints = ngx.ctx.balancer_least_conn_tried_endpoints
+  if not tried_endpoints then
+    tried_endpoints = {}
+    ngx.ctx.balancer_least_conn_tried_endpoints = tried_endpoints
+  end
+
+  local filtered_peers
+  for _, peer in ipairs(peers) do
+    if not tried_endpoints[get_upstream_name(peer)] then
+      if not filtered_peers then
+        filtered_peers = {}
+      end
+      table_insert(filtered_peers, peer)
+    end
+  end
+
+  if not filtered_peers then
+    ngx.log(ngx.WARN, "all endpoints have been retried")
+    filtered_peers = util.deepcopy(peers)
+  end
+
+  local endpoint_string = get_upstream_name(pick(self, filtered_peers))
+  tried_endpoints[endpoint_string] = true
+
+  -- every endpoint picked by a try counts as in flight until the request ends
+  local key = counter_key(self.backend_name, endpoint_string)
+  incr(key, 1)
+  local picked_endpoints = ngx.ctx.balancer_least_conn_picked_endpoints
+  if not picked_endpoints then
+    picked_endpoints = {}
+    ngx.ctx.balancer_least_conn_picked_endpoints = picked_endpoints
+  end
+  table_insert(picked_endpoints, key)
+
+  return endpoint_string
+end
+
+function _M.after_balance(_)
+  local picked_endpoints = ngx.ctx.balancer_least_conn_picked_endpoints
+  if not picked_endpoints then
+    return
+  end
+
+  for _, key in ipairs(picked_endpoints) do
+    incr(key, -1)
+  end
+
+  ngx.ctx.balancer_least_conn_picked_endpoints = nil
+end
+
+function _M.sync(self, backend)
+  self.traffic_shaping_policy = backend.trafficShapingPolicy
+  self.alternative_backends = backend.alternativeBackends
+  self.slow_start:sync(backend, self.peers)
+
+  local normalized_endpoints_added, normalized_endpoints_removed =
+    util.diff_endpoints(self.peers, backend.endpoints)
+
+  if #normalized_endpoints_added == 0 and #normalized_endpoints_removed == 0 then
+    ngx.log(ngx.INFO, "endpoints did not change for backend " .. tostring(backend.name))
+    return
+  end
+
+  ngx_log(INFO, string_format("[%s] peers have changed for backend %s", self.name, backend.name))
+
+  self.peers = backend.endpoints
+
+  for _, endpoint_string in ipairs(normalized_endpoints_removed) do
+    ngx.shared.balancer_least_conn:delete(counter_key(self.backend_name, endpoint_string))
+  end
+end
+
+function _M.new(self, backend)
+  local o = {
+    backend_name = backend.name,
+    peers = backend.endpoints,
+    slow_start = slow_start:new(backend),
+    traffic_shaping_policy = backend.trafficShapingPolicy,
+    alternative_backends = backend.alternativeBackends,
+  }
+  setmetatable(o, self)
+  self.__index = self
+  return o
+end
+
+return _M
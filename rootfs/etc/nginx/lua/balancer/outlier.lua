@@ -0,0 +1,164 @@
+-- Passive outlier detection.
+--
+-- The outcome of every try to an upstream endpoint is recorded in a shared
+-- dictionary. After outlierDetection.consecutiveErrors consecutive 5xx
+-- responses or connection failures the endpoint is ejected from the load
+-- balancing rotation. The ejection time doubles every time the same endpoint
+-- is ejected again, up to outlierDetection.maxEjectionTime.
+--
+local split = require("util.split")
+local util = require("util")
+
+local ngx = ngx
+local math = math
+local pairs = pairs
+local ipairs = ipairs
+local tonumber = tonumber
+local tostring = tostring
+local table = table
+local string_format = string.format
+
+local FAILURES_PREFIX = "failures:"
+local EJECTED_PREFIX = "ejected:"
+local EJECTIONS_PREFIX = "ejections:"
+
+local _M = {}
+
+-- outlier detection configuration and endpoints, indexed by backend name
+local backends = {}
+
+local function dict()
+  return ngx.shared.balancer_outlier
+end
+
+function _M.sync(backend)
+  local config = backend.outlierDetection
+  if not config or not config.consecutiveErrors or config.consecutiveErrors <= 0 then
+    backends[backend.name] = nil
+    return
+  end
+
+  local endpoints = util.get_nodes(backend.endpoints)
+  local endpoints_count = 0
+  for _, _ in pairs(endpoints) do
+    endpoints_count = endpoints_count + 1
+  end
+
+  backends[backend.name] = {
+    consecutive_errors = config.consecutiveErrors,
+    base_ejection_time = tonumber(config.baseEjectionTime) or 30,
+    max_ejection_time = tonumber(config.maxEjectionTime) or 300,
+    max_ejection_percent = tonumber(config.maxEjectionPercent) or 50,
+    endpoints = endpoints,
+    endpoints_count = endpoints_count,
+  }
+end
+
+function _M.remove(backend_name)
+  backends[backend_name] = nil
+end
+
+-- max_tries returns the number of times a balancer of the backend should be
+-- asked for another endpoint when the returned one is ejected
+function _M.max_tries(backend_name)
+  local config = backends[backend_name]
+  if not config then
+    return 0
+  end
+
+  return config.endpoints_count
+end
+
+function _M.is_ejected(endpoint_string)
+  local ejected_until = dict():get(EJECTED_PREFIX .. endpoint_string)
+  return ejected_until ~= nil and ejected_until > ngx.now()
+end
+
+-- get_ejections returns the endpoints of the backends currently ejected,
+-- with the time they are ejected until
+function _M.get_ejections()
+  local ejections = {}
+  for backend_name, config in pairs(backends) do
+    for endpoint_string, _ in pairs(config.endpoints) do
+      local ejected_until = dict():get(EJECTED_PREFIX .. endpoint_string)
+      if ejected_until ~= nil and ejected_until > ngx.now() then
+        table.insert(ejections, {
+          backend = backend_name,
+          endpoint = endpoint_string,
+          ejectedUntil = ejected_until,
+        })
+      end
+    end
+  end
+
+  return ejections
+end
+
+local function ejected_count(config)
+  local count = 0
+  for endpoint_string, _ in pairs(config.endpoints) do
+    if _M.is_ejected(endpoint_string) then
+      count = count + 1
+    end
+  end
+
+  return count
+end
+
+local function eject(backend_name, config, endpoint_string)
+  if (ejected_count(config) + 1) * 100 > config.max_ejection_percent * config.endpoints_count then
+    ngx.log(ngx.INFO, string_format("not ejecting endpoint %s of backend %s, the maximum " ..
+                                    "percentage of ejected endpoints was reached",
+                                    endpoint_string, backend_name))
+    return
+  end
+
+  local ejections = (dict():get(EJECTIONS_PREFIX .. endpoint_string) or 0) + 1
+  local ejection_time = math.min(config.base_ejection_time * 2 ^ (ejections - 1),
+                                 config.max_ejection_time)
+
+  local ok, err = dict():set(EJECTED_PREFIX .. endpoint_string, ngx.now() + ejection_time,
+                             ejection_time)
+  if not ok then
+    ngx.log(ngx.ERR, "balancer_outlier:set failed " .. tostring(err))
+    return
+  end
+
+  -- the number of ejections is forgotten once the endpoint stays healthy for
+  -- the maximum ejection time after being readmitted
+  dict():set(EJECTIONS_PREFIX .. endpoint_string, ejections,
+             ejection_time + config.max_ejection_time)
+  dict():delete(FAILURES_PREFIX .. endpoint_string)
+
+  ngx.log(ngx.WARN, string_format("ejecting endpoint %s of backend %s for %ss after %s " ..
+                                  "consecutive failures", endpoint_string, backend_name,
+                                  ejection_time, config.consecutive_errors))
+end
+
+-- after_balance records the outcome of every try of the request
+function _M.after_balance(backend_name)
+  local config = backends[backend_name]
+  if not config then
+    return
+  end
+
+  local addrs = split.split_upstream_var(ngx.var.upstream_addr) or {}
+  local statuses = split.split_upstream_var(ngx.var.upstream_status) or {}
+
+  for i, endpoint_string in ipairs(addrs) do
+    if config.endpoints[endpoint_string] then
+      local status = tonumber(statuses[i])
+      if not status or status >= 500 then
+        local failures = dict():incr(FAILURES_PREFIX .. endpoint_string, 1, 0)
+        if failures and failures >= config.consecutive_errors
+           and not _M.is_ejected(endpoint_string) then
+          eject(backend_name, config, endpoint_string)
+        end
+      else
+        dict():delete(FAILURES_PREFIX .. endpoint_string)
+      end
+    end
+  end
+end
+
+return _M
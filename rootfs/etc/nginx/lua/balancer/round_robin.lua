@@ -1,15 +1,45 @@
 local balancer_resty = require("balancer.resty")
 local resty_roundrobin = require("resty.roundrobin")
+local slow_start = require("balancer.slow_start")
 local util = require("util")
 
+local ngx = ngx
+local math = math
+local pairs = pairs
+local string_format = string.format
+local ngx_log = ngx.log
+local INFO = ngx.INFO
 local setmetatable = setmetatable
 
+-- weight of the endpoints receiving the full load while other endpoints of
+-- the backend are slow starting
+local FULL_WEIGHT = 100
+-- measured in seconds
+local SLOW_START_UPDATE_INTERVAL = 1
+
 local _M = balancer_resty:new({ factory = resty_roundrobin, name = "round_robin" })
 
+local function get_weighted_nodes(self)
+  local nodes = util.get_nodes(self.endpoints)
+  if not self.slow_start:is_ramping() then
+    return nodes
+  end
+
+  for endpoint_string, weight in pairs(nodes) do
+    nodes[endpoint_string] =
+      math.max(1, math.floor(weight * FULL_WEIGHT * self.slow_start:factor(endpoint_string)))
+  end
+
+  return nodes
+end
+
 function _M.new(self, backend)
   local nodes = util.get_nodes(backend.endpoints)
   local o = {
     instance = self.factory:new(nodes),
+    endpoints = backend.endpoints,
+    slow_start = slow_start:new(backend),
+    slow_start_updated_at = 0,
     traffic_shaping_policy = backend.trafficShapingPolicy,
     alternative_backends = backend.alternativeBackends,
   }
@@ -18,7 +48,34 @@ function _M.new(self, backend)
   return o
 end
 
+function _M.sync(self, backend)
+  self.traffic_shaping_policy = backend.trafficShapingPolicy
+  self.alternative_backends = backend.alternativeBackends
+
+  self.slow_start:sync(backend, self.endpoints)
+  self.endpoints = backend.endpoints
+
+  local nodes = get_weighted_nodes(self)
+  local changed = not util.deep_compare(self.instance.nodes, nodes)
+  if not changed then
+    return
+  end
+
+  ngx_log(INFO, string_format("[%s] nodes have changed for backend %s", self.name, backend.name))
+
+  self.slow_start_updated_at = ngx.now()
+  self.instance:reinit(nodes)
+end
+
 function _M.balance(self)
+  if self.slow_start:is_ramping() then
+    local now = ngx.now()
+    if now - self.slow_start_updated_at >= SLOW_START_UPDATE_INTERVAL then
+      self.slow_start_updated_at = now
+      self.instance:reinit(get_weighted_nodes(self))
+    end
+  end
+
   return self.instance:find()
 end
 
@@ -0,0 +1,76 @@
+-- Slow start for newly added endpoints.
+--
+-- When endpoints are added to a backend with slowStartSeconds set, the share
+-- of the full load they receive grows linearly over the window instead of
+-- sending them full load immediately, which causes error spikes on scale-up
+-- for services that need to warm up (e.g. JIT compilation, caches).
+--
+local util = require("util")
+
+local ngx = ngx
+local math = math
+local next = next
+local ipairs = ipairs
+local tonumber = tonumber
+local setmetatable = setmetatable
+
+-- endpoints receive at least this share of the full load when they are added
+-- to avoid starving them of the traffic needed to warm up
+local MIN_FACTOR = 0.1
+
+local _M = {}
+
+function _M.new(self, backend)
+  local o = {
+    window = tonumber(backend.slowStartSeconds) or 0,
+    added_at = {},
+  }
+  setmetatable(o, self)
+  self.__index = self
+  return o
+end
+
+-- sync records the time at which endpoints were added to the backend. It must
+-- be called with the endpoints known before the backend was updated.
+function _M.sync(self, backend, old_endpoints)
+  self.window = tonumber(backend.slowStartSeconds) or 0
+
+  local endpoints_added, endpoints_removed = util.diff_endpoints(old_endpoints, backend.endpoints)
+
+  for _, endpoint_string in ipairs(endpoints_removed) do
+    self.added_at[endpoint_string] = nil
+  end
+
+  if self.window <= 0 then
+    self.added_at = {}
+    return
+  end
+
+  local now = ngx.now()
+  for _, endpoint_string in ipairs(endpoints_added) do
+    self.added_at[endpoint_string] = now
+  end
+end
+
+function _M.is_ramping(self)
+  return next(self.added_at) ~= nil
+end
+
+-- factor returns the share of the full load the endpoint can receive, between
+-- MIN_FACTOR and 1
+function _M.factor(self, endpoint_string)
+  local added_at = self.added_at[endpoint_string]
+  if not added_at then
+    return 1
+  end
+
+  local elapsed = ngx.now() - added_at
+  if elapsed >= self.window then
+    self.added_at[endpoint_string] = nil
+    return 1
+  end
+
+  return math.max(elapsed / self.window, MIN_FACTOR)
+end
+
+return _M
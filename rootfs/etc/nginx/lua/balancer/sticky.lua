@@ -3,6 +3,8 @@ local ck = require("resty.cookie")
 local ngx_balancer = require("ngx.balancer")
 local split = require("util.split")
 local same_site = require("util.same_site")
+local sticky_store = require("balancer.sticky_store")
+local util = require("util")
 
 local ngx = ngx
 local pairs = pairs
@@ -24,7 +26,8 @@ function _M.new(self)
     alternative_backends = nil,
     cookie_session_affinity = nil,
     traffic_shaping_policy = nil,
-    backend_key = nil
+    backend_key = nil,
+    endpoints = nil
   }
 
   setmetatable(o, self)
@@ -170,12 +173,33 @@ local function should_set_cookie(self)
   return false
 end
 
+-- rewrite looks the endpoint of the session up in the store, it can't be
+-- queried from the balancer phase
+function _M.rewrite(self)
+  local key = self:get_cookie()
+  if key then
+    ngx.ctx.balancer_sticky_stored_upstream = sticky_store.get(self.backend_key, key)
+  end
+end
+
+-- get_stored_upstream returns the stored endpoint of the session if it is
+-- still an endpoint of the backend
+local function get_stored_upstream(self)
+  local upstream = ngx.ctx.balancer_sticky_stored_upstream
+  if upstream and self.endpoints and self.endpoints[upstream] then
+    return upstream
+  end
+
+  return nil
+end
+
 function _M.balance(self)
-  local upstream_from_cookie
+  local upstream_from_cookie, stored_upstream
 
   local key = self:get_cookie()
   if key then
-    upstream_from_cookie = self.instance:find(key)
+    stored_upstream = get_stored_upstream(self)
+    upstream_from_cookie = stored_upstream or self.instance:find(key)
   end
 
   local last_failure = self.get_last_failure()
@@ -183,6 +207,9 @@ function _M.balance(self)
     self.cookie_session_affinity.change_on_failure or upstream_from_cookie == nil
 
   if not should_pick_new_upstream then
+    if not stored_upstream then
+      sticky_store.set(self.backend_key, key, upstream_from_cookie)
+    end
     return upstream_from_cookie
   end
 
@@ -193,6 +220,7 @@ function _M.balance(self)
     ngx.log(ngx.WARN, string.format("failed to get new upstream; using upstream %s", new_upstream))
   elseif should_set_cookie(self) then
     self:set_cookie(key)
+    sticky_store.set(self.backend_key, key, new_upstream)
   end
 
   return new_upstream
@@ -206,6 +234,7 @@ function _M.sync(self, backend)
   self.alternative_backends = backend.alternativeBackends
   self.cookie_session_affinity = backend.sessionAffinityConfig.cookieSessionAffinity
   self.backend_key = ngx.md5(ngx.md5(backend.name) .. backend.name)
+  self.endpoints = util.get_nodes(backend.endpoints)
 end
 
 return _M
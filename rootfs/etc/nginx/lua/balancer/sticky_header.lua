@@ -0,0 +1,52 @@
+-- An affinity mode which routes the requests with the same value of a header to
+-- the same endpoint. It is meant for clients that don't honor cookies, e.g.
+-- mobile or API clients sending a device ID or an API key.
+-- Like the balanced affinity mode, some sessions are redistributed when a
+-- deployment gets scaled. Requests without the header are not sticky.
+--
+local balancer_resty = require("balancer.resty")
+local resty_chash = require("resty.chash")
+local util = require("util")
+
+local ngx = ngx
+local math_random = math.random
+local string_lower = string.lower
+local tostring = tostring
+local setmetatable = setmetatable
+
+local _M = balancer_resty:new({ factory = resty_chash, name = "sticky_header" })
+
+local function header_variable(backend)
+  local header_name = backend.sessionAffinityConfig.headerSessionAffinity.name
+  return "http_" .. util.replace_special_char(string_lower(header_name), "-", "_")
+end
+
+function _M.new(self, backend)
+  local nodes = util.get_nodes(backend.endpoints)
+
+  local o = {
+    instance = self.factory:new(nodes),
+    header_variable = header_variable(backend),
+    traffic_shaping_policy = backend.trafficShapingPolicy,
+    alternative_backends = backend.alternativeBackends,
+  }
+  setmetatable(o, self)
+  self.__index = self
+  return o
+end
+
+function _M.balance(self)
+  local key = ngx.var[self.header_variable]
+  if not key or key == "" then
+    key = tostring(math_random())
+  end
+
+  return self.instance:find(key)
+end
+
+function _M.sync(self, backend)
+  self.header_variable = header_variable(backend)
+  balancer_resty.sync(self, backend)
+end
+
+return _M
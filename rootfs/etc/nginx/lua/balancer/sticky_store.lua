@@ -0,0 +1,151 @@
+-- Redis store of the endpoints of the sticky sessions.
+--
+-- The cookie of a sticky session only carries the key hashed to pick its
+-- endpoint, so the session moves to another endpoint when the endpoints of the
+-- backend change. When the session-affinity-redis-host ConfigMap setting is
+-- set, the endpoint picked for a session is also stored in redis and the
+-- session keeps being routed to it, by every controller replica, for as long
+-- as it is an endpoint of the backend.
+--
+-- The store is read in the rewrite phase, cosockets are not available in the
+-- balancer phase, and written from a timer. When redis can't be reached the
+-- sessions fall back to the cookie only.
+--
+local redis = require("resty.redis")
+local configuration = require("configuration")
+
+local ngx = ngx
+local tostring = tostring
+local tonumber = tonumber
+
+-- measured in seconds
+local RETRY_INTERVAL = 5
+local KEY_PREFIX = "ingress-nginx:affinity:"
+
+local _M = {}
+
+-- worker local, redis is not queried again before this time once it failed
+local unavailable_until = 0
+
+local function config()
+  return configuration.session_affinity_redis
+end
+
+function _M.is_enabled()
+  local cfg = config()
+  return cfg ~= nil and cfg.host ~= nil and cfg.host ~= "" and
+    (tonumber(cfg.port) or 0) > 0 and ngx.now() >= unavailable_until
+end
+
+local function mark_unavailable(err)
+  unavailable_until = ngx.now() + RETRY_INTERVAL
+  ngx.log(ngx.WARN, "session affinity store is unavailable, falling back to the cookie: ",
+          tostring(err))
+end
+
+local function connect()
+  local cfg = config()
+
+  local red, err = redis:new()
+  if not red then
+    return nil, err
+  end
+  red:set_timeout(cfg.connect_timeout)
+
+  local ok
+  ok, err = red:connect(cfg.host, cfg.port, { pool_size = cfg.pool_size })
+  if not ok then
+    return nil, err
+  end
+
+  return red
+end
+
+-- close drops a connection which failed, it can't be reused by the pool
+local function close(red)
+  local ok, err = red:close()
+  if not ok then
+    ngx.log(ngx.WARN, "failed to close the session affinity store connection: ", err)
+  end
+end
+
+local function release(red)
+  local cfg = config()
+  local ok, err = red:set_keepalive(cfg.max_idle_timeout, cfg.pool_size)
+  if not ok then
+    ngx.log(ngx.WARN, "failed to keep the session affinity store connection alive: ", err)
+  end
+end
+
+local function get_key(backend_key, session_key)
+  return KEY_PREFIX .. backend_key .. ":" .. session_key
+end
+
+-- get returns the endpoint stored for the session and extends its TTL. It
+-- returns nil when there is none or the store can't be reached.
+function _M.get(backend_key, session_key)
+  if not _M.is_enabled() then
+    return nil
+  end
+
+  local red, err = connect()
+  if not red then
+    mark_unavailable(err)
+    return nil
+  end
+
+  local key = get_key(backend_key, session_key)
+  red:init_pipeline()
+  red:get(key)
+  red:expire(key, config().ttl)
+  local results
+  results, err = red:commit_pipeline()
+  if not results then
+    close(red)
+    mark_unavailable(err)
+    return nil
+  end
+  release(red)
+
+  local endpoint = results[1]
+  if not endpoint or endpoint == ngx.null then
+    return nil
+  end
+
+  return endpoint
+end
+
+local function store(premature, key, endpoint)
+  if premature then
+    return
+  end
+
+  local red, err = connect()
+  if not red then
+    mark_unavailable(err)
+    return
+  end
+
+  local ok
+  ok, err = red:set(key, endpoint, "EX", config().ttl)
+  if not ok then
+    close(red)
+    mark_unavailable(err)
+    return
+  end
+  release(red)
+end
+
+-- set stores the endpoint of the session in the background
+function _M.set(backend_key, session_key, endpoint)
+  if not _M.is_enabled() then
+    return
+  end
+
+  local ok, err = ngx.timer.at(0, store, get_key(backend_key, session_key), endpoint)
+  if not ok then
+    ngx.log(ngx.ERR, "failed to create timer to store the session endpoint: ", err)
+  end
+end
+
+return _M
@@ -0,0 +1,267 @@
+-- Bot detection and challenge.
+--
+-- The requests of the locations with bot detection are scored with heuristics
+-- on their headers, the reputation of the address of the client and the rate
+-- of its requests to the Ingress. The clients scored from the challenge score
+-- are served a cookie or JavaScript challenge, and are proxied once they send
+-- back its signed cookie. The requests scored from the block score are denied,
+-- whether the client passed the challenge or not.
+--
+local ck = require("resty.cookie")
+local resty_ipmatcher = require("resty.ipmatcher")
+local configuration = require("configuration")
+
+local ngx = ngx
+local ipairs = ipairs
+local tonumber = tonumber
+local tostring = tostring
+local string_find = string.find
+local string_format = string.format
+local string_lower = string.lower
+local string_reverse = string.reverse
+
+local COOKIE_NAME = "ingress_bot_challenge"
+local COOKIE_MAX_AGE = 3600 -- seconds
+local RATE_WINDOW = 60 -- seconds
+
+-- a request is scored with the sum of the scores of its signals
+local SCORE_EMPTY_USER_AGENT = 60
+local SCORE_AUTOMATION_USER_AGENT = 40
+local SCORE_MISSING_ACCEPT = 10
+local SCORE_MISSING_ACCEPT_LANGUAGE = 10
+local SCORE_BAD_REPUTATION = 100
+local SCORE_RATE_EXCEEDED = 50
+
+-- lowercase substrings of the User-Agent of HTTP libraries and automation
+-- tools, the browsers never send them
+local AUTOMATION_USER_AGENTS = {
+  "curl/", "wget/", "python-requests", "python-urllib", "aiohttp", "go-http-client",
+  "java/", "okhttp", "libwww-perl", "scrapy", "httpclient", "headlesschrome",
+  "phantomjs", "selenium",
+}
+
+local JAVASCRIPT_CHALLENGE = [[<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Checking your browser</title></head>
+<body>
+<noscript>JavaScript is required to access this page.</noscript>
+<script>
+document.cookie = "%s=" + "%s".split("").reverse().join("") + "; max-age=%d; path=/; SameSite=Lax%s";
+window.location.reload();
+</script>
+</body>
+</html>
+]]
+
+local _M = {}
+
+-- the matcher of the reputation CIDRs, built again when they change
+local reputation_cidrs
+local reputation_matcher
+
+local function dict()
+  return ngx.shared.bot_detection
+end
+
+-- signing_key returns the key of the bot-detection-secret Secret. A client
+-- passes the challenge on one replica and sends its cookie to any other one
+-- behind the load balancer, and after the reloads: a key of the replica or of
+-- the configuration would challenge it again, looping on the JavaScript
+-- challenge, so the challenges are only served with the shared key.
+local function signing_key()
+  return configuration.get_secret("bot_detection_secret")
+end
+
+local function is_automation_user_agent(user_agent)
+  local lower = string_lower(user_agent)
+  for _, pattern in ipairs(AUTOMATION_USER_AGENTS) do
+    if string_find(lower, pattern, 1, true) then
+      return true
+    end
+  end
+
+  return false
+end
+
+local function has_bad_reputation(cidrs, address)
+  if not cidrs or #cidrs == 0 then
+    return false
+  end
+
+  if cidrs ~= reputation_cidrs then
+    local matcher, err = resty_ipmatcher.new(cidrs)
+    if not matcher then
+      ngx.log(ngx.ERR, "failed to initialize resty-ipmatcher: ", err)
+      return false
+    end
+    reputation_cidrs = cidrs
+    reputation_matcher = matcher
+  end
+
+  local matched, err = reputation_matcher:match(address)
+  if err then
+    ngx.log(ngx.ERR, "failed to match ip: '", address, "': ", err)
+    return false
+  end
+
+  return matched
+end
+
+-- rate_exceeded counts the requests of the client to the Ingress in a fixed
+-- window of a minute
+local function rate_exceeded(rate_limit, address)
+  if not rate_limit or rate_limit <= 0 then
+    return false
+  end
+
+  local key = ngx.var.namespace .. "/" .. ngx.var.ingress_name .. "/" .. address
+  local requests, err = dict():incr(key, 1, 0, RATE_WINDOW)
+  if not requests then
+    ngx.log(ngx.ERR, "bot_detection:incr failed " .. tostring(err))
+    return false
+  end
+
+  return requests > rate_limit
+end
+
+-- score returns the bot score of the request
+function _M.score(config, location_config)
+  local score = 0
+  local address = ngx.var.remote_addr
+
+  local user_agent = ngx.var.http_user_agent
+  if not user_agent or user_agent == "" then
+    score = score + SCORE_EMPTY_USER_AGENT
+  elseif is_automation_user_agent(user_agent) then
+    score = score + SCORE_AUTOMATION_USER_AGENT
+  end
+
+  if not ngx.var.http_accept then
+    score = score + SCORE_MISSING_ACCEPT
+  end
+  if not ngx.var.http_accept_language then
+    score = score + SCORE_MISSING_ACCEPT_LANGUAGE
+  end
+
+  if config and has_bad_reputation(config.reputation_cidrs, address) then
+    score = score + SCORE_BAD_REPUTATION
+  end
+
+  if rate_exceeded(location_config.rate_limit, address) then
+    score = score + SCORE_RATE_EXCEEDED
+  end
+
+  return score
+end
+
+-- the challenge cookie is bound to the address and the User-Agent of the
+-- client, it cannot be shared by the clients of a bot
+local function sign(secret, expires)
+  local payload = string_format("%s|%s|%s", expires, ngx.var.remote_addr, ngx.var.http_user_agent or "")
+  return ngx.encode_base64(ngx.hmac_sha1(secret, payload), true)
+end
+
+local function challenge_value(secret)
+  local expires = ngx.time() + COOKIE_MAX_AGE
+  return expires .. "|" .. sign(secret, expires)
+end
+
+-- passed_challenge returns true when the request carries a valid challenge
+-- cookie
+function _M.passed_challenge(secret)
+  local cookie, err = ck:new()
+  if not cookie then
+    ngx.log(ngx.ERR, err)
+    return false
+  end
+
+  local raw_value = cookie:get(COOKIE_NAME)
+  if not raw_value then
+    return false
+  end
+
+  local expires, signature = raw_value:match("^(%d+)|(.+)$")
+  if not expires or tonumber(expires) < ngx.time() then
+    return false
+  end
+
+  return signature == sign(secret, expires)
+end
+
+local function serve_cookie_challenge(secret)
+  local cookie, err = ck:new()
+  if not cookie then
+    ngx.log(ngx.ERR, err)
+    return
+  end
+
+  local ok
+  ok, err = cookie:set({
+    key = COOKIE_NAME,
+    value = challenge_value(secret),
+    path = "/",
+    httponly = true,
+    samesite = "Lax",
+    secure = ngx.var.https == "on",
+    max_age = COOKIE_MAX_AGE,
+  })
+  if not ok then
+    ngx.log(ngx.ERR, err)
+    return
+  end
+
+  ngx.header["Cache-Control"] = "no-store"
+  -- 307 keeps the method and the body of the request
+  return ngx.redirect(ngx.var.request_uri, ngx.HTTP_TEMPORARY_REDIRECT)
+end
+
+local function serve_javascript_challenge(secret)
+  local secure = ""
+  if ngx.var.https == "on" then
+    secure = "; Secure"
+  end
+
+  ngx.status = ngx.HTTP_FORBIDDEN
+  ngx.header["Content-Type"] = "text/html"
+  ngx.header["Cache-Control"] = "no-store"
+  -- the value is reversed so that the clients must run the script
+  ngx.print(string_format(JAVASCRIPT_CHALLENGE, COOKIE_NAME,
+    string_reverse(challenge_value(secret)), COOKIE_MAX_AGE, secure))
+
+  return ngx.exit(ngx.HTTP_FORBIDDEN)
+end
+
+function _M.rewrite(config, location_config)
+  if not location_config or not location_config.enabled then
+    return
+  end
+
+  local score = _M.score(config, location_config)
+
+  if location_config.block_score > 0 and score >= location_config.block_score then
+    ngx.log(ngx.INFO, "blocking request of ", ngx.var.remote_addr, " with bot score ", score)
+    return ngx.exit(ngx.HTTP_FORBIDDEN)
+  end
+
+  if location_config.challenge == "none" or score < location_config.challenge_score then
+    return
+  end
+
+  local secret = signing_key()
+  if not secret then
+    ngx.log(ngx.WARN, "bot-detection-secret is not configured, the bot detection challenges are disabled")
+    return
+  end
+
+  if _M.passed_challenge(secret) then
+    return
+  end
+
+  if location_config.challenge == "javascript" then
+    return serve_javascript_challenge(secret)
+  end
+
+  return serve_cookie_challenge(secret)
+end
+
+return _M
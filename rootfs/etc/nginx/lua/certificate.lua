@@ -1,6 +1,9 @@
 local http = require("resty.http")
+local lrucache = require("resty.lrucache")
+local keyless = require("keyless")
 local ssl = require("ngx.ssl")
 local ocsp = require("ngx.ocsp")
+local ocsp_status = require("ocsp_status")
 local ngx = ngx
 local string = string
 local tostring = tostring
@@ -10,15 +13,34 @@ local unpack = unpack
 local dns_lookup = require("util.dns").lookup
 
 local _M = {
-  is_ocsp_stapling_enabled = false
+  is_ocsp_stapling_enabled = false,
+  -- OpenSSL engine loading the private keys kept by the external key provider
+  ssl_key_engine = "",
 }
 
 local DEFAULT_CERT_HOSTNAME = "_"
+-- prefix of the keys of the ECDSA certificates in certificate_data, see
+-- configuration.lua
+local ECDSA_PREFIX = "ecdsa:"
+-- prefix of the keys of the identifiers of the private keys kept by the
+-- external key provider in certificate_data
+local KEY_ID_PREFIX = "keyid:"
 
 local certificate_data = ngx.shared.certificate_data
 local certificate_servers = ngx.shared.certificate_servers
 local ocsp_response_cache = ngx.shared.ocsp_response_cache
 
+-- the ECDSA certificates and keys parsed by the worker, by certificate UID
+local ECDSA_CACHE_SIZE = 1000
+local ecdsa_cache
+do
+  local err
+  ecdsa_cache, err = lrucache.new(ECDSA_CACHE_SIZE)
+  if not ecdsa_cache then
+    return error("failed to create the cache of the ECDSA certificates: " .. (err or "unknown"))
+  end
+end
+
 local function get_der_cert_and_priv_key(pem_cert_key)
   local der_cert, der_cert_err = ssl.cert_pem_to_der(pem_cert_key)
   if not der_cert then
@@ -33,6 +55,42 @@ local function get_der_cert_and_priv_key(pem_cert_key)
   return der_cert, der_priv_key, nil
 end
 
+-- get_ecdsa_cert_and_priv_key returns the ECDSA certificate chain and private
+-- key, parsed once per worker. A new PEM of the certificate replaces the
+-- cached ones.
+local function get_ecdsa_cert_and_priv_key(uid, pem_cert_key)
+  local cached = ecdsa_cache:get(uid)
+  if cached and cached.pem_cert_key == pem_cert_key then
+    return cached.cert, cached.priv_key, nil
+  end
+
+  local cert, cert_err = ssl.parse_pem_cert(pem_cert_key)
+  if not cert then
+    return nil, nil, "failed to parse ECDSA certificate chain: " .. cert_err
+  end
+
+  local priv_key, priv_key_err = ssl.parse_pem_priv_key(pem_cert_key)
+  if not priv_key then
+    return nil, nil, "failed to parse ECDSA private key: " .. priv_key_err
+  end
+
+  ecdsa_cache:set(uid, { pem_cert_key = pem_cert_key, cert = cert, priv_key = priv_key })
+
+  return cert, priv_key, nil
+end
+
+local function set_cert_and_key(cert, priv_key)
+  local set_cert_ok, set_cert_err = ssl.set_cert(cert)
+  if not set_cert_ok then
+    return "failed to set cert: " .. set_cert_err
+  end
+
+  local set_priv_key_ok, set_priv_key_err = ssl.set_priv_key(priv_key)
+  if not set_priv_key_ok then
+    return "failed to set private key: " .. set_priv_key_err
+  end
+end
+
 local function set_der_cert_and_key(der_cert, der_priv_key)
   local set_cert_ok, set_cert_err = ssl.set_der_cert(der_cert)
   if not set_cert_ok then
@@ -45,6 +103,23 @@ local function set_der_cert_and_key(der_cert, der_priv_key)
   end
 end
 
+local function set_der_cert_and_engine_key(der_cert, key_id)
+  local set_cert_ok, set_cert_err = ssl.set_der_cert(der_cert)
+  if not set_cert_ok then
+    return "failed to set DER cert: " .. set_cert_err
+  end
+
+  local priv_key, err = keyless.priv_key(_M.ssl_key_engine, key_id)
+  if not priv_key then
+    return err
+  end
+
+  local set_priv_key_ok, set_priv_key_err = ssl.set_priv_key(priv_key)
+  if not set_priv_key_ok then
+    return "failed to set private key: " .. set_priv_key_err
+  end
+end
+
 local function get_pem_cert_uid(raw_hostname)
   -- Convert hostname to ASCII lowercase (see RFC 6125 6.4.1) so that requests with uppercase
   -- host would lead to the right certificate being chosen (controller serves certificates for
@@ -123,6 +198,7 @@ local function fetch_and_cache_ocsp_response(uid, der_cert)
   local url, err = ocsp.get_ocsp_responder_from_der_chain(der_cert)
   if not url and err then
     ngx.log(ngx.ERR, "could not extract OCSP responder URL: ", err)
+    ocsp_status.failure(uid, "could not extract OCSP responder URL: " .. err)
     return
   end
   if not url and not err then
@@ -134,6 +210,7 @@ local function fetch_and_cache_ocsp_response(uid, der_cert)
   request, err = ocsp.create_ocsp_request(der_cert)
   if not request then
     ngx.log(ngx.ERR, "could not create OCSP request: ", err)
+    ocsp_status.failure(uid, "could not create OCSP request: " .. err)
     return
   end
 
@@ -141,10 +218,12 @@ local function fetch_and_cache_ocsp_response(uid, der_cert)
   ocsp_response, err = do_ocsp_request(url, request)
   if err then
     ngx.log(ngx.ERR, "could not get OCSP response: ", err)
+    ocsp_status.failure(uid, "could not get OCSP response: " .. err)
     return
   end
   if not ocsp_response or #ocsp_response == 0 then
     ngx.log(ngx.ERR, "OCSP responder returned an empty response")
+    ocsp_status.failure(uid, "OCSP responder returned an empty response")
     return
   end
 
@@ -174,12 +253,17 @@ local function fetch_and_cache_ocsp_response(uid, der_cert)
     -- and we keep sending request. It might make things worse for the responder.
 
     ngx.log(ngx.NOTICE, "OCSP response validation failed: ", err)
+    ocsp_status.failure(uid, "OCSP response validation failed: " .. err)
     return
   end
 
-  -- Normally this should be (nextUpdate - thisUpdate), but Lua API does not expose
-  -- those attributes.
+  -- the response is refetched when it expires, or after 3 days when the
+  -- responder does not set nextUpdate
   local expiry = 3600 * 24 * 3
+  local next_update = ocsp_status.success(uid, ocsp_response)
+  if next_update and next_update > ngx.time() then
+    expiry = next_update - ngx.time()
+  end
   local success, forcible
   success, err, forcible = ocsp_response_cache:set(uid, ocsp_response, expiry)
   if not success then
@@ -253,18 +337,51 @@ function _M.call()
     return ngx.exit(ngx.ERROR)
   end
 
-  local der_cert, der_priv_key, der_err = get_der_cert_and_priv_key(pem_cert)
-  if der_err then
-    ngx.log(ngx.ERR, der_err)
-    return ngx.exit(ngx.ERROR)
-  end
+  local der_cert, set_der_err
+  local key_id = certificate_data:get(KEY_ID_PREFIX .. pem_cert_uid)
+  if key_id then
+    -- the private key is kept by the external key provider
+    local der_cert_err
+    der_cert, der_cert_err = ssl.cert_pem_to_der(pem_cert)
+    if not der_cert then
+      ngx.log(ngx.ERR, "failed to convert certificate chain from PEM to DER: " .. der_cert_err)
+      return ngx.exit(ngx.ERROR)
+    end
 
-  local set_der_err = set_der_cert_and_key(der_cert, der_priv_key)
+    set_der_err = set_der_cert_and_engine_key(der_cert, key_id)
+  else
+    local der_priv_key, der_err
+    der_cert, der_priv_key, der_err = get_der_cert_and_priv_key(pem_cert)
+    if der_err then
+      ngx.log(ngx.ERR, der_err)
+      return ngx.exit(ngx.ERROR)
+    end
+
+    set_der_err = set_der_cert_and_key(der_cert, der_priv_key)
+  end
   if set_der_err then
     ngx.log(ngx.ERR, set_der_err)
     return ngx.exit(ngx.ERROR)
   end
 
+  -- with both an RSA and an ECDSA certificate, OpenSSL serves the one
+  -- supported by the client
+  local ecdsa_pem_cert = certificate_data:get(ECDSA_PREFIX .. pem_cert_uid)
+  if ecdsa_pem_cert then
+    local ecdsa_cert, ecdsa_priv_key, ecdsa_err =
+      get_ecdsa_cert_and_priv_key(pem_cert_uid, ecdsa_pem_cert)
+    if ecdsa_err then
+      ngx.log(ngx.ERR, ecdsa_err)
+      return ngx.exit(ngx.ERROR)
+    end
+
+    local set_err = set_cert_and_key(ecdsa_cert, ecdsa_priv_key)
+    if set_err then
+      ngx.log(ngx.ERR, set_err)
+      return ngx.exit(ngx.ERROR)
+    end
+  end
+
   if is_ocsp_stapling_enabled_for(pem_cert_uid) then
     local _, err = ocsp_staple(pem_cert_uid, der_cert)
     if err then
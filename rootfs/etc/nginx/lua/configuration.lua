@@ -1,4 +1,9 @@
 local cjson = require("cjson.safe")
+local acme = require("acme")
+local denylist = require("denylist")
+local drain = require("drain")
+local ocsp_status = require("ocsp_status")
+local outlier = require("balancer.outlier")
 
 local io = io
 local ngx = ngx
@@ -6,6 +11,7 @@ local tostring = tostring
 local string = string
 local table = table
 local pairs = pairs
+local type = type
 
 -- this is the Lua representation of Configuration struct in internal/ingress/types.go
 local configuration_data = ngx.shared.configuration_data
@@ -14,9 +20,17 @@ local certificate_servers = ngx.shared.certificate_servers
 local ocsp_response_cache = ngx.shared.ocsp_response_cache
 
 local EMPTY_UID = "-1"
+-- prefix of the keys of the ECDSA certificates in certificate_data
+local ECDSA_PREFIX = "ecdsa:"
+-- prefix of the keys of the identifiers of the private keys kept by the
+-- external key provider in certificate_data
+local KEY_ID_PREFIX = "keyid:"
 
 local _M = {}
 
+-- decoded secrets, cached per worker until the raw value changes
+local secrets_cache = { raw = nil, data = {} }
+
 function _M.get_backends_data()
   return configuration_data:get("backends")
 end
@@ -33,6 +47,31 @@ function _M.get_raw_backends_last_synced_at()
   return raw_backends_last_synced_at
 end
 
+-- get_secret returns the value of a Secret referenced by the configuration
+-- ConfigMap, or nil when the Secret is not configured
+function _M.get_secret(name)
+  local raw = configuration_data:get("secrets")
+  if not raw then
+    return nil
+  end
+
+  if raw ~= secrets_cache.raw then
+    local data = cjson.decode(raw)
+    if type(data) ~= "table" then
+      ngx.log(ngx.ERR, "invalid secrets in the configuration")
+      data = {}
+    end
+    secrets_cache = { raw = raw, data = data }
+  end
+
+  local value = secrets_cache.data[name]
+  if type(value) ~= "string" or value == "" then
+    return nil
+  end
+
+  return value
+end
+
 local function fetch_request_body()
   ngx.req.read_body()
   local body = ngx.req.get_body_data()
@@ -112,6 +151,7 @@ local function handle_servers()
         -- delete ocsp cache after certificate_data:set succeed
         if is_renew then
             ocsp_response_cache:delete(uid)
+            ocsp_status.remove(uid)
         end
     else
       local err_msg = string.format("error setting certificate for %s: %s\n",
@@ -123,6 +163,32 @@ local function handle_servers()
         .. "LRU entry has been removed to store %s", uid)
       ngx.log(ngx.WARN, msg)
     end
+
+    -- the ECDSA certificate of a certificate with both an RSA and an ECDSA
+    -- certificate, removed when the Secret does not contain it anymore
+    local ecdsa_cert = configuration.ecdsaCertificates and configuration.ecdsaCertificates[uid]
+    if ecdsa_cert then
+      success, set_err = certificate_data:set(ECDSA_PREFIX .. uid, ecdsa_cert)
+      if not success then
+        local err_msg = string.format("error setting ECDSA certificate for %s: %s\n",
+          uid, tostring(set_err))
+        table.insert(err_buf, err_msg)
+      end
+    else
+      certificate_data:delete(ECDSA_PREFIX .. uid)
+    end
+
+    local key_id = configuration.keyIds and configuration.keyIds[uid]
+    if key_id then
+      success, set_err = certificate_data:set(KEY_ID_PREFIX .. uid, key_id)
+      if not success then
+        local err_msg = string.format("error setting private key identifier for %s: %s\n",
+          uid, tostring(set_err))
+        table.insert(err_buf, err_msg)
+      end
+    else
+      certificate_data:delete(KEY_ID_PREFIX .. uid)
+    end
   end
 
   if #err_buf > 0 then
@@ -185,6 +251,114 @@ local function handle_certs()
   end
 end
 
+-- handle_ocsp returns the OCSP stapling status of the hosts
+local function handle_ocsp()
+  if ngx.var.request_method ~= "GET" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only GET requests are allowed!")
+    return
+  end
+
+  local hosts = ocsp_status.get_hosts(certificate_servers)
+  ngx.status = ngx.HTTP_OK
+  if #hosts == 0 then
+    ngx.print("[]")
+    return
+  end
+  ngx.print(cjson.encode(hosts))
+end
+
+-- handle_acme_challenges adds the challenge of a pending ACME order, with a
+-- token and a key authorization, or removes it, with only a token
+local function handle_ejections()
+  if ngx.var.request_method ~= "GET" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only GET requests are allowed!")
+    return
+  end
+
+  local ejections = outlier.get_ejections()
+  ngx.status = ngx.HTTP_OK
+  if #ejections == 0 then
+    ngx.print("[]")
+    return
+  end
+  ngx.print(cjson.encode(ejections))
+end
+
+local function handle_acme_challenges()
+  if ngx.var.request_method ~= "POST" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only POST requests are allowed!")
+    return
+  end
+
+  local challenge, err = cjson.decode(fetch_request_body() or "")
+  if not challenge or type(challenge.token) ~= "string" or challenge.token == "" then
+    ngx.log(ngx.ERR, "could not parse ACME challenge: ", tostring(err))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  if not challenge.keyAuthorization or challenge.keyAuthorization == "" then
+    acme.remove_challenge(challenge.token)
+    ngx.status = ngx.HTTP_CREATED
+    return
+  end
+
+  local ok, set_err = acme.set_challenge(challenge.token, challenge.keyAuthorization)
+  if not ok then
+    ngx.log(ngx.ERR, "error setting ACME challenge: ", tostring(set_err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
+-- handle_denylist returns or replaces the addresses denied to all the servers
+local function handle_denylist()
+  if ngx.var.request_method == "GET" then
+    ngx.status = ngx.HTTP_OK
+    ngx.print(denylist.get_entries())
+    return
+  end
+
+  local ok, err = denylist.set_entries(fetch_request_body())
+  if not ok then
+    ngx.log(ngx.ERR, "error setting denylist: ", tostring(err))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
+-- handle_secrets replaces the Secrets used by the Lua modules. The values
+-- are never returned.
+local function handle_secrets()
+  if ngx.var.request_method ~= "POST" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only POST requests are allowed!")
+    return
+  end
+
+  local secrets = fetch_request_body()
+  if not secrets then
+    ngx.log(ngx.ERR, "dynamic-configuration: unable to read valid request body")
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  local success, err = configuration_data:safe_set("secrets", secrets)
+  if not success then
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    ngx.log(ngx.ERR, "error setting secrets: " .. tostring(err))
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
 
 local function handle_backends()
   if ngx.var.request_method == "GET" then
@@ -247,6 +421,36 @@ function _M.call()
     return
   end
 
+  if ngx.var.request_uri == "/configuration/ocsp" then
+    handle_ocsp()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/ejections" then
+    handle_ejections()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/acme-challenges" then
+    handle_acme_challenges()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/denylist" then
+    handle_denylist()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/secrets" then
+    handle_secrets()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/drain" then
+    drain.handle()
+    return
+  end
+
   ngx.status = ngx.HTTP_NOT_FOUND
   ngx.print("Not found!")
 end
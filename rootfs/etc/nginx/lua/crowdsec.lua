@@ -0,0 +1,134 @@
+-- CrowdSec bouncer.
+--
+-- The decisions of the CrowdSec Local API about the address of the client are
+-- queried in the access phase, and cached by the replica in a shared
+-- dictionary. The requests of the addresses with a decision are denied, the
+-- captcha decisions are applied as bans. The requests are allowed when the
+-- Local API cannot be reached, without caching.
+--
+-- The API key of the bouncer is read from the crowdsec-api-key-secret Secret,
+-- it is never rendered in nginx.conf.
+--
+local http = require("resty.http")
+local cjson = require("cjson.safe")
+local configuration = require("configuration")
+
+local ngx = ngx
+local type = type
+local tonumber = tonumber
+local tostring = tostring
+local math_min = math.min
+local math_floor = math.floor
+local string_format = string.format
+
+local BANNED = "ban"
+local ALLOWED = "ok"
+
+local _M = {}
+
+local function cache()
+  return ngx.shared.crowdsec_cache
+end
+
+-- parse_duration returns the seconds of a Go duration of the Local API, like
+-- 3h59m58.123s, nil if it cannot be parsed
+function _M.parse_duration(duration)
+  if type(duration) ~= "string" or duration == "" or duration:sub(1, 1) == "-" then
+    return nil
+  end
+
+  local seconds = 0
+  local rest = duration:gsub("(%d+%.?%d*)(%a+)", function(value, unit)
+    local factor = ({ h = 3600, m = 60, s = 1, ms = 0.001 })[unit]
+    if not factor then
+      return nil
+    end
+    seconds = seconds + tonumber(value) * factor
+    return ""
+  end)
+  if rest ~= "" then
+    return nil
+  end
+
+  return math_floor(seconds)
+end
+
+-- query returns the decision of the Local API about the address, and the
+-- time in seconds it is valid
+local function query(config, address)
+  local api_key = configuration.get_secret("crowdsec_api_key")
+  if not api_key then
+    return nil, nil, "the API key is not configured"
+  end
+
+  local httpc = http.new()
+  httpc:set_timeout(config.timeout)
+
+  local res, err = httpc:request_uri(
+    string_format("%s/v1/decisions?ip=%s", config.lapi_url, ngx.escape_uri(address)), {
+      method = "GET",
+      headers = {
+        ["X-Api-Key"] = api_key,
+        ["User-Agent"] = "ingress-nginx",
+      },
+    })
+  if not res then
+    return nil, nil, err
+  end
+  if res.status ~= ngx.HTTP_OK then
+    return nil, nil, "unexpected status " .. tostring(res.status)
+  end
+
+  -- the Local API returns null for the addresses without decision
+  local decisions = cjson.decode(res.body)
+  if type(decisions) ~= "table" or #decisions == 0 then
+    return ALLOWED, config.cache_ttl
+  end
+
+  local ttl = config.cache_ttl
+  local duration = _M.parse_duration(decisions[1].duration)
+  if duration and duration > 0 then
+    ttl = math_min(ttl, duration)
+  end
+
+  return BANNED, ttl
+end
+
+function _M.is_banned(config, address)
+  local decision = cache():get(address)
+  if decision then
+    return decision == BANNED
+  end
+
+  local ttl, err
+  decision, ttl, err = query(config, address)
+  if not decision then
+    ngx.log(ngx.ERR, "could not query the CrowdSec Local API about ", address, ": ", tostring(err))
+    return false
+  end
+
+  -- a 0 expiration would cache the decision forever
+  if ttl > 0 then
+    local ok
+    ok, err = cache():safe_set(address, decision, ttl)
+    if not ok then
+      ngx.log(ngx.WARN, "could not cache the CrowdSec decision about ", address, ": ", tostring(err))
+    end
+  end
+
+  return decision == BANNED
+end
+
+function _M.access(config)
+  if not config or not config.enabled then
+    return
+  end
+
+  local address = ngx.var.remote_addr
+  if _M.is_banned(config, address) then
+    ngx.log(ngx.INFO, "denying request of ", address, ", it is banned by CrowdSec")
+    return ngx.exit(ngx.HTTP_FORBIDDEN)
+  end
+end
+
+return _M
@@ -0,0 +1,209 @@
+-- CSRF protection.
+--
+-- The clients of the locations with CSRF protection are issued a signed token
+-- in a cookie readable by their scripts. The requests with an unsafe method
+-- must come from the origin of the Ingress or a trusted origin, and send the
+-- token of the cookie back in a header or in the csrf_token field of a form,
+-- which the pages of other sites cannot read (double-submit cookie).
+--
+local ck = require("resty.cookie")
+local resty_random = require("resty.random")
+local resty_string = require("resty.string")
+local configuration = require("configuration")
+local bit = require("bit")
+
+local ngx = ngx
+local ipairs = ipairs
+local type = type
+local string_lower = string.lower
+local string_byte = string.byte
+
+local COOKIE_NAME = "ingress_csrf"
+local FORM_FIELD = "csrf_token"
+local FORM_CONTENT_TYPE = "application/x-www-form-urlencoded"
+
+local SAFE_METHODS = { GET = true, HEAD = true, OPTIONS = true, TRACE = true }
+
+local _M = {}
+
+-- signing_key returns the key of the csrf-secret Secret. The token issued by
+-- a replica is sent back to any other one and after the reloads, so the
+-- tokens are only signed with the shared key.
+local function signing_key()
+  return configuration.get_secret("csrf_secret")
+end
+
+-- constant_time_equals compares the strings without returning at the first
+-- differing byte, which would leak the length of the matching prefix
+local function constant_time_equals(a, b)
+  if type(a) ~= "string" or type(b) ~= "string" or #a ~= #b then
+    return false
+  end
+
+  local difference = 0
+  for i = 1, #a do
+    difference = bit.bor(difference, bit.bxor(string_byte(a, i), string_byte(b, i)))
+  end
+
+  return difference == 0
+end
+
+local function sign(secret, nonce)
+  return ngx.encode_base64(ngx.hmac_sha1(secret, nonce), true)
+end
+
+local function new_token(secret)
+  local nonce = resty_string.to_hex(resty_random.bytes(16, true) or resty_random.bytes(16))
+  return nonce .. "." .. sign(secret, nonce)
+end
+
+-- valid_token returns true when the token was signed with the secret
+function _M.valid_token(secret, token)
+  if type(token) ~= "string" then
+    return false
+  end
+
+  local nonce, signature = token:match("^(%x+)%.(.+)$")
+  if not nonce then
+    return false
+  end
+
+  return constant_time_equals(signature, sign(secret, nonce))
+end
+
+local function is_trusted_origin(location_config, origin)
+  local own_origin = ngx.var.pass_access_scheme .. "://" .. ngx.var.best_http_host
+  if string_lower(origin) == string_lower(own_origin) then
+    return true
+  end
+
+  for _, trusted_origin in ipairs(location_config.trusted_origins or {}) do
+    if string_lower(origin) == trusted_origin then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- check_origin returns the reason the origin of the request is rejected, nil
+-- when it is allowed. The browsers send Sec-Fetch-Site and Origin with the
+-- unsafe requests, the requests without them do not come from a browser.
+local function check_origin(location_config)
+  local origin = ngx.var.http_origin
+  local fetch_site = ngx.var.http_sec_fetch_site
+
+  if origin and origin ~= "null" then
+    if not is_trusted_origin(location_config, origin) then
+      return "origin " .. origin .. " is not trusted"
+    end
+    return nil
+  end
+
+  if fetch_site == "cross-site" or fetch_site == "same-site" or origin == "null" then
+    return "request from another site without a trusted origin"
+  end
+
+  return nil
+end
+
+local function submitted_token(location_config)
+  local header_variable = "http_" .. string_lower(location_config.header_name):gsub("-", "_")
+  local token = ngx.var[header_variable]
+  if token then
+    return token
+  end
+
+  local content_type = ngx.var.content_type
+  if not content_type or string_lower(content_type):sub(1, #FORM_CONTENT_TYPE) ~= FORM_CONTENT_TYPE then
+    return nil
+  end
+
+  ngx.req.read_body()
+  -- the forms buffered to a file cannot be read, their tokens are missing
+  local args = ngx.req.get_post_args()
+  if not args then
+    return nil
+  end
+
+  local value = args[FORM_FIELD]
+  if type(value) == "table" then
+    return nil
+  end
+
+  return value
+end
+
+local function issue_token(secret)
+  local cookie, err = ck:new()
+  if not cookie then
+    ngx.log(ngx.ERR, err)
+    return
+  end
+
+  local ok
+  ok, err = cookie:set({
+    key = COOKIE_NAME,
+    value = new_token(secret),
+    path = "/",
+    -- the scripts of the pages read the token to send it back in the header
+    httponly = false,
+    samesite = "Lax",
+    secure = ngx.var.https == "on",
+  })
+  if not ok then
+    ngx.log(ngx.ERR, err)
+  end
+end
+
+local function reject(reason)
+  ngx.log(ngx.INFO, "rejecting request of ", ngx.var.remote_addr, " to ", ngx.var.request_uri,
+    ": CSRF protection: ", reason)
+  ngx.ctx.csrf_rejected = true
+  return ngx.exit(ngx.HTTP_FORBIDDEN)
+end
+
+function _M.rewrite(location_config)
+  if not location_config or not location_config.enabled then
+    return
+  end
+
+  local secret = signing_key()
+  if not secret then
+    ngx.log(ngx.ERR, "csrf-secret is not configured, the tokens cannot be issued")
+    if SAFE_METHODS[ngx.req.get_method()] then
+      return
+    end
+    return reject("csrf-secret is not configured")
+  end
+
+  local cookie, err = ck:new()
+  if not cookie then
+    ngx.log(ngx.ERR, err)
+    return
+  end
+  local cookie_token = cookie:get(COOKIE_NAME)
+  local has_valid_cookie = _M.valid_token(secret, cookie_token)
+
+  if SAFE_METHODS[ngx.req.get_method()] then
+    if not has_valid_cookie then
+      issue_token(secret)
+    end
+    return
+  end
+
+  local reason = check_origin(location_config)
+  if reason then
+    return reject(reason)
+  end
+
+  if not has_valid_cookie then
+    return reject("missing or invalid token cookie")
+  end
+
+  if not constant_time_equals(submitted_token(location_config), cookie_token) then
+    return reject("the submitted token does not match the cookie")
+  end
+end
+
+return _M
@@ -0,0 +1,136 @@
+-- Global IP denylist.
+--
+-- The addresses of the Denylists are posted by the controller to
+-- /configuration/denylist and kept in a shared dictionary, every worker builds
+-- a matcher of them again when they change. The requests of the denied
+-- addresses are rejected by all the servers in the rewrite phase, before the
+-- access phase where `satisfy any` could let them through.
+--
+local cjson = require("cjson.safe")
+local resty_ipmatcher = require("resty.ipmatcher")
+
+local ngx = ngx
+local ipairs = ipairs
+local type = type
+local tostring = tostring
+
+-- measured in seconds
+local SYNC_INTERVAL = 1
+
+local _M = {}
+
+-- the entries of the matcher of the worker, as posted by the controller
+local raw_entries
+local matcher
+
+local function dict()
+  return ngx.shared.denylist
+end
+
+-- new_matcher returns a matcher of the addresses of the entries, whose value
+-- is the Unix time at which they expire, 0 for never
+local function new_matcher(entries)
+  if type(entries) ~= "table" then
+    return nil, "the denylist is not an array"
+  end
+
+  if #entries == 0 then
+    return nil
+  end
+
+  local expirations = {}
+  for _, entry in ipairs(entries) do
+    if type(entry) ~= "table" or type(entry.cidr) ~= "string" then
+      return nil, "invalid denylist entry"
+    end
+    expirations[entry.cidr] = entry.expiresAt or 0
+  end
+
+  return resty_ipmatcher.new_with_value(expirations)
+end
+
+local function sync()
+  local raw = dict():get("entries")
+  if raw == raw_entries then
+    return
+  end
+
+  local entries, err = cjson.decode(raw or "[]")
+  if not entries then
+    ngx.log(ngx.ERR, "could not parse denylist: ", tostring(err))
+    return
+  end
+
+  local new
+  new, err = new_matcher(entries)
+  if err then
+    ngx.log(ngx.ERR, "could not build denylist matcher: ", tostring(err))
+    return
+  end
+
+  matcher = new
+  raw_entries = raw
+end
+
+function _M.get_entries()
+  return dict():get("entries") or "[]"
+end
+
+-- set_entries saves the entries posted by the controller, the workers apply
+-- them within a second
+function _M.set_entries(raw)
+  local entries, err = cjson.decode(raw or "")
+  if not entries then
+    return false, "could not parse denylist: " .. tostring(err)
+  end
+
+  local _
+  _, err = new_matcher(entries)
+  if err then
+    return false, err
+  end
+
+  local ok, set_err = dict():safe_set("entries", raw)
+  if not ok then
+    return false, set_err
+  end
+
+  return true
+end
+
+function _M.is_denied(address)
+  if not matcher then
+    return false
+  end
+
+  local expires_at, err = matcher:match(address)
+  if err then
+    ngx.log(ngx.ERR, "failed to match ip: '", address, "': ", err)
+    return false
+  end
+  if not expires_at then
+    return false
+  end
+
+  return expires_at == 0 or expires_at > ngx.time()
+end
+
+function _M.init_worker()
+  sync()
+
+  local ok, err = ngx.timer.every(SYNC_INTERVAL, sync)
+  if not ok then
+    ngx.log(ngx.ERR, "error when setting up timer.every for denylist sync: ", err)
+  end
+end
+
+function _M.rewrite()
+  if _M.is_denied(ngx.var.remote_addr) then
+    ngx.log(ngx.INFO, "denying request of ", ngx.var.remote_addr, ", it is in the denylist")
+    return ngx.exit(ngx.HTTP_FORBIDDEN)
+  end
+end
+
+setmetatable(_M, {__index = { sync = sync }})
+
+return _M
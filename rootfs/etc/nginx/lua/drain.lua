@@ -0,0 +1,131 @@
+-- Connection draining.
+--
+-- The requests in flight are counted per protocol, http, websocket or grpc,
+-- in a shared dictionary. On shutdown the controller starts draining with a
+-- POST on /configuration/drain: the new requests are rejected, and the
+-- controller polls the requests still in flight with a GET until they are
+-- done or the grace period of their protocol elapsed. The protocol counted
+-- for a request is recorded by $request_id in the dictionary, ngx.ctx is
+-- reset by the internal redirects of the error pages.
+--
+local cjson = require("cjson.safe")
+
+local ngx = ngx
+local ipairs = ipairs
+local string_lower = string.lower
+local string_sub = string.sub
+local tostring = tostring
+
+local DRAINING_KEY = "draining"
+
+local PROTOCOLS = { "http", "websocket", "grpc" }
+
+local _M = {}
+
+local function dict()
+  return ngx.shared.drain
+end
+
+local function protocol()
+  local upgrade = ngx.var.http_upgrade
+  if upgrade ~= nil and string_lower(upgrade) == "websocket" then
+    return "websocket"
+  end
+
+  local content_type = ngx.var.http_content_type
+  if content_type ~= nil and string_sub(content_type, 1, 16) == "application/grpc" then
+    return "grpc"
+  end
+
+  return "http"
+end
+
+-- request_key returns the key of the protocol counted for the request
+local function request_key()
+  return "request:" .. ngx.var.request_id
+end
+
+function _M.is_draining()
+  return dict():get(DRAINING_KEY) == true
+end
+
+-- start rejects the new requests, the requests in flight are served until
+-- NGINX stops
+function _M.start()
+  local ok, err = dict():set(DRAINING_KEY, true)
+  if not ok then
+    return nil, err
+  end
+  return true
+end
+
+-- active_requests returns the number of requests in flight per protocol
+function _M.active_requests()
+  local requests = {}
+  for _, name in ipairs(PROTOCOLS) do
+    requests[name] = dict():get(name) or 0
+  end
+  return requests
+end
+
+function _M.rewrite()
+  -- a request redirected to another location is counted once, by the first
+  -- location
+  if dict():get(request_key()) then
+    return
+  end
+
+  if _M.is_draining() then
+    return ngx.exit(ngx.HTTP_SERVICE_UNAVAILABLE)
+  end
+
+  local name = protocol()
+  local _, err = dict():incr(name, 1, 0)
+  if err then
+    ngx.log(ngx.ERR, "drain:incr failed " .. tostring(err))
+    return
+  end
+
+  -- the request is released in the log phase
+  local ok
+  ok, err = dict():safe_set(request_key(), name)
+  if not ok then
+    ngx.log(ngx.ERR, "drain:safe_set failed " .. tostring(err))
+    dict():incr(name, -1, 0)
+  end
+end
+
+function _M.log()
+  local name = dict():get(request_key())
+  if not name then
+    return
+  end
+  dict():delete(request_key())
+
+  local _, err = dict():incr(name, -1, 0)
+  if err then
+    ngx.log(ngx.ERR, "drain:incr failed " .. tostring(err))
+  end
+end
+
+-- handle serves /configuration/drain, a POST starts draining and a GET
+-- returns the requests in flight in the format of ActiveRequests in
+-- internal/nginx/drain.go
+function _M.handle()
+  if ngx.var.request_method == "POST" then
+    local ok, err = _M.start()
+    if not ok then
+      ngx.log(ngx.ERR, "error starting to drain: ", tostring(err))
+      ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+      return
+    end
+
+    ngx.status = ngx.HTTP_CREATED
+    return
+  end
+
+  ngx.status = ngx.HTTP_OK
+  ngx.print(cjson.encode(_M.active_requests()))
+end
+
+return _M
@@ -0,0 +1,86 @@
+-- Country and ASN access.
+--
+-- The locations with geo access rules allow or deny the requests by the
+-- country and the autonomous system the GeoIP2 databases locate the client
+-- in. The denied requests are answered with the status code of the location:
+--
+-- * a client of a denied country or autonomous system is denied
+-- * with allowed countries, a client of another country is denied, as well as
+--   the clients which are not located, e.g. without the GeoIP2 databases
+-- * with allowed autonomous systems, likewise
+--
+local ngx = ngx
+local ipairs = ipairs
+
+local _M = {}
+
+-- geo_var returns the first non empty variable, the variables of the GeoIP2
+-- databases which are not loaded are not defined
+local function geo_var(...)
+  for _, name in ipairs({ ... }) do
+    local value = ngx.var[name]
+    if value and value ~= "" then
+      return value
+    end
+  end
+
+  return nil
+end
+
+local function contains(codes, code)
+  if not codes or not code then
+    return false
+  end
+
+  for _, c in ipairs(codes) do
+    if c == code then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- denied returns the reason the client is denied by the rules, nil when it
+-- is allowed
+function _M.denied(rules, country, asn)
+  if contains(rules.deny_countries, country) then
+    return "denied country " .. country
+  end
+
+  if contains(rules.deny_asns, asn) then
+    return "denied autonomous system " .. asn
+  end
+
+  if rules.allow_countries and #rules.allow_countries > 0 and not contains(rules.allow_countries, country) then
+    return "country " .. (country or "unknown") .. " not allowed"
+  end
+
+  if rules.allow_asns and #rules.allow_asns > 0 and not contains(rules.allow_asns, asn) then
+    return "autonomous system " .. (asn or "unknown") .. " not allowed"
+  end
+
+  return nil
+end
+
+function _M.rewrite(rules)
+  -- the status code is only set for the locations with rules
+  if not rules or not rules.status_code or rules.status_code == 0 then
+    return
+  end
+
+  local country = geo_var("geoip2_city_country_code", "geoip2_country_code")
+  -- $geoip2_asn defaults to 0 when the client is not located
+  local asn = geo_var("geoip2_asn")
+  if asn == "0" then
+    asn = nil
+  end
+
+  local reason = _M.denied(rules, country, asn)
+  if reason then
+    ngx.log(ngx.INFO, "rejecting request of ", ngx.var.remote_addr, ": ", reason)
+    return ngx.exit(rules.status_code)
+  end
+end
+
+return _M
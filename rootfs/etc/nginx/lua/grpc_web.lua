@@ -0,0 +1,145 @@
+-- gRPC-Web to gRPC translation.
+--
+-- Browsers send gRPC-Web requests over HTTP/1.1 and can't read the HTTP/2
+-- trailers carrying the status of a gRPC call. The requests are forwarded to
+-- the backend as gRPC calls, and the status received in the trailers is
+-- appended to the response body as a gRPC-Web trailers frame. The base64
+-- encoded grpc-web-text variant is decoded and encoded on the fly.
+--
+local bit = require("bit")
+
+local ngx = ngx
+local string_char = string.char
+local string_sub = string.sub
+
+local CONTENT_TYPE_PREFIX = "application/grpc-web"
+local TEXT_SUFFIX = "-text"
+-- flag of the gRPC-Web frames carrying the trailers instead of a message
+local TRAILERS_FLAG = 0x80
+-- gRPC status code
+local UNKNOWN = "2"
+
+local _M = {}
+
+local function uint32(value)
+  return string_char(bit.band(bit.rshift(value, 24), 0xff), bit.band(bit.rshift(value, 16), 0xff),
+                     bit.band(bit.rshift(value, 8), 0xff), bit.band(value, 0xff))
+end
+
+local function read_body()
+  ngx.req.read_body()
+
+  local body = ngx.req.get_body_data()
+  if body then
+    return body
+  end
+
+  local file_name = ngx.req.get_body_file()
+  if not file_name then
+    return ""
+  end
+
+  local file, err = io.open(file_name, "rb")
+  if not file then
+    ngx.log(ngx.ERR, "failed to open the gRPC-Web request body: ", err)
+    return nil
+  end
+  body = file:read("*a")
+  file:close()
+
+  return body
+end
+
+-- rewrite turns a gRPC-Web request into a gRPC call
+function _M.rewrite()
+  local content_type = ngx.var.http_content_type
+  if not content_type or
+      string_sub(content_type, 1, #CONTENT_TYPE_PREFIX) ~= CONTENT_TYPE_PREFIX then
+    return
+  end
+
+  -- the suffix is empty or the message format, e.g. +proto
+  local suffix = string_sub(content_type, #CONTENT_TYPE_PREFIX + 1)
+  local text = string_sub(suffix, 1, #TEXT_SUFFIX) == TEXT_SUFFIX
+  if text then
+    suffix = string_sub(suffix, #TEXT_SUFFIX + 1)
+  end
+
+  if text then
+    local body = read_body()
+    local decoded = body and ngx.decode_base64(body)
+    if not decoded then
+      ngx.log(ngx.WARN, "invalid base64 encoded gRPC-Web request body")
+      return ngx.exit(ngx.HTTP_BAD_REQUEST)
+    end
+    ngx.req.set_body_data(decoded)
+  end
+
+  ngx.req.set_header("Content-Type", "application/grpc" .. suffix)
+
+  ngx.ctx.grpc_web = {
+    content_type = content_type,
+    text = text,
+    pending = "",
+  }
+end
+
+function _M.header()
+  local state = ngx.ctx.grpc_web
+  if not state then
+    return
+  end
+
+  -- errors of NGINX itself are not gRPC responses
+  if ngx.status ~= ngx.HTTP_OK then
+    ngx.ctx.grpc_web = nil
+    return
+  end
+
+  -- trailers-only responses carry the status in the headers, which gRPC-Web
+  -- clients can read
+  state.status_in_headers = ngx.header["grpc-status"] ~= nil
+
+  ngx.header["Content-Type"] = state.content_type
+  ngx.header["Content-Length"] = nil
+end
+
+local function trailers_frame()
+  -- a call without status was interrupted
+  local status = ngx.var.upstream_trailer_grpc_status or UNKNOWN
+  local trailers = "grpc-status:" .. status .. "\r\n"
+  local message = ngx.var.upstream_trailer_grpc_message
+  if message and message ~= "" then
+    trailers = trailers .. "grpc-message:" .. message .. "\r\n"
+  end
+
+  return string_char(TRAILERS_FLAG) .. uint32(#trailers) .. trailers
+end
+
+function _M.body()
+  local state = ngx.ctx.grpc_web
+  if not state then
+    return
+  end
+
+  local chunk, eof = ngx.arg[1], ngx.arg[2]
+  if eof and not state.status_in_headers then
+    chunk = chunk .. trailers_frame()
+  end
+
+  if state.text then
+    -- only whole groups of 3 bytes are encoded before the end of the body,
+    -- so the chunks don't contain base64 padding
+    chunk = state.pending .. chunk
+    if not eof then
+      local length = #chunk - #chunk % 3
+      state.pending = string_sub(chunk, length + 1)
+      chunk = string_sub(chunk, 1, length)
+    end
+    chunk = ngx.encode_base64(chunk)
+  end
+
+  ngx.arg[1] = chunk
+end
+
+return _M
@@ -0,0 +1,87 @@
+-- Private keys kept outside of the Secrets.
+--
+-- The certificates of the Secrets with a tls.key-id key come without their
+-- private key. The key is loaded through the OpenSSL engine configured with
+-- ssl-key-engine, which delegates the signatures to the HSM or the KMS, so the
+-- key material never reaches the pod.
+--
+local ffi = require("ffi")
+
+local ngx = ngx
+local C = ffi.C
+local string_format = string.format
+
+ffi.cdef[[
+void *ENGINE_by_id(const char *id);
+int ENGINE_init(void *e);
+int ENGINE_free(void *e);
+void *ENGINE_load_private_key(void *e, const char *key_id, void *ui_method,
+                              void *callback_data);
+void EVP_PKEY_free(void *pkey);
+]]
+
+local _M = {}
+
+-- initialized engines, indexed by name
+local engines = {}
+-- private keys loaded in this worker, indexed by engine name and key identifier
+local priv_keys = {}
+
+local function get_engine(engine_id)
+  local engine = engines[engine_id]
+  if engine then
+    return engine
+  end
+
+  engine = C.ENGINE_by_id(engine_id)
+  if engine == nil then
+    return nil, string_format("OpenSSL engine %s not found", engine_id)
+  end
+
+  -- ENGINE_init takes a functional reference to the engine, the structural
+  -- one returned by ENGINE_by_id is not needed anymore
+  local ok = C.ENGINE_init(engine) == 1
+  C.ENGINE_free(engine)
+  if not ok then
+    return nil, string_format("failed to initialize OpenSSL engine %s", engine_id)
+  end
+
+  engines[engine_id] = engine
+  return engine
+end
+
+-- priv_key returns the private key identified by key_id in the engine, to be
+-- given to ngx.ssl.set_priv_key. A new key identifier is expected when the
+-- key is rotated, the keys are cached for the lifetime of the worker.
+function _M.priv_key(engine_id, key_id)
+  if not engine_id or engine_id == "" then
+    return nil, "no OpenSSL engine configured to load the private key " .. key_id ..
+                ", see ssl-key-engine"
+  end
+
+  local cache_key = engine_id .. ":" .. key_id
+  local priv_key = priv_keys[cache_key]
+  if priv_key then
+    return priv_key
+  end
+
+  local engine, err = get_engine(engine_id)
+  if not engine then
+    return nil, err
+  end
+
+  local pkey = C.ENGINE_load_private_key(engine, key_id, nil, nil)
+  if pkey == nil then
+    return nil, string_format("failed to load private key %s with OpenSSL engine %s",
+                              key_id, engine_id)
+  end
+
+  priv_key = ffi.gc(pkey, C.EVP_PKEY_free)
+  priv_keys[cache_key] = priv_key
+  ngx.log(ngx.INFO, string_format("loaded private key %s with OpenSSL engine %s",
+                                  key_id, engine_id))
+
+  return priv_key
+end
+
+return _M
@@ -1,11 +1,25 @@
 local ngx_re_split = require("ngx.re").split
+local cjson = require("cjson.safe")
+local resty_random = require("resty.random")
 
 local certificate_configured_for_current_request =
   require("certificate").configured_for_current_request
 local global_throttle = require("global_throttle")
+local websocket = require("websocket")
+local bot_detection = require("bot_detection")
+local csrf = require("csrf")
+local normalization = require("normalization")
+local geo_access = require("geo_access")
+local request_validation = require("request_validation")
+local crowdsec = require("crowdsec")
+local drain = require("drain")
+local denylist = require("denylist")
+local auto_ban = require("auto_ban")
+local configuration = require("configuration")
 
 local ngx = ngx
 local io = io
+local pairs = pairs
 local math = math
 local string = string
 local original_randomseed = math.randomseed
@@ -14,9 +28,14 @@ local ngx_redirect = ngx.redirect
 
 local _M = {}
 
+-- measured in seconds
+local GENERAL_CONFIG_SYNC_INTERVAL = 1
+
 local seeds = {}
 -- general Nginx configuration passed by controller to be used in this module
 local config
+-- the last general configuration posted by the controller and applied
+local raw_general_config
 
 local function get_seed_from_urandom()
   local seed
@@ -98,10 +117,42 @@ local function parse_x_forwarded_host()
   return hosts[1]
 end
 
+-- sync_general_config applies the keys of the configuration ConfigMap
+-- changed without reloading NGINX, posted to /configuration/general
+local function sync_general_config()
+  local raw_config = configuration.get_general_data()
+  if not raw_config or raw_config == raw_general_config then
+    return
+  end
+
+  local new_config, err = cjson.decode(raw_config)
+  if not new_config then
+    ngx.log(ngx.ERR, "could not parse general configuration: ", err)
+    return
+  end
+
+  for key, value in pairs(new_config) do
+    config[key] = value
+  end
+  raw_general_config = raw_config
+end
+
 function _M.init_worker()
   randomseed()
+  denylist.init_worker()
+
+  -- the general configuration posted before the last reload is older than
+  -- the configuration of the template
+  raw_general_config = configuration.get_general_data()
+
+  local ok, err = ngx.timer.every(GENERAL_CONFIG_SYNC_INTERVAL, sync_general_config)
+  if not ok then
+    ngx.log(ngx.ERR, "error when setting up timer.every for sync_general_config: ", err)
+  end
 end
 
+_M.sync_general_config = sync_general_config
+
 function _M.set_config(new_config)
   config = new_config
 end
@@ -110,6 +161,22 @@ end
 -- This is where we do variable assignments to be used in subsequent
 -- phases or redirection
 function _M.rewrite(location_config)
+  denylist.rewrite()
+  auto_ban.rewrite(config.auto_ban)
+  request_validation.rewrite(location_config.strict_request_validation)
+  geo_access.rewrite(location_config.geo_access)
+
+  if config.drain then
+    drain.rewrite()
+  end
+
+  if location_config.csp_nonce then
+    -- the nonce of the Content-Security-Policy is unpredictable and differs
+    -- in every response, unlike the ID of the request sent by the clients
+    local bytes = resty_random.bytes(16, true) or resty_random.bytes(16)
+    ngx.var.csp_nonce = ngx.encode_base64(bytes)
+  end
+
   ngx.var.pass_access_scheme = ngx.var.scheme
 
   ngx.var.best_http_host = ngx.var.http_host or ngx.var.host
@@ -164,11 +231,31 @@ function _M.rewrite(location_config)
     return ngx_redirect(uri, config.http_redirect_code)
   end
 
+  normalization.rewrite(location_config.normalization)
   global_throttle.throttle(config.global_throttle, location_config.global_throttle)
+  bot_detection.rewrite(config.bot_detection, location_config.bot_detection)
+  csrf.rewrite(location_config.csrf)
+  websocket.rewrite(location_config.websocket)
 end
 
-function _M.header()
-  if config.hsts and ngx.var.scheme == "https" and certificate_configured_for_current_request then
+function _M.access()
+  crowdsec.access(config.crowdsec)
+end
+
+-- header sets the Strict-Transport-Security header of the HTTPS responses,
+-- the one of the security headers of the location replacing the one of the
+-- hsts configuration
+function _M.header(security_headers_hsts)
+  if ngx.var.scheme ~= "https" or not certificate_configured_for_current_request then
+    return
+  end
+
+  if security_headers_hsts and security_headers_hsts ~= "" then
+    ngx.header["Strict-Transport-Security"] = security_headers_hsts
+    return
+  end
+
+  if config.hsts then
     local value = "max-age=" .. config.hsts_max_age
     if config.hsts_include_subdomains then
       value = value .. "; includeSubDomains"
@@ -180,4 +267,12 @@ function _M.header()
   end
 end
 
+function _M.log()
+  auto_ban.log(config.auto_ban)
+  if config.drain then
+    drain.log()
+  end
+  websocket.log()
+end
+
 return _M
@@ -5,10 +5,12 @@ local string = string
 local tostring = tostring
 local socket = ngx.socket.tcp
 local cjson = require("cjson.safe")
+local split = require("util.split")
 local new_tab = require "table.new"
 local clear_tab = require "table.clear"
 local table = table
 local pairs = pairs
+local ipairs = ipairs
 
 
 -- if an Nginx worker processes more than (MAX_BATCH_SIZE/FLUSH_INTERVAL) RPS
@@ -31,13 +33,89 @@ local function send(payload)
   assert(s:close())
 end
 
+-- endpoint_metrics returns the address, status, connect time and response
+-- time of every upstream attempt of the request. The connect time is -1 when
+-- the connection to the endpoint failed.
+local function endpoint_metrics()
+  local addrs = split.split_upstream_var(ngx.var.upstream_addr)
+  if not addrs or #addrs == 0 then
+    return nil
+  end
+
+  local statuses = split.split_upstream_var(ngx.var.upstream_status) or {}
+  local connect_times = split.split_upstream_var(ngx.var.upstream_connect_time) or {}
+  local response_times = split.split_upstream_var(ngx.var.upstream_response_time) or {}
+
+  local endpoints = new_tab(#addrs, 0)
+  for i, addr in ipairs(addrs) do
+    endpoints[i] = {
+      address = addr,
+      status = statuses[i] or "-",
+      connectTime = tonumber(connect_times[i]) or -1,
+      responseTime = tonumber(response_times[i]) or -1,
+    }
+  end
+
+  return endpoints
+end
+
+-- denial returns the reason the request was denied by the ingress controller,
+-- nil when it was not denied. The 401 and 403 responses of the backends are
+-- not denials.
+local function denial()
+  if ngx.ctx.auto_banned then
+    return "auto-ban"
+  end
+  if ngx.ctx.csrf_rejected then
+    return "csrf"
+  end
+  if ngx.ctx.request_violation then
+    return "invalid-request"
+  end
+  if ngx.var.limit_req_status == "REJECTED" then
+    return "limit-req"
+  end
+  if ngx.var.limit_conn_status == "REJECTED" then
+    return "limit-conn"
+  end
+
+  local global_rate_limit_exceeding = ngx.var.global_rate_limit_exceeding
+  if global_rate_limit_exceeding == "y" or global_rate_limit_exceeding == "c" then
+    return "global-throttle"
+  end
+
+  local status = ngx.var.status
+  if status ~= "401" and status ~= "403" then
+    return nil
+  end
+
+  local upstream_addr = ngx.var.upstream_addr
+  if upstream_addr and upstream_addr ~= "" then
+    return nil
+  end
+
+  -- auth_status is the status of the external authentication, basic and
+  -- digest authentications respond with 401
+  if status == "401" or ngx.var.auth_status == status then
+    return "auth"
+  end
+
+  if ngx.var.source_range_restricted == "1" then
+    return "source-range"
+  end
+
+  return nil
+end
+
 local function metrics()
-  return {
+  local request_metrics = {
     host = ngx.var.host or "-",
     namespace = ngx.var.namespace or "-",
     ingress = ngx.var.ingress_name or "-",
     service = ngx.var.service_name or "-",
     canary = ngx.var.proxy_alternative_upstream_name or "-",
+    canaryBackend = ngx.ctx.canary_backend or "-",
+    variant = ngx.ctx.canary_variant or "-",
     path = ngx.var.location_path or "-",
 
     method = ngx.var.request_method or "-",
@@ -51,7 +129,34 @@ local function metrics()
     upstreamResponseTime = tonumber(ngx.var.upstream_response_time) or -1,
     upstreamResponseLength = tonumber(ngx.var.upstream_response_length) or -1,
     --upstreamStatus = ngx.var.upstream_status or "-",
+
+    denial = denial(),
+    clientBanned = ngx.ctx.client_banned,
+    requestViolation = ngx.ctx.request_violation,
   }
+
+  if _M.is_ewma_metrics_enabled then
+    -- the score is -1 when the endpoint was not picked by the ewma load balancer
+    local ewma_score = tonumber(ngx.var.balancer_ewma_score)
+    if ewma_score and ewma_score >= 0 then
+      request_metrics.upstreamAddr = split.get_last_value(ngx.var.upstream_addr)
+      request_metrics.ewmaScore = ewma_score
+    end
+  end
+
+  if _M.is_endpoint_metrics_enabled then
+    request_metrics.endpoints = endpoint_metrics()
+  end
+
+  if _M.is_tracing_enabled then
+    -- the trace ID is attached as an exemplar to the duration metrics
+    local trace_id = ngx.var.opentelemetry_trace_id
+    if trace_id and trace_id ~= "" then
+      request_metrics.traceId = trace_id
+    end
+  end
+
+  return request_metrics
 end
 
 local function flush(premature)
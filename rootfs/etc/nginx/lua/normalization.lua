@@ -0,0 +1,128 @@
+-- Request normalization.
+--
+-- NGINX routes the requests with their normalized path, percent-decoded with
+-- the dot segments resolved and the duplicate slashes merged, but proxies the
+-- path as sent by the client. The locations with normalization redirect, deny
+-- or rewrite the requests so that the backends see the path and the headers
+-- the Ingress rules were applied to.
+--
+local ngx = ngx
+local type = type
+local ipairs = ipairs
+local pairs = pairs
+local string_find = string.find
+local string_lower = string.lower
+local table_concat = table.concat
+
+-- the headers the backends could read from either the first or the last of
+-- several values
+local SINGLE_VALUE_HEADERS = {
+  ["host"] = true,
+  ["content-length"] = true,
+  ["content-type"] = true,
+  ["transfer-encoding"] = true,
+  ["authorization"] = true,
+  ["proxy-authorization"] = true,
+  ["x-forwarded-host"] = true,
+  ["x-forwarded-proto"] = true,
+  ["x-original-url"] = true,
+  ["x-rewrite-url"] = true,
+}
+
+-- lowercase encodings of the characters a path can be traversed or cut with:
+-- dot, slash, backslash, NUL, and percent for the double encodings
+local ENCODED_TRAVERSAL_SEQUENCES = { "%2e", "%2f", "%5c", "%00", "%25" }
+
+-- the requests with more headers are denied rather than partially checked
+local MAX_HEADERS = 100
+
+local _M = {}
+
+local function raw_path()
+  local request_uri = ngx.var.request_uri
+  local query_start = string_find(request_uri, "?", 1, true)
+  if query_start then
+    return request_uri:sub(1, query_start - 1), request_uri:sub(query_start)
+  end
+
+  return request_uri, ""
+end
+
+-- has_encoded_traversal returns true when the path contains an encoded
+-- traversal sequence or a NUL byte
+function _M.has_encoded_traversal(path)
+  if string_find(path, "\0", 1, true) then
+    return true
+  end
+
+  local lower = string_lower(path)
+  for _, sequence in ipairs(ENCODED_TRAVERSAL_SEQUENCES) do
+    if string_find(lower, sequence, 1, true) then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- normalize_headers merges the values of the headers sent several times, it
+-- returns the name of the first single value header sent several times. The
+-- names are lowercased by get_headers, merging the headers sent several times
+-- with a different case.
+local function normalize_headers()
+  local headers, err = ngx.req.get_headers(MAX_HEADERS)
+  if err == "truncated" then
+    return "more than " .. MAX_HEADERS .. " headers"
+  end
+
+  for name, value in pairs(headers) do
+    if type(value) == "table" then
+      if SINGLE_VALUE_HEADERS[name] then
+        return name
+      end
+
+      local separator = ", "
+      if name == "cookie" then
+        separator = "; "
+      end
+      ngx.req.set_header(name, table_concat(value, separator))
+    end
+  end
+
+  return nil
+end
+
+function _M.rewrite(location_config)
+  if not location_config then
+    return
+  end
+
+  local path, query = raw_path()
+
+  if location_config.reject_encoded_traversal and _M.has_encoded_traversal(path) then
+    ngx.log(ngx.INFO, "rejecting request of ", ngx.var.remote_addr,
+      ": encoded traversal sequence in the path ", path)
+    return ngx.exit(ngx.HTTP_BAD_REQUEST)
+  end
+
+  if location_config.duplicate_headers then
+    local duplicate = normalize_headers()
+    if duplicate then
+      ngx.log(ngx.INFO, "rejecting request of ", ngx.var.remote_addr, ": duplicate header ", duplicate)
+      return ngx.exit(ngx.HTTP_BAD_REQUEST)
+    end
+  end
+
+  if location_config.merge_slashes and string_find(path, "//", 1, true) then
+    -- the redirect keeps the method, the body and the encoding of the path
+    return ngx.redirect(path:gsub("/+", "/") .. query, ngx.HTTP_PERMANENT_REDIRECT)
+  end
+
+  if location_config.percent_encoding then
+    -- a changed URI is proxied as the normalized path NGINX routed the request
+    -- with, instead of the path of the client
+    ngx.req.set_uri(ngx.var.uri)
+  end
+end
+
+return _M
@@ -0,0 +1,155 @@
+-- OCSP stapling status.
+--
+-- The outcome of the last OCSP fetch of every certificate is kept in a shared
+-- dictionary, with the thisUpdate and nextUpdate times of the last valid
+-- response and the number of consecutive failed fetches. The controller reads
+-- it per host from /configuration/ocsp to expose the OCSP metrics and to
+-- report the certificates failing to be stapled.
+--
+local cjson = require("cjson.safe")
+local ffi = require("ffi")
+
+local ngx = ngx
+local C = ffi.C
+local ipairs = ipairs
+local tostring = tostring
+local table_insert = table.insert
+
+ffi.cdef[[
+void *d2i_OCSP_RESPONSE(void **a, const unsigned char **in, long len);
+void OCSP_RESPONSE_free(void *resp);
+void *OCSP_response_get1_basic(void *resp);
+void OCSP_BASICRESP_free(void *bs);
+void *OCSP_resp_get0(void *bs, int idx);
+int OCSP_single_get0_status(void *single, int *reason, void **revtime,
+                            void **thisupd, void **nextupd);
+int ASN1_TIME_diff(int *pday, int *psec, const void *from, const void *to);
+]]
+
+local _M = {}
+
+local function dict()
+  return ngx.shared.ocsp_status
+end
+
+-- to_unix_time converts an ASN1_GENERALIZEDTIME to a Unix time
+local function to_unix_time(asn1_time)
+  if asn1_time == nil then
+    return nil
+  end
+
+  local day, sec = ffi.new("int[1]"), ffi.new("int[1]")
+  -- a nil from is the current time
+  if C.ASN1_TIME_diff(day, sec, nil, asn1_time) ~= 1 then
+    return nil
+  end
+
+  return ngx.time() + day[0] * 86400 + sec[0]
+end
+
+-- update_times returns the thisUpdate and nextUpdate times of the first
+-- response of a DER encoded OCSP response. nextUpdate is nil when the
+-- responder does not set it.
+function _M.update_times(der_response)
+  local buf = ffi.new("const unsigned char *[1]", ffi.cast("const unsigned char *", der_response))
+  local resp = C.d2i_OCSP_RESPONSE(nil, buf, #der_response)
+  if resp == nil then
+    return nil, nil, "failed to parse OCSP response"
+  end
+  resp = ffi.gc(resp, C.OCSP_RESPONSE_free)
+
+  local bs = C.OCSP_response_get1_basic(resp)
+  if bs == nil then
+    return nil, nil, "OCSP response is not a basic response"
+  end
+  bs = ffi.gc(bs, C.OCSP_BASICRESP_free)
+
+  local single = C.OCSP_resp_get0(bs, 0)
+  if single == nil then
+    return nil, nil, "OCSP response does not contain any certificate status"
+  end
+
+  local this_update, next_update = ffi.new("void *[1]"), ffi.new("void *[1]")
+  if C.OCSP_single_get0_status(single, nil, nil, this_update, next_update) < 0 then
+    return nil, nil, "failed to get the certificate status of the OCSP response"
+  end
+
+  return to_unix_time(this_update[0]), to_unix_time(next_update[0])
+end
+
+local function get(uid)
+  local status = dict():get(uid)
+  if not status then
+    return {}
+  end
+
+  return cjson.decode(status) or {}
+end
+
+local function set(uid, status)
+  local ok, err, forcible = dict():set(uid, cjson.encode(status))
+  if not ok then
+    ngx.log(ngx.ERR, "ocsp_status:set failed " .. tostring(err))
+  end
+  if forcible then
+    ngx.log(ngx.NOTICE, "removed an existing item when saving OCSP status, ",
+      "consider increasing shared dictionary size for 'ocsp_status'")
+  end
+end
+
+-- success records a valid OCSP response fetched for the certificate and
+-- returns its nextUpdate time, if any
+function _M.success(uid, der_response)
+  local this_update, next_update, err = _M.update_times(der_response)
+  if err then
+    ngx.log(ngx.NOTICE, "could not get the update times of the OCSP response: ", err)
+  end
+
+  set(uid, {
+    success = true,
+    fetchedAt = ngx.time(),
+    thisUpdate = this_update,
+    nextUpdate = next_update,
+    failures = 0,
+  })
+
+  return next_update
+end
+
+-- failure records a failed OCSP fetch for the certificate. The update times
+-- of the last valid response are kept, it is stapled until it expires.
+function _M.failure(uid, err)
+  local status = get(uid)
+  status.success = false
+  status.error = tostring(err)
+  status.fetchedAt = ngx.time()
+  status.failures = (status.failures or 0) + 1
+
+  set(uid, status)
+end
+
+function _M.remove(uid)
+  dict():delete(uid)
+end
+
+-- get_hosts returns the status of the certificates of the hosts, in the
+-- format of OCSPStatus in internal/nginx/ocsp.go
+function _M.get_hosts(certificate_servers)
+  local hosts = {}
+
+  for _, hostname in ipairs(certificate_servers:get_keys(0)) do
+    local uid = certificate_servers:get(hostname)
+    local status = uid and dict():get(uid)
+    if status then
+      status = cjson.decode(status)
+      if status then
+        status.hostname = hostname
+        table_insert(hosts, status)
+      end
+    end
+  end
+
+  return hosts
+end
+
+return _M
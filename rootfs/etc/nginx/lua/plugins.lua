@@ -1,17 +1,66 @@
 local require = require
 local ngx = ngx
 local ipairs = ipairs
+local pairs = pairs
+local type = type
 local string_format = string.format
 local ngx_log = ngx.log
 local INFO = ngx.INFO
 local ERR = ngx.ERR
 local pcall = pcall
+local cjson = require("cjson.safe")
 
 local _M = {}
 local MAX_NUMBER_OF_PLUGINS = 20
+
+-- the phases plugins.run is called in
+local PHASES = {
+  init_worker = true,
+  rewrite = true,
+  header_filter = true,
+  body_filter = true,
+  log = true,
+}
+
 local plugins = {}
+-- the names of the loaded plugins, to load them once
+local loaded = {}
+-- the JSON configurations of the locations decoded once per worker, they
+-- are the ones of the annotations of nginx.conf
+local decoded_configs = {}
+
+-- plugin_phases returns the set of phases the plugin runs in: the declared
+-- phases of the plugin, or its functions named after a phase
+local function plugin_phases(plugin)
+  local phases = {}
+
+  if plugin.phases == nil then
+    for phase in pairs(PHASES) do
+      if type(plugin[phase]) == "function" then
+        phases[phase] = true
+      end
+    end
+    return phases
+  end
+
+  if type(plugin.phases) ~= "table" then
+    return nil, "phases must be a list of phases"
+  end
+
+  for _, phase in ipairs(plugin.phases) do
+    if not PHASES[phase] then
+      return nil, string_format("unknown phase \"%s\"", phase)
+    end
+    if type(plugin[phase]) ~= "function" then
+      return nil, string_format("no function for the declared phase \"%s\"", phase)
+    end
+    phases[phase] = true
+  end
+
+  return phases
+end
 
-local function load_plugin(name)
+local function load_plugin(name, global)
   local path = string_format("plugins.%s.main", name)
 
   local ok, plugin = pcall(require, path)
@@ -19,40 +68,88 @@ local function load_plugin(name)
     ngx_log(ERR, string_format("error loading plugin \"%s\": %s", path, plugin))
     return
   end
+
+  local phases, err = plugin_phases(plugin)
+  if not phases then
+    ngx_log(ERR, string_format("error loading plugin \"%s\": %s", path, err))
+    return
+  end
+
   local index = #plugins
   if (plugin.name == nil or plugin.name == '') then
     plugin.name = name
   end
-  plugins[index + 1] = plugin
+  plugins[index + 1] = { name = name, module = plugin, phases = phases, global = global }
 end
 
-function _M.init(names)
+-- init loads the plugins enabled in all the locations, then the plugins only
+-- enabled by the annotations of some locations. They run in this order.
+function _M.init(names, ingress_names)
   local count = 0
-  for _, name in ipairs(names) do
-    if count >= MAX_NUMBER_OF_PLUGINS then
-      ngx_log(ERR, "the total number of plugins exceed the maximum number: ", MAX_NUMBER_OF_PLUGINS)
-      break
+  local function load(plugin_names, global)
+    for _, name in ipairs(plugin_names or {}) do
+      if count >= MAX_NUMBER_OF_PLUGINS then
+        ngx_log(ERR, "the total number of plugins exceed the maximum number: ", MAX_NUMBER_OF_PLUGINS)
+        return
+      end
+      if not loaded[name] then
+        loaded[name] = true
+        load_plugin(name, global)
+        count = count + 1 -- ignore loading failure, just count the total
+      end
     end
-    load_plugin(name)
-    count = count + 1 -- ignore loading failure, just count the total
   end
+
+  load(names, true)
+  load(ingress_names, false)
 end
 
-function _M.run()
+local function decode_config(name, value)
+  if type(value) ~= "string" then
+    return nil
+  end
+
+  local config = decoded_configs[value]
+  if config == nil then
+    local err
+    config, err = cjson.decode(value)
+    if not config then
+      ngx_log(ERR, string_format("invalid configuration of plugin \"%s\": %s", name, err))
+      config = false
+    end
+    decoded_configs[value] = config
+  end
+
+  return config or nil
+end
+
+-- run runs the plugins enabled in the location in the current phase. The
+-- location plugins map the names of the plugins to false when they are
+-- disabled, true or their JSON configuration when they are enabled.
+function _M.run(location_plugins)
   local phase = ngx.get_phase()
+  location_plugins = location_plugins or {}
 
   for _, plugin in ipairs(plugins) do
-    if plugin[phase] then
-      ngx_log(INFO, string_format("running plugin \"%s\" in phase \"%s\"", plugin.name, phase))
+    local location_plugin = location_plugins[plugin.name]
+    local enabled
+    if location_plugin == nil then
+      enabled = plugin.global
+    else
+      enabled = location_plugin ~= false
+    end
+
+    if enabled and plugin.phases[phase] then
+      ngx_log(INFO, string_format("running plugin \"%s\" in phase \"%s\"", plugin.module.name, phase))
 
       -- TODO: consider sandboxing this, should we?
       -- probably yes, at least prohibit plugin from accessing env vars etc
       -- but since the plugins are going to be installed by ingress-nginx
       -- operator they can be assumed to be safe also
-      local ok, err = pcall(plugin[phase])
+      local ok, err = pcall(plugin.module[phase], decode_config(plugin.name, location_plugin))
       if not ok then
         ngx_log(ERR, string_format("error while running plugin \"%s\" in phase \"%s\": %s",
-            plugin.name, phase, err))
+            plugin.module.name, phase, err))
       end
     end
   end
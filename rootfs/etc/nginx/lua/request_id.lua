@@ -0,0 +1,49 @@
+-- Request ID generation.
+--
+-- The request IDs are generated in the UUID format from the 128 random bits
+-- of $request_id: a random UUID, version 4, or a UUID starting with the Unix
+-- time of the request in milliseconds, version 7, sorting the request IDs by
+-- time. The servers set $generated_request_id once per request, the value is
+-- kept by the internal redirects.
+--
+local ngx = ngx
+local tonumber = tonumber
+local math_floor = math.floor
+local string_format = string.format
+local string_sub = string.sub
+
+local _M = {}
+
+-- uuid formats the random hexadecimal digits as a UUID of the version, the
+-- first 12 digits being replaced by the timestamp, if any
+local function uuid(version, random, timestamp)
+  local first = timestamp or string_sub(random, 1, 12)
+  -- the two most significant bits of the variant are 10
+  local variant = string_format("%x", 8 + tonumber(string_sub(random, 17, 17), 16) % 4)
+
+  return string_format("%s-%s-%s%s-%s%s-%s",
+    string_sub(first, 1, 8), string_sub(first, 9, 12),
+    version, string_sub(random, 14, 16),
+    variant, string_sub(random, 18, 20),
+    string_sub(random, 21, 32))
+end
+
+-- generate returns the request ID of the request in the format, uuid4 or
+-- uuid7, or the one already generated before an internal redirect
+function _M.generate(format)
+  local request_id = ngx.var.generated_request_id
+  if request_id and request_id ~= "" then
+    return request_id
+  end
+
+  local random = ngx.var.request_id
+  if format == "uuid4" then
+    return uuid("4", random)
+  elseif format == "uuid7" then
+    return uuid("7", random, string_format("%012x", math_floor(ngx.now() * 1000)))
+  end
+
+  return random
+end
+
+return _M
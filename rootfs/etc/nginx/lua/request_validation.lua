@@ -0,0 +1,134 @@
+-- Strict request validation.
+--
+-- A request that NGINX and a proxy in front of or behind it read differently
+-- can smuggle a second request in its body. The locations with strict request
+-- validation deny the ambiguous requests with a 400 response:
+--
+-- * length-conflict: a Content-Length header with a Transfer-Encoding header,
+--   or several different Content-Length headers
+-- * line-folding: a header continued on the next line (obsolete line folding)
+-- * invalid-header-name: a header name with characters other than the token
+--   characters, which NGINX ignores with ignore_invalid_headers
+-- * absolute-uri-mismatch: an absolute URI in the request line whose host is
+--   not the one of the Host header
+-- * too-many-headers: more than 100 headers, which are not all checked
+--
+local ngx = ngx
+local type = type
+local ipairs = ipairs
+local string_find = string.find
+local string_lower = string.lower
+local string_match = string.match
+local ngx_re_split = require("ngx.re").split
+
+-- the requests with more headers are denied rather than partially checked
+local MAX_HEADERS = 100
+
+local _M = {}
+
+local function is_token(name)
+  return string_match(name, "^[%w!#$%%&'*+%-.^_`|~]+$") ~= nil
+end
+
+-- check_raw_header returns the violation of the header lines of the request
+-- as received, nil when they are valid
+function _M.check_raw_header(raw_header)
+  local lines = ngx_re_split(raw_header, "\r?\n", "jo")
+  for _, line in ipairs(lines) do
+    if line ~= "" then
+      local first = line:sub(1, 1)
+      if first == " " or first == "\t" then
+        return "line-folding"
+      end
+
+      local colon = string_find(line, ":", 1, true)
+      if not colon or not is_token(line:sub(1, colon - 1)) then
+        return "invalid-header-name"
+      end
+    end
+  end
+
+  return nil
+end
+
+-- check_length returns the violation of the length headers of the request,
+-- nil when they are valid
+function _M.check_length(headers)
+  local content_length = headers["content-length"]
+  if not content_length then
+    return nil
+  end
+
+  if headers["transfer-encoding"] then
+    return "length-conflict"
+  end
+
+  if type(content_length) == "table" then
+    for _, value in ipairs(content_length) do
+      if value ~= content_length[1] then
+        return "length-conflict"
+      end
+    end
+  end
+
+  return nil
+end
+
+-- check_absolute_uri returns the violation of an absolute URI of the request
+-- line, nil when the request line has a path or the host of the Host header
+function _M.check_absolute_uri(request_line, host)
+  local target = string_match(request_line or "", "^%S+%s+(%S+)")
+  if not target then
+    return nil
+  end
+
+  local authority = string_match(target, "^%a[%w+.-]*://([^/?#]*)")
+  if not authority then
+    return nil
+  end
+
+  if not host or string_lower(authority) ~= string_lower(host) then
+    return "absolute-uri-mismatch"
+  end
+
+  return nil
+end
+
+local function violation()
+  -- the HTTP/2 and HTTP/3 headers are framed, they cannot be folded or
+  -- smuggled, and their raw header is not available
+  if (ngx.req.http_version() or 1) < 2 then
+    local raw_header, err = ngx.req.raw_header(true)
+    if not raw_header then
+      ngx.log(ngx.ERR, "could not read the raw header of the request: ", err)
+      return nil
+    end
+
+    local raw_violation = _M.check_raw_header(raw_header)
+    if raw_violation then
+      return raw_violation
+    end
+  end
+
+  local headers, err = ngx.req.get_headers(MAX_HEADERS)
+  if err == "truncated" then
+    return "too-many-headers"
+  end
+
+  return _M.check_length(headers) or _M.check_absolute_uri(ngx.var.request, ngx.var.http_host)
+end
+
+function _M.rewrite(strict)
+  if not strict then
+    return
+  end
+
+  local request_violation = violation()
+  if request_violation then
+    ngx.log(ngx.INFO, "rejecting request of ", ngx.var.remote_addr, ": ", request_violation)
+    ngx.ctx.request_violation = request_violation
+    return ngx.exit(ngx.HTTP_BAD_REQUEST)
+  end
+end
+
+return _M
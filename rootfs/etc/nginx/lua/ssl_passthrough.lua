@@ -0,0 +1,68 @@
+-- SSL passthrough.
+--
+-- The HTTPS port is served by the stream module, which reads the server name
+-- of the TLS ClientHello with ssl_preread, and then passes the accepted
+-- connection with the stream pass directive:
+--
+--   HTTPS port => SSL proxy port of the http module
+--   HTTPS port => passthrough socket -> endpoint of the passthrough backend
+--
+-- Passing a connection hands it over to another listener of NGINX without
+-- connecting to it: the client address and the data read by ssl_preread are
+-- kept, and the connections of the SSL passthrough servers are only proxied
+-- once, as is, to the endpoints of their backend. The PROXY protocol header,
+-- when enabled, is read once on the HTTPS port.
+--
+-- The backends are named after the server name and are synced dynamically
+-- with the other stream backends.
+--
+local tcp_udp_balancer = require("tcp_udp_balancer")
+
+local ngx = ngx
+local string_lower = string.lower
+local tostring = tostring
+
+local BACKEND_PREFIX = "ssl-passthrough-"
+
+local _M = {}
+
+-- backend_name returns the name of the backend of the server name of the
+-- connection, or nil when it is not an SSL passthrough server
+function _M.backend_name()
+  local server_name = ngx.var.ssl_preread_server_name
+  if not server_name or server_name == "" then
+    return nil
+  end
+
+  local backend_name = BACKEND_PREFIX .. string_lower(server_name)
+  if not tcp_udp_balancer.has_backend(backend_name) then
+    return nil
+  end
+
+  return backend_name
+end
+
+-- preread selects the listener the connection received on the HTTPS port is
+-- passed to
+function _M.preread(passthrough_address, ssl_proxy_address)
+  if _M.backend_name() then
+    ngx.var.ssl_passthrough_upstream = passthrough_address
+  else
+    ngx.var.ssl_passthrough_upstream = ssl_proxy_address
+  end
+end
+
+-- route selects the backend of a connection passed to the passthrough
+-- socket
+function _M.route()
+  local backend_name = _M.backend_name()
+  if not backend_name then
+    ngx.log(ngx.WARN, "no SSL passthrough backend for server name ",
+            tostring(ngx.var.ssl_preread_server_name))
+    return ngx.exit(ngx.ERROR)
+  end
+
+  ngx.var.proxy_upstream_name = backend_name
+end
+
+return _M
@@ -1,9 +1,10 @@
 local ngx_balancer = require("ngx.balancer")
 local cjson = require("cjson.safe")
 local util = require("util")
-local dns_lookup = require("util.dns").lookup
+local external_name = require("util.external_name")
 local configuration = require("tcp_udp_configuration")
 local round_robin = require("balancer.round_robin")
+local chash = require("balancer.chash")
 
 local ngx = ngx
 local table = table
@@ -22,7 +23,9 @@ local BACKENDS_FORCE_SYNC_INTERVAL = 30
 
 local DEFAULT_LB_ALG = "round_robin"
 local IMPLEMENTATIONS = {
-  round_robin = round_robin
+  round_robin = round_robin,
+  -- used for the session affinity of the stream services
+  chash = chash,
 }
 
 local PROHIBITED_LOCALHOST_PORT = configuration.prohibited_localhost_port or '10246'
@@ -30,6 +33,8 @@ local PROHIBITED_PEER_PATTERN = "^127.*:" .. PROHIBITED_LOCALHOST_PORT .. "$"
 
 local _M = {}
 local balancers = {}
+-- names of all the synced backends, including the ones without endpoints
+local backend_names = {}
 local backends_with_external_name = {}
 local backends_last_synced_at = 0
 
@@ -46,19 +51,6 @@ local function get_implementation(backend)
   return implementation
 end
 
-local function resolve_external_names(original_backend)
-  local backend = util.deepcopy(original_backend)
-  local endpoints = {}
-  for _, endpoint in ipairs(backend.endpoints) do
-    local ips = dns_lookup(endpoint.address)
-    for _, ip in ipairs(ips) do
-      table.insert(endpoints, {address = ip, port = endpoint.port})
-    end
-  end
-  backend.endpoints = endpoints
-  return backend
-end
-
 local function format_ipv6_endpoints(endpoints)
   local formatted_endpoints = {}
   for _, endpoint in ipairs(endpoints) do
@@ -71,18 +63,22 @@ local function format_ipv6_endpoints(endpoints)
   return formatted_endpoints
 end
 
-local function is_backend_with_external_name(backend)
-  local serv_type = backend.service and backend.service.spec
-                      and backend.service.spec["type"]
-  return serv_type == "ExternalName"
-end
-
 local function sync_backend(backend)
+  -- the connections of a service without endpoints are closed until it has
+  -- endpoints again
   if not backend.endpoints or #backend.endpoints == 0 then
+    balancers[backend.name] = nil
     return
   end
 
   ngx.log(ngx.INFO, "sync tcp/udp backend: ", backend.name)
+
+  if external_name.is_backend_with_external_name(backend) then
+    backend = external_name.resolve(backend)
+  end
+
+  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
+
   local implementation = get_implementation(backend)
   local balancer = balancers[backend.name]
 
@@ -101,12 +97,6 @@ local function sync_backend(backend)
     return
   end
 
-  if is_backend_with_external_name(backend) then
-    backend = resolve_external_names(backend)
-  end
-
-  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
-
   balancer:sync(backend)
 end
 
@@ -116,8 +106,12 @@ local function sync_backends()
   local current_timestamp = ngx.time()
   if current_timestamp - backends_last_synced_at < BACKENDS_FORCE_SYNC_INTERVAL
       and raw_backends_last_synced_at <= backends_last_synced_at then
+    -- the backends with external names are synced when their host names
+    -- resolve to other addresses
     for _, backend_with_external_name in pairs(backends_with_external_name) do
-      sync_backend(backend_with_external_name)
+      if external_name.is_outdated(backend_with_external_name) then
+        sync_backend(backend_with_external_name)
+      end
     end
     return
   end
@@ -125,6 +119,7 @@ local function sync_backends()
   local backends_data = configuration.get_backends_data()
   if not backends_data then
     balancers = {}
+    backend_names = {}
     return
   end
 
@@ -135,21 +130,27 @@ local function sync_backends()
   end
 
   local balancers_to_keep = {}
+  local new_backend_names = {}
   for _, new_backend in ipairs(new_backends) do
-    sync_backend(new_backend)
-    balancers_to_keep[new_backend.name] = balancers[new_backend.name]
-    if is_backend_with_external_name(new_backend) then
+    if external_name.is_backend_with_external_name(new_backend) then
       local backend_with_external_name = util.deepcopy(new_backend)
       backends_with_external_name[backend_with_external_name.name] = backend_with_external_name
+      sync_backend(backend_with_external_name)
+    else
+      sync_backend(new_backend)
     end
+    balancers_to_keep[new_backend.name] = balancers[new_backend.name]
+    new_backend_names[new_backend.name] = true
   end
 
   for backend_name, _ in pairs(balancers) do
     if not balancers_to_keep[backend_name] then
       balancers[backend_name] = nil
       backends_with_external_name[backend_name] = nil
+      external_name.remove(backend_name)
     end
   end
+  backend_names = new_backend_names
   backends_last_synced_at = raw_backends_last_synced_at
 end
 
@@ -163,6 +164,10 @@ local function get_balancer()
   return balancer
 end
 
+function _M.has_backend(backend_name)
+  return backend_names[backend_name] ~= nil
+end
+
 function _M.init_worker()
   sync_backends() -- when worker starts, sync backends without delay
   local _, err = ngx.timer.every(BACKENDS_SYNC_INTERVAL, sync_backends)
@@ -175,6 +180,7 @@ end
 function _M.balance()
   local balancer = get_balancer()
   if not balancer then
+    ngx.log(ngx.WARN, "no endpoint available for tcp/udp backend: ", ngx.var.proxy_upstream_name)
     return
   end
 
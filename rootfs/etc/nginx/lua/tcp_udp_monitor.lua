@@ -0,0 +1,98 @@
+-- TCP and UDP services metrics.
+--
+-- The sessions of the TCP and UDP services are batched by every worker and
+-- sent to the controller every second, through the socket of the request
+-- metrics of monitor.lua, as {"stream": {...}} objects.
+--
+local ngx = ngx
+local tonumber = tonumber
+local tostring = tostring
+local assert = assert
+local socket = ngx.socket.tcp
+local cjson = require("cjson.safe")
+local new_tab = require "table.new"
+local clear_tab = require "table.clear"
+
+-- if an Nginx worker handles more than (MAX_BATCH_SIZE/FLUSH_INTERVAL)
+-- sessions per second then it will start dropping metrics
+local MAX_BATCH_SIZE = 10000
+local FLUSH_INTERVAL = 1 -- second
+
+local sessions_batch = new_tab(MAX_BATCH_SIZE, 0)
+local sessions_count = 0
+
+local _M = {}
+
+-- services are the namespace and name of the service of the upstreams, by
+-- upstream name
+_M.services = {}
+
+local function send(payload)
+  local s = assert(socket())
+  assert(s:connect("unix:/tmp/nginx/prometheus-nginx.socket"))
+  assert(s:send(payload))
+  assert(s:close())
+end
+
+local function session_metrics(port, protocol)
+  local service = _M.services[ngx.var.proxy_upstream_name] or {}
+
+  return {
+    stream = {
+      protocol = protocol,
+      port = port,
+      namespace = service.namespace or "",
+      service = service.name or "",
+      status = ngx.var.status,
+
+      bytesReceived = tonumber(ngx.var.bytes_received) or -1,
+      bytesSent = tonumber(ngx.var.bytes_sent) or -1,
+      sessionTime = tonumber(ngx.var.session_time) or -1,
+    },
+  }
+end
+
+local function flush(premature)
+  if premature then
+    return
+  end
+
+  if sessions_count == 0 then
+    return
+  end
+
+  local payload, err = cjson.encode(sessions_batch)
+  sessions_count = 0
+  clear_tab(sessions_batch)
+  if not payload then
+    ngx.log(ngx.ERR, "error when encoding stream metrics: ", tostring(err))
+    return
+  end
+
+  send(payload)
+end
+
+function _M.init_worker()
+  local _, err = ngx.timer.every(FLUSH_INTERVAL, flush)
+  if err then
+    ngx.log(ngx.ERR, "error when setting up timer.every: ", tostring(err))
+  end
+end
+
+-- call records the session of the TCP or UDP service exposed on the port
+function _M.call(port, protocol)
+  if sessions_count >= MAX_BATCH_SIZE then
+    ngx.log(ngx.WARN, "omitting metrics for the session, current batch is full")
+    return
+  end
+
+  sessions_count = sessions_count + 1
+  sessions_batch[sessions_count] = session_metrics(port, protocol)
+end
+
+setmetatable(_M, {__index = {
+  flush = flush,
+  get_sessions_batch = function() return sessions_batch end,
+}})
+
+return _M
@@ -0,0 +1,54 @@
+-- Routing of TLS TCP streams based on the server name.
+--
+-- The server name sent by the client in the TLS ClientHello is read by
+-- ssl_preread, without terminating TLS, and the connection is proxied to the
+-- upstream of the matching hostname. Exact hostnames take precedence over
+-- wildcard hostnames, and the connections without a matching server name go
+-- to the default upstream of the port, if any.
+--
+local ngx = ngx
+local string_find = string.find
+local string_lower = string.lower
+local string_sub = string.sub
+local tostring = tostring
+
+local _M = {}
+
+-- upstream_name returns the name of the upstream of the server name of the
+-- connection, or the default upstream
+function _M.upstream_name(routes, default)
+  local server_name = ngx.var.ssl_preread_server_name
+  if not server_name or server_name == "" then
+    return default
+  end
+
+  server_name = string_lower(server_name)
+  local upstream_name = routes[server_name]
+  if upstream_name then
+    return upstream_name
+  end
+
+  -- a wildcard only matches a single label
+  local dot = string_find(server_name, ".", 1, true)
+  if dot then
+    upstream_name = routes["*" .. string_sub(server_name, dot)]
+    if upstream_name then
+      return upstream_name
+    end
+  end
+
+  return default
+end
+
+function _M.route(routes, default)
+  local upstream_name = _M.upstream_name(routes, default)
+  if not upstream_name then
+    ngx.log(ngx.INFO, "no stream route for server name ",
+            tostring(ngx.var.ssl_preread_server_name))
+    return ngx.exit(ngx.ERROR)
+  end
+
+  ngx.var.proxy_upstream_name = upstream_name
+end
+
+return _M
@@ -0,0 +1,63 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function mock_request(status, duration)
+  mock_ngx({
+    status = status,
+    var = {},
+    now = function() return 10 + duration end,
+    req = { start_time = function() return 10 end },
+  })
+end
+
+describe("access log sampling", function()
+  local sampling = { rate = 0.01, min_status = 500, slow_threshold = 2 }
+
+  local function require_with_random(value)
+    stub(math, "random", value)
+    return require("access_log_sampling")
+  end
+
+  after_each(function()
+    reset_ngx()
+    math.random:revert()
+    package.loaded["access_log_sampling"] = nil
+  end)
+
+  it("logs the requests with an error status", function()
+    mock_request(502, 0)
+    local access_log_sampling = require_with_random(0.5)
+
+    assert.is_true(access_log_sampling.sampled(sampling))
+  end)
+
+  it("logs the slow requests", function()
+    mock_request(200, 3)
+    local access_log_sampling = require_with_random(0.5)
+
+    assert.is_true(access_log_sampling.sampled(sampling))
+  end)
+
+  it("samples the other requests with the rate", function()
+    mock_request(200, 0)
+    local access_log_sampling = require_with_random(0.005)
+
+    assert.is_true(access_log_sampling.sampled(sampling))
+  end)
+
+  it("marks the requests not sampled", function()
+    mock_request(200, 0)
+    local access_log_sampling = require_with_random(0.5)
+
+    access_log_sampling.header(sampling)
+    assert.equal("0", ngx.var.access_log_sampled)
+  end)
+end)
@@ -0,0 +1,54 @@
+describe("acme", function()
+  local acme = require("acme")
+  local exit_status, body
+
+  local function mock_ngx(uri)
+    exit_status, body = nil, nil
+
+    local _ngx = {
+      var = { uri = uri },
+      header = {},
+      print = function(content) body = content end,
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = ngx })
+    _G.ngx = _ngx
+  end
+
+  after_each(function()
+    reset_ngx()
+    ngx.shared.acme_challenges:flush_all()
+  end)
+
+  it("serves the key authorization of known tokens", function()
+    acme.set_challenge("token1", "token1.thumbprint")
+
+    mock_ngx("/.well-known/acme-challenge/token1")
+    acme.rewrite()
+
+    assert.are.equal(ngx.HTTP_OK, exit_status)
+    assert.are.equal("token1.thumbprint", body)
+    assert.are.equal("text/plain", ngx.header["Content-Type"])
+  end)
+
+  it("leaves unknown tokens and other locations to the Ingresses", function()
+    acme.set_challenge("token1", "token1.thumbprint")
+
+    mock_ngx("/.well-known/acme-challenge/token2")
+    acme.rewrite()
+    assert.is_nil(exit_status)
+
+    mock_ngx("/token1")
+    acme.rewrite()
+    assert.is_nil(exit_status)
+  end)
+
+  it("stops serving removed tokens", function()
+    acme.set_challenge("token1", "token1.thumbprint")
+    acme.remove_challenge("token1")
+
+    mock_ngx("/.well-known/acme-challenge/token1")
+    acme.rewrite()
+    assert.is_nil(exit_status)
+  end)
+end)
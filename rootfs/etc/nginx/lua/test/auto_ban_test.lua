@@ -0,0 +1,95 @@
+local original_ngx = ngx
+
+describe("auto_ban", function()
+  local auto_ban, exit_status, ctx
+
+  local config = {
+    enabled = true,
+    status_codes = { 401, 403, 429 },
+    threshold = 3,
+    window = 60,
+    duration = 600,
+  }
+
+  local function mock_ngx(status)
+    exit_status = nil
+    ctx = {}
+
+    local _ngx = {
+      var = { remote_addr = "192.0.2.10", status = status },
+      ctx = ctx,
+      exit = function(code) exit_status = code end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+  end
+
+  local function respond(status, times)
+    for _ = 1, times do
+      mock_ngx(status)
+      auto_ban.rewrite(config)
+      auto_ban.log(config)
+    end
+  end
+
+  before_each(function()
+    auto_ban = require_without_cache("auto_ban")
+  end)
+
+  after_each(function()
+    _G.ngx = original_ngx
+    ngx.shared.auto_ban:flush_all()
+  end)
+
+  it("bans the clients reaching the threshold", function()
+    respond("401", 2)
+    assert.is_false(auto_ban.is_banned("192.0.2.10"))
+
+    respond("429", 1)
+    assert.is_true(ctx.client_banned)
+    assert.is_true(auto_ban.is_banned("192.0.2.10"))
+
+    respond("200", 1)
+    assert.are.equal(ngx.HTTP_FORBIDDEN, exit_status)
+    assert.is_true(ctx.auto_banned)
+  end)
+
+  it("does not count the other status codes", function()
+    respond("404", 5)
+    respond("200", 5)
+
+    assert.is_false(auto_ban.is_banned("192.0.2.10"))
+  end)
+
+  it("does not count the requests of the banned clients", function()
+    respond("403", 3)
+    respond("403", 3)
+
+    assert.is_nil(ctx.client_banned)
+    assert.is_nil(ngx.shared.auto_ban:get("count:192.0.2.10"))
+  end)
+
+  it("does not count the requests of the banned clients after an internal redirect", function()
+    respond("403", 3)
+
+    -- the error page of the denied request is served with a new ngx.ctx
+    for _ = 1, 3 do
+      mock_ngx("403")
+      auto_ban.log(config)
+    end
+
+    assert.is_nil(ctx.client_banned)
+    assert.is_nil(ngx.shared.auto_ban:get("count:192.0.2.10"))
+  end)
+
+  it("does nothing when it is disabled", function()
+    local disabled = { enabled = false }
+    for _ = 1, 5 do
+      mock_ngx("401")
+      auto_ban.rewrite(disabled)
+      auto_ban.log(disabled)
+    end
+
+    assert.is_false(auto_ban.is_banned("192.0.2.10"))
+  end)
+end)
@@ -0,0 +1,169 @@
+local cookie = require("resty.cookie")
+local configuration = require("configuration")
+
+local original_ngx = ngx
+local original_cookie_new = cookie.new
+local original_get_secret = configuration.get_secret
+
+local canary
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+
+  package.loaded["balancer.canary"] = nil
+  canary = require("balancer.canary")
+end
+
+local function mock_cookie(value)
+  local o = { value = value }
+  local mock = {
+    get = function(self, n) return self.value end,
+    set = function(self, c) self.value = c.value ; self.data = c ; return true, nil end
+  }
+  setmetatable(o, mock)
+  mock.__index = mock
+
+  cookie.new = function(self) return o end
+  return o
+end
+
+describe("Canary", function()
+  local backend_name = "default-canary-app-80"
+  local policy
+
+  before_each(function()
+    mock_ngx({ var = { https = "on" } })
+    policy = { weight = 20, sticky = true, stickyCookie = "", stickyMaxAge = 0 }
+    configuration.get_secret = function(name)
+      if name == "canary_sticky_secret" then
+        return "signing-key"
+      end
+      return nil
+    end
+  end)
+
+  after_each(function()
+    _G.ngx = original_ngx
+    cookie.new = original_cookie_new
+    configuration.get_secret = original_get_secret
+  end)
+
+  describe("cookie_name()", function()
+    it("returns the configured cookie name", function()
+      policy.stickyCookie = "assignment"
+      assert.equal("assignment", canary.cookie_name(policy, backend_name))
+    end)
+
+    it("derives a cookie name from the backend name", function()
+      local name = canary.cookie_name(policy, backend_name)
+      assert.equal("canary_" .. ngx.md5(backend_name):sub(1, 8), name)
+    end)
+  end)
+
+  describe("get_assignment()", function()
+    it("returns nil when there is no cookie", function()
+      mock_cookie(nil)
+      assert.is_nil(canary.get_assignment(policy, backend_name))
+    end)
+
+    it("returns the assignment persisted by set_assignment()", function()
+      local c = mock_cookie(nil)
+
+      canary.set_assignment(policy, backend_name, true)
+      assert.is_true(canary.get_assignment(policy, backend_name))
+      assert.is_true(c.data.secure)
+      assert.is_nil(c.data.max_age)
+
+      canary.set_assignment(policy, backend_name, false)
+      assert.is_false(canary.get_assignment(policy, backend_name))
+    end)
+
+    it("ignores assignments issued for another backend", function()
+      mock_cookie(nil)
+
+      canary.set_assignment(policy, "another-backend", true)
+      assert.is_nil(canary.get_assignment(policy, backend_name))
+    end)
+
+    it("ignores tampered assignments", function()
+      local c = mock_cookie(nil)
+
+      canary.set_assignment(policy, backend_name, false)
+      c.value = c.value:gsub("^stable", "canary")
+      assert.is_nil(canary.get_assignment(policy, backend_name))
+    end)
+
+    it("ignores assignments signed with another key", function()
+      mock_cookie(nil)
+
+      canary.set_assignment(policy, backend_name, true)
+      configuration.get_secret = function() return "rotated-key" end
+      assert.is_nil(canary.get_assignment(policy, backend_name))
+    end)
+
+    it("returns nil without a secret", function()
+      local c = mock_cookie(nil)
+
+      canary.set_assignment(policy, backend_name, true)
+      configuration.get_secret = function() return nil end
+      assert.is_nil(canary.get_assignment(policy, backend_name))
+
+      c.value = nil
+      canary.set_assignment(policy, backend_name, true)
+      assert.is_nil(c.value)
+    end)
+  end)
+
+  describe("set_assignment()", function()
+    it("sets max age when configured", function()
+      local c = mock_cookie(nil)
+      policy.stickyMaxAge = 3600
+
+      canary.set_assignment(policy, backend_name, true)
+      assert.equal(3600, c.data.max_age)
+    end)
+  end)
+
+  describe("matches_geo()", function()
+    it("returns false without geo rules", function()
+      mock_ngx({ var = { geoip2_city_country_code = "DE", geoip2_city_continent_code = "EU" } })
+      assert.is_false(canary.matches_geo(policy))
+    end)
+
+    it("matches the country of the client", function()
+      policy.geoCountries = { "DE", "FR" }
+
+      mock_ngx({ var = { geoip2_city_country_code = "FR" } })
+      assert.is_true(canary.matches_geo(policy))
+
+      mock_ngx({ var = { geoip2_country_code = "DE" } })
+      assert.is_true(canary.matches_geo(policy))
+
+      mock_ngx({ var = { geoip2_city_country_code = "US" } })
+      assert.is_false(canary.matches_geo(policy))
+    end)
+
+    it("matches the continent of the client", function()
+      policy.geoContinents = { "EU" }
+
+      mock_ngx({ var = { geoip2_city_continent_code = "EU" } })
+      assert.is_true(canary.matches_geo(policy))
+
+      mock_ngx({ var = { geoip2_city_continent_code = "", geoip2_continent_code = "EU" } })
+      assert.is_true(canary.matches_geo(policy))
+
+      mock_ngx({ var = { geoip2_city_continent_code = "NA" } })
+      assert.is_false(canary.matches_geo(policy))
+    end)
+
+    it("does not match the clients which are not located", function()
+      policy.geoCountries = { "DE" }
+      policy.geoContinents = { "EU" }
+
+      mock_ngx({ var = {} })
+      assert.is_false(canary.matches_geo(policy))
+    end)
+  end)
+end)
@@ -27,5 +27,70 @@ describe("Balancer chash", function()
       local peer = instance:balance()
       assert.equal("10.184.7.40:8080", peer)
     end)
+
+    describe("with bounded loads", function()
+      local backend, instance
+
+      before_each(function()
+        ngx.var = { request_uri = "/alma/armud"}
+        local balancer_chash = require_without_cache("balancer.chash")
+
+        local points = { "10.184.7.40:8080", "10.184.7.41:8080" }
+        local resty_chash = package.loaded["resty.chash"]
+        resty_chash.new = function(self, nodes)
+          return {
+            nodes = nodes,
+            npoints = #points,
+            find = function(self, key)
+              return points[1], 1
+            end,
+            next = function(self, index)
+              local new_index = index % #points + 1
+              return points[new_index], new_index
+            end,
+          }
+        end
+
+        backend = {
+          name = "my-dummy-backend",
+          upstreamHashByConfig = {
+            ["upstream-hash-by"] = "$request_uri",
+            ["upstream-hash-by-bounded-load-factor"] = 1.25,
+          },
+          endpoints = {
+            { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 },
+            { address = "10.184.7.41", port = "8080", maxFails = 0, failTimeout = 0 },
+          }
+        }
+        instance = balancer_chash:new(backend)
+      end)
+
+      after_each(function()
+        ngx.shared.balancer_bounded_load:flush_all()
+      end)
+
+      it("returns the endpoint of the key when it is below capacity", function()
+        ngx.shared.balancer_bounded_load:set("10.184.7.40:8080", 1)
+        ngx.shared.balancer_bounded_load:set("10.184.7.41:8080", 1)
+
+        assert.equal("10.184.7.40:8080", instance:balance())
+        assert.equal(2, ngx.shared.balancer_bounded_load:get("10.184.7.40:8080"))
+      end)
+
+      it("spills over to the next endpoint when the endpoint of the key is at capacity", function()
+        ngx.shared.balancer_bounded_load:set("10.184.7.40:8080", 4)
+        ngx.shared.balancer_bounded_load:set("10.184.7.41:8080", 0)
+
+        assert.equal("10.184.7.41:8080", instance:balance())
+      end)
+
+      it("releases the endpoint after the request", function()
+        instance:balance()
+        assert.equal(1, ngx.shared.balancer_bounded_load:get("10.184.7.40:8080"))
+
+        instance:after_balance()
+        assert.equal(0, ngx.shared.balancer_bounded_load:get("10.184.7.40:8080"))
+      end)
+    end)
   end)
 end)
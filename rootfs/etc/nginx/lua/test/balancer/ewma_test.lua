@@ -81,6 +81,20 @@ describe("Balancer ewma", function()
       assert.are.equals(expected_ewma, ngx.shared.balancer_ewma:get("10.10.10.2:8080"))
       assert.are.equals(ngx_now, ngx.shared.balancer_ewma_last_touched_at:get("10.10.10.2:8080"))
     end)
+
+    it("updates EWMA stats with the configured decay time", function()
+      local configuration = require("configuration")
+      configuration.ewma_decay_time = 20
+      ngx.var = { upstream_addr = "10.10.10.2:8080", upstream_connect_time = "0.02", upstream_response_time = "0.1" }
+
+      instance:after_balance()
+      configuration.ewma_decay_time = nil
+
+      local weight = math.exp(-5 / 20)
+      local expected_ewma = 0.3 * weight + 0.12 * (1.0 - weight)
+
+      assert.are.equals(expected_ewma, ngx.shared.balancer_ewma:get(ngx.var.upstream_addr))
+    end)
   end)
 
   describe("balance()", function()
@@ -110,6 +124,22 @@ describe("Balancer ewma", function()
       assert.are.equals(0.16240233988393523723, ngx.var.balancer_ewma_score)
     end)
 
+    it("scores the endpoints without stats with the configured initial weight", function()
+      local configuration = require("configuration")
+      configuration.ewma_initial_weight = 0.5
+      local two_endpoints_backend = util.deepcopy(backend)
+      table.remove(two_endpoints_backend.endpoints, 2)
+      table.insert(two_endpoints_backend.endpoints,
+                   { address = "10.10.10.4", port = "8080", maxFails = 0, failTimeout = 0 })
+      table.remove(two_endpoints_backend.endpoints, 2)
+      local two_endpoints_instance = balancer_ewma:new(two_endpoints_backend)
+
+      local peer = two_endpoints_instance:balance()
+      configuration.ewma_initial_weight = nil
+
+      assert.equal("10.10.10.1:8080", peer)
+    end)
+
     it("doesn't pick the tried endpoint while retry", function()
       local two_endpoints_backend = util.deepcopy(backend)
       table.remove(two_endpoints_backend.endpoints, 2)
@@ -0,0 +1,87 @@
+local bit = require("bit")
+
+describe("gRPC health checks", function()
+  local healthcheck_grpc = require("balancer.healthcheck_grpc")
+
+  local function frame(frame_type, flags, stream_id, payload)
+    local length = #payload
+    return string.char(bit.band(bit.rshift(length, 16), 0xff), bit.band(bit.rshift(length, 8), 0xff),
+                       bit.band(length, 0xff), frame_type, flags, 0, 0, 0, stream_id) .. payload
+  end
+
+  local function response_message(status)
+    return string.char(0, 0, 0, 0, 2, 0x08, status)
+  end
+
+  local settings = frame(0x4, 0, 0, "")
+  local response_headers = frame(0x1, 0x4, 1, string.char(0x88))
+
+  local function mock_socket(response)
+    local sock = { sent = {}, position = 1 }
+    function sock.send(self, data)
+      table.insert(self.sent, data)
+      return #data
+    end
+    function sock.receive(self, size)
+      if self.position + size - 1 > #response then
+        return nil, "closed"
+      end
+      local data = response:sub(self.position, self.position + size - 1)
+      self.position = self.position + size
+      return data
+    end
+    return sock
+  end
+
+  it("succeeds when the server is serving", function()
+    local sock = mock_socket(settings .. response_headers .. frame(0x0, 0, 1, response_message(1)))
+
+    local ok, err = healthcheck_grpc.check(sock, "10.10.10.1:50051", "")
+    assert.is_true(ok)
+    assert.is_nil(err)
+
+    assert.are.equal("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n", sock.sent[1]:sub(1, 24))
+    assert.is_truthy(sock.sent[1]:find("/grpc.health.v1.Health/Check", 1, true))
+    -- the settings of the server are acknowledged
+    assert.are.equal(frame(0x4, 0x1, 0, ""), sock.sent[2])
+  end)
+
+  it("fails when the server is not serving", function()
+    local sock = mock_socket(settings .. response_headers .. frame(0x0, 0, 1, response_message(2)))
+
+    local ok, err = healthcheck_grpc.check(sock, "10.10.10.1:50051", "")
+    assert.is_false(ok)
+    assert.are.equal("unexpected serving status 2", err)
+  end)
+
+  it("sends the service name", function()
+    local sock = mock_socket(settings .. response_headers .. frame(0x0, 0, 1, response_message(1)))
+
+    assert.is_true(healthcheck_grpc.check(sock, "10.10.10.1:50051", "helloworld.Greeter"))
+    assert.is_truthy(sock.sent[1]:find(string.char(0x0a, 18) .. "helloworld.Greeter", 1, true))
+  end)
+
+  it("reads a response message split over several frames", function()
+    local message = response_message(1)
+    local sock = mock_socket(response_headers .. frame(0x0, 0, 1, message:sub(1, 3)) ..
+                             frame(0x0, 0, 1, message:sub(4)))
+
+    assert.is_true(healthcheck_grpc.check(sock, "10.10.10.1:50051", ""))
+  end)
+
+  it("fails when the call ends without a response", function()
+    local sock = mock_socket(settings .. frame(0x1, 0x5, 1, string.char(0x88)))
+
+    local ok, err = healthcheck_grpc.check(sock, "10.10.10.1:50051", "unknown.Service")
+    assert.is_false(ok)
+    assert.are.equal("call failed without a response", err)
+  end)
+
+  it("fails when the connection is closed", function()
+    local sock = mock_socket(settings)
+
+    local ok, err = healthcheck_grpc.check(sock, "10.10.10.1:50051", "")
+    assert.is_false(ok)
+    assert.are.equal("closed", err)
+  end)
+end)
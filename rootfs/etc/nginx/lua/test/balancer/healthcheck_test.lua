@@ -0,0 +1,209 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("Balancer health checks", function()
+  local healthcheck = require("balancer.healthcheck")
+  local ngx_now = 1543238266
+  local backend
+  local responses
+
+  local function mock_socket()
+    local sock = {}
+    function sock.settimeout() end
+    function sock.close() end
+    function sock.connect(_, host, port)
+      sock.endpoint_string = host .. ":" .. port
+      local response = responses[sock.endpoint_string]
+      if response == "refused" then
+        return nil, "connection refused"
+      end
+      return true
+    end
+    function sock.send(_, request)
+      sock.request = request
+      return #request
+    end
+    function sock.receive()
+      return responses[sock.endpoint_string]
+    end
+    return sock
+  end
+
+  before_each(function()
+    responses = {}
+    mock_ngx({
+      now = function() return ngx_now end,
+      socket = { tcp = mock_socket },
+    })
+    package.loaded["balancer.healthcheck"] = nil
+    healthcheck = require("balancer.healthcheck")
+
+    backend = {
+      name = "namespace-service-port",
+      healthCheck = {
+        type = "http",
+        path = "/healthz",
+        interval = 5,
+        timeout = 1,
+        healthyThreshold = 2,
+        unhealthyThreshold = 2,
+      },
+      endpoints = {
+        { address = "10.10.10.1", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.2", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.3", port = "8080", maxFails = 0, failTimeout = 0 },
+      }
+    }
+    healthcheck.sync(backend)
+  end)
+
+  after_each(function()
+    reset_ngx()
+    ngx.shared.balancer_healthcheck:flush_all()
+  end)
+
+  it("marks an endpoint unhealthy after consecutive failed checks", function()
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+    assert.is_false(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+    assert.are.equal(1, healthcheck.version())
+  end)
+
+  it("resets the failures after a successful check", function()
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+    healthcheck.record(backend.name, "10.10.10.1:8080", true)
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+    assert.are.equal(0, healthcheck.version())
+  end)
+
+  it("marks an endpoint healthy again after consecutive successful checks", function()
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+
+    healthcheck.record(backend.name, "10.10.10.1:8080", true)
+    assert.is_false(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+
+    healthcheck.record(backend.name, "10.10.10.1:8080", true)
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+    assert.are.equal(2, healthcheck.version())
+  end)
+
+  it("filters the unhealthy endpoints", function()
+    healthcheck.record(backend.name, "10.10.10.2:8080", false)
+    healthcheck.record(backend.name, "10.10.10.2:8080", false)
+
+    local filtered_backend = healthcheck.filter(backend)
+    assert.are.same({
+      { address = "10.10.10.1", port = "8080", maxFails = 0, failTimeout = 0 },
+      { address = "10.10.10.3", port = "8080", maxFails = 0, failTimeout = 0 },
+    }, filtered_backend.endpoints)
+    assert.are.equal(3, #backend.endpoints)
+  end)
+
+  it("keeps all the endpoints when none is healthy", function()
+    for _, endpoint in ipairs(backend.endpoints) do
+      healthcheck.record(backend.name, endpoint.address .. ":" .. endpoint.port, false)
+      healthcheck.record(backend.name, endpoint.address .. ":" .. endpoint.port, false)
+    end
+
+    assert.are.equal(backend, healthcheck.filter(backend))
+  end)
+
+  it("ignores backends without health checks", function()
+    backend.healthCheck = { type = "" }
+    healthcheck.sync(backend)
+
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+    healthcheck.record(backend.name, "10.10.10.1:8080", false)
+
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+    assert.are.equal(backend, healthcheck.filter(backend))
+  end)
+
+  it("forgets the status of removed endpoints", function()
+    healthcheck.record(backend.name, "10.10.10.3:8080", false)
+    healthcheck.record(backend.name, "10.10.10.3:8080", false)
+
+    table.remove(backend.endpoints, 3)
+    healthcheck.sync(backend)
+
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.3:8080"))
+  end)
+
+  it("checks the endpoints with http requests", function()
+    responses["10.10.10.2:8080"] = "HTTP/1.1 503 Service Unavailable"
+    responses["10.10.10.3:8080"] = "refused"
+    responses["10.10.10.1:8080"] = "HTTP/1.1 200 OK"
+
+    healthcheck.run()
+    ngx_now = ngx_now + 5
+    healthcheck.run()
+
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+    assert.is_false(healthcheck.is_healthy(backend.name, "10.10.10.2:8080"))
+    assert.is_false(healthcheck.is_healthy(backend.name, "10.10.10.3:8080"))
+  end)
+
+  it("does not check the endpoints before the interval elapsed", function()
+    responses["10.10.10.1:8080"] = "HTTP/1.1 500 Internal Server Error"
+
+    healthcheck.run()
+    ngx_now = ngx_now + 1
+    healthcheck.run()
+
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+  end)
+
+  it("checks the endpoints with tcp connections", function()
+    backend.healthCheck.type = "tcp"
+    healthcheck.sync(backend)
+    responses["10.10.10.3:8080"] = "refused"
+
+    healthcheck.run()
+    ngx_now = ngx_now + 5
+    healthcheck.run()
+
+    assert.is_true(healthcheck.is_healthy(backend.name, "10.10.10.1:8080"))
+    assert.is_false(healthcheck.is_healthy(backend.name, "10.10.10.3:8080"))
+  end)
+
+  it("caps the number of concurrent checks", function()
+    local spawned = 0
+    ngx.thread = {
+      spawn = function(f, ...)
+        spawned = spawned + 1
+        f(...)
+        return {}
+      end,
+      wait = function() end,
+    }
+
+    backend.endpoints = {}
+    for i = 1, 40 do
+      table.insert(backend.endpoints, { address = "10.10.11." .. i, port = "8080" })
+      responses["10.10.11." .. i .. ":8080"] = "refused"
+    end
+    healthcheck.sync(backend)
+
+    healthcheck.run()
+    ngx_now = ngx_now + 5
+    healthcheck.run()
+
+    assert.are.equal(64, spawned)
+    for i = 1, 40 do
+      assert.is_false(healthcheck.is_healthy(backend.name, "10.10.11." .. i .. ":8080"))
+    end
+  end)
+end)
@@ -0,0 +1,134 @@
+local util = require("util")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local BACKEND_NAME = "namespace-service-port"
+
+local function set_in_flight(endpoint_string, count, backend_name)
+  local key = (backend_name or BACKEND_NAME) .. "|" .. endpoint_string
+  ngx.shared.balancer_least_conn:set(key, count)
+end
+
+local function get_in_flight(endpoint_string, backend_name)
+  local key = (backend_name or BACKEND_NAME) .. "|" .. endpoint_string
+  return ngx.shared.balancer_least_conn:get(key) or 0
+end
+
+describe("Balancer least_conn", function()
+  local balancer_least_conn = require("balancer.least_conn")
+  local backend, instance
+
+  before_each(function()
+    mock_ngx({ ctx = {} })
+    package.loaded["balancer.least_conn"] = nil
+    balancer_least_conn = require("balancer.least_conn")
+
+    backend = {
+      name = BACKEND_NAME, ["load-balance"] = "least_conn",
+      endpoints = {
+        { address = "10.10.10.1", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.2", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.3", port = "8080", maxFails = 0, failTimeout = 0 },
+      }
+    }
+    set_in_flight("10.10.10.1:8080", 3)
+    set_in_flight("10.10.10.2:8080", 1)
+    set_in_flight("10.10.10.3:8080", 5)
+
+    instance = balancer_least_conn:new(backend)
+  end)
+
+  after_each(function()
+    reset_ngx()
+    ngx.shared.balancer_least_conn:flush_all()
+  end)
+
+  describe("balance()", function()
+    it("returns the endpoint with the lowest number of in-flight requests", function()
+      local peer = instance:balance()
+      assert.equal("10.10.10.2:8080", peer)
+      assert.equal(2, get_in_flight("10.10.10.2:8080"))
+    end)
+
+    it("doesn't pick the tried endpoint while retry", function()
+      ngx.ctx.balancer_least_conn_tried_endpoints = {
+        ["10.10.10.2:8080"] = true,
+      }
+
+      local peer = instance:balance()
+      assert.equal("10.10.10.1:8080", peer)
+    end)
+
+    it("picks from all the endpoints when all of them have been tried", function()
+      ngx.ctx.balancer_least_conn_tried_endpoints = {
+        ["10.10.10.1:8080"] = true,
+        ["10.10.10.2:8080"] = true,
+        ["10.10.10.3:8080"] = true,
+      }
+
+      local peer = instance:balance()
+      assert.equal("10.10.10.2:8080", peer)
+    end)
+
+    it("returns the single endpoint when there is only one", function()
+      backend.endpoints = { { address = "10.10.10.3", port = "8080", maxFails = 0, failTimeout = 0 } }
+      instance = balancer_least_conn:new(backend)
+
+      local peer = instance:balance()
+      assert.equal("10.10.10.3:8080", peer)
+      assert.equal(6, get_in_flight("10.10.10.3:8080"))
+    end)
+
+    it("counts the requests of an endpoint separately for every backend", function()
+      set_in_flight("10.10.10.2:8080", 10, "other-backend")
+
+      local peer = instance:balance()
+      assert.equal("10.10.10.2:8080", peer)
+      assert.equal(2, get_in_flight("10.10.10.2:8080"))
+      assert.equal(10, get_in_flight("10.10.10.2:8080", "other-backend"))
+    end)
+  end)
+
+  describe("after_balance()", function()
+    it("releases every endpoint picked for the request", function()
+      instance:balance()
+      instance:balance()
+      assert.equal(2, get_in_flight("10.10.10.2:8080"))
+      assert.equal(4, get_in_flight("10.10.10.1:8080"))
+
+      instance:after_balance()
+      assert.equal(1, get_in_flight("10.10.10.2:8080"))
+      assert.equal(3, get_in_flight("10.10.10.1:8080"))
+    end)
+
+    it("never makes the number of in-flight requests negative", function()
+      instance:balance()
+      ngx.shared.balancer_least_conn:delete(BACKEND_NAME .. "|10.10.10.2:8080")
+
+      instance:after_balance()
+      assert.equal(0, get_in_flight("10.10.10.2:8080"))
+    end)
+  end)
+
+  describe("sync()", function()
+    it("removes the counters of the endpoints that left the backend", function()
+      local new_backend = util.deepcopy(backend)
+      table.remove(new_backend.endpoints, 3)
+
+      instance:sync(new_backend)
+
+      assert.are.same(new_backend.endpoints, instance.peers)
+      assert.equal(0, get_in_flight("10.10.10.3:8080"))
+      assert.equal(3, get_in_flight("10.10.10.1:8080"))
+    end)
+  end)
+end)
@@ -0,0 +1,132 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("Balancer outlier detection", function()
+  local outlier = require("balancer.outlier")
+  local ngx_now = 1543238266
+  local backend
+
+  local function serve(upstream_addr, upstream_status)
+    mock_ngx({
+      now = function() return ngx_now end,
+      var = { upstream_addr = upstream_addr, upstream_status = upstream_status },
+    })
+    outlier.after_balance(backend.name)
+  end
+
+  before_each(function()
+    mock_ngx({ now = function() return ngx_now end })
+    package.loaded["balancer.outlier"] = nil
+    outlier = require("balancer.outlier")
+
+    backend = {
+      name = "namespace-service-port",
+      outlierDetection = {
+        consecutiveErrors = 3,
+        baseEjectionTime = 10,
+        maxEjectionTime = 30,
+        maxEjectionPercent = 50,
+      },
+      endpoints = {
+        { address = "10.10.10.1", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.2", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.3", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.4", port = "8080", maxFails = 0, failTimeout = 0 },
+      }
+    }
+    outlier.sync(backend)
+  end)
+
+  after_each(function()
+    reset_ngx()
+    ngx.shared.balancer_outlier:flush_all()
+  end)
+
+  it("ejects an endpoint after consecutive failures", function()
+    serve("10.10.10.1:8080", "502")
+    serve("10.10.10.1:8080", "503")
+    assert.is_false(outlier.is_ejected("10.10.10.1:8080"))
+
+    serve("10.10.10.1:8080", "500")
+    assert.is_true(outlier.is_ejected("10.10.10.1:8080"))
+  end)
+
+  it("resets the failures after a successful response", function()
+    serve("10.10.10.1:8080", "502")
+    serve("10.10.10.1:8080", "502")
+    serve("10.10.10.1:8080", "200")
+    serve("10.10.10.1:8080", "502")
+    assert.is_false(outlier.is_ejected("10.10.10.1:8080"))
+  end)
+
+  it("records every try of the request", function()
+    serve("10.10.10.1:8080, 10.10.10.2:8080", "502, 200")
+    serve("10.10.10.1:8080, 10.10.10.2:8080", "504, 200")
+    serve("10.10.10.1:8080 : 10.10.10.2:8080", "502 : 200")
+    assert.is_true(outlier.is_ejected("10.10.10.1:8080"))
+    assert.is_false(outlier.is_ejected("10.10.10.2:8080"))
+  end)
+
+  it("readmits the endpoint with an exponential ejection time", function()
+    for _ = 1, 3 do
+      serve("10.10.10.1:8080", "502")
+    end
+    assert.is_true(outlier.is_ejected("10.10.10.1:8080"))
+
+    ngx_now = ngx_now + 11
+    assert.is_false(outlier.is_ejected("10.10.10.1:8080"))
+
+    for _ = 1, 3 do
+      serve("10.10.10.1:8080", "502")
+    end
+    ngx_now = ngx_now + 11
+    assert.is_true(outlier.is_ejected("10.10.10.1:8080"))
+
+    ngx_now = ngx_now + 10
+    assert.is_false(outlier.is_ejected("10.10.10.1:8080"))
+  end)
+
+  it("does not eject more than the maximum percentage of endpoints", function()
+    for _, endpoint_string in ipairs({ "10.10.10.1:8080", "10.10.10.2:8080", "10.10.10.3:8080" }) do
+      for _ = 1, 3 do
+        serve(endpoint_string, "502")
+      end
+    end
+
+    assert.is_true(outlier.is_ejected("10.10.10.1:8080"))
+    assert.is_true(outlier.is_ejected("10.10.10.2:8080"))
+    assert.is_false(outlier.is_ejected("10.10.10.3:8080"))
+  end)
+
+  it("returns the ejected endpoints", function()
+    for _ = 1, 3 do
+      serve("10.10.10.2:8080", "502")
+    end
+
+    assert.same({
+      { backend = "namespace-service-port", endpoint = "10.10.10.2:8080", ejectedUntil = ngx_now + 10 },
+    }, outlier.get_ejections())
+
+    ngx_now = ngx_now + 11
+    assert.same({}, outlier.get_ejections())
+  end)
+
+  it("ignores backends without outlier detection", function()
+    backend.outlierDetection = nil
+    outlier.sync(backend)
+
+    for _ = 1, 3 do
+      serve("10.10.10.1:8080", "502")
+    end
+    assert.is_false(outlier.is_ejected("10.10.10.1:8080"))
+    assert.equal(0, outlier.max_tries(backend.name))
+  end)
+end)
@@ -0,0 +1,73 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("Balancer slow start", function()
+  local slow_start = require("balancer.slow_start")
+  local ngx_now = 1543238266
+  local backend, old_endpoints, instance
+
+  before_each(function()
+    mock_ngx({ now = function() return ngx_now end })
+    package.loaded["balancer.slow_start"] = nil
+    slow_start = require("balancer.slow_start")
+
+    old_endpoints = {
+      { address = "10.10.10.1", port = "8080", maxFails = 0, failTimeout = 0 },
+    }
+    backend = {
+      name = "namespace-service-port", slowStartSeconds = 30,
+      endpoints = {
+        { address = "10.10.10.1", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.10.10.2", port = "8080", maxFails = 0, failTimeout = 0 },
+      }
+    }
+
+    instance = slow_start:new(backend)
+  end)
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("does not ramp the endpoints known when the balancer is created", function()
+    assert.is_false(instance:is_ramping())
+    assert.equal(1, instance:factor("10.10.10.1:8080"))
+  end)
+
+  it("ramps up the endpoints added to the backend", function()
+    instance:sync(backend, old_endpoints)
+    assert.is_true(instance:is_ramping())
+    assert.equal(1, instance:factor("10.10.10.1:8080"))
+    assert.equal(0.1, instance:factor("10.10.10.2:8080"))
+
+    ngx_now = ngx_now + 15
+    assert.equal(0.5, instance:factor("10.10.10.2:8080"))
+
+    ngx_now = ngx_now + 15
+    assert.equal(1, instance:factor("10.10.10.2:8080"))
+    assert.is_false(instance:is_ramping())
+  end)
+
+  it("forgets the endpoints removed from the backend", function()
+    instance:sync(backend, old_endpoints)
+
+    local new_backend = { name = backend.name, slowStartSeconds = 30, endpoints = old_endpoints }
+    instance:sync(new_backend, backend.endpoints)
+    assert.is_false(instance:is_ramping())
+  end)
+
+  it("does not ramp up endpoints when slow start is disabled", function()
+    backend.slowStartSeconds = nil
+    instance:sync(backend, old_endpoints)
+    assert.is_false(instance:is_ramping())
+    assert.equal(1, instance:factor("10.10.10.2:8080"))
+  end)
+end)
@@ -0,0 +1,59 @@
+describe("Balancer sticky header", function()
+  local balancer_sticky_header, backend, requested_keys
+
+  before_each(function()
+    requested_keys = {}
+    balancer_sticky_header = require_without_cache("balancer.sticky_header")
+
+    local resty_chash = package.loaded["resty.chash"]
+    resty_chash.new = function(self, nodes)
+      return {
+        nodes = nodes,
+        find = function(self, key)
+          table.insert(requested_keys, key)
+          return "10.184.7.40:8080"
+        end
+      }
+    end
+
+    backend = {
+      name = "my-dummy-backend",
+      sessionAffinityConfig = {
+        name = "", mode = "header", headerSessionAffinity = { name = "X-Device-ID" },
+      },
+      endpoints = { { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 } }
+    }
+  end)
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("hashes the value of the header", function()
+    ngx.var = { http_x_device_id = "device-1" }
+    local instance = balancer_sticky_header:new(backend)
+
+    assert.equal("10.184.7.40:8080", instance:balance())
+    assert.are.same({ "device-1" }, requested_keys)
+  end)
+
+  it("is not sticky when the header is missing", function()
+    ngx.var = {}
+    local instance = balancer_sticky_header:new(backend)
+
+    instance:balance()
+    assert.equal(1, #requested_keys)
+    assert.is_not_nil(requested_keys[1])
+  end)
+
+  it("uses the new header name after a sync", function()
+    ngx.var = { http_x_device_id = "device-1", http_x_api_key = "key-1" }
+    local instance = balancer_sticky_header:new(backend)
+
+    backend.sessionAffinityConfig.headerSessionAffinity.name = "X-Api-Key"
+    instance:sync(backend)
+
+    instance:balance()
+    assert.are.same({ "key-1" }, requested_keys)
+  end)
+end)
@@ -0,0 +1,64 @@
+local redis = require("resty.redis")
+local configuration = require("configuration")
+
+local original_redis_new = redis.new
+local original_redis_config = configuration.session_affinity_redis
+
+describe("Sticky store", function()
+  local sticky_store
+  local connection
+
+  local function mock_redis(commit_result, commit_err)
+    connection = {
+      set_timeout = function() end,
+      connect = function() return true end,
+      init_pipeline = function() end,
+      get = function() end,
+      expire = function() end,
+      commit_pipeline = function() return commit_result, commit_err end,
+      set_keepalive = spy.new(function() return true end),
+      close = spy.new(function() return true end),
+    }
+    redis.new = function() return connection end
+  end
+
+  before_each(function()
+    configuration.session_affinity_redis = {
+      host = "redis", port = 6379, connect_timeout = 100, max_idle_timeout = 1000,
+      pool_size = 10, ttl = 3600,
+    }
+    package.loaded["balancer.sticky_store"] = nil
+    sticky_store = require("balancer.sticky_store")
+  end)
+
+  after_each(function()
+    redis.new = original_redis_new
+    configuration.session_affinity_redis = original_redis_config
+  end)
+
+  describe("get()", function()
+    it("returns the stored endpoint and releases the connection", function()
+      mock_redis({ "10.0.0.1:8080", 1 })
+
+      assert.equal("10.0.0.1:8080", sticky_store.get("backend", "session"))
+      assert.spy(connection.set_keepalive).was_called()
+      assert.spy(connection.close).was_not_called()
+    end)
+
+    it("returns nil and releases the connection when there is no endpoint", function()
+      mock_redis({ ngx.null, 0 })
+
+      assert.is_nil(sticky_store.get("backend", "session"))
+      assert.spy(connection.set_keepalive).was_called()
+    end)
+
+    it("closes the connection when the commands fail", function()
+      mock_redis(nil, "timeout")
+
+      assert.is_nil(sticky_store.get("backend", "session"))
+      assert.spy(connection.close).was_called()
+      assert.spy(connection.set_keepalive).was_not_called()
+      assert.is_false(sticky_store.is_enabled())
+    end)
+  end)
+end)
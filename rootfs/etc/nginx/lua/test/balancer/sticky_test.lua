@@ -595,6 +595,78 @@ describe("Sticky", function()
 
   end)
 
+  describe("with a session affinity store", function()
+    local original_sticky_store = package.loaded["balancer.sticky_store"]
+    local mocked_cookie_new = cookie.new
+    local stored_upstreams
+
+    before_each(function()
+      stored_upstreams = {}
+      package.loaded["balancer.sticky_store"] = {
+        get = function(_, key) return stored_upstreams[key] end,
+        set = function(_, key, upstream) stored_upstreams[key] = upstream end,
+      }
+      reset_sticky_balancer()
+
+      cookie.new = function(self)
+        return {
+          set = function() return true, nil end,
+          get = function() return "a-session-key" end,
+        }, false
+      end
+    end)
+
+    after_each(function()
+      package.loaded["balancer.sticky_store"] = original_sticky_store
+      cookie.new = mocked_cookie_new
+      ngx.ctx.balancer_sticky_stored_upstream = nil
+    end)
+
+    local function test_stored_endpoint_with(sticky_balancer_type)
+      local sticky_balancer_instance = sticky_balancer_type:new(get_several_test_backends(false))
+      stored_upstreams["a-session-key"] = "10.184.7.41:8080"
+
+      sticky_balancer_instance:rewrite()
+
+      assert.equal("10.184.7.41:8080", sticky_balancer_instance:balance())
+    end
+
+    it("routes the session to the stored endpoint", function() test_stored_endpoint_with(sticky_balanced) end)
+    it("routes the session to the stored endpoint", function() test_stored_endpoint_with(sticky_persistent) end)
+
+    local function test_removed_endpoint_with(sticky_balancer_type)
+      local backend = get_several_test_backends(false)
+      table.remove(backend.endpoints, 2)
+      local sticky_balancer_instance = sticky_balancer_type:new(backend)
+      stored_upstreams["a-session-key"] = "10.184.7.41:8080"
+
+      sticky_balancer_instance:rewrite()
+
+      local peer = sticky_balancer_instance:balance()
+      assert.equal(test_backend_endpoint, peer)
+
+      local stored = {}
+      for _, upstream in pairs(stored_upstreams) do
+        stored[upstream] = true
+      end
+      assert.is_true(stored[test_backend_endpoint])
+    end
+
+    it("stores a new endpoint when the stored one was removed", function() test_removed_endpoint_with(sticky_balanced) end)
+    it("stores a new endpoint when the stored one was removed", function() test_removed_endpoint_with(sticky_persistent) end)
+
+    local function test_unavailable_store_with(sticky_balancer_type)
+      local sticky_balancer_instance = sticky_balancer_type:new(test_backend)
+
+      sticky_balancer_instance:rewrite()
+
+      assert.equal(test_backend_endpoint, sticky_balancer_instance:balance())
+    end
+
+    it("falls back to the cookie without a stored endpoint", function() test_unavailable_store_with(sticky_balanced) end)
+    it("falls back to the cookie without a stored endpoint", function() test_unavailable_store_with(sticky_persistent) end)
+  end)
+
   describe("set_cookie()", function()
 
     local function test_with(sticky_balancer_type)
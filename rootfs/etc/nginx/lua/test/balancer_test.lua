@@ -37,7 +37,8 @@ local function reset_expected_implementations()
     ["my-dummy-app-3"] = package.loaded["balancer.sticky_persistent"],
     ["my-dummy-app-4"] = package.loaded["balancer.ewma"],
     ["my-dummy-app-5"] = package.loaded["balancer.sticky_balanced"],
-    ["my-dummy-app-6"] = package.loaded["balancer.chashsubset"]
+    ["my-dummy-app-6"] = package.loaded["balancer.chashsubset"],
+    ["my-dummy-app-7"] = package.loaded["balancer.least_conn"]
   }
 end
 
@@ -88,6 +89,10 @@ local function reset_backends()
       ["load-balance"] = "ewma",                  -- upstreamHashByConfig will take priority.
       upstreamHashByConfig = { ["upstream-hash-by"] = "$request_uri", ["upstream-hash-by-subset"] = "true", }
     },
+    {
+      name = "my-dummy-app-7",
+      ["load-balance"] = "least_conn",
+    },
   }
 end
 
@@ -148,6 +153,41 @@ describe("Balancer", function()
         assert.are.same(expected, balancer.get_balancer())
       end
     end)
+
+    it("records the canary variant serving the request", function()
+      local backend = {
+        name = "my-dummy-app-100", ["load-balance"] = "round_robin",
+        alternativeBackends = { "my-dummy-canary-app-100" },
+        endpoints = { { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 } },
+      }
+      local canary_backend = {
+        name = "my-dummy-canary-app-100", ["load-balance"] = "round_robin",
+        endpoints = { { address = "11.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 } },
+        trafficShapingPolicy = {
+          weight = 0,
+          header = "",
+          headerValue = "",
+          cookie = ""
+        },
+      }
+
+      mock_ngx({ var = { proxy_upstream_name = backend.name }, ctx = {} })
+
+      balancer.sync_backend(backend)
+      balancer.sync_backend(canary_backend)
+
+      balancer.get_balancer()
+      assert.equal("my-dummy-canary-app-100", ngx.ctx.canary_backend)
+      assert.equal("stable", ngx.ctx.canary_variant)
+
+      canary_backend.trafficShapingPolicy.weight = 100
+      balancer.sync_backend(canary_backend)
+      ngx.ctx = {}
+
+      balancer.get_balancer()
+      assert.equal("my-dummy-canary-app-100", ngx.ctx.canary_backend)
+      assert.equal("canary", ngx.ctx.canary_variant)
+    end)
   end)
 
   describe("route_to_alternative_balancer()", function()
@@ -339,6 +379,119 @@ describe("Balancer", function()
         end)
       end)
 
+      describe("canary by query", function()
+        it("returns correct result for given query parameters", function()
+          local test_patterns = {
+            {
+              case_title = "no custom query value and query value is 'always'",
+              query_name = "canary",
+              query_value = "",
+              request_query_name = "canary",
+              request_query_value = "always",
+              expected_result = true,
+            },
+            {
+              case_title = "no custom query value and query value is 'never'",
+              query_name = "canary",
+              query_value = "",
+              request_query_name = "canary",
+              request_query_value = "never",
+              expected_result = false,
+            },
+            {
+              case_title = "custom query value is set and query value is 'always'",
+              query_name = "canary",
+              query_value = "beta",
+              request_query_name = "canary",
+              request_query_value = "always",
+              expected_result = false,
+            },
+            {
+              case_title = "custom query value is set and query value matches",
+              query_name = "canary",
+              query_value = "beta",
+              request_query_name = "canary",
+              request_query_value = "beta",
+              expected_result = true,
+            },
+            {
+              case_title = "query name is undefined",
+              query_name = "canary",
+              query_value = "",
+              request_query_name = "foo",
+              request_query_value = "always",
+              expected_result = false,
+            },
+          }
+
+          for _, test_pattern in pairs(test_patterns) do
+            mock_ngx({ var = {
+              ["arg_" .. test_pattern.request_query_name] = test_pattern.request_query_value,
+              request_uri = "/"
+            }})
+            backend.trafficShapingPolicy.query = test_pattern.query_name
+            backend.trafficShapingPolicy.queryValue = test_pattern.query_value
+            balancer.sync_backend(backend)
+            assert.message("\nTest data pattern: " .. test_pattern.case_title)
+              .equal(test_pattern.expected_result, balancer.route_to_alternative_balancer(_primaryBalancer))
+            reset_ngx()
+          end
+        end)
+      end)
+
+      describe("canary by geo", function()
+        it("routes the clients of the countries and continents to the canary", function()
+          backend.trafficShapingPolicy.geoCountries = { "CH" }
+          backend.trafficShapingPolicy.geoContinents = { "EU" }
+          balancer.sync_backend(backend)
+
+          mock_ngx({ var = { geoip2_city_country_code = "CH", geoip2_city_continent_code = "EU", request_uri = "/" } })
+          assert.is_true(balancer.route_to_alternative_balancer(_primaryBalancer))
+
+          mock_ngx({ var = { geoip2_city_country_code = "FR", geoip2_city_continent_code = "EU", request_uri = "/" } })
+          assert.is_true(balancer.route_to_alternative_balancer(_primaryBalancer))
+
+          mock_ngx({ var = { geoip2_city_country_code = "US", geoip2_city_continent_code = "NA", request_uri = "/" } })
+          assert.is_false(balancer.route_to_alternative_balancer(_primaryBalancer))
+        end)
+
+        it("gives precedence to the header rules", function()
+          backend.trafficShapingPolicy.header = "canary"
+          backend.trafficShapingPolicy.geoContinents = { "EU" }
+          balancer.sync_backend(backend)
+
+          mock_ngx({ var = { http_canary = "never", geoip2_city_continent_code = "EU", request_uri = "/" } })
+          assert.is_false(balancer.route_to_alternative_balancer(_primaryBalancer))
+        end)
+      end)
+
+      describe("sticky canary", function()
+        local canary = require("balancer.canary")
+        local original_get_assignment = canary.get_assignment
+        local original_set_assignment = canary.set_assignment
+
+        after_each(function()
+          canary.get_assignment = original_get_assignment
+          canary.set_assignment = original_set_assignment
+        end)
+
+        it("ignores the assignments to a canary with a weight of 0", function()
+          local set_assignment = spy.new(function() end)
+          canary.get_assignment = function() return true end
+          canary.set_assignment = set_assignment
+
+          backend.trafficShapingPolicy.sticky = true
+          backend.trafficShapingPolicy.weight = 0
+          balancer.sync_backend(backend)
+          assert.is_false(balancer.route_to_alternative_balancer(_primaryBalancer))
+          assert.spy(set_assignment).was_not_called()
+
+          backend.trafficShapingPolicy.weight = 10
+          balancer.sync_backend(backend)
+          assert.is_true(balancer.route_to_alternative_balancer(_primaryBalancer))
+        end)
+      end)
+
     end)
 
     -- Affinitized request prefers backend it is affinitized to.
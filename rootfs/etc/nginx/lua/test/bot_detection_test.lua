@@ -0,0 +1,210 @@
+local cookie = require("resty.cookie")
+local configuration = require("configuration")
+
+local original_ngx = ngx
+local original_cookie_new = cookie.new
+local original_get_secret = configuration.get_secret
+
+describe("bot detection", function()
+  local bot_detection, exit_status, redirect
+
+  local browser_var = {
+    remote_addr = "192.0.2.10",
+    namespace = "default",
+    ingress_name = "shop",
+    request_uri = "/cart?id=1",
+    http_user_agent = "Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/128.0",
+    http_accept = "text/html",
+    http_accept_language = "en-US",
+  }
+
+  local function mock_ngx(var)
+    exit_status = nil
+    redirect = nil
+
+    local _ngx = {
+      var = var,
+      header = {},
+      exit = function(status) exit_status = status end,
+      redirect = function(uri, status) redirect = { uri = uri, status = status } end,
+      print = function() end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    bot_detection = require_without_cache("bot_detection")
+  end
+
+  local function mock_cookie(value)
+    local o = { value = value }
+    local mock = {
+      get = function(self, n) return self.value end,
+      set = function(self, c) self.value = c.value ; self.data = c ; return true, nil end
+    }
+    setmetatable(o, mock)
+    mock.__index = mock
+
+    cookie.new = function(self) return o end
+    return o
+  end
+
+  local function with(var, overrides)
+    local merged = {}
+    for k, v in pairs(var) do merged[k] = v end
+    for k, v in pairs(overrides) do merged[k] = v end
+    return merged
+  end
+
+  local config = { reputation_cidrs = { "203.0.113.0/24" } }
+  local location_config = {
+    enabled = true, challenge = "cookie", challenge_score = 50, block_score = 100, rate_limit = 0,
+  }
+
+  before_each(function()
+    configuration.get_secret = function(name)
+      if name == "bot_detection_secret" then
+        return "s3cr3t"
+      end
+      return nil
+    end
+  end)
+
+  after_each(function()
+    reset_ngx()
+    cookie.new = original_cookie_new
+    configuration.get_secret = original_get_secret
+    ngx.shared.bot_detection:flush_all()
+  end)
+
+  describe("score()", function()
+    it("does not score the requests of browsers", function()
+      mock_ngx(browser_var)
+      assert.equal(0, bot_detection.score(config, location_config))
+    end)
+
+    it("scores the requests without User-Agent and headers", function()
+      mock_ngx({ remote_addr = "192.0.2.10" })
+      assert.equal(80, bot_detection.score(config, location_config))
+    end)
+
+    it("scores the User-Agents of automation tools", function()
+      mock_ngx(with(browser_var, { http_user_agent = "python-requests/2.31.0" }))
+      assert.equal(40, bot_detection.score(config, location_config))
+    end)
+
+    it("scores the addresses with a bad reputation", function()
+      mock_ngx(with(browser_var, { remote_addr = "203.0.113.7" }))
+      assert.equal(100, bot_detection.score(config, location_config))
+    end)
+
+    it("scores the clients above the rate limit", function()
+      mock_ngx(browser_var)
+      local limited = with(location_config, { rate_limit = 2 })
+
+      assert.equal(0, bot_detection.score(config, limited))
+      assert.equal(0, bot_detection.score(config, limited))
+      assert.equal(50, bot_detection.score(config, limited))
+    end)
+  end)
+
+  describe("rewrite()", function()
+    it("ignores the locations without bot detection", function()
+      mock_ngx({ remote_addr = "203.0.113.7" })
+
+      bot_detection.rewrite(config, { enabled = false })
+
+      assert.is_nil(exit_status)
+      assert.is_nil(redirect)
+    end)
+
+    it("proxies the requests below the challenge score", function()
+      mock_ngx(browser_var)
+      mock_cookie(nil)
+
+      bot_detection.rewrite(config, location_config)
+
+      assert.is_nil(exit_status)
+      assert.is_nil(redirect)
+    end)
+
+    it("blocks the requests from the block score", function()
+      mock_ngx(with(browser_var, { remote_addr = "203.0.113.7" }))
+
+      bot_detection.rewrite(config, location_config)
+
+      assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+    end)
+
+    it("serves a cookie challenge and proxies the requests with its cookie", function()
+      mock_ngx(with(browser_var, { http_user_agent = "" }))
+      local challenge_cookie = mock_cookie(nil)
+
+      bot_detection.rewrite(config, location_config)
+
+      assert.same({ uri = "/cart?id=1", status = ngx.HTTP_TEMPORARY_REDIRECT }, redirect)
+      assert.is_true(challenge_cookie.data.httponly)
+      assert.equal("ingress_bot_challenge", challenge_cookie.data.key)
+
+      redirect = nil
+      bot_detection.rewrite(config, location_config)
+
+      assert.is_nil(redirect)
+      assert.is_nil(exit_status)
+    end)
+
+    it("rejects the cookies signed for another client", function()
+      mock_ngx(with(browser_var, { http_user_agent = "" }))
+      local challenge_cookie = mock_cookie(nil)
+      bot_detection.rewrite(config, location_config)
+
+      ngx.var = with(ngx.var, { remote_addr = "192.0.2.11" })
+      redirect = nil
+      mock_cookie(challenge_cookie.value)
+      bot_detection.rewrite(config, location_config)
+
+      assert.is_not_nil(redirect)
+    end)
+
+    it("serves a JavaScript challenge", function()
+      mock_ngx(with(browser_var, { http_user_agent = "" }))
+      mock_cookie(nil)
+      local body
+      ngx.print = function(b) body = b end
+
+      bot_detection.rewrite(config, with(location_config, { challenge = "javascript" }))
+
+      assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+      assert.equal("no-store", ngx.header["Cache-Control"])
+      assert.truthy(body:find("ingress_bot_challenge=", 1, true))
+    end)
+
+    it("does not challenge the requests without the shared secret", function()
+      mock_ngx(with(browser_var, { http_user_agent = "" }))
+      mock_cookie(nil)
+      configuration.get_secret = function() return nil end
+
+      bot_detection.rewrite(config, location_config)
+
+      assert.is_nil(exit_status)
+      assert.is_nil(redirect)
+    end)
+
+    it("still blocks the requests from the block score without the shared secret", function()
+      mock_ngx(with(browser_var, { remote_addr = "203.0.113.7" }))
+      configuration.get_secret = function() return nil end
+
+      bot_detection.rewrite(config, location_config)
+
+      assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+    end)
+
+    it("never challenges the requests with the none challenge", function()
+      mock_ngx(with(browser_var, { http_user_agent = "" }))
+
+      bot_detection.rewrite(config, with(location_config, { challenge = "none" }))
+
+      assert.is_nil(exit_status)
+      assert.is_nil(redirect)
+    end)
+  end)
+end)
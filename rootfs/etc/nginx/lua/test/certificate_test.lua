@@ -54,6 +54,8 @@ describe("Certificate", function()
       ssl.clear_certs = function() return true, "" end
       ssl.set_der_cert = function(cert) return true, "" end
       ssl.set_der_priv_key = function(priv_key) return true, "" end
+      ssl.set_cert = function(cert) return true, "" end
+      ssl.set_priv_key = function(priv_key) return true, "" end
 
       ngx.exit = function(status) end
 
@@ -72,6 +74,80 @@ describe("Certificate", function()
       assert_certificate_is_set(EXAMPLE_CERT)
     end)
 
+    it("sets the ECDSA certificate and key too when there is one", function()
+      set_certificate("hostname", EXAMPLE_CERT, UUID)
+      -- the fixture is not an ECDSA certificate, which only matters to OpenSSL
+      ngx.shared.certificate_data:set("ecdsa:" .. UUID, DEFAULT_CERT)
+      spy.on(ssl, "set_cert")
+      spy.on(ssl, "set_priv_key")
+
+      assert_certificate_is_set(EXAMPLE_CERT)
+      assert.spy(ssl.set_cert).was_called(1)
+      assert.spy(ssl.set_priv_key).was_called(1)
+    end)
+
+    it("parses the ECDSA certificate and key once", function()
+      local uid = "ecdsa-cache-uid"
+      set_certificate("hostname", EXAMPLE_CERT, uid)
+      ngx.shared.certificate_data:set("ecdsa:" .. uid, DEFAULT_CERT)
+      spy.on(ssl, "parse_pem_cert")
+      spy.on(ssl, "parse_pem_priv_key")
+
+      assert.has_no.errors(certificate.call)
+      assert.has_no.errors(certificate.call)
+      assert.spy(ssl.parse_pem_cert).was_called(1)
+      assert.spy(ssl.parse_pem_priv_key).was_called(1)
+
+      -- a renewed certificate is parsed again
+      ngx.shared.certificate_data:set("ecdsa:" .. uid, EXAMPLE_CERT)
+      assert.has_no.errors(certificate.call)
+      assert.spy(ssl.parse_pem_cert).was_called(2)
+      assert.spy(ssl.parse_pem_priv_key).was_called(2)
+    end)
+
+    describe("with the private key kept by the external key provider", function()
+      local keyless = require("keyless")
+      local original_priv_key = keyless.priv_key
+
+      before_each(function()
+        certificate.ssl_key_engine = "kms"
+        ssl.set_priv_key = function(priv_key) return true, "" end
+        keyless.priv_key = function(engine_id, key_id) return "key " .. key_id, nil end
+        set_certificate("hostname", EXAMPLE_CERT, UUID)
+        ngx.shared.certificate_data:set("keyid:" .. UUID, "projects/p/keys/k")
+      end)
+
+      after_each(function()
+        certificate.ssl_key_engine = ""
+        keyless.priv_key = original_priv_key
+      end)
+
+      it("sets the certificate and the private key loaded by the engine", function()
+        spy.on(ngx, "log")
+        spy.on(ssl, "set_der_cert")
+        spy.on(ssl, "set_der_priv_key")
+        spy.on(ssl, "set_priv_key")
+        spy.on(keyless, "priv_key")
+
+        assert.has_no.errors(certificate.call)
+        assert.spy(ngx.log).was_not_called_with(ngx.ERR, _)
+        assert.spy(ssl.set_der_cert).was_called_with(ssl.cert_pem_to_der(EXAMPLE_CERT))
+        assert.spy(keyless.priv_key).was_called_with("kms", "projects/p/keys/k")
+        assert.spy(ssl.set_priv_key).was_called_with("key projects/p/keys/k")
+        assert.spy(ssl.set_der_priv_key).was_not_called()
+      end)
+
+      it("fails when the private key can not be loaded", function()
+        keyless.priv_key = function() return nil, "engine kms not found" end
+        spy.on(ngx, "log")
+        spy.on(ngx, "exit")
+
+        assert.has_no.errors(certificate.call)
+        assert.spy(ngx.log).was_called_with(ngx.ERR, "engine kms not found")
+        assert.spy(ngx.exit).was_called_with(ngx.ERROR)
+      end)
+    end)
+
     it("sets certificate and key for wildcard cert", function()
       ssl.server_name = function() return "sub.hostname", nil end
       set_certificate("*.hostname", EXAMPLE_CERT, UUID)
@@ -0,0 +1,119 @@
+local configuration = require("configuration")
+
+local original_ngx = ngx
+local original_http = package.loaded["resty.http"]
+local original_get_secret = configuration.get_secret
+
+describe("crowdsec", function()
+  local crowdsec, exit_status, requests, response
+
+  local config = {
+    enabled = true,
+    lapi_url = "http://crowdsec-service.crowdsec:8080",
+    cache_ttl = 60,
+    timeout = 200,
+  }
+
+  before_each(function()
+    exit_status = nil
+    requests = {}
+    response = { status = 200, body = "null" }
+
+    package.loaded["resty.http"] = {
+      new = function()
+        return {
+          set_timeout = function() end,
+          request_uri = function(_, uri, params)
+            table.insert(requests, { uri = uri, params = params })
+            if not response then
+              return nil, "timeout"
+            end
+            return response
+          end,
+        }
+      end,
+    }
+
+    local _ngx = {
+      var = { remote_addr = "192.0.2.10" },
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    configuration.get_secret = function(name)
+      if name == "crowdsec_api_key" then
+        return "s3cr3t"
+      end
+      return nil
+    end
+
+    crowdsec = require_without_cache("crowdsec")
+  end)
+
+  after_each(function()
+    _G.ngx = original_ngx
+    package.loaded["resty.http"] = original_http
+    configuration.get_secret = original_get_secret
+    ngx.shared.crowdsec_cache:flush_all()
+  end)
+
+  it("queries the Local API with the API key", function()
+    crowdsec.access(config)
+
+    assert.are.equal(1, #requests)
+    assert.are.equal("http://crowdsec-service.crowdsec:8080/v1/decisions?ip=192.0.2.10", requests[1].uri)
+    assert.are.equal("s3cr3t", requests[1].params.headers["X-Api-Key"])
+    assert.is_nil(exit_status)
+  end)
+
+  it("allows the requests without querying the Local API without the API key", function()
+    configuration.get_secret = function() return nil end
+
+    crowdsec.access(config)
+
+    assert.are.equal(0, #requests)
+    assert.is_nil(exit_status)
+  end)
+
+  it("denies the requests of the addresses with a decision", function()
+    response.body = '[{"type":"ban","scope":"Ip","value":"192.0.2.10","duration":"3h59m58.5s"}]'
+
+    crowdsec.access(config)
+
+    assert.are.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+
+  it("caches the decisions", function()
+    response.body = '[{"type":"ban","scope":"Ip","value":"192.0.2.10","duration":"4h"}]'
+
+    crowdsec.access(config)
+    crowdsec.access(config)
+
+    assert.are.equal(1, #requests)
+    assert.are.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+
+  it("allows the requests when the Local API cannot be reached", function()
+    response = nil
+
+    crowdsec.access(config)
+    crowdsec.access(config)
+
+    assert.are.equal(2, #requests)
+    assert.is_nil(exit_status)
+  end)
+
+  it("does nothing when it is disabled", function()
+    crowdsec.access({ enabled = false })
+
+    assert.are.equal(0, #requests)
+  end)
+
+  it("parses the durations of the decisions", function()
+    assert.are.equal(14398, crowdsec.parse_duration("3h59m58.5s"))
+    assert.are.equal(300, crowdsec.parse_duration("5m"))
+    assert.is_nil(crowdsec.parse_duration("-1s"))
+    assert.is_nil(crowdsec.parse_duration("forever"))
+  end)
+end)
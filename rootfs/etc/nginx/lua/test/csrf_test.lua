@@ -0,0 +1,200 @@
+local cookie = require("resty.cookie")
+local configuration = require("configuration")
+
+local original_ngx = ngx
+local original_cookie_new = cookie.new
+local original_get_secret = configuration.get_secret
+
+describe("csrf", function()
+  local csrf, exit_status
+
+  local browser_var = {
+    remote_addr = "192.0.2.10",
+    request_uri = "/cart",
+    pass_access_scheme = "https",
+    best_http_host = "shop.example.com",
+    https = "on",
+  }
+
+  local function mock_ngx(method, var, post_args)
+    exit_status = nil
+
+    local _ngx = {
+      var = var,
+      ctx = {},
+      req = {
+        get_method = function() return method end,
+        read_body = function() end,
+        get_post_args = function() return post_args end,
+      },
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    configuration.get_secret = function(name)
+      if name == "csrf_secret" then
+        return "s3cr3t"
+      end
+      return nil
+    end
+
+    csrf = require_without_cache("csrf")
+  end
+
+  local function mock_cookie(value)
+    local o = { value = value }
+    local mock = {
+      get = function(self, n) return self.value end,
+      set = function(self, c) self.value = c.value ; self.data = c ; return true, nil end
+    }
+    setmetatable(o, mock)
+    mock.__index = mock
+
+    cookie.new = function(self) return o end
+    return o
+  end
+
+  local function with(var, overrides)
+    local merged = {}
+    for k, v in pairs(var) do merged[k] = v end
+    for k, v in pairs(overrides) do merged[k] = v end
+    return merged
+  end
+
+  local location_config = {
+    enabled = true, header_name = "X-CSRF-Token", trusted_origins = { "https://admin.example.com" },
+  }
+
+  -- issue_token returns the token of the cookie issued to a GET request
+  local function issue_token()
+    mock_ngx("GET", browser_var)
+    local token_cookie = mock_cookie(nil)
+    csrf.rewrite(location_config)
+    return token_cookie.value
+  end
+
+  after_each(function()
+    reset_ngx()
+    cookie.new = original_cookie_new
+    configuration.get_secret = original_get_secret
+  end)
+
+  it("ignores the locations without CSRF protection", function()
+    mock_ngx("POST", with(browser_var, { http_origin = "https://evil.example.org" }))
+
+    csrf.rewrite({ enabled = false })
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("issues a token cookie readable by the scripts to the safe requests", function()
+    mock_ngx("GET", browser_var)
+    local token_cookie = mock_cookie(nil)
+
+    csrf.rewrite(location_config)
+
+    assert.is_nil(exit_status)
+    assert.equal("ingress_csrf", token_cookie.data.key)
+    assert.is_false(token_cookie.data.httponly)
+    assert.is_true(token_cookie.data.secure)
+    assert.is_true(csrf.valid_token("s3cr3t", token_cookie.value))
+    assert.is_false(csrf.valid_token("other", token_cookie.value))
+  end)
+
+  it("proxies the unsafe requests sending the token of their cookie back", function()
+    local token = issue_token()
+
+    mock_ngx("POST", with(browser_var, {
+      http_origin = "https://shop.example.com", http_sec_fetch_site = "same-origin", http_x_csrf_token = token,
+    }))
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("reads the token of the forms", function()
+    local token = issue_token()
+
+    mock_ngx("POST", with(browser_var, { content_type = "application/x-www-form-urlencoded" }), { csrf_token = token })
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("allows the trusted origins", function()
+    local token = issue_token()
+
+    mock_ngx("DELETE", with(browser_var, {
+      http_origin = "https://Admin.example.com", http_sec_fetch_site = "same-site", http_x_csrf_token = token,
+    }))
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("rejects the requests of other origins", function()
+    local token = issue_token()
+
+    mock_ngx("POST", with(browser_var, { http_origin = "https://evil.example.org", http_x_csrf_token = token }))
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+    assert.is_true(ngx.ctx.csrf_rejected)
+  end)
+
+  it("rejects the cross-site requests without origin", function()
+    local token = issue_token()
+
+    mock_ngx("POST", with(browser_var, { http_sec_fetch_site = "cross-site", http_x_csrf_token = token }))
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+
+  it("rejects the requests without token", function()
+    local token = issue_token()
+
+    mock_ngx("POST", with(browser_var, { http_origin = "https://shop.example.com" }))
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+
+  it("rejects the unsafe requests without csrf-secret", function()
+    local token = issue_token()
+
+    mock_ngx("POST", with(browser_var, { http_origin = "https://shop.example.com", http_x_csrf_token = token }))
+    configuration.get_secret = function() return nil end
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+
+  it("does not issue tokens without csrf-secret", function()
+    mock_ngx("GET", browser_var)
+    configuration.get_secret = function() return nil end
+    local token_cookie = mock_cookie(nil)
+    csrf.rewrite(location_config)
+
+    assert.is_nil(exit_status)
+    assert.is_nil(token_cookie.value)
+  end)
+
+  it("rejects the tokens which were not signed by the controller", function()
+    local token = "0123456789abcdef.forged"
+
+    mock_ngx("POST", with(browser_var, { http_origin = "https://shop.example.com", http_x_csrf_token = token }))
+    mock_cookie(token)
+    csrf.rewrite(location_config)
+
+    assert.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+end)
@@ -0,0 +1,92 @@
+local cjson = require("cjson.safe")
+
+local original_ngx = ngx
+
+describe("denylist", function()
+  local denylist, exit_status
+
+  local function mock_ngx(var)
+    exit_status = nil
+
+    local _ngx = {
+      var = var,
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+  end
+
+  before_each(function()
+    mock_ngx({ remote_addr = "192.0.2.10" })
+    denylist = require_without_cache("denylist")
+  end)
+
+  after_each(function()
+    _G.ngx = original_ngx
+    ngx.shared.denylist:flush_all()
+  end)
+
+  local function set_entries(entries)
+    local ok, err = denylist.set_entries(cjson.encode(entries))
+    assert.is_nil(err)
+    assert.is_true(ok)
+    denylist.sync()
+  end
+
+  it("denies the addresses of the entries", function()
+    set_entries({ { cidr = "192.0.2.0/24" }, { cidr = "2001:db8::/32" } })
+
+    assert.is_true(denylist.is_denied("192.0.2.10"))
+    assert.is_true(denylist.is_denied("2001:db8::1"))
+    assert.is_false(denylist.is_denied("198.51.100.1"))
+  end)
+
+  it("does not deny the addresses of the expired entries", function()
+    set_entries({
+      { cidr = "192.0.2.10", expiresAt = ngx.time() - 10 },
+      { cidr = "192.0.2.20", expiresAt = ngx.time() + 3600 },
+    })
+
+    assert.is_false(denylist.is_denied("192.0.2.10"))
+    assert.is_true(denylist.is_denied("192.0.2.20"))
+  end)
+
+  it("rejects the requests of the denied addresses", function()
+    set_entries({ { cidr = "192.0.2.0/24" } })
+
+    denylist.rewrite()
+
+    assert.are.equal(ngx.HTTP_FORBIDDEN, exit_status)
+  end)
+
+  it("lets the requests of the other addresses through", function()
+    set_entries({ { cidr = "198.51.100.0/24" } })
+
+    denylist.rewrite()
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("clears the denylist with an empty array", function()
+    set_entries({ { cidr = "192.0.2.0/24" } })
+    set_entries({})
+
+    assert.is_false(denylist.is_denied("192.0.2.10"))
+    assert.are.equal("[]", denylist.get_entries())
+  end)
+
+  it("keeps the entries when the posted denylist is invalid", function()
+    set_entries({ { cidr = "192.0.2.0/24" } })
+
+    local ok, err = denylist.set_entries("{")
+    assert.is_false(ok)
+    assert.is_not_nil(err)
+
+    ok, err = denylist.set_entries('[{"cidr":"not-an-address"}]')
+    assert.is_false(ok)
+    assert.is_not_nil(err)
+
+    denylist.sync()
+    assert.is_true(denylist.is_denied("192.0.2.10"))
+  end)
+end)
@@ -0,0 +1,69 @@
+local original_ngx = ngx
+
+describe("drain", function()
+  local drain, exit_status
+
+  local function mock_ngx(var)
+    exit_status = nil
+    var.request_id = var.request_id or "0123456789abcdef"
+
+    local _ngx = {
+      ctx = {},
+      var = var,
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    drain = require_without_cache("drain")
+  end
+
+  after_each(function()
+    reset_ngx()
+    ngx.shared.drain:flush_all()
+  end)
+
+  it("counts the requests in flight per protocol", function()
+    mock_ngx({ http_upgrade = "websocket", request_id = "1" })
+    drain.rewrite()
+
+    mock_ngx({ http_content_type = "application/grpc+proto", request_id = "2" })
+    drain.rewrite()
+
+    mock_ngx({ request_id = "3" })
+    drain.rewrite()
+
+    assert.are.same({ http = 1, websocket = 1, grpc = 1 }, drain.active_requests())
+
+    drain.log()
+    assert.are.same({ http = 0, websocket = 1, grpc = 1 }, drain.active_requests())
+
+    -- the request is released once
+    drain.log()
+    assert.are.same({ http = 0, websocket = 1, grpc = 1 }, drain.active_requests())
+  end)
+
+  it("counts the requests once across internal redirects", function()
+    mock_ngx({ request_id = "1" })
+    drain.rewrite()
+
+    -- the location of the internal redirect runs with a new ngx.ctx
+    mock_ngx({ request_id = "1" })
+    drain.rewrite()
+    assert.are.same({ http = 1, websocket = 0, grpc = 0 }, drain.active_requests())
+
+    mock_ngx({ request_id = "1" })
+    drain.log()
+    assert.are.same({ http = 0, websocket = 0, grpc = 0 }, drain.active_requests())
+  end)
+
+  it("rejects the new requests when draining", function()
+    mock_ngx({})
+    assert.is_true(drain.start())
+
+    drain.rewrite()
+
+    assert.are.equal(ngx.HTTP_SERVICE_UNAVAILABLE, exit_status)
+    assert.are.same({ http = 0, websocket = 0, grpc = 0 }, drain.active_requests())
+  end)
+end)
@@ -0,0 +1,72 @@
+local original_ngx = ngx
+
+describe("geo_access", function()
+  local geo_access, exit_status
+
+  local function mock_ngx(var)
+    exit_status = nil
+
+    local _ngx = {
+      var = var,
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    geo_access = require_without_cache("geo_access")
+  end
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("ignores the locations without rules", function()
+    mock_ngx({ geoip2_city_country_code = "RU" })
+
+    geo_access.rewrite({ allow_countries = {}, deny_countries = {}, allow_asns = {}, deny_asns = {}, status_code = 0 })
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("denies the clients of the denied countries and autonomous systems", function()
+    mock_ngx({})
+
+    local rules = { deny_countries = { "KP", "RU" }, deny_asns = { "64496" } }
+
+    assert.equal("denied country RU", geo_access.denied(rules, "RU", "15169"))
+    assert.equal("denied autonomous system 64496", geo_access.denied(rules, "DE", "64496"))
+    assert.is_nil(geo_access.denied(rules, "DE", "15169"))
+    assert.is_nil(geo_access.denied(rules, nil, nil))
+  end)
+
+  it("only allows the clients of the allowed countries and autonomous systems", function()
+    mock_ngx({})
+
+    local rules = { allow_countries = { "DE", "FR" }, allow_asns = {} }
+    assert.is_nil(geo_access.denied(rules, "FR", nil))
+    assert.equal("country US not allowed", geo_access.denied(rules, "US", nil))
+    assert.equal("country unknown not allowed", geo_access.denied(rules, nil, nil))
+
+    rules = { allow_asns = { "15169" } }
+    assert.is_nil(geo_access.denied(rules, "US", "15169"))
+    assert.equal("autonomous system 8075 not allowed", geo_access.denied(rules, "US", "8075"))
+  end)
+
+  it("answers the denied requests with the status code of the location", function()
+    mock_ngx({ geoip2_country_code = "US", geoip2_asn = "0", remote_addr = "192.0.2.1" })
+
+    geo_access.rewrite({ allow_countries = { "DE" }, deny_countries = {}, allow_asns = {}, deny_asns = {}, status_code = 451 })
+
+    assert.equal(451, exit_status)
+  end)
+
+  it("proxies the allowed requests", function()
+    mock_ngx({ geoip2_city_country_code = "DE", geoip2_asn = "3320" })
+
+    geo_access.rewrite({
+      allow_countries = { "DE" }, deny_countries = {}, allow_asns = {}, deny_asns = { "64496" }, status_code = 403,
+    })
+
+    assert.is_nil(exit_status)
+  end)
+end)
@@ -0,0 +1,149 @@
+local original_ngx = ngx
+
+describe("grpc_web", function()
+  local grpc_web, request_headers, request_body, exit_status
+
+  local function mock_ngx(mock)
+    request_headers, request_body, exit_status = {}, nil, nil
+
+    local _ngx = {
+      ctx = {},
+      header = {},
+      status = 200,
+      arg = {},
+      req = {
+        read_body = function() end,
+        get_body_data = function() return mock.body end,
+        get_body_file = function() return nil end,
+        set_body_data = function(data) request_body = data end,
+        set_header = function(name, value) request_headers[name] = value end,
+      },
+      exit = function(status) exit_status = status end,
+      var = mock.var or {},
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    grpc_web = require_without_cache("grpc_web")
+  end
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  describe("rewrite()", function()
+    it("ignores requests which are not gRPC-Web", function()
+      mock_ngx({ var = { http_content_type = "application/json" } })
+
+      grpc_web.rewrite()
+
+      assert.is_nil(ngx.ctx.grpc_web)
+      assert.are.same({}, request_headers)
+    end)
+
+    it("forwards the request as a gRPC call", function()
+      mock_ngx({ var = { http_content_type = "application/grpc-web+proto" } })
+
+      grpc_web.rewrite()
+
+      assert.are.equal("application/grpc+proto", request_headers["Content-Type"])
+      assert.is_false(ngx.ctx.grpc_web.text)
+      assert.is_nil(request_body)
+    end)
+
+    it("decodes the body of grpc-web-text requests", function()
+      mock_ngx({
+        var = { http_content_type = "application/grpc-web-text" },
+        body = ngx.encode_base64("\0\0\0\0\2\10\0"),
+      })
+
+      grpc_web.rewrite()
+
+      assert.are.equal("application/grpc", request_headers["Content-Type"])
+      assert.are.equal("\0\0\0\0\2\10\0", request_body)
+      assert.is_true(ngx.ctx.grpc_web.text)
+    end)
+
+    it("rejects grpc-web-text requests with an invalid body", function()
+      mock_ngx({
+        var = { http_content_type = "application/grpc-web-text" },
+        body = "not base64!",
+      })
+
+      grpc_web.rewrite()
+
+      assert.are.equal(ngx.HTTP_BAD_REQUEST, exit_status)
+    end)
+  end)
+
+  describe("response", function()
+    local trailers = "\128\0\0\0\15grpc-status:0\r\n"
+
+    local function rewrite(content_type)
+      mock_ngx({
+        var = {
+          http_content_type = content_type,
+          upstream_trailer_grpc_status = "0",
+        },
+      })
+      grpc_web.rewrite()
+    end
+
+    it("sets the gRPC-Web content type", function()
+      rewrite("application/grpc-web+proto")
+      ngx.header["Content-Type"] = "application/grpc+proto"
+      ngx.header["Content-Length"] = "10"
+
+      grpc_web.header()
+
+      assert.are.equal("application/grpc-web+proto", ngx.header["Content-Type"])
+      assert.is_nil(ngx.header["Content-Length"])
+    end)
+
+    it("appends the trailers to the body", function()
+      rewrite("application/grpc-web")
+      grpc_web.header()
+
+      ngx.arg[1], ngx.arg[2] = "message", false
+      grpc_web.body()
+      assert.are.equal("message", ngx.arg[1])
+
+      ngx.arg[1], ngx.arg[2] = "", true
+      grpc_web.body()
+      assert.are.equal(trailers, ngx.arg[1])
+    end)
+
+    it("does not append the trailers of trailers-only responses", function()
+      rewrite("application/grpc-web")
+      ngx.header["grpc-status"] = "5"
+      grpc_web.header()
+
+      ngx.arg[1], ngx.arg[2] = "", true
+      grpc_web.body()
+      assert.are.equal("", ngx.arg[1])
+    end)
+
+    it("encodes the body of grpc-web-text responses", function()
+      rewrite("application/grpc-web-text")
+      grpc_web.header()
+
+      ngx.arg[1], ngx.arg[2] = "abcd", false
+      grpc_web.body()
+      assert.are.equal(ngx.encode_base64("abc"), ngx.arg[1])
+
+      ngx.arg[1], ngx.arg[2] = "", true
+      grpc_web.body()
+      assert.are.equal(ngx.encode_base64("d" .. trailers), ngx.arg[1])
+    end)
+
+    it("does not translate the errors of NGINX", function()
+      rewrite("application/grpc-web")
+      ngx.status = 502
+      grpc_web.header()
+
+      ngx.arg[1], ngx.arg[2] = "Bad Gateway", true
+      grpc_web.body()
+      assert.are.equal("Bad Gateway", ngx.arg[1])
+    end)
+  end)
+end)
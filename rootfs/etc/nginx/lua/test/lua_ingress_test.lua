@@ -7,3 +7,56 @@ describe("lua_ingress", function()
       string.format("ignoring math.randomseed(%d) since PRNG is already seeded for worker %d", 100, ngx.worker.pid()))
   end)
 end)
+
+describe("lua_ingress general configuration", function()
+  local lua_ingress = require("lua_ingress")
+  local original_ngx = ngx
+
+  before_each(function()
+    local _ngx = {
+      var = { scheme = "https" },
+      header = {},
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    lua_ingress.set_config({ hsts = true, hsts_max_age = "600" })
+  end)
+
+  after_each(function()
+    _G.ngx = original_ngx
+    ngx.shared.configuration_data:delete("general")
+  end)
+
+  it("applies the general configuration posted by the controller", function()
+    ngx.shared.configuration_data:set("general",
+      '{"hsts":true,"hsts_max_age":"3600","hsts_preload":true}')
+
+    lua_ingress.sync_general_config()
+    lua_ingress.header()
+
+    assert.are.equal("max-age=3600; preload", ngx.header["Strict-Transport-Security"])
+  end)
+
+  it("keeps the configuration when the general configuration is invalid", function()
+    ngx.shared.configuration_data:set("general", "{")
+
+    lua_ingress.sync_general_config()
+    lua_ingress.header()
+
+    assert.are.equal("max-age=600", ngx.header["Strict-Transport-Security"])
+  end)
+
+  it("applies the HSTS of the security headers of the location", function()
+    lua_ingress.header("max-age=63072000; includeSubDomains; preload")
+
+    assert.are.equal("max-age=63072000; includeSubDomains; preload", ngx.header["Strict-Transport-Security"])
+  end)
+
+  it("does not send the HSTS header in the HTTP responses", function()
+    ngx.var.scheme = "http"
+    lua_ingress.header("max-age=63072000; includeSubDomains; preload")
+
+    assert.is_nil(ngx.header["Strict-Transport-Security"])
+  end)
+end)
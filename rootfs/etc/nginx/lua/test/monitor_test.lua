@@ -53,6 +53,82 @@ describe("Monitor", function()
     assert.equal(10, #monitor.get_metrics_batch())
   end)
 
+  it("adds the ewma score of the endpoint when enabled", function()
+    mock_ngx({ var = { upstream_addr = "10.10.0.1:8080, 10.10.0.2:8080", balancer_ewma_score = "0.25" } })
+    local monitor = require("monitor")
+    monitor.call()
+
+    monitor.is_ewma_metrics_enabled = true
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_nil(metrics_batch[1].ewmaScore)
+    assert.equal("10.10.0.2:8080", metrics_batch[2].upstreamAddr)
+    assert.equal(0.25, metrics_batch[2].ewmaScore)
+  end)
+
+  it("adds the upstream attempts of the request when endpoint metrics are enabled", function()
+    mock_ngx({ var = {
+      upstream_addr = "10.10.0.1:8080, 10.10.0.2:8080",
+      upstream_status = "502, 200",
+      upstream_connect_time = "-, 0.001",
+      upstream_response_time = "0.003, 0.120",
+    } })
+    local monitor = require("monitor")
+    monitor.call()
+
+    monitor.is_endpoint_metrics_enabled = true
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_nil(metrics_batch[1].endpoints)
+    assert.are.same({
+      { address = "10.10.0.1:8080", status = "502", connectTime = -1, responseTime = 0.003 },
+      { address = "10.10.0.2:8080", status = "200", connectTime = 0.001, responseTime = 0.12 },
+    }, metrics_batch[2].endpoints)
+  end)
+
+  it("adds the trace id of the request when tracing is enabled", function()
+    mock_ngx({ var = { opentelemetry_trace_id = "4bf92f3577b34da6a3ce929d0e0e4736" } })
+    local monitor = require("monitor")
+    monitor.call()
+
+    monitor.is_tracing_enabled = true
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_nil(metrics_batch[1].traceId)
+    assert.equal("4bf92f3577b34da6a3ce929d0e0e4736", metrics_batch[2].traceId)
+  end)
+
+  it("adds the reason the request was denied", function()
+    local monitor = require("monitor")
+    local cases = {
+      { var = { status = "503", limit_req_status = "REJECTED" }, denial = "limit-req" },
+      { var = { status = "503", limit_conn_status = "REJECTED" }, denial = "limit-conn" },
+      { var = { status = "429", global_rate_limit_exceeding = "y" }, denial = "global-throttle" },
+      { var = { status = "401" }, denial = "auth" },
+      { var = { status = "403", auth_status = "403", source_range_restricted = "1" }, denial = "auth" },
+      { var = { status = "403", source_range_restricted = "1" }, denial = "source-range" },
+      { var = { status = "403" }, ctx = { auto_banned = true }, denial = "auto-ban" },
+      { var = { status = "403" }, ctx = { csrf_rejected = true }, denial = "csrf" },
+      { var = { status = "400" }, ctx = { request_violation = "line-folding" }, denial = "invalid-request" },
+      { var = { status = "403", upstream_addr = "10.10.0.1:8080", source_range_restricted = "1" }, denial = nil },
+      { var = { status = "200", limit_req_status = "PASSED" }, denial = nil },
+    }
+
+    for _, case in ipairs(cases) do
+      mock_ngx({ var = case.var, ctx = case.ctx or {} })
+      monitor.call()
+    end
+
+    local metrics_batch = monitor.get_metrics_batch()
+    for i, case in ipairs(cases) do
+      assert.equal(case.denial, metrics_batch[i].denial)
+    end
+    assert.equal("line-folding", metrics_batch[9].requestViolation)
+  end)
+
   describe("flush", function()
     it("short circuits when premature is true (when worker is shutting down)", function()
       local tcp_mock = mock_ngx_socket_tcp()
@@ -98,14 +174,18 @@ describe("Monitor", function()
         upstream_response_length = "456",
         upstream_status = "200",
       }
-      mock_ngx({ var = ngx_var_mock })
+      local ngx_ctx_mock = {
+        canary_backend = "default-http-svc-canary-80",
+        canary_variant = "canary",
+      }
+      mock_ngx({ var = ngx_var_mock, ctx = ngx_ctx_mock })
       local monitor = require("monitor")
       monitor.call()
 
       local ngx_var_mock1 = ngx_var_mock
       ngx_var_mock1.status = "201"
       ngx_var_mock1.request_method = "POST"
-      mock_ngx({ var = ngx_var_mock })
+      mock_ngx({ var = ngx_var_mock, ctx = ngx_ctx_mock })
       monitor.call()
 
       monitor.flush()
@@ -117,6 +197,8 @@ describe("Monitor", function()
           ingress = "example",
           service = "http-svc",
           canary = "default-http-svc-canary-80",
+          canaryBackend = "default-http-svc-canary-80",
+          variant = "canary",
           path = "/",
 
           method = "GET",
@@ -136,6 +218,8 @@ describe("Monitor", function()
           ingress = "example",
           service = "http-svc",
           canary = "default-http-svc-canary-80",
+          canaryBackend = "default-http-svc-canary-80",
+          variant = "canary",
           path = "/",
 
           method = "POST",
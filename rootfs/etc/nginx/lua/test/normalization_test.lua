@@ -0,0 +1,146 @@
+local original_ngx = ngx
+
+-- lowercased_headers returns the headers as get_headers without raw does,
+-- with their names lowercased and the values of the same name merged
+local function lowercased_headers(request_headers)
+  local lowercased = {}
+  for name, value in pairs(request_headers or {}) do
+    local lower_name = string.lower(name)
+    local values = type(value) == "table" and value or { value }
+    local existing = lowercased[lower_name]
+    if existing == nil and #values == 1 then
+      lowercased[lower_name] = values[1]
+    else
+      if type(existing) ~= "table" then
+        existing = { existing }
+      end
+      for _, v in ipairs(values) do
+        table.insert(existing, v)
+      end
+      lowercased[lower_name] = existing
+    end
+  end
+  return lowercased
+end
+
+describe("normalization", function()
+  local normalization, exit_status, redirect, uri, headers
+
+  local function mock_ngx(var, request_headers)
+    exit_status = nil
+    redirect = nil
+    uri = nil
+    headers = {}
+
+    local _ngx = {
+      var = var,
+      req = {
+        get_headers = function() return lowercased_headers(request_headers) end,
+        set_header = function(name, value) headers[name] = value end,
+        set_uri = function(new_uri) uri = new_uri end,
+      },
+      exit = function(status) exit_status = status end,
+      redirect = function(target, status) redirect = { uri = target, status = status } end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    normalization = require_without_cache("normalization")
+  end
+
+  local all = {
+    merge_slashes = true, percent_encoding = true, reject_encoded_traversal = true, duplicate_headers = true,
+  }
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("ignores the locations without normalization", function()
+    mock_ngx({ request_uri = "/static//..%2fadmin", uri = "/admin" })
+
+    normalization.rewrite({})
+
+    assert.is_nil(exit_status)
+    assert.is_nil(redirect)
+    assert.is_nil(uri)
+  end)
+
+  it("detects the encoded traversal sequences", function()
+    mock_ngx({})
+
+    assert.is_true(normalization.has_encoded_traversal("/static/%2E%2E/admin"))
+    assert.is_true(normalization.has_encoded_traversal("/static/..%2fadmin"))
+    assert.is_true(normalization.has_encoded_traversal("/static/..%5Cadmin"))
+    assert.is_true(normalization.has_encoded_traversal("/static/%252e%252e/admin"))
+    assert.is_true(normalization.has_encoded_traversal("/file%00.png"))
+    assert.is_false(normalization.has_encoded_traversal("/files/report%20q1.pdf"))
+  end)
+
+  it("rejects the paths with encoded traversal sequences", function()
+    mock_ngx({ request_uri = "/static/..%2fadmin?page=1", uri = "/admin" })
+
+    normalization.rewrite(all)
+
+    assert.equal(ngx.HTTP_BAD_REQUEST, exit_status)
+  end)
+
+  it("does not check the query string", function()
+    mock_ngx({ request_uri = "/search?q=%2e%2e", uri = "/search" })
+
+    normalization.rewrite({ reject_encoded_traversal = true })
+
+    assert.is_nil(exit_status)
+  end)
+
+  it("redirects the paths with duplicate slashes keeping their encoding", function()
+    mock_ngx({ request_uri = "//api///users/a%20b?page=1", uri = "/api/users/a b" })
+
+    normalization.rewrite(all)
+
+    assert.same({ uri = "/api/users/a%20b?page=1", status = ngx.HTTP_PERMANENT_REDIRECT }, redirect)
+  end)
+
+  it("proxies the normalized path", function()
+    mock_ngx({ request_uri = "/api/./users/%61bc", uri = "/api/users/abc" })
+
+    normalization.rewrite(all)
+
+    assert.is_nil(exit_status)
+    assert.equal("/api/users/abc", uri)
+  end)
+
+  it("merges the duplicate headers", function()
+    mock_ngx({ request_uri = "/", uri = "/" }, {
+      ["Accept"] = { "text/html", "application/json" },
+      ["Cookie"] = { "a=1", "b=2" },
+      ["User-Agent"] = "curl/8.0",
+    })
+
+    normalization.rewrite({ duplicate_headers = true })
+
+    assert.is_nil(exit_status)
+    assert.same({ ["accept"] = "text/html, application/json", ["cookie"] = "a=1; b=2" }, headers)
+  end)
+
+  it("rejects the duplicate single value headers", function()
+    mock_ngx({ request_uri = "/", uri = "/" }, {
+      ["Content-Length"] = { "10", "20" },
+    })
+
+    normalization.rewrite({ duplicate_headers = true })
+
+    assert.equal(ngx.HTTP_BAD_REQUEST, exit_status)
+  end)
+
+  it("rejects the single value headers sent several times with a different case", function()
+    mock_ngx({ request_uri = "/", uri = "/" }, {
+      ["Host"] = "shop.example.com",
+      ["host"] = "evil.example.org",
+    })
+
+    normalization.rewrite({ duplicate_headers = true })
+
+    assert.equal(ngx.HTTP_BAD_REQUEST, exit_status)
+  end)
+end)
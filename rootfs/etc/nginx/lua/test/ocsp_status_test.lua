@@ -0,0 +1,71 @@
+describe("ocsp_status", function()
+  local ocsp_status = require("ocsp_status")
+  local certificate_servers = ngx.shared.certificate_servers
+  local original_update_times = ocsp_status.update_times
+  local now = ngx.time()
+
+  before_each(function()
+    ocsp_status.update_times = function() return now - 3600, now + 86400 end
+    certificate_servers:set("example.com", "uid1")
+    certificate_servers:set("www.example.com", "uid1")
+    certificate_servers:set("other.com", "uid2")
+  end)
+
+  after_each(function()
+    ocsp_status.update_times = original_update_times
+    ngx.shared.ocsp_status:flush_all()
+    certificate_servers:flush_all()
+  end)
+
+  local function get_host(hostname)
+    for _, status in ipairs(ocsp_status.get_hosts(certificate_servers)) do
+      if status.hostname == hostname then
+        return status
+      end
+    end
+  end
+
+  it("records valid responses with their update times", function()
+    assert.are.equal(now + 86400, ocsp_status.success("uid1", "response"))
+
+    local status = get_host("example.com")
+    assert.is_true(status.success)
+    assert.are.equal(now - 3600, status.thisUpdate)
+    assert.are.equal(now + 86400, status.nextUpdate)
+    assert.are.equal(0, status.failures)
+    assert.are.same(status.nextUpdate, get_host("www.example.com").nextUpdate)
+    assert.is_nil(get_host("other.com"))
+  end)
+
+  it("counts consecutive failures and keeps the last valid response", function()
+    ocsp_status.success("uid1", "response")
+    ocsp_status.failure("uid1", "timeout")
+    ocsp_status.failure("uid1", "timeout")
+
+    local status = get_host("example.com")
+    assert.is_false(status.success)
+    assert.are.equal("timeout", status.error)
+    assert.are.equal(2, status.failures)
+    assert.are.equal(now + 86400, status.nextUpdate)
+
+    ocsp_status.success("uid1", "response")
+    assert.are.equal(0, get_host("example.com").failures)
+  end)
+
+  it("forgets removed certificates", function()
+    ocsp_status.failure("uid2", "timeout")
+    ocsp_status.remove("uid2")
+
+    assert.is_nil(get_host("other.com"))
+    assert.are.same({}, ocsp_status.get_hosts(certificate_servers))
+  end)
+
+  it("fails to parse invalid responses", function()
+    ocsp_status.update_times = original_update_times
+
+    local this_update, next_update, err = ocsp_status.update_times("invalid")
+    assert.is_nil(this_update)
+    assert.is_nil(next_update)
+    assert.are.equal("failed to parse OCSP response", err)
+  end)
+end)
@@ -19,5 +19,59 @@ describe("plugins", function()
       assert.has_no.errors(plugins.run)
       assert.are.same(plugins_to_mock, called_plugins)
     end)
+
+    describe("with the plugins of the locations", function()
+      local plugins, calls
+
+      before_each(function()
+        ngx.get_phase = function() return "rewrite" end
+        calls = {}
+
+        local function mock_plugin(name, module)
+          module = module or {}
+          module.rewrite = module.rewrite or function(config)
+            calls[#calls + 1] = { name = name, config = config }
+          end
+          package.loaded["plugins." .. name .. ".main"] = module
+        end
+
+        mock_plugin("global")
+        mock_plugin("ingress_b")
+        mock_plugin("ingress_a")
+        mock_plugin("header_only", { phases = { "header_filter" }, header_filter = function() end })
+        mock_plugin("undeclared_function", { phases = { "log" } })
+
+        plugins = require_without_cache("plugins")
+        plugins.init({ "global" }, { "ingress_a", "ingress_b", "header_only", "undeclared_function" })
+      end)
+
+      it("runs only the global plugins without location plugins", function()
+        plugins.run()
+
+        assert.are.same({ { name = "global" } }, calls)
+      end)
+
+      it("runs the enabled plugins in the loading order with their configuration", function()
+        plugins.run({ ingress_b = '{"greeting":"hi"}', ingress_a = true })
+
+        assert.are.same({
+          { name = "global" },
+          { name = "ingress_a" },
+          { name = "ingress_b", config = { greeting = "hi" } },
+        }, calls)
+      end)
+
+      it("does not run the disabled plugins", function()
+        plugins.run({ global = false, ingress_a = true })
+
+        assert.are.same({ { name = "ingress_a" } }, calls)
+      end)
+
+      it("runs the plugins only in their declared phases", function()
+        plugins.run({ header_only = true, undeclared_function = true, global = false })
+
+        assert.are.same({}, calls)
+      end)
+    end)
   end)
-end)
\ No newline at end of file
+end)
@@ -0,0 +1,43 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("request ID", function()
+  local request_id = require("request_id")
+
+  before_each(function()
+    mock_ngx({
+      var = { request_id = "0123456789abcdef0123456789abcdef" },
+      now = function() return 1700000000.123 end,
+    })
+  end)
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("formats the random request ID as a UUID version 4", function()
+    assert.equal("01234567-89ab-4def-8123-456789abcdef", request_id.generate("uuid4"))
+  end)
+
+  it("starts the UUID version 7 with the time of the request", function()
+    assert.equal("018bcfe5-687b-7def-8123-456789abcdef", request_id.generate("uuid7"))
+  end)
+
+  it("keeps the request ID generated before an internal redirect", function()
+    ngx.var.generated_request_id = "01234567-89ab-4def-8123-456789abcdef"
+
+    assert.equal("01234567-89ab-4def-8123-456789abcdef", request_id.generate("uuid7"))
+  end)
+
+  it("returns the random request ID for the other formats", function()
+    assert.equal("0123456789abcdef0123456789abcdef", request_id.generate("hex"))
+  end)
+end)
@@ -0,0 +1,139 @@
+local original_ngx = ngx
+
+describe("request validation", function()
+  local request_validation, exit_status
+
+  local function mock_ngx(http_version, raw_header, headers, var)
+    exit_status = nil
+
+    local _ngx = {
+      var = var or {},
+      ctx = {},
+      req = {
+        http_version = function() return http_version end,
+        raw_header = function() return raw_header end,
+        get_headers = function() return headers or {} end,
+      },
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    request_validation = require_without_cache("request_validation")
+  end
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  describe("check_raw_header()", function()
+    before_each(function()
+      mock_ngx(1.1)
+    end)
+
+    it("accepts the valid headers", function()
+      assert.is_nil(request_validation.check_raw_header(
+        "Host: example.com\r\nUser-Agent: curl/8.0\r\nX-Custom_Header: a:b\r\n\r\n"))
+    end)
+
+    it("detects the obsolete line folding", function()
+      assert.equal("line-folding", request_validation.check_raw_header(
+        "Host: example.com\r\nX-Custom: a\r\n b\r\n\r\n"))
+      assert.equal("line-folding", request_validation.check_raw_header(
+        "Host: example.com\r\n\tTransfer-Encoding: chunked\r\n\r\n"))
+    end)
+
+    it("detects the invalid header names", function()
+      assert.equal("invalid-header-name", request_validation.check_raw_header(
+        "Host: example.com\r\nTransfer-Encoding : chunked\r\n\r\n"))
+      assert.equal("invalid-header-name", request_validation.check_raw_header(
+        "Host: example.com\r\nX[Custom]: a\r\n\r\n"))
+      assert.equal("invalid-header-name", request_validation.check_raw_header(
+        "Host: example.com\r\nno colon\r\n\r\n"))
+    end)
+  end)
+
+  describe("check_length()", function()
+    before_each(function()
+      mock_ngx(1.1)
+    end)
+
+    it("accepts a single length", function()
+      assert.is_nil(request_validation.check_length({ ["content-length"] = "10" }))
+      assert.is_nil(request_validation.check_length({ ["transfer-encoding"] = "chunked" }))
+      assert.is_nil(request_validation.check_length({ ["content-length"] = { "10", "10" } }))
+    end)
+
+    it("detects the conflicting lengths", function()
+      assert.equal("length-conflict", request_validation.check_length({
+        ["content-length"] = "10", ["transfer-encoding"] = "chunked",
+      }))
+      assert.equal("length-conflict", request_validation.check_length({ ["content-length"] = { "10", "20" } }))
+    end)
+  end)
+
+  describe("check_absolute_uri()", function()
+    before_each(function()
+      mock_ngx(1.1)
+    end)
+
+    it("accepts the paths and the absolute URIs of the host", function()
+      assert.is_nil(request_validation.check_absolute_uri("GET /admin HTTP/1.1", "example.com"))
+      assert.is_nil(request_validation.check_absolute_uri("GET http://Example.com/admin HTTP/1.1", "example.com"))
+    end)
+
+    it("detects the absolute URIs of another host", function()
+      assert.equal("absolute-uri-mismatch",
+        request_validation.check_absolute_uri("GET http://internal.local/admin HTTP/1.1", "example.com"))
+      assert.equal("absolute-uri-mismatch",
+        request_validation.check_absolute_uri("GET http://internal.local/admin HTTP/1.1", nil))
+    end)
+  end)
+
+  describe("rewrite()", function()
+    it("ignores the locations without strict validation", function()
+      mock_ngx(1.1, "Host: example.com\r\nX-Custom: a\r\n b\r\n\r\n")
+
+      request_validation.rewrite(false)
+
+      assert.is_nil(exit_status)
+    end)
+
+    it("denies the invalid requests", function()
+      mock_ngx(1.1, "Host: example.com\r\nX-Custom: a\r\n b\r\n\r\n")
+
+      request_validation.rewrite(true)
+
+      assert.equal(ngx.HTTP_BAD_REQUEST, exit_status)
+      assert.equal("line-folding", ngx.ctx.request_violation)
+    end)
+
+    it("does not read the raw header of the HTTP/2 requests", function()
+      mock_ngx(2.0, nil, { ["content-length"] = "10" },
+        { request = "POST /api HTTP/2.0", http_host = "example.com" })
+
+      request_validation.rewrite(true)
+
+      assert.is_nil(exit_status)
+    end)
+
+    it("denies the requests with too many headers", function()
+      mock_ngx(2.0)
+      ngx.req.get_headers = function() return {}, "truncated" end
+
+      request_validation.rewrite(true)
+
+      assert.equal(ngx.HTTP_BAD_REQUEST, exit_status)
+      assert.equal("too-many-headers", ngx.ctx.request_violation)
+    end)
+
+    it("proxies the valid requests", function()
+      mock_ngx(1.1, "Host: example.com\r\nContent-Length: 2\r\n\r\n", { ["content-length"] = "2" },
+        { request = "POST /api HTTP/1.1", http_host = "example.com" })
+
+      request_validation.rewrite(true)
+
+      assert.is_nil(exit_status)
+    end)
+  end)
+end)
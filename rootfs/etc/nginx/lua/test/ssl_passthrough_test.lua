@@ -0,0 +1,56 @@
+local original_ngx = ngx
+
+describe("ssl_passthrough", function()
+  local ssl_passthrough, exit_status
+  local backend_names = { ["ssl-passthrough-app.example.com"] = true }
+
+  local function mock_ngx(server_name)
+    exit_status = nil
+
+    local _ngx = {
+      var = { ssl_preread_server_name = server_name },
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    package.loaded["tcp_udp_balancer"] = {
+      has_backend = function(backend_name) return backend_names[backend_name] ~= nil end,
+    }
+    ssl_passthrough = require_without_cache("ssl_passthrough")
+  end
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["tcp_udp_balancer"] = nil
+  end)
+
+  it("passes the connections of the passthrough servers to the passthrough socket", function()
+    mock_ngx("App.example.com")
+    ssl_passthrough.preread("unix:/tmp/nginx/ssl-passthrough.sock", "127.0.0.1:442")
+    assert.are.equal("unix:/tmp/nginx/ssl-passthrough.sock", ngx.var.ssl_passthrough_upstream)
+  end)
+
+  it("passes the other connections to the SSL proxy port", function()
+    mock_ngx("other.example.com")
+    ssl_passthrough.preread("unix:/tmp/nginx/ssl-passthrough.sock", "127.0.0.1:442")
+    assert.are.equal("127.0.0.1:442", ngx.var.ssl_passthrough_upstream)
+
+    mock_ngx(nil)
+    ssl_passthrough.preread("unix:/tmp/nginx/ssl-passthrough.sock", "127.0.0.1:442")
+    assert.are.equal("127.0.0.1:442", ngx.var.ssl_passthrough_upstream)
+  end)
+
+  it("routes the connections of the passthrough socket to the backend", function()
+    mock_ngx("app.example.com")
+    ssl_passthrough.route()
+    assert.are.equal("ssl-passthrough-app.example.com", ngx.var.proxy_upstream_name)
+    assert.is_nil(exit_status)
+  end)
+
+  it("closes the connections of the passthrough socket without backend", function()
+    mock_ngx("other.example.com")
+    ssl_passthrough.route()
+    assert.are.equal(ngx.ERROR, exit_status)
+  end)
+end)
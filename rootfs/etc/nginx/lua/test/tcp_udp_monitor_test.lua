@@ -0,0 +1,106 @@
+local cjson = require("cjson.safe")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function mock_ngx_socket_tcp()
+  local tcp_mock = {}
+  stub(tcp_mock, "connect", true)
+  stub(tcp_mock, "send", true)
+  stub(tcp_mock, "close", true)
+
+  local socket_mock = {}
+  stub(socket_mock, "tcp", tcp_mock)
+  mock_ngx({ socket = socket_mock })
+
+  return tcp_mock
+end
+
+describe("TCP and UDP monitor", function()
+  local ngx_var_mock = {
+    proxy_upstream_name = "tcp-default-postgres-5432",
+    status = "200",
+    bytes_received = "1024",
+    bytes_sent = "4096",
+    session_time = "12.500",
+  }
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["tcp_udp_monitor"] = nil
+  end)
+
+  it("batches the sessions with the service of their upstream", function()
+    mock_ngx({ var = ngx_var_mock })
+    local tcp_udp_monitor = require("tcp_udp_monitor")
+    tcp_udp_monitor.services = {
+      ["tcp-default-postgres-5432"] = { namespace = "default", name = "postgres" },
+    }
+
+    tcp_udp_monitor.call("5432", "TCP")
+
+    assert.are.same({
+      {
+        stream = {
+          protocol = "TCP",
+          port = "5432",
+          namespace = "default",
+          service = "postgres",
+          status = "200",
+          bytesReceived = 1024,
+          bytesSent = 4096,
+          sessionTime = 12.5,
+        },
+      },
+    }, tcp_udp_monitor.get_sessions_batch())
+  end)
+
+  it("leaves the service empty for the sessions without upstream", function()
+    mock_ngx({ var = { status = "502", bytes_received = "0", bytes_sent = "0", session_time = "0.001" } })
+    local tcp_udp_monitor = require("tcp_udp_monitor")
+
+    tcp_udp_monitor.call("8443", "TCP")
+
+    local session = tcp_udp_monitor.get_sessions_batch()[1].stream
+    assert.equal("", session.namespace)
+    assert.equal("", session.service)
+    assert.equal("502", session.status)
+  end)
+
+  describe("flush", function()
+    it("short circuits when there's no sessions batched", function()
+      local tcp_mock = mock_ngx_socket_tcp()
+      local tcp_udp_monitor = require("tcp_udp_monitor")
+
+      tcp_udp_monitor.flush()
+      assert.stub(tcp_mock.connect).was_not_called()
+    end)
+
+    it("JSON encodes and sends the batched sessions", function()
+      local tcp_mock = mock_ngx_socket_tcp()
+      mock_ngx({ var = ngx_var_mock })
+      local tcp_udp_monitor = require("tcp_udp_monitor")
+      tcp_udp_monitor.services = {
+        ["tcp-default-postgres-5432"] = { namespace = "default", name = "postgres" },
+      }
+
+      tcp_udp_monitor.call("5432", "TCP")
+      local expected_payload = cjson.encode(tcp_udp_monitor.get_sessions_batch())
+
+      tcp_udp_monitor.flush()
+
+      assert.stub(tcp_mock.connect).was_called_with(tcp_mock, "unix:/tmp/nginx/prometheus-nginx.socket")
+      assert.stub(tcp_mock.send).was_called_with(tcp_mock, expected_payload)
+      assert.stub(tcp_mock.close).was_called_with(tcp_mock)
+      assert.equal(0, #tcp_udp_monitor.get_sessions_batch())
+    end)
+  end)
+end)
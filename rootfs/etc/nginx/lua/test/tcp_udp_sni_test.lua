@@ -0,0 +1,58 @@
+local original_ngx = ngx
+
+describe("tcp_udp_sni", function()
+  local tcp_udp_sni, exit_status
+
+  local function mock_ngx(server_name)
+    exit_status = nil
+
+    local _ngx = {
+      var = { ssl_preread_server_name = server_name },
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    tcp_udp_sni = require_without_cache("tcp_udp_sni")
+  end
+
+  local routes = {
+    ["db.example.com"] = "tcp-default-db-5432",
+    ["*.example.com"] = "tcp-default-wildcard-5432",
+  }
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("routes an exact hostname", function()
+    mock_ngx("DB.example.com")
+    tcp_udp_sni.route(routes, nil)
+    assert.are.equal("tcp-default-db-5432", ngx.var.proxy_upstream_name)
+    assert.is_nil(exit_status)
+  end)
+
+  it("routes a wildcard hostname", function()
+    mock_ngx("cache.example.com")
+    tcp_udp_sni.route(routes, nil)
+    assert.are.equal("tcp-default-wildcard-5432", ngx.var.proxy_upstream_name)
+  end)
+
+  it("does not match several labels with a wildcard", function()
+    mock_ngx("a.cache.example.com")
+    assert.is_nil(tcp_udp_sni.upstream_name(routes, nil))
+  end)
+
+  it("routes the connections without server name to the default upstream", function()
+    mock_ngx(nil)
+    tcp_udp_sni.route(routes, "tcp-default-fallback-5432")
+    assert.are.equal("tcp-default-fallback-5432", ngx.var.proxy_upstream_name)
+  end)
+
+  it("closes the connections without matching route", function()
+    mock_ngx("example.org")
+    tcp_udp_sni.route(routes, nil)
+    assert.is_nil(ngx.var.proxy_upstream_name)
+    assert.are.equal(ngx.ERROR, exit_status)
+  end)
+end)
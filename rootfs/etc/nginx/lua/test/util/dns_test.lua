@@ -63,6 +63,13 @@ describe("dns.lookup", function()
       assert.spy(spy_ngx_log).was_called_with(ngx.ERR, "failed to query the DNS server for ", "example.com", ":\n", "no A record resolved\nno AAAA record resolved")
     end)
 
+    it("returns the expired addresses when the query returns nil", function()
+      dns._cache:set("example.com", { "192.168.1.1" }, -1)
+      helpers.mock_resty_dns_query(nil, nil, "oops!")
+      assert.are.same({ "192.168.1.1" }, dns_lookup("example.com"))
+      assert.spy(spy_ngx_log).was_called_with(ngx.WARN, "using the expired addresses of ", "example.com", ": ", "192.168.1.1")
+    end)
+
     it("returns host when the query returns nil and number of dots is not less than configured ndots", function()
       helpers.mock_resty_dns_query(nil, nil, "oops!")
       assert.are.same({ "a.b.c.d.example.com" }, dns_lookup("a.b.c.d.example.com"))    
@@ -133,4 +140,16 @@ describe("dns.lookup", function()
     assert.are.same({ "192.168.1.1", "1.2.3.4" }, dns_lookup("example.com."))
     assert.spy(spy_cache_set).was_called_with(match.is_table(), "example.com.", { "192.168.1.1", "1.2.3.4" }, 60)
   end)
+
+  it("returns the remaining ttl of the addresses", function()
+    helpers.mock_resty_dns_query("example.com.", { { name = "example.com.", address = "192.168.1.1", ttl = 60 } })
+
+    local addresses, ttl = dns_lookup("example.com.")
+    assert.are.same({ "192.168.1.1" }, addresses)
+    assert.are.equal(60, ttl)
+
+    addresses, ttl = dns_lookup("example.com.")
+    assert.are.same({ "192.168.1.1" }, addresses)
+    assert.is_true(ttl > 0 and ttl <= 60)
+  end)
 end)
@@ -0,0 +1,85 @@
+local util = require("util")
+
+local original_ngx = ngx
+
+describe("external_name", function()
+  local external_name, now, addresses, lookups
+  local backend
+
+  before_each(function()
+    now = 1543238266
+    addresses = { ["example.com"] = { "192.168.1.1" } }
+    lookups = 0
+
+    local _ngx = { now = function() return now end }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    package.loaded["util.dns"] = {
+      lookup = function(host)
+        lookups = lookups + 1
+        return addresses[host], 60
+      end,
+    }
+    external_name = require_without_cache("util.external_name")
+
+    backend = {
+      name = "example-com", service = { spec = { ["type"] = "ExternalName" } },
+      endpoints = { { address = "example.com", port = "80", maxFails = 0, failTimeout = 0 } },
+    }
+  end)
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["util.dns"] = nil
+  end)
+
+  it("resolves the host names of the endpoints", function()
+    addresses["example.com"] = { "192.168.1.1", "1.2.3.4" }
+
+    local resolved_backend = external_name.resolve(backend)
+
+    assert.are.same({
+      { address = "192.168.1.1", port = "80" },
+      { address = "1.2.3.4", port = "80" },
+    }, resolved_backend.endpoints)
+    assert.are.equal("example.com", backend.endpoints[1].address)
+  end)
+
+  it("is outdated until it is resolved", function()
+    assert.is_true(external_name.is_outdated(backend))
+
+    external_name.resolve(backend)
+    assert.is_false(external_name.is_outdated(backend))
+
+    local changed_backend = util.deepcopy(backend)
+    assert.is_true(external_name.is_outdated(changed_backend))
+  end)
+
+  it("is not resolved again before its records expire", function()
+    external_name.resolve(backend)
+
+    now = now + 30
+    assert.is_false(external_name.is_outdated(backend))
+    assert.are.equal(1, lookups)
+  end)
+
+  it("is outdated when its expired records resolve to other addresses", function()
+    external_name.resolve(backend)
+
+    now = now + 60
+    assert.is_false(external_name.is_outdated(backend))
+    assert.are.equal(2, lookups)
+
+    addresses["example.com"] = { "1.2.3.4" }
+    now = now + 60
+    assert.is_true(external_name.is_outdated(backend))
+  end)
+
+  it("forgets the removed backends", function()
+    external_name.resolve(backend)
+    external_name.remove(backend.name)
+
+    assert.is_true(external_name.is_outdated(backend))
+  end)
+end)
@@ -0,0 +1,103 @@
+local original_ngx = ngx
+
+describe("websocket", function()
+  local websocket, exit_status
+
+  local function mock_ngx(var)
+    exit_status = nil
+
+    local _ngx = {
+      ctx = {},
+      var = var,
+      exit = function(status) exit_status = status end,
+    }
+    setmetatable(_ngx, { __index = original_ngx })
+    _G.ngx = _ngx
+
+    websocket = require_without_cache("websocket")
+  end
+
+  local request_count = 0
+
+  local function upgrade_var(request_id)
+    request_count = request_count + 1
+    return {
+      http_upgrade = "websocket", namespace = "default", ingress_name = "chat",
+      request_id = request_id or tostring(request_count),
+    }
+  end
+
+  local config = { read_timeout = 3600, send_timeout = 0, max_connections = 2 }
+
+  after_each(function()
+    reset_ngx()
+    ngx.shared.websocket_connections:flush_all()
+  end)
+
+  it("ignores requests without an Upgrade header", function()
+    mock_ngx({ namespace = "default", ingress_name = "chat" })
+
+    websocket.rewrite(config)
+
+    assert.is_nil(ngx.ctx.websocket_timeouts)
+    assert.is_nil(ngx.shared.websocket_connections:get("default/chat"))
+  end)
+
+  it("sets the timeouts of WebSocket connections", function()
+    mock_ngx(upgrade_var())
+    local ngx_balancer = require("ngx.balancer")
+    local s = stub(ngx_balancer, "set_timeouts", true)
+
+    websocket.rewrite(config)
+    websocket.balance()
+
+    assert.stub(s).was_called_with(nil, nil, 3600)
+    s:revert()
+  end)
+
+  it("does not change the timeouts when they are not set", function()
+    mock_ngx(upgrade_var())
+
+    websocket.rewrite({ read_timeout = 0, send_timeout = 0, max_connections = 0 })
+
+    assert.is_nil(ngx.ctx.websocket_timeouts)
+  end)
+
+  it("rejects the connections above the limit", function()
+    for _ = 1, 2 do
+      mock_ngx(upgrade_var())
+      websocket.rewrite(config)
+      assert.is_nil(exit_status)
+    end
+
+    mock_ngx(upgrade_var())
+    websocket.rewrite(config)
+    assert.are.equal(ngx.HTTP_SERVICE_UNAVAILABLE, exit_status)
+
+    -- the rejected connection is released in the log phase
+    websocket.log()
+    assert.are.equal(2, ngx.shared.websocket_connections:get("default/chat"))
+  end)
+
+  it("releases the connections", function()
+    mock_ngx(upgrade_var())
+    websocket.rewrite(config)
+    websocket.log()
+
+    assert.are.equal(0, ngx.shared.websocket_connections:get("default/chat"))
+  end)
+
+  it("counts the connections once across internal redirects", function()
+    mock_ngx(upgrade_var("redirected"))
+    websocket.rewrite(config)
+
+    -- the location of the internal redirect runs with a new ngx.ctx
+    mock_ngx(upgrade_var("redirected"))
+    websocket.rewrite(config)
+    assert.are.equal(1, ngx.shared.websocket_connections:get("default/chat"))
+
+    mock_ngx(upgrade_var("redirected"))
+    websocket.log()
+    assert.are.equal(0, ngx.shared.websocket_connections:get("default/chat"))
+  end)
+end)
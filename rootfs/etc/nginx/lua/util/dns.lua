@@ -2,8 +2,10 @@ local resolver = require("resty.dns.resolver")
 local lrucache = require("resty.lrucache")
 local resolv_conf = require("util.resolv_conf")
 
+local ngx = ngx
 local ngx_log = ngx.log
 local ngx_INFO = ngx.INFO
+local ngx_WARN = ngx.WARN
 local ngx_ERR = ngx.ERR
 local string_format = string.format
 local table_concat = table.concat
@@ -18,6 +20,9 @@ local MAXIMUM_TTL_VALUE = 2147483647
 -- for every host we will try two queries for the following types with the order set here
 local QTYPES_TO_CHECK = { resolver.TYPE_A, resolver.TYPE_AAAA }
 
+-- the time the cached addresses of every host expire
+local expires_at = {}
+
 local cache
 do
   local err
@@ -29,6 +34,7 @@ end
 
 local function cache_set(host, addresses, ttl)
   cache:set(host, addresses, ttl)
+  expires_at[host] = ngx.now() + ttl
   ngx_log(ngx_INFO, string_format("cache set for '%s' with value of [%s] and ttl of %s.",
     host, table_concat(addresses, ", "), ttl))
 end
@@ -88,10 +94,39 @@ local function resolve_host(r, host)
   return nil, nil, dns_errors
 end
 
+-- remaining_ttl returns the seconds until the cached addresses of the host
+-- expire
+local function remaining_ttl(host)
+  local expiry = expires_at[host]
+  if not expiry then
+    return nil
+  end
+
+  local ttl = expiry - ngx.now()
+  if ttl < 0 then
+    return 0
+  end
+  return ttl
+end
+
+-- lookup_failed returns the addresses of the expired records of the host
+-- when the DNS servers cannot be queried, and the host itself otherwise
+local function lookup_failed(host, stale_addresses)
+  if stale_addresses then
+    ngx_log(ngx_WARN, "using the expired addresses of ", host, ": ",
+      table_concat(stale_addresses, ", "))
+    return stale_addresses
+  end
+
+  return { host }
+end
+
+-- lookup returns the addresses of the host and the seconds until they expire,
+-- nil when the host could not be resolved
 function _M.lookup(host)
-  local cached_addresses = cache:get(host)
+  local cached_addresses, stale_addresses = cache:get(host)
   if cached_addresses then
-    return cached_addresses
+    return cached_addresses, remaining_ttl(host)
   end
 
   local r, err = resolver:new{
@@ -102,7 +137,7 @@ function _M.lookup(host)
 
   if not r then
     ngx_log(ngx_ERR, string_format("failed to instantiate the resolver: %s", err))
-    return { host }
+    return lookup_failed(host, stale_addresses)
   end
 
   local addresses, ttl, dns_errors
@@ -115,13 +150,13 @@ function _M.lookup(host)
     addresses, ttl, dns_errors = resolve_host(r, host)
     if addresses then
       cache_set(host, addresses, ttl)
-      return addresses
+      return addresses, ttl
     end
 
     ngx_log(ngx_ERR, "failed to query the DNS server for ",
       host, ":\n", table_concat(dns_errors, "\n"))
 
-    return { host }
+    return lookup_failed(host, stale_addresses)
   end
 
   -- for non fully qualified domains if number of dots in
@@ -144,7 +179,7 @@ function _M.lookup(host)
     addresses, ttl, dns_errors = resolve_host(r, new_host)
     if addresses then
       cache_set(host, addresses, ttl)
-      return addresses
+      return addresses, ttl
     end
   end
 
@@ -153,7 +188,7 @@ function _M.lookup(host)
       host, ":\n", table_concat(dns_errors, "\n"))
   end
 
-  return { host }
+  return lookup_failed(host, stale_addresses)
 end
 
 setmetatable(_M, {__index = { _cache = cache }})
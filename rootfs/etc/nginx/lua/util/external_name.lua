@@ -0,0 +1,91 @@
+-- ExternalName resolution.
+--
+-- The endpoints of the backends of the ExternalName services are host names,
+-- resolved by the balancers. The balancer of such a backend is synced again
+-- when the DNS records of its host names expire and resolve to other
+-- addresses, following their TTL without waiting for the controller.
+--
+local util = require("util")
+local dns_lookup = require("util.dns").lookup
+
+local ngx = ngx
+local ipairs = ipairs
+local table_insert = table.insert
+
+local _M = {}
+
+-- the resolved endpoints of the backends and the time they expire, indexed
+-- by backend name
+local resolutions = {}
+
+function _M.is_backend_with_external_name(backend)
+  local serv_type = backend.service and backend.service.spec
+                      and backend.service.spec["type"]
+  return serv_type == "ExternalName"
+end
+
+-- resolve_endpoints returns the resolved endpoints of the backend and the
+-- seconds until the first of them expires, zero when a host name could not
+-- be resolved
+local function resolve_endpoints(backend)
+  local endpoints = {}
+  local ttl
+  for _, endpoint in ipairs(backend.endpoints) do
+    local ips, ips_ttl = dns_lookup(endpoint.address)
+    ips_ttl = ips_ttl or 0
+    if not ttl or ips_ttl < ttl then
+      ttl = ips_ttl
+    end
+
+    for _, ip in ipairs(ips) do
+      table_insert(endpoints, { address = ip, port = endpoint.port })
+    end
+  end
+
+  return endpoints, ttl or 0
+end
+
+local function set_resolution(backend, endpoints, ttl)
+  resolutions[backend.name] = {
+    backend = backend,
+    -- the endpoints given to the balancers are modified
+    endpoints = util.deepcopy(endpoints),
+    expires_at = ngx.now() + ttl,
+  }
+end
+
+-- resolve returns the backend with its host names resolved
+function _M.resolve(original_backend)
+  local backend = util.deepcopy(original_backend)
+  local ttl
+  backend.endpoints, ttl = resolve_endpoints(original_backend)
+  set_resolution(original_backend, backend.endpoints, ttl)
+  return backend
+end
+
+-- is_outdated returns true when the backend was not resolved yet, or when
+-- the records of its host names expired and resolve to other addresses
+function _M.is_outdated(backend)
+  local resolution = resolutions[backend.name]
+  if not resolution or resolution.backend ~= backend then
+    return true
+  end
+
+  if ngx.now() < resolution.expires_at then
+    return false
+  end
+
+  local endpoints, ttl = resolve_endpoints(backend)
+  if not util.deep_compare(resolution.endpoints, endpoints) then
+    return true
+  end
+
+  resolution.expires_at = ngx.now() + ttl
+  return false
+end
+
+function _M.remove(backend_name)
+  resolutions[backend_name] = nil
+end
+
+return _M
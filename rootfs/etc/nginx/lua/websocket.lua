@@ -0,0 +1,116 @@
+-- WebSocket timeouts and connection limit.
+--
+-- The timeouts of the requests upgraded to WebSocket are set by the balancer,
+-- so long-lived sockets don't force a long proxy-read-timeout on the regular
+-- requests of the location. The WebSocket connections to every Ingress are
+-- counted in a shared dictionary and the ones above the limit are rejected.
+-- The connection counted for a request is recorded by $request_id in the
+-- dictionary, ngx.ctx is reset by the internal redirects of the error pages.
+--
+local ngx_balancer = require("ngx.balancer")
+
+local ngx = ngx
+local string_lower = string.lower
+local tostring = tostring
+
+local _M = {}
+
+local function dict()
+  return ngx.shared.websocket_connections
+end
+
+-- request_key returns the key of the connection counted for the request
+local function request_key()
+  return "request:" .. ngx.var.request_id
+end
+
+local function is_upgrade()
+  local upgrade = ngx.var.http_upgrade
+  return upgrade ~= nil and string_lower(upgrade) == "websocket"
+end
+
+-- a zero timeout keeps the timeout of the location
+local function positive_or_nil(value)
+  if value and value > 0 then
+    return value
+  end
+  return nil
+end
+
+function _M.rewrite(config)
+  if not config or not is_upgrade() then
+    return
+  end
+
+  if config.read_timeout > 0 or config.send_timeout > 0 then
+    ngx.ctx.websocket_timeouts = {
+      read = positive_or_nil(config.read_timeout),
+      send = positive_or_nil(config.send_timeout),
+    }
+  end
+
+  if config.max_connections <= 0 then
+    return
+  end
+
+  -- the connection of a request redirected to another location is counted
+  -- once, by the first location
+  if dict():get(request_key()) then
+    return
+  end
+
+  local key = ngx.var.namespace .. "/" .. ngx.var.ingress_name
+  local connections, err = dict():incr(key, 1, 0)
+  if not connections then
+    ngx.log(ngx.ERR, "websocket_connections:incr failed " .. tostring(err))
+    return
+  end
+
+  -- the connection is released in the log phase, even when it is rejected
+  local ok
+  ok, err = dict():safe_set(request_key(), key)
+  if not ok then
+    ngx.log(ngx.ERR, "websocket_connections:safe_set failed " .. tostring(err))
+    dict():incr(key, -1, 0)
+    return
+  end
+
+  if connections > config.max_connections then
+    ngx.log(ngx.WARN, "rejecting WebSocket connection to ingress ", key,
+            ", the maximum of ", config.max_connections, " connections was reached")
+    return ngx.exit(ngx.HTTP_SERVICE_UNAVAILABLE)
+  end
+end
+
+-- balance sets the timeouts of the WebSocket connections, the timeouts of the
+-- upstream can only be changed by the balancer
+function _M.balance()
+  local timeouts = ngx.ctx.websocket_timeouts
+  if not timeouts then
+    return
+  end
+
+  local ok, err = ngx_balancer.set_timeouts(nil, timeouts.send, timeouts.read)
+  if not ok then
+    ngx.log(ngx.ERR, "failed to set the WebSocket timeouts: ", err)
+  end
+end
+
+function _M.log()
+  if not is_upgrade() then
+    return
+  end
+
+  local key = dict():get(request_key())
+  if not key then
+    return
+  end
+  dict():delete(request_key())
+
+  local _, err = dict():incr(key, -1, 0)
+  if err then
+    ngx.log(ngx.ERR, "websocket_connections:incr failed " .. tostring(err))
+  end
+end
+
+return _M
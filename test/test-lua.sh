@@ -31,12 +31,24 @@ SHDICT_ARGS=(
     "--shdict" "certificate_data 16M"
     "--shdict" "certificate_servers 1M"
     "--shdict" "ocsp_response_cache 1M"
+    "--shdict" "ocsp_status 1M"
     "--shdict" "balancer_ewma 1M"
     "--shdict" "quota_tracker 1M"
     "--shdict" "high_throughput_tracker 1M"
     "--shdict" "balancer_ewma_last_touched_at 1M"
     "--shdict" "balancer_ewma_locks 512k"
+    "--shdict" "balancer_least_conn 1M"
+    "--shdict" "balancer_bounded_load 1M"
+    "--shdict" "balancer_outlier 1M"
+    "--shdict" "balancer_healthcheck 1M"
     "--shdict" "global_throttle_cache 5M"
+    "--shdict" "websocket_connections 1M"
+    "--shdict" "acme_challenges 1M"
+    "--shdict" "drain 1M"
+    "--shdict" "bot_detection 1M"
+    "--shdict" "denylist 1M"
+    "--shdict" "crowdsec_cache 1M"
+    "--shdict" "auto_ban 1M"
     "./rootfs/etc/nginx/lua/test/run.lua"
 )
 